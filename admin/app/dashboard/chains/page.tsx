@@ -2,14 +2,17 @@
 
 import { useState } from 'react';
 import useSWR, { mutate } from 'swr';
-import { getChains, updateChain, updateChainStatus, createChain } from '@/lib/api/admin';
-import type { ChainConfig } from '@/lib/api/admin';
+import { getChains, updateChain, updateChainStatus, createChain, upsertWithdrawFee, upsertChainToken, deleteChainToken, upsertRpcEndpoint, deleteRpcEndpoint, getRpcHealth } from '@/lib/api/admin';
+import type { ChainConfig, RpcEndpointStatus } from '@/lib/api/admin';
 import toast from 'react-hot-toast';
 
 export default function ChainsPage() {
   const { data: chains = [], isLoading, error } = useSWR('/admin/chains', getChains, {
     refreshInterval: 5000,
   });
+  const { data: rpcHealth = [] } = useSWR('/admin/chains/rpc-health', getRpcHealth, {
+    refreshInterval: 10000,
+  });
 
   const [editingChain, setEditingChain] = useState<ChainConfig | null>(null);
   const [showEditModal, setShowEditModal] = useState(false);
@@ -30,8 +33,19 @@ export default function ChainsPage() {
       block_explorer_url: '',
       usdt_contract_address: '',
       usdt_decimals: 18,
+      withdraw_confirmations: 12,
+      required_confirmations: 12,
+      tx_type: 'legacy',
+      max_gas_price_gwei: '0',
+      max_priority_fee_gwei: '0',
+      max_gas_limit: 0,
+      multisend_contract_address: '',
+      cold_wallet_address: '',
       platform_deposit_address: '',
+      deposit_xpub: '',
+      deposit_sweep_key: '',
       platform_withdraw_private_key: '',
+      signer_type: 'local',
       enabled: true,
     });
     setShowEditModal(true);
@@ -66,6 +80,175 @@ export default function ChainsPage() {
     }
   };
 
+  const handleEditWithdrawFee = async (chain: ChainConfig) => {
+    const asset = prompt(`${chain.chain_name} 提现资产`, 'USDT')?.trim().toUpperCase();
+    if (!asset) {
+      return;
+    }
+    const current = chain.withdraw_fees?.find((f) => f.asset === asset);
+    const fee = prompt(`${chain.chain_name} ${asset} 提现手续费（每笔）`, current?.fee ?? '0');
+    if (fee === null) {
+      return;
+    }
+    const minAmount = prompt(`${chain.chain_name} ${asset} 最小提现金额（含手续费）`, current?.min_amount ?? '0');
+    if (minAmount === null) {
+      return;
+    }
+
+    try {
+      await upsertWithdrawFee(chain.id, { asset, fee, min_amount: minAmount });
+      mutate('/admin/chains');
+      toast.success('提现手续费已更新');
+    } catch (error: any) {
+      toast.error(error.response?.data?.error || '操作失败');
+    }
+  };
+
+  const handleEditToken = async (chain: ChainConfig) => {
+    const asset = prompt(`${chain.chain_name} 资产符号（如 USDT、USDC、ETH）`, 'USDT')?.trim().toUpperCase();
+    if (!asset) {
+      return;
+    }
+    const current = chain.tokens?.find((t) => t.asset === asset);
+    const contractAddress = prompt(`${asset} 合约地址（原生币留空）`, current?.contract_address ?? '');
+    if (contractAddress === null) {
+      return;
+    }
+    const decimals = prompt(`${asset} 链上精度`, String(current?.decimals ?? 18));
+    if (decimals === null) {
+      return;
+    }
+    const minDeposit = prompt(`${asset} 最小充值金额（低于该金额不入账）`, current?.min_deposit_amount ?? '0');
+    if (minDeposit === null) {
+      return;
+    }
+    const hotAlert = prompt(`${asset} 热钱包余额告警值（低于该值告警，0 表示不告警）`, current?.hot_alert_balance ?? '0');
+    if (hotAlert === null) {
+      return;
+    }
+    const hotPause = prompt(`${asset} 热钱包余额暂停值（低于该值自动暂停提现，0 表示不暂停）`, current?.hot_pause_balance ?? '0');
+    if (hotPause === null) {
+      return;
+    }
+    const riskLarge = prompt(
+      `${asset} 风控单笔大额阈值（${asset} 数量，0 表示${asset === 'USDT' ? '使用系统配置' : '不检查'}）`,
+      current?.risk_large_amount ?? '0'
+    );
+    if (riskLarge === null) {
+      return;
+    }
+    const riskDaily = prompt(
+      `${asset} 风控 24 小时累计限额（${asset} 数量，0 表示${asset === 'USDT' ? '使用系统配置' : '不检查'}）`,
+      current?.risk_daily_limit ?? '0'
+    );
+    if (riskDaily === null) {
+      return;
+    }
+    const depositEnabled = confirm(`开放 ${chain.chain_name} ${asset} 充值？`);
+    const withdrawEnabled = confirm(`开放 ${chain.chain_name} ${asset} 提现？`);
+
+    try {
+      await upsertChainToken(chain.id, {
+        asset,
+        contract_address: contractAddress.trim(),
+        decimals: parseInt(decimals, 10),
+        deposit_enabled: depositEnabled,
+        withdraw_enabled: withdrawEnabled,
+        min_deposit_amount: minDeposit,
+        hot_alert_balance: hotAlert,
+        hot_pause_balance: hotPause,
+        risk_large_amount: riskLarge,
+        risk_daily_limit: riskDaily,
+      });
+      mutate('/admin/chains');
+      toast.success(`${asset} 配置已更新`);
+    } catch (error: any) {
+      toast.error(error.response?.data?.error || '操作失败');
+    }
+  };
+
+  const handleDeleteToken = async (chain: ChainConfig, asset: string) => {
+    if (!confirm(`确定要删除${chain.chain_name}的 ${asset} 吗？`)) {
+      return;
+    }
+
+    try {
+      await deleteChainToken(chain.id, asset);
+      mutate('/admin/chains');
+      toast.success(`${asset} 已删除`);
+    } catch (error: any) {
+      toast.error(error.response?.data?.error || '操作失败');
+    }
+  };
+
+  const handleEditRpcEndpoint = async (chain: ChainConfig) => {
+    const url = prompt(`${chain.chain_name} RPC 节点地址（http/https）`, '')?.trim();
+    if (!url) {
+      return;
+    }
+    const current = chain.rpc_endpoints?.find((e) => e.url === url);
+    const priority = prompt('优先级（数值越小越优先，链配置的 RPC 地址默认为 0）', String(current?.priority ?? 1));
+    if (priority === null) {
+      return;
+    }
+    const weight = prompt('权重（同优先级节点按权重分配请求）', String(current?.weight ?? 1));
+    if (weight === null) {
+      return;
+    }
+    const enabled = confirm('启用该节点？');
+
+    try {
+      await upsertRpcEndpoint(chain.id, {
+        url,
+        priority: parseInt(priority, 10) || 0,
+        weight: parseInt(weight, 10) || 1,
+        enabled,
+      });
+      mutate('/admin/chains');
+      mutate('/admin/chains/rpc-health');
+      toast.success('RPC 节点已更新');
+    } catch (error: any) {
+      toast.error(error.response?.data?.error || '操作失败');
+    }
+  };
+
+  const handleDeleteRpcEndpoint = async (chain: ChainConfig, endpointId: string, url: string) => {
+    if (!confirm(`确定要删除${chain.chain_name}的 RPC 节点 ${url} 吗？`)) {
+      return;
+    }
+
+    try {
+      await deleteRpcEndpoint(chain.id, endpointId);
+      mutate('/admin/chains');
+      mutate('/admin/chains/rpc-health');
+      toast.success('RPC 节点已删除');
+    } catch (error: any) {
+      toast.error(error.response?.data?.error || '操作失败');
+    }
+  };
+
+  // 节点地址可能带 API key，列表中只显示主机名
+  const rpcHost = (url: string) => {
+    try {
+      return new URL(url).host;
+    } catch {
+      return url;
+    }
+  };
+
+  const rpcStateLabel = (status: RpcEndpointStatus) => {
+    switch (status.state) {
+      case 'healthy':
+        return <span className="text-green-400">正常</span>;
+      case 'lagging':
+        return <span className="text-yellow-400">落后 {status.lag} 块</span>;
+      case 'half_open':
+        return <span className="text-yellow-400">试探中</span>;
+      default:
+        return <span className="text-red-400" title={status.last_error}>熔断</span>;
+    }
+  };
+
   if (isLoading) {
     return <div className="p-6">加载中...</div>;
   }
@@ -95,6 +278,9 @@ export default function ChainsPage() {
                 <th className="text-left p-4 text-gray-400 font-semibold">Chain ID</th>
                 <th className="text-left p-4 text-gray-400 font-semibold">USDT合约</th>
                 <th className="text-left p-4 text-gray-400 font-semibold">收款地址</th>
+                <th className="text-left p-4 text-gray-400 font-semibold">RPC 节点</th>
+                <th className="text-left p-4 text-gray-400 font-semibold">资产（充值 / 提现）</th>
+                <th className="text-left p-4 text-gray-400 font-semibold">提现手续费 / 最小额</th>
                 <th className="text-left p-4 text-gray-400 font-semibold">状态</th>
                 <th className="text-left p-4 text-gray-400 font-semibold">操作</th>
               </tr>
@@ -102,7 +288,7 @@ export default function ChainsPage() {
             <tbody>
               {chains.length === 0 ? (
                 <tr>
-                  <td colSpan={6} className="text-center p-8 text-gray-400">
+                  <td colSpan={9} className="text-center p-8 text-gray-400">
                     暂无链配置
                   </td>
                 </tr>
@@ -121,6 +307,69 @@ export default function ChainsPage() {
                     <td className="p-4 text-sm text-gray-400 font-mono">
                       {chain.platform_deposit_address.slice(0, 6)}...{chain.platform_deposit_address.slice(-4)}
                     </td>
+                    <td className="p-4 text-sm text-gray-400">
+                      {(rpcHealth.find((h) => h.chain_id === chain.chain_id)?.endpoints ?? []).map((status) => {
+                        const endpoint = chain.rpc_endpoints?.find((e) => e.url === status.url);
+                        return (
+                          <div key={status.url} className="whitespace-nowrap">
+                            <span className="font-mono" title={status.url}>{rpcHost(status.url)}</span>
+                            {' '}P{status.priority}/W{status.weight} {rpcStateLabel(status)}
+                            {status.head > 0 && <span className="ml-1 text-gray-500">#{status.head}</span>}
+                            {endpoint && (
+                              <button
+                                onClick={() => handleDeleteRpcEndpoint(chain, endpoint.id, endpoint.url)}
+                                className="ml-2 text-red-400 hover:text-red-300"
+                              >
+                                删除
+                              </button>
+                            )}
+                          </div>
+                        );
+                      })}
+                      {chain.rpc_endpoints?.filter((e) => !e.enabled).map((e) => (
+                        <div key={e.id} className="whitespace-nowrap text-gray-500">
+                          <span className="font-mono" title={e.url}>{rpcHost(e.url)}</span> 已停用
+                          <button
+                            onClick={() => handleDeleteRpcEndpoint(chain, e.id, e.url)}
+                            className="ml-2 text-red-400 hover:text-red-300"
+                          >
+                            删除
+                          </button>
+                        </div>
+                      ))}
+                    </td>
+                    <td className="p-4 text-sm text-gray-400">
+                      {chain.tokens && chain.tokens.length > 0
+                        ? chain.tokens.map((t) => (
+                            <div key={t.asset}>
+                              {t.asset}
+                              {t.contract_address ? '' : '（原生）'}: {t.deposit_enabled ? '✓' : '✗'} / {t.withdraw_enabled ? '✓' : '✗'}
+                              {t.withdraw_paused && (
+                                <span className="ml-1 text-yellow-400" title={t.pause_reason}>
+                                  提现已暂停
+                                </span>
+                              )}
+                              {t.asset !== 'USDT' && (
+                                <button
+                                  onClick={() => handleDeleteToken(chain, t.asset)}
+                                  className="ml-2 text-red-400 hover:text-red-300"
+                                >
+                                  删除
+                                </button>
+                              )}
+                            </div>
+                          ))
+                        : '-'}
+                    </td>
+                    <td className="p-4 text-sm text-gray-400">
+                      {chain.withdraw_fees && chain.withdraw_fees.length > 0
+                        ? chain.withdraw_fees.map((f) => (
+                            <div key={f.asset}>
+                              {f.asset}: {f.fee} / {f.min_amount}
+                            </div>
+                          ))
+                        : '免手续费'}
+                    </td>
                     <td className="p-4">
                       <span className={`px-2 py-1 text-xs rounded ${
                         chain.enabled 
@@ -137,6 +386,24 @@ export default function ChainsPage() {
                       >
                         编辑
                       </button>
+                      <button
+                        onClick={() => handleEditWithdrawFee(chain)}
+                        className="text-yellow-400 hover:text-yellow-300"
+                      >
+                        手续费
+                      </button>
+                      <button
+                        onClick={() => handleEditToken(chain)}
+                        className="text-blue-400 hover:text-blue-300"
+                      >
+                        资产
+                      </button>
+                      <button
+                        onClick={() => handleEditRpcEndpoint(chain)}
+                        className="text-purple-400 hover:text-purple-300"
+                      >
+                        节点
+                      </button>
                       <button
                         onClick={() => handleToggleStatus(chain)}
                         className={chain.enabled ? 'text-red-400 hover:text-red-300' : 'text-green-400 hover:text-green-300'}
@@ -271,6 +538,99 @@ export default function ChainsPage() {
                 </div>
               </div>
 
+              <div>
+                <label className="block text-xs font-medium text-gray-400 mb-1.5">
+                  提现确认数
+                </label>
+                <input
+                  type="number"
+                  min={1}
+                  value={formData.withdraw_confirmations || 12}
+                  onChange={(e) => setFormData({...formData, withdraw_confirmations: parseInt(e.target.value)})}
+                  className="w-full px-3 py-2 text-sm bg-[#151a35] border border-gray-700 rounded text-white focus:ring-1 focus:ring-primary focus:border-transparent"
+                />
+                <p className="text-xs text-gray-500 mt-1">提现交易打包后达到该确认数才标记完成</p>
+              </div>
+
+              <div>
+                <label className="block text-xs font-medium text-gray-400 mb-1.5">
+                  充值确认数
+                </label>
+                <input
+                  type="number"
+                  min={1}
+                  value={formData.required_confirmations || 12}
+                  onChange={(e) => setFormData({...formData, required_confirmations: parseInt(e.target.value)})}
+                  className="w-full px-3 py-2 text-sm bg-[#151a35] border border-gray-700 rounded text-white focus:ring-1 focus:ring-primary focus:border-transparent"
+                />
+                <p className="text-xs text-gray-500 mt-1">充值交易达到该确认数且所在区块仍在主链上才入账（交易所在区块计为 1 个确认）</p>
+              </div>
+
+              <div className="grid grid-cols-2 gap-3">
+                <div>
+                  <label className="block text-xs font-medium text-gray-400 mb-1.5">
+                    提现交易类型
+                  </label>
+                  <select
+                    value={formData.tx_type || 'legacy'}
+                    onChange={(e) => setFormData({...formData, tx_type: e.target.value as ChainConfig['tx_type']})}
+                    className="w-full px-3 py-2 text-sm bg-[#151a35] border border-gray-700 rounded text-white focus:ring-1 focus:ring-primary focus:border-transparent"
+                  >
+                    <option value="legacy">Legacy（gasPrice）</option>
+                    <option value="eip1559">EIP-1559（maxFee + tip）</option>
+                  </select>
+                </div>
+                <div>
+                  <label className="block text-xs font-medium text-gray-400 mb-1.5">
+                    Gas Limit 上限
+                  </label>
+                  <input
+                    type="number"
+                    min={0}
+                    value={formData.max_gas_limit || 0}
+                    onChange={(e) => setFormData({...formData, max_gas_limit: parseInt(e.target.value) || 0})}
+                    className="w-full px-3 py-2 text-sm bg-[#151a35] border border-gray-700 rounded text-white focus:ring-1 focus:ring-primary focus:border-transparent"
+                  />
+                </div>
+                <div>
+                  <label className="block text-xs font-medium text-gray-400 mb-1.5">
+                    Gas Price / Max Fee 上限（Gwei）
+                  </label>
+                  <input
+                    type="text"
+                    value={formData.max_gas_price_gwei || '0'}
+                    onChange={(e) => setFormData({...formData, max_gas_price_gwei: e.target.value})}
+                    className="w-full px-3 py-2 text-sm bg-[#151a35] border border-gray-700 rounded text-white font-mono focus:ring-1 focus:ring-primary focus:border-transparent"
+                  />
+                </div>
+                <div>
+                  <label className="block text-xs font-medium text-gray-400 mb-1.5">
+                    小费上限（Gwei，仅 EIP-1559）
+                  </label>
+                  <input
+                    type="text"
+                    value={formData.max_priority_fee_gwei || '0'}
+                    onChange={(e) => setFormData({...formData, max_priority_fee_gwei: e.target.value})}
+                    className="w-full px-3 py-2 text-sm bg-[#151a35] border border-gray-700 rounded text-white font-mono focus:ring-1 focus:ring-primary focus:border-transparent"
+                  />
+                </div>
+              </div>
+              <p className="text-xs text-gray-500 -mt-2">上限填 0 表示不限；网络费用超过上限时提现失败并解冻资金</p>
+
+              <div>
+                <label className="block text-xs font-medium text-gray-400 mb-1.5">
+                  批量提现合约地址
+                </label>
+                <input
+                  type="text"
+                  value={formData.multisend_contract_address || ''}
+                  onChange={(e) => setFormData({...formData, multisend_contract_address: e.target.value})}
+                  className="w-full px-3 py-2 text-sm bg-[#151a35] border border-gray-700 rounded text-white font-mono focus:ring-1 focus:ring-primary focus:border-transparent"
+                  placeholder="0x...（留空表示逐笔发送）"
+                />
+                <p className="text-xs text-gray-500 mt-1">Disperse 合约地址；热钱包需预先 approve 该合约 USDT 额度，并开启 withdraw.batch.enabled</p>
+              </div>
+
               <div>
                 <label className="block text-xs font-medium text-gray-400 mb-1.5">
                   收款地址 *
@@ -286,18 +646,167 @@ export default function ChainsPage() {
 
               <div>
                 <label className="block text-xs font-medium text-gray-400 mb-1.5">
-                  提现私钥 <span className="text-red-400">(敏感)</span>
+                  充值地址 xpub
+                </label>
+                <input
+                  type="text"
+                  value={formData.deposit_xpub || ''}
+                  onChange={(e) => setFormData({...formData, deposit_xpub: e.target.value})}
+                  className="w-full px-3 py-2 text-sm bg-[#151a35] border border-gray-700 rounded text-white font-mono focus:ring-1 focus:ring-primary focus:border-transparent"
+                  placeholder="xpub...（留空表示使用共享收款地址）"
+                />
+                <p className="text-xs text-gray-500 mt-1">账户层级 m/44&apos;/60&apos;/0&apos; 的扩展公钥，用户地址为 xpub/0/序号；已分配地址后不可修改</p>
+              </div>
+
+              <div>
+                <label className="block text-xs font-medium text-gray-400 mb-1.5">
+                  归集私钥 xprv <span className="text-red-400">(敏感)</span>
                 </label>
                 <input
                   type="password"
-                  value={formData.platform_withdraw_private_key || ''}
-                  onChange={(e) => setFormData({...formData, platform_withdraw_private_key: e.target.value})}
+                  value={formData.deposit_sweep_key || ''}
+                  onChange={(e) => setFormData({...formData, deposit_sweep_key: e.target.value})}
                   className="w-full px-3 py-2 text-sm bg-[#151a35] border border-gray-700 rounded text-white font-mono focus:ring-1 focus:ring-primary focus:border-transparent"
-                  placeholder={editingChain ? "留空不修改" : "0x..."}
+                  placeholder={editingChain ? "留空不修改" : "xprv..."}
                 />
-                <p className="text-xs text-gray-500 mt-1">
-                  {editingChain ? "留空则保持原私钥" : "私钥将存储在数据库"}
+                <p className="text-xs text-gray-500 mt-1">必须与 xpub 对应，加密存储，仅用于把充值地址中的资金归集到提现热钱包</p>
+              </div>
+
+              <div>
+                <label className="block text-xs font-medium text-gray-400 mb-1.5">
+                  提现签名方式
+                </label>
+                <select
+                  value={formData.signer_type || 'local'}
+                  onChange={(e) => setFormData({...formData, signer_type: e.target.value as ChainConfig['signer_type']})}
+                  className="w-full px-3 py-2 text-sm bg-[#151a35] border border-gray-700 rounded text-white focus:ring-1 focus:ring-primary focus:border-transparent"
+                >
+                  <option value="local">本地私钥（加密存储）</option>
+                  <option value="keystore">Keystore 文件</option>
+                  <option value="remote">远程签名服务</option>
+                </select>
+              </div>
+
+              {formData.signer_type === 'keystore' && (
+                <div className="grid grid-cols-2 gap-3">
+                  <div>
+                    <label className="block text-xs font-medium text-gray-400 mb-1.5">
+                      Keystore 文件路径 *
+                    </label>
+                    <input
+                      type="text"
+                      value={formData.signer_keystore_path || ''}
+                      onChange={(e) => setFormData({...formData, signer_keystore_path: e.target.value})}
+                      className="w-full px-3 py-2 text-sm bg-[#151a35] border border-gray-700 rounded text-white font-mono focus:ring-1 focus:ring-primary focus:border-transparent"
+                      placeholder="/etc/expchange/keystore/UTC--..."
+                    />
+                  </div>
+                  <div>
+                    <label className="block text-xs font-medium text-gray-400 mb-1.5">
+                      Keystore 密码 <span className="text-red-400">(敏感)</span>
+                    </label>
+                    <input
+                      type="password"
+                      value={formData.signer_keystore_password || ''}
+                      onChange={(e) => setFormData({...formData, signer_keystore_password: e.target.value})}
+                      className="w-full px-3 py-2 text-sm bg-[#151a35] border border-gray-700 rounded text-white font-mono focus:ring-1 focus:ring-primary focus:border-transparent"
+                      placeholder={editingChain ? "留空不修改" : ""}
+                    />
+                  </div>
+                </div>
+              )}
+
+              {formData.signer_type === 'remote' && (
+                <div className="space-y-3">
+                  <div className="grid grid-cols-3 gap-3">
+                    <div className="col-span-2">
+                      <label className="block text-xs font-medium text-gray-400 mb-1.5">
+                        签名服务地址 *
+                      </label>
+                      <input
+                        type="text"
+                        value={formData.signer_remote_url || ''}
+                        onChange={(e) => setFormData({...formData, signer_remote_url: e.target.value})}
+                        className="w-full px-3 py-2 text-sm bg-[#151a35] border border-gray-700 rounded text-white font-mono focus:ring-1 focus:ring-primary focus:border-transparent"
+                        placeholder="http://127.0.0.1:9000"
+                      />
+                    </div>
+                    <div>
+                      <label className="block text-xs font-medium text-gray-400 mb-1.5">
+                        RPC 方法
+                      </label>
+                      <input
+                        type="text"
+                        value={formData.signer_remote_method || ''}
+                        onChange={(e) => setFormData({...formData, signer_remote_method: e.target.value})}
+                        className="w-full px-3 py-2 text-sm bg-[#151a35] border border-gray-700 rounded text-white font-mono focus:ring-1 focus:ring-primary focus:border-transparent"
+                        placeholder="eth_signTransaction"
+                      />
+                    </div>
+                  </div>
+                  <div>
+                    <label className="block text-xs font-medium text-gray-400 mb-1.5">
+                      签名账户地址 *
+                    </label>
+                    <input
+                      type="text"
+                      value={formData.platform_withdraw_address || ''}
+                      onChange={(e) => setFormData({...formData, platform_withdraw_address: e.target.value})}
+                      className="w-full px-3 py-2 text-sm bg-[#151a35] border border-gray-700 rounded text-white font-mono focus:ring-1 focus:ring-primary focus:border-transparent"
+                      placeholder="0x..."
+                    />
+                  </div>
+                  <div>
+                    <label className="block text-xs font-medium text-gray-400 mb-1.5">
+                      访问令牌 <span className="text-red-400">(敏感)</span>
+                    </label>
+                    <input
+                      type="password"
+                      value={formData.signer_remote_auth_token || ''}
+                      onChange={(e) => setFormData({...formData, signer_remote_auth_token: e.target.value})}
+                      className="w-full px-3 py-2 text-sm bg-[#151a35] border border-gray-700 rounded text-white font-mono focus:ring-1 focus:ring-primary focus:border-transparent"
+                      placeholder={editingChain ? "留空不修改" : "可选"}
+                    />
+                  </div>
+                </div>
+              )}
+
+              {(formData.signer_type || 'local') === 'local' && (
+                <div>
+                  <label className="block text-xs font-medium text-gray-400 mb-1.5">
+                    提现私钥 <span className="text-red-400">(敏感)</span>
+                  </label>
+                  <input
+                    type="password"
+                    value={formData.platform_withdraw_private_key || ''}
+                    onChange={(e) => setFormData({...formData, platform_withdraw_private_key: e.target.value})}
+                    className="w-full px-3 py-2 text-sm bg-[#151a35] border border-gray-700 rounded text-white font-mono focus:ring-1 focus:ring-primary focus:border-transparent"
+                    placeholder={editingChain ? "留空不修改" : "0x..."}
+                  />
+                  <p className="text-xs text-gray-500 mt-1">
+                    {editingChain ? "留空则保持原私钥" : "私钥加密存储，保存后不可查看"}
+                  </p>
+                </div>
+              )}
+
+              {editingChain?.platform_withdraw_address && (
+                <p className="text-xs text-gray-400 font-mono">
+                  当前提现地址：{editingChain.platform_withdraw_address}
                 </p>
+              )}
+
+              <div>
+                <label className="block text-xs font-medium text-gray-400 mb-1.5">
+                  冷钱包地址
+                </label>
+                <input
+                  type="text"
+                  value={formData.cold_wallet_address || ''}
+                  onChange={(e) => setFormData({...formData, cold_wallet_address: e.target.value})}
+                  className="w-full px-3 py-2 text-sm bg-[#151a35] border border-gray-700 rounded text-white font-mono focus:ring-1 focus:ring-primary focus:border-transparent"
+                  placeholder="0x...（留空表示不监控冷钱包）"
+                />
+                <p className="text-xs text-gray-500 mt-1">只读取余额，计入储备覆盖率；热钱包告警值和暂停值在资产配置中设置</p>
               </div>
             </div>
 
@@ -2,7 +2,8 @@
 
 import { useMemo } from 'react';
 import useSWR from 'swr';
-import { adminApi, type DepositRecord } from '@/lib/api/admin';
+import toast from 'react-hot-toast';
+import { adminApi, type DepositRecord, type DepositSweep, type UnclaimedDeposit } from '@/lib/api/admin';
 import { getChains } from '@/lib/api/admin';
 
 export default function DepositsPage() {
@@ -14,6 +15,77 @@ export default function DepositsPage() {
     }
   );
 
+  const { data: unclaimed = [], mutate: mutateUnclaimed } = useSWR(
+    '/admin/deposits/unclaimed',
+    () => adminApi.getUnclaimedDeposits(),
+    {
+      refreshInterval: 30000,
+    }
+  );
+
+  const handleAssign = async (deposit: UnclaimedDeposit) => {
+    const userId = prompt(`将 ${deposit.amount} ${deposit.asset} 入账给用户（输入用户ID）`);
+    if (!userId) {
+      return;
+    }
+
+    try {
+      await adminApi.assignUnclaimedDeposit(deposit.id, userId.trim());
+      toast.success('已入账');
+      mutateUnclaimed();
+      mutate();
+    } catch (error: any) {
+      toast.error(error.response?.data?.error || '操作失败');
+    }
+  };
+
+  const handleIgnore = async (deposit: UnclaimedDeposit) => {
+    const note = prompt('忽略原因（例如平台内部转账）');
+    if (note === null) {
+      return;
+    }
+
+    try {
+      await adminApi.ignoreUnclaimedDeposit(deposit.id, note);
+      toast.success('已忽略');
+      mutateUnclaimed();
+    } catch (error: any) {
+      toast.error(error.response?.data?.error || '操作失败');
+    }
+  };
+
+  const { data: sweeps = [], mutate: mutateSweeps } = useSWR(
+    '/admin/deposits/sweeps',
+    () => adminApi.getDepositSweeps(),
+    {
+      refreshInterval: 30000,
+    }
+  );
+
+  const handleSweep = async () => {
+    if (!confirm('确定要把用户充值地址中的 USDT 归集到提现热钱包吗？\n余额不足以支付 gas 的地址会先由热钱包补充 gas')) {
+      return;
+    }
+
+    try {
+      const result = await adminApi.triggerDepositSweep();
+      toast.success(`归集任务已创建：${result.task_id}`);
+      mutateSweeps();
+    } catch (error: any) {
+      toast.error(error.response?.data?.error || '操作失败');
+    }
+  };
+
+  const getSweepStatusText = (status: string) => {
+    const text = {
+      gas_pending: '补充 gas 中',
+      broadcast: '已广播',
+      completed: '已完成',
+      failed: '失败',
+    };
+    return text[status as keyof typeof text] || status;
+  };
+
   const { data: chains = [] } = useSWR('/admin/chains', getChains);
 
   // 创建链ID到链配置的映射
@@ -26,6 +98,7 @@ export default function DepositsPage() {
   const getStatusBadge = (status: string) => {
     const styles = {
       pending: 'bg-yellow-500/20 text-yellow-500',
+      confirming: 'bg-blue-500/20 text-blue-400',
       confirmed: 'bg-green-500/20 text-green-500',
       failed: 'bg-red-500/20 text-red-500',
     };
@@ -35,6 +108,7 @@ export default function DepositsPage() {
   const getStatusText = (status: string) => {
     const text = {
       pending: '待确认',
+      confirming: '确认中',
       confirmed: '已确认',
       failed: '失败',
     };
@@ -45,14 +119,87 @@ export default function DepositsPage() {
     <div>
       <div className="flex items-center justify-between mb-6">
         <h1 className="text-3xl font-bold">充值记录</h1>
-        <button
-          onClick={() => mutate()}
-          className="px-4 py-2 bg-primary hover:bg-primary-dark rounded-lg transition text-sm"
-        >
-          刷新
-        </button>
+        <div className="flex gap-2">
+          <button
+            onClick={handleSweep}
+            className="px-4 py-2 bg-gray-700 hover:bg-gray-600 rounded-lg transition text-sm"
+          >
+            归集充值地址
+          </button>
+          <button
+            onClick={() => mutate()}
+            className="px-4 py-2 bg-primary hover:bg-primary-dark rounded-lg transition text-sm"
+          >
+            刷新
+          </button>
+        </div>
       </div>
 
+      {unclaimed.length > 0 && (
+        <div className="bg-[#0f1429] rounded-lg border border-orange-500/40 overflow-hidden mb-6">
+          <div className="px-4 py-3 border-b border-gray-800 text-sm">
+            <span className="font-semibold text-orange-400">待归属充值</span>
+            <span className="text-gray-400 ml-2">链上扫描到但转出地址不是注册用户钱包，用户提交交易哈希后自动入账，也可手动指定</span>
+          </div>
+          <div className="overflow-x-auto">
+            <table className="w-full">
+              <thead className="bg-[#151a35]">
+                <tr>
+                  <th className="text-left p-4">链</th>
+                  <th className="text-left p-4">转出地址</th>
+                  <th className="text-right p-4">金额</th>
+                  <th className="text-left p-4">交易哈希</th>
+                  <th className="text-left p-4">区块</th>
+                  <th className="text-left p-4">操作</th>
+                </tr>
+              </thead>
+              <tbody>
+                {unclaimed.map((deposit: UnclaimedDeposit) => {
+                  const explorerUrl = chainMap.get(deposit.chain_id)?.block_explorer_url || 'https://bscscan.com';
+
+                  return (
+                    <tr key={deposit.id} className="border-t border-gray-800 hover:bg-[#151a35]">
+                      <td className="p-4 text-sm">{deposit.chain}</td>
+                      <td className="p-4 text-xs font-mono">{deposit.from_address}</td>
+                      <td className="p-4 text-right font-mono">
+                        {parseFloat(deposit.amount).toFixed(8)} {deposit.asset}
+                      </td>
+                      <td className="p-4">
+                        <a
+                          href={`${explorerUrl}/tx/${deposit.tx_hash}`}
+                          target="_blank"
+                          rel="noopener noreferrer"
+                          className="text-primary hover:underline text-xs font-mono"
+                        >
+                          {deposit.tx_hash.substring(0, 10)}...{deposit.tx_hash.substring(60)}
+                        </a>
+                      </td>
+                      <td className="p-4 text-sm text-gray-400">{deposit.block_number}</td>
+                      <td className="p-4">
+                        <div className="flex gap-2">
+                          <button
+                            onClick={() => handleAssign(deposit)}
+                            className="px-3 py-1 bg-green-600 hover:bg-green-700 rounded text-xs transition"
+                          >
+                            指定用户
+                          </button>
+                          <button
+                            onClick={() => handleIgnore(deposit)}
+                            className="px-3 py-1 bg-gray-600 hover:bg-gray-700 rounded text-xs transition"
+                          >
+                            忽略
+                          </button>
+                        </div>
+                      </td>
+                    </tr>
+                  );
+                })}
+              </tbody>
+            </table>
+          </div>
+        </div>
+      )}
+
       {isLoading ? (
         <div className="text-center py-12 text-gray-400">加载中...</div>
       ) : (
@@ -67,7 +214,7 @@ export default function DepositsPage() {
                   <th className="text-right p-4">金额</th>
                   <th className="text-left p-4">链</th>
                   <th className="text-left p-4">交易哈希</th>
-                  <th className="text-left p-4">任务ID</th>
+                  <th className="text-left p-4">来源/任务</th>
                   <th className="text-left p-4">状态</th>
                   <th className="text-left p-4">时间</th>
                 </tr>
@@ -110,7 +257,11 @@ export default function DepositsPage() {
                           </a>
                         </td>
                         <td className="p-4">
-                          {deposit.task_id ? (
+                          {deposit.source === 'scanner' ? (
+                            <span className="text-cyan-400 text-xs">链上扫描</span>
+                          ) : deposit.source === 'admin' ? (
+                            <span className="text-orange-400 text-xs">管理员指定</span>
+                          ) : deposit.task_id ? (
                             <a
                               href={`/dashboard/tasks`}
                               className="text-blue-400 hover:underline text-xs font-mono"
@@ -125,6 +276,7 @@ export default function DepositsPage() {
                         <td className="p-4">
                           <span className={`px-2 py-1 rounded text-xs ${getStatusBadge(deposit.status)}`}>
                             {getStatusText(deposit.status)}
+                            {deposit.status === 'confirming' && ` ${deposit.confirmations}/${deposit.required_confirmations}`}
                           </span>
                         </td>
                         <td className="p-4 text-sm text-gray-400">
@@ -139,6 +291,66 @@ export default function DepositsPage() {
           </div>
         </div>
       )}
+
+      {sweeps.length > 0 && (
+        <div className="bg-[#0f1429] rounded-lg border border-gray-800 overflow-hidden mt-6">
+          <div className="px-4 py-3 border-b border-gray-800 text-sm">
+            <span className="font-semibold">充值地址归集</span>
+            <span className="text-gray-400 ml-2">用户专属充值地址 → 提现热钱包</span>
+          </div>
+          <div className="overflow-x-auto">
+            <table className="w-full">
+              <thead className="bg-[#151a35]">
+                <tr>
+                  <th className="text-left p-4">链</th>
+                  <th className="text-left p-4">充值地址</th>
+                  <th className="text-right p-4">金额</th>
+                  <th className="text-right p-4">补充 gas</th>
+                  <th className="text-left p-4">交易哈希</th>
+                  <th className="text-left p-4">状态</th>
+                  <th className="text-left p-4">时间</th>
+                </tr>
+              </thead>
+              <tbody>
+                {sweeps.map((sweep: DepositSweep) => {
+                  const explorerUrl = chainMap.get(sweep.chain_id)?.block_explorer_url || 'https://bscscan.com';
+
+                  return (
+                    <tr key={sweep.id} className="border-t border-gray-800 hover:bg-[#151a35]">
+                      <td className="p-4 text-sm">{sweep.chain}</td>
+                      <td className="p-4 text-xs font-mono">{sweep.deposit_address}</td>
+                      <td className="p-4 text-right font-mono">
+                        {parseFloat(sweep.amount).toFixed(8)} {sweep.asset}
+                      </td>
+                      <td className="p-4 text-right font-mono text-xs text-gray-400">{sweep.gas_amount}</td>
+                      <td className="p-4">
+                        {sweep.tx_hash ? (
+                          <a
+                            href={`${explorerUrl}/tx/${sweep.tx_hash}`}
+                            target="_blank"
+                            rel="noopener noreferrer"
+                            className="text-primary hover:underline text-xs font-mono"
+                          >
+                            {sweep.tx_hash.substring(0, 10)}...{sweep.tx_hash.substring(60)}
+                          </a>
+                        ) : (
+                          <span className="text-gray-500 text-xs">-</span>
+                        )}
+                      </td>
+                      <td className="p-4 text-sm" title={sweep.reason}>
+                        {getSweepStatusText(sweep.status)}
+                      </td>
+                      <td className="p-4 text-sm text-gray-400">
+                        {new Date(sweep.created_at).toLocaleString('zh-CN')}
+                      </td>
+                    </tr>
+                  );
+                })}
+              </tbody>
+            </table>
+          </div>
+        </div>
+      )}
     </div>
   );
 }
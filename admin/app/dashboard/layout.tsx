@@ -3,6 +3,7 @@
 import Link from 'next/link';
 import { usePathname, useRouter } from 'next/navigation';
 import { useEffect } from 'react';
+import { adminApi } from '@/lib/api/admin';
 
 export default function DashboardLayout({
   children,
@@ -19,7 +20,9 @@ export default function DashboardLayout({
     }
   }, [router]);
 
-  const handleLogout = () => {
+  const handleLogout = async () => {
+    // 通知后端使令牌失效（失败不影响本地登出）
+    await adminApi.adminLogout().catch(() => {});
     localStorage.removeItem('admin_token');
     router.push('/login');
   };
@@ -33,6 +36,7 @@ export default function DashboardLayout({
     { href: '/dashboard/market-maker', label: '做市商盈亏', icon: '🤖' },
     { href: '/dashboard/deposits', label: '充值记录', icon: '💰' },
     { href: '/dashboard/withdrawals', label: '提现记录', icon: '💸' },
+    { href: '/dashboard/wallet-monitor', label: '钱包监控', icon: '🏦' },
     { href: '/dashboard/tasks', label: '队列任务', icon: '📝' },
     { href: '/dashboard/chains', label: '链配置', icon: '🔗' },
     { href: '/dashboard/settings', label: '系统设置', icon: '⚙️' },
@@ -227,6 +227,11 @@ export default function TasksPage() {
                       <td className="p-4">
                         <div className="max-w-xs">
                           <div className="text-sm">{task.Message}</div>
+                          {task.Status === 'pending' && task.RunAt && (
+                            <div className="text-xs text-gray-400 mt-1">
+                              下次执行: {new Date(task.RunAt).toLocaleString('zh-CN')}
+                            </div>
+                          )}
                           {task.Error && (
                             <div className="text-xs text-red-400 mt-1 truncate" title={task.Error}>
                               错误: {task.Error}
@@ -0,0 +1,191 @@
+'use client';
+
+import { useState } from 'react';
+import useSWR from 'swr';
+import toast from 'react-hot-toast';
+import { adminApi, type AssetCoverage, type WalletBalanceSnapshot } from '@/lib/api/admin';
+
+export default function WalletMonitorPage() {
+  const { data, isLoading, mutate } = useSWR(
+    '/admin/wallet-monitor',
+    () => adminApi.getWalletMonitor(),
+    {
+      refreshInterval: 30000, // 每30秒自动刷新
+    }
+  );
+  const snapshots = data?.snapshots ?? [];
+  const coverage = data?.coverage ?? [];
+
+  // 点击某一行查看该链该资产的历史
+  const [selected, setSelected] = useState<{ chain_id: number; asset: string } | null>(null);
+  const { data: history = [] } = useSWR(
+    selected ? ['/admin/wallet-monitor/history', selected.chain_id, selected.asset] : null,
+    () => adminApi.getWalletMonitorHistory(selected!)
+  );
+
+  const handleRun = async () => {
+    try {
+      const result = await adminApi.triggerWalletMonitor();
+      toast.success(`余额检查任务已创建：${result.task_id}`);
+      setTimeout(() => mutate(), 3000);
+    } catch (error: any) {
+      toast.error(error.response?.data?.error || '操作失败');
+    }
+  };
+
+  const getStatusText = (status: string) => {
+    const text = {
+      ok: '正常',
+      low: '余额不足',
+      paused: '提现已暂停',
+      error: '读取失败',
+    };
+    return text[status as keyof typeof text] || status;
+  };
+
+  const getStatusColor = (status: string) => {
+    const colors = {
+      ok: 'text-green-400',
+      low: 'text-yellow-400',
+      paused: 'text-red-400',
+      error: 'text-gray-400',
+    };
+    return colors[status as keyof typeof colors] || 'text-gray-400';
+  };
+
+  return (
+    <div>
+      <div className="flex items-center justify-between mb-6">
+        <h1 className="text-3xl font-bold">钱包监控</h1>
+        <div className="flex gap-2">
+          <button
+            onClick={handleRun}
+            className="px-4 py-2 bg-gray-700 hover:bg-gray-600 rounded-lg transition text-sm"
+          >
+            立即检查
+          </button>
+          <button
+            onClick={() => mutate()}
+            className="px-4 py-2 bg-primary hover:bg-primary-dark rounded-lg transition text-sm"
+          >
+            刷新
+          </button>
+        </div>
+      </div>
+
+      {coverage.length > 0 && (
+        <div className="grid grid-cols-1 md:grid-cols-3 gap-4 mb-6">
+          {coverage.map((c: AssetCoverage) => (
+            <div key={c.asset} className="bg-[#0f1429] rounded-lg border border-gray-800 p-4">
+              <div className="text-sm text-gray-400">{c.asset} 储备覆盖率</div>
+              <div className="text-2xl font-bold font-mono mt-1">
+                {parseFloat(c.liabilities) > 0 ? `${(parseFloat(c.ratio) * 100).toFixed(2)}%` : '-'}
+                {!c.complete && <span className="text-xs text-gray-500 ml-2">部分链读取失败</span>}
+              </div>
+              <div className="text-xs text-gray-400 mt-2 font-mono">
+                热 {c.hot_balance} + 冷 {c.cold_balance} / 负债 {c.liabilities}
+              </div>
+            </div>
+          ))}
+        </div>
+      )}
+
+      <div className="bg-[#0f1429] rounded-lg border border-gray-800 overflow-hidden">
+        <div className="overflow-x-auto">
+          <table className="w-full">
+            <thead className="bg-[#151a35]">
+              <tr>
+                <th className="text-left p-4">链</th>
+                <th className="text-left p-4">资产</th>
+                <th className="text-right p-4">热钱包</th>
+                <th className="text-right p-4">冷钱包</th>
+                <th className="text-right p-4">待发出提现</th>
+                <th className="text-right p-4">用户负债</th>
+                <th className="text-left p-4">状态</th>
+                <th className="text-left p-4">检查时间</th>
+              </tr>
+            </thead>
+            <tbody>
+              {isLoading ? (
+                <tr>
+                  <td colSpan={8} className="p-8 text-center text-gray-400">
+                    加载中...
+                  </td>
+                </tr>
+              ) : snapshots.length === 0 ? (
+                <tr>
+                  <td colSpan={8} className="p-8 text-center text-gray-400">
+                    暂无数据，点击“立即检查”读取链上余额
+                  </td>
+                </tr>
+              ) : (
+                snapshots.map((s: WalletBalanceSnapshot) => (
+                  <tr
+                    key={s.id}
+                    onClick={() => setSelected({ chain_id: s.chain_id, asset: s.asset })}
+                    className="border-t border-gray-800 hover:bg-[#151a35] cursor-pointer"
+                  >
+                    <td className="p-4 text-sm">{s.chain}</td>
+                    <td className="p-4 text-sm">{s.asset}</td>
+                    <td className="p-4 text-right font-mono" title={s.hot_address}>{s.hot_balance}</td>
+                    <td className="p-4 text-right font-mono" title={s.cold_address}>
+                      {s.cold_address ? s.cold_balance : '-'}
+                    </td>
+                    <td className="p-4 text-right font-mono">{s.pending_withdrawals}</td>
+                    <td className="p-4 text-right font-mono text-gray-400">{s.liabilities}</td>
+                    <td className={`p-4 text-sm ${getStatusColor(s.status)}`} title={s.message}>
+                      {getStatusText(s.status)}
+                      {s.message && <div className="text-xs text-gray-500">{s.message}</div>}
+                    </td>
+                    <td className="p-4 text-sm text-gray-400">
+                      {new Date(s.created_at).toLocaleString('zh-CN')}
+                    </td>
+                  </tr>
+                ))
+              )}
+            </tbody>
+          </table>
+        </div>
+      </div>
+
+      {selected && (
+        <div className="bg-[#0f1429] rounded-lg border border-gray-800 overflow-hidden mt-6">
+          <div className="px-4 py-3 border-b border-gray-800 text-sm flex justify-between">
+            <span className="font-semibold">
+              {snapshots.find((s) => s.chain_id === selected.chain_id)?.chain} {selected.asset} 余额历史
+            </span>
+            <button onClick={() => setSelected(null)} className="text-gray-400 hover:text-white">
+              关闭
+            </button>
+          </div>
+          <div className="overflow-x-auto max-h-96">
+            <table className="w-full">
+              <thead className="bg-[#151a35]">
+                <tr>
+                  <th className="text-left p-3 text-sm">时间</th>
+                  <th className="text-right p-3 text-sm">热钱包</th>
+                  <th className="text-right p-3 text-sm">冷钱包</th>
+                  <th className="text-right p-3 text-sm">待发出提现</th>
+                  <th className="text-left p-3 text-sm">状态</th>
+                </tr>
+              </thead>
+              <tbody>
+                {history.map((s: WalletBalanceSnapshot) => (
+                  <tr key={s.id} className="border-t border-gray-800">
+                    <td className="p-3 text-sm text-gray-400">{new Date(s.created_at).toLocaleString('zh-CN')}</td>
+                    <td className="p-3 text-right font-mono text-sm">{s.hot_balance}</td>
+                    <td className="p-3 text-right font-mono text-sm">{s.cold_address ? s.cold_balance : '-'}</td>
+                    <td className="p-3 text-right font-mono text-sm">{s.pending_withdrawals}</td>
+                    <td className={`p-3 text-sm ${getStatusColor(s.status)}`} title={s.message}>
+                      {getStatusText(s.status)}
+                    </td>
+                  </tr>
+                ))}
+              </tbody>
+            </table>
+          </div>
+        </div>
+      )}
+    </div>
+  );
+}
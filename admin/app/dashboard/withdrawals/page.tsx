@@ -1,19 +1,63 @@
 'use client';
 
-import { useMemo } from 'react';
+import { useMemo, useState } from 'react';
 import useSWR from 'swr';
+import toast from 'react-hot-toast';
 import { adminApi, type WithdrawRecord } from '@/lib/api/admin';
 import { getChains } from '@/lib/api/admin';
 
+const riskFlagText: Record<string, string> = {
+  large_amount: '大额',
+  daily_limit: '超日限额',
+  new_account: '新账户',
+  new_address: '新地址',
+  deposit_velocity: '充值即提',
+};
+
 export default function WithdrawalsPage() {
+  const [statusFilter, setStatusFilter] = useState('');
   const { data: withdrawals = [], isLoading, error, mutate } = useSWR(
-    '/admin/withdrawals',
-    () => adminApi.getWithdrawals(),
+    ['/admin/withdrawals', statusFilter],
+    () => adminApi.getWithdrawals(statusFilter || undefined),
     {
       refreshInterval: 10000, // 每10秒自动刷新
     }
   );
 
+  const handleReview = async (withdrawal: WithdrawRecord, approve: boolean) => {
+    const note = prompt(approve ? '审核通过备注（可选）' : '拒绝原因（资金将解冻退回用户）');
+    if (note === null) {
+      return;
+    }
+
+    try {
+      if (approve) {
+        await adminApi.approveWithdrawal(withdrawal.id, note);
+        toast.success('已审核通过，提现进入处理队列');
+      } else {
+        await adminApi.rejectWithdrawal(withdrawal.id, note);
+        toast.success('已拒绝，资金已解冻');
+      }
+      mutate();
+    } catch (error: any) {
+      toast.error(error.response?.data?.error || '操作失败');
+    }
+  };
+
+  const handleSpeedUp = async (withdrawal: WithdrawRecord) => {
+    if (!confirm('确定使用相同 nonce 提高 gas price 重新广播该提现交易吗？')) {
+      return;
+    }
+
+    try {
+      await adminApi.speedUpWithdrawal(withdrawal.id);
+      toast.success('已加速，替换交易已广播');
+      mutate();
+    } catch (error: any) {
+      toast.error(error.response?.data?.error || '加速失败');
+    }
+  };
+
   const { data: chains = [] } = useSWR('/admin/chains', getChains);
 
   // 创建链ID到链配置的映射
@@ -25,20 +69,30 @@ export default function WithdrawalsPage() {
 
   const getStatusBadge = (status: string) => {
     const styles = {
+      pending_review: 'bg-orange-500/20 text-orange-500',
       pending: 'bg-yellow-500/20 text-yellow-500',
+      batching: 'bg-indigo-500/20 text-indigo-400',
       processing: 'bg-blue-500/20 text-blue-500',
+      broadcast: 'bg-cyan-500/20 text-cyan-500',
+      review: 'bg-purple-500/20 text-purple-400',
       completed: 'bg-green-500/20 text-green-500',
       failed: 'bg-red-500/20 text-red-500',
+      rejected: 'bg-red-500/20 text-red-400',
     };
     return styles[status as keyof typeof styles] || 'bg-gray-700 text-gray-400';
   };
 
   const getStatusText = (status: string) => {
     const text = {
+      pending_review: '待审核',
       pending: '待处理',
+      batching: '等待批量',
       processing: '处理中',
+      broadcast: '确认中',
+      review: '待人工核对',
       completed: '已完成',
       failed: '失败',
+      rejected: '已拒绝',
     };
     return text[status as keyof typeof text] || status;
   };
@@ -48,6 +102,22 @@ export default function WithdrawalsPage() {
       <div className="flex items-center justify-between mb-6">
         <h1 className="text-3xl font-bold">提现记录</h1>
         <div className="flex gap-3 items-center">
+          <select
+            value={statusFilter}
+            onChange={(e) => setStatusFilter(e.target.value)}
+            className="px-3 py-2 bg-[#151a35] border border-gray-700 rounded-lg text-sm"
+          >
+            <option value="">全部状态</option>
+            <option value="pending_review">待审核</option>
+            <option value="pending">待处理</option>
+            <option value="batching">等待批量</option>
+            <option value="processing">处理中</option>
+            <option value="broadcast">确认中</option>
+            <option value="review">待人工核对</option>
+            <option value="completed">已完成</option>
+            <option value="failed">失败</option>
+            <option value="rejected">已拒绝</option>
+          </select>
           {error && (
             <span className="text-red-500 text-sm">
               加载失败: {error.message || '未知错误'}
@@ -81,14 +151,16 @@ export default function WithdrawalsPage() {
                   <th className="text-left p-4">提现地址</th>
                   <th className="text-left p-4">交易哈希</th>
                   <th className="text-left p-4">任务ID</th>
+                  <th className="text-left p-4">风控</th>
                   <th className="text-left p-4">状态</th>
                   <th className="text-left p-4">时间</th>
+                  <th className="text-left p-4">操作</th>
                 </tr>
               </thead>
               <tbody>
                 {withdrawals.length === 0 ? (
                   <tr>
-                    <td colSpan={10} className="text-center p-8 text-gray-400">
+                    <td colSpan={12} className="text-center p-8 text-gray-400">
                       暂无提现记录
                     </td>
                   </tr>
@@ -108,7 +180,12 @@ export default function WithdrawalsPage() {
                             : '-'}
                         </td>
                         <td className="p-4 font-semibold">{withdrawal.asset}</td>
-                        <td className="p-4 text-right font-mono">{parseFloat(withdrawal.amount).toFixed(8)}</td>
+                        <td className="p-4 text-right font-mono">
+                          <div>{parseFloat(withdrawal.amount).toFixed(8)}</div>
+                          {parseFloat(withdrawal.fee || '0') > 0 && (
+                            <div className="text-xs text-gray-400">手续费 {parseFloat(withdrawal.fee).toFixed(8)}</div>
+                          )}
+                        </td>
                         <td className="p-4 text-sm">
                           {chain ? chain.chain_name : `ID: ${withdrawal.chain_id}`}
                         </td>
@@ -142,14 +219,59 @@ export default function WithdrawalsPage() {
                             <span className="text-gray-500 text-xs">-</span>
                           )}
                         </td>
+                        <td className="p-4 text-xs">
+                          <div className="font-mono">{withdrawal.risk_score ?? 0}</div>
+                          <div className="text-gray-400">
+                            {withdrawal.risk_flags
+                              ? withdrawal.risk_flags.split(',').map((flag) => riskFlagText[flag] || flag).join('、')
+                              : '-'}
+                          </div>
+                        </td>
                         <td className="p-4">
-                          <span className={`px-2 py-1 rounded text-xs ${getStatusBadge(withdrawal.status)}`}>
+                          <span
+                            className={`px-2 py-1 rounded text-xs ${getStatusBadge(withdrawal.status)}`}
+                            title={withdrawal.fail_reason || withdrawal.review_note || undefined}
+                          >
                             {getStatusText(withdrawal.status)}
                           </span>
+                          {withdrawal.status === 'broadcast' && (
+                            <div className="text-xs text-gray-400 mt-1">
+                              确认数 {withdrawal.confirmations}/{chainMap.get(withdrawal.chain_id)?.withdraw_confirmations ?? '-'}
+                              {withdrawal.bump_count > 0 && ` · 已加速 ${withdrawal.bump_count} 次`}
+                              {withdrawal.batch_id && ' · 批量'}
+                            </div>
+                          )}
                         </td>
                         <td className="p-4 text-sm text-gray-400">
                           {new Date(withdrawal.created_at).toLocaleString('zh-CN')}
                         </td>
+                        <td className="p-4">
+                          {withdrawal.status === 'pending_review' ? (
+                            <div className="flex gap-2">
+                              <button
+                                onClick={() => handleReview(withdrawal, true)}
+                                className="px-3 py-1 bg-green-600 hover:bg-green-700 rounded text-xs transition"
+                              >
+                                通过
+                              </button>
+                              <button
+                                onClick={() => handleReview(withdrawal, false)}
+                                className="px-3 py-1 bg-red-600 hover:bg-red-700 rounded text-xs transition"
+                              >
+                                拒绝
+                              </button>
+                            </div>
+                          ) : withdrawal.status === 'broadcast' ? (
+                            <button
+                              onClick={() => handleSpeedUp(withdrawal)}
+                              className="px-3 py-1 bg-cyan-600 hover:bg-cyan-700 rounded text-xs transition"
+                            >
+                              加速
+                            </button>
+                          ) : (
+                            <span className="text-gray-500 text-xs">-</span>
+                          )}
+                        </td>
                       </tr>
                     );
                   })
@@ -1,13 +1,18 @@
 'use client';
 
 import { useState } from 'react';
-import { useRouter } from 'next/navigation';
 import toast from 'react-hot-toast';
+import { adminApi } from '@/lib/api/admin';
+
+type Step = 'password' | 'totp' | 'setup';
 
 export default function LoginPage() {
-  const router = useRouter();
   const [username, setUsername] = useState('');
   const [password, setPassword] = useState('');
+  const [totpCode, setTotpCode] = useState('');
+  const [step, setStep] = useState<Step>('password');
+  const [totpSecret, setTotpSecret] = useState('');
+  const [otpauthUrl, setOtpauthUrl] = useState('');
   const [loading, setLoading] = useState(false);
 
   const handleLogin = async (e: React.FormEvent) => {
@@ -15,66 +20,126 @@ export default function LoginPage() {
     setLoading(true);
 
     try {
-      // 简化的登录逻辑
-      if (username === 'admin' && password === 'admin') {
-        // 设置token
-        localStorage.setItem('admin_token', 'admin-token-placeholder');
-        console.log('✅ 登录成功，token已设置:', localStorage.getItem('admin_token'));
-        
-        // 使用window.location.href强制跳转
-        window.location.href = '/dashboard/pairs';
-      } else {
-        toast.error('用户名或密码错误\n默认账号: admin / admin');
+      const result = await adminApi.adminLogin(username, password, totpCode);
+      localStorage.setItem('admin_token', result.token);
+
+      // 首次登录需要先绑定 TOTP
+      if (result.totp_setup_required) {
+        const setup = await adminApi.setupTOTP();
+        setTotpSecret(setup.secret);
+        setOtpauthUrl(setup.otpauth_url);
+        setTotpCode('');
+        setStep('setup');
+        toast('请使用身份验证器绑定 TOTP');
         setLoading(false);
+        return;
+      }
+
+      window.location.href = '/dashboard/pairs';
+    } catch (error: any) {
+      if (error.response?.data?.totp_required) {
+        setStep('totp');
+      } else {
+        toast.error(error.response?.data?.error || '登录失败，请重试');
       }
-    } catch (error) {
-      console.error('登录错误:', error);
-      toast.error('登录失败，请重试');
       setLoading(false);
     }
   };
 
+  const handleEnableTOTP = async (e: React.FormEvent) => {
+    e.preventDefault();
+    setLoading(true);
+
+    try {
+      await adminApi.enableTOTP(totpCode);
+      localStorage.removeItem('admin_token');
+      toast.success('TOTP 绑定成功，请使用新的验证码重新登录');
+      setTotpCode('');
+      setStep('totp');
+    } catch (error: any) {
+      toast.error(error.response?.data?.error || '验证码错误');
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  const inputClass = 'w-full px-4 py-2 bg-[#151a35] border border-gray-700 rounded-lg';
+  const buttonClass =
+    'w-full py-3 bg-primary hover:bg-primary-dark rounded-lg font-semibold transition disabled:opacity-50';
+
   return (
     <div className="min-h-screen flex items-center justify-center">
       <div className="bg-[#0f1429] rounded-lg border border-gray-800 p-8 w-96">
         <h1 className="text-2xl font-bold text-center mb-8">管理后台登录</h1>
-        <form onSubmit={handleLogin} className="space-y-4">
-          <div>
-            <label className="block text-sm text-gray-400 mb-2">用户名</label>
+
+        {step === 'setup' ? (
+          <form onSubmit={handleEnableTOTP} className="space-y-4">
+            <p className="text-sm text-gray-400">
+              在身份验证器（Google Authenticator 等）中添加以下密钥，然后输入生成的 6 位验证码完成绑定。
+            </p>
+            <div className="p-3 bg-[#151a35] rounded-lg break-all font-mono text-sm">{totpSecret}</div>
+            <a href={otpauthUrl} className="block text-xs text-primary break-all">
+              {otpauthUrl}
+            </a>
             <input
               type="text"
-              value={username}
-              onChange={(e) => setUsername(e.target.value)}
-              className="w-full px-4 py-2 bg-[#151a35] border border-gray-700 rounded-lg"
-              placeholder="请输入用户名"
+              inputMode="numeric"
+              value={totpCode}
+              onChange={(e) => setTotpCode(e.target.value)}
+              className={inputClass}
+              placeholder="6 位验证码"
+              maxLength={6}
               required
             />
-          </div>
-          <div>
-            <label className="block text-sm text-gray-400 mb-2">密码</label>
-            <input
-              type="password"
-              value={password}
-              onChange={(e) => setPassword(e.target.value)}
-              className="w-full px-4 py-2 bg-[#151a35] border border-gray-700 rounded-lg"
-              placeholder="请输入密码"
-              required
-            />
-          </div>
-          <button
-            type="submit"
-            disabled={loading}
-            className="w-full py-3 bg-primary hover:bg-primary-dark rounded-lg font-semibold transition disabled:opacity-50"
-          >
-            {loading ? '登录中...' : '登录'}
-          </button>
-        </form>
-        <p className="text-xs text-gray-400 text-center mt-4">
-          默认账号: admin / admin
-        </p>
+            <button type="submit" disabled={loading} className={buttonClass}>
+              {loading ? '验证中...' : '完成绑定'}
+            </button>
+          </form>
+        ) : (
+          <form onSubmit={handleLogin} className="space-y-4">
+            <div>
+              <label className="block text-sm text-gray-400 mb-2">用户名</label>
+              <input
+                type="text"
+                value={username}
+                onChange={(e) => setUsername(e.target.value)}
+                className={inputClass}
+                placeholder="请输入用户名"
+                required
+              />
+            </div>
+            <div>
+              <label className="block text-sm text-gray-400 mb-2">密码</label>
+              <input
+                type="password"
+                value={password}
+                onChange={(e) => setPassword(e.target.value)}
+                className={inputClass}
+                placeholder="请输入密码"
+                required
+              />
+            </div>
+            {step === 'totp' && (
+              <div>
+                <label className="block text-sm text-gray-400 mb-2">TOTP 验证码</label>
+                <input
+                  type="text"
+                  inputMode="numeric"
+                  value={totpCode}
+                  onChange={(e) => setTotpCode(e.target.value)}
+                  className={inputClass}
+                  placeholder="6 位验证码"
+                  maxLength={6}
+                  required
+                />
+              </div>
+            )}
+            <button type="submit" disabled={loading} className={buttonClass}>
+              {loading ? '登录中...' : '登录'}
+            </button>
+          </form>
+        )}
       </div>
     </div>
   );
 }
-
-
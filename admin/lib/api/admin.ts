@@ -77,13 +77,93 @@ export interface ChainConfig {
   block_explorer_url: string;
   usdt_contract_address: string;
   usdt_decimals: number;
+  withdraw_confirmations: number; // 提现完成所需确认数
+  required_confirmations: number; // 充值入账所需确认数
+  tx_type?: 'legacy' | 'eip1559'; // 提现交易类型
+  max_gas_price_gwei?: string; // gas price / max fee 上限（gwei），0 表示不限
+  max_priority_fee_gwei?: string; // EIP-1559 小费上限（gwei），0 表示不限
+  max_gas_limit?: number; // 估算 gas limit 上限，0 表示不限
+  multisend_contract_address?: string; // 批量提现（Disperse）合约地址，为空表示不使用批量提现
   platform_deposit_address: string;
-  platform_withdraw_private_key?: string;
+  deposit_xpub?: string; // 用户充值地址扩展公钥（m/44'/60'/0'），为空时所有用户使用共享收款地址
+  deposit_sweep_key?: string; // 只写：与 xpub 对应的扩展私钥，用于归集
+  platform_withdraw_private_key?: string; // 只写：接口不会返回
+  platform_withdraw_address?: string;
+  cold_wallet_address?: string; // 冷钱包地址（只读取余额，用于储备覆盖率），为空表示不监控
+  signer_type?: 'local' | 'keystore' | 'remote';
+  signer_keystore_path?: string;
+  signer_keystore_password?: string; // 只写
+  signer_remote_url?: string;
+  signer_remote_method?: string;
+  signer_remote_auth_token?: string; // 只写
   enabled: boolean;
+  withdraw_fees?: WithdrawFee[]; // 只读：通过 upsertWithdrawFee 修改
+  tokens?: ChainToken[]; // 只读：通过 upsertChainToken 修改
+  rpc_endpoints?: ChainRpcEndpoint[]; // 只读：通过 upsertRpcEndpoint 修改
   created_at: string;
   updated_at: string;
 }
 
+// 提现手续费与最小提现额（按链、资产）
+export interface WithdrawFee {
+  id: string;
+  chain_id: number;
+  asset: string;
+  fee: string;
+  min_amount: string;
+}
+
+// 链上资产（合约为空表示原生币）
+export interface ChainToken {
+  id: string;
+  chain_id: number;
+  asset: string;
+  contract_address: string;
+  decimals: number;
+  deposit_enabled: boolean;
+  withdraw_enabled: boolean;
+  min_deposit_amount: string;
+  hot_alert_balance: string; // 热钱包余额低于该值时告警，0 表示不告警
+  hot_pause_balance: string; // 热钱包余额低于该值时自动暂停提现，0 表示不暂停
+  risk_large_amount: string; // 风控单笔大额阈值（资产数量），0 表示 USDT 使用系统配置、其他资产不检查
+  risk_daily_limit: string; // 风控 24 小时累计限额（资产数量），0 同上
+  withdraw_paused: boolean; // 只读：余额监控自动暂停
+  pause_reason?: string;
+}
+
+// 链的 RPC 节点（与 rpc_url 组成节点池，优先级数值越小越优先，同级按权重分配）
+export interface ChainRpcEndpoint {
+  id: string;
+  chain_id: number;
+  url: string;
+  priority: number;
+  weight: number;
+  enabled: boolean;
+}
+
+// RPC 节点健康状态
+export interface RpcEndpointStatus {
+  url: string;
+  priority: number;
+  weight: number;
+  state: 'healthy' | 'lagging' | 'open' | 'half_open';
+  head: number;
+  lag: number;
+  latency_ms: number;
+  consecutive_failures: number;
+  requests: number;
+  failures: number;
+  last_error?: string;
+  last_checked_at?: string;
+  open_until?: string;
+}
+
+export interface ChainRpcHealth {
+  chain_id: number;
+  chain_name: string;
+  endpoints: RpcEndpointStatus[];
+}
+
 // 任务接口
 export interface Task {
   ID: string;
@@ -99,6 +179,8 @@ export interface Task {
   StartedAt?: string;
   EndedAt?: string;
   Error?: string;
+  Attempts: number; // 已重试次数
+  RunAt?: string; // 等待重试时的下次执行时间
 }
 
 // 任务日志接口
@@ -122,25 +204,123 @@ export interface DepositRecord {
   tx_hash: string;
   chain: string;
   chain_id: number;
-  status: string; // pending, confirmed, failed
+  status: string; // pending, confirming（已打包等待确认）, confirmed, failed
   task_id?: string;
+  source: 'manual' | 'scanner' | 'admin'; // 用户提交hash / 链上扫描 / 管理员指定
+  from_address?: string;
+  block_number: number;
+  block_hash?: string;
+  confirmations: number;
+  required_confirmations: number;
+  created_at: string;
+  updated_at: string;
+}
+
+// 扫描到但无法归属用户的充值
+export interface UnclaimedDeposit {
+  id: string;
+  chain_id: number;
+  chain: string;
+  asset: string;
+  amount: string;
+  tx_hash: string;
+  from_address: string;
+  to_address: string;
+  block_number: number;
+  status: string; // unclaimed, credited, ignored
+  deposit_id?: string;
+  handled_by?: string;
+  handled_at?: string;
+  note?: string;
+  created_at: string;
+  updated_at: string;
+}
+
+// 充值地址归集记录
+export interface DepositSweep {
+  id: string;
+  task_id: string;
+  chain_id: number;
+  chain: string;
+  asset: string;
+  user_id: string;
+  deposit_address: string;
+  to_address: string;
+  amount: string;
+  gas_tx_hash?: string;
+  gas_amount: string;
+  tx_hash?: string;
+  block_number: number;
+  status: string; // gas_pending, broadcast, completed, failed
+  reason?: string;
   created_at: string;
   updated_at: string;
 }
 
+// 钱包余额快照（余额监控每轮按链、资产记录一条）
+export interface WalletBalanceSnapshot {
+  id: string;
+  chain_id: number;
+  chain: string;
+  asset: string;
+  hot_address: string;
+  hot_balance: string;
+  cold_address?: string;
+  cold_balance: string;
+  pending_withdrawals: string; // 该链该资产尚未确认的提现（到账金额）
+  liabilities: string; // 该资产全部用户余额（所有链合计）
+  status: 'ok' | 'low' | 'paused' | 'error';
+  message?: string;
+  created_at: string;
+}
+
+// 资产储备覆盖率：各链热钱包和冷钱包余额合计 / 用户负债
+export interface AssetCoverage {
+  asset: string;
+  hot_balance: string;
+  cold_balance: string;
+  liabilities: string;
+  ratio: string;
+  complete: boolean; // 有链余额读取失败时为 false
+}
+
+export interface WalletMonitorResult {
+  snapshots: WalletBalanceSnapshot[];
+  coverage: AssetCoverage[];
+}
+
 // 提现记录接口
 export interface WithdrawRecord {
   id: string;
   user_id: string;
   user?: User;
   asset: string;
-  amount: string;
+  amount: string; // 申请金额（含手续费）
+  fee: string; // 提现手续费，到账金额 = amount - fee
   address: string;
   tx_hash?: string;
   chain: string;
   chain_id: number;
-  status: string; // pending, processing, completed, failed
+  status: string; // pending_review, pending, batching, processing, broadcast, completed, failed, rejected
   task_id?: string;
+  batch_id?: string; // 所属批量交易
+  from_address?: string;
+  nonce: number;
+  tx_type?: 'legacy' | 'eip1559';
+  gas_limit: number;
+  gas_price?: string; // wei，EIP-1559 为 max fee per gas
+  gas_tip_cap?: string; // wei，EIP-1559 max priority fee per gas
+  broadcast_at?: string;
+  bump_count: number;
+  replaced_tx_hashes: string; // 被加速替换的交易hash，逗号分隔
+  block_number: number;
+  confirmations: number;
+  fail_reason?: string;
+  risk_score: number;
+  risk_flags: string; // 命中的风控规则，逗号分隔
+  reviewed_by?: string;
+  reviewed_at?: string;
+  review_note?: string;
   created_at: string;
   updated_at: string;
 }
@@ -157,6 +337,58 @@ export interface SystemConfig {
   updated_at: string;
 }
 
+// 管理员账户
+export interface AdminAccount {
+  id: string;
+  username: string;
+  role: string; // viewer, operator, finance, superadmin
+  totp_enabled: boolean;
+  disabled: boolean;
+  last_login_at?: string;
+  last_login_ip?: string;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface AdminLoginResponse {
+  token: string;
+  expires_in: number;
+  admin: AdminAccount;
+  permissions: string[];
+  totp_setup_required: boolean;
+}
+
+// ==================== 管理员认证 ====================
+
+export const adminLogin = async (username: string, password: string, totpCode?: string) => {
+  const response = await axios.post<AdminLoginResponse>('/admin/auth/login', {
+    username,
+    password,
+    totp_code: totpCode || '',
+  });
+  return response.data;
+};
+
+export const adminLogout = async () => {
+  const response = await axios.post('/admin/auth/logout');
+  return response.data;
+};
+
+export const getAdminMe = async () => {
+  const response = await axios.get<{ admin: AdminAccount; permissions: string[] }>('/admin/auth/me');
+  return response.data;
+};
+
+export const setupTOTP = async () => {
+  const response = await axios.post<{ secret: string; otpauth_url: string }>('/admin/auth/totp/setup');
+  return response.data;
+};
+
+export const enableTOTP = async (code: string) => {
+  const response = await axios.post('/admin/auth/totp/enable', { code });
+  return response.data;
+};
+
 // ==================== 用户管理 ====================
 
 export const getUsers = async () => {
@@ -185,8 +417,76 @@ export const getDeposits = async () => {
   return response.data;
 };
 
-export const getWithdrawals = async () => {
-  const response = await axios.get<WithdrawRecord[]>('/admin/withdrawals');
+export const getUnclaimedDeposits = async (status?: string) => {
+  const response = await axios.get<UnclaimedDeposit[]>('/admin/deposits/unclaimed', {
+    params: status ? { status } : undefined,
+  });
+  return response.data;
+};
+
+// 将待归属充值指定给用户并入账
+export const assignUnclaimedDeposit = async (id: string, userId: string, note?: string) => {
+  const response = await axios.post<{ message: string; deposit: DepositRecord }>(`/admin/deposits/unclaimed/${id}/assign`, { user_id: userId, note });
+  return response.data;
+};
+
+// 忽略待归属充值
+export const ignoreUnclaimedDeposit = async (id: string, note?: string) => {
+  const response = await axios.post<{ message: string; deposit: UnclaimedDeposit }>(`/admin/deposits/unclaimed/${id}/ignore`, { note });
+  return response.data;
+};
+
+export const getDepositSweeps = async (status?: string) => {
+  const response = await axios.get<DepositSweep[]>('/admin/deposits/sweeps', {
+    params: status ? { status } : undefined,
+  });
+  return response.data;
+};
+
+// 手动触发充值地址归集
+export const triggerDepositSweep = async () => {
+  const response = await axios.post<{ message: string; task_id: string }>('/admin/deposits/sweep');
+  return response.data;
+};
+
+export const getWalletMonitor = async () => {
+  const response = await axios.get<WalletMonitorResult>('/admin/wallet-monitor');
+  return response.data;
+};
+
+export const getWalletMonitorHistory = async (params?: { chain_id?: number; asset?: string }) => {
+  const response = await axios.get<WalletBalanceSnapshot[]>('/admin/wallet-monitor/history', { params });
+  return response.data;
+};
+
+// 手动触发钱包余额检查
+export const triggerWalletMonitor = async () => {
+  const response = await axios.post<{ message: string; task_id: string }>('/admin/wallet-monitor/run');
+  return response.data;
+};
+
+export const getWithdrawals = async (status?: string) => {
+  const response = await axios.get<WithdrawRecord[]>('/admin/withdrawals', {
+    params: status ? { status } : undefined,
+  });
+  return response.data;
+};
+
+// 风控审核：通过后进入提现队列
+export const approveWithdrawal = async (id: string, note?: string) => {
+  const response = await axios.post<{ message: string; withdrawal: WithdrawRecord }>(`/admin/withdrawals/${id}/approve`, { note });
+  return response.data;
+};
+
+// 风控审核：拒绝并解冻资金
+export const rejectWithdrawal = async (id: string, note?: string) => {
+  const response = await axios.post<{ message: string; withdrawal: WithdrawRecord }>(`/admin/withdrawals/${id}/reject`, { note });
+  return response.data;
+};
+
+// 加速未确认的提现（相同 nonce 提高 gas price 重新广播）
+export const speedUpWithdrawal = async (id: string) => {
+  const response = await axios.post<{ message: string; withdrawal: WithdrawRecord }>(`/admin/withdrawals/${id}/speed-up`);
   return response.data;
 };
 
@@ -297,6 +597,58 @@ export const deleteChain = async (id: string) => {
   return response.data;
 };
 
+export const upsertWithdrawFee = async (chainId: string, data: { asset: string; fee: string; min_amount: string }) => {
+  const response = await axios.put<WithdrawFee>(`/admin/chains/${chainId}/withdraw-fees`, data);
+  return response.data;
+};
+
+export const deleteWithdrawFee = async (chainId: string, asset: string) => {
+  const response = await axios.delete(`/admin/chains/${chainId}/withdraw-fees/${asset}`);
+  return response.data;
+};
+
+export const upsertChainToken = async (
+  chainId: string,
+  data: {
+    asset: string;
+    contract_address: string;
+    decimals: number;
+    deposit_enabled: boolean;
+    withdraw_enabled: boolean;
+    min_deposit_amount: string;
+    hot_alert_balance?: string;
+    hot_pause_balance?: string;
+    risk_large_amount?: string;
+    risk_daily_limit?: string;
+  }
+) => {
+  const response = await axios.put<ChainToken>(`/admin/chains/${chainId}/tokens`, data);
+  return response.data;
+};
+
+export const deleteChainToken = async (chainId: string, asset: string) => {
+  const response = await axios.delete(`/admin/chains/${chainId}/tokens/${asset}`);
+  return response.data;
+};
+
+export const upsertRpcEndpoint = async (
+  chainId: string,
+  data: { url: string; priority: number; weight: number; enabled: boolean }
+) => {
+  const response = await axios.put<ChainRpcEndpoint>(`/admin/chains/${chainId}/rpc-endpoints`, data);
+  return response.data;
+};
+
+export const deleteRpcEndpoint = async (chainId: string, endpointId: string) => {
+  const response = await axios.delete(`/admin/chains/${chainId}/rpc-endpoints/${endpointId}`);
+  return response.data;
+};
+
+export const getRpcHealth = async () => {
+  const response = await axios.get<ChainRpcHealth[]>('/admin/chains/rpc-health');
+  return response.data;
+};
+
 // ==================== 任务管理 ====================
 
 export const getAllTasks = async () => {
@@ -346,9 +698,55 @@ export const reloadSystemConfigs = async () => {
   return response.data;
 };
 
+// ==================== 审计日志 ====================
+
+export interface AdminAuditLog {
+  id: string;
+  admin_id: string;
+  admin_username: string;
+  admin_role: string;
+  action: string;
+  method: string;
+  path: string;
+  target_type: string;
+  target_id: string;
+  request: string;
+  before: string;
+  after: string;
+  changes: string;
+  status_code: number;
+  ip_address: string;
+  user_agent: string;
+  created_at: string;
+}
+
+export const getAuditLogs = async (params: {
+  admin_id?: string;
+  admin?: string;
+  action?: string;
+  target_type?: string;
+  target_id?: string;
+  start_date?: string;
+  end_date?: string;
+  page?: number;
+  page_size?: number;
+}) => {
+  const response = await axios.get<{ logs: AdminAuditLog[]; page: number; total: number }>('/admin/audit-logs', {
+    params,
+  });
+  return response.data;
+};
+
 // ==================== 统一导出 adminApi 对象 ====================
 
 export const adminApi = {
+  // 管理员认证
+  adminLogin,
+  adminLogout,
+  getAdminMe,
+  setupTOTP,
+  enableTOTP,
+
   // 用户管理
   getUsers,
   
@@ -360,7 +758,18 @@ export const adminApi = {
   
   // 充提记录
   getDeposits,
+  getUnclaimedDeposits,
+  assignUnclaimedDeposit,
+  getDepositSweeps,
+  triggerDepositSweep,
+  ignoreUnclaimedDeposit,
+  getWalletMonitor,
+  getWalletMonitorHistory,
+  triggerWalletMonitor,
   getWithdrawals,
+  approveWithdrawal,
+  rejectWithdrawal,
+  speedUpWithdrawal,
   
   // 统计数据
   getStats,
@@ -384,6 +793,13 @@ export const adminApi = {
   updateChain,
   updateChainStatus,
   deleteChain,
+  upsertWithdrawFee,
+  deleteWithdrawFee,
+  upsertChainToken,
+  deleteChainToken,
+  upsertRpcEndpoint,
+  deleteRpcEndpoint,
+  getRpcHealth,
   
   // 任务管理
   getAllTasks,
@@ -397,5 +813,8 @@ export const adminApi = {
   getSystemConfig,
   updateSystemConfig,
   reloadSystemConfigs,
+
+  // 审计日志
+  getAuditLogs,
 };
 
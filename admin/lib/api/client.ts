@@ -26,7 +26,8 @@ apiClient.interceptors.request.use(
 apiClient.interceptors.response.use(
   (response) => response,
   (error) => {
-    if (error.response?.status === 401) {
+    // 登录接口的 401（密码错误、需要 TOTP）由登录页自行处理
+    if (error.response?.status === 401 && !error.config?.url?.includes('/admin/auth/login')) {
       localStorage.removeItem('admin_token');
       window.location.href = '/login';
     }
@@ -1,7 +1,10 @@
 package config
 
 import (
+	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -16,11 +19,57 @@ type Config struct {
 	DBName      string
 	JWTSecret   string
 	CORSOrigins string
+	// Sign-In with Ethereum (EIP-4361)
+	SIWEDomain    string        // 登录消息中的域名（需与前端域名一致）
+	SIWEURI       string        // 登录消息中的 URI
+	SIWEStatement string        // 登录消息中的说明文字
+	SIWENonceTTL  time.Duration // 登录 nonce 有效期
+	// 登录会话
+	AccessTokenTTL  time.Duration // 访问令牌（JWT）有效期
+	RefreshTokenTTL time.Duration // 刷新令牌有效期
+	// 敏感数据加密主密钥（API 密钥等）
+	SecretEncryptionKey string
+	// 链上钱包私钥加密主密钥（环境变量或本地密钥文件）
+	WalletMasterKey         string
+	WalletMasterKeyPrevious string // 轮换主密钥时填写旧密钥，启动时自动重新加密
+	// 管理后台（独立于交易用户的签名密钥）
+	AdminJWTSecret         string
+	AdminTokenTTL          time.Duration
+	AdminBootstrapUsername string // 没有任何管理员时自动创建的超级管理员
+	AdminBootstrapPassword string // 为空时随机生成并打印到日志
+	// 限流
+	RateLimitBackend string // memory 或 redis
+	RedisURL         string // redis://[:password@]host:port/db
+	TrustedProxies   string // 可信反向代理 IP/CIDR（逗号分隔），用于获取真实客户端 IP
 }
 
 func Load() (*Config, error) {
 	godotenv.Load()
 
+	// 加密主密钥必须显式配置：退回使用 JWT 密钥或默认值会让 JWT 密钥泄露时连带暴露加密数据
+	secretEncryptionKey := os.Getenv("SECRET_ENCRYPTION_KEY")
+	if secretEncryptionKey == "" {
+		return nil, fmt.Errorf("SECRET_ENCRYPTION_KEY is required (deployments that relied on the old fallback must set it to the previous JWT_SECRET value)")
+	}
+
+	walletMasterKey, err := getEnvOrFile("WALLET_MASTER_KEY", "WALLET_MASTER_KEY_FILE")
+	if err != nil {
+		return nil, err
+	}
+	if walletMasterKey == "" {
+		return nil, fmt.Errorf("WALLET_MASTER_KEY or WALLET_MASTER_KEY_FILE is required (deployments that relied on the old fallback must set it to the previous SECRET_ENCRYPTION_KEY value)")
+	}
+	walletMasterKeyPrevious, err := getEnvOrFile("WALLET_MASTER_KEY_PREVIOUS", "WALLET_MASTER_KEY_PREVIOUS_FILE")
+	if err != nil {
+		return nil, err
+	}
+
+	// 管理后台令牌密钥必须单独配置：由 JWT_SECRET 推导的默认值可被预测，知道用户 JWT 密钥即可伪造管理员令牌
+	adminJWTSecret := os.Getenv("ADMIN_JWT_SECRET")
+	if adminJWTSecret == "" {
+		return nil, fmt.Errorf("ADMIN_JWT_SECRET is required (existing admin sessions must log in again after it is set)")
+	}
+
 	return &Config{
 		ServerPort: "8383",
 		// MySQL 配置（使用共享 Docker MySQL）
@@ -33,9 +82,55 @@ func Load() (*Config, error) {
 		// JWT 配置
 		JWTSecret:   getEnv("JWT_SECRET", "your-secret-key"),
 		CORSOrigins: getEnv("CORS_ORIGINS", "http://localhost:3000"),
+		// SIWE 配置
+		SIWEDomain:    getEnv("SIWE_DOMAIN", "localhost:3000"),
+		SIWEURI:       getEnv("SIWE_URI", "http://localhost:3000"),
+		SIWEStatement: getEnv("SIWE_STATEMENT", "Sign in to Velocity Exchange"),
+		SIWENonceTTL:  getEnvDuration("SIWE_NONCE_TTL", 5*time.Minute),
+		// 会话配置
+		AccessTokenTTL:  getEnvDuration("ACCESS_TOKEN_TTL", 15*time.Minute),
+		RefreshTokenTTL: getEnvDuration("REFRESH_TOKEN_TTL", 30*24*time.Hour),
+		// 加密配置
+		SecretEncryptionKey: secretEncryptionKey,
+		// 钱包私钥主密钥
+		WalletMasterKey:         walletMasterKey,
+		WalletMasterKeyPrevious: walletMasterKeyPrevious,
+		// 管理后台配置
+		AdminJWTSecret:         adminJWTSecret,
+		AdminTokenTTL:          getEnvDuration("ADMIN_TOKEN_TTL", 8*time.Hour),
+		AdminBootstrapUsername: getEnv("ADMIN_BOOTSTRAP_USERNAME", "admin"),
+		AdminBootstrapPassword: getEnv("ADMIN_BOOTSTRAP_PASSWORD", ""),
+		// 限流配置
+		RateLimitBackend: getEnv("RATE_LIMIT_BACKEND", "memory"),
+		RedisURL:         getEnv("REDIS_URL", "redis://localhost:6379/0"),
+		TrustedProxies:   getEnv("TRUSTED_PROXIES", ""),
 	}, nil
 }
 
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(key))
+	if err != nil || value <= 0 {
+		return defaultValue
+	}
+	return value
+}
+
+// getEnvOrFile 优先读取环境变量，否则读取 fileKey 指定的密钥文件内容
+func getEnvOrFile(key, fileKey string) (string, error) {
+	if value := os.Getenv(key); value != "" {
+		return value, nil
+	}
+	path := os.Getenv(fileKey)
+	if path == "" {
+		return "", nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", fileKey, err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
 func getEnv(key, defaultValue string) string {
 	value := os.Getenv(key)
 	if value == "" {
@@ -0,0 +1,49 @@
+package config
+
+import "testing"
+
+func TestLoadRequiresSecretKeys(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantErr bool
+	}{
+		{
+			name:    "missing encryption key",
+			env:     map[string]string{"JWT_SECRET": "jwt", "WALLET_MASTER_KEY": "wallet", "ADMIN_JWT_SECRET": "admin"},
+			wantErr: true,
+		},
+		{
+			name:    "missing wallet master key",
+			env:     map[string]string{"JWT_SECRET": "jwt", "SECRET_ENCRYPTION_KEY": "enc", "ADMIN_JWT_SECRET": "admin"},
+			wantErr: true,
+		},
+		{
+			name:    "missing admin jwt secret",
+			env:     map[string]string{"JWT_SECRET": "jwt", "SECRET_ENCRYPTION_KEY": "enc", "WALLET_MASTER_KEY": "wallet"},
+			wantErr: true,
+		},
+		{
+			name: "all keys set",
+			env:  map[string]string{"JWT_SECRET": "jwt", "SECRET_ENCRYPTION_KEY": "enc", "WALLET_MASTER_KEY": "wallet", "ADMIN_JWT_SECRET": "admin"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{"JWT_SECRET", "SECRET_ENCRYPTION_KEY", "WALLET_MASTER_KEY", "WALLET_MASTER_KEY_FILE", "ADMIN_JWT_SECRET"} {
+				t.Setenv(key, tt.env[key])
+			}
+			cfg, err := Load()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load err=%v, wantErr=%v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if cfg.SecretEncryptionKey != tt.env["SECRET_ENCRYPTION_KEY"] || cfg.WalletMasterKey != tt.env["WALLET_MASTER_KEY"] ||
+				cfg.AdminJWTSecret != tt.env["ADMIN_JWT_SECRET"] {
+				t.Fatalf("unexpected keys: encryption=%q wallet=%q admin=%q", cfg.SecretEncryptionKey, cfg.WalletMasterKey, cfg.AdminJWTSecret)
+			}
+		})
+	}
+}
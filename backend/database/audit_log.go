@@ -0,0 +1,44 @@
+package database
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// auditLogTriggers 在数据库层禁止修改和删除审计日志（模型钩子只约束经过 GORM 且未跳过钩子的写入）
+// TRUNCATE、DROP 和拥有 TRIGGER 权限的账号仍可绕过，生产环境应用账号不应授予这些权限
+var auditLogTriggers = map[string]map[string]string{
+	"mysql": {
+		"admin_audit_logs_no_update": "CREATE TRIGGER admin_audit_logs_no_update BEFORE UPDATE ON admin_audit_logs FOR EACH ROW " +
+			"SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'audit log is append-only'",
+		"admin_audit_logs_no_delete": "CREATE TRIGGER admin_audit_logs_no_delete BEFORE DELETE ON admin_audit_logs FOR EACH ROW " +
+			"SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'audit log is append-only'",
+	},
+	"sqlite": {
+		"admin_audit_logs_no_update": "CREATE TRIGGER IF NOT EXISTS admin_audit_logs_no_update BEFORE UPDATE ON admin_audit_logs " +
+			"BEGIN SELECT RAISE(ABORT, 'audit log is append-only'); END",
+		"admin_audit_logs_no_delete": "CREATE TRIGGER IF NOT EXISTS admin_audit_logs_no_delete BEFORE DELETE ON admin_audit_logs " +
+			"BEGIN SELECT RAISE(ABORT, 'audit log is append-only'); END",
+	},
+}
+
+// protectAuditLog 创建审计日志只追加触发器（已存在时跳过）
+func protectAuditLog(db *gorm.DB, dbType string) error {
+	for name, statement := range auditLogTriggers[dbType] {
+		if dbType == "mysql" {
+			var count int64
+			if err := db.Raw("SELECT COUNT(*) FROM information_schema.triggers WHERE trigger_schema = DATABASE() AND trigger_name = ?", name).
+				Scan(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				continue
+			}
+		}
+		if err := db.Exec(statement).Error; err != nil {
+			return fmt.Errorf("create trigger %s: %w", name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package database
+
+import (
+	"expchange-backend/models"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestAuditLogTriggersBlockRawWrites(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	if err := db.AutoMigrate(&models.AdminAuditLog{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := protectAuditLog(db, "sqlite"); err != nil {
+		t.Fatalf("protectAuditLog: %v", err)
+	}
+	// 重复执行（每次启动都会调用）不报错
+	if err := protectAuditLog(db, "sqlite"); err != nil {
+		t.Fatalf("protectAuditLog again: %v", err)
+	}
+
+	entry := &models.AdminAuditLog{Action: "PUT /api/admin/configs/:id", AdminUsername: "root"}
+	if err := db.Create(entry).Error; err != nil {
+		t.Fatalf("append: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		write func() error
+	}{
+		{name: "raw update", write: func() error {
+			return db.Exec("UPDATE admin_audit_logs SET admin_username = ? WHERE id = ?", "someone", entry.ID).Error
+		}},
+		{name: "raw delete", write: func() error {
+			return db.Exec("DELETE FROM admin_audit_logs WHERE id = ?", entry.ID).Error
+		}},
+		{name: "update skipping hooks", write: func() error {
+			return db.Session(&gorm.Session{SkipHooks: true}).Model(entry).Update("admin_username", "someone").Error
+		}},
+		{name: "delete skipping hooks", write: func() error {
+			return db.Session(&gorm.Session{SkipHooks: true}).Delete(entry).Error
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.write(); err == nil {
+				t.Fatal("expected the trigger to reject the write")
+			}
+		})
+	}
+
+	var saved models.AdminAuditLog
+	if err := db.First(&saved, "id = ?", entry.ID).Error; err != nil || saved.AdminUsername != "root" {
+		t.Fatalf("audit log changed: %+v, err=%v", saved, err)
+	}
+}
@@ -2,6 +2,7 @@ package database
 
 import (
 	"fmt"
+	"log"
 
 	"expchange-backend/config"
 	"expchange-backend/models"
@@ -69,13 +70,35 @@ func InitDB(cfg *config.Config) error {
 		&models.WithdrawRecord{},
 		&models.SystemConfig{},
 		&models.ChainConfig{},
+		&models.WithdrawFee{},
+		&models.ChainToken{},
+		&models.ChainRpcEndpoint{},
+		&models.WithdrawBatch{},
+		&models.DepositScanCursor{},
+		&models.UnclaimedDeposit{},
+		&models.DepositAddress{},
+		&models.DepositSweep{},
+		&models.WalletBalanceSnapshot{},
 		&models.Task{},
 		&models.TaskLog{},
 		&models.MarketMakerPnL{},
+		&models.ReferralCommission{},
+		&models.TreasurySweep{},
+		&models.UserSession{},
+		&models.APIKey{},
+		&models.AdminUser{},
+		&models.AdminAuditLog{},
+		&models.WithdrawAddress{},
+		&models.VerificationCode{},
 	)
 	if err != nil {
 		return fmt.Errorf("failed to migrate database: %w", err)
 	}
 
+	// 创建失败（例如账号没有 TRIGGER 权限）只告警，审计日志仍由模型钩子保护
+	if err := protectAuditLog(DB, dbType); err != nil {
+		log.Printf("⚠️  审计日志数据库触发器创建失败，仅由应用层保证只追加: %v", err)
+	}
+
 	return nil
 }
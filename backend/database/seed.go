@@ -16,6 +16,11 @@ var klineGenerationMutex sync.Mutex
 
 // 自动检测并初始化数据（仅初始化基础配置）
 func AutoSeed() {
+	// 补充后续版本新增的系统配置项（已有数据库升级后也能在管理后台修改）
+	defer ensureSystemConfigs(addedSystemConfigs)
+	// 已有链补充 USDT 资产注册（充值提现按链上资产注册表处理）
+	defer ensureChainTokens()
+
 	// 检查是否已有交易对
 	var count int64
 	DB.Model(&models.TradingPair{}).Count(&count)
@@ -340,6 +345,16 @@ func seedSystemConfig() {
 		{Key: "fee.vip3.maker", Value: "0.0002", Description: "VIP3用户Maker手续费率(0.02%)", Category: "fee", ValueType: "number"},
 		{Key: "fee.vip3.taker", Value: "0.0005", Description: "VIP3用户Taker手续费率(0.05%)", Category: "fee", ValueType: "number"},
 
+		// 邀请返佣配置
+		{Key: "referral.enabled", Value: "true", Description: "是否启用邀请返佣", Category: "referral", ValueType: "boolean"},
+		{Key: "referral.commission.rate", Value: "0.2", Description: "邀请返佣比例(占被邀请人手续费)", Category: "referral", ValueType: "number"},
+		{Key: "referral.settlement.mode", Value: "realtime", Description: "返佣结算模式(realtime/daily)", Category: "referral", ValueType: "string"},
+
+		// 国库归集配置
+		{Key: "treasury.account.address", Value: "", Description: "国库账户钱包地址（手续费归集目标）", Category: "treasury", ValueType: "string"},
+		{Key: "treasury.sweep.enabled", Value: "false", Description: "是否每日自动归集手续费到国库", Category: "treasury", ValueType: "boolean"},
+		{Key: "treasury.sweep.min_amount", Value: "0", Description: "单资产最小归集金额", Category: "treasury", ValueType: "number"},
+
 		// 平台配置
 		{Key: "platform.name", Value: "Velocity Exchange", Description: "平台名称", Category: "platform", ValueType: "string"},
 		{Key: "platform.deposit.address", Value: "0x88888886757311de33778ce108fb312588e368db", Description: "平台充值收款地址", Category: "platform", ValueType: "string"},
@@ -354,6 +369,132 @@ func seedSystemConfig() {
 	log.Printf("✅ 创建了 %d 个系统配置\n", len(configs))
 }
 
+// addedSystemConfigs 初始化之后新增的系统配置项（缺失时自动补充，不覆盖已有值）
+var addedSystemConfigs = []models.SystemConfig{
+	// 限流配置（rate: 每秒请求数，0 表示不限流；burst: 突发上限）
+	{Key: "ratelimit.enabled", Value: "true", Description: "是否启用接口限流", Category: "ratelimit", ValueType: "boolean"},
+	{Key: "ratelimit.public.rate", Value: "20", Description: "公开行情接口：每秒请求数（按IP）", Category: "ratelimit", ValueType: "number"},
+	{Key: "ratelimit.public.burst", Value: "40", Description: "公开行情接口：突发上限", Category: "ratelimit", ValueType: "number"},
+	{Key: "ratelimit.order.rate", Value: "10", Description: "下单/撤单：每秒请求数（按用户）", Category: "ratelimit", ValueType: "number"},
+	{Key: "ratelimit.order.burst", Value: "20", Description: "下单/撤单：突发上限", Category: "ratelimit", ValueType: "number"},
+	{Key: "ratelimit.account.rate", Value: "10", Description: "账户接口：每秒请求数（按用户）", Category: "ratelimit", ValueType: "number"},
+	{Key: "ratelimit.account.burst", Value: "30", Description: "账户接口：突发上限", Category: "ratelimit", ValueType: "number"},
+	{Key: "ratelimit.auth.rate", Value: "0.2", Description: "登录接口：每秒请求数（按IP）", Category: "ratelimit", ValueType: "number"},
+	{Key: "ratelimit.auth.burst", Value: "10", Description: "登录接口：突发上限", Category: "ratelimit", ValueType: "number"},
+
+	// 提现安全配置
+	{Key: "withdraw.require_2fa", Value: "true", Description: "提现是否必须二次验证（TOTP 或邮件验证码）", Category: "withdraw", ValueType: "boolean"},
+	{Key: "withdraw.address_lock_hours", Value: "24", Description: "新增提现地址生效前的锁定时长（小时）", Category: "withdraw", ValueType: "number"},
+	{Key: "withdraw.security_lock_hours", Value: "24", Description: "安全设置变更（新IP登录、创建API密钥等）后锁定提现时长（小时）", Category: "withdraw", ValueType: "number"},
+	{Key: "withdraw.code_ttl_minutes", Value: "10", Description: "邮件验证码有效期（分钟）", Category: "withdraw", ValueType: "number"},
+
+	// 提现风控（评分达到阈值进入人工审核）
+	{Key: "withdraw.risk.enabled", Value: "true", Description: "是否启用提现风控评分", Category: "withdraw", ValueType: "boolean"},
+	{Key: "withdraw.risk.review_score", Value: "50", Description: "风险评分达到该值进入人工审核", Category: "withdraw", ValueType: "number"},
+	{Key: "withdraw.risk.large_amount", Value: "10000", Description: "USDT 单笔大额提现阈值（60分，其他资产在链资产配置中设置）", Category: "withdraw", ValueType: "number"},
+	{Key: "withdraw.risk.daily_limit", Value: "50000", Description: "USDT 24小时累计提现限额（超出60分，其他资产在链资产配置中设置）", Category: "withdraw", ValueType: "number"},
+	{Key: "withdraw.risk.new_account_days", Value: "7", Description: "注册不足该天数视为新账户（30分）", Category: "withdraw", ValueType: "number"},
+	{Key: "withdraw.risk.deposit_velocity_hours", Value: "24", Description: "充值后快速提现的检测窗口（小时，30分）", Category: "withdraw", ValueType: "number"},
+	{Key: "withdraw.risk.deposit_velocity_ratio", Value: "0.8", Description: "窗口内充值金额达到提现金额的该比例视为快速提现", Category: "withdraw", ValueType: "number"},
+	{Key: "withdraw.tracker.interval", Value: "15", Description: "提现链上确认检查间隔（秒）", Category: "withdraw", ValueType: "number"},
+	{Key: "withdraw.tracker.stuck_minutes", Value: "10", Description: "提现交易广播后超过该分钟数未打包则加速替换", Category: "withdraw", ValueType: "number"},
+	{Key: "withdraw.tracker.fee_bump_percent", Value: "20", Description: "加速替换时 gas price 提高的百分比（最低10）", Category: "withdraw", ValueType: "number"},
+	{Key: "withdraw.gas.limit_margin_percent", Value: "20", Description: "提现交易 gas limit 在估算值基础上增加的安全余量（百分比）", Category: "withdraw", ValueType: "number"},
+	{Key: "withdraw.tracker.max_bumps", Value: "3", Description: "单笔提现最多加速替换次数", Category: "withdraw", ValueType: "number"},
+	{Key: "withdraw.nonce.reconcile_interval", Value: "60", Description: "热钱包 nonce 与链上对账间隔（秒）", Category: "withdraw", ValueType: "number"},
+	{Key: "withdraw.nonce.fill_gaps", Value: "true", Description: "对账发现 nonce 缺口时发送零值自转账补洞，并替换长时间卡住的非提现交易", Category: "withdraw", ValueType: "boolean"},
+	{Key: "withdraw.batch.enabled", Value: "false", Description: "启用批量提现（需在链配置中设置 multisend 合约并为其授权 USDT 额度）", Category: "withdraw", ValueType: "boolean"},
+	{Key: "withdraw.batch.window_seconds", Value: "60", Description: "批量提现收集窗口（秒），最早的提现等待满该时长后发送", Category: "withdraw", ValueType: "number"},
+	{Key: "withdraw.batch.max_size", Value: "50", Description: "单笔批量交易最多包含的提现数", Category: "withdraw", ValueType: "number"},
+
+	// 充值扫描（间隔见 deposit.check.interval）
+	{Key: "deposit.scanner.enabled", Value: "true", Description: "是否扫描链上转入充值地址的 Transfer 事件并自动入账", Category: "deposit", ValueType: "boolean"},
+	{Key: "deposit.scanner.batch_blocks", Value: "2000", Description: "单次 eth_getLogs 查询的区块数（受 RPC 节点限制）", Category: "deposit", ValueType: "number"},
+	{Key: "deposit.scanner.native_batch_blocks", Value: "100", Description: "开放原生币充值时单轮扫描的区块数（需要逐个区块读取交易）", Category: "deposit", ValueType: "number"},
+	// 用户提交交易哈希的充值验证
+	{Key: "deposit.verify.require_sender", Value: "true", Description: "用户提交的充值交易必须由其登录钱包转出（转入专属充值地址的不校验）", Category: "deposit", ValueType: "boolean"},
+	{Key: "deposit.verify.router_addresses", Value: "", Description: "中转合约地址（逗号分隔）：Transfer 由这些合约转出时改为校验交易发起地址", Category: "deposit", ValueType: "string"},
+	{Key: "deposit.verify.retry_seconds", Value: "15", Description: "充值交易已打包、等待确认时的重新验证间隔（秒）", Category: "deposit", ValueType: "number"},
+	{Key: "deposit.verify.pending_timeout_hours", Value: "24", Description: "提交后超过该小时数交易仍未上链则标记充值失败", Category: "deposit", ValueType: "number"},
+	// 任务重试（交易未上链、RPC 故障等按重试次数指数退避）
+	{Key: "task.queue.retry_base_seconds", Value: "10", Description: "任务首次重试等待时间（秒），之后每次翻倍", Category: "task", ValueType: "number"},
+	{Key: "task.queue.retry_max_seconds", Value: "300", Description: "任务重试最长等待时间（秒）", Category: "task", ValueType: "number"},
+	// 用户充值地址归集（链配置 deposit_xpub 和归集私钥后生效）
+	{Key: "deposit.sweep.enabled", Value: "false", Description: "是否定时把用户充值地址中开放充值的资产归集到提现热钱包", Category: "deposit", ValueType: "boolean"},
+	{Key: "deposit.sweep.interval_minutes", Value: "10", Description: "充值地址归集任务间隔（分钟，补充 gas、转出、确认分多次推进）", Category: "deposit", ValueType: "number"},
+	{Key: "deposit.sweep.min_amount", Value: "10", Description: "充值地址 USDT 最小归集金额（低于该金额暂不归集，其他资产使用最小充值额）", Category: "deposit", ValueType: "number"},
+	// RPC 节点池（链配置的 RpcURL 与备用节点按优先级、权重分配请求，故障时切换）
+	{Key: "rpc.health.interval_seconds", Value: "30", Description: "RPC 节点健康检查间隔（秒）", Category: "rpc", ValueType: "number"},
+	{Key: "rpc.pool.failure_threshold", Value: "3", Description: "RPC 节点连续失败多少次后熔断", Category: "rpc", ValueType: "number"},
+	{Key: "rpc.pool.open_seconds", Value: "30", Description: "RPC 节点熔断时长（秒），到期后放行一个请求试探", Category: "rpc", ValueType: "number"},
+	{Key: "rpc.pool.max_head_lag", Value: "20", Description: "RPC 节点区块高度落后同链最高节点超过该值时不再分配请求", Category: "rpc", ValueType: "number"},
+	{Key: "rpc.pool.request_timeout_seconds", Value: "15", Description: "单个 RPC 请求超时（秒），超时后切换节点", Category: "rpc", ValueType: "number"},
+	// 钱包余额监控（告警值、暂停值在链资产配置中设置）
+	{Key: "monitor.wallet.enabled", Value: "true", Description: "是否定时检查各链提现热钱包和冷钱包余额", Category: "monitor", ValueType: "boolean"},
+	{Key: "monitor.wallet.interval_minutes", Value: "5", Description: "钱包余额检查间隔（分钟）", Category: "monitor", ValueType: "number"},
+	{Key: "monitor.wallet.auto_pause", Value: "true", Description: "热钱包余额低于资产暂停值时是否自动暂停该资产提现（余额恢复后自动解除）", Category: "monitor", ValueType: "boolean"},
+	{Key: "monitor.wallet.min_coverage_ratio", Value: "1", Description: "资产储备覆盖率（热钱包+冷钱包余额 / 用户负债）低于该值时告警", Category: "monitor", ValueType: "number"},
+	{Key: "monitor.wallet.alert_repeat_minutes", Value: "60", Description: "同一余额问题持续存在时重复告警的间隔（分钟）", Category: "monitor", ValueType: "number"},
+	{Key: "monitor.wallet.history_days", Value: "30", Description: "钱包余额快照保留天数", Category: "monitor", ValueType: "number"},
+	{Key: "alert.webhook_url", Value: "", Description: "运维告警 webhook 地址（POST JSON {to, subject, body}，为空时只写日志）", Category: "alert", ValueType: "string"},
+	{Key: "alert.recipient", Value: "ops", Description: "运维告警接收方（随 webhook 发送）", Category: "alert", ValueType: "string"},
+}
+
+// ensureChainTokens 为没有 USDT 资产注册的链按链配置的 USDT 合约和精度补充一条（开放充值提现）
+func ensureChainTokens() {
+	var chains []models.ChainConfig
+	DB.Find(&chains)
+
+	created := 0
+	for _, chain := range chains {
+		if chain.UsdtContractAddress == "" {
+			continue
+		}
+		var count int64
+		DB.Model(&models.ChainToken{}).Where("chain_id = ? AND asset = ?", chain.ChainID, "USDT").Count(&count)
+		if count > 0 {
+			continue
+		}
+		token := models.ChainToken{
+			ChainID:         chain.ChainID,
+			Asset:           "USDT",
+			ContractAddress: chain.UsdtContractAddress,
+			Decimals:        chain.UsdtDecimals,
+			DepositEnabled:  true,
+			WithdrawEnabled: true,
+		}
+		if err := DB.Create(&token).Error; err != nil {
+			log.Printf("⚠️  补充链资产配置失败: ChainID=%d, err=%v", chain.ChainID, err)
+			continue
+		}
+		created++
+	}
+	if created > 0 {
+		log.Printf("✅ 补充了 %d 条 USDT 链资产配置", created)
+	}
+}
+
+// ensureSystemConfigs 补充缺失的系统配置项
+func ensureSystemConfigs(configs []models.SystemConfig) {
+	created := 0
+	for _, config := range configs {
+		var count int64
+		DB.Model(&models.SystemConfig{}).Where("`key` = ?", config.Key).Count(&count)
+		if count > 0 {
+			continue
+		}
+		if err := DB.Create(&config).Error; err != nil {
+			log.Printf("❌ 创建系统配置失败: %s, %v", config.Key, err)
+			continue
+		}
+		created++
+	}
+
+	if created > 0 {
+		log.Printf("✅ 补充了 %d 个系统配置", created)
+	}
+}
+
 func seedChainConfig() {
 	var count int64
 	DB.Model(&models.ChainConfig{}).Count(&count)
@@ -373,6 +514,9 @@ func seedChainConfig() {
 			UsdtContractAddress:        "0xdac17f958d2ee523a2206206994597c13d831ec7", // USDT on Ethereum
 			UsdtDecimals:               6,                                            // Ethereum USDT使用6位精度
 			PlatformDepositAddress:     "0x88888886757311de33778ce108fb312588e368db",
+			WithdrawConfirmations:      12,
+			RequiredConfirmations:      12,
+			TxType:                     models.TxTypeEIP1559,
 			PlatformWithdrawPrivateKey: "",    // 需要在管理后台配置
 			Enabled:                    false, // 默认禁用，管理员可手动启用
 		},
@@ -384,6 +528,9 @@ func seedChainConfig() {
 			UsdtContractAddress:        "0x55d398326f99059fF775485246999027B3197955",
 			UsdtDecimals:               18, // BSC USDT使用18位精度
 			PlatformDepositAddress:     "0x88888886757311de33778ce108fb312588e368db",
+			WithdrawConfirmations:      15,
+			RequiredConfirmations:      15,
+			TxType:                     models.TxTypeLegacy,
 			PlatformWithdrawPrivateKey: "",   // 需要在管理后台配置
 			Enabled:                    true, // 默认启用
 		},
@@ -395,6 +542,9 @@ func seedChainConfig() {
 			UsdtContractAddress:        "0xc2132d05d31c914a87c6611c10748aeb04b58e8f", // USDT on Polygon
 			UsdtDecimals:               6,                                            // Polygon USDT使用6位精度
 			PlatformDepositAddress:     "0x88888886757311de33778ce108fb312588e368db",
+			WithdrawConfirmations:      128,
+			RequiredConfirmations:      128,
+			TxType:                     models.TxTypeEIP1559,
 			PlatformWithdrawPrivateKey: "",    // 需要在管理后台配置
 			Enabled:                    false, // 默认禁用
 		},
@@ -406,6 +556,9 @@ func seedChainConfig() {
 			UsdtContractAddress:        "0xfd086bc7cd5c481dcc9c85ebe478a1c0b69fcbb9", // USDT on Arbitrum
 			UsdtDecimals:               6,                                            // Arbitrum USDT使用6位精度
 			PlatformDepositAddress:     "0x88888886757311de33778ce108fb312588e368db",
+			WithdrawConfirmations:      20,
+			RequiredConfirmations:      20,
+			TxType:                     models.TxTypeEIP1559,
 			PlatformWithdrawPrivateKey: "",    // 需要在管理后台配置
 			Enabled:                    false, // 默认禁用
 		},
@@ -417,13 +570,27 @@ func seedChainConfig() {
 			UsdtContractAddress:        "0x49433da9Bb68917A4dc35eB7565629289aA1BDf8", // 测试USDT
 			UsdtDecimals:               6,                                            // Sepolia测试USDT使用6位精度
 			PlatformDepositAddress:     "0x88888886757311de33778ce108fb312588e368db",
+			WithdrawConfirmations:      3,
+			RequiredConfirmations:      3,
+			TxType:                     models.TxTypeEIP1559,
 			PlatformWithdrawPrivateKey: "",   // 需要在管理后台配置
 			Enabled:                    true, // 测试网默认启用
 		},
 	}
 
+	// 各链原生币（默认关闭充值提现，可在管理后台「链配置」→「资产」中开启）
+	nativeAssets := map[int]string{1: "ETH", 56: "BNB", 137: "POL", 42161: "ETH", 11155111: "ETH"}
+
 	for _, chain := range chains {
 		DB.Create(&chain)
+		DB.Create(&models.ChainToken{ChainID: chain.ChainID, Asset: nativeAssets[chain.ChainID], Decimals: 18})
+
+		// 默认 USDT 提现手续费和最小提现额（可在管理后台修改）
+		fee, minAmount := decimal.NewFromInt(1), decimal.NewFromInt(10)
+		if chain.ChainID == 1 {
+			fee, minAmount = decimal.NewFromInt(5), decimal.NewFromInt(20)
+		}
+		DB.Create(&models.WithdrawFee{ChainID: chain.ChainID, Asset: "USDT", Fee: fee, MinAmount: minAmount})
 	}
 
 	log.Printf("✅ 创建了 %d 个链配置\n", len(chains))
@@ -5,6 +5,10 @@ import (
 	"log"
 	"strconv"
 	"sync"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
 )
 
 // SystemConfigManager 系统配置管理器（支持热更新）
@@ -69,6 +73,20 @@ func (m *SystemConfigManager) GetInt(key string, defaultValue int) int {
 	return intValue
 }
 
+// GetDecimal 获取金额类配置
+func (m *SystemConfigManager) GetDecimal(key string, defaultValue decimal.Decimal) decimal.Decimal {
+	value := m.Get(key, "")
+	if value == "" {
+		return defaultValue
+	}
+
+	decValue, err := decimal.NewFromString(value)
+	if err != nil {
+		return defaultValue
+	}
+	return decValue
+}
+
 // GetBool 获取布尔配置
 func (m *SystemConfigManager) GetBool(key string, defaultValue bool) bool {
 	value := m.Get(key, "")
@@ -93,5 +111,28 @@ func (m *SystemConfigManager) Reload() {
 	m.LoadFromDB()
 }
 
+// Upsert 写入配置到数据库（不存在则创建）并热更新到内存
+func (m *SystemConfigManager) Upsert(key, value, description, category, valueType string) error {
+	var config models.SystemConfig
+	err := DB.Session(&gorm.Session{Logger: DB.Logger.LogMode(logger.Silent)}).
+		Where("`key` = ?", key).First(&config).Error
+	if err != nil {
+		config = models.SystemConfig{
+			Key:         key,
+			Value:       value,
+			Description: description,
+			Category:    category,
+			ValueType:   valueType,
+		}
+		if err := DB.Create(&config).Error; err != nil {
+			return err
+		}
+	} else {
+		if err := DB.Model(&config).Update("value", value).Error; err != nil {
+			return err
+		}
+	}
 
-
+	m.Set(key, value)
+	return nil
+}
@@ -1,34 +1,71 @@
 package handlers
 
 import (
+	"errors"
 	"expchange-backend/database"
 	"expchange-backend/models"
 	"expchange-backend/queue"
+	"expchange-backend/services"
 	"log"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
 )
 
-type AdminHandler struct{}
+type AdminHandler struct {
+	sessionService *services.SessionService
+}
 
 func NewAdminHandler() *AdminHandler {
-	return &AdminHandler{}
+	return &AdminHandler{
+		// 管理端只查询和吊销会话，不签发刷新令牌
+		sessionService: services.NewSessionService(0),
+	}
 }
 
-// 获取所有用户（排除虚拟用户）
+// 获取所有用户（排除虚拟用户和平台手续费账户）
 func (h *AdminHandler) GetUsers(c *gin.Context) {
 	var users []models.User
 	database.DB.
-		Where("wallet_address != ?", "0x0000000000000000000000000000000000000000").
+		Where("wallet_address NOT IN ?", services.GetSystemWalletAddresses()).
 		Order("created_at DESC").
 		Find(&users)
 
 	c.JSON(http.StatusOK, users)
 }
 
+// 管理员：获取用户的有效登录会话
+func (h *AdminHandler) GetUserSessions(c *gin.Context) {
+	userID := c.Param("id")
+	c.JSON(http.StatusOK, h.sessionService.GetUserSessions(userID))
+}
+
+// 管理员：吊销用户的所有登录会话（强制下线）
+func (h *AdminHandler) RevokeUserSessions(c *gin.Context) {
+	userID := c.Param("id")
+
+	var user models.User
+	if err := database.DB.Where("id = ?", userID).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	count, err := h.sessionService.RevokeAllForUser(user.ID, services.SessionRevokeAdmin)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke sessions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Sessions revoked",
+		"revoked": count,
+	})
+}
+
 // 获取做市商盈亏记录
 func (h *AdminHandler) GetMarketMakerPnL(c *gin.Context) {
 	symbol := c.Query("symbol") // 可选：按交易对筛选
@@ -527,10 +564,10 @@ func (h *AdminHandler) GetStats(c *gin.Context) {
 	// 排除虚拟模拟用户（钱包地址为全0的用户）
 	simulatorWallet := "0x0000000000000000000000000000000000000000"
 
-	// 统计真实用户数（排除模拟器用户）
+	// 统计真实用户数（排除模拟器用户和平台手续费账户）
 	var userCount int64
 	database.DB.Model(&models.User{}).
-		Where("wallet_address != ?", simulatorWallet).
+		Where("wallet_address NOT IN ?", services.GetSystemWalletAddresses()).
 		Count(&userCount)
 
 	// 统计真实订单数（排除模拟器用户的订单）
@@ -733,14 +770,205 @@ func (h *AdminHandler) GetAllDeposits(c *gin.Context) {
 	c.JSON(http.StatusOK, deposits)
 }
 
-// 获取所有提现记录
+// 获取扫描到的待归属充值（可按状态筛选，默认 unclaimed）
+func (h *AdminHandler) GetUnclaimedDeposits(c *gin.Context) {
+	status := c.DefaultQuery("status", "unclaimed")
+
+	var deposits []models.UnclaimedDeposit
+	database.DB.Where("status = ?", status).Order("created_at DESC").Limit(500).Find(&deposits)
+
+	c.JSON(http.StatusOK, deposits)
+}
+
+type assignDepositRequest struct {
+	UserID string `json:"user_id" binding:"required"`
+	Note   string `json:"note" binding:"max=255"`
+}
+
+// 将待归属充值指定给用户并入账
+func (h *AdminHandler) AssignUnclaimedDeposit(c *gin.Context) {
+	var req assignDepositRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	deposit, err := services.AssignUnclaimedDeposit(c.Param("id"), req.UserID, c.GetString("admin_id"), req.Note)
+	if err != nil {
+		c.JSON(reviewErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Deposit credited",
+		"deposit": deposit,
+	})
+}
+
+// 忽略待归属充值
+func (h *AdminHandler) IgnoreUnclaimedDeposit(c *gin.Context) {
+	var req reviewWithdrawalRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	deposit, err := services.IgnoreUnclaimedDeposit(c.Param("id"), c.GetString("admin_id"), req.Note)
+	if err != nil {
+		c.JSON(reviewErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Deposit ignored",
+		"deposit": deposit,
+	})
+}
+
+// 获取充值地址归集记录（可按状态筛选）
+func (h *AdminHandler) GetDepositSweeps(c *gin.Context) {
+	query := database.DB.Order("created_at DESC").Limit(500)
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var sweeps []models.DepositSweep
+	query.Find(&sweeps)
+
+	c.JSON(http.StatusOK, sweeps)
+}
+
+// 手动触发充值地址归集
+func (h *AdminHandler) TriggerDepositSweep(c *gin.Context) {
+	task, err := queue.GetQueue().AddDepositSweepTask("admin:" + c.GetString("admin_username"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Deposit sweep task created",
+		"task_id": task.ID,
+	})
+}
+
+// 获取钱包余额监控：每条链每个资产最近一次的快照和按资产汇总的储备覆盖率
+func (h *AdminHandler) GetWalletMonitor(c *gin.Context) {
+	result, err := services.GetLatestWalletSnapshots()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// 获取钱包余额快照历史（可按 chain_id、asset 筛选）
+func (h *AdminHandler) GetWalletMonitorHistory(c *gin.Context) {
+	chainID, _ := strconv.Atoi(c.Query("chain_id"))
+	c.JSON(http.StatusOK, services.GetWalletSnapshotHistory(chainID, c.Query("asset")))
+}
+
+// 手动触发钱包余额检查
+func (h *AdminHandler) TriggerWalletMonitor(c *gin.Context) {
+	task, err := queue.GetQueue().AddWalletMonitorTask("admin:" + c.GetString("admin_username"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Wallet monitor task created",
+		"task_id": task.ID,
+	})
+}
+
+// 获取所有提现记录（可按状态筛选，如 status=pending_review）
 func (h *AdminHandler) GetAllWithdrawals(c *gin.Context) {
+	query := database.DB.Preload("User").Order("created_at DESC").Limit(500)
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
 	var withdrawals []models.WithdrawRecord
-	database.DB.Preload("User").Order("created_at DESC").Limit(500).Find(&withdrawals)
+	query.Find(&withdrawals)
 
 	c.JSON(http.StatusOK, withdrawals)
 }
 
+type reviewWithdrawalRequest struct {
+	Note string `json:"note" binding:"max=255"`
+}
+
+// 审核通过提现（进入提现队列）
+func (h *AdminHandler) ApproveWithdrawal(c *gin.Context) {
+	var req reviewWithdrawalRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	withdrawal, err := services.ApproveWithdrawal(c.Param("id"), c.GetString("admin_id"), req.Note)
+	if err != nil {
+		c.JSON(reviewErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	enqueueWithdrawal(withdrawal)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Withdrawal approved",
+		"withdrawal": withdrawal,
+	})
+}
+
+// 审核拒绝提现（解冻资金）
+func (h *AdminHandler) RejectWithdrawal(c *gin.Context) {
+	var req reviewWithdrawalRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	withdrawal, err := services.RejectWithdrawal(c.Param("id"), c.GetString("admin_id"), req.Note)
+	if err != nil {
+		c.JSON(reviewErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Withdrawal rejected",
+		"withdrawal": withdrawal,
+	})
+}
+
+// 加速未确认的提现（相同 nonce 提高 gas price 替换交易）
+func (h *AdminHandler) SpeedUpWithdrawal(c *gin.Context) {
+	withdrawal, err := queue.GetQueue().SpeedUpWithdrawal(c.Param("id"))
+	if err != nil {
+		c.JSON(reviewErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Withdrawal transaction replaced",
+		"withdrawal": withdrawal,
+	})
+}
+
+func reviewErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrWithdrawNotReviewable), errors.Is(err, services.ErrWithdrawNotBroadcast),
+		errors.Is(err, services.ErrWithdrawMaxBumps), errors.Is(err, services.ErrUnclaimedDepositHandled),
+		errors.Is(err, services.ErrDepositAlreadyRecorded):
+		return http.StatusConflict
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
 // 获取所有系统配置
 func (h *AdminHandler) GetSystemConfigs(c *gin.Context) {
 	category := c.Query("category")
@@ -0,0 +1,295 @@
+package handlers
+
+import (
+	"errors"
+	"expchange-backend/config"
+	"expchange-backend/database"
+	"expchange-backend/middleware"
+	"expchange-backend/models"
+	"expchange-backend/services"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type AdminAuthHandler struct {
+	cfg            *config.Config
+	accountService *services.AdminAccountService
+}
+
+func NewAdminAuthHandler(cfg *config.Config) *AdminAuthHandler {
+	return &AdminAuthHandler{
+		cfg:            cfg,
+		accountService: services.NewAdminAccountService(cfg.SecretEncryptionKey),
+	}
+}
+
+// EnsureBootstrapAdmin 启动时确保至少有一个超级管理员
+func (h *AdminAuthHandler) EnsureBootstrapAdmin() {
+	if err := h.accountService.EnsureBootstrapAdmin(h.cfg.AdminBootstrapUsername, h.cfg.AdminBootstrapPassword); err != nil {
+		log.Printf("❌ 创建初始管理员失败: %v", err)
+	}
+}
+
+type AdminLoginRequest struct {
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required"`
+	TOTPCode string `json:"totp_code"`
+}
+
+// Login 管理员登录（密码 + TOTP）
+func (h *AdminAuthHandler) Login(c *gin.Context) {
+	var req AdminLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	admin, err := h.accountService.Authenticate(req.Username, req.Password, req.TOTPCode, c.ClientIP())
+	h.recordLogin(c, req.Username, admin, err)
+	if err != nil {
+		if errors.Is(err, services.ErrAdminTOTPRequired) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error(), "totp_required": true})
+			return
+		}
+		log.Printf("⚠️  管理员登录失败: Username=%s, IP=%s, %v", req.Username, c.ClientIP(), err)
+		// 锁定与密码错误返回相同信息，不暴露账户状态
+		if errors.Is(err, services.ErrAdminLocked) {
+			err = services.ErrAdminInvalidCredentials
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	token, err := h.generateToken(admin)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	log.Printf("🔐 管理员登录: %s (%s), IP=%s", admin.Username, admin.Role, c.ClientIP())
+
+	c.JSON(http.StatusOK, gin.H{
+		"token":               token,
+		"expires_in":          int(h.cfg.AdminTokenTTL.Seconds()),
+		"admin":               admin,
+		"permissions":         services.GetAdminRolePermissions(admin.Role),
+		"totp_setup_required": !admin.TOTPEnabled,
+	})
+}
+
+// GetMe 当前管理员信息
+func (h *AdminAuthHandler) GetMe(c *gin.Context) {
+	admin, ok := h.currentAdmin(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"admin":       admin,
+		"permissions": services.GetAdminRolePermissions(admin.Role),
+	})
+}
+
+// Logout 登出（该管理员所有已签发的令牌失效）
+func (h *AdminAuthHandler) Logout(c *gin.Context) {
+	if err := h.accountService.Logout(c.GetString("admin_id")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to logout"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out"})
+}
+
+// SetupTOTP 生成 TOTP 密钥（返回 otpauth 链接供扫码）
+func (h *AdminAuthHandler) SetupTOTP(c *gin.Context) {
+	admin, ok := h.currentAdmin(c)
+	if !ok {
+		return
+	}
+
+	secret, otpauthURL, err := h.accountService.SetupTOTP(admin)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"secret":      secret,
+		"otpauth_url": otpauthURL,
+	})
+}
+
+// EnableTOTP 验证验证码并启用 TOTP（启用后需重新登录）
+func (h *AdminAuthHandler) EnableTOTP(c *gin.Context) {
+	var req struct {
+		Code string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	admin, ok := h.currentAdmin(c)
+	if !ok {
+		return
+	}
+
+	if err := h.accountService.EnableTOTP(admin, req.Code); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "TOTP enabled, please login again"})
+}
+
+// ChangePassword 修改自己的密码（修改后需重新登录）
+func (h *AdminAuthHandler) ChangePassword(c *gin.Context) {
+	var req struct {
+		OldPassword string `json:"old_password" binding:"required"`
+		NewPassword string `json:"new_password" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	admin, ok := h.currentAdmin(c)
+	if !ok {
+		return
+	}
+
+	if err := h.accountService.ChangePassword(admin, req.OldPassword, req.NewPassword); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password changed, please login again"})
+}
+
+// 超级管理员：获取管理员列表
+func (h *AdminAuthHandler) GetAdmins(c *gin.Context) {
+	var admins []models.AdminUser
+	database.DB.Order("created_at ASC").Find(&admins)
+	c.JSON(http.StatusOK, admins)
+}
+
+// 超级管理员：创建管理员
+func (h *AdminAuthHandler) CreateAdmin(c *gin.Context) {
+	var req struct {
+		Username string `json:"username" binding:"required,max=50"`
+		Password string `json:"password" binding:"required"`
+		Role     string `json:"role" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	admin, err := h.accountService.Create(req.Username, req.Password, req.Role)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, admin)
+}
+
+// 超级管理员：修改管理员角色或禁用
+func (h *AdminAuthHandler) UpdateAdmin(c *gin.Context) {
+	var req struct {
+		Role     *string `json:"role"`
+		Disabled *bool   `json:"disabled"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	admin, err := h.accountService.Update(c.Param("id"), req.Role, req.Disabled)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, admin)
+}
+
+// 超级管理员：重置管理员密码
+func (h *AdminAuthHandler) ResetAdminPassword(c *gin.Context) {
+	var req struct {
+		Password string `json:"password" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.accountService.ResetPassword(c.Param("id"), req.Password); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password reset"})
+}
+
+// 超级管理员：清除管理员的 TOTP 绑定（下次登录需重新绑定）
+func (h *AdminAuthHandler) ResetAdminTOTP(c *gin.Context) {
+	if err := h.accountService.ResetTOTP(c.Param("id")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset TOTP"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "TOTP reset"})
+}
+
+// recordLogin 登录尝试写入审计日志（登录接口不经过审计中间件）
+func (h *AdminAuthHandler) recordLogin(c *gin.Context, username string, admin *models.AdminUser, err error) {
+	entry := &models.AdminAuditLog{
+		AdminUsername: username,
+		Action:        "admin.login",
+		Method:        c.Request.Method,
+		Path:          c.Request.URL.Path,
+		TargetType:    "admin_user",
+		StatusCode:    http.StatusOK,
+		IPAddress:     c.ClientIP(),
+		UserAgent:     c.Request.UserAgent(),
+	}
+	if admin != nil {
+		entry.AdminID = admin.ID
+		entry.AdminRole = admin.Role
+		entry.TargetID = admin.ID
+	}
+	if err != nil {
+		entry.StatusCode = http.StatusUnauthorized
+		entry.Request = services.MaskedJSON(gin.H{"error": err.Error()})
+	}
+	services.RecordAdminAudit(entry)
+}
+
+func (h *AdminAuthHandler) currentAdmin(c *gin.Context) (*models.AdminUser, bool) {
+	var admin models.AdminUser
+	if err := database.DB.Where("id = ?", c.GetString("admin_id")).First(&admin).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Admin not found"})
+		return nil, false
+	}
+	return &admin, true
+}
+
+func (h *AdminAuthHandler) generateToken(admin *models.AdminUser) (string, error) {
+	claims := middleware.AdminClaims{
+		AdminID:      admin.ID,
+		Username:     admin.Username,
+		TokenVersion: admin.TokenVersion,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Audience:  jwt.ClaimStrings{middleware.AdminTokenAudience},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(h.cfg.AdminTokenTTL)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(h.cfg.AdminJWTSecret))
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"expchange-backend/config"
+	"expchange-backend/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type APIKeyHandler struct {
+	apiKeyService *services.APIKeyService
+}
+
+func NewAPIKeyHandler(cfg *config.Config) *APIKeyHandler {
+	return &APIKeyHandler{
+		apiKeyService: services.NewAPIKeyService(cfg.SecretEncryptionKey),
+	}
+}
+
+type CreateAPIKeyRequest struct {
+	Label       string   `json:"label" binding:"required,max=50"`
+	Permissions []string `json:"permissions"`  // read, trade, withdraw（read 默认包含）
+	IPWhitelist []string `json:"ip_whitelist"` // IP 或 CIDR，提现权限必填
+}
+
+// CreateAPIKey 创建 API 密钥（secret 只在创建时返回一次）
+func (h *APIKeyHandler) CreateAPIKey(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	var req CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	key, secret, err := h.apiKeyService.Create(userID, req.Label, req.Permissions, req.IPWhitelist)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"api_key": key,
+		"secret":  secret,
+		"message": "Please save the secret now, it will not be shown again",
+	})
+}
+
+// GetAPIKeys 获取我的 API 密钥列表
+func (h *APIKeyHandler) GetAPIKeys(c *gin.Context) {
+	userID := c.GetString("user_id")
+	c.JSON(http.StatusOK, h.apiKeyService.List(userID))
+}
+
+// RevokeAPIKey 吊销 API 密钥
+func (h *APIKeyHandler) RevokeAPIKey(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	if err := h.apiKeyService.Revoke(userID, c.Param("id")); err != nil {
+		if err == services.ErrAPIKeyNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke API key"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "API key revoked"})
+}
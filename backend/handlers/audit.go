@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"expchange-backend/services"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AuditHandler struct{}
+
+func NewAuditHandler() *AuditHandler {
+	return &AuditHandler{}
+}
+
+// 管理员：查询审计日志
+// 参数：admin_id, admin, action, target_type, target_id, start_date, end_date（含当天）, page, page_size
+func (h *AuditHandler) GetAuditLogs(c *gin.Context) {
+	filter := services.AuditLogFilter{
+		AdminID:    c.Query("admin_id"),
+		Admin:      c.Query("admin"),
+		Action:     c.Query("action"),
+		TargetType: c.Query("target_type"),
+		TargetID:   c.Query("target_id"),
+	}
+
+	if v := c.Query("start_date"); v != "" {
+		start, err := time.ParseInLocation("2006-01-02", v, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid start_date, use YYYY-MM-DD"})
+			return
+		}
+		filter.Start = &start
+	}
+	if v := c.Query("end_date"); v != "" {
+		end, err := time.ParseInLocation("2006-01-02", v, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid end_date, use YYYY-MM-DD"})
+			return
+		}
+		end = end.AddDate(0, 0, 1)
+		filter.End = &end
+	}
+
+	filter.Page, _ = strconv.Atoi(c.DefaultQuery("page", "1"))
+	filter.PageSize, _ = strconv.Atoi(c.DefaultQuery("page_size", "50"))
+
+	logs, total := services.QueryAuditLogs(filter)
+
+	c.JSON(http.StatusOK, gin.H{
+		"logs":  logs,
+		"total": total,
+		"page":  filter.Page,
+	})
+}
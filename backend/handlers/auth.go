@@ -3,14 +3,21 @@ package handlers
 import (
 	"crypto/rand"
 	"encoding/hex"
+	"errors"
 	"expchange-backend/config"
 	"expchange-backend/database"
 	"expchange-backend/middleware"
 	"expchange-backend/models"
+	"expchange-backend/pkg/siwe"
+	"expchange-backend/services"
+	"expchange-backend/utils"
+	"log"
 	"net/http"
+	"regexp"
 	"strings"
 	"time"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"gorm.io/gorm"
@@ -18,20 +25,31 @@ import (
 )
 
 type AuthHandler struct {
-	cfg *config.Config
+	cfg                   *config.Config
+	referralService       *services.ReferralService
+	contractWalletService *services.ContractWalletService
+	sessionService        *services.SessionService
 }
 
 func NewAuthHandler(cfg *config.Config) *AuthHandler {
-	return &AuthHandler{cfg: cfg}
+	return &AuthHandler{
+		cfg:                   cfg,
+		referralService:       services.NewReferralService(),
+		contractWalletService: services.NewContractWalletService(),
+		sessionService:        services.NewSessionService(cfg.RefreshTokenTTL),
+	}
 }
 
 type NonceRequest struct {
 	WalletAddress string `json:"wallet_address" binding:"required"`
+	ChainID       int    `json:"chain_id"`      // 钱包当前链ID（可选，默认1）
+	ReferralCode  string `json:"referral_code"` // 邀请码（可选，写入登录消息，首次登录成功后绑定）
 }
 
 type LoginRequest struct {
 	WalletAddress string `json:"wallet_address" binding:"required"`
 	Signature     string `json:"signature" binding:"required"`
+	ReferralCode  string `json:"referral_code"` // 邀请码（可选，仅首次登录时绑定）
 }
 
 func (h *AuthHandler) GetNonce(c *gin.Context) {
@@ -41,20 +59,58 @@ func (h *AuthHandler) GetNonce(c *gin.Context) {
 		return
 	}
 
+	if !common.IsHexAddress(req.WalletAddress) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid wallet address"})
+		return
+	}
+
 	// 转换为小写
 	walletAddress := strings.ToLower(req.WalletAddress)
 
+	// 系统账户（做市商、平台手续费账户）不允许登录
+	if services.IsSystemWallet(walletAddress) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "System account cannot login"})
+		return
+	}
+
 	var user models.User
 	// 使用静默模式查询，避免打印 "record not found" 日志
 	result := database.DB.Session(&gorm.Session{Logger: database.DB.Logger.LogMode(logger.Silent)}).
 		Where("wallet_address = ?", walletAddress).First(&user)
 
-	if result.Error != nil {
+	// 生成 SIWE (EIP-4361) 登录消息，nonce 一次性使用且有过期时间
+	chainID := req.ChainID
+	if chainID <= 0 {
+		chainID = 1
+	}
+	issuedAt := time.Now().UTC().Truncate(time.Second)
+	expiresAt := issuedAt.Add(h.cfg.SIWENonceTTL)
+	message := &siwe.Message{
+		Domain:         h.cfg.SIWEDomain,
+		Address:        walletAddress,
+		Statement:      h.cfg.SIWEStatement,
+		URI:            h.cfg.SIWEURI,
+		Version:        "1",
+		ChainID:        chainID,
+		Nonce:          generateNonce(),
+		IssuedAt:       issuedAt,
+		ExpirationTime: expiresAt,
+	}
+	// 邀请码写入登录消息随签名一起确认，登录成功后才绑定
+	newUser := result.Error != nil
+	if code := req.ReferralCode; code != "" && (newUser || user.LastLoginAt == nil) {
+		if referralCodePattern.MatchString(code) {
+			message.Resources = []string{referralResourcePrefix + code}
+		}
+	}
+
+	if newUser {
 		// 创建新用户
-		nonce := generateNonce()
 		user = models.User{
-			WalletAddress: walletAddress,
-			Nonce:         nonce,
+			WalletAddress:  walletAddress,
+			Nonce:          message.Nonce,
+			SignInMessage:  message.String(),
+			NonceExpiresAt: &expiresAt,
 		}
 		if err := database.DB.Create(&user).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
@@ -62,13 +118,20 @@ func (h *AuthHandler) GetNonce(c *gin.Context) {
 		}
 	} else {
 		// 更新nonce
-		nonce := generateNonce()
-		user.Nonce = nonce
+		user.Nonce = message.Nonce
+		user.SignInMessage = message.String()
+		user.NonceExpiresAt = &expiresAt
+		// 老用户补发邀请码
+		if user.ReferralCode == "" {
+			user.ReferralCode = utils.GenerateReferralCode()
+		}
 		database.DB.Save(&user)
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"nonce": user.Nonce,
+		"nonce":      user.Nonce,
+		"message":    user.SignInMessage,
+		"expires_at": expiresAt,
 	})
 }
 
@@ -82,29 +145,172 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	// 转换为小写
 	walletAddress := strings.ToLower(req.WalletAddress)
 
+	if services.IsSystemWallet(walletAddress) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "System account cannot login"})
+		return
+	}
+
 	var user models.User
 	if err := database.DB.Where("wallet_address = ?", walletAddress).First(&user).Error; err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
 		return
 	}
 
-	// 实际项目中应该验证签名
-	// 这里简化处理，跳过签名验证
-	// verifySignature(user.Nonce, req.Signature, req.WalletAddress)
+	// 校验 nonce 是否存在且未过期
+	if user.Nonce == "" || user.SignInMessage == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Nonce not found, please request a new one"})
+		return
+	}
+	if user.NonceExpiresAt == nil || time.Now().After(*user.NonceExpiresAt) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Nonce expired, please request a new one"})
+		return
+	}
+
+	// 校验签名（EIP-191 personal_sign，恢复签名者地址；不匹配时按合约钱包校验）
+	if err := h.verifySignature(user.SignInMessage, req.Signature, user.WalletAddress); err != nil {
+		log.Printf("⚠️  登录签名校验失败: Wallet=%s, %v", user.WalletAddress, err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid signature"})
+		return
+	}
+
+	// 消费 nonce（条件更新保证并发请求只有一个能成功）
+	consumed := database.DB.Model(&models.User{}).
+		Where("id = ? AND nonce = ?", user.ID, user.Nonce).
+		Updates(map[string]interface{}{
+			"nonce":            "",
+			"sign_in_message":  "",
+			"nonce_expires_at": nil,
+		})
+	if consumed.Error != nil || consumed.RowsAffected != 1 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Nonce already used, please request a new one"})
+		return
+	}
+
+	// 首次登录时绑定邀请人（优先使用已签名消息中的邀请码）
+	if user.LastLoginAt == nil {
+		code := signedReferralCode(user.SignInMessage)
+		if code == "" {
+			code = req.ReferralCode
+		}
+		h.bindReferrer(&user, code)
+	}
+
+	now := time.Now()
+	user.LastLoginAt = &now
+	database.DB.Model(&user).Update("last_login_at", now)
+
+	// 创建登录会话（刷新令牌存服务端）
+	session, refreshToken, err := h.sessionService.CreateSession(user.ID, c.Request.UserAgent(), c.ClientIP())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create session"})
+		return
+	}
 
 	// 生成JWT token
-	token, err := h.generateToken(user.ID, user.WalletAddress)
+	token, err := h.generateToken(user.ID, user.WalletAddress, session.ID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"token": token,
-		"user":  user,
+		"token":         token,
+		"refresh_token": refreshToken,
+		"expires_in":    int(h.cfg.AccessTokenTTL.Seconds()),
+		"user":          user,
 	})
 }
 
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// Refresh 使用刷新令牌换取新的访问令牌（刷新令牌同时轮换）
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	session, refreshToken, err := h.sessionService.Rotate(req.RefreshToken, c.Request.UserAgent(), c.ClientIP())
+	if err != nil {
+		// 不向客户端区分过期、吊销、重放等原因，详情只记录日志
+		log.Printf("⚠️  刷新令牌失败: IP=%s, %v", c.ClientIP(), err)
+		if isSessionError(err) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid refresh token"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh session"})
+		}
+		return
+	}
+
+	var user models.User
+	if err := database.DB.Where("id = ?", session.UserID).First(&user).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	token, err := h.generateToken(user.ID, user.WalletAddress, session.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"token":         token,
+		"refresh_token": refreshToken,
+		"expires_in":    int(h.cfg.AccessTokenTTL.Seconds()),
+	})
+}
+
+// isSessionError 刷新令牌本身无效（不存在、过期、吊销、重放），其余为服务端错误
+func isSessionError(err error) bool {
+	for _, target := range []error{
+		services.ErrSessionNotFound,
+		services.ErrSessionExpired,
+		services.ErrSessionRevoked,
+		services.ErrRefreshTokenReused,
+		services.ErrRefreshTokenInvalid,
+	} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// Logout 登出当前会话（访问令牌和刷新令牌同时失效）
+func (h *AuthHandler) Logout(c *gin.Context) {
+	sessionID := c.GetString("session_id")
+
+	if err := h.sessionService.Revoke(sessionID, services.SessionRevokeLogout); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to logout"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out"})
+}
+
+// GetSessions 获取当前用户的登录设备列表
+func (h *AuthHandler) GetSessions(c *gin.Context) {
+	userID := c.GetString("user_id")
+	currentID := c.GetString("session_id")
+
+	type sessionItem struct {
+		models.UserSession
+		Current bool `json:"current"`
+	}
+
+	sessions := h.sessionService.GetUserSessions(userID)
+	result := make([]sessionItem, 0, len(sessions))
+	for _, s := range sessions {
+		result = append(result, sessionItem{UserSession: s, Current: s.ID == currentID})
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
 func (h *AuthHandler) GetProfile(c *gin.Context) {
 	userID := c.GetString("user_id")
 
@@ -117,12 +323,13 @@ func (h *AuthHandler) GetProfile(c *gin.Context) {
 	c.JSON(http.StatusOK, user)
 }
 
-func (h *AuthHandler) generateToken(userID string, walletAddress string) (string, error) {
+func (h *AuthHandler) generateToken(userID, walletAddress, sessionID string) (string, error) {
 	claims := middleware.Claims{
 		UserID:        userID,
 		WalletAddress: walletAddress,
+		SessionID:     sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(h.cfg.AccessTokenTTL)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
@@ -131,9 +338,54 @@ func (h *AuthHandler) generateToken(userID string, walletAddress string) (string
 	return token.SignedString([]byte(h.cfg.JWTSecret))
 }
 
+// referralResourcePrefix 登录消息中携带邀请码的资源 URI 前缀
+const referralResourcePrefix = "urn:expchange:referral:"
+
+// referralCodePattern 邀请码只允许字母和数字（写入签名消息，不能包含换行等字符）
+var referralCodePattern = regexp.MustCompile(`^[A-Za-z0-9]{1,32}$`)
+
+// signedReferralCode 从已签名的登录消息中取出邀请码
+func signedReferralCode(message string) string {
+	for _, resource := range siwe.ParseResources(message) {
+		if code, ok := strings.CutPrefix(resource, referralResourcePrefix); ok && referralCodePattern.MatchString(code) {
+			return code
+		}
+	}
+	return ""
+}
+
+// bindReferrer 绑定邀请关系（邀请码无效时不影响登录）
+func (h *AuthHandler) bindReferrer(user *models.User, code string) {
+	if code == "" || user.ReferrerID != "" || !h.referralService.IsEnabled() {
+		return
+	}
+	if err := h.referralService.BindReferrer(database.DB, user, code); err != nil {
+		log.Printf("⚠️  绑定邀请人失败: UserID=%s, Code=%s, %v", user.ID, code, err)
+		return
+	}
+	log.Printf("🤝 邀请关系已绑定: UserID=%s, ReferrerID=%s", user.ID, user.ReferrerID)
+}
+
+// verifySignature 先按 EOA 校验签名，ecrecover 不匹配时回退到 EIP-1271 合约钱包校验
+func (h *AuthHandler) verifySignature(message, signature, address string) error {
+	err := siwe.VerifySignature(message, signature, address)
+	if err == nil {
+		return nil
+	}
+	// 合约钱包签名长度不固定（如 Safe 多签拼接），格式错误时同样尝试合约校验
+	if !errors.Is(err, siwe.ErrSignerMismatch) && !errors.Is(err, siwe.ErrInvalidSignature) {
+		return err
+	}
+
+	chainID, ok := siwe.ParseChainID(message)
+	if !ok {
+		return err
+	}
+	return h.contractWalletService.VerifySignature(chainID, message, signature, address)
+}
+
 func generateNonce() string {
 	b := make([]byte, 16)
 	rand.Read(b)
 	return hex.EncodeToString(b)
 }
-
@@ -1,23 +1,31 @@
 package handlers
 
 import (
+	"expchange-backend/config"
 	"expchange-backend/database"
 	"expchange-backend/models"
 	"expchange-backend/queue"
+	"expchange-backend/services"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/gin-gonic/gin"
 	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
 
-type BalanceHandler struct{}
+type BalanceHandler struct {
+	withdrawSecurity *services.WithdrawSecurityService
+}
 
-func NewBalanceHandler() *BalanceHandler {
-	return &BalanceHandler{}
+func NewBalanceHandler(cfg *config.Config) *BalanceHandler {
+	return &BalanceHandler{
+		withdrawSecurity: services.NewWithdrawSecurityService(cfg.SecretEncryptionKey),
+	}
 }
 
 func (h *BalanceHandler) GetBalances(c *gin.Context) {
@@ -108,10 +116,17 @@ func (h *BalanceHandler) Deposit(c *gin.Context) {
 		return
 	}
 
+	// 验证资产在该链开放充值
+	token, err := services.ValidateDepositAsset(chainConfig.ChainID, req.Asset, amount)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	// 创建充值记录（待验证状态）
 	deposit := models.DepositRecord{
 		UserID:  userID,
-		Asset:   req.Asset,
+		Asset:   token.Asset,
 		Amount:  amount,
 		TxHash:  strings.ToLower(req.TxHash),
 		Chain:   chainConfig.ChainName,
@@ -142,12 +157,57 @@ func (h *BalanceHandler) Deposit(c *gin.Context) {
 	})
 }
 
+// GetDepositAddress 获取用户在指定链的充值地址
+// 链配置了 xpub 时返回用户专属地址（首次请求时分配），否则返回平台共享充值地址（shared=true，需要提交交易hash）
+func (h *BalanceHandler) GetDepositAddress(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	chainID, err := strconv.Atoi(c.Query("chainId"))
+	if err != nil || chainID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Chain ID is required"})
+		return
+	}
+
+	var chainConfig models.ChainConfig
+	if err := database.DB.Where("chain_id = ? AND enabled = ?", chainID, true).First(&chainConfig).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Chain not found or disabled"})
+		return
+	}
+
+	if chainConfig.DepositXpub == "" {
+		c.JSON(http.StatusOK, gin.H{
+			"chain_id": chainConfig.ChainID,
+			"chain":    chainConfig.ChainName,
+			"address":  common.HexToAddress(chainConfig.PlatformDepositAddress).Hex(),
+			"shared":   true,
+		})
+		return
+	}
+
+	address, err := services.GetDepositAddress(userID, &chainConfig)
+	if err != nil {
+		log.Printf("❌ 分配充值地址失败: 用户ID=%s, Chain=%s, err=%v", userID, chainConfig.ChainName, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to allocate deposit address"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"chain_id": chainConfig.ChainID,
+		"chain":    chainConfig.ChainName,
+		"address":  common.HexToAddress(address.Address).Hex(),
+		"shared":   false,
+	})
+}
+
 type WithdrawRequest struct {
 	Asset   string `json:"asset" binding:"required"`
 	Amount  string `json:"amount" binding:"required"`
 	Address string `json:"address" binding:"required"`
 	Chain   string `json:"chain"`   // bsc, sepolia
 	ChainID int    `json:"chainId"` // 链ID
+
+	TOTPCode  string `json:"totp_code"`  // 已启用 TOTP 时必填
+	EmailCode string `json:"email_code"` // 未启用 TOTP、已验证邮箱时必填
 }
 
 // Withdraw 创建提现申请并冻结资金
@@ -181,6 +241,21 @@ func (h *BalanceHandler) Withdraw(c *gin.Context) {
 		return
 	}
 
+	// 提现安全检查：锁定期、地址白名单、二次验证（API 密钥只能提现到地址簿）
+	viaAPIKey := c.GetString("api_key_id") != ""
+	if err := h.withdrawSecurity.CheckWithdrawal(&user, req.Address, viaAPIKey, req.TOTPCode, req.EmailCode); err != nil {
+		log.Printf("⚠️  提现安全检查未通过: UserID=%s, Address=%s, %v", userID, req.Address, err)
+		c.JSON(securityErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	// 风控评分：低风险自动通过，其余进入人工审核
+	risk := services.EvaluateWithdrawRisk(&user, req.ChainID, req.Asset, req.Address, amount)
+	status := "pending"
+	if risk.RequiresReview {
+		status = "pending_review"
+	}
+
 	// 开始事务
 	tx := database.DB.Begin()
 	defer func() {
@@ -228,15 +303,33 @@ func (h *BalanceHandler) Withdraw(c *gin.Context) {
 		return
 	}
 
-	// 创建提现记录（待处理状态）
+	// 提现手续费和最小提现额（手续费从申请金额中扣除）
+	fee, err := services.CalculateWithdrawFee(chainConfig.ChainID, req.Asset, amount)
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 验证资产在该链开放提现
+	if _, err := services.ValidateWithdrawAsset(chainConfig.ChainID, req.Asset, amount, fee); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 创建提现记录（待处理或待审核状态）
 	withdrawal := models.WithdrawRecord{
-		UserID:  userID,
-		Asset:   req.Asset,
-		Amount:  amount,
-		Address: strings.ToLower(req.Address),
-		Chain:   chainConfig.ChainName,
-		ChainID: chainConfig.ChainID,
-		Status:  "pending",
+		UserID:    userID,
+		Asset:     req.Asset,
+		Amount:    amount,
+		Fee:       fee,
+		Address:   strings.ToLower(req.Address),
+		Chain:     chainConfig.ChainName,
+		ChainID:   chainConfig.ChainID,
+		Status:    status,
+		RiskScore: risk.Score,
+		RiskFlags: strings.Join(risk.Flags, ","),
 	}
 
 	if err := tx.Create(&withdrawal).Error; err != nil {
@@ -251,22 +344,36 @@ func (h *BalanceHandler) Withdraw(c *gin.Context) {
 		return
 	}
 
-	// 创建提现处理任务
+	if withdrawal.Status == "pending_review" {
+		log.Printf("🛡️  提现进入风控审核: WithdrawID=%s, 评分=%d, 规则=%s", withdrawal.ID, risk.Score, withdrawal.RiskFlags)
+		c.JSON(http.StatusOK, gin.H{
+			"message":    "Withdrawal request submitted for review",
+			"withdrawal": withdrawal,
+		})
+		return
+	}
+
+	enqueueWithdrawal(&withdrawal)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Withdrawal request submitted",
+		"withdrawal": withdrawal,
+	})
+}
+
+// enqueueWithdrawal 创建提现处理任务（只有待处理状态的提现才能进入队列）
+func enqueueWithdrawal(withdrawal *models.WithdrawRecord) {
 	taskQueue := queue.GetQueue()
 	task, err := taskQueue.AddWithdrawTask(withdrawal.ID)
 	if err != nil {
 		log.Printf("❌ 创建提现处理任务失败: %v", err)
 		// 不影响提现记录创建，只记录日志
-	} else {
-		// 更新提现记录，关联任务ID
-		database.DB.Model(&withdrawal).Update("task_id", task.ID)
-		log.Printf("✅ 提现处理任务已创建: TaskID=%s, WithdrawID=%s", task.ID, withdrawal.ID)
+		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message":    "Withdrawal request submitted",
-		"withdrawal": withdrawal,
-	})
+	// 更新提现记录，关联任务ID
+	database.DB.Model(withdrawal).Update("task_id", task.ID)
+	log.Printf("✅ 提现处理任务已创建: TaskID=%s, WithdrawID=%s", task.ID, withdrawal.ID)
 }
 
 // GetDepositRecords 获取充值记录
@@ -1,33 +1,242 @@
 package handlers
 
 import (
+	"errors"
 	"expchange-backend/database"
 	"expchange-backend/models"
+	"expchange-backend/services"
 	"net/http"
+	"strings"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
 )
 
 type ChainHandler struct{}
 
+// chainRequest 创建/更新链配置请求（私钥、keystore 密码、签名服务令牌只写：加密后保存，任何接口都不返回）
+type chainRequest struct {
+	models.ChainConfig
+	PlatformWithdrawPrivateKey string `json:"platform_withdraw_private_key"`
+	SignerKeystorePassword     string `json:"signer_keystore_password"`
+	SignerRemoteAuthToken      string `json:"signer_remote_auth_token"`
+	DepositSweepKey            string `json:"deposit_sweep_key"`
+}
+
+// applySignerConfig 校验并保存提现签名配置（敏感字段为空时保持原值），同时确定提现地址
+func applySignerConfig(chain *models.ChainConfig, req *chainRequest) error {
+	vault, err := services.GetWalletKeyVault()
+	if err != nil {
+		return err
+	}
+
+	chain.SignerType = req.SignerType
+	if chain.SignerType == "" {
+		chain.SignerType = models.SignerTypeLocal
+	}
+	chain.SignerKeystorePath = req.SignerKeystorePath
+	chain.SignerRemoteURL = req.SignerRemoteURL
+	chain.SignerRemoteMethod = req.SignerRemoteMethod
+
+	if req.PlatformWithdrawPrivateKey != "" {
+		sealed, _, err := vault.SealPrivateKey(req.PlatformWithdrawPrivateKey)
+		if err != nil {
+			return err
+		}
+		chain.PlatformWithdrawPrivateKey = sealed
+	}
+	if req.SignerKeystorePassword != "" {
+		if chain.SignerKeystorePassword, err = vault.SealSecret(req.SignerKeystorePassword); err != nil {
+			return err
+		}
+	}
+	if req.SignerRemoteAuthToken != "" {
+		if chain.SignerRemoteAuthToken, err = vault.SealSecret(req.SignerRemoteAuthToken); err != nil {
+			return err
+		}
+	}
+
+	switch chain.SignerType {
+	case models.SignerTypeLocal:
+		chain.PlatformWithdrawAddress = ""
+		if chain.PlatformWithdrawPrivateKey == "" {
+			return nil
+		}
+	case models.SignerTypeKeystore:
+		if chain.SignerKeystorePath == "" {
+			return errors.New("signer_keystore_path is required for keystore signer")
+		}
+	case models.SignerTypeRemote:
+		if chain.SignerRemoteURL == "" {
+			return errors.New("signer_remote_url is required for remote signer")
+		}
+		if !common.IsHexAddress(req.PlatformWithdrawAddress) {
+			return errors.New("platform_withdraw_address is required for remote signer")
+		}
+		chain.PlatformWithdrawAddress = common.HexToAddress(req.PlatformWithdrawAddress).Hex()
+		return nil
+	default:
+		return services.ErrInvalidSignerType
+	}
+
+	// 本地私钥和 keystore：加载一次签名器，校验配置可用并得到提现地址
+	txSigner, err := vault.SignerForChain(chain)
+	if err != nil {
+		return err
+	}
+	chain.PlatformWithdrawAddress = txSigner.Address().Hex()
+	return nil
+}
+
+// applyGasConfig 校验并保存提现交易类型和 gas 上限
+func applyGasConfig(chain *models.ChainConfig, req *chainRequest) error {
+	txType, err := services.ValidateTxType(req.TxType)
+	if err != nil {
+		return err
+	}
+	if req.MaxGasPriceGwei.IsNegative() || req.MaxPriorityFeeGwei.IsNegative() {
+		return errors.New("gas caps must not be negative")
+	}
+
+	chain.TxType = txType
+	chain.MaxGasPriceGwei = req.MaxGasPriceGwei
+	chain.MaxPriorityFeeGwei = req.MaxPriorityFeeGwei
+	chain.MaxGasLimit = req.MaxGasLimit
+	return nil
+}
+
+// applyMultisendConfig 校验并保存批量提现合约地址（为空表示该链不使用批量提现）
+func applyMultisendConfig(chain *models.ChainConfig, req *chainRequest) error {
+	if req.MultisendContractAddress == "" {
+		chain.MultisendContractAddress = ""
+		return nil
+	}
+	if !common.IsHexAddress(req.MultisendContractAddress) {
+		return errors.New("invalid multisend_contract_address")
+	}
+	chain.MultisendContractAddress = common.HexToAddress(req.MultisendContractAddress).Hex()
+	return nil
+}
+
+// applyColdWalletConfig 校验并保存冷钱包地址（只用于余额监控，为空表示不监控冷钱包）
+func applyColdWalletConfig(chain *models.ChainConfig, req *chainRequest) error {
+	if req.ColdWalletAddress == "" {
+		chain.ColdWalletAddress = ""
+		return nil
+	}
+	if !common.IsHexAddress(req.ColdWalletAddress) {
+		return errors.New("invalid cold_wallet_address")
+	}
+	chain.ColdWalletAddress = common.HexToAddress(req.ColdWalletAddress).Hex()
+	return nil
+}
+
+// applyDepositAddressConfig 校验并保存用户充值地址的 xpub 和归集用扩展私钥（私钥为空时保持原值）
+// 已分配充值地址后不允许修改 xpub，否则已分配的地址无法再派生私钥归集
+func applyDepositAddressConfig(chain *models.ChainConfig, req *chainRequest, previousXpub string) error {
+	xpub := strings.TrimSpace(req.DepositXpub)
+	if xpub != "" {
+		key, err := services.ParseDepositXpub(xpub)
+		if err != nil {
+			return err
+		}
+		xpub = key.String()
+	}
+
+	if xpub != previousXpub {
+		var count int64
+		database.DB.Model(&models.DepositAddress{}).Where("chain_id = ?", chain.ChainID).Count(&count)
+		if count > 0 {
+			return services.ErrDepositXpubLocked
+		}
+		// 原归集私钥与新 xpub 不对应
+		chain.DepositSweepKey = ""
+	}
+	chain.DepositXpub = xpub
+
+	if req.DepositSweepKey == "" {
+		return nil
+	}
+	if xpub == "" {
+		return errors.New("deposit_xpub is required when deposit_sweep_key is set")
+	}
+	vault, err := services.GetWalletKeyVault()
+	if err != nil {
+		return err
+	}
+	chain.DepositSweepKey, err = vault.SealDepositSweepKey(req.DepositSweepKey, xpub)
+	return err
+}
+
 func NewChainHandler() *ChainHandler {
 	return &ChainHandler{}
 }
 
+// chainResponse 链配置及其资产和提现手续费（管理端另含 RPC 节点）
+type chainResponse struct {
+	models.ChainConfig
+	Tokens       []models.ChainToken       `json:"tokens"`
+	WithdrawFees []models.WithdrawFee      `json:"withdraw_fees"`
+	RpcEndpoints []models.ChainRpcEndpoint `json:"rpc_endpoints,omitempty"`
+}
+
+func withWithdrawFees(chains []models.ChainConfig) []chainResponse {
+	fees := services.GetWithdrawFeesByChain()
+	tokens := services.GetChainTokensByChain()
+	result := make([]chainResponse, 0, len(chains))
+	for _, chain := range chains {
+		chainFees := fees[chain.ChainID]
+		if chainFees == nil {
+			chainFees = []models.WithdrawFee{}
+		}
+		chainTokens := tokens[chain.ChainID]
+		if chainTokens == nil {
+			chainTokens = []models.ChainToken{}
+		}
+		result = append(result, chainResponse{ChainConfig: chain, Tokens: chainTokens, WithdrawFees: chainFees})
+	}
+	return result
+}
+
 // GetChains 获取所有链配置（包括禁用的）
 func (h *ChainHandler) GetChains(c *gin.Context) {
 	var chains []models.ChainConfig
 	database.DB.Order("chain_id ASC").Find(&chains)
-	c.JSON(http.StatusOK, chains)
+
+	result := withWithdrawFees(chains)
+	endpoints := services.GetChainRpcEndpointsByChain()
+	for i := range result {
+		result[i].RpcEndpoints = endpoints[result[i].ChainID]
+		if result[i].RpcEndpoints == nil {
+			result[i].RpcEndpoints = []models.ChainRpcEndpoint{}
+		}
+	}
+	c.JSON(http.StatusOK, result)
 }
 
-// GetEnabledChains 获取启用的链配置
+// GetEnabledChains 获取启用的链配置（含资产、提现手续费和最小提现额）
 func (h *ChainHandler) GetEnabledChains(c *gin.Context) {
 	var chains []models.ChainConfig
 	database.DB.Where("enabled = ?", true).
 		Order("chain_id ASC").
 		Find(&chains)
-	c.JSON(http.StatusOK, chains)
+
+	// 冷钱包地址、热钱包余额阈值、风控阈值和暂停原因只在管理端展示，用户只看到提现是否暂停
+	result := withWithdrawFees(chains)
+	for i := range result {
+		result[i].ColdWalletAddress = ""
+		for j := range result[i].Tokens {
+			token := &result[i].Tokens[j]
+			token.HotAlertBalance = decimal.Zero
+			token.HotPauseBalance = decimal.Zero
+			token.RiskLargeAmount = decimal.Zero
+			token.RiskDailyLimit = decimal.Zero
+			token.PauseReason = ""
+		}
+	}
+	c.JSON(http.StatusOK, result)
 }
 
 // GetChain 获取单个链配置
@@ -45,8 +254,34 @@ func (h *ChainHandler) GetChain(c *gin.Context) {
 
 // CreateChain 创建链配置（管理员）- 注意：链名称和Chain ID创建后不可修改
 func (h *ChainHandler) CreateChain(c *gin.Context) {
-	var chain models.ChainConfig
-	if err := c.ShouldBindJSON(&chain); err != nil {
+	var req chainRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	chain := req.ChainConfig
+	if err := services.ValidateRpcURL(chain.RpcURL); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := applySignerConfig(&chain, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := applyGasConfig(&chain, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := applyMultisendConfig(&chain, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := applyColdWalletConfig(&chain, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := applyDepositAddressConfig(&chain, &req, ""); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -74,6 +309,10 @@ func (h *ChainHandler) CreateChain(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create chain"})
 		return
 	}
+	if err := services.SyncUsdtToken(database.DB, &chain); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to register USDT for chain"})
+		return
+	}
 
 	c.JSON(http.StatusOK, chain)
 }
@@ -88,25 +327,58 @@ func (h *ChainHandler) UpdateChain(c *gin.Context) {
 		return
 	}
 
-	var req models.ChainConfig
+	var req chainRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	if err := services.ValidateRpcURL(req.RpcURL); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	// 更新字段（ChainName和ChainID不可修改）
 	chain.RpcURL = req.RpcURL
 	chain.BlockExplorerURL = req.BlockExplorerURL
 	chain.UsdtContractAddress = req.UsdtContractAddress
 	chain.UsdtDecimals = req.UsdtDecimals
 	chain.PlatformDepositAddress = req.PlatformDepositAddress
-	
-	// 只在提供了私钥且不为空时更新
-	if req.PlatformWithdrawPrivateKey != "" {
-		chain.PlatformWithdrawPrivateKey = req.PlatformWithdrawPrivateKey
+	if req.WithdrawConfirmations > 0 {
+		chain.WithdrawConfirmations = req.WithdrawConfirmations
+	}
+	if req.RequiredConfirmations > 0 {
+		chain.RequiredConfirmations = req.RequiredConfirmations
 	}
 
-	if err := database.DB.Save(&chain).Error; err != nil {
+	// 私钥等敏感字段只在提供了新值时更新
+	if err := applySignerConfig(&chain, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := applyGasConfig(&chain, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := applyMultisendConfig(&chain, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := applyColdWalletConfig(&chain, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := applyDepositAddressConfig(&chain, &req, chain.DepositXpub); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&chain).Error; err != nil {
+			return err
+		}
+		return services.SyncUsdtToken(tx, &chain)
+	}); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update chain"})
 		return
 	}
@@ -175,5 +447,211 @@ func (h *ChainHandler) DeleteChain(c *gin.Context) {
 	}
 
 	database.DB.Delete(&chain)
+	database.DB.Where("chain_id = ?", chain.ChainID).Delete(&models.WithdrawFee{})
+	database.DB.Where("chain_id = ?", chain.ChainID).Delete(&models.ChainToken{})
+	database.DB.Where("chain_id = ?", chain.ChainID).Delete(&models.ChainRpcEndpoint{})
 	c.JSON(http.StatusOK, gin.H{"message": "Chain deleted successfully"})
 }
+
+type withdrawFeeRequest struct {
+	Asset     string `json:"asset" binding:"required"`
+	Fee       string `json:"fee" binding:"required"`
+	MinAmount string `json:"min_amount" binding:"required"`
+}
+
+// UpsertWithdrawFee 设置链上资产的提现手续费和最小提现额（管理员）
+func (h *ChainHandler) UpsertWithdrawFee(c *gin.Context) {
+	var chain models.ChainConfig
+	if err := database.DB.Where("id = ?", c.Param("id")).First(&chain).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Chain not found"})
+		return
+	}
+
+	var req withdrawFeeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	fee, err := decimal.NewFromString(req.Fee)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid fee"})
+		return
+	}
+	minAmount, err := decimal.NewFromString(req.MinAmount)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid min_amount"})
+		return
+	}
+
+	config, err := services.UpsertWithdrawFee(chain.ChainID, req.Asset, fee, minAmount)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, config)
+}
+
+// DeleteWithdrawFee 删除链上资产的提现手续费配置（管理员）
+func (h *ChainHandler) DeleteWithdrawFee(c *gin.Context) {
+	var chain models.ChainConfig
+	if err := database.DB.Where("id = ?", c.Param("id")).First(&chain).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Chain not found"})
+		return
+	}
+
+	if err := services.DeleteWithdrawFee(chain.ChainID, c.Param("asset")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Withdraw fee deleted"})
+}
+
+type chainTokenRequest struct {
+	Asset            string `json:"asset" binding:"required"`
+	ContractAddress  string `json:"contract_address"` // 为空表示原生币
+	Decimals         *int   `json:"decimals" binding:"required"`
+	DepositEnabled   bool   `json:"deposit_enabled"`
+	WithdrawEnabled  bool   `json:"withdraw_enabled"`
+	MinDepositAmount string `json:"min_deposit_amount"`
+	HotAlertBalance  string `json:"hot_alert_balance"` // 热钱包余额告警值，为空表示 0
+	HotPauseBalance  string `json:"hot_pause_balance"` // 热钱包余额暂停提现值，为空表示 0
+	RiskLargeAmount  string `json:"risk_large_amount"` // 风控单笔大额阈值，为空表示 0
+	RiskDailyLimit   string `json:"risk_daily_limit"`  // 风控 24 小时累计限额，为空表示 0
+}
+
+// UpsertChainToken 设置链上资产（合约、精度、充值提现开关、最小充值额）（管理员）
+func (h *ChainHandler) UpsertChainToken(c *gin.Context) {
+	var chain models.ChainConfig
+	if err := database.DB.Where("id = ?", c.Param("id")).First(&chain).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Chain not found"})
+		return
+	}
+
+	var req chainTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	amounts := make(map[string]decimal.Decimal, 5)
+	for field, value := range map[string]string{
+		"min_deposit_amount": req.MinDepositAmount,
+		"hot_alert_balance":  req.HotAlertBalance,
+		"hot_pause_balance":  req.HotPauseBalance,
+		"risk_large_amount":  req.RiskLargeAmount,
+		"risk_daily_limit":   req.RiskDailyLimit,
+	} {
+		amounts[field] = decimal.Zero
+		if value == "" {
+			continue
+		}
+		amount, err := decimal.NewFromString(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + field})
+			return
+		}
+		amounts[field] = amount
+	}
+
+	token, err := services.UpsertChainToken(&chain, models.ChainToken{
+		Asset:            req.Asset,
+		ContractAddress:  req.ContractAddress,
+		Decimals:         *req.Decimals,
+		DepositEnabled:   req.DepositEnabled,
+		WithdrawEnabled:  req.WithdrawEnabled,
+		MinDepositAmount: amounts["min_deposit_amount"],
+		HotAlertBalance:  amounts["hot_alert_balance"],
+		HotPauseBalance:  amounts["hot_pause_balance"],
+		RiskLargeAmount:  amounts["risk_large_amount"],
+		RiskDailyLimit:   amounts["risk_daily_limit"],
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, token)
+}
+
+// DeleteChainToken 删除链上资产（管理员）
+func (h *ChainHandler) DeleteChainToken(c *gin.Context) {
+	var chain models.ChainConfig
+	if err := database.DB.Where("id = ?", c.Param("id")).First(&chain).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Chain not found"})
+		return
+	}
+
+	if err := services.DeleteChainToken(chain.ChainID, c.Param("asset")); err != nil {
+		status := http.StatusNotFound
+		if errors.Is(err, services.ErrUsdtTokenRequired) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Chain token deleted"})
+}
+
+type rpcEndpointRequest struct {
+	URL      string `json:"url" binding:"required"`
+	Priority int    `json:"priority"`
+	Weight   int    `json:"weight"`
+	Enabled  *bool  `json:"enabled"`
+}
+
+// UpsertRpcEndpoint 按 URL 设置链的 RPC 节点（优先级、权重、启用）（管理员）
+func (h *ChainHandler) UpsertRpcEndpoint(c *gin.Context) {
+	var chain models.ChainConfig
+	if err := database.DB.Where("id = ?", c.Param("id")).First(&chain).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Chain not found"})
+		return
+	}
+
+	var req rpcEndpointRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Weight == 0 {
+		req.Weight = 1
+	}
+	enabled := req.Enabled == nil || *req.Enabled
+
+	endpoint, err := services.UpsertChainRpcEndpoint(&chain, models.ChainRpcEndpoint{
+		URL:      req.URL,
+		Priority: req.Priority,
+		Weight:   req.Weight,
+		Enabled:  enabled,
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, endpoint)
+}
+
+// DeleteRpcEndpoint 删除链的 RPC 节点（管理员）
+func (h *ChainHandler) DeleteRpcEndpoint(c *gin.Context) {
+	var chain models.ChainConfig
+	if err := database.DB.Where("id = ?", c.Param("id")).First(&chain).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Chain not found"})
+		return
+	}
+
+	if err := services.DeleteChainRpcEndpoint(chain.ChainID, c.Param("endpoint")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "RPC endpoint deleted"})
+}
+
+// GetRpcHealth 各启用链的 RPC 节点健康状态（状态、区块高度、落后块数、熔断）（管理员）
+func (h *ChainHandler) GetRpcHealth(c *gin.Context) {
+	c.JSON(http.StatusOK, services.GetRpcHealth())
+}
@@ -1,21 +1,30 @@
 package handlers
 
 import (
+	"encoding/csv"
 	"expchange-backend/database"
 	"expchange-backend/models"
+	"expchange-backend/queue"
 	"expchange-backend/services"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
 )
 
 type FeeHandler struct {
-	feeService *services.FeeService
+	feeService      *services.FeeService
+	treasuryService *services.TreasuryService
 }
 
 func NewFeeHandler() *FeeHandler {
 	return &FeeHandler{
-		feeService: services.NewFeeService(),
+		feeService:      services.NewFeeService(),
+		treasuryService: services.NewTreasuryService(),
 	}
 }
 
@@ -79,3 +88,172 @@ func (h *FeeHandler) UpdateUserLevel(c *gin.Context) {
 	c.JSON(http.StatusOK, user)
 }
 
+// 管理员：手续费收入报表（支持 CSV 导出）
+// 参数：group_by=day|pair|asset|user_level, start_date/end_date=YYYY-MM-DD（含结束日）, format=csv
+func (h *FeeHandler) GetFeeRevenue(c *gin.Context) {
+	groupBy := c.DefaultQuery("group_by", "day")
+
+	var start, end *time.Time
+	if value := c.Query("start_date"); value != "" {
+		t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid start_date format, use YYYY-MM-DD"})
+			return
+		}
+		start = &t
+	}
+	if value := c.Query("end_date"); value != "" {
+		t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid end_date format, use YYYY-MM-DD"})
+			return
+		}
+		t = t.AddDate(0, 0, 1)
+		end = &t
+	}
+
+	rows, err := h.feeService.GetRevenueReport(groupBy, start, end)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if c.Query("format") == "csv" {
+		writeFeeRevenueCSV(c, groupBy, rows)
+		return
+	}
+
+	// 按资产汇总
+	totals := make(map[string]decimal.Decimal)
+	for _, row := range rows {
+		totals[row.Asset] = totals[row.Asset].Add(row.NetRevenue)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"group_by":     groupBy,
+		"rows":         rows,
+		"net_by_asset": totals,
+	})
+}
+
+// writeFeeRevenueCSV 导出手续费收入报表为 CSV
+func writeFeeRevenueCSV(c *gin.Context, groupBy string, rows []services.FeeRevenueRow) {
+	filename := fmt.Sprintf("fee_revenue_%s_%s.csv", groupBy, time.Now().Format("20060102150405"))
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+
+	keyHeader := map[string]string{
+		"day":        "day",
+		"pair":       "symbol",
+		"user_level": "user_level",
+	}[groupBy]
+
+	w := csv.NewWriter(c.Writer)
+	header := []string{"asset", "total_fee", "commission", "net_revenue", "fee_count"}
+	if keyHeader != "" {
+		header = append([]string{keyHeader}, header...)
+	}
+	w.Write(header)
+
+	for _, row := range rows {
+		record := []string{
+			row.Asset,
+			row.TotalFee.String(),
+			row.Commission.String(),
+			row.NetRevenue.String(),
+			strconv.FormatInt(row.FeeCount, 10),
+		}
+		if keyHeader != "" {
+			key := strings.Join([]string{row.Day, row.Symbol, row.UserLevel}, "")
+			record = append([]string{key}, record...)
+		}
+		w.Write(record)
+	}
+	w.Flush()
+}
+
+// 管理员：查看平台手续费账户和国库账户余额
+func (h *FeeHandler) GetTreasury(c *gin.Context) {
+	overview, err := h.treasuryService.GetOverview()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, overview)
+}
+
+// 管理员：更新国库配置（热更新）
+func (h *FeeHandler) UpdateTreasuryConfig(c *gin.Context) {
+	var req struct {
+		Address        *string `json:"address"`          // 国库账户钱包地址
+		SweepEnabled   *bool   `json:"sweep_enabled"`    // 是否每日自动归集
+		MinSweepAmount *string `json:"min_sweep_amount"` // 最小归集金额
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	sysConfig := database.GetSystemConfigManager()
+
+	if req.Address != nil {
+		address := strings.ToLower(strings.TrimSpace(*req.Address))
+		if !strings.HasPrefix(address, "0x") || len(address) != 42 || services.IsSystemWallet(address) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid treasury address"})
+			return
+		}
+		if err := sysConfig.Upsert("treasury.account.address", address, "国库账户钱包地址（手续费归集目标）", "treasury", "string"); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update treasury address"})
+			return
+		}
+	}
+
+	if req.MinSweepAmount != nil {
+		amount, err := decimal.NewFromString(*req.MinSweepAmount)
+		if err != nil || amount.IsNegative() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid min_sweep_amount"})
+			return
+		}
+		if err := sysConfig.Upsert("treasury.sweep.min_amount", amount.String(), "单资产最小归集金额", "treasury", "number"); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update min_sweep_amount"})
+			return
+		}
+	}
+
+	if req.SweepEnabled != nil {
+		value := "false"
+		if *req.SweepEnabled {
+			value = "true"
+		}
+		if err := sysConfig.Upsert("treasury.sweep.enabled", value, "是否每日自动归集手续费到国库", "treasury", "boolean"); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update sweep_enabled"})
+			return
+		}
+	}
+
+	h.GetTreasury(c)
+}
+
+// 管理员：获取国库归集记录
+func (h *FeeHandler) GetTreasurySweeps(c *gin.Context) {
+	var sweeps []models.TreasurySweep
+	database.DB.Order("created_at DESC").Limit(500).Find(&sweeps)
+
+	c.JSON(http.StatusOK, sweeps)
+}
+
+// 管理员：手动触发国库归集
+func (h *FeeHandler) TriggerTreasurySweep(c *gin.Context) {
+	task, err := queue.GetQueue().AddTreasurySweepTask("admin:" + c.GetString("admin_username"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Treasury sweep task created",
+		"task_id": task.ID,
+	})
+}
@@ -0,0 +1,232 @@
+package handlers
+
+import (
+	"expchange-backend/database"
+	"expchange-backend/models"
+	"expchange-backend/queue"
+	"expchange-backend/services"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+)
+
+type ReferralHandler struct {
+	referralService *services.ReferralService
+}
+
+func NewReferralHandler() *ReferralHandler {
+	return &ReferralHandler{
+		referralService: services.NewReferralService(),
+	}
+}
+
+// GetReferralStats 获取我的邀请码和返佣统计
+func (h *ReferralHandler) GetReferralStats(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	var user models.User
+	if err := database.DB.Where("id = ?", userID).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, h.referralService.GetStats(&user))
+}
+
+// GetInvitees 获取我邀请的用户列表
+func (h *ReferralHandler) GetInvitees(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	var invitees []models.User
+	database.DB.Where("referrer_id = ?", userID).
+		Order("referred_at DESC").
+		Limit(100).
+		Find(&invitees)
+
+	type invitee struct {
+		ID            string     `json:"id"`
+		WalletAddress string     `json:"wallet_address"`
+		ReferredAt    *time.Time `json:"referred_at"`
+	}
+
+	result := make([]invitee, 0, len(invitees))
+	for _, u := range invitees {
+		result = append(result, invitee{
+			ID:            u.ID,
+			WalletAddress: maskWalletAddress(u.WalletAddress),
+			ReferredAt:    u.ReferredAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// GetCommissions 获取我的返佣到账记录
+func (h *ReferralHandler) GetCommissions(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	var commissions []models.ReferralCommission
+	database.DB.Where("referrer_id = ?", userID).
+		Order("created_at DESC").
+		Limit(100).
+		Find(&commissions)
+
+	c.JSON(http.StatusOK, commissions)
+}
+
+// 管理员：获取返佣配置
+func (h *ReferralHandler) GetReferralConfig(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"enabled": h.referralService.IsEnabled(),
+		"rate":    h.referralService.GetDefaultRate(),
+		"mode":    h.referralService.GetMode(),
+	})
+}
+
+// 管理员：更新返佣配置（热更新）
+func (h *ReferralHandler) UpdateReferralConfig(c *gin.Context) {
+	var req struct {
+		Enabled *bool   `json:"enabled"`
+		Rate    *string `json:"rate"` // 0-1，例如 0.2 表示返还20%手续费
+		Mode    *string `json:"mode"` // realtime, daily
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	sysConfig := database.GetSystemConfigManager()
+
+	if req.Rate != nil {
+		rate, err := decimal.NewFromString(*req.Rate)
+		if err != nil || rate.IsNegative() || rate.GreaterThan(decimal.NewFromInt(1)) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "rate must be between 0 and 1"})
+			return
+		}
+		if err := sysConfig.Upsert("referral.commission.rate", rate.String(), "邀请返佣比例(占被邀请人手续费)", "referral", "number"); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update rate"})
+			return
+		}
+	}
+
+	if req.Mode != nil {
+		if *req.Mode != services.ReferralModeRealtime && *req.Mode != services.ReferralModeDaily {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "mode must be realtime or daily"})
+			return
+		}
+		if err := sysConfig.Upsert("referral.settlement.mode", *req.Mode, "返佣结算模式(realtime/daily)", "referral", "string"); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update mode"})
+			return
+		}
+	}
+
+	if req.Enabled != nil {
+		value := "false"
+		if *req.Enabled {
+			value = "true"
+		}
+		if err := sysConfig.Upsert("referral.enabled", value, "是否启用邀请返佣", "referral", "boolean"); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update status"})
+			return
+		}
+	}
+
+	h.GetReferralConfig(c)
+}
+
+// 管理员：设置用户个人返佣比例（rate 为空则恢复系统默认）
+func (h *ReferralHandler) UpdateUserReferralRate(c *gin.Context) {
+	userID := c.Param("id")
+
+	var req struct {
+		Rate string `json:"rate"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var user models.User
+	if err := database.DB.Where("id = ?", userID).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	rate := decimal.NullDecimal{}
+	if req.Rate != "" {
+		value, err := decimal.NewFromString(req.Rate)
+		if err != nil || value.IsNegative() || value.GreaterThan(decimal.NewFromInt(1)) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "rate must be between 0 and 1"})
+			return
+		}
+		rate = decimal.NullDecimal{Decimal: value, Valid: true}
+	}
+
+	user.ReferralRate = rate
+	database.DB.Model(&user).Update("referral_rate", rate)
+
+	c.JSON(http.StatusOK, user)
+}
+
+// 管理员：获取所有返佣记录
+func (h *ReferralHandler) GetAllCommissions(c *gin.Context) {
+	query := database.DB.Preload("Referee").Order("created_at DESC")
+
+	if referrerID := c.Query("referrer_id"); referrerID != "" {
+		query = query.Where("referrer_id = ?", referrerID)
+	}
+
+	var commissions []models.ReferralCommission
+	query.Limit(500).Find(&commissions)
+
+	c.JSON(http.StatusOK, commissions)
+}
+
+// 管理员：手动触发某一天的返佣结算
+func (h *ReferralHandler) SettleReferral(c *gin.Context) {
+	var req struct {
+		Date string `json:"date"` // 格式：2024-01-01，默认昨天
+	}
+
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	now := time.Now()
+	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()).AddDate(0, 0, -1)
+	if req.Date != "" {
+		date, err := time.ParseInLocation("2006-01-02", req.Date, now.Location())
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format, use YYYY-MM-DD"})
+			return
+		}
+		start = date
+	}
+	end := start.AddDate(0, 0, 1)
+
+	task, err := queue.GetQueue().AddReferralSettlementTask(start, end)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Referral settlement task created",
+		"task_id": task.ID,
+	})
+}
+
+// maskWalletAddress 钱包地址脱敏：0x1234...abcd
+func maskWalletAddress(address string) string {
+	if len(address) < 10 {
+		return address
+	}
+	return address[:6] + "..." + address[len(address)-4:]
+}
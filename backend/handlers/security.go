@@ -0,0 +1,287 @@
+package handlers
+
+import (
+	"errors"
+	"expchange-backend/config"
+	"expchange-backend/database"
+	"expchange-backend/models"
+	"expchange-backend/services"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SecurityHandler 用户安全设置：TOTP、邮箱、提现地址簿、白名单模式
+type SecurityHandler struct {
+	withdrawSecurity *services.WithdrawSecurityService
+}
+
+func NewSecurityHandler(cfg *config.Config) *SecurityHandler {
+	return &SecurityHandler{
+		withdrawSecurity: services.NewWithdrawSecurityService(cfg.SecretEncryptionKey),
+	}
+}
+
+// secondFactorRequest 敏感操作附带的二次验证码
+type secondFactorRequest struct {
+	TOTPCode  string `json:"totp_code"`
+	EmailCode string `json:"email_code"`
+}
+
+// GetStatus 获取安全设置状态
+func (h *SecurityHandler) GetStatus(c *gin.Context) {
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	var lockedUntil *time.Time
+	lockReason := ""
+	if user.WithdrawLockedUntil != nil && time.Now().Before(*user.WithdrawLockedUntil) {
+		lockedUntil = user.WithdrawLockedUntil
+		lockReason = user.WithdrawLockReason
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"totp_enabled":            user.TOTPEnabled,
+		"email":                   user.Email,
+		"email_verified":          user.EmailVerified,
+		"withdraw_whitelist_only": user.WithdrawWhitelistOnly,
+		"withdraw_locked_until":   lockedUntil,
+		"withdraw_lock_reason":    lockReason,
+		"require_2fa":             database.GetSystemConfigManager().GetBool("withdraw.require_2fa", true),
+		"address_lock_hours":      database.GetSystemConfigManager().GetInt("withdraw.address_lock_hours", 24),
+	})
+}
+
+// SetupTOTP 生成 TOTP 密钥（返回 otpauth 链接供扫码）
+func (h *SecurityHandler) SetupTOTP(c *gin.Context) {
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	secret, otpauthURL, err := h.withdrawSecurity.SetupTOTP(user)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"secret":      secret,
+		"otpauth_url": otpauthURL,
+	})
+}
+
+// EnableTOTP 验证验证码并启用 TOTP
+func (h *SecurityHandler) EnableTOTP(c *gin.Context) {
+	var req struct {
+		Code string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	if err := h.withdrawSecurity.EnableTOTP(user, req.Code); err != nil {
+		c.JSON(securityErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "TOTP enabled"})
+}
+
+// DisableTOTP 关闭 TOTP（关闭后提现锁定一段时间）
+func (h *SecurityHandler) DisableTOTP(c *gin.Context) {
+	var req struct {
+		Code string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	if err := h.withdrawSecurity.DisableTOTP(user, req.Code); err != nil {
+		c.JSON(securityErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "TOTP disabled, withdrawals are temporarily locked"})
+}
+
+// SetEmail 绑定或更换邮箱（发送验证码到新邮箱）
+func (h *SecurityHandler) SetEmail(c *gin.Context) {
+	var req struct {
+		Email string `json:"email" binding:"required"`
+		secondFactorRequest
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	if err := h.withdrawSecurity.SetEmail(user, req.Email, req.TOTPCode, req.EmailCode); err != nil {
+		c.JSON(securityErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Verification code sent"})
+}
+
+// VerifyEmail 校验邮箱验证码
+func (h *SecurityHandler) VerifyEmail(c *gin.Context) {
+	var req struct {
+		Code string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	if err := h.withdrawSecurity.VerifyEmail(user, req.Code); err != nil {
+		c.JSON(securityErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Email verified"})
+}
+
+// SendCode 发送邮件验证码（purpose: withdraw / security / email_verify）
+func (h *SecurityHandler) SendCode(c *gin.Context) {
+	var req struct {
+		Purpose string `json:"purpose" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	sentTo, err := h.withdrawSecurity.SendCode(user, req.Purpose)
+	if err != nil {
+		c.JSON(securityErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Verification code sent",
+		"sent_to": sentTo,
+	})
+}
+
+// GetWithdrawAddresses 获取提现地址簿
+func (h *SecurityHandler) GetWithdrawAddresses(c *gin.Context) {
+	c.JSON(http.StatusOK, h.withdrawSecurity.GetAddresses(c.GetString("user_id")))
+}
+
+// AddWithdrawAddress 添加提现地址（需二次验证，锁定期后生效）
+func (h *SecurityHandler) AddWithdrawAddress(c *gin.Context) {
+	var req struct {
+		Address string `json:"address" binding:"required"`
+		Label   string `json:"label"`
+		secondFactorRequest
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	entry, err := h.withdrawSecurity.AddAddress(user, req.Address, req.Label, req.TOTPCode, req.EmailCode)
+	if err != nil {
+		c.JSON(securityErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, entry)
+}
+
+// DeleteWithdrawAddress 删除提现地址
+func (h *SecurityHandler) DeleteWithdrawAddress(c *gin.Context) {
+	if err := h.withdrawSecurity.DeleteAddress(c.GetString("user_id"), c.Param("id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Address deleted"})
+}
+
+// SetWithdrawWhitelist 开启/关闭白名单模式（关闭需二次验证，关闭后提现锁定一段时间）
+func (h *SecurityHandler) SetWithdrawWhitelist(c *gin.Context) {
+	var req struct {
+		Enabled *bool `json:"enabled" binding:"required"`
+		secondFactorRequest
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	if err := h.withdrawSecurity.SetWhitelistOnly(user, *req.Enabled, req.TOTPCode, req.EmailCode); err != nil {
+		c.JSON(securityErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":                 "Withdrawal whitelist updated",
+		"withdraw_whitelist_only": *req.Enabled,
+	})
+}
+
+func (h *SecurityHandler) currentUser(c *gin.Context) (*models.User, bool) {
+	var user models.User
+	if err := database.DB.Where("id = ?", c.GetString("user_id")).First(&user).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found, please login again"})
+		return nil, false
+	}
+	return &user, true
+}
+
+// securityErrorStatus 安全检查错误对应的 HTTP 状态码
+func securityErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrSecondFactorRequired), errors.Is(err, services.ErrSecondFactorInvalid):
+		return http.StatusUnauthorized
+	case errors.Is(err, services.ErrSecondFactorNotBound), errors.Is(err, services.ErrWithdrawLocked),
+		errors.Is(err, services.ErrAddressNotWhitelisted), errors.Is(err, services.ErrAddressLocked):
+		return http.StatusForbidden
+	case errors.Is(err, services.ErrVerificationCodeTooFast):
+		return http.StatusTooManyRequests
+	default:
+		return http.StatusBadRequest
+	}
+}
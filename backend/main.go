@@ -8,6 +8,7 @@ import (
 	"expchange-backend/matching"
 	"expchange-backend/middleware"
 	"expchange-backend/queue"
+	"expchange-backend/services"
 	"expchange-backend/simulator"
 	"expchange-backend/websocket"
 	"log"
@@ -30,6 +31,15 @@ func main() {
 	// 自动初始化数据（首次启动时）
 	database.AutoSeed()
 
+	// 初始化钱包私钥加密存储，并加密历史明文私钥
+	walletVault, err := services.InitWalletKeyVault(cfg.WalletMasterKey, cfg.WalletMasterKeyPrevious)
+	if err != nil {
+		log.Fatal("Failed to initialize wallet key vault:", err)
+	}
+	if err := walletVault.EncryptChainPrivateKeys(); err != nil {
+		log.Fatal("Failed to encrypt chain private keys:", err)
+	}
+
 	// 初始化撮合引擎
 	matchingManager := matching.NewManager()
 
@@ -68,32 +78,51 @@ func main() {
 	// 只添加Recovery中间件，不添加Logger中间件以减少日志输出
 	r.Use(gin.Recovery())
 
+	// 只信任配置的反向代理转发的客户端 IP（限流按 IP 计数）
+	if err := middleware.SetTrustedProxies(r, cfg.TrustedProxies); err != nil {
+		log.Fatal("Invalid TRUSTED_PROXIES:", err)
+	}
+
 	// 中间件
 	r.Use(middleware.CORSMiddleware(cfg))
 
+	// 限流（各类别参数见系统配置 ratelimit.*，支持热更新）
+	rateLimiter := middleware.NewRateLimiter(cfg)
+
 	// 初始化处理器
 	authHandler := handlers.NewAuthHandler(cfg)
 	marketHandler := handlers.NewMarketHandler(matchingManager)
 	orderHandler := handlers.NewOrderHandler(matchingManager)
-	balanceHandler := handlers.NewBalanceHandler()
+	balanceHandler := handlers.NewBalanceHandler(cfg)
 	wsHandler := handlers.NewWebSocketHandler(wsHub)
 	adminHandler := handlers.NewAdminHandler()
 	klineHandler := handlers.NewKlineHandler(klineGenerator)
 	feeHandler := handlers.NewFeeHandler()
 	chainHandler := handlers.NewChainHandler()
+	referralHandler := handlers.NewReferralHandler()
+	apiKeyHandler := handlers.NewAPIKeyHandler(cfg)
+	securityHandler := handlers.NewSecurityHandler(cfg)
+	adminAuthHandler := handlers.NewAdminAuthHandler(cfg)
+	auditHandler := handlers.NewAuditHandler()
+	adminAuthHandler.EnsureBootstrapAdmin()
 
 	// API路由
 	api := r.Group("/api")
 	{
 		// 公开路由
 		auth := api.Group("/auth")
+		auth.Use(rateLimiter.Limit(middleware.RateLimitAuth))
 		{
 			auth.POST("/nonce", authHandler.GetNonce)
 			auth.POST("/login", authHandler.Login)
+			auth.POST("/refresh", authHandler.Refresh)
+			auth.POST("/logout", middleware.AuthMiddleware(cfg), authHandler.Logout)
+			auth.GET("/sessions", middleware.AuthMiddleware(cfg), authHandler.GetSessions)
 		}
 
 		// 市场数据（公开）
 		market := api.Group("/market")
+		market.Use(rateLimiter.Limit(middleware.RateLimitPublic))
 		{
 			market.GET("/pairs", marketHandler.GetTradingPairs)
 			market.GET("/ticker/:symbol", marketHandler.GetTicker)
@@ -105,11 +134,37 @@ func main() {
 		}
 
 		// 链配置（公开，只返回启用的链）
-		api.GET("/chains", chainHandler.GetEnabledChains)
+		api.GET("/chains", rateLimiter.Limit(middleware.RateLimitPublic), chainHandler.GetEnabledChains)
 
-		// 需要认证的路由
+		// API 密钥管理（仅限钱包登录，API 密钥不能管理自身）
+		apiKeys := api.Group("/api-keys")
+		apiKeys.Use(middleware.AuthMiddleware(cfg), rateLimiter.Limit(middleware.RateLimitAccount))
+		{
+			apiKeys.POST("", apiKeyHandler.CreateAPIKey)
+			apiKeys.GET("", apiKeyHandler.GetAPIKeys)
+			apiKeys.DELETE("/:id", apiKeyHandler.RevokeAPIKey)
+		}
+
+		// 安全设置（仅限钱包登录）：二次验证、提现地址簿、白名单模式
+		security := api.Group("/security")
+		security.Use(middleware.AuthMiddleware(cfg), rateLimiter.Limit(middleware.RateLimitAccount))
+		{
+			security.GET("", securityHandler.GetStatus)
+			security.POST("/totp/setup", securityHandler.SetupTOTP)
+			security.POST("/totp/enable", securityHandler.EnableTOTP)
+			security.POST("/totp/disable", securityHandler.DisableTOTP)
+			security.POST("/email", securityHandler.SetEmail)
+			security.POST("/email/verify", securityHandler.VerifyEmail)
+			security.POST("/codes", securityHandler.SendCode)
+			security.GET("/withdraw-addresses", securityHandler.GetWithdrawAddresses)
+			security.POST("/withdraw-addresses", securityHandler.AddWithdrawAddress)
+			security.DELETE("/withdraw-addresses/:id", securityHandler.DeleteWithdrawAddress)
+			security.PUT("/withdraw-whitelist", securityHandler.SetWithdrawWhitelist)
+		}
+
+		// 需要认证的路由（JWT 或 API 密钥签名），按用户限流
 		authenticated := api.Group("")
-		authenticated.Use(middleware.AuthMiddleware(cfg))
+		authenticated.Use(middleware.UserAuthMiddleware(cfg), rateLimiter.Limit(middleware.RateLimitAccount))
 		{
 			// 用户信息
 			authenticated.GET("/profile", authHandler.GetProfile)
@@ -117,19 +172,20 @@ func main() {
 			// 订单
 			orders := authenticated.Group("/orders")
 			{
-				orders.POST("", orderHandler.CreateOrder)
+				orders.POST("", rateLimiter.Limit(middleware.RateLimitOrder), middleware.RequireScope(services.APIKeyScopeTrade), orderHandler.CreateOrder)
 				orders.GET("", orderHandler.GetOrders)
 				orders.GET("/:id", orderHandler.GetOrder)
-				orders.DELETE("/:id", orderHandler.CancelOrder)
+				orders.DELETE("/:id", rateLimiter.Limit(middleware.RateLimitOrder), middleware.RequireScope(services.APIKeyScopeTrade), orderHandler.CancelOrder)
 			}
 
 			// 余额
 			balances := authenticated.Group("/balances")
 			{
 				balances.GET("", balanceHandler.GetBalances)
+				balances.GET("/deposit-address", balanceHandler.GetDepositAddress)
 				balances.GET("/:asset", balanceHandler.GetBalance)
-				balances.POST("/deposit", balanceHandler.Deposit)
-				balances.POST("/withdraw", balanceHandler.Withdraw)
+				balances.POST("/deposit", middleware.RequireScope(services.APIKeyScopeTrade), balanceHandler.Deposit)
+				balances.POST("/withdraw", middleware.RequireScope(services.APIKeyScopeWithdraw), balanceHandler.Withdraw)
 				balances.GET("/deposits", balanceHandler.GetDepositRecords)
 				balances.GET("/withdraws", balanceHandler.GetWithdrawRecords)
 			}
@@ -140,70 +196,133 @@ func main() {
 				fees.GET("/stats", feeHandler.GetUserFeeStats)
 				fees.GET("/records", feeHandler.GetUserFeeRecords)
 			}
+
+			// 邀请返佣
+			referral := authenticated.Group("/referral")
+			{
+				referral.GET("", referralHandler.GetReferralStats)
+				referral.GET("/invitees", referralHandler.GetInvitees)
+				referral.GET("/commissions", referralHandler.GetCommissions)
+			}
 		}
 
-		// 管理后台路由
+		// 管理员登录（公开）
+		api.POST("/admin/auth/login", rateLimiter.Limit(middleware.RateLimitAuth), adminAuthHandler.Login)
+
+		// 管理后台路由（管理员令牌 + 角色权限，所有非 GET 操作记录审计日志）
+		requirePerm := middleware.RequireAdminPermission
 		admin := api.Group("/admin")
-		admin.Use(middleware.AdminAuthMiddleware(cfg))
+		admin.Use(middleware.AdminAuthMiddleware(cfg), middleware.AdminAuditMiddleware())
 		{
-			admin.GET("/users", adminHandler.GetUsers)
-			admin.GET("/orders", adminHandler.GetAllOrders)
-			admin.GET("/trades", adminHandler.GetAllTrades)
-			admin.GET("/deposits", adminHandler.GetAllDeposits)
-			admin.GET("/withdrawals", adminHandler.GetAllWithdrawals)
-			admin.GET("/stats", adminHandler.GetStats)
+			// 当前管理员（未绑定 TOTP 时也可访问，用于完成绑定）
+			admin.GET("/auth/me", adminAuthHandler.GetMe)
+			admin.POST("/auth/logout", adminAuthHandler.Logout)
+			admin.POST("/auth/totp/setup", adminAuthHandler.SetupTOTP)
+			admin.POST("/auth/totp/enable", adminAuthHandler.EnableTOTP)
+			admin.POST("/auth/password", adminAuthHandler.ChangePassword)
+
+			// 管理员账户管理
+			admin.GET("/admins", requirePerm(services.AdminPermAdmin), adminAuthHandler.GetAdmins)
+			admin.POST("/admins", requirePerm(services.AdminPermAdmin), adminAuthHandler.CreateAdmin)
+			admin.PUT("/admins/:id", requirePerm(services.AdminPermAdmin), adminAuthHandler.UpdateAdmin)
+			admin.POST("/admins/:id/reset-password", requirePerm(services.AdminPermAdmin), adminAuthHandler.ResetAdminPassword)
+			admin.POST("/admins/:id/reset-totp", requirePerm(services.AdminPermAdmin), adminAuthHandler.ResetAdminTOTP)
+
+			// 审计日志
+			admin.GET("/audit-logs", requirePerm(services.AdminPermAudit), auditHandler.GetAuditLogs)
+
+			admin.GET("/users", requirePerm(services.AdminPermView), adminHandler.GetUsers)
+			admin.GET("/users/:id/sessions", requirePerm(services.AdminPermView), adminHandler.GetUserSessions)
+			admin.POST("/users/:id/sessions/revoke", requirePerm(services.AdminPermUser), adminHandler.RevokeUserSessions)
+			admin.GET("/orders", requirePerm(services.AdminPermView), adminHandler.GetAllOrders)
+			admin.GET("/trades", requirePerm(services.AdminPermView), adminHandler.GetAllTrades)
+			admin.GET("/deposits", requirePerm(services.AdminPermView), adminHandler.GetAllDeposits)
+			admin.GET("/deposits/unclaimed", requirePerm(services.AdminPermView), adminHandler.GetUnclaimedDeposits)
+			admin.POST("/deposits/unclaimed/:id/assign", requirePerm(services.AdminPermFinance), adminHandler.AssignUnclaimedDeposit)
+			admin.POST("/deposits/unclaimed/:id/ignore", requirePerm(services.AdminPermFinance), adminHandler.IgnoreUnclaimedDeposit)
+			admin.GET("/deposits/sweeps", requirePerm(services.AdminPermView), adminHandler.GetDepositSweeps)
+			admin.POST("/deposits/sweep", requirePerm(services.AdminPermFinance), adminHandler.TriggerDepositSweep)
+			admin.GET("/wallet-monitor", requirePerm(services.AdminPermView), adminHandler.GetWalletMonitor)
+			admin.GET("/wallet-monitor/history", requirePerm(services.AdminPermView), adminHandler.GetWalletMonitorHistory)
+			admin.POST("/wallet-monitor/run", requirePerm(services.AdminPermFinance), adminHandler.TriggerWalletMonitor)
+			admin.GET("/withdrawals", requirePerm(services.AdminPermView), adminHandler.GetAllWithdrawals)
+			admin.POST("/withdrawals/:id/approve", requirePerm(services.AdminPermFinance), adminHandler.ApproveWithdrawal)
+			admin.POST("/withdrawals/:id/reject", requirePerm(services.AdminPermFinance), adminHandler.RejectWithdrawal)
+			admin.POST("/withdrawals/:id/speed-up", requirePerm(services.AdminPermFinance), adminHandler.SpeedUpWithdrawal)
+			admin.GET("/stats", requirePerm(services.AdminPermView), adminHandler.GetStats)
 
 			// 交易对管理
-			admin.GET("/pairs", adminHandler.GetTradingPairs)
-			admin.POST("/pairs", adminHandler.CreateTradingPair)
-			admin.PUT("/pairs/:id", adminHandler.UpdateTradingPair)
-			admin.PUT("/pairs/:id/status", adminHandler.UpdateTradingPairStatus)
-			admin.PUT("/pairs/:id/simulator", adminHandler.UpdateTradingPairSimulator)
-			admin.POST("/pairs/batch-activity", adminHandler.BatchUpdatePairsActivity)
-			admin.POST("/pairs/batch-init", adminHandler.BatchGenerateInitData)
-			admin.POST("/pairs/batch-klines", adminHandler.BatchGenerateKlines)
+			admin.GET("/pairs", requirePerm(services.AdminPermView), adminHandler.GetTradingPairs)
+			admin.POST("/pairs", requirePerm(services.AdminPermMarket), adminHandler.CreateTradingPair)
+			admin.PUT("/pairs/:id", requirePerm(services.AdminPermMarket), adminHandler.UpdateTradingPair)
+			admin.PUT("/pairs/:id/status", requirePerm(services.AdminPermMarket), adminHandler.UpdateTradingPairStatus)
+			admin.PUT("/pairs/:id/simulator", requirePerm(services.AdminPermMarket), adminHandler.UpdateTradingPairSimulator)
+			admin.POST("/pairs/batch-activity", requirePerm(services.AdminPermMarket), adminHandler.BatchUpdatePairsActivity)
+			admin.POST("/pairs/batch-init", requirePerm(services.AdminPermMarket), adminHandler.BatchGenerateInitData)
+			admin.POST("/pairs/batch-klines", requirePerm(services.AdminPermMarket), adminHandler.BatchGenerateKlines)
 
 			// 数据生成任务
-			admin.POST("/pairs/generate-trades", adminHandler.GenerateTradeDataForPair)
-			admin.POST("/pairs/generate-klines", adminHandler.GenerateKlineDataForPair)
+			admin.POST("/pairs/generate-trades", requirePerm(services.AdminPermMarket), adminHandler.GenerateTradeDataForPair)
+			admin.POST("/pairs/generate-klines", requirePerm(services.AdminPermMarket), adminHandler.GenerateKlineDataForPair)
 
 			// 任务管理
-			admin.GET("/tasks", adminHandler.GetAllTasks)
-			admin.GET("/tasks/:id", adminHandler.GetTaskStatus)
-			admin.GET("/tasks/:id/logs", adminHandler.GetTaskLogs)
-			admin.POST("/tasks/:id/retry", adminHandler.RetryTask)
-			admin.GET("/tasks/running", adminHandler.GetRunningTask)
+			admin.GET("/tasks", requirePerm(services.AdminPermView), adminHandler.GetAllTasks)
+			admin.GET("/tasks/:id", requirePerm(services.AdminPermView), adminHandler.GetTaskStatus)
+			admin.GET("/tasks/:id/logs", requirePerm(services.AdminPermView), adminHandler.GetTaskLogs)
+			admin.POST("/tasks/:id/retry", requirePerm(services.AdminPermMarket), adminHandler.RetryTask)
+			admin.GET("/tasks/running", requirePerm(services.AdminPermView), adminHandler.GetRunningTask)
 
 			// K线管理
-			admin.POST("/klines/generate", klineHandler.GenerateHistoricalKlines)
+			admin.POST("/klines/generate", requirePerm(services.AdminPermMarket), klineHandler.GenerateHistoricalKlines)
 
 			// 手续费管理
-			admin.GET("/fees", feeHandler.GetAllFeeRecords)
-			admin.GET("/fees/configs", feeHandler.GetFeeConfigs)
-			admin.PUT("/users/:id/level", feeHandler.UpdateUserLevel)
+			admin.GET("/fees", requirePerm(services.AdminPermView), feeHandler.GetAllFeeRecords)
+			admin.GET("/fees/configs", requirePerm(services.AdminPermView), feeHandler.GetFeeConfigs)
+			admin.PUT("/users/:id/level", requirePerm(services.AdminPermUser), feeHandler.UpdateUserLevel)
+			admin.GET("/fees/revenue", requirePerm(services.AdminPermView), feeHandler.GetFeeRevenue)
+
+			// 国库归集
+			admin.GET("/treasury", requirePerm(services.AdminPermView), feeHandler.GetTreasury)
+			admin.PUT("/treasury/config", requirePerm(services.AdminPermFinance), feeHandler.UpdateTreasuryConfig)
+			admin.GET("/treasury/sweeps", requirePerm(services.AdminPermView), feeHandler.GetTreasurySweeps)
+			admin.POST("/treasury/sweep", requirePerm(services.AdminPermFinance), feeHandler.TriggerTreasurySweep)
+
+			// 邀请返佣管理
+			admin.GET("/referral/config", requirePerm(services.AdminPermView), referralHandler.GetReferralConfig)
+			admin.PUT("/referral/config", requirePerm(services.AdminPermFinance), referralHandler.UpdateReferralConfig)
+			admin.GET("/referral/commissions", requirePerm(services.AdminPermView), referralHandler.GetAllCommissions)
+			admin.POST("/referral/settle", requirePerm(services.AdminPermFinance), referralHandler.SettleReferral)
+			admin.PUT("/users/:id/referral-rate", requirePerm(services.AdminPermUser), referralHandler.UpdateUserReferralRate)
 
 			// 系统配置管理
-			admin.GET("/configs", adminHandler.GetSystemConfigs)
-			admin.GET("/configs/:id", adminHandler.GetSystemConfig)
-			admin.PUT("/configs/:id", adminHandler.UpdateSystemConfig)
-			admin.POST("/configs/reload", adminHandler.ReloadSystemConfigs)
+			admin.GET("/configs", requirePerm(services.AdminPermSystem), adminHandler.GetSystemConfigs)
+			admin.GET("/configs/:id", requirePerm(services.AdminPermSystem), adminHandler.GetSystemConfig)
+			admin.PUT("/configs/:id", requirePerm(services.AdminPermSystem), adminHandler.UpdateSystemConfig)
+			admin.POST("/configs/reload", requirePerm(services.AdminPermSystem), adminHandler.ReloadSystemConfigs)
 
 			// 链配置管理
-			admin.GET("/chains", chainHandler.GetChains)
-			admin.GET("/chains/:id", chainHandler.GetChain)
-			admin.POST("/chains", chainHandler.CreateChain)
-			admin.PUT("/chains/:id", chainHandler.UpdateChain)
-			admin.PUT("/chains/:id/status", chainHandler.UpdateChainStatus)
-			admin.DELETE("/chains/:id", chainHandler.DeleteChain)
+			admin.GET("/chains", requirePerm(services.AdminPermSystem), chainHandler.GetChains)
+			admin.GET("/chains/rpc-health", requirePerm(services.AdminPermView), chainHandler.GetRpcHealth)
+			admin.GET("/chains/:id", requirePerm(services.AdminPermSystem), chainHandler.GetChain)
+			admin.POST("/chains", requirePerm(services.AdminPermSystem), chainHandler.CreateChain)
+			admin.PUT("/chains/:id", requirePerm(services.AdminPermSystem), chainHandler.UpdateChain)
+			admin.PUT("/chains/:id/status", requirePerm(services.AdminPermSystem), chainHandler.UpdateChainStatus)
+			admin.DELETE("/chains/:id", requirePerm(services.AdminPermSystem), chainHandler.DeleteChain)
+			admin.PUT("/chains/:id/withdraw-fees", requirePerm(services.AdminPermFinance), chainHandler.UpsertWithdrawFee)
+			admin.DELETE("/chains/:id/withdraw-fees/:asset", requirePerm(services.AdminPermFinance), chainHandler.DeleteWithdrawFee)
+			admin.PUT("/chains/:id/tokens", requirePerm(services.AdminPermSystem), chainHandler.UpsertChainToken)
+			admin.DELETE("/chains/:id/tokens/:asset", requirePerm(services.AdminPermSystem), chainHandler.DeleteChainToken)
+			admin.PUT("/chains/:id/rpc-endpoints", requirePerm(services.AdminPermSystem), chainHandler.UpsertRpcEndpoint)
+			admin.DELETE("/chains/:id/rpc-endpoints/:endpoint", requirePerm(services.AdminPermSystem), chainHandler.DeleteRpcEndpoint)
 
 			// 做市商盈亏管理
-			admin.GET("/market-maker/pnl", adminHandler.GetMarketMakerPnL)
-			admin.GET("/market-maker/stats", adminHandler.GetMarketMakerStats)
+			admin.GET("/market-maker/pnl", requirePerm(services.AdminPermView), adminHandler.GetMarketMakerPnL)
+			admin.GET("/market-maker/stats", requirePerm(services.AdminPermView), adminHandler.GetMarketMakerStats)
 		}
 	}
 
 	// WebSocket路由
-	r.GET("/ws", wsHandler.HandleWebSocket)
+	r.GET("/ws", rateLimiter.Limit(middleware.RateLimitPublic), wsHandler.HandleWebSocket)
 
 	// 启动服务器
 	log.Printf("Server starting on port %s", cfg.ServerPort)
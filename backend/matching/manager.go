@@ -4,6 +4,7 @@ import (
 	"expchange-backend/database"
 	"expchange-backend/models"
 	"expchange-backend/services"
+	"fmt"
 	"log"
 	"sync"
 	"time"
@@ -191,7 +192,9 @@ func (m *Manager) processBatch(trades []*models.Trade) {
 			}
 
 			// 更新用户余额（在事务中）
-			m.updateBalancesInTx(tx, buyOrder, sellOrder, trade)
+			if err := m.updateBalancesInTx(tx, buyOrder, sellOrder, trade); err != nil {
+				return err
+			}
 		}
 
 		// 6. 批量更新订单
@@ -214,7 +217,7 @@ func (m *Manager) processBatch(trades []*models.Trade) {
 }
 
 // updateBalancesInTx 在事务中更新用户余额（性能优化版）
-func (m *Manager) updateBalancesInTx(tx *gorm.DB, buyOrder, sellOrder *models.Order, trade *models.Trade) {
+func (m *Manager) updateBalancesInTx(tx *gorm.DB, buyOrder, sellOrder *models.Order, trade *models.Trade) error {
 	cost := trade.Price.Mul(trade.Quantity)
 	baseAsset := getBaseAsset(buyOrder.Symbol)
 	quoteAsset := getQuoteAsset(buyOrder.Symbol)
@@ -268,13 +271,18 @@ func (m *Manager) updateBalancesInTx(tx *gorm.DB, buyOrder, sellOrder *models.Or
 	if !buyerIsMaker {
 		buyerOrderSide = "taker"
 	}
-	m.feeService.RecordFeeInTx(tx, buyOrder.UserID, buyOrder.ID, trade.ID, baseAsset, buyerFee, buyerFeeRate, buyerOrderSide)
+	if err := m.feeService.RecordFeeInTx(tx, buyOrder.UserID, buyOrder.ID, trade.ID, baseAsset, buyerFee, buyerFeeRate, buyerOrderSide); err != nil {
+		return fmt.Errorf("record buyer fee for trade %s: %w", trade.ID, err)
+	}
 
 	sellerOrderSide := "maker"
 	if buyerIsMaker {
 		sellerOrderSide = "taker"
 	}
-	m.feeService.RecordFeeInTx(tx, sellOrder.UserID, sellOrder.ID, trade.ID, quoteAsset, sellerFee, sellerFeeRate, sellerOrderSide)
+	if err := m.feeService.RecordFeeInTx(tx, sellOrder.UserID, sellOrder.ID, trade.ID, quoteAsset, sellerFee, sellerFeeRate, sellerOrderSide); err != nil {
+		return fmt.Errorf("record seller fee for trade %s: %w", trade.ID, err)
+	}
+	return nil
 }
 
 func (m *Manager) updateBalances(buyOrder, sellOrder *models.Order, trade *models.Trade) {
@@ -0,0 +1,153 @@
+package middleware
+
+import (
+	"bytes"
+	"expchange-backend/config"
+	"expchange-backend/services"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+// API 密钥签名请求头
+const (
+	HeaderAPIKey        = "X-API-KEY"
+	HeaderAPITimestamp  = "X-API-TIMESTAMP"   // 毫秒时间戳
+	HeaderAPIRecvWindow = "X-API-RECV-WINDOW" // 毫秒，默认 5000，最大 60000
+	HeaderAPISignature  = "X-API-SIGNATURE"   // hex(HMAC-SHA256(secret, timestamp + recvWindow + method + path + body))
+)
+
+var (
+	sharedAPIKeyOnce    sync.Once
+	sharedAPIKeyService *services.APIKeyService
+)
+
+// getAPIKeyService 所有路由组共用同一个 API 密钥服务（防重放记录必须共享）
+func getAPIKeyService(cfg *config.Config) *services.APIKeyService {
+	sharedAPIKeyOnce.Do(func() {
+		sharedAPIKeyService = services.NewAPIKeyService(cfg.SecretEncryptionKey)
+		if cfg.RateLimitBackend == "redis" {
+			opts, err := redis.ParseURL(cfg.RedisURL)
+			if err != nil {
+				log.Printf("⚠️  REDIS_URL 无效，API 密钥防重放使用内存记录: %v", err)
+				return
+			}
+			sharedAPIKeyService.UseRedisReplayStore(redis.NewClient(opts))
+		}
+	})
+	return sharedAPIKeyService
+}
+
+// APIKeyAuthMiddleware 校验 HMAC-SHA256 签名的 API 密钥请求
+func APIKeyAuthMiddleware(cfg *config.Config) gin.HandlerFunc {
+	apiKeyService := getAPIKeyService(cfg)
+
+	return func(c *gin.Context) {
+		accessKey := c.GetHeader(HeaderAPIKey)
+		signature := c.GetHeader(HeaderAPISignature)
+		timestamp := c.GetHeader(HeaderAPITimestamp)
+		if accessKey == "" || signature == "" || timestamp == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "API key, timestamp and signature headers are required"})
+			c.Abort()
+			return
+		}
+
+		// recvWindow 参与签名，不能被篡改；服务端上限 MaxAPIRecvWindow
+		recvWindowHeader := c.GetHeader(HeaderAPIRecvWindow)
+		recvWindow := services.DefaultAPIRecvWindow
+		if recvWindowHeader != "" {
+			ms, err := strconv.ParseInt(recvWindowHeader, 10, 64)
+			if err != nil || ms <= 0 || time.Duration(ms)*time.Millisecond > services.MaxAPIRecvWindow {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid recvWindow"})
+				c.Abort()
+				return
+			}
+			recvWindow = time.Duration(ms) * time.Millisecond
+		}
+
+		// 时间戳必须在 recvWindow 内，防止请求被截获后重放
+		ms, err := strconv.ParseInt(timestamp, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid timestamp"})
+			c.Abort()
+			return
+		}
+		drift := time.Since(time.UnixMilli(ms))
+		if drift > recvWindow || drift < -recvWindow {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Timestamp outside of recvWindow"})
+			c.Abort()
+			return
+		}
+
+		// 读取请求体参与签名，读取后放回供后续 handler 使用
+		var body []byte
+		if c.Request.Body != nil {
+			body, err = io.ReadAll(c.Request.Body)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+				c.Abort()
+				return
+			}
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		}
+
+		key, err := apiKeyService.Authenticate(accessKey, signature, timestamp, recvWindowHeader, c.Request.Method,
+			c.Request.URL.RequestURI(), string(body), apiKeyClientIP(c, cfg))
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			c.Abort()
+			return
+		}
+
+		c.Set("user_id", key.UserID)
+		c.Set("api_key_id", key.ID)
+		c.Set("api_key_permissions", key.Permissions)
+		c.Next()
+	}
+}
+
+// apiKeyClientIP IP 白名单校验使用的客户端地址
+// 未配置 TRUSTED_PROXIES 时只认 TCP 连接地址：X-Forwarded-For 可由调用方任意伪造，白名单又是提现免二次验证的前提
+func apiKeyClientIP(c *gin.Context, cfg *config.Config) string {
+	if cfg.TrustedProxies == "" {
+		return c.RemoteIP()
+	}
+	return c.ClientIP()
+}
+
+// UserAuthMiddleware 用户接口鉴权：带 X-API-KEY 头时按 API 密钥签名校验，否则按 JWT 校验
+func UserAuthMiddleware(cfg *config.Config) gin.HandlerFunc {
+	jwtAuth := AuthMiddleware(cfg)
+	apiKeyAuth := APIKeyAuthMiddleware(cfg)
+
+	return func(c *gin.Context) {
+		if c.GetHeader(HeaderAPIKey) != "" {
+			apiKeyAuth(c)
+			return
+		}
+		jwtAuth(c)
+	}
+}
+
+// RequireScope 要求 API 密钥拥有指定权限（JWT 登录的用户不受限制）
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := c.Get("api_key_id"); !ok {
+			c.Next()
+			return
+		}
+
+		if !services.HasScope(c.GetString("api_key_permissions"), scope) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "API key does not have " + scope + " permission"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"expchange-backend/config"
+	"expchange-backend/models"
+	"expchange-backend/services"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const testEncryptionKey = "middleware-test-encryption-key"
+
+// signedAPIKeyRequest 按 API 密钥签名规则构造 GET 请求
+func signedAPIKeyRequest(accessKey, secret, path, remoteAddr, forwardedFor string) *http.Request {
+	timestamp := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + http.MethodGet + path))
+
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.RemoteAddr = remoteAddr
+	req.Header.Set(HeaderAPIKey, accessKey)
+	req.Header.Set(HeaderAPITimestamp, timestamp)
+	req.Header.Set(HeaderAPISignature, hex.EncodeToString(mac.Sum(nil)))
+	if forwardedFor != "" {
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+	}
+	return req
+}
+
+func TestAPIKeyAuthIPWhitelist(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	newTestDB(t, &models.User{}, &models.APIKey{})
+	key, secret, err := services.NewAPIKeyService(testEncryptionKey).Create("user-1", "bot", []string{"read"}, []string{"203.0.113.5"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		trustedProxies string
+		remoteAddr     string
+		forwardedFor   string
+		wantStatus     int
+	}{
+		{name: "direct from whitelisted ip", remoteAddr: "203.0.113.5:40000", wantStatus: http.StatusOK},
+		{name: "direct from other ip", remoteAddr: "198.51.100.7:40000", wantStatus: http.StatusUnauthorized},
+		{
+			// 未配置可信代理时伪造 X-Forwarded-For 无效
+			name: "spoofed forwarded for", remoteAddr: "198.51.100.7:40000", forwardedFor: "203.0.113.5",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "forwarded by trusted proxy", trustedProxies: "10.0.0.1", remoteAddr: "10.0.0.1:40000", forwardedFor: "203.0.113.5",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "forwarded by untrusted proxy", trustedProxies: "10.0.0.1", remoteAddr: "198.51.100.7:40000", forwardedFor: "203.0.113.5",
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{SecretEncryptionKey: testEncryptionKey, TrustedProxies: tt.trustedProxies}
+			r := gin.New()
+			var proxies []string
+			if tt.trustedProxies != "" {
+				proxies = []string{tt.trustedProxies}
+			}
+			if err := r.SetTrustedProxies(proxies); err != nil {
+				t.Fatal(err)
+			}
+			r.GET("/api/v1/account", APIKeyAuthMiddleware(cfg), func(c *gin.Context) {
+				c.JSON(http.StatusOK, gin.H{"user_id": c.GetString("user_id")})
+			})
+
+			// 每个用例使用不同路径，避免签名被防重放拒绝
+			path := fmt.Sprintf("/api/v1/account?case=%d", i)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, signedAPIKeyRequest(key.AccessKey, secret, path, tt.remoteAddr, tt.forwardedFor))
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status=%d body=%s, want %d", w.Code, w.Body.String(), tt.wantStatus)
+			}
+		})
+	}
+}
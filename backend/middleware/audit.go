@@ -0,0 +1,232 @@
+package middleware
+
+import (
+	"bytes"
+	"expchange-backend/database"
+	"expchange-backend/models"
+	"expchange-backend/services"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// auditTarget 审计对象：根据路由参数加载变更前后的快照
+type auditTarget struct {
+	targetType string
+	load       func(c *gin.Context) interface{}
+}
+
+func loadByID(model func() interface{}) func(c *gin.Context) interface{} {
+	return func(c *gin.Context) interface{} {
+		record := model()
+		if err := database.DB.Where("id = ?", c.Param("id")).First(record).Error; err != nil {
+			return nil
+		}
+		return record
+	}
+}
+
+func loadConfigCategory(category string) func(c *gin.Context) interface{} {
+	return func(c *gin.Context) interface{} {
+		var configs []models.SystemConfig
+		database.DB.Where("category = ?", category).Find(&configs)
+
+		// 以配置键为字段，便于计算差异
+		snapshot := make(map[string]string, len(configs))
+		for _, cfg := range configs {
+			snapshot[cfg.Key] = cfg.Value
+		}
+		return snapshot
+	}
+}
+
+// loadSystemConfig 系统配置快照（值按配置键脱敏，见 services.SystemConfigSnapshot）
+func loadSystemConfig(c *gin.Context) interface{} {
+	var config models.SystemConfig
+	if err := database.DB.Where("id = ?", c.Param("id")).First(&config).Error; err != nil {
+		return nil
+	}
+	return services.SystemConfigSnapshot(&config)
+}
+
+// chainIDOfParam 路由参数 id（链配置记录 ID）对应的链 ID
+func chainIDOfParam(c *gin.Context) (int, bool) {
+	var chain models.ChainConfig
+	if err := database.DB.Select("chain_id").Where("id = ?", c.Param("id")).First(&chain).Error; err != nil {
+		return 0, false
+	}
+	return chain.ChainID, true
+}
+
+// loadChainWithdrawFees 链的提现手续费快照（以资产为字段）
+func loadChainWithdrawFees(c *gin.Context) interface{} {
+	chainID, ok := chainIDOfParam(c)
+	if !ok {
+		return nil
+	}
+	var fees []models.WithdrawFee
+	database.DB.Where("chain_id = ?", chainID).Find(&fees)
+
+	snapshot := make(map[string]models.WithdrawFee, len(fees))
+	for _, fee := range fees {
+		snapshot[fee.Asset] = fee
+	}
+	return snapshot
+}
+
+// loadChainTokens 链的资产配置快照（以资产为字段）
+func loadChainTokens(c *gin.Context) interface{} {
+	chainID, ok := chainIDOfParam(c)
+	if !ok {
+		return nil
+	}
+	var tokens []models.ChainToken
+	database.DB.Where("chain_id = ?", chainID).Find(&tokens)
+
+	snapshot := make(map[string]models.ChainToken, len(tokens))
+	for _, token := range tokens {
+		snapshot[token.Asset] = token
+	}
+	return snapshot
+}
+
+// loadChainRpcEndpoints 链的 RPC 节点快照（以节点 ID 为字段）
+func loadChainRpcEndpoints(c *gin.Context) interface{} {
+	chainID, ok := chainIDOfParam(c)
+	if !ok {
+		return nil
+	}
+	var endpoints []models.ChainRpcEndpoint
+	database.DB.Where("chain_id = ?", chainID).Find(&endpoints)
+
+	snapshot := make(map[string]models.ChainRpcEndpoint, len(endpoints))
+	for _, endpoint := range endpoints {
+		snapshot[endpoint.ID] = endpoint
+	}
+	return snapshot
+}
+
+var (
+	chainTarget       = auditTarget{"chain", loadByID(func() interface{} { return &models.ChainConfig{} })}
+	pairTarget        = auditTarget{"trading_pair", loadByID(func() interface{} { return &models.TradingPair{} })}
+	userTarget        = auditTarget{"user", loadByID(func() interface{} { return &models.User{} })}
+	adminTarget       = auditTarget{"admin_user", loadByID(func() interface{} { return &models.AdminUser{} })}
+	systemConfigTgt   = auditTarget{"system_config", loadSystemConfig}
+	taskTarget        = auditTarget{"task", loadByID(func() interface{} { return &models.Task{} })}
+	referralConfigTgt = auditTarget{"referral_config", loadConfigCategory("referral")}
+	treasuryConfigTgt = auditTarget{"treasury_config", loadConfigCategory("treasury")}
+	withdrawFeeTarget = auditTarget{"chain_withdraw_fees", loadChainWithdrawFees}
+	chainTokenTarget  = auditTarget{"chain_tokens", loadChainTokens}
+	rpcEndpointTarget = auditTarget{"chain_rpc_endpoints", loadChainRpcEndpoints}
+)
+
+// auditTargets 路由（不含 /api/admin 前缀）到审计对象的映射
+var auditTargets = map[string]auditTarget{
+	"/chains/:id":                         chainTarget,
+	"/chains/:id/status":                  chainTarget,
+	"/chains/:id/withdraw-fees":           withdrawFeeTarget,
+	"/chains/:id/withdraw-fees/:asset":    withdrawFeeTarget,
+	"/chains/:id/tokens":                  chainTokenTarget,
+	"/chains/:id/tokens/:asset":           chainTokenTarget,
+	"/chains/:id/rpc-endpoints":           rpcEndpointTarget,
+	"/chains/:id/rpc-endpoints/:endpoint": rpcEndpointTarget,
+	"/pairs/:id":                          pairTarget,
+	"/pairs/:id/status":                   pairTarget,
+	"/pairs/:id/simulator":                pairTarget,
+	"/users/:id/level":                    userTarget,
+	"/users/:id/referral-rate":            userTarget,
+	"/users/:id/sessions/revoke":          userTarget,
+	"/admins/:id":                         adminTarget,
+	"/admins/:id/reset-password":          adminTarget,
+	"/admins/:id/reset-totp":              adminTarget,
+	"/configs/:id":                        systemConfigTgt,
+	"/tasks/:id/retry":                    taskTarget,
+	"/referral/config":                    referralConfigTgt,
+	"/treasury/config":                    treasuryConfigTgt,
+}
+
+// auditResponseWriter 记录响应体（新建对象时作为变更后快照）
+type auditResponseWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *auditResponseWriter) Write(data []byte) (int, error) {
+	if w.body.Len() < 64*1024 {
+		w.body.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+// AdminAuditMiddleware 记录所有非 GET 管理操作：操作人、动作、对象、变更前后差异、IP
+// 需放在 AdminAuthMiddleware 之后
+func AdminAuditMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead || c.Request.Method == http.MethodOptions {
+			c.Next()
+			return
+		}
+
+		var body []byte
+		if c.Request.Body != nil {
+			body, _ = io.ReadAll(c.Request.Body)
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		}
+
+		route := strings.TrimPrefix(c.FullPath(), "/api/admin")
+		target, hasTarget := auditTargets[route]
+
+		var before interface{}
+		if hasTarget {
+			before = target.load(c)
+		}
+
+		writer := &auditResponseWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+
+		c.Next()
+
+		entry := &models.AdminAuditLog{
+			AdminID:       c.GetString("admin_id"),
+			AdminUsername: c.GetString("admin_username"),
+			AdminRole:     c.GetString("admin_role"),
+			Action:        c.Request.Method + " " + c.FullPath(),
+			Method:        c.Request.Method,
+			Path:          c.Request.URL.Path,
+			TargetID:      c.Param("id"),
+			Request:       services.MaskRequestBody(c.ContentType(), body),
+			StatusCode:    writer.Status(),
+			IPAddress:     c.ClientIP(),
+			UserAgent:     c.Request.UserAgent(),
+		}
+
+		// 系统配置请求体只有 value，需按配置键判断是否敏感
+		if snapshot, ok := before.(map[string]interface{}); ok && target.targetType == systemConfigTgt.targetType {
+			if key, ok := snapshot["key"].(string); ok {
+				entry.Request = services.MaskSystemConfigRequest(key, body)
+			}
+		}
+
+		if hasTarget {
+			entry.TargetType = target.targetType
+			entry.Before = services.MaskedJSON(before)
+			// 请求失败时对象未变更，不再重复加载
+			if writer.Status() < http.StatusBadRequest {
+				entry.After = services.MaskedJSON(target.load(c))
+			} else {
+				entry.After = entry.Before
+			}
+		} else {
+			// 无固定对象的操作（新建、批量任务等）：以路由段作为对象类型，响应体作为变更后快照
+			entry.TargetType = strings.Split(strings.Trim(route, "/"), "/")[0]
+			if writer.Status() < http.StatusBadRequest {
+				entry.After = services.MaskRequestBody("application/json", writer.body.Bytes())
+			}
+		}
+		entry.Changes = services.DiffSnapshots(entry.Before, entry.After)
+
+		services.RecordAdminAudit(entry)
+	}
+}
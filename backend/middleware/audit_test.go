@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"encoding/json"
+	"expchange-backend/database"
+	"expchange-backend/models"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+)
+
+// TestAdminAuditChainChildren 链的手续费、资产和 RPC 节点修改记录变更前快照和按键的差异
+func TestAdminAuditChainChildren(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	newTestDB(t, &models.ChainConfig{}, &models.WithdrawFee{}, &models.ChainToken{}, &models.ChainRpcEndpoint{}, &models.AdminAuditLog{})
+
+	chain := &models.ChainConfig{ChainName: "bsc", ChainID: 56}
+	fee := &models.WithdrawFee{ChainID: 56, Asset: "USDT", Fee: decimal.NewFromInt(1)}
+	token := &models.ChainToken{ChainID: 56, Asset: "BNB", Decimals: 18}
+	endpoint := &models.ChainRpcEndpoint{ChainID: 56, URL: "https://bsc.example.com", Weight: 1}
+	for _, row := range []interface{}{chain, fee, token, endpoint} {
+		if err := database.DB.Create(row).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := gin.New()
+	admin := r.Group("/api/admin", AdminAuditMiddleware())
+	admin.PUT("/chains/:id/withdraw-fees", func(c *gin.Context) {
+		database.DB.Model(fee).Update("fee", decimal.NewFromInt(2))
+		c.JSON(http.StatusOK, fee)
+	})
+	admin.DELETE("/chains/:id/tokens/:asset", func(c *gin.Context) {
+		database.DB.Where("chain_id = ? AND asset = ?", 56, c.Param("asset")).Delete(&models.ChainToken{})
+		c.JSON(http.StatusOK, gin.H{"message": "deleted"})
+	})
+	admin.PUT("/chains/:id/rpc-endpoints", func(c *gin.Context) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid url"})
+	})
+
+	tests := []struct {
+		method      string
+		path        string
+		wantType    string
+		wantBefore  string
+		wantChanged string // 为空表示无差异
+	}{
+		{method: http.MethodPut, path: "/api/admin/chains/" + chain.ID + "/withdraw-fees", wantType: "chain_withdraw_fees", wantBefore: "USDT", wantChanged: "USDT"},
+		{method: http.MethodDelete, path: "/api/admin/chains/" + chain.ID + "/tokens/BNB", wantType: "chain_tokens", wantBefore: "BNB", wantChanged: "BNB"},
+		{method: http.MethodPut, path: "/api/admin/chains/" + chain.ID + "/rpc-endpoints", wantType: "chain_rpc_endpoints", wantBefore: endpoint.ID},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.wantType, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, strings.NewReader(`{}`)))
+
+			var entry models.AdminAuditLog
+			if err := database.DB.Order("created_at DESC").Where("target_type = ?", tt.wantType).First(&entry).Error; err != nil {
+				t.Fatalf("audit log not recorded: %v", err)
+			}
+			if entry.TargetID != chain.ID {
+				t.Fatalf("target id=%s, want %s", entry.TargetID, chain.ID)
+			}
+			var before map[string]interface{}
+			if err := json.Unmarshal([]byte(entry.Before), &before); err != nil || before[tt.wantBefore] == nil {
+				t.Fatalf("before=%s, want snapshot keyed by %s", entry.Before, tt.wantBefore)
+			}
+			var changes map[string]interface{}
+			json.Unmarshal([]byte(entry.Changes), &changes)
+			if tt.wantChanged == "" {
+				if len(changes) != 0 {
+					t.Fatalf("changes=%s, want none for a failed request", entry.Changes)
+				}
+				return
+			}
+			if len(changes) != 1 || changes[tt.wantChanged] == nil {
+				t.Fatalf("changes=%s, want only %s", entry.Changes, tt.wantChanged)
+			}
+		})
+	}
+}
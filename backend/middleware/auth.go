@@ -2,6 +2,9 @@ package middleware
 
 import (
 	"expchange-backend/config"
+	"expchange-backend/database"
+	"expchange-backend/models"
+	"expchange-backend/services"
 	"net/http"
 	"strings"
 
@@ -12,6 +15,7 @@ import (
 type Claims struct {
 	UserID        string `json:"user_id"`
 	WalletAddress string `json:"wallet_address"`
+	SessionID     string `json:"sid"` // 登录会话ID，用于吊销检查
 	jwt.RegisteredClaims
 }
 
@@ -45,12 +49,31 @@ func AuthMiddleware(cfg *config.Config) gin.HandlerFunc {
 			return
 		}
 
+		// 检查吊销列表（登出、管理员吊销、刷新令牌重放）
+		if services.IsSessionRevoked(claims.SessionID) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Session revoked"})
+			c.Abort()
+			return
+		}
+
 		c.Set("user_id", claims.UserID)
 		c.Set("wallet_address", claims.WalletAddress)
+		c.Set("session_id", claims.SessionID)
 		c.Next()
 	}
 }
 
+// AdminClaims 管理后台令牌（使用独立密钥签发，与交易用户令牌互不通用）
+type AdminClaims struct {
+	AdminID      string `json:"admin_id"`
+	Username     string `json:"username"`
+	TokenVersion int    `json:"ver"` // 与账户 token_version 不一致时令牌失效
+	jwt.RegisteredClaims
+}
+
+// AdminTokenAudience 管理后台令牌的 audience
+const AdminTokenAudience = "admin"
+
 func AdminAuthMiddleware(cfg *config.Config) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
@@ -67,30 +90,48 @@ func AdminAuthMiddleware(cfg *config.Config) gin.HandlerFunc {
 			return
 		}
 
-		tokenString := parts[1]
+		claims := &AdminClaims{}
+		token, err := jwt.ParseWithClaims(parts[1], claims, func(token *jwt.Token) (interface{}, error) {
+			return []byte(cfg.AdminJWTSecret), nil
+		}, jwt.WithAudience(AdminTokenAudience), jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
 
-		// 简化处理：接受固定的admin token或有效的JWT token
-		if tokenString == "admin-token-placeholder" {
-			// 接受前端设置的简单token
-			c.Set("admin_id", "admin")
-			c.Next()
+		if err != nil || !token.Valid {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			c.Abort()
 			return
 		}
 
-		// 尝试解析JWT token
-		claims := &Claims{}
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			return []byte(cfg.JWTSecret), nil
-		})
-
-		if err != nil || !token.Valid {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		// 每次请求读取最新的账户状态（禁用、角色变更、登出后立即生效）
+		var admin models.AdminUser
+		if err := database.DB.Where("id = ?", claims.AdminID).First(&admin).Error; err != nil ||
+			admin.Disabled || admin.TokenVersion != claims.TokenVersion {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Session expired, please login again"})
 			c.Abort()
 			return
 		}
 
-		c.Set("admin_id", claims.UserID)
+		c.Set("admin_id", admin.ID)
+		c.Set("admin_username", admin.Username)
+		c.Set("admin_role", admin.Role)
+		c.Set("admin_totp_enabled", admin.TOTPEnabled)
 		c.Next()
 	}
 }
 
+// RequireAdminPermission 校验管理员角色权限（未绑定 TOTP 的账户只能访问 TOTP 绑定接口）
+func RequireAdminPermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !c.GetBool("admin_totp_enabled") {
+			c.JSON(http.StatusForbidden, gin.H{"error": "TOTP setup required"})
+			c.Abort()
+			return
+		}
+
+		if !services.AdminRoleHasPermission(c.GetString("admin_role"), permission) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
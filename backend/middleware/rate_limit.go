@@ -0,0 +1,171 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"expchange-backend/config"
+	"expchange-backend/database"
+	"expchange-backend/pkg/ratelimit"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+// 限流类别（每类单独的令牌桶配置）
+const (
+	RateLimitPublic  = "public"  // 公开行情数据
+	RateLimitOrder   = "order"   // 下单/撤单
+	RateLimitAccount = "account" // 账户读写（余额、订单查询等）
+	RateLimitAuth    = "auth"    // 登录/刷新令牌（按 IP）
+)
+
+// rateLimitDefaults 各类别默认限流参数：每秒令牌数、桶容量
+// 可通过系统配置 ratelimit.<类别>.rate / ratelimit.<类别>.burst 热更新，rate 为 0 表示不限流
+var rateLimitDefaults = map[string]struct {
+	rate  float64
+	burst int
+}{
+	RateLimitPublic:  {rate: 20, burst: 40},
+	RateLimitOrder:   {rate: 10, burst: 20},
+	RateLimitAccount: {rate: 10, burst: 30},
+	RateLimitAuth:    {rate: 0.2, burst: 10},
+}
+
+// RateLimiter 令牌桶限流（内存或 Redis 后端）
+type RateLimiter struct {
+	backend  ratelimit.Limiter
+	fallback *ratelimit.MemoryLimiter // Redis 不可用时降级使用
+
+	errMu          sync.Mutex
+	lastErrLog     time.Time
+	unhealthyUntil time.Time // 后端异常后暂停访问的截止时间，避免每个请求都等待超时
+}
+
+// rateLimitBackendRetry 后端异常后重试间隔
+const rateLimitBackendRetry = 10 * time.Second
+
+var errRateLimitBackendDown = errors.New("rate limit backend unavailable")
+
+// NewRateLimiter 根据配置创建限流器（RATE_LIMIT_BACKEND=redis 时使用 REDIS_URL）
+func NewRateLimiter(cfg *config.Config) *RateLimiter {
+	limiter := &RateLimiter{fallback: ratelimit.NewMemoryLimiter()}
+	limiter.backend = limiter.fallback
+
+	if cfg.RateLimitBackend == "redis" {
+		opts, err := redis.ParseURL(cfg.RedisURL)
+		if err != nil {
+			log.Printf("⚠️  REDIS_URL 无效，限流使用内存后端: %v", err)
+			return limiter
+		}
+		limiter.backend = ratelimit.NewRedisLimiter(redis.NewClient(opts), "ratelimit:")
+		log.Printf("✅ 限流使用 Redis 后端: %s", opts.Addr)
+	}
+	return limiter
+}
+
+// SetTrustedProxies 只信任配置的反向代理转发的客户端 IP
+// 未配置时不信任任何代理（gin 默认信任所有来源的 X-Forwarded-For，按 IP 限流可被逐请求伪造绕过）
+func SetTrustedProxies(r *gin.Engine, trustedProxies string) error {
+	if trustedProxies == "" {
+		return r.SetTrustedProxies(nil)
+	}
+	return r.SetTrustedProxies(strings.Split(trustedProxies, ","))
+}
+
+// rateLimitParams 读取类别限流参数（每次请求读取，配置修改后立即生效）
+func rateLimitParams(class string) (float64, int) {
+	defaults := rateLimitDefaults[class]
+	sysConfig := database.GetSystemConfigManager()
+
+	rate := defaults.rate
+	if value := sysConfig.Get("ratelimit."+class+".rate", ""); value != "" {
+		if parsed, err := strconv.ParseFloat(value, 64); err == nil && parsed >= 0 {
+			rate = parsed
+		}
+	}
+	burst := sysConfig.GetInt("ratelimit."+class+".burst", defaults.burst)
+	if burst < 1 {
+		burst = 1
+	}
+	return rate, burst
+}
+
+// Limit 按类别限流：已登录请求按用户 ID 计数，其余按客户端 IP 计数
+// 需要按用户计数的路由应放在认证中间件之后
+func (l *RateLimiter) Limit(class string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !database.GetSystemConfigManager().GetBool("ratelimit.enabled", true) {
+			c.Next()
+			return
+		}
+
+		rate, burst := rateLimitParams(class)
+		if rate <= 0 {
+			c.Next()
+			return
+		}
+
+		key := class + ":ip:" + c.ClientIP()
+		if userID := c.GetString("user_id"); userID != "" && class != RateLimitAuth {
+			key = class + ":user:" + userID
+		}
+
+		result, err := l.allow(c.Request.Context(), key, rate, burst)
+		if err != nil {
+			result, _ = l.fallback.Allow(context.Background(), key, rate, burst)
+		}
+
+		c.Header("X-RateLimit-Limit", strconv.Itoa(result.Limit))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Header("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(result.ResetAfter)))
+
+		if !result.Allowed {
+			c.Header("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many requests"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// allow 调用限流后端；Redis 异常时返回错误，由调用方降级为内存限流（日志每分钟最多一条）
+func (l *RateLimiter) allow(ctx context.Context, key string, rate float64, burst int) (ratelimit.Result, error) {
+	if l.backend == l.fallback {
+		return l.fallback.Allow(ctx, key, rate, burst)
+	}
+
+	l.errMu.Lock()
+	unhealthy := time.Now().Before(l.unhealthyUntil)
+	l.errMu.Unlock()
+	if unhealthy {
+		return ratelimit.Result{}, errRateLimitBackendDown
+	}
+
+	result, err := l.backend.Allow(ctx, key, rate, burst)
+	if err != nil {
+		l.errMu.Lock()
+		l.unhealthyUntil = time.Now().Add(rateLimitBackendRetry)
+		if time.Since(l.lastErrLog) > time.Minute {
+			l.lastErrLog = time.Now()
+			log.Printf("⚠️  限流后端异常，暂时使用内存限流: %v", err)
+		}
+		l.errMu.Unlock()
+	}
+	return result, err
+}
+
+func ceilSeconds(d time.Duration) int {
+	if d <= 0 {
+		return 0
+	}
+	return int(math.Ceil(d.Seconds()))
+}
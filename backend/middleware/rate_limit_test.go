@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"expchange-backend/config"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRateLimitForwardedFor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	newTestDB(t)
+	burst := rateLimitDefaults[RateLimitAuth].burst
+
+	tests := []struct {
+		name           string
+		trustedProxies string
+		remoteAddr     string
+		wantLimited    bool
+	}{
+		{
+			// 未配置可信代理：每次更换 X-Forwarded-For 仍计入同一连接地址的令牌桶
+			name: "forged header without trusted proxies", remoteAddr: "198.51.100.7:40000", wantLimited: true,
+		},
+		{
+			name: "forged header from untrusted proxy", trustedProxies: "10.0.0.1", remoteAddr: "198.51.100.8:40000", wantLimited: true,
+		},
+		{
+			// 可信代理转发的不同客户端各自计数
+			name: "distinct clients behind trusted proxy", trustedProxies: "10.0.0.2", remoteAddr: "10.0.0.2:40000",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			if err := SetTrustedProxies(r, tt.trustedProxies); err != nil {
+				t.Fatal(err)
+			}
+			r.POST("/api/v1/auth/login", NewRateLimiter(&config.Config{}).Limit(RateLimitAuth), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			limited := false
+			for i := 0; i <= burst; i++ {
+				req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", nil)
+				req.RemoteAddr = tt.remoteAddr
+				req.Header.Set("X-Forwarded-For", fmt.Sprintf("203.0.113.%d", i+1))
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				if w.Code == http.StatusTooManyRequests {
+					if i != burst {
+						t.Fatalf("request %d limited, want the first %d allowed", i+1, burst)
+					}
+					limited = true
+				}
+			}
+			if limited != tt.wantLimited {
+				t.Fatalf("limited=%v after %d requests with distinct X-Forwarded-For, want %v", limited, burst+1, tt.wantLimited)
+			}
+		})
+	}
+}
+
+func TestSetTrustedProxiesInvalid(t *testing.T) {
+	if err := SetTrustedProxies(gin.New(), "10.0.0.1,not-an-ip"); err == nil {
+		t.Fatal("SetTrustedProxies accepted an invalid entry")
+	}
+}
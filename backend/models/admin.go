@@ -0,0 +1,34 @@
+package models
+
+import (
+	"expchange-backend/utils"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AdminUser 管理后台账户（与交易用户完全独立）
+type AdminUser struct {
+	ID               string     `gorm:"primaryKey;size:24" json:"id"`
+	Username         string     `gorm:"size:50;uniqueIndex;not null" json:"username"`
+	PasswordHash     string     `gorm:"size:100;not null" json:"-"`
+	Role             string     `gorm:"size:20;not null;index" json:"role"` // viewer, operator, finance, superadmin
+	TOTPSecret       string     `gorm:"type:text" json:"-"`                 // 加密存储
+	TOTPEnabled      bool       `gorm:"default:false" json:"totp_enabled"`
+	LastTOTPStep     int64      `json:"-"`                  // 最近一次使用的验证码时间步，防止重放
+	TokenVersion     int        `gorm:"default:1" json:"-"` // 递增后该管理员的所有登录令牌失效
+	Disabled         bool       `gorm:"default:false;index" json:"disabled"`
+	FailedLoginCount int        `gorm:"default:0" json:"-"`
+	LockedUntil      *time.Time `json:"locked_until,omitempty"`
+	LastLoginAt      *time.Time `json:"last_login_at,omitempty"`
+	LastLoginIP      string     `gorm:"size:64" json:"last_login_ip,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
+
+func (a *AdminUser) BeforeCreate(tx *gorm.DB) error {
+	if a.ID == "" {
+		a.ID = utils.GenerateObjectID()
+	}
+	return nil
+}
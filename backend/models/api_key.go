@@ -0,0 +1,30 @@
+package models
+
+import (
+	"expchange-backend/utils"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// APIKey 用户 API 密钥（程序化交易使用 HMAC-SHA256 签名请求）
+type APIKey struct {
+	ID              string     `gorm:"primaryKey;size:24" json:"id"`
+	UserID          string     `gorm:"size:24;index;not null" json:"user_id"`
+	Label           string     `gorm:"size:50" json:"label"`
+	AccessKey       string     `gorm:"size:64;uniqueIndex;not null" json:"access_key"` // 公开的 Key ID
+	SecretEncrypted string     `gorm:"type:text;not null" json:"-"`                    // 加密存储的签名密钥（仅创建时返回明文）
+	Permissions     string     `gorm:"size:100;not null" json:"permissions"`           // 逗号分隔：read,trade,withdraw
+	IPWhitelist     string     `gorm:"type:text" json:"ip_whitelist"`                  // 逗号分隔的IP/CIDR，为空表示不限制
+	LastUsedAt      *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt       *time.Time `gorm:"index" json:"revoked_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+func (k *APIKey) BeforeCreate(tx *gorm.DB) error {
+	if k.ID == "" {
+		k.ID = utils.GenerateObjectID()
+	}
+	return nil
+}
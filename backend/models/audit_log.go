@@ -0,0 +1,48 @@
+package models
+
+import (
+	"errors"
+	"expchange-backend/utils"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrAuditLogImmutable 审计日志只允许追加
+var ErrAuditLogImmutable = errors.New("audit log is append-only")
+
+// AdminAuditLog 管理员操作审计日志（只追加，不允许修改和删除）
+type AdminAuditLog struct {
+	ID            string    `gorm:"primaryKey;size:24" json:"id"`
+	AdminID       string    `gorm:"size:24;index" json:"admin_id"`
+	AdminUsername string    `gorm:"size:50;index" json:"admin_username"`
+	AdminRole     string    `gorm:"size:20" json:"admin_role"`
+	Action        string    `gorm:"size:150;index;not null" json:"action"` // 例如 PUT /api/admin/chains/:id、admin.login
+	Method        string    `gorm:"size:10" json:"method"`
+	Path          string    `gorm:"size:255" json:"path"`             // 实际请求路径
+	TargetType    string    `gorm:"size:50;index" json:"target_type"` // chain, trading_pair, system_config, user ...
+	TargetID      string    `gorm:"size:64;index" json:"target_id"`
+	Request       string    `gorm:"type:text" json:"request,omitempty"` // 请求体（敏感字段已脱敏）
+	Before        string    `gorm:"type:text" json:"before,omitempty"`  // 变更前快照（已脱敏）
+	After         string    `gorm:"type:text" json:"after,omitempty"`   // 变更后快照（已脱敏）
+	Changes       string    `gorm:"type:text" json:"changes,omitempty"` // 字段级差异 {"field": {"from": x, "to": y}}
+	StatusCode    int       `gorm:"index" json:"status_code"`
+	IPAddress     string    `gorm:"size:64" json:"ip_address"`
+	UserAgent     string    `gorm:"size:255" json:"user_agent"`
+	CreatedAt     time.Time `gorm:"index" json:"created_at"`
+}
+
+func (a *AdminAuditLog) BeforeCreate(tx *gorm.DB) error {
+	if a.ID == "" {
+		a.ID = utils.GenerateObjectID()
+	}
+	return nil
+}
+
+func (a *AdminAuditLog) BeforeUpdate(tx *gorm.DB) error {
+	return ErrAuditLogImmutable
+}
+
+func (a *AdminAuditLog) BeforeDelete(tx *gorm.DB) error {
+	return ErrAuditLogImmutable
+}
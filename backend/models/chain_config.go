@@ -4,29 +4,131 @@ import (
 	"expchange-backend/utils"
 	"time"
 
+	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
 )
 
 // ChainConfig 链配置
 // 注意：ChainName 和 ChainID 创建后不可修改
 type ChainConfig struct {
-	ID                         string    `gorm:"primaryKey;size:24" json:"id"`
-	ChainName                  string    `gorm:"uniqueIndex;size:100;not null" json:"chain_name"`        // 链名称（创建后不可修改）
-	ChainID                    int       `gorm:"uniqueIndex;not null" json:"chain_id"`                   // 链ID（创建后不可修改）
-	RpcURL                     string    `gorm:"type:varchar(500);not null" json:"rpc_url"`              // RPC地址
-	BlockExplorerURL           string    `gorm:"type:varchar(500)" json:"block_explorer_url"`            // 区块浏览器地址
-	UsdtContractAddress        string    `gorm:"size:42;not null" json:"usdt_contract_address"`          // USDT合约地址
-	UsdtDecimals               int       `gorm:"not null;default:18" json:"usdt_decimals"`               // USDT精度（6或18）
-	PlatformDepositAddress     string    `gorm:"size:42;not null" json:"platform_deposit_address"`       // 平台充值收款地址
-	PlatformWithdrawPrivateKey string    `gorm:"type:varchar(500)" json:"platform_withdraw_private_key"` // 平台提现转账私钥
-	Enabled                    bool      `gorm:"default:true" json:"enabled"`                            // 是否启用
-	CreatedAt                  time.Time `json:"created_at"`
-	UpdatedAt                  time.Time `json:"updated_at"`
+	ID                         string          `gorm:"primaryKey;size:24" json:"id"`
+	ChainName                  string          `gorm:"uniqueIndex;size:100;not null" json:"chain_name"`                    // 链名称（创建后不可修改）
+	ChainID                    int             `gorm:"uniqueIndex;not null" json:"chain_id"`                               // 链ID（创建后不可修改）
+	RpcURL                     string          `gorm:"type:varchar(500);not null" json:"rpc_url"`                          // RPC地址
+	BlockExplorerURL           string          `gorm:"type:varchar(500)" json:"block_explorer_url"`                        // 区块浏览器地址
+	UsdtContractAddress        string          `gorm:"size:42;not null" json:"usdt_contract_address"`                      // USDT合约地址
+	UsdtDecimals               int             `gorm:"not null;default:18" json:"usdt_decimals"`                           // USDT精度（6或18）
+	PlatformDepositAddress     string          `gorm:"size:42;not null" json:"platform_deposit_address"`                   // 平台充值收款地址
+	PlatformWithdrawPrivateKey string          `gorm:"type:varchar(500)" json:"-"`                                         // 平台提现转账私钥（信封加密存储，接口不返回）
+	PlatformWithdrawAddress    string          `gorm:"size:42" json:"platform_withdraw_address"`                           // 提现热钱包地址（remote 方式需手动填写）
+	SignerType                 string          `gorm:"size:20;default:'local'" json:"signer_type"`                         // 提现签名方式：local/keystore/remote
+	SignerKeystorePath         string          `gorm:"type:varchar(500)" json:"signer_keystore_path"`                      // keystore 文件路径（keystore）
+	SignerKeystorePassword     string          `gorm:"type:varchar(500)" json:"-"`                                         // keystore 密码（加密存储）
+	SignerRemoteURL            string          `gorm:"type:varchar(500)" json:"signer_remote_url"`                         // 远程签名服务地址（remote）
+	SignerRemoteMethod         string          `gorm:"size:50" json:"signer_remote_method"`                                // 远程签名 JSON-RPC 方法，默认 eth_signTransaction
+	SignerRemoteAuthToken      string          `gorm:"type:varchar(500)" json:"-"`                                         // 远程签名服务令牌（加密存储）
+	WithdrawConfirmations      int             `gorm:"not null;default:12" json:"withdraw_confirmations"`                  // 提现交易完成所需确认数
+	RequiredConfirmations      int             `gorm:"not null;default:12" json:"required_confirmations"`                  // 充值入账所需确认数
+	TxType                     string          `gorm:"size:10;default:'legacy'" json:"tx_type"`                            // 提现交易类型：legacy / eip1559
+	MaxGasPriceGwei            decimal.Decimal `gorm:"type:decimal(20,9);not null;default:0" json:"max_gas_price_gwei"`    // gas price（EIP-1559 为 max fee per gas）上限，0 表示不限
+	MaxPriorityFeeGwei         decimal.Decimal `gorm:"type:decimal(20,9);not null;default:0" json:"max_priority_fee_gwei"` // EIP-1559 小费上限，0 表示不限
+	MaxGasLimit                uint64          `gorm:"not null;default:0" json:"max_gas_limit"`                            // 估算 gas limit 上限，0 表示不限
+	MultisendContractAddress   string          `gorm:"size:42" json:"multisend_contract_address"`                          // 批量提现 multisend（Disperse）合约地址，为空时不批量发送
+	DepositXpub                string          `gorm:"type:varchar(200)" json:"deposit_xpub"`                              // 用户充值地址的扩展公钥（BIP-44 账户层级 m/44'/60'/0'），为空时使用共享充值地址
+	DepositSweepKey            string          `gorm:"type:varchar(500)" json:"-"`                                         // 与 xpub 对应的扩展私钥（信封加密存储，仅用于归集）
+	ColdWalletAddress          string          `gorm:"size:42" json:"cold_wallet_address"`                                 // 冷钱包地址（只读监控余额，计入资产覆盖率）
+	Enabled                    bool            `gorm:"default:true" json:"enabled"`                                        // 是否启用
+	CreatedAt                  time.Time       `json:"created_at"`
+	UpdatedAt                  time.Time       `json:"updated_at"`
 }
 
+// 提现签名方式
+const (
+	SignerTypeLocal    = "local"    // 数据库中加密保存的私钥
+	SignerTypeKeystore = "keystore" // go-ethereum 加密 keystore 文件
+	SignerTypeRemote   = "remote"   // 外部 HTTP 签名服务
+)
+
+// 提现交易类型
+const (
+	TxTypeLegacy  = "legacy"  // gasPrice 交易
+	TxTypeEIP1559 = "eip1559" // DynamicFeeTx（maxFeePerGas + maxPriorityFeePerGas）
+)
+
 func (c *ChainConfig) BeforeCreate(tx *gorm.DB) error {
 	if c.ID == "" {
 		c.ID = utils.GenerateObjectID()
 	}
 	return nil
 }
+
+// WithdrawFee 提现手续费与最小提现额（按链、资产配置，未配置时不收手续费、不限最小额）
+type WithdrawFee struct {
+	ID        string          `gorm:"primaryKey;size:24" json:"id"`
+	ChainID   int             `gorm:"not null;uniqueIndex:idx_withdraw_fee_chain_asset" json:"chain_id"`
+	Asset     string          `gorm:"size:10;not null;uniqueIndex:idx_withdraw_fee_chain_asset" json:"asset"`
+	Fee       decimal.Decimal `gorm:"type:decimal(30,8);not null;default:0" json:"fee"`        // 每笔固定手续费（从提现金额中扣除）
+	MinAmount decimal.Decimal `gorm:"type:decimal(30,8);not null;default:0" json:"min_amount"` // 最小提现金额（含手续费）
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+func (f *WithdrawFee) BeforeCreate(tx *gorm.DB) error {
+	if f.ID == "" {
+		f.ID = utils.GenerateObjectID()
+	}
+	return nil
+}
+
+// ChainToken 链上资产注册表（按链、资产配置充值提现的合约、精度和开关）
+// ContractAddress 为空表示链原生币（ETH/BNB 等）；USDT 与链配置的 USDT 合约和精度保持同步
+type ChainToken struct {
+	ID               string          `gorm:"primaryKey;size:24" json:"id"`
+	ChainID          int             `gorm:"not null;uniqueIndex:idx_chain_token_chain_asset" json:"chain_id"`
+	Asset            string          `gorm:"size:10;not null;uniqueIndex:idx_chain_token_chain_asset" json:"asset"`
+	ContractAddress  string          `gorm:"size:42" json:"contract_address"`                                 // ERC20 合约地址，为空表示原生币
+	Decimals         int             `gorm:"not null;default:18" json:"decimals"`                             // 链上精度
+	DepositEnabled   bool            `gorm:"not null;default:false" json:"deposit_enabled"`                   // 是否开放充值
+	WithdrawEnabled  bool            `gorm:"not null;default:false" json:"withdraw_enabled"`                  // 是否开放提现
+	MinDepositAmount decimal.Decimal `gorm:"type:decimal(30,8);not null;default:0" json:"min_deposit_amount"` // 最小充值额（低于该金额不入账，最小提现额见提现手续费配置）
+	HotAlertBalance  decimal.Decimal `gorm:"type:decimal(30,8);not null;default:0" json:"hot_alert_balance"`  // 提现热钱包余额低于该值时告警（0 表示不告警）
+	HotPauseBalance  decimal.Decimal `gorm:"type:decimal(30,8);not null;default:0" json:"hot_pause_balance"`  // 低于该值时自动暂停该资产提现；原生币低于该值时暂停全链提现（0 表示不暂停）
+	WithdrawPaused   bool            `gorm:"not null;default:false" json:"withdraw_paused"`                   // 热钱包余额不足已自动暂停提现（由余额监控设置和解除）
+	PauseReason      string          `gorm:"size:255" json:"pause_reason"`                                    // 暂停原因
+	RiskLargeAmount  decimal.Decimal `gorm:"type:decimal(30,8);not null;default:0" json:"risk_large_amount"`  // 风控：单笔大额提现阈值（0 表示 USDT 使用系统配置，其他资产不检查）
+	RiskDailyLimit   decimal.Decimal `gorm:"type:decimal(30,8);not null;default:0" json:"risk_daily_limit"`   // 风控：24 小时累计提现限额（同上）
+	CreatedAt        time.Time       `json:"created_at"`
+	UpdatedAt        time.Time       `json:"updated_at"`
+}
+
+// IsNative 是否为链原生币
+func (t *ChainToken) IsNative() bool {
+	return t.ContractAddress == ""
+}
+
+func (t *ChainToken) BeforeCreate(tx *gorm.DB) error {
+	if t.ID == "" {
+		t.ID = utils.GenerateObjectID()
+	}
+	return nil
+}
+
+// ChainRpcEndpoint 链的 RPC 节点注册表（与链配置的 RpcURL 组成节点池，按优先级和权重分配请求，故障时自动切换）
+// RpcURL 未在注册表中出现时按优先级 0、权重 1 的主节点处理
+type ChainRpcEndpoint struct {
+	ID        string    `gorm:"primaryKey;size:24" json:"id"`
+	ChainID   int       `gorm:"not null;index" json:"chain_id"`
+	URL       string    `gorm:"type:varchar(500);not null" json:"url"` // HTTP/HTTPS 地址
+	Priority  int       `gorm:"not null;default:0" json:"priority"`    // 优先级，数值越小越优先
+	Weight    int       `gorm:"not null;default:1" json:"weight"`      // 同优先级节点的请求权重
+	Enabled   bool      `gorm:"not null;default:true" json:"enabled"`  // 是否启用
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (e *ChainRpcEndpoint) BeforeCreate(tx *gorm.DB) error {
+	if e.ID == "" {
+		e.ID = utils.GenerateObjectID()
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"expchange-backend/utils"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// DepositAddress 用户的专属充值地址（由链配置的 xpub 按 BIP-44 路径 .../0/index 派生）
+type DepositAddress struct {
+	ID              string    `gorm:"primaryKey;size:24" json:"id"`
+	UserID          string    `gorm:"size:24;not null;uniqueIndex:idx_deposit_address_user_chain" json:"user_id"`
+	ChainID         int       `gorm:"not null;uniqueIndex:idx_deposit_address_user_chain;uniqueIndex:idx_deposit_address_chain_address;uniqueIndex:idx_deposit_address_chain_index" json:"chain_id"`
+	Address         string    `gorm:"size:42;not null;uniqueIndex:idx_deposit_address_chain_address" json:"address"` // 小写地址
+	DerivationIndex uint32    `gorm:"not null;uniqueIndex:idx_deposit_address_chain_index" json:"derivation_index"`  // 派生序号
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+func (d *DepositAddress) BeforeCreate(tx *gorm.DB) error {
+	if d.ID == "" {
+		d.ID = utils.GenerateObjectID()
+	}
+	return nil
+}
+
+// DepositSweep 充值地址归集记录（充值地址 -> 提现热钱包）
+// 充值地址原生币不足以支付 gas 时先由热钱包补充 gas（gas_pending），补充到账后再转出代币（broadcast）
+type DepositSweep struct {
+	ID             string          `gorm:"primaryKey;size:24" json:"id"`
+	TaskID         string          `gorm:"size:24;index" json:"task_id"` // 发起归集的任务ID
+	ChainID        int             `gorm:"not null;index" json:"chain_id"`
+	Chain          string          `gorm:"size:20;not null" json:"chain"`
+	Asset          string          `gorm:"size:10;not null" json:"asset"`
+	UserID         string          `gorm:"size:24;not null" json:"user_id"`                          // 充值地址所属用户
+	DepositAddress string          `gorm:"size:42;not null;index" json:"deposit_address"`            // 充值地址
+	ToAddress      string          `gorm:"size:42;not null" json:"to_address"`                       // 热钱包地址
+	Amount         decimal.Decimal `gorm:"type:decimal(30,18);not null" json:"amount"`               // 归集金额
+	GasNonce       uint64          `gorm:"not null;default:0" json:"gas_nonce"`                      // 补充 gas 交易的热钱包 nonce
+	GasTxHash      string          `gorm:"size:66" json:"gas_tx_hash,omitempty"`                     // 补充 gas 的交易hash
+	GasAmount      decimal.Decimal `gorm:"type:decimal(30,18);not null;default:0" json:"gas_amount"` // 补充的原生币数量
+	Nonce          uint64          `gorm:"not null;default:0" json:"nonce"`                          // 代币转出交易的充值地址 nonce
+	TxHash         string          `gorm:"size:66" json:"tx_hash,omitempty"`                         // 代币转出交易hash
+	BlockNumber    uint64          `gorm:"not null;default:0" json:"block_number"`                   // 代币转出交易所在区块
+	Status         string          `gorm:"size:20;not null;index" json:"status"`                     // gas_pending, broadcast, completed, failed
+	Reason         string          `gorm:"type:varchar(500)" json:"reason,omitempty"`                // 失败原因
+	CreatedAt      time.Time       `gorm:"index" json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+}
+
+func (s *DepositSweep) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == "" {
+		s.ID = utils.GenerateObjectID()
+	}
+	return nil
+}
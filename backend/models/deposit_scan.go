@@ -0,0 +1,54 @@
+package models
+
+import (
+	"expchange-backend/utils"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// DepositScanCursor 充值扫描进度（每条链一条）
+type DepositScanCursor struct {
+	ID          string    `gorm:"primaryKey;size:24" json:"id"`
+	ChainID     int       `gorm:"uniqueIndex;not null" json:"chain_id"`
+	BlockNumber uint64    `gorm:"not null" json:"block_number"` // 已扫描到的区块（含）
+	BlockHash   string    `gorm:"size:66" json:"block_hash"`    // 该区块hash，用于检测链重组
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func (c *DepositScanCursor) BeforeCreate(tx *gorm.DB) error {
+	if c.ID == "" {
+		c.ID = utils.GenerateObjectID()
+	}
+	return nil
+}
+
+// UnclaimedDeposit 扫描到但无法归属用户的入账（例如从交易所或合约钱包转入）
+// 用户提交该交易hash验证成功，或管理员指定用户后入账
+type UnclaimedDeposit struct {
+	ID          string          `gorm:"primaryKey;size:24" json:"id"`
+	ChainID     int             `gorm:"not null;index" json:"chain_id"`
+	Chain       string          `gorm:"size:20;not null" json:"chain"`
+	Asset       string          `gorm:"size:10;not null" json:"asset"`
+	Amount      decimal.Decimal `gorm:"type:decimal(30,8);not null" json:"amount"`
+	TxHash      string          `gorm:"size:66;uniqueIndex;not null" json:"tx_hash"`
+	FromAddress string          `gorm:"size:42" json:"from_address"`            // 转出地址（多个 Transfer 时为第一个）
+	ToAddress   string          `gorm:"size:42" json:"to_address"`              // 收款地址
+	BlockNumber uint64          `gorm:"not null;default:0" json:"block_number"` // 所在区块
+	Status      string          `gorm:"size:20;not null;index" json:"status"`   // unclaimed, credited, ignored
+	DepositID   string          `gorm:"size:24" json:"deposit_id,omitempty"`    // 入账后关联的充值记录
+	HandledBy   string          `gorm:"size:24" json:"handled_by,omitempty"`    // 处理的管理员ID
+	HandledAt   *time.Time      `json:"handled_at,omitempty"`                   // 处理时间
+	Note        string          `gorm:"size:255" json:"note,omitempty"`         // 处理备注
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+}
+
+func (d *UnclaimedDeposit) BeforeCreate(tx *gorm.DB) error {
+	if d.ID == "" {
+		d.ID = utils.GenerateObjectID()
+	}
+	return nil
+}
@@ -45,4 +45,23 @@ func (f *FeeRecord) BeforeCreate(tx *gorm.DB) error {
 	return nil
 }
 
+// 国库归集记录（平台手续费账户 -> 国库账户）
+type TreasurySweep struct {
+	ID         string          `gorm:"primaryKey;size:24" json:"id"`
+	TaskID     string          `gorm:"size:24;index" json:"task_id"`              // 关联的归集任务ID
+	Asset      string          `gorm:"size:10;not null;index" json:"asset"`       // 归集资产
+	Amount     decimal.Decimal `gorm:"type:decimal(30,8);not null" json:"amount"` // 归集金额
+	FromUserID string          `gorm:"size:24;not null" json:"from_user_id"`      // 平台手续费账户
+	ToUserID   string          `gorm:"size:24;not null" json:"to_user_id"`        // 国库账户
+	ToAddress  string          `gorm:"size:42;not null" json:"to_address"`        // 国库账户钱包地址
+	Status     string          `gorm:"size:20;not null;index" json:"status"`      // completed, failed
+	Reason     string          `gorm:"type:varchar(500)" json:"reason,omitempty"` // 失败原因
+	CreatedAt  time.Time       `gorm:"index" json:"created_at"`
+}
 
+func (t *TreasurySweep) BeforeCreate(tx *gorm.DB) error {
+	if t.ID == "" {
+		t.ID = utils.GenerateObjectID()
+	}
+	return nil
+}
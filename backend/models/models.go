@@ -10,18 +10,39 @@ import (
 )
 
 type User struct {
-	ID            string    `gorm:"primaryKey;size:24" json:"id"`
-	WalletAddress string    `gorm:"uniqueIndex;size:42;not null" json:"wallet_address"`
-	Nonce         string    `gorm:"size:100" json:"-"`
-	UserLevel     string    `gorm:"size:20;default:'normal'" json:"user_level"` // normal, vip1, vip2, vip3
-	CreatedAt     time.Time `json:"created_at"`
-	UpdatedAt     time.Time `json:"updated_at"`
+	ID            string `gorm:"primaryKey;size:24" json:"id"`
+	WalletAddress string `gorm:"uniqueIndex;size:42;not null" json:"wallet_address"`
+	Nonce         string `gorm:"size:100" json:"-"`
+	UserLevel     string `gorm:"size:20;default:'normal'" json:"user_level"` // normal, vip1, vip2, vip3
+	// 登录签名（SIWE），nonce 一次性使用
+	SignInMessage  string     `gorm:"type:text" json:"-"` // 签发给用户签名的 SIWE 消息
+	NonceExpiresAt *time.Time `json:"-"`                  // nonce 过期时间
+	// 邀请返佣
+	ReferralCode string              `gorm:"size:16;index" json:"referral_code"`                // 我的邀请码
+	ReferrerID   string              `gorm:"size:24;index" json:"referrer_id,omitempty"`        // 邀请人ID（首次登录时绑定，之后不可修改）
+	ReferralRate decimal.NullDecimal `gorm:"type:decimal(10,4)" json:"referral_rate,omitempty"` // 个人返佣比例（为空则使用系统配置）
+	ReferredAt   *time.Time          `json:"referred_at,omitempty"`                             // 绑定邀请关系时间
+	LastLoginAt  *time.Time          `json:"last_login_at,omitempty"`                           // 最近登录时间
+	// 提现安全
+	Email                 string     `gorm:"size:255" json:"email,omitempty"`               // 接收验证码的邮箱
+	EmailVerified         bool       `gorm:"default:false" json:"email_verified"`           // 邮箱是否已验证
+	TOTPSecret            string     `gorm:"size:255" json:"-"`                             // TOTP 密钥（加密存储）
+	TOTPEnabled           bool       `gorm:"default:false" json:"totp_enabled"`             // 是否已启用 TOTP
+	LastTOTPStep          int64      `gorm:"default:0" json:"-"`                            // 最近一次使用的 TOTP 时间步（防重放）
+	WithdrawWhitelistOnly bool       `gorm:"default:false" json:"withdraw_whitelist_only"`  // 只允许提现到地址簿中的地址
+	WithdrawLockedUntil   *time.Time `json:"withdraw_locked_until,omitempty"`               // 安全设置变更后的提现锁定截止时间
+	WithdrawLockReason    string     `gorm:"size:50" json:"withdraw_lock_reason,omitempty"` // 锁定原因
+	CreatedAt             time.Time  `json:"created_at"`
+	UpdatedAt             time.Time  `json:"updated_at"`
 }
 
 func (u *User) BeforeCreate(tx *gorm.DB) error {
 	if u.ID == "" {
 		u.ID = utils.GenerateObjectID()
 	}
+	if u.ReferralCode == "" {
+		u.ReferralCode = utils.GenerateReferralCode()
+	}
 	// 强制转换为小写
 	u.WalletAddress = strings.ToLower(u.WalletAddress)
 	return nil
@@ -162,18 +183,24 @@ type OrderBookItem struct {
 
 // 充值记录
 type DepositRecord struct {
-	ID        string          `gorm:"primaryKey;size:24" json:"id"`
-	UserID    string          `gorm:"size:24;index;not null" json:"user_id"`
-	Asset     string          `gorm:"size:10;not null" json:"asset"`
-	Amount    decimal.Decimal `gorm:"type:decimal(30,8);not null" json:"amount"`
-	TxHash    string          `gorm:"size:66;uniqueIndex;not null" json:"tx_hash"` // 交易hash
-	Chain     string          `gorm:"size:20;not null;default:'bsc'" json:"chain"` // bsc, sepolia
-	ChainID   int             `gorm:"not null;default:56" json:"chain_id"`         // 链ID
-	Status    string          `gorm:"size:20;not null;index" json:"status"`        // pending, confirmed, failed
-	TaskID    string          `gorm:"size:24;index" json:"task_id,omitempty"`      // 关联的验证任务ID
-	CreatedAt time.Time       `json:"created_at"`
-	UpdatedAt time.Time       `json:"updated_at"`
-	User      User            `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	ID                    string          `gorm:"primaryKey;size:24" json:"id"`
+	UserID                string          `gorm:"size:24;index;not null" json:"user_id"`
+	Asset                 string          `gorm:"size:10;not null" json:"asset"`
+	Amount                decimal.Decimal `gorm:"type:decimal(30,8);not null" json:"amount"`
+	TxHash                string          `gorm:"size:66;uniqueIndex;not null" json:"tx_hash"`      // 交易hash
+	Chain                 string          `gorm:"size:20;not null;default:'bsc'" json:"chain"`      // bsc, sepolia
+	ChainID               int             `gorm:"not null;default:56" json:"chain_id"`              // 链ID
+	Status                string          `gorm:"size:20;not null;index" json:"status"`             // pending, confirming（已打包等待确认）, confirmed, failed
+	TaskID                string          `gorm:"size:24;index" json:"task_id,omitempty"`           // 关联的验证任务ID
+	Source                string          `gorm:"size:10;not null;default:'manual'" json:"source"`  // 来源：manual（用户提交hash）, scanner（链上扫描）, admin（管理员指定）
+	FromAddress           string          `gorm:"size:42" json:"from_address,omitempty"`            // 转出地址（扫描入账时记录）
+	BlockNumber           uint64          `gorm:"not null;default:0" json:"block_number"`           // 所在区块（扫描入账时记录）
+	BlockHash             string          `gorm:"size:66" json:"block_hash,omitempty"`              // 所在区块hash（入账前核对仍在主链上）
+	Confirmations         int             `gorm:"not null;default:0" json:"confirmations"`          // 当前确认数
+	RequiredConfirmations int             `gorm:"not null;default:0" json:"required_confirmations"` // 入账所需确认数（验证时按链配置记录）
+	CreatedAt             time.Time       `json:"created_at"`
+	UpdatedAt             time.Time       `json:"updated_at"`
+	User                  User            `gorm:"foreignKey:UserID" json:"user,omitempty"`
 }
 
 func (d *DepositRecord) BeforeCreate(tx *gorm.DB) error {
@@ -185,19 +212,43 @@ func (d *DepositRecord) BeforeCreate(tx *gorm.DB) error {
 
 // 提现记录
 type WithdrawRecord struct {
-	ID        string          `gorm:"primaryKey;size:24" json:"id"`
-	UserID    string          `gorm:"size:24;index;not null" json:"user_id"`
-	Asset     string          `gorm:"size:10;not null" json:"asset"`
-	Amount    decimal.Decimal `gorm:"type:decimal(30,8);not null" json:"amount"`
-	Address   string          `gorm:"size:42;not null" json:"address"`             // 提现地址
-	TxHash    string          `gorm:"size:66;index" json:"tx_hash"`                // 转账hash（成功后填充）
-	Chain     string          `gorm:"size:20;not null;default:'bsc'" json:"chain"` // bsc, sepolia
-	ChainID   int             `gorm:"not null;default:56" json:"chain_id"`         // 链ID
-	Status    string          `gorm:"size:20;not null;index" json:"status"`        // pending, processing, completed, failed
-	TaskID    string          `gorm:"size:24;index" json:"task_id,omitempty"`      // 关联的处理任务ID
-	CreatedAt time.Time       `json:"created_at"`
-	UpdatedAt time.Time       `json:"updated_at"`
-	User      User            `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	ID      string          `gorm:"primaryKey;size:24" json:"id"`
+	UserID  string          `gorm:"size:24;index;not null" json:"user_id"`
+	Asset   string          `gorm:"size:10;not null" json:"asset"`
+	Amount  decimal.Decimal `gorm:"type:decimal(30,8);not null" json:"amount"`        // 申请金额（冻结金额，含手续费）
+	Fee     decimal.Decimal `gorm:"type:decimal(30,8);not null;default:0" json:"fee"` // 提现手续费（到账金额 = Amount - Fee）
+	Address string          `gorm:"size:42;not null" json:"address"`                  // 提现地址
+	TxHash  string          `gorm:"size:66;index" json:"tx_hash"`                     // 转账hash（广播后填充，加速替换后为最新交易）
+	Chain   string          `gorm:"size:20;not null;default:'bsc'" json:"chain"`      // bsc, sepolia
+	ChainID int             `gorm:"not null;default:56" json:"chain_id"`              // 链ID
+	Status  string          `gorm:"size:20;not null;index" json:"status"`             // pending_review, pending, batching, processing, broadcast, completed, failed, rejected, review（状态无法确认，人工核对）
+	TaskID  string          `gorm:"size:24;index" json:"task_id,omitempty"`           // 关联的处理任务ID
+	BatchID string          `gorm:"size:24;index" json:"batch_id,omitempty"`          // 所属批量交易ID（批量发送时填充）
+
+	// 链上确认跟踪（broadcast 状态下由确认跟踪器轮询交易回执）
+	FromAddress      string     `gorm:"size:42" json:"from_address,omitempty"` // 发送地址（提现热钱包）
+	Nonce            uint64     `gorm:"default:0" json:"nonce"`                // 交易 nonce（加速替换时沿用）
+	TxType           string     `gorm:"size:10" json:"tx_type,omitempty"`      // 交易类型：legacy / eip1559
+	GasLimit         uint64     `gorm:"default:0" json:"gas_limit"`            // 估算后的 gas limit（加速替换时沿用）
+	GasPrice         string     `gorm:"size:78" json:"gas_price,omitempty"`    // 最近一次广播的 gas price（EIP-1559 为 max fee per gas，wei）
+	GasTipCap        string     `gorm:"size:78" json:"gas_tip_cap,omitempty"`  // EIP-1559 max priority fee per gas（wei）
+	BroadcastAt      *time.Time `json:"broadcast_at,omitempty"`                // 最近一次广播时间
+	BumpCount        int        `gorm:"default:0" json:"bump_count"`           // 加速替换次数
+	ReplacedTxHashes string     `gorm:"type:text" json:"replaced_tx_hashes"`   // 被替换的交易hash，逗号分隔
+	BlockNumber      uint64     `gorm:"default:0" json:"block_number"`         // 打包区块高度
+	Confirmations    int        `gorm:"default:0" json:"confirmations"`        // 当前确认数
+	FailReason       string     `gorm:"size:255" json:"fail_reason,omitempty"` // 失败原因
+
+	// 风控审核
+	RiskScore  int        `gorm:"default:0" json:"risk_score"`          // 风险评分（达到阈值进入人工审核）
+	RiskFlags  string     `gorm:"size:255" json:"risk_flags"`           // 命中的风控规则，逗号分隔
+	ReviewedBy string     `gorm:"size:24" json:"reviewed_by,omitempty"` // 审核管理员ID
+	ReviewedAt *time.Time `json:"reviewed_at,omitempty"`
+	ReviewNote string     `gorm:"size:255" json:"review_note,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	User      User      `gorm:"foreignKey:UserID" json:"user,omitempty"`
 }
 
 func (w *WithdrawRecord) BeforeCreate(tx *gorm.DB) error {
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"expchange-backend/utils"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// ReferralCommission 邀请返佣记录（每条手续费记录最多产生一条返佣）
+type ReferralCommission struct {
+	ID          string          `gorm:"primaryKey;size:24" json:"id"`
+	ReferrerID  string          `gorm:"size:24;index;not null" json:"referrer_id"`         // 邀请人（收佣方）
+	RefereeID   string          `gorm:"size:24;index;not null" json:"referee_id"`          // 被邀请人（交易方）
+	FeeRecordID string          `gorm:"size:24;uniqueIndex;not null" json:"fee_record_id"` // 关联的手续费记录
+	TradeID     string          `gorm:"size:24;index" json:"trade_id"`                     // 关联的成交ID
+	Asset       string          `gorm:"size:10;not null" json:"asset"`                     // 返佣资产（与手续费资产一致）
+	FeeAmount   decimal.Decimal `gorm:"type:decimal(30,8);not null" json:"fee_amount"`     // 被邀请人支付的手续费
+	Rate        decimal.Decimal `gorm:"type:decimal(10,4);not null" json:"rate"`           // 返佣比例
+	Amount      decimal.Decimal `gorm:"type:decimal(30,8);not null" json:"amount"`         // 返佣金额
+	Mode        string          `gorm:"size:20;not null" json:"mode"`                      // realtime, daily
+	Status      string          `gorm:"size:20;not null;index" json:"status"`              // settled
+	SettledAt   *time.Time      `gorm:"index" json:"settled_at,omitempty"`                 // 到账时间
+	CreatedAt   time.Time       `gorm:"index" json:"created_at"`
+	Referee     User            `gorm:"foreignKey:RefereeID" json:"referee,omitempty"`
+}
+
+func (r *ReferralCommission) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == "" {
+		r.ID = utils.GenerateObjectID()
+	}
+	return nil
+}
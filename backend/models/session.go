@@ -0,0 +1,31 @@
+package models
+
+import (
+	"expchange-backend/utils"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// UserSession 用户登录会话（每次登录一条，刷新令牌轮换时更新）
+type UserSession struct {
+	ID                string     `gorm:"primaryKey;size:24" json:"id"`
+	UserID            string     `gorm:"size:24;index;not null" json:"user_id"`
+	RefreshTokenHash  string     `gorm:"size:64;uniqueIndex;not null" json:"-"` // 当前刷新令牌的 SHA-256
+	PreviousTokenHash string     `gorm:"size:64;index" json:"-"`                // 上一个刷新令牌，用于检测令牌重放
+	UserAgent         string     `gorm:"size:255" json:"user_agent"`            // 设备信息
+	IPAddress         string     `gorm:"size:64" json:"ip_address"`             // 最近一次使用的IP
+	ExpiresAt         time.Time  `gorm:"index" json:"expires_at"`               // 刷新令牌过期时间
+	LastUsedAt        time.Time  `json:"last_used_at"`
+	RevokedAt         *time.Time `gorm:"index" json:"revoked_at,omitempty"`
+	RevokeReason      string     `gorm:"size:50" json:"revoke_reason,omitempty"` // logout, admin, reuse_detected
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
+
+func (s *UserSession) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == "" {
+		s.ID = utils.GenerateObjectID()
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"expchange-backend/utils"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// WalletBalanceSnapshot 钱包余额快照（余额监控每轮按链、资产记录一条）
+// 状态：ok 正常；low 低于告警值或不足以支付待发出提现；paused 低于暂停值，已暂停提现；error 链上余额读取失败
+type WalletBalanceSnapshot struct {
+	ID                 string          `gorm:"primaryKey;size:24" json:"id"`
+	ChainID            int             `gorm:"not null;index:idx_wallet_snapshot_chain_asset" json:"chain_id"`
+	Chain              string          `gorm:"size:100;not null" json:"chain"`
+	Asset              string          `gorm:"size:10;not null;index:idx_wallet_snapshot_chain_asset" json:"asset"`
+	HotAddress         string          `gorm:"size:42" json:"hot_address"`                                       // 提现热钱包地址
+	HotBalance         decimal.Decimal `gorm:"type:decimal(30,8);not null;default:0" json:"hot_balance"`         // 热钱包链上余额
+	ColdAddress        string          `gorm:"size:42" json:"cold_address"`                                      // 冷钱包地址（未配置为空）
+	ColdBalance        decimal.Decimal `gorm:"type:decimal(30,8);not null;default:0" json:"cold_balance"`        // 冷钱包链上余额
+	PendingWithdrawals decimal.Decimal `gorm:"type:decimal(30,8);not null;default:0" json:"pending_withdrawals"` // 该链该资产尚未确认的提现（到账金额）
+	Liabilities        decimal.Decimal `gorm:"type:decimal(30,8);not null;default:0" json:"liabilities"`         // 该资产全部用户余额（可用+冻结，所有链合计）
+	Status             string          `gorm:"size:20;not null" json:"status"`
+	Message            string          `gorm:"size:255" json:"message"`
+	CreatedAt          time.Time       `gorm:"index" json:"created_at"`
+}
+
+func (s *WalletBalanceSnapshot) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == "" {
+		s.ID = utils.GenerateObjectID()
+	}
+	return nil
+}
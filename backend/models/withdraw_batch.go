@@ -0,0 +1,45 @@
+package models
+
+import (
+	"expchange-backend/utils"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// WithdrawBatch 批量提现交易（同一条链、同一代币的多笔提现合并为一次 multisend 合约调用）
+// 交易的确认跟踪和加速替换在批量层面进行，包含的提现记录通过 WithdrawRecord.BatchID 关联
+type WithdrawBatch struct {
+	ID              string          `gorm:"primaryKey;size:24" json:"id"`
+	ChainID         int             `gorm:"not null;index" json:"chain_id"`
+	Asset           string          `gorm:"size:10;not null;default:'USDT'" json:"asset"`    // 批量转出的代币（同一批次只包含同一资产）
+	ContractAddress string          `gorm:"size:42;not null" json:"contract_address"`        // multisend 合约地址
+	Status          string          `gorm:"size:20;not null;index" json:"status"`            // processing, broadcast, completed, failed, review（状态无法确认，人工核对）
+	RecordCount     int             `gorm:"not null;default:0" json:"record_count"`          // 包含的提现笔数
+	TotalAmount     decimal.Decimal `gorm:"type:decimal(30,8);not null" json:"total_amount"` // 链上转出总额（扣除手续费后）
+
+	FromAddress      string     `gorm:"size:42" json:"from_address"`
+	Nonce            uint64     `gorm:"default:0" json:"nonce"`
+	TxType           string     `gorm:"size:10" json:"tx_type"`
+	GasLimit         uint64     `gorm:"default:0" json:"gas_limit"`
+	GasPrice         string     `gorm:"size:78" json:"gas_price"`   // gas price（EIP-1559 为 max fee per gas，wei）
+	GasTipCap        string     `gorm:"size:78" json:"gas_tip_cap"` // EIP-1559 max priority fee per gas（wei）
+	TxHash           string     `gorm:"size:66;index" json:"tx_hash"`
+	ReplacedTxHashes string     `gorm:"type:text" json:"replaced_tx_hashes"` // 被替换的交易hash，逗号分隔
+	BumpCount        int        `gorm:"default:0" json:"bump_count"`
+	BroadcastAt      *time.Time `json:"broadcast_at,omitempty"`
+	BlockNumber      uint64     `gorm:"default:0" json:"block_number"`
+	Confirmations    int        `gorm:"default:0" json:"confirmations"`
+	FailReason       string     `gorm:"size:255" json:"fail_reason,omitempty"` // 失败原因（失败后包含的提现回退为单笔发送）
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (b *WithdrawBatch) BeforeCreate(tx *gorm.DB) error {
+	if b.ID == "" {
+		b.ID = utils.GenerateObjectID()
+	}
+	return nil
+}
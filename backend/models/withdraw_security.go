@@ -0,0 +1,53 @@
+package models
+
+import (
+	"expchange-backend/utils"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// WithdrawAddress 提现地址簿（新地址在锁定期结束后才能使用）
+type WithdrawAddress struct {
+	ID          string    `gorm:"primaryKey;size:24" json:"id"`
+	UserID      string    `gorm:"size:24;not null;uniqueIndex:idx_withdraw_address_user" json:"user_id"`
+	Address     string    `gorm:"size:42;not null;uniqueIndex:idx_withdraw_address_user" json:"address"` // 小写地址
+	Label       string    `gorm:"size:50" json:"label"`
+	ActivatedAt time.Time `json:"activated_at"` // 锁定期结束时间（之后才可提现到该地址）
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func (a *WithdrawAddress) BeforeCreate(tx *gorm.DB) error {
+	if a.ID == "" {
+		a.ID = utils.GenerateObjectID()
+	}
+	return nil
+}
+
+// 验证码用途
+const (
+	VerificationPurposeWithdraw    = "withdraw"     // 提现确认
+	VerificationPurposeSecurity    = "security"     // 修改安全设置（地址簿、白名单、TOTP）
+	VerificationPurposeEmailVerify = "email_verify" // 绑定邮箱
+)
+
+// VerificationCode 邮件验证码（只保存哈希，一次性使用）
+type VerificationCode struct {
+	ID        string     `gorm:"primaryKey;size:24" json:"id"`
+	UserID    string     `gorm:"size:24;index;not null" json:"user_id"`
+	Purpose   string     `gorm:"size:20;not null" json:"purpose"`
+	Target    string     `gorm:"size:255" json:"target"`    // 发送目标（邮箱）
+	CodeHash  string     `gorm:"size:64;not null" json:"-"` // 验证码 SHA-256
+	Attempts  int        `gorm:"default:0" json:"attempts"` // 校验失败次数
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func (v *VerificationCode) BeforeCreate(tx *gorm.DB) error {
+	if v.ID == "" {
+		v.ID = utils.GenerateObjectID()
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package hdwallet
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"math/big"
+)
+
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+var errInvalidBase58 = errors.New("invalid base58check string")
+
+// base58CheckEncode 追加 4 字节双 SHA-256 校验和后 base58 编码
+func base58CheckEncode(payload []byte) string {
+	data := append(append([]byte{}, payload...), checksum(payload)...)
+
+	n := new(big.Int).SetBytes(data)
+	radix := big.NewInt(58)
+	mod := new(big.Int)
+	var out []byte
+	for n.Sign() > 0 {
+		n.DivMod(n, radix, mod)
+		out = append(out, base58Alphabet[mod.Int64()])
+	}
+	// 前导零字节编码为 '1'
+	for _, b := range data {
+		if b != 0 {
+			break
+		}
+		out = append(out, base58Alphabet[0])
+	}
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return string(out)
+}
+
+// base58CheckDecode base58 解码并校验末尾 4 字节校验和
+func base58CheckDecode(encoded string) ([]byte, error) {
+	n := new(big.Int)
+	radix := big.NewInt(58)
+	for _, c := range []byte(encoded) {
+		digit := bytes.IndexByte([]byte(base58Alphabet), c)
+		if digit < 0 {
+			return nil, errInvalidBase58
+		}
+		n.Mul(n, radix)
+		n.Add(n, big.NewInt(int64(digit)))
+	}
+
+	var leading int
+	for leading < len(encoded) && encoded[leading] == base58Alphabet[0] {
+		leading++
+	}
+	data := append(make([]byte, leading), n.Bytes()...)
+	if len(data) < 4 {
+		return nil, errInvalidBase58
+	}
+
+	payload, sum := data[:len(data)-4], data[len(data)-4:]
+	if !bytes.Equal(checksum(payload), sum) {
+		return nil, errInvalidBase58
+	}
+	return payload, nil
+}
+
+func checksum(payload []byte) []byte {
+	first := sha256.Sum256(payload)
+	second := sha256.Sum256(first[:])
+	return second[:4]
+}
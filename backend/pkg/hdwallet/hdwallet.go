@@ -0,0 +1,228 @@
+package hdwallet
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"golang.org/x/crypto/ripemd160"
+)
+
+// HardenedOffset 强化派生序号起点（i ≥ 2^31）
+const HardenedOffset uint32 = 0x80000000
+
+// BIP-32 主网序列化版本号
+var (
+	versionPublic  = []byte{0x04, 0x88, 0xb2, 0x1e} // xpub
+	versionPrivate = []byte{0x04, 0x88, 0xad, 0xe4} // xprv
+)
+
+var (
+	ErrInvalidExtendedKey = errors.New("invalid extended key")
+	ErrHardenedFromPublic = errors.New("cannot derive a hardened child from a public key")
+	ErrInvalidChild       = errors.New("derived key is invalid, use the next index")
+)
+
+// ExtendedKey BIP-32 扩展密钥（xpub 只能派生非强化子密钥，xprv 还可以得到私钥）
+type ExtendedKey struct {
+	depth       byte
+	parentFP    []byte
+	childNumber uint32
+	chainCode   []byte
+	publicKey   *ecdsa.PublicKey
+	privateKey  *ecdsa.PrivateKey // xpub 为 nil
+}
+
+// NewMasterKey 由种子生成主私钥（m）
+func NewMasterKey(seed []byte) (*ExtendedKey, error) {
+	if len(seed) < 16 || len(seed) > 64 {
+		return nil, errors.New("seed must be 16 to 64 bytes")
+	}
+	mac := hmac.New(sha512.New, []byte("Bitcoin seed"))
+	mac.Write(seed)
+	sum := mac.Sum(nil)
+
+	privateKey, err := crypto.ToECDSA(sum[:32])
+	if err != nil {
+		return nil, ErrInvalidChild
+	}
+	return &ExtendedKey{
+		parentFP:   make([]byte, 4),
+		chainCode:  sum[32:],
+		publicKey:  &privateKey.PublicKey,
+		privateKey: privateKey,
+	}, nil
+}
+
+// ParseExtendedKey 解析 base58check 编码的 xpub / xprv
+func ParseExtendedKey(encoded string) (*ExtendedKey, error) {
+	payload, err := base58CheckDecode(strings.TrimSpace(encoded))
+	if err != nil || len(payload) != 78 {
+		return nil, ErrInvalidExtendedKey
+	}
+
+	key := &ExtendedKey{
+		depth:       payload[4],
+		parentFP:    payload[5:9],
+		childNumber: binary.BigEndian.Uint32(payload[9:13]),
+		chainCode:   payload[13:45],
+	}
+	keyData := payload[45:78]
+
+	switch {
+	case bytes.Equal(payload[:4], versionPublic):
+		if key.publicKey, err = crypto.DecompressPubkey(keyData); err != nil {
+			return nil, ErrInvalidExtendedKey
+		}
+	case bytes.Equal(payload[:4], versionPrivate):
+		if keyData[0] != 0 {
+			return nil, ErrInvalidExtendedKey
+		}
+		if key.privateKey, err = crypto.ToECDSA(keyData[1:]); err != nil {
+			return nil, ErrInvalidExtendedKey
+		}
+		key.publicKey = &key.privateKey.PublicKey
+	default:
+		return nil, ErrInvalidExtendedKey
+	}
+	return key, nil
+}
+
+// IsPrivate 是否为扩展私钥
+func (k *ExtendedKey) IsPrivate() bool {
+	return k.privateKey != nil
+}
+
+// Neuter 对应的扩展公钥
+func (k *ExtendedKey) Neuter() *ExtendedKey {
+	return &ExtendedKey{
+		depth:       k.depth,
+		parentFP:    k.parentFP,
+		childNumber: k.childNumber,
+		chainCode:   k.chainCode,
+		publicKey:   k.publicKey,
+	}
+}
+
+// String base58check 编码（xpub / xprv）
+func (k *ExtendedKey) String() string {
+	payload := make([]byte, 0, 78)
+	if k.IsPrivate() {
+		payload = append(payload, versionPrivate...)
+	} else {
+		payload = append(payload, versionPublic...)
+	}
+	payload = append(payload, k.depth)
+	payload = append(payload, k.parentFP...)
+	payload = binary.BigEndian.AppendUint32(payload, k.childNumber)
+	payload = append(payload, k.chainCode...)
+	if k.IsPrivate() {
+		payload = append(payload, 0)
+		payload = append(payload, common.LeftPadBytes(k.privateKey.D.Bytes(), 32)...)
+	} else {
+		payload = append(payload, crypto.CompressPubkey(k.publicKey)...)
+	}
+	return base58CheckEncode(payload)
+}
+
+// Child 派生第 i 个子密钥（CKDpriv / CKDpub）
+func (k *ExtendedKey) Child(i uint32) (*ExtendedKey, error) {
+	hardened := i >= HardenedOffset
+	if hardened && !k.IsPrivate() {
+		return nil, ErrHardenedFromPublic
+	}
+
+	compressed := crypto.CompressPubkey(k.publicKey)
+	data := make([]byte, 0, 37)
+	if hardened {
+		data = append(data, 0)
+		data = append(data, common.LeftPadBytes(k.privateKey.D.Bytes(), 32)...)
+	} else {
+		data = append(data, compressed...)
+	}
+	data = binary.BigEndian.AppendUint32(data, i)
+
+	mac := hmac.New(sha512.New, k.chainCode)
+	mac.Write(data)
+	sum := mac.Sum(nil)
+
+	curve := crypto.S256()
+	il := new(big.Int).SetBytes(sum[:32])
+	if il.Cmp(curve.Params().N) >= 0 {
+		return nil, ErrInvalidChild
+	}
+
+	child := &ExtendedKey{
+		depth:       k.depth + 1,
+		parentFP:    fingerprint(compressed),
+		childNumber: i,
+		chainCode:   sum[32:],
+	}
+
+	if k.IsPrivate() {
+		d := new(big.Int).Add(il, k.privateKey.D)
+		d.Mod(d, curve.Params().N)
+		if d.Sign() == 0 {
+			return nil, ErrInvalidChild
+		}
+		privateKey, err := crypto.ToECDSA(common.LeftPadBytes(d.Bytes(), 32))
+		if err != nil {
+			return nil, ErrInvalidChild
+		}
+		child.privateKey = privateKey
+		child.publicKey = &privateKey.PublicKey
+		return child, nil
+	}
+
+	x, y := curve.ScalarBaseMult(sum[:32])
+	x, y = curve.Add(x, y, k.publicKey.X, k.publicKey.Y)
+	if x.Sign() == 0 && y.Sign() == 0 {
+		return nil, ErrInvalidChild
+	}
+	child.publicKey = &ecdsa.PublicKey{Curve: curve, X: x, Y: y}
+	return child, nil
+}
+
+// Derive 依次派生路径上的子密钥
+func (k *ExtendedKey) Derive(path ...uint32) (*ExtendedKey, error) {
+	key := k
+	for _, i := range path {
+		next, err := key.Child(i)
+		if err != nil {
+			return nil, fmt.Errorf("derive %d: %w", i, err)
+		}
+		key = next
+	}
+	return key, nil
+}
+
+// Address 以太坊地址
+func (k *ExtendedKey) Address() common.Address {
+	return crypto.PubkeyToAddress(*k.publicKey)
+}
+
+// PrivateKeyHex 十六进制私钥（仅扩展私钥）
+func (k *ExtendedKey) PrivateKeyHex() (string, error) {
+	if !k.IsPrivate() {
+		return "", errors.New("extended key has no private key")
+	}
+	return hex.EncodeToString(common.LeftPadBytes(k.privateKey.D.Bytes(), 32)), nil
+}
+
+// fingerprint 父密钥指纹：HASH160(公钥) 前 4 字节
+func fingerprint(compressed []byte) []byte {
+	sha := sha256.Sum256(compressed)
+	hasher := ripemd160.New()
+	hasher.Write(sha[:])
+	return hasher.Sum(nil)[:4]
+}
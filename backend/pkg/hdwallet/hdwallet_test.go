@@ -0,0 +1,231 @@
+package hdwallet
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+const h = HardenedOffset
+
+// bip32Key 测试向量中的一个派生路径及其序列化结果
+type bip32Key struct {
+	path []uint32
+	xpub string
+	xprv string
+}
+
+// bip32Vectors BIP-32 官方测试向量 1-3
+var bip32Vectors = []struct {
+	name string
+	seed string
+	keys []bip32Key
+}{
+	{
+		name: "vector 1",
+		seed: "000102030405060708090a0b0c0d0e0f",
+		keys: []bip32Key{
+			{
+				path: nil,
+				xpub: "xpub661MyMwAqRbcFtXgS5sYJABqqG9YLmC4Q1Rdap9gSE8NqtwybGhePY2gZ29ESFjqJoCu1Rupje8YtGqsefD265TMg7usUDFdp6W1EGMcet8",
+				xprv: "xprv9s21ZrQH143K3QTDL4LXw2F7HEK3wJUD2nW2nRk4stbPy6cq3jPPqjiChkVvvNKmPGJxWUtg6LnF5kejMRNNU3TGtRBeJgk33yuGBxrMPHi",
+			},
+			{
+				path: []uint32{0 + h},
+				xpub: "xpub68Gmy5EdvgibQVfPdqkBBCHxA5htiqg55crXYuXoQRKfDBFA1WEjWgP6LHhwBZeNK1VTsfTFUHCdrfp1bgwQ9xv5ski8PX9rL2dZXvgGDnw",
+				xprv: "xprv9uHRZZhk6KAJC1avXpDAp4MDc3sQKNxDiPvvkX8Br5ngLNv1TxvUxt4cV1rGL5hj6KCesnDYUhd7oWgT11eZG7XnxHrnYeSvkzY7d2bhkJ7",
+			},
+			{
+				path: []uint32{0 + h, 1},
+				xpub: "xpub6ASuArnXKPbfEwhqN6e3mwBcDTgzisQN1wXN9BJcM47sSikHjJf3UFHKkNAWbWMiGj7Wf5uMash7SyYq527Hqck2AxYysAA7xmALppuCkwQ",
+				xprv: "xprv9wTYmMFdV23N2TdNG573QoEsfRrWKQgWeibmLntzniatZvR9BmLnvSxqu53Kw1UmYPxLgboyZQaXwTCg8MSY3H2EU4pWcQDnRnrVA1xe8fs",
+			},
+			{
+				path: []uint32{0 + h, 1, 2 + h},
+				xpub: "xpub6D4BDPcP2GT577Vvch3R8wDkScZWzQzMMUm3PWbmWvVJrZwQY4VUNgqFJPMM3No2dFDFGTsxxpG5uJh7n7epu4trkrX7x7DogT5Uv6fcLW5",
+				xprv: "xprv9z4pot5VBttmtdRTWfWQmoH1taj2axGVzFqSb8C9xaxKymcFzXBDptWmT7FwuEzG3ryjH4ktypQSAewRiNMjANTtpgP4mLTj34bhnZX7UiM",
+			},
+			{
+				path: []uint32{0 + h, 1, 2 + h, 2},
+				xpub: "xpub6FHa3pjLCk84BayeJxFW2SP4XRrFd1JYnxeLeU8EqN3vDfZmbqBqaGJAyiLjTAwm6ZLRQUMv1ZACTj37sR62cfN7fe5JnJ7dh8zL4fiyLHV",
+				xprv: "xprvA2JDeKCSNNZky6uBCviVfJSKyQ1mDYahRjijr5idH2WwLsEd4Hsb2Tyh8RfQMuPh7f7RtyzTtdrbdqqsunu5Mm3wDvUAKRHSC34sJ7in334",
+			},
+			{
+				path: []uint32{0 + h, 1, 2 + h, 2, 1000000000},
+				xpub: "xpub6H1LXWLaKsWFhvm6RVpEL9P4KfRZSW7abD2ttkWP3SSQvnyA8FSVqNTEcYFgJS2UaFcxupHiYkro49S8yGasTvXEYBVPamhGW6cFJodrTHy",
+				xprv: "xprvA41z7zogVVwxVSgdKUHDy1SKmdb533PjDz7J6N6mV6uS3ze1ai8FHa8kmHScGpWmj4WggLyQjgPie1rFSruoUihUZREPSL39UNdE3BBDu76",
+			},
+		},
+	},
+	{
+		name: "vector 2",
+		seed: "fffcf9f6f3f0edeae7e4e1dedbd8d5d2cfccc9c6c3c0bdbab7b4b1aeaba8a5a29f9c999693908d8a8784817e7b7875726f6c696663605d5a5754514e4b484542",
+		keys: []bip32Key{
+			{
+				path: nil,
+				xpub: "xpub661MyMwAqRbcFW31YEwpkMuc5THy2PSt5bDMsktWQcFF8syAmRUapSCGu8ED9W6oDMSgv6Zz8idoc4a6mr8BDzTJY47LJhkJ8UB7WEGuduB",
+				xprv: "xprv9s21ZrQH143K31xYSDQpPDxsXRTUcvj2iNHm5NUtrGiGG5e2DtALGdso3pGz6ssrdK4PFmM8NSpSBHNqPqm55Qn3LqFtT2emdEXVYsCzC2U",
+			},
+			{
+				path: []uint32{0},
+				xpub: "xpub69H7F5d8KSRgmmdJg2KhpAK8SR3DjMwAdkxj3ZuxV27CprR9LgpeyGmXUbC6wb7ERfvrnKZjXoUmmDznezpbZb7ap6r1D3tgFxHmwMkQTPH",
+				xprv: "xprv9vHkqa6EV4sPZHYqZznhT2NPtPCjKuDKGY38FBWLvgaDx45zo9WQRUT3dKYnjwih2yJD9mkrocEZXo1ex8G81dwSM1fwqWpWkeS3v86pgKt",
+			},
+			{
+				path: []uint32{0, 2147483647 + h},
+				xpub: "xpub6ASAVgeehLbnwdqV6UKMHVzgqAG8Gr6riv3Fxxpj8ksbH9ebxaEyBLZ85ySDhKiLDBrQSARLq1uNRts8RuJiHjaDMBU4Zn9h8LZNnBC5y4a",
+				xprv: "xprv9wSp6B7kry3Vj9m1zSnLvN3xH8RdsPP1Mh7fAaR7aRLcQMKTR2vidYEeEg2mUCTAwCd6vnxVrcjfy2kRgVsFawNzmjuHc2YmYRmagcEPdU9",
+			},
+			{
+				path: []uint32{0, 2147483647 + h, 1},
+				xpub: "xpub6DF8uhdarytz3FWdA8TvFSvvAh8dP3283MY7p2V4SeE2wyWmG5mg5EwVvmdMVCQcoNJxGoWaU9DCWh89LojfZ537wTfunKau47EL2dhHKon",
+				xprv: "xprv9zFnWC6h2cLgpmSA46vutJzBcfJ8yaJGg8cX1e5StJh45BBciYTRXSd25UEPVuesF9yog62tGAQtHjXajPPdbRCHuWS6T8XA2ECKADdw4Ef",
+			},
+			{
+				path: []uint32{0, 2147483647 + h, 1, 2147483646 + h},
+				xpub: "xpub6ERApfZwUNrhLCkDtcHTcxd75RbzS1ed54G1LkBUHQVHQKqhMkhgbmJbZRkrgZw4koxb5JaHWkY4ALHY2grBGRjaDMzQLcgJvLJuZZvRcEL",
+				xprv: "xprvA1RpRA33e1JQ7ifknakTFpgNXPmW2YvmhqLQYMmrj4xJXXWYpDPS3xz7iAxn8L39njGVyuoseXzU6rcxFLJ8HFsTjSyQbLYnMpCqE2VbFWc",
+			},
+			{
+				path: []uint32{0, 2147483647 + h, 1, 2147483646 + h, 2},
+				xpub: "xpub6FnCn6nSzZAw5Tw7cgR9bi15UV96gLZhjDstkXXxvCLsUXBGXPdSnLFbdpq8p9HmGsApME5hQTZ3emM2rnY5agb9rXpVGyy3bdW6EEgAtqt",
+				xprv: "xprvA2nrNbFZABcdryreWet9Ea4LvTJcGsqrMzxHx98MMrotbir7yrKCEXw7nadnHM8Dq38EGfSh6dqA9QWTyefMLEcBYJUuekgW4BYPJcr9E7j",
+			},
+		},
+	},
+	{
+		// 私钥带前导零，检查序列化补齐到 32 字节
+		name: "vector 3",
+		seed: "4b381541583be4423346c643850da4b320e46a87ae3d2a4e6da11eba819cd4acba45d239319ac14f863b8d5ab5a0d0c64d2e8a1e7d1457df2e5a3c51c73235be",
+		keys: []bip32Key{
+			{
+				path: nil,
+				xpub: "xpub661MyMwAqRbcEZVB4dScxMAdx6d4nFc9nvyvH3v4gJL378CSRZiYmhRoP7mBy6gSPSCYk6SzXPTf3ND1cZAceL7SfJ1Z3GC8vBgp2epUt13",
+				xprv: "xprv9s21ZrQH143K25QhxbucbDDuQ4naNntJRi4KUfWT7xo4EKsHt2QJDu7KXp1A3u7Bi1j8ph3EGsZ9Xvz9dGuVrtHHs7pXeTzjuxBrCmmhgC6",
+			},
+			{
+				path: []uint32{0 + h},
+				xpub: "xpub68NZiKmJWnxxS6aaHmn81bvJeTESw724CRDs6HbuccFQN9Ku14VQrADWgqbhhTHBaohPX4CjNLf9fq9MYo6oDaPPLPxSb7gwQN3ih19Zm4Y",
+				xprv: "xprv9uPDJpEQgRQfDcW7BkF7eTya6RPxXeJCqCJGHuCJ4GiRVLzkTXBAJMu2qaMWPrS7AANYqdq6vcBcBUdJCVVFceUvJFjaPdGZ2y9WACViL4L",
+			},
+		},
+	},
+}
+
+func TestBIP32Vectors(t *testing.T) {
+	for _, vector := range bip32Vectors {
+		seed, err := hex.DecodeString(vector.seed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		master, err := NewMasterKey(seed)
+		if err != nil {
+			t.Fatalf("%s: NewMasterKey: %v", vector.name, err)
+		}
+		for _, want := range vector.keys {
+			key, err := master.Derive(want.path...)
+			if err != nil {
+				t.Fatalf("%s %v: Derive: %v", vector.name, want.path, err)
+			}
+			if got := key.String(); got != want.xprv {
+				t.Fatalf("%s %v: xprv=%s, want %s", vector.name, want.path, got, want.xprv)
+			}
+			if got := key.Neuter().String(); got != want.xpub {
+				t.Fatalf("%s %v: xpub=%s, want %s", vector.name, want.path, got, want.xpub)
+			}
+
+			// 序列化后重新解析得到相同的密钥
+			for _, encoded := range []string{want.xprv, want.xpub} {
+				parsed, err := ParseExtendedKey(encoded)
+				if err != nil {
+					t.Fatalf("%s %v: ParseExtendedKey(%s): %v", vector.name, want.path, encoded, err)
+				}
+				if parsed.String() != encoded {
+					t.Fatalf("%s %v: round trip=%s, want %s", vector.name, want.path, parsed.String(), encoded)
+				}
+			}
+		}
+	}
+}
+
+// TestPublicDerivationMatchesPrivate 充值地址由 xpub 派生，必须与 xprv 派生的私钥对应同一地址
+func TestPublicDerivationMatchesPrivate(t *testing.T) {
+	for _, vector := range bip32Vectors {
+		seed, _ := hex.DecodeString(vector.seed)
+		master, err := NewMasterKey(seed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		account, err := master.Derive(44+h, 60+h, 0+h, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		xpub, err := ParseExtendedKey(account.Neuter().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if xpub.IsPrivate() {
+			t.Fatalf("%s: parsed xpub reports a private key", vector.name)
+		}
+
+		for _, index := range []uint32{0, 1, 7, 1000} {
+			private, err := account.Child(index)
+			if err != nil {
+				t.Fatal(err)
+			}
+			public, err := xpub.Child(index)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if public.Address() != private.Address() {
+				t.Fatalf("%s index %d: xpub address=%s, xprv address=%s", vector.name, index, public.Address().Hex(), private.Address().Hex())
+			}
+			if public.String() != private.Neuter().String() {
+				t.Fatalf("%s index %d: xpub child=%s, want %s", vector.name, index, public.String(), private.Neuter().String())
+			}
+			if _, err := public.PrivateKeyHex(); err == nil {
+				t.Fatalf("%s index %d: public child returned a private key", vector.name, index)
+			}
+		}
+	}
+}
+
+func TestHardenedFromPublicRejected(t *testing.T) {
+	xpub, err := ParseExtendedKey(bip32Vectors[0].keys[0].xpub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := xpub.Child(0 + h); !errors.Is(err, ErrHardenedFromPublic) {
+		t.Fatalf("Child(0H) err=%v, want ErrHardenedFromPublic", err)
+	}
+	if _, err := xpub.Derive(0, 1+h); !errors.Is(err, ErrHardenedFromPublic) {
+		t.Fatalf("Derive(0/1H) err=%v, want ErrHardenedFromPublic", err)
+	}
+}
+
+func TestParseExtendedKeyInvalid(t *testing.T) {
+	valid := bip32Vectors[0].keys[0].xpub
+	// 修改最后一个字符，校验和不再匹配
+	last := valid[len(valid)-1]
+	replacement := byte('9')
+	if last == replacement {
+		replacement = '8'
+	}
+
+	tests := []struct {
+		name    string
+		encoded string
+	}{
+		{name: "bad checksum", encoded: valid[:len(valid)-1] + string(replacement)},
+		{name: "invalid character", encoded: valid[:10] + "0" + valid[11:]},
+		{name: "truncated", encoded: valid[:len(valid)-8]},
+		{name: "empty", encoded: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseExtendedKey(tt.encoded); !errors.Is(err, ErrInvalidExtendedKey) {
+				t.Fatalf("ParseExtendedKey err=%v, want ErrInvalidExtendedKey", err)
+			}
+		})
+	}
+}
@@ -2,15 +2,19 @@ package noncemanager
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math/big"
 	"sync"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/ethclient"
 	"gorm.io/gorm"
 )
 
+// ErrSkipNonce GapFiller 返回该错误表示该 nonce 由其他流程负责恢复（例如已广播提现的加速替换），对账时跳过
+var ErrSkipNonce = errors.New("nonce is handled elsewhere")
+
 // WalletNonce 钱包nonce记录
 type WalletNonce struct {
 	ID        uint      `gorm:"primaryKey"`
@@ -20,13 +24,58 @@ type WalletNonce struct {
 	UpdatedAt time.Time `gorm:"autoUpdateTime"`
 }
 
+// ChainClient 对账所需的链上查询接口（ethclient.Client 已实现）
+type ChainClient interface {
+	PendingNonceAt(ctx context.Context, account common.Address) (uint64, error)
+	NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error)
+}
+
+// GapFiller 使用指定 nonce 发送一笔交易（通常是零值自转账）
+// replace 为 true 表示替换交易池中卡住的交易，需要提高 gas price
+type GapFiller func(ctx context.Context, nonce uint64, replace bool) error
+
+// ReconcileOptions 对账选项
+type ReconcileOptions struct {
+	Fill       GapFiller     // 为 nil 时只校准本地 nonce，不补洞也不替换
+	StuckAfter time.Duration // 最低未打包 nonce 超过该时长不变时用 Fill 替换（0 表示不替换）
+}
+
+// ReconcileResult 对账结果
+type ReconcileResult struct {
+	Local    uint64   // 对账前本地记录的下一个 nonce
+	Next     uint64   // 对账后本地记录的下一个 nonce
+	Latest   uint64   // 已打包的交易数（NonceAt latest）
+	Pending  uint64   // 含交易池的下一个 nonce（PendingNonceAt）
+	Filled   []uint64 // 已补洞的 nonce
+	Replaced []uint64 // 已替换的卡住 nonce
+}
+
+// stuckNonce 最低未打包 nonce 及首次观察到的时间
+type stuckNonce struct {
+	nonce uint64
+	since time.Time
+}
+
 // NonceManager Nonce 管理器（线程安全）
 type NonceManager struct {
 	db         *gorm.DB
 	locks      map[string]*sync.Mutex // address_chainid -> mutex
 	locksMutex sync.RWMutex           // 保护 locks map
-	nonceCache map[string]uint64      // address_chainid -> nonce (内存缓存)
+	nonceCache map[string]uint64      // address_chainid -> 下一个可用 nonce (内存缓存)
 	cacheMutex sync.RWMutex           // 保护 cache
+	stuck      map[string]stuckNonce  // address_chainid -> 最低未打包 nonce（在钱包锁内访问）
+	stuckMutex sync.Mutex             // 保护 stuck map
+}
+
+// NonceLease 已分配但尚未使用的 nonce，持有期间钱包锁不释放
+// 交易发送成功后调用 Commit，失败时调用 Rollback 归还 nonce
+type NonceLease struct {
+	Nonce   uint64
+	nm      *NonceManager
+	address string
+	chainID int
+	lock    *sync.Mutex
+	done    bool
 }
 
 // NewNonceManager 创建 Nonce 管理器
@@ -38,12 +87,17 @@ func NewNonceManager(db *gorm.DB) *NonceManager {
 		db:         db,
 		locks:      make(map[string]*sync.Mutex),
 		nonceCache: make(map[string]uint64),
+		stuck:      make(map[string]stuckNonce),
 	}
 }
 
+func nonceKey(address string, chainID int) string {
+	return fmt.Sprintf("%s_%d", address, chainID)
+}
+
 // getOrCreateLock 获取或创建钱包的互斥锁
 func (nm *NonceManager) getOrCreateLock(address string, chainID int) *sync.Mutex {
-	key := fmt.Sprintf("%s_%d", address, chainID)
+	key := nonceKey(address, chainID)
 
 	nm.locksMutex.RLock()
 	lock, exists := nm.locks[key]
@@ -68,146 +122,223 @@ func (nm *NonceManager) getOrCreateLock(address string, chainID int) *sync.Mutex
 }
 
 // AcquireNonce 获取下一个可用的 nonce（线程安全）
-// 返回：nonce, release函数, error
-func (nm *NonceManager) AcquireNonce(rpcURL, address string, chainID int) (uint64, func(), error) {
-	// 1. 获取该钱包的锁
+// 返回的 lease 持有钱包锁：发送成功后 Commit，失败时 Rollback（可 defer Rollback，Commit 后为空操作）
+func (nm *NonceManager) AcquireNonce(client ChainClient, address string, chainID int) (*NonceLease, error) {
 	lock := nm.getOrCreateLock(address, chainID)
 	lock.Lock()
 
-	// 2. 从缓存或数据库获取当前 nonce
-	cacheKey := fmt.Sprintf("%s_%d", address, chainID)
+	nonce, err := nm.loadNonce(address, chainID, func() (uint64, error) {
+		// 第一次使用，从链上查询
+		return client.PendingNonceAt(context.Background(), common.HexToAddress(address))
+	})
+	if err != nil {
+		lock.Unlock()
+		return nil, err
+	}
 
-	nm.cacheMutex.RLock()
-	cachedNonce, hasCached := nm.nonceCache[cacheKey]
-	nm.cacheMutex.RUnlock()
+	return &NonceLease{
+		Nonce:   nonce,
+		nm:      nm,
+		address: address,
+		chainID: chainID,
+		lock:    lock,
+	}, nil
+}
 
-	var currentNonce uint64
+// Commit 确认 nonce 已被使用：同步持久化下一个 nonce 并释放钱包锁
+// 交易已发出，持久化失败时内存仍会前进，返回的错误仅用于告警
+func (l *NonceLease) Commit() error {
+	if l.done {
+		return nil
+	}
+	l.done = true
+	defer l.lock.Unlock()
 
+	return l.nm.store(l.address, l.chainID, l.Nonce+1)
+}
+
+// Rollback 归还未使用的 nonce 并释放钱包锁（已 Commit 或 Rollback 时为空操作）
+func (l *NonceLease) Rollback() {
+	if l.done {
+		return
+	}
+	l.done = true
+	l.lock.Unlock()
+}
+
+// loadNonce 从缓存或数据库读取下一个可用 nonce，都没有时用 initial 初始化（调用方持有钱包锁）
+func (nm *NonceManager) loadNonce(address string, chainID int, initial func() (uint64, error)) (uint64, error) {
+	key := nonceKey(address, chainID)
+
+	nm.cacheMutex.RLock()
+	cachedNonce, hasCached := nm.nonceCache[key]
+	nm.cacheMutex.RUnlock()
 	if hasCached {
-		// 使用缓存的 nonce
-		currentNonce = cachedNonce
-	} else {
-		// 从数据库加载
-		var record WalletNonce
-		err := nm.db.Where("address = ? AND chain_id = ?", address, chainID).First(&record).Error
-
-		if err == gorm.ErrRecordNotFound {
-			// 第一次使用，从链上查询
-			onChainNonce, err := nm.getOnChainNonce(rpcURL, address)
-			if err != nil {
-				lock.Unlock()
-				return 0, nil, fmt.Errorf("failed to get on-chain nonce: %w", err)
-			}
-			currentNonce = onChainNonce
-
-			// 保存到数据库
-			record = WalletNonce{
-				Address: address,
-				ChainID: chainID,
-				Nonce:   currentNonce,
-			}
-			nm.db.Create(&record)
-		} else if err != nil {
-			lock.Unlock()
-			return 0, nil, fmt.Errorf("failed to query nonce: %w", err)
-		} else {
-			currentNonce = record.Nonce
-		}
+		return cachedNonce, nil
+	}
 
-		// 更新缓存
-		nm.cacheMutex.Lock()
-		nm.nonceCache[cacheKey] = currentNonce
-		nm.cacheMutex.Unlock()
+	var record WalletNonce
+	err := nm.db.Where("address = ? AND chain_id = ?", address, chainID).First(&record).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		nonce, err := initial()
+		if err != nil {
+			return 0, fmt.Errorf("failed to get on-chain nonce: %w", err)
+		}
+		record = WalletNonce{Address: address, ChainID: chainID, Nonce: nonce}
+		if err := nm.db.Create(&record).Error; err != nil {
+			return 0, fmt.Errorf("failed to save nonce: %w", err)
+		}
+	} else if err != nil {
+		return 0, fmt.Errorf("failed to query nonce: %w", err)
 	}
 
-	// 3. 递增 nonce（内存）
-	nextNonce := currentNonce
 	nm.cacheMutex.Lock()
-	nm.nonceCache[cacheKey] = currentNonce + 1
+	nm.nonceCache[key] = record.Nonce
+	nm.cacheMutex.Unlock()
+	return record.Nonce, nil
+}
+
+// store 更新缓存并同步写入数据库（调用方持有钱包锁）
+func (nm *NonceManager) store(address string, chainID int, nonce uint64) error {
+	nm.cacheMutex.Lock()
+	nm.nonceCache[nonceKey(address, chainID)] = nonce
 	nm.cacheMutex.Unlock()
 
-	// 4. 创建 release 函数
-	released := false
-	release := func() {
-		if !released {
-			released = true
+	result := nm.db.Model(&WalletNonce{}).
+		Where("address = ? AND chain_id = ?", address, chainID).
+		Update("nonce", nonce)
+	if result.Error != nil {
+		return fmt.Errorf("failed to save nonce: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		if err := nm.db.Create(&WalletNonce{Address: address, ChainID: chainID, Nonce: nonce}).Error; err != nil {
+			return fmt.Errorf("failed to save nonce: %w", err)
+		}
+	}
+	return nil
+}
+
+// Reconcile 与链上 nonce 对账（持有钱包锁，期间不会分配新 nonce）
+//  1. 本地落后于 PendingNonceAt（外部交易使用了该地址）：前进到链上值
+//  2. 本地领先于 PendingNonceAt：[pending, local) 中的 nonce 已分配但节点没有对应交易，会阻塞后续所有交易，用 Fill 逐个补洞
+//  3. 最低未打包 nonce（NonceAt latest）超过 StuckAfter 不变：用 Fill 替换卡住的交易
+func (nm *NonceManager) Reconcile(ctx context.Context, client ChainClient, address string, chainID int, opts ReconcileOptions) (*ReconcileResult, error) {
+	lock := nm.getOrCreateLock(address, chainID)
+	lock.Lock()
+	defer lock.Unlock()
 
-			// 更新数据库（异步，避免阻塞）
-			go func() {
-				nm.db.Model(&WalletNonce{}).
-					Where("address = ? AND chain_id = ?", address, chainID).
-					Update("nonce", currentNonce+1)
-			}()
+	account := common.HexToAddress(address)
+	pending, err := client.PendingNonceAt(ctx, account)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pending nonce: %w", err)
+	}
+	latest, err := client.NonceAt(ctx, account, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest nonce: %w", err)
+	}
+	local, err := nm.loadNonce(address, chainID, func() (uint64, error) { return pending, nil })
+	if err != nil {
+		return nil, err
+	}
 
-			lock.Unlock()
+	result := &ReconcileResult{Local: local, Next: local, Latest: latest, Pending: pending}
+
+	if local < pending {
+		if err := nm.store(address, chainID, pending); err != nil {
+			return result, err
 		}
+		result.Next = pending
+	}
+
+	if opts.Fill == nil {
+		return result, nil
 	}
 
-	return nextNonce, release, nil
+	next := pending
+	for next < local {
+		err := opts.Fill(ctx, next, false)
+		if errors.Is(err, ErrSkipNonce) {
+			next++
+			continue
+		}
+		if err != nil {
+			return result, fmt.Errorf("fill nonce %d: %w", next, err)
+		}
+		result.Filled = append(result.Filled, next)
+
+		// 缺口补上后，交易池中排队的后续交易会变为 pending，重新查询以跳过已存在的 nonce
+		refreshed, err := client.PendingNonceAt(ctx, account)
+		if err != nil {
+			return result, fmt.Errorf("failed to get pending nonce: %w", err)
+		}
+		if refreshed > next+1 {
+			next = refreshed
+		} else {
+			next++
+		}
+	}
+
+	if replaced, err := nm.replaceStuck(ctx, address, chainID, latest, pending, opts); err != nil {
+		return result, err
+	} else if replaced {
+		result.Replaced = append(result.Replaced, latest)
+	}
+	return result, nil
 }
 
-// getOnChainNonce 从链上查询实际的 nonce
-func (nm *NonceManager) getOnChainNonce(rpcURL, address string) (uint64, error) {
-	client, err := ethclient.Dial(rpcURL)
-	if err != nil {
-		return 0, err
+// replaceStuck 最低未打包 nonce 超过 StuckAfter 不变时替换该交易（调用方持有钱包锁）
+func (nm *NonceManager) replaceStuck(ctx context.Context, address string, chainID int, latest, pending uint64, opts ReconcileOptions) (bool, error) {
+	key := nonceKey(address, chainID)
+	nm.stuckMutex.Lock()
+	defer nm.stuckMutex.Unlock()
+
+	if latest >= pending {
+		delete(nm.stuck, key)
+		return false, nil
 	}
-	defer client.Close()
 
-	nonce, err := client.PendingNonceAt(context.Background(), common.HexToAddress(address))
-	if err != nil {
-		return 0, err
+	state, tracked := nm.stuck[key]
+	if !tracked || state.nonce != latest {
+		nm.stuck[key] = stuckNonce{nonce: latest, since: time.Now()}
+		return false, nil
+	}
+	if opts.StuckAfter <= 0 || time.Since(state.since) < opts.StuckAfter {
+		return false, nil
 	}
 
-	return nonce, nil
+	err := opts.Fill(ctx, latest, true)
+	if errors.Is(err, ErrSkipNonce) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("replace stuck nonce %d: %w", latest, err)
+	}
+	// 重新计时，替换交易仍未打包时下一次再加速
+	nm.stuck[key] = stuckNonce{nonce: latest, since: time.Now()}
+	return true, nil
 }
 
 // SyncFromChain 从链上同步 nonce（用于恢复或重新校准）
-func (nm *NonceManager) SyncFromChain(rpcURL, address string, chainID int) error {
-	onChainNonce, err := nm.getOnChainNonce(rpcURL, address)
+func (nm *NonceManager) SyncFromChain(client ChainClient, address string, chainID int) error {
+	onChainNonce, err := client.PendingNonceAt(context.Background(), common.HexToAddress(address))
 	if err != nil {
 		return err
 	}
 
-	cacheKey := fmt.Sprintf("%s_%d", address, chainID)
-
 	// 获取锁
 	lock := nm.getOrCreateLock(address, chainID)
 	lock.Lock()
 	defer lock.Unlock()
 
-	// 更新缓存
-	nm.cacheMutex.Lock()
-	nm.nonceCache[cacheKey] = onChainNonce
-	nm.cacheMutex.Unlock()
-
-	// 更新数据库
-	return nm.db.Model(&WalletNonce{}).
-		Where("address = ? AND chain_id = ?", address, chainID).
-		Updates(map[string]interface{}{
-			"nonce": onChainNonce,
-		}).Error
+	return nm.store(address, chainID, onChainNonce)
 }
 
 // ResetNonce 重置 nonce（危险操作，仅用于恢复）
 func (nm *NonceManager) ResetNonce(address string, chainID int, newNonce uint64) error {
-	cacheKey := fmt.Sprintf("%s_%d", address, chainID)
-
 	// 获取锁
 	lock := nm.getOrCreateLock(address, chainID)
 	lock.Lock()
 	defer lock.Unlock()
 
-	// 更新缓存
-	nm.cacheMutex.Lock()
-	nm.nonceCache[cacheKey] = newNonce
-	nm.cacheMutex.Unlock()
-
-	// 更新数据库
-	return nm.db.Model(&WalletNonce{}).
-		Where("address = ? AND chain_id = ?", address, chainID).
-		Updates(map[string]interface{}{
-			"nonce": newNonce,
-		}).Error
+	return nm.store(address, chainID, newNonce)
 }
-
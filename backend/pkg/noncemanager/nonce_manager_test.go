@@ -0,0 +1,304 @@
+package noncemanager
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const testAddress = "0x0000000000000000000000000000000000000009"
+
+// fakeChain 模拟链上 nonce：pending 含交易池，latest 为已打包交易数
+type fakeChain struct {
+	pending uint64
+	latest  uint64
+	calls   int
+}
+
+func (c *fakeChain) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	c.calls++
+	return c.pending, nil
+}
+
+func (c *fakeChain) NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error) {
+	return c.latest, nil
+}
+
+func newTestManager(t *testing.T) (*NonceManager, *gorm.DB) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	return NewNonceManager(db), db
+}
+
+func storedNonce(t *testing.T, db *gorm.DB) uint64 {
+	t.Helper()
+	var record WalletNonce
+	if err := db.Where("address = ? AND chain_id = ?", testAddress, 1).First(&record).Error; err != nil {
+		t.Fatalf("load stored nonce: %v", err)
+	}
+	return record.Nonce
+}
+
+func TestLeaseCommitRollback(t *testing.T) {
+	tests := []struct {
+		name       string
+		commit     []bool // 每次分配后 Commit（true）或 Rollback（false）
+		wantNonces []uint64
+		wantStored uint64
+	}{
+		{name: "commit advances", commit: []bool{true, true}, wantNonces: []uint64{7, 8}, wantStored: 9},
+		{name: "rollback returns nonce", commit: []bool{false, true}, wantNonces: []uint64{7, 7}, wantStored: 8},
+		{name: "rollback only", commit: []bool{false, false}, wantNonces: []uint64{7, 7}, wantStored: 7},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nm, db := newTestManager(t)
+			chain := &fakeChain{pending: 7, latest: 7}
+
+			var got []uint64
+			for _, commit := range tt.commit {
+				lease, err := nm.AcquireNonce(chain, testAddress, 1)
+				if err != nil {
+					t.Fatalf("AcquireNonce: %v", err)
+				}
+				got = append(got, lease.Nonce)
+				if commit {
+					if err := lease.Commit(); err != nil {
+						t.Fatalf("Commit: %v", err)
+					}
+				}
+				// Commit 后 Rollback 为空操作，也不能重复释放锁
+				lease.Rollback()
+			}
+			if !reflect.DeepEqual(got, tt.wantNonces) {
+				t.Fatalf("nonces=%v, want %v", got, tt.wantNonces)
+			}
+			if stored := storedNonce(t, db); stored != tt.wantStored {
+				t.Fatalf("stored nonce=%d, want %d", stored, tt.wantStored)
+			}
+			// 只在首次分配时查询链上 nonce，之后使用本地记录
+			if chain.calls != 1 {
+				t.Fatalf("PendingNonceAt calls=%d, want 1", chain.calls)
+			}
+		})
+	}
+}
+
+func TestLeaseHoldsWalletLock(t *testing.T) {
+	nm, _ := newTestManager(t)
+	chain := &fakeChain{pending: 3, latest: 3}
+
+	lease, err := nm.AcquireNonce(chain, testAddress, 1)
+	if err != nil {
+		t.Fatalf("AcquireNonce: %v", err)
+	}
+	acquired := make(chan uint64)
+	go func() {
+		next, err := nm.AcquireNonce(chain, testAddress, 1)
+		if err != nil {
+			close(acquired)
+			return
+		}
+		acquired <- next.Nonce
+		next.Rollback()
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("second lease acquired while the first is still held")
+	case <-time.After(50 * time.Millisecond):
+	}
+	if err := lease.Commit(); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	select {
+	case nonce := <-acquired:
+		if nonce != 4 {
+			t.Fatalf("second lease nonce=%d, want 4", nonce)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("second lease not acquired after commit")
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	errFill := errors.New("broadcast failed")
+
+	tests := []struct {
+		name        string
+		local       uint64
+		pending     uint64
+		latest      uint64
+		noFill      bool
+		skip        map[uint64]bool
+		fillErr     error
+		wantNext    uint64
+		wantFilled  []uint64
+		wantFillArg []uint64
+		wantErr     bool
+	}{
+		{
+			// 外部交易使用了该地址：本地前进到链上值
+			name: "local behind chain", local: 3, pending: 5, latest: 5,
+			wantNext: 5,
+		},
+		{
+			name: "in sync", local: 5, pending: 5, latest: 5,
+			wantNext: 5,
+		},
+		{
+			// 已分配但节点没有对应交易的 nonce 逐个补洞，本地记录保持不变
+			name: "gap filled", local: 8, pending: 5, latest: 5,
+			wantNext: 8, wantFilled: []uint64{5, 6, 7}, wantFillArg: []uint64{5, 6, 7},
+		},
+		{
+			// 其他流程负责的 nonce（已广播的提现/批量交易）跳过，不发零值交易
+			name: "in-use nonce skipped", local: 8, pending: 5, latest: 5,
+			skip:     map[uint64]bool{6: true},
+			wantNext: 8, wantFilled: []uint64{5, 7}, wantFillArg: []uint64{5, 6, 7},
+		},
+		{
+			name: "no filler only calibrates", local: 8, pending: 5, latest: 5, noFill: true,
+			wantNext: 8,
+		},
+		{
+			name: "fill error stops", local: 8, pending: 5, latest: 5, fillErr: errFill,
+			wantNext: 8, wantFillArg: []uint64{5}, wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nm, db := newTestManager(t)
+			if err := nm.ResetNonce(testAddress, 1, tt.local); err != nil {
+				t.Fatalf("ResetNonce: %v", err)
+			}
+			chain := &fakeChain{pending: tt.pending, latest: tt.latest}
+
+			var fillArgs []uint64
+			opts := ReconcileOptions{}
+			if !tt.noFill {
+				opts.Fill = func(ctx context.Context, nonce uint64, replace bool) error {
+					if replace {
+						t.Fatalf("unexpected replacement of nonce %d", nonce)
+					}
+					fillArgs = append(fillArgs, nonce)
+					if tt.fillErr != nil {
+						return tt.fillErr
+					}
+					if tt.skip[nonce] {
+						return ErrSkipNonce
+					}
+					return nil
+				}
+			}
+
+			result, err := nm.Reconcile(context.Background(), chain, testAddress, 1, opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Reconcile err=%v, wantErr=%v", err, tt.wantErr)
+			}
+			if result.Next != tt.wantNext {
+				t.Fatalf("Next=%d, want %d", result.Next, tt.wantNext)
+			}
+			if !reflect.DeepEqual(result.Filled, tt.wantFilled) {
+				t.Fatalf("Filled=%v, want %v", result.Filled, tt.wantFilled)
+			}
+			if !reflect.DeepEqual(fillArgs, tt.wantFillArg) {
+				t.Fatalf("Fill called with %v, want %v", fillArgs, tt.wantFillArg)
+			}
+			if stored := storedNonce(t, db); stored != tt.wantNext {
+				t.Fatalf("stored nonce=%d, want %d", stored, tt.wantNext)
+			}
+		})
+	}
+}
+
+// TestReconcileSkipsQueuedNonces 补上缺口后交易池中排队的后续交易变为 pending，不能再为它们补洞
+func TestReconcileSkipsQueuedNonces(t *testing.T) {
+	nm, _ := newTestManager(t)
+	if err := nm.ResetNonce(testAddress, 1, 10); err != nil {
+		t.Fatalf("ResetNonce: %v", err)
+	}
+	chain := &fakeChain{pending: 5, latest: 5}
+
+	var fillArgs []uint64
+	result, err := nm.Reconcile(context.Background(), chain, testAddress, 1, ReconcileOptions{
+		Fill: func(ctx context.Context, nonce uint64, replace bool) error {
+			fillArgs = append(fillArgs, nonce)
+			if nonce == 5 {
+				// 6、7 已在交易池排队
+				chain.pending = 8
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	if want := []uint64{5, 8, 9}; !reflect.DeepEqual(fillArgs, want) || !reflect.DeepEqual(result.Filled, want) {
+		t.Fatalf("Fill called with %v (filled %v), want %v", fillArgs, result.Filled, want)
+	}
+}
+
+func TestReconcileReplacesStuck(t *testing.T) {
+	nm, _ := newTestManager(t)
+	chain := &fakeChain{pending: 6, latest: 5}
+
+	var replaced []uint64
+	opts := ReconcileOptions{
+		StuckAfter: 30 * time.Millisecond,
+		Fill: func(ctx context.Context, nonce uint64, replace bool) error {
+			if replace {
+				replaced = append(replaced, nonce)
+			}
+			return nil
+		},
+	}
+	reconcile := func() *ReconcileResult {
+		t.Helper()
+		result, err := nm.Reconcile(context.Background(), chain, testAddress, 1, opts)
+		if err != nil {
+			t.Fatalf("Reconcile: %v", err)
+		}
+		return result
+	}
+
+	// 首次观察只开始计时
+	if result := reconcile(); len(result.Replaced) != 0 {
+		t.Fatalf("replaced on first observation: %v", result.Replaced)
+	}
+	// 未超过 StuckAfter 不替换
+	if result := reconcile(); len(result.Replaced) != 0 {
+		t.Fatalf("replaced before StuckAfter: %v", result.Replaced)
+	}
+	time.Sleep(40 * time.Millisecond)
+	if result := reconcile(); !reflect.DeepEqual(result.Replaced, []uint64{5}) {
+		t.Fatalf("Replaced=%v, want [5]", result.Replaced)
+	}
+
+	// 已打包后清除计时，不再替换
+	chain.latest = 6
+	time.Sleep(40 * time.Millisecond)
+	if result := reconcile(); len(result.Replaced) != 0 {
+		t.Fatalf("replaced after the nonce was mined: %v", result.Replaced)
+	}
+	if !reflect.DeepEqual(replaced, []uint64{5}) {
+		t.Fatalf("replacement fills=%v, want [5]", replaced)
+	}
+}
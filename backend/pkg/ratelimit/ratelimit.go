@@ -0,0 +1,100 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Result 一次限流判断的结果
+type Result struct {
+	Allowed    bool
+	Limit      int           // 桶容量（突发上限）
+	Remaining  int           // 剩余令牌数
+	RetryAfter time.Duration // 被拒绝时距离下一个令牌的时间
+	ResetAfter time.Duration // 令牌桶恢复满额的时间
+}
+
+// Limiter 令牌桶限流器：rate 为每秒补充的令牌数，burst 为桶容量
+type Limiter interface {
+	Allow(ctx context.Context, key string, rate float64, burst int) (Result, error)
+}
+
+// newResult 根据扣减后的令牌数计算结果
+func newResult(allowed bool, tokens, rate float64, burst int) Result {
+	result := Result{
+		Allowed:    allowed,
+		Limit:      burst,
+		Remaining:  int(math.Floor(tokens)),
+		ResetAfter: time.Duration((float64(burst) - tokens) / rate * float64(time.Second)),
+	}
+	if !allowed {
+		result.RetryAfter = time.Duration((1 - tokens) / rate * float64(time.Second))
+	}
+	return result
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// MemoryLimiter 进程内令牌桶（单实例部署使用）
+type MemoryLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	now     func() time.Time
+}
+
+// NewMemoryLimiter 创建内存限流器，并定期清理长时间未使用的令牌桶
+func NewMemoryLimiter() *MemoryLimiter {
+	l := &MemoryLimiter{
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+	go l.cleanupLoop(time.Minute, 10*time.Minute)
+	return l
+}
+
+func (l *MemoryLimiter) Allow(ctx context.Context, key string, rate float64, burst int) (Result, error) {
+	now := l.now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(burst), last: now}
+		l.buckets[key] = b
+	}
+
+	// 按时间补充令牌（不超过桶容量）
+	elapsed := now.Sub(b.last).Seconds()
+	if elapsed > 0 {
+		b.tokens = math.Min(float64(burst), b.tokens+elapsed*rate)
+		b.last = now
+	}
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	return newResult(allowed, b.tokens, rate, burst), nil
+}
+
+func (l *MemoryLimiter) cleanupLoop(interval, idle time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		cutoff := l.now().Add(-idle)
+		l.mu.Lock()
+		for key, b := range l.buckets {
+			if b.last.Before(cutoff) {
+				delete(l.buckets, key)
+			}
+		}
+		l.mu.Unlock()
+	}
+}
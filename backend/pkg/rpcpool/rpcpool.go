@@ -0,0 +1,592 @@
+package rpcpool
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// ErrNoEndpoint 链上没有可用的 RPC 节点（未配置或全部熔断）
+var ErrNoEndpoint = errors.New("no available RPC endpoint")
+
+// Endpoint RPC 节点配置（仅支持 HTTP/HTTPS）
+type Endpoint struct {
+	URL      string
+	Priority int // 优先级，数值越小越优先；同级节点全部不可用时才使用下一级
+	Weight   int // 同优先级节点按权重分配请求（<=0 按 1 处理）
+}
+
+// Options 连接池参数（可通过 SetOptions 热更新）
+type Options struct {
+	FailureThreshold int           // 连续失败达到该次数后熔断
+	OpenDuration     time.Duration // 熔断时长，到期后放行一个请求试探（半开）
+	MaxHeadLag       uint64        // 区块高度落后同链最高节点超过该值视为落后，其他节点可用时不再分配请求
+	RequestTimeout   time.Duration // 单个 HTTP 请求超时
+}
+
+// DefaultOptions 默认参数
+func DefaultOptions() Options {
+	return Options{
+		FailureThreshold: 3,
+		OpenDuration:     30 * time.Second,
+		MaxHeadLag:       20,
+		RequestTimeout:   15 * time.Second,
+	}
+}
+
+// 节点状态
+const (
+	StateHealthy  = "healthy"   // 正常
+	StateLagging  = "lagging"   // 区块高度落后
+	StateOpen     = "open"      // 熔断中
+	StateHalfOpen = "half_open" // 熔断到期，下一个请求用于试探
+)
+
+// EndpointStatus 节点健康状态
+type EndpointStatus struct {
+	URL                 string     `json:"url"`
+	Priority            int        `json:"priority"`
+	Weight              int        `json:"weight"`
+	State               string     `json:"state"`
+	Head                uint64     `json:"head"`       // 最近一次健康检查的区块高度
+	Lag                 uint64     `json:"lag"`        // 落后同链最高节点的区块数
+	LatencyMs           int64      `json:"latency_ms"` // 最近一次成功请求的耗时
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	Requests            uint64     `json:"requests"`
+	Failures            uint64     `json:"failures"`
+	LastError           string     `json:"last_error,omitempty"`
+	LastCheckedAt       *time.Time `json:"last_checked_at,omitempty"`
+	OpenUntil           *time.Time `json:"open_until,omitempty"`
+}
+
+// StateChange 节点状态变化（熔断、恢复、落后等）
+type StateChange struct {
+	ChainID int
+	URL     string
+	From    string
+	To      string
+	Err     error
+}
+
+type endpoint struct {
+	Endpoint
+	failures      int       // 连续失败次数
+	openUntil     time.Time // 熔断截止时间
+	probing       bool      // 半开状态下已放行试探请求
+	head          uint64
+	lag           uint64
+	lagging       bool
+	latency       time.Duration
+	requests      uint64
+	totalFailures uint64
+	lastError     string
+	lastChecked   time.Time
+}
+
+type chainPool struct {
+	endpoints []*endpoint
+	client    *ethclient.Client
+}
+
+// Pool 按链共享的 RPC 客户端：每条链一个 ethclient，请求按优先级和权重分配到节点，
+// 节点失败时自动切换到下一个（广播交易仅在连接未建立时切换，避免重复广播），连续失败的节点熔断
+type Pool struct {
+	mu     sync.Mutex
+	opts   Options
+	chains map[int]*chainPool
+	http   *http.Client
+	rand   *rand.Rand
+	notify func(StateChange)
+}
+
+// New 创建连接池，notify 为节点状态变化回调（可为 nil）
+func New(opts Options, notify func(StateChange)) *Pool {
+	return &Pool{
+		opts:   opts,
+		chains: make(map[int]*chainPool),
+		http:   &http.Client{},
+		rand:   rand.New(rand.NewSource(time.Now().UnixNano())),
+		notify: notify,
+	}
+}
+
+// SetOptions 更新连接池参数
+func (p *Pool) SetOptions(opts Options) {
+	p.mu.Lock()
+	p.opts = opts
+	p.mu.Unlock()
+}
+
+// Client 返回链的共享客户端（调用方不要 Close）
+// endpoints 与上次不同时更新节点列表，同一 URL 的节点保留健康状态
+func (p *Pool) Client(chainID int, endpoints []Endpoint) (*ethclient.Client, error) {
+	if len(endpoints) == 0 {
+		return nil, ErrNoEndpoint
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	cp := p.chains[chainID]
+	if cp == nil {
+		// 真实地址由 transport 按节点选择，这里的 URL 只用于构造 HTTP 客户端
+		rpcClient, err := rpc.DialOptions(context.Background(), fmt.Sprintf("http://chain-%d.rpcpool", chainID),
+			rpc.WithHTTPClient(&http.Client{Transport: &transport{pool: p, chainID: chainID}}))
+		if err != nil {
+			return nil, err
+		}
+		cp = &chainPool{client: ethclient.NewClient(rpcClient)}
+		p.chains[chainID] = cp
+	}
+
+	existing := make(map[string]*endpoint, len(cp.endpoints))
+	for _, ep := range cp.endpoints {
+		existing[ep.URL] = ep
+	}
+	updated := make([]*endpoint, 0, len(endpoints))
+	for _, e := range endpoints {
+		e.Weight = max(e.Weight, 1)
+		ep, ok := existing[e.URL]
+		if !ok {
+			ep = &endpoint{}
+		}
+		ep.Endpoint = e
+		updated = append(updated, ep)
+	}
+	cp.endpoints = updated
+	return cp.client, nil
+}
+
+// Session 返回固定节点的客户端，用于需要前后一致读取的一组调用（如先查 nonce 再查回执）：
+// 第一个请求按正常规则选择节点（失败时切换），得到响应后之后的所有请求都发往该节点，
+// 该节点失败时直接返回错误而不切换，避免不同节点的区块视图不一致导致误判
+// 必须先通过 Client 设置链的节点列表；每次逻辑操作创建一个新的 Session
+func (p *Pool) Session(chainID int) (*ethclient.Client, error) {
+	p.mu.Lock()
+	_, ok := p.chains[chainID]
+	p.mu.Unlock()
+	if !ok {
+		return nil, ErrNoEndpoint
+	}
+
+	rpcClient, err := rpc.DialOptions(context.Background(), fmt.Sprintf("http://chain-%d.rpcpool", chainID),
+		rpc.WithHTTPClient(&http.Client{Transport: &transport{pool: p, chainID: chainID, sticky: true}}))
+	if err != nil {
+		return nil, err
+	}
+	return ethclient.NewClient(rpcClient), nil
+}
+
+// Status 链上各节点的健康状态
+func (p *Pool) Status(chainID int) []EndpointStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	cp := p.chains[chainID]
+	if cp == nil {
+		return []EndpointStatus{}
+	}
+	now := time.Now()
+	result := make([]EndpointStatus, 0, len(cp.endpoints))
+	for _, ep := range cp.endpoints {
+		status := EndpointStatus{
+			URL:                 ep.URL,
+			Priority:            ep.Priority,
+			Weight:              ep.Weight,
+			State:               p.state(ep, now),
+			Head:                ep.head,
+			Lag:                 ep.lag,
+			LatencyMs:           ep.latency.Milliseconds(),
+			ConsecutiveFailures: ep.failures,
+			Requests:            ep.requests,
+			Failures:            ep.totalFailures,
+			LastError:           ep.lastError,
+		}
+		if !ep.lastChecked.IsZero() {
+			checked := ep.lastChecked
+			status.LastCheckedAt = &checked
+		}
+		if status.State == StateOpen {
+			openUntil := ep.openUntil
+			status.OpenUntil = &openUntil
+		}
+		result = append(result, status)
+	}
+	return result
+}
+
+// CheckHealth 查询各链所有节点的最新区块高度：失败计入熔断，成功时关闭熔断；
+// 落后同链最高节点超过 MaxHeadLag 的节点标记为落后
+func (p *Pool) CheckHealth(ctx context.Context, chainIDs ...int) {
+	type probe struct {
+		chainID int
+		ep      *endpoint
+		head    uint64
+		latency time.Duration
+		err     error
+	}
+
+	p.mu.Lock()
+	var probes []*probe
+	for _, chainID := range chainIDs {
+		if cp := p.chains[chainID]; cp != nil {
+			for _, ep := range cp.endpoints {
+				probes = append(probes, &probe{chainID: chainID, ep: ep})
+			}
+		}
+	}
+	p.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, pr := range probes {
+		wg.Add(1)
+		go func(pr *probe) {
+			defer wg.Done()
+			pr.head, pr.latency, pr.err = p.blockNumber(ctx, pr.ep)
+		}(pr)
+	}
+	wg.Wait()
+
+	p.mu.Lock()
+	var changes []StateChange
+	now := time.Now()
+	heads := make(map[int]uint64)
+	for _, pr := range probes {
+		pr.ep.lastChecked = now
+		if pr.err != nil {
+			if change, ok := p.recordFailure(pr.chainID, pr.ep, pr.err); ok {
+				changes = append(changes, change)
+			}
+			continue
+		}
+		pr.ep.head = pr.head
+		heads[pr.chainID] = max(heads[pr.chainID], pr.head)
+		if change, ok := p.recordSuccess(pr.chainID, pr.ep, pr.latency); ok {
+			changes = append(changes, change)
+		}
+	}
+	for _, pr := range probes {
+		if pr.err != nil {
+			continue
+		}
+		before := p.state(pr.ep, now)
+		pr.ep.lag = heads[pr.chainID] - pr.head
+		pr.ep.lagging = pr.ep.lag > p.opts.MaxHeadLag
+		if after := p.state(pr.ep, now); after != before {
+			changes = append(changes, StateChange{ChainID: pr.chainID, URL: pr.ep.URL, From: before, To: after,
+				Err: fmt.Errorf("head %d, %d blocks behind", pr.head, pr.ep.lag)})
+		}
+	}
+	p.mu.Unlock()
+
+	p.emit(changes...)
+}
+
+// state 节点当前状态（调用方持有锁）
+func (p *Pool) state(ep *endpoint, now time.Time) string {
+	if ep.failures >= max(p.opts.FailureThreshold, 1) {
+		if now.Before(ep.openUntil) {
+			return StateOpen
+		}
+		return StateHalfOpen
+	}
+	if ep.lagging {
+		return StateLagging
+	}
+	return StateHealthy
+}
+
+// pick 选择下一个节点：优先正常节点，其次落后节点；同级按优先级取最小一级再按权重随机
+// 半开节点只放行一个试探请求
+func (p *Pool) pick(chainID int, tried map[*endpoint]bool) *endpoint {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	cp := p.chains[chainID]
+	if cp == nil {
+		return nil
+	}
+
+	now := time.Now()
+	var healthy, lagging []*endpoint
+	for _, ep := range cp.endpoints {
+		if tried[ep] {
+			continue
+		}
+		switch p.state(ep, now) {
+		case StateHealthy:
+			healthy = append(healthy, ep)
+		case StateHalfOpen:
+			if !ep.probing {
+				healthy = append(healthy, ep)
+			}
+		case StateLagging:
+			lagging = append(lagging, ep)
+		}
+	}
+	candidates := healthy
+	if len(candidates) == 0 {
+		candidates = lagging
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	priority := candidates[0].Priority
+	for _, ep := range candidates {
+		priority = min(priority, ep.Priority)
+	}
+	var tier []*endpoint
+	total := 0
+	for _, ep := range candidates {
+		if ep.Priority == priority {
+			tier = append(tier, ep)
+			total += ep.Weight
+		}
+	}
+	chosen := tier[len(tier)-1]
+	n := p.rand.Intn(total)
+	for _, ep := range tier {
+		if n -= ep.Weight; n < 0 {
+			chosen = ep
+			break
+		}
+	}
+
+	if p.state(chosen, now) == StateHalfOpen {
+		chosen.probing = true
+	}
+	chosen.requests++
+	return chosen
+}
+
+// recordSuccess 请求成功：清零连续失败并关闭熔断（调用方持有锁）
+func (p *Pool) recordSuccess(chainID int, ep *endpoint, latency time.Duration) (StateChange, bool) {
+	now := time.Now()
+	before := p.state(ep, now)
+	ep.failures = 0
+	ep.probing = false
+	ep.lastError = ""
+	ep.latency = latency
+	after := p.state(ep, now)
+	return StateChange{ChainID: chainID, URL: ep.URL, From: before, To: after}, before != after
+}
+
+// recordFailure 请求失败：连续失败达到阈值或半开试探失败时熔断（调用方持有锁）
+func (p *Pool) recordFailure(chainID int, ep *endpoint, err error) (StateChange, bool) {
+	now := time.Now()
+	before := p.state(ep, now)
+	ep.failures++
+	ep.totalFailures++
+	ep.lastError = err.Error()
+	if ep.probing || ep.failures >= max(p.opts.FailureThreshold, 1) {
+		ep.openUntil = now.Add(p.opts.OpenDuration)
+	}
+	ep.probing = false
+	after := p.state(ep, now)
+	return StateChange{ChainID: chainID, URL: ep.URL, From: before, To: after, Err: err}, before != after
+}
+
+func (p *Pool) emit(changes ...StateChange) {
+	if p.notify == nil {
+		return
+	}
+	for _, change := range changes {
+		p.notify(change)
+	}
+}
+
+// do 向节点发送一次 JSON-RPC 请求；非 2xx 响应视为节点故障
+func (p *Pool) do(ctx context.Context, ep *endpoint, header http.Header, body []byte) (*http.Response, time.Duration, error) {
+	p.mu.Lock()
+	timeout := p.opts.RequestTimeout
+	p.mu.Unlock()
+
+	cancel := context.CancelFunc(func() {})
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, ep.URL, bytes.NewReader(body))
+	if err != nil {
+		cancel()
+		return nil, 0, err
+	}
+	req.Header = header.Clone()
+
+	start := time.Now()
+	resp, err := p.http.Do(req)
+	latency := time.Since(start)
+	if err != nil {
+		cancel()
+		return nil, latency, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 256))
+		resp.Body.Close()
+		cancel()
+		return nil, latency, fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(snippet)))
+	}
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, latency, nil
+}
+
+// blockNumber 健康检查：查询节点最新区块高度
+func (p *Pool) blockNumber(ctx context.Context, ep *endpoint) (uint64, time.Duration, error) {
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	body := []byte(`{"jsonrpc":"2.0","id":1,"method":"eth_blockNumber","params":[]}`)
+
+	resp, latency, err := p.do(ctx, ep, header, body)
+	if err != nil {
+		return 0, latency, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Result *hexutil.Uint64 `json:"result"`
+		Error  *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return 0, latency, fmt.Errorf("invalid eth_blockNumber response: %w", err)
+	}
+	if result.Error != nil {
+		return 0, latency, fmt.Errorf("eth_blockNumber: %s", result.Error.Message)
+	}
+	if result.Result == nil {
+		return 0, latency, errors.New("eth_blockNumber: empty result")
+	}
+	return uint64(*result.Result), latency, nil
+}
+
+// transport 按节点选择转发 ethclient 的 HTTP 请求，失败时切换节点
+// sticky 时固定使用第一个成功响应的节点（见 Pool.Session）
+type transport struct {
+	pool    *Pool
+	chainID int
+	sticky  bool
+
+	mu     sync.Mutex
+	pinned *endpoint
+}
+
+// pinnedEndpoint 会话已固定的节点（未固定时为 nil）
+func (t *transport) pinnedEndpoint() *endpoint {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.pinned
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	broadcast := isBroadcast(body)
+
+	// 会话已固定节点：只发往该节点，失败不切换
+	if pinned := t.pinnedEndpoint(); pinned != nil {
+		t.pool.mu.Lock()
+		pinned.requests++
+		t.pool.mu.Unlock()
+		return t.send(req, pinned, body)
+	}
+
+	tried := make(map[*endpoint]bool)
+	var lastErr error
+	for {
+		ep := t.pool.pick(t.chainID, tried)
+		if ep == nil {
+			if lastErr != nil {
+				return nil, lastErr
+			}
+			return nil, ErrNoEndpoint
+		}
+		tried[ep] = true
+
+		resp, err := t.send(req, ep, body)
+		if err == nil {
+			if t.sticky {
+				t.mu.Lock()
+				if t.pinned == nil {
+					t.pinned = ep
+				}
+				t.mu.Unlock()
+			}
+			return resp, nil
+		}
+		if req.Context().Err() != nil {
+			return nil, err
+		}
+		// 广播交易时节点可能已收到请求，只有连接未建立时才换节点重发
+		if broadcast && !isDialError(err) {
+			return nil, err
+		}
+		lastErr = err
+	}
+}
+
+// send 向节点发送请求并记录结果（成功关闭熔断，失败计入熔断）
+func (t *transport) send(req *http.Request, ep *endpoint, body []byte) (*http.Response, error) {
+	resp, latency, err := t.pool.do(req.Context(), ep, req.Header, body)
+
+	t.pool.mu.Lock()
+	var change StateChange
+	var changed bool
+	switch {
+	case err == nil:
+		change, changed = t.pool.recordSuccess(t.chainID, ep, latency)
+	case req.Context().Err() != nil:
+		// 调用方取消或超时，不计入节点故障
+		ep.probing = false
+	default:
+		change, changed = t.pool.recordFailure(t.chainID, ep, err)
+	}
+	t.pool.mu.Unlock()
+	if changed {
+		t.pool.emit(change)
+	}
+	return resp, err
+}
+
+// isBroadcast 请求（含批量请求）是否广播交易
+func isBroadcast(body []byte) bool {
+	return bytes.Contains(body, []byte(`"eth_sendRawTransaction"`)) || bytes.Contains(body, []byte(`"eth_sendTransaction"`))
+}
+
+func isDialError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// cancelBody 响应体读完关闭时释放请求超时的 context
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
@@ -0,0 +1,304 @@
+package rpcpool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// fakeNode 最小 JSON-RPC 节点：返回固定区块高度，可切换为 502 故障
+type fakeNode struct {
+	head  atomic.Uint64
+	fail  atomic.Bool
+	calls atomic.Int64
+	sends atomic.Int64
+	srv   *httptest.Server
+}
+
+func newFakeNode(t *testing.T, head uint64) *fakeNode {
+	t.Helper()
+	n := &fakeNode{}
+	n.head.Store(head)
+	n.srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n.calls.Add(1)
+		body, _ := io.ReadAll(r.Body)
+		if n.fail.Load() {
+			http.Error(w, "bad gateway", http.StatusBadGateway)
+			return
+		}
+		var req struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+		}
+		json.Unmarshal(body, &req)
+		var result interface{}
+		switch req.Method {
+		case "eth_blockNumber":
+			result = fmt.Sprintf("0x%x", n.head.Load())
+		case "eth_sendRawTransaction":
+			n.sends.Add(1)
+			result = common.Hash{1}.Hex()
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "result": result})
+	}))
+	t.Cleanup(n.srv.Close)
+	return n
+}
+
+func testOptions() Options {
+	return Options{FailureThreshold: 2, OpenDuration: 200 * time.Millisecond, MaxHeadLag: 10, RequestTimeout: 2 * time.Second}
+}
+
+func mustClient(t *testing.T, pool *Pool, chainID int, endpoints ...Endpoint) interface {
+	BlockNumber(context.Context) (uint64, error)
+	SendTransaction(context.Context, *types.Transaction) error
+} {
+	t.Helper()
+	client, err := pool.Client(chainID, endpoints)
+	if err != nil {
+		t.Fatalf("Client: %v", err)
+	}
+	return client
+}
+
+func TestClientNoEndpoints(t *testing.T) {
+	pool := New(testOptions(), nil)
+	if _, err := pool.Client(1, nil); !errors.Is(err, ErrNoEndpoint) {
+		t.Fatalf("expected ErrNoEndpoint, got %v", err)
+	}
+	if _, err := pool.Session(1); !errors.Is(err, ErrNoEndpoint) {
+		t.Fatalf("expected ErrNoEndpoint for unknown chain session, got %v", err)
+	}
+}
+
+func TestPriorityAndFailover(t *testing.T) {
+	primary := newFakeNode(t, 100)
+	backup := newFakeNode(t, 100)
+	pool := New(testOptions(), nil)
+	client := mustClient(t, pool, 1,
+		Endpoint{URL: primary.srv.URL, Priority: 0, Weight: 1},
+		Endpoint{URL: backup.srv.URL, Priority: 1, Weight: 1})
+	ctx := context.Background()
+
+	for i := 0; i < 5; i++ {
+		if _, err := client.BlockNumber(ctx); err != nil {
+			t.Fatalf("BlockNumber: %v", err)
+		}
+	}
+	if primary.calls.Load() != 5 || backup.calls.Load() != 0 {
+		t.Fatalf("expected all requests on primary, got primary=%d backup=%d", primary.calls.Load(), backup.calls.Load())
+	}
+
+	// 主节点故障：请求切换到备用节点，连续失败达到阈值后熔断
+	primary.fail.Store(true)
+	for i := 0; i < 5; i++ {
+		if _, err := client.BlockNumber(ctx); err != nil {
+			t.Fatalf("BlockNumber during failover: %v", err)
+		}
+	}
+	if got := primary.calls.Load(); got != 5+2 {
+		t.Fatalf("expected primary to be tried until the circuit opened (7 calls), got %d", got)
+	}
+	if state := pool.Status(1)[0].State; state != StateOpen {
+		t.Fatalf("expected primary open, got %s", state)
+	}
+
+	// 熔断到期后放行一个试探请求，成功即恢复
+	primary.fail.Store(false)
+	time.Sleep(250 * time.Millisecond)
+	if state := pool.Status(1)[0].State; state != StateHalfOpen {
+		t.Fatalf("expected primary half_open, got %s", state)
+	}
+	if _, err := client.BlockNumber(ctx); err != nil {
+		t.Fatalf("BlockNumber probe: %v", err)
+	}
+	if state := pool.Status(1)[0].State; state != StateHealthy {
+		t.Fatalf("expected primary healthy after probe, got %s", state)
+	}
+}
+
+func TestAllEndpointsOpen(t *testing.T) {
+	node := newFakeNode(t, 100)
+	node.fail.Store(true)
+	pool := New(testOptions(), nil)
+	client := mustClient(t, pool, 1, Endpoint{URL: node.srv.URL, Weight: 1})
+
+	var err error
+	for i := 0; i < 3; i++ {
+		_, err = client.BlockNumber(context.Background())
+	}
+	if err == nil || !strings.Contains(err.Error(), ErrNoEndpoint.Error()) {
+		t.Fatalf("expected no available endpoint error, got %v", err)
+	}
+}
+
+func TestLaggingEndpointSkipped(t *testing.T) {
+	behind := newFakeNode(t, 100)
+	ahead := newFakeNode(t, 200)
+	pool := New(testOptions(), nil)
+	client := mustClient(t, pool, 1,
+		Endpoint{URL: behind.srv.URL, Priority: 0, Weight: 1},
+		Endpoint{URL: ahead.srv.URL, Priority: 1, Weight: 1})
+
+	pool.CheckHealth(context.Background(), 1)
+	status := pool.Status(1)
+	if status[0].State != StateLagging || status[0].Lag != 100 {
+		t.Fatalf("expected behind node lagging by 100, got %s lag=%d", status[0].State, status[0].Lag)
+	}
+
+	before := behind.calls.Load()
+	for i := 0; i < 3; i++ {
+		if _, err := client.BlockNumber(context.Background()); err != nil {
+			t.Fatalf("BlockNumber: %v", err)
+		}
+	}
+	if behind.calls.Load() != before {
+		t.Fatalf("lagging node received %d requests", behind.calls.Load()-before)
+	}
+
+	behind.head.Store(195)
+	pool.CheckHealth(context.Background(), 1)
+	if state := pool.Status(1)[0].State; state != StateHealthy {
+		t.Fatalf("expected caught-up node healthy, got %s", state)
+	}
+}
+
+func TestBroadcastFailover(t *testing.T) {
+	tx := types.NewTx(&types.LegacyTx{Nonce: 1, Gas: 21000})
+
+	tests := []struct {
+		name      string
+		primary   func(t *testing.T) string
+		wantErr   bool
+		wantSends int64
+	}{
+		{
+			// 节点已收到请求但返回错误：可能已广播，不能换节点重发
+			name: "http error does not fail over",
+			primary: func(t *testing.T) string {
+				n := newFakeNode(t, 100)
+				n.fail.Store(true)
+				return n.srv.URL
+			},
+			wantErr:   true,
+			wantSends: 0,
+		},
+		{
+			// 连接未建立：节点一定没收到交易，可以换节点
+			name: "dial error fails over",
+			primary: func(t *testing.T) string {
+				ln, err := net.Listen("tcp", "127.0.0.1:0")
+				if err != nil {
+					t.Fatal(err)
+				}
+				url := "http://" + ln.Addr().String()
+				ln.Close()
+				return url
+			},
+			wantErr:   false,
+			wantSends: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backup := newFakeNode(t, 100)
+			pool := New(testOptions(), nil)
+			client := mustClient(t, pool, 1,
+				Endpoint{URL: tt.primary(t), Priority: 0, Weight: 1},
+				Endpoint{URL: backup.srv.URL, Priority: 1, Weight: 1})
+
+			err := client.SendTransaction(context.Background(), tx)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SendTransaction err=%v, wantErr=%v", err, tt.wantErr)
+			}
+			if backup.sends.Load() != tt.wantSends {
+				t.Fatalf("backup sends=%d, want %d", backup.sends.Load(), tt.wantSends)
+			}
+		})
+	}
+}
+
+func TestSessionPinsEndpoint(t *testing.T) {
+	a := newFakeNode(t, 100)
+	b := newFakeNode(t, 100)
+	pool := New(testOptions(), nil)
+	mustClient(t, pool, 1, Endpoint{URL: a.srv.URL, Weight: 1}, Endpoint{URL: b.srv.URL, Weight: 1})
+
+	session, err := pool.Session(1)
+	if err != nil {
+		t.Fatalf("Session: %v", err)
+	}
+	for i := 0; i < 20; i++ {
+		if _, err := session.BlockNumber(context.Background()); err != nil {
+			t.Fatalf("BlockNumber: %v", err)
+		}
+	}
+	if a.calls.Load() != 0 && b.calls.Load() != 0 {
+		t.Fatalf("session spread requests across nodes: a=%d b=%d", a.calls.Load(), b.calls.Load())
+	}
+
+	// 固定节点故障时返回错误，不切换到另一个节点
+	pinned, other := a, b
+	if b.calls.Load() > 0 {
+		pinned, other = b, a
+	}
+	pinned.fail.Store(true)
+	otherCalls := other.calls.Load()
+	if _, err := session.BlockNumber(context.Background()); err == nil {
+		t.Fatal("expected pinned node failure to surface as an error")
+	}
+	if other.calls.Load() != otherCalls {
+		t.Fatal("session failed over to another node after pinning")
+	}
+}
+
+func TestSessionFailsOverBeforeFirstResponse(t *testing.T) {
+	down := newFakeNode(t, 100)
+	down.fail.Store(true)
+	up := newFakeNode(t, 100)
+	pool := New(testOptions(), nil)
+	mustClient(t, pool, 1,
+		Endpoint{URL: down.srv.URL, Priority: 0, Weight: 1},
+		Endpoint{URL: up.srv.URL, Priority: 1, Weight: 1})
+
+	session, err := pool.Session(1)
+	if err != nil {
+		t.Fatalf("Session: %v", err)
+	}
+	if _, err := session.BlockNumber(context.Background()); err != nil {
+		t.Fatalf("expected failover before the session is pinned, got %v", err)
+	}
+	down.fail.Store(false)
+	downCalls := down.calls.Load()
+	for i := 0; i < 3; i++ {
+		session.BlockNumber(context.Background())
+	}
+	if down.calls.Load() != downCalls {
+		t.Fatal("session moved away from the node it was pinned to")
+	}
+}
+
+func TestReconfigureKeepsState(t *testing.T) {
+	a := newFakeNode(t, 100)
+	pool := New(testOptions(), nil)
+	client := mustClient(t, pool, 1, Endpoint{URL: a.srv.URL, Weight: 1})
+	client.BlockNumber(context.Background())
+
+	mustClient(t, pool, 1, Endpoint{URL: a.srv.URL, Priority: 2, Weight: 0})
+	status := pool.Status(1)
+	if len(status) != 1 || status[0].Requests != 1 || status[0].Priority != 2 || status[0].Weight != 1 {
+		t.Fatalf("unexpected status after reconfigure: %+v", status)
+	}
+}
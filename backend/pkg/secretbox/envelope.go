@@ -0,0 +1,97 @@
+package secretbox
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+// envelopePrefix 信封加密密文前缀，格式：env1:<被主密钥加密的数据密钥>:<被数据密钥加密的明文>
+const envelopePrefix = "env1:"
+
+// ErrNotEnvelope 不是信封加密格式的密文
+var ErrNotEnvelope = errors.New("value is not envelope encrypted")
+
+// IsEnvelope 判断是否为信封加密密文（用于区分历史明文数据）
+func IsEnvelope(value string) bool {
+	return strings.HasPrefix(value, envelopePrefix)
+}
+
+// SealEnvelope 信封加密：每条数据使用随机数据密钥加密，数据密钥再由主密钥加密
+// 主密钥轮换时只需重新加密数据密钥（RewrapEnvelope），无需接触明文
+func (b *Box) SealEnvelope(plaintext string) (string, error) {
+	dataKey := make([]byte, 32)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", err
+	}
+	dataKeyHex := hex.EncodeToString(dataKey)
+
+	dataBox, err := New(dataKeyHex)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := dataBox.Seal(plaintext)
+	if err != nil {
+		return "", err
+	}
+	wrappedKey, err := b.Seal(dataKeyHex)
+	if err != nil {
+		return "", err
+	}
+
+	return envelopePrefix + wrappedKey + ":" + sealed, nil
+}
+
+// OpenEnvelope 解密信封加密密文
+func (b *Box) OpenEnvelope(value string) (string, error) {
+	dataBox, sealed, err := b.openDataKey(value)
+	if err != nil {
+		return "", err
+	}
+	return dataBox.Open(sealed)
+}
+
+// RewrapEnvelope 使用新的主密钥重新加密数据密钥（密文主体不变）
+func (b *Box) RewrapEnvelope(value string, newMaster *Box) (string, error) {
+	wrappedKey, sealed, err := splitEnvelope(value)
+	if err != nil {
+		return "", err
+	}
+	dataKeyHex, err := b.Open(wrappedKey)
+	if err != nil {
+		return "", err
+	}
+	rewrapped, err := newMaster.Seal(dataKeyHex)
+	if err != nil {
+		return "", err
+	}
+	return envelopePrefix + rewrapped + ":" + sealed, nil
+}
+
+func (b *Box) openDataKey(value string) (*Box, string, error) {
+	wrappedKey, sealed, err := splitEnvelope(value)
+	if err != nil {
+		return nil, "", err
+	}
+	dataKeyHex, err := b.Open(wrappedKey)
+	if err != nil {
+		return nil, "", err
+	}
+	dataBox, err := New(dataKeyHex)
+	if err != nil {
+		return nil, "", ErrDecrypt
+	}
+	return dataBox, sealed, nil
+}
+
+func splitEnvelope(value string) (string, string, error) {
+	if !IsEnvelope(value) {
+		return "", "", ErrNotEnvelope
+	}
+	parts := strings.SplitN(strings.TrimPrefix(value, envelopePrefix), ":", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", ErrDecrypt
+	}
+	return parts[0], parts[1], nil
+}
@@ -0,0 +1,129 @@
+package secretbox
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func newTestBox(t *testing.T, masterKey string) *Box {
+	t.Helper()
+	box, err := New(masterKey)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return box
+}
+
+func TestEnvelopeRoundTrip(t *testing.T) {
+	box := newTestBox(t, "master-key")
+	for _, plaintext := range []string{"4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318", "", "含有:分隔符:的明文"} {
+		sealed, err := box.SealEnvelope(plaintext)
+		if err != nil {
+			t.Fatalf("SealEnvelope: %v", err)
+		}
+		if !IsEnvelope(sealed) || (plaintext != "" && strings.Contains(sealed, plaintext)) {
+			t.Fatalf("sealed value %q is not an opaque envelope", sealed)
+		}
+		opened, err := box.OpenEnvelope(sealed)
+		if err != nil || opened != plaintext {
+			t.Fatalf("OpenEnvelope=%q err=%v, want %q", opened, err, plaintext)
+		}
+	}
+
+	// 每次加密使用新的数据密钥
+	first, _ := box.SealEnvelope("secret")
+	second, _ := box.SealEnvelope("secret")
+	if first == second {
+		t.Fatal("sealing the same plaintext twice produced identical envelopes")
+	}
+}
+
+func TestOpenEnvelopeInvalid(t *testing.T) {
+	box := newTestBox(t, "master-key")
+	sealed, err := box.SealEnvelope("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrappedKey, body, err := splitEnvelope(sealed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 修改 base64 中间的一个字符，GCM 认证失败
+	tamper := func(s string) string {
+		i := len(s) / 2
+		c := byte('A')
+		if s[i] == c {
+			c = 'B'
+		}
+		return s[:i] + string(c) + s[i+1:]
+	}
+	otherBox := newTestBox(t, "other-master-key")
+	otherSealed, _ := otherBox.SealEnvelope("other secret")
+	_, otherBody, _ := splitEnvelope(otherSealed)
+
+	tests := []struct {
+		name    string
+		box     *Box
+		value   string
+		wantErr error
+	}{
+		{name: "wrong master key", box: otherBox, value: sealed, wantErr: ErrDecrypt},
+		{name: "tampered data key", box: box, value: envelopePrefix + tamper(wrappedKey) + ":" + body, wantErr: ErrDecrypt},
+		{name: "tampered body", box: box, value: envelopePrefix + wrappedKey + ":" + tamper(body), wantErr: ErrDecrypt},
+		{name: "body from another envelope", box: box, value: envelopePrefix + wrappedKey + ":" + otherBody, wantErr: ErrDecrypt},
+		{name: "missing body", box: box, value: envelopePrefix + wrappedKey, wantErr: ErrDecrypt},
+		{name: "plaintext", box: box, value: "secret", wantErr: ErrNotEnvelope},
+		{name: "legacy sealed value", box: box, value: mustSeal(t, box, "secret"), wantErr: ErrNotEnvelope},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.box.OpenEnvelope(tt.value); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("OpenEnvelope err=%v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func mustSeal(t *testing.T, box *Box, plaintext string) string {
+	t.Helper()
+	sealed, err := box.Seal(plaintext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sealed
+}
+
+func TestRewrapEnvelope(t *testing.T) {
+	oldBox := newTestBox(t, "old-master-key")
+	newBox := newTestBox(t, "new-master-key")
+	sealed, err := oldBox.SealEnvelope("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rewrapped, err := oldBox.RewrapEnvelope(sealed, newBox)
+	if err != nil {
+		t.Fatalf("RewrapEnvelope: %v", err)
+	}
+	if opened, err := newBox.OpenEnvelope(rewrapped); err != nil || opened != "secret" {
+		t.Fatalf("new master OpenEnvelope=%q err=%v, want secret", opened, err)
+	}
+	if _, err := oldBox.OpenEnvelope(rewrapped); !errors.Is(err, ErrDecrypt) {
+		t.Fatalf("old master OpenEnvelope err=%v, want ErrDecrypt", err)
+	}
+	// 只重新加密数据密钥，密文主体不变
+	_, oldBody, _ := splitEnvelope(sealed)
+	_, newBody, _ := splitEnvelope(rewrapped)
+	if oldBody != newBody {
+		t.Fatal("rewrap changed the envelope body")
+	}
+
+	// 主密钥不匹配时不能重新加密
+	if _, err := newBox.RewrapEnvelope(sealed, oldBox); !errors.Is(err, ErrDecrypt) {
+		t.Fatalf("RewrapEnvelope with wrong master err=%v, want ErrDecrypt", err)
+	}
+	if _, err := oldBox.RewrapEnvelope("secret", newBox); !errors.Is(err, ErrNotEnvelope) {
+		t.Fatalf("RewrapEnvelope plaintext err=%v, want ErrNotEnvelope", err)
+	}
+}
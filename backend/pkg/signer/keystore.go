@@ -0,0 +1,30 @@
+package signer
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+)
+
+// NewKeystoreSigner 从 go-ethereum 加密 keystore JSON 文件创建签名器
+// 注意：解密使用 scrypt，耗时较长，调用方应缓存返回的签名器
+func NewKeystoreSigner(path, passphrase string) (*PrivateKeySigner, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keystore file: %w", err)
+	}
+	return NewKeystoreSignerFromJSON(data, passphrase)
+}
+
+// NewKeystoreSignerFromJSON 从 keystore JSON 内容创建签名器
+func NewKeystoreSignerFromJSON(keyJSON []byte, passphrase string) (*PrivateKeySigner, error) {
+	key, err := keystore.DecryptKey(keyJSON, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt keystore: %w", err)
+	}
+	return &PrivateKeySigner{
+		key:     key.PrivateKey,
+		address: key.Address,
+	}, nil
+}
@@ -0,0 +1,228 @@
+package signer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// DefaultRemoteMethod 远程签名默认使用的 JSON-RPC 方法（Web3Signer 兼容）
+const DefaultRemoteMethod = "eth_signTransaction"
+
+// ErrRemoteTxMismatch 远程签名器返回的交易与请求不一致
+var ErrRemoteTxMismatch = errors.New("remote signer returned a different transaction")
+
+// RemoteSigner 通过 HTTP JSON-RPC 调用外部签名服务（Web3Signer / Clef 风格）
+// 私钥不进入本进程；返回的签名交易会校验内容和签名者，防止被替换
+type RemoteSigner struct {
+	url       string
+	address   common.Address
+	authToken string
+	method    string
+	client    *http.Client
+	requestID atomic.Uint64
+}
+
+// RemoteSignerOption 远程签名器可选配置
+type RemoteSignerOption func(*RemoteSigner)
+
+// WithAuthToken 设置 Authorization: Bearer 令牌
+func WithAuthToken(token string) RemoteSignerOption {
+	return func(s *RemoteSigner) { s.authToken = token }
+}
+
+// WithMethod 设置签名使用的 JSON-RPC 方法（如 Clef 的 account_signTransaction）
+func WithMethod(method string) RemoteSignerOption {
+	return func(s *RemoteSigner) { s.method = method }
+}
+
+// WithHTTPClient 设置 HTTP 客户端
+func WithHTTPClient(client *http.Client) RemoteSignerOption {
+	return func(s *RemoteSigner) { s.client = client }
+}
+
+// NewRemoteSigner 创建远程签名器，address 为远程服务中用于签名的账户
+func NewRemoteSigner(url string, address common.Address, opts ...RemoteSignerOption) *RemoteSigner {
+	s := &RemoteSigner{
+		url:     url,
+		address: address,
+		method:  DefaultRemoteMethod,
+		client:  &http.Client{Timeout: 15 * time.Second},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *RemoteSigner) Address() common.Address {
+	return s.address
+}
+
+// remoteTxArgs 签名请求参数（同时提供 data 和 input，兼容不同实现）
+type remoteTxArgs struct {
+	From                 common.Address  `json:"from"`
+	To                   *common.Address `json:"to,omitempty"`
+	Gas                  hexutil.Uint64  `json:"gas"`
+	GasPrice             *hexutil.Big    `json:"gasPrice,omitempty"`
+	MaxFeePerGas         *hexutil.Big    `json:"maxFeePerGas,omitempty"`
+	MaxPriorityFeePerGas *hexutil.Big    `json:"maxPriorityFeePerGas,omitempty"`
+	Value                *hexutil.Big    `json:"value"`
+	Nonce                hexutil.Uint64  `json:"nonce"`
+	Data                 hexutil.Bytes   `json:"data"`
+	Input                hexutil.Bytes   `json:"input"`
+	ChainID              *hexutil.Big    `json:"chainId"`
+}
+
+type rpcRequest struct {
+	JSONRPC string        `json:"jsonrpc"`
+	ID      uint64        `json:"id"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+type rpcResponse struct {
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func (s *RemoteSigner) SignTx(ctx context.Context, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	args := remoteTxArgs{
+		From:    s.address,
+		To:      tx.To(),
+		Gas:     hexutil.Uint64(tx.Gas()),
+		Value:   (*hexutil.Big)(tx.Value()),
+		Nonce:   hexutil.Uint64(tx.Nonce()),
+		Data:    tx.Data(),
+		Input:   tx.Data(),
+		ChainID: (*hexutil.Big)(chainID),
+	}
+	if tx.Type() == types.DynamicFeeTxType {
+		args.MaxFeePerGas = (*hexutil.Big)(tx.GasFeeCap())
+		args.MaxPriorityFeePerGas = (*hexutil.Big)(tx.GasTipCap())
+	} else {
+		args.GasPrice = (*hexutil.Big)(tx.GasPrice())
+	}
+
+	result, err := s.call(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := parseSignResult(result)
+	if err != nil {
+		return nil, err
+	}
+	signed := new(types.Transaction)
+	if err := signed.UnmarshalBinary(raw); err != nil {
+		return nil, fmt.Errorf("remote signer returned invalid transaction: %w", err)
+	}
+
+	if err := verifySignedTx(tx, signed, chainID, s.address); err != nil {
+		return nil, err
+	}
+	return signed, nil
+}
+
+func (s *RemoteSigner) call(ctx context.Context, args remoteTxArgs) (json.RawMessage, error) {
+	body, err := json.Marshal(rpcRequest{
+		JSONRPC: "2.0",
+		ID:      s.requestID.Add(1),
+		Method:  s.method,
+		Params:  []interface{}{args},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.authToken != "" {
+		req.Header.Set("Authorization", "Bearer "+s.authToken)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("remote signer request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("remote signer response read failed: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("remote signer returned HTTP %d", resp.StatusCode)
+	}
+
+	var rpcResp rpcResponse
+	if err := json.Unmarshal(data, &rpcResp); err != nil {
+		return nil, fmt.Errorf("remote signer returned invalid JSON: %w", err)
+	}
+	if rpcResp.Error != nil {
+		return nil, fmt.Errorf("remote signer error %d: %s", rpcResp.Error.Code, rpcResp.Error.Message)
+	}
+	return rpcResp.Result, nil
+}
+
+// parseSignResult 兼容两种返回格式：原始交易十六进制（Web3Signer）或 {"raw": "0x..."}（Clef）
+func parseSignResult(result json.RawMessage) ([]byte, error) {
+	var rawHex string
+	if err := json.Unmarshal(result, &rawHex); err != nil {
+		var obj struct {
+			Raw string `json:"raw"`
+		}
+		if err := json.Unmarshal(result, &obj); err != nil || obj.Raw == "" {
+			return nil, errors.New("remote signer returned unexpected result")
+		}
+		rawHex = obj.Raw
+	}
+	raw, err := hexutil.Decode(strings.TrimSpace(rawHex))
+	if err != nil {
+		return nil, fmt.Errorf("remote signer returned invalid hex: %w", err)
+	}
+	return raw, nil
+}
+
+// verifySignedTx 校验签名交易与请求一致且由预期账户签名
+func verifySignedTx(expected, signed *types.Transaction, chainID *big.Int, from common.Address) error {
+	sameTo := (expected.To() == nil && signed.To() == nil) ||
+		(expected.To() != nil && signed.To() != nil && *expected.To() == *signed.To())
+	if !sameTo ||
+		expected.Type() != signed.Type() ||
+		expected.Nonce() != signed.Nonce() ||
+		expected.Gas() != signed.Gas() ||
+		expected.Value().Cmp(signed.Value()) != 0 ||
+		expected.GasFeeCap().Cmp(signed.GasFeeCap()) != 0 ||
+		expected.GasTipCap().Cmp(signed.GasTipCap()) != 0 ||
+		!bytes.Equal(expected.Data(), signed.Data()) {
+		return ErrRemoteTxMismatch
+	}
+
+	sender, err := types.Sender(types.LatestSignerForChainID(chainID), signed)
+	if err != nil {
+		return fmt.Errorf("remote signer returned invalid signature: %w", err)
+	}
+	if sender != from {
+		return fmt.Errorf("%w: signed by %s, expected %s", ErrRemoteTxMismatch, sender.Hex(), from.Hex())
+	}
+	return nil
+}
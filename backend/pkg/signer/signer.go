@@ -0,0 +1,59 @@
+package signer
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// ErrInvalidPrivateKey 私钥格式错误
+var ErrInvalidPrivateKey = errors.New("invalid private key")
+
+// Signer 交易签名器（提现逻辑只依赖该接口，不直接接触私钥）
+type Signer interface {
+	// Address 签名账户地址
+	Address() common.Address
+	// SignTx 按 chainID 对交易签名
+	SignTx(ctx context.Context, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error)
+}
+
+// PrivateKeySigner 使用内存中的私钥签名
+type PrivateKeySigner struct {
+	key     *ecdsa.PrivateKey
+	address common.Address
+}
+
+// NewPrivateKeySigner 从十六进制私钥创建签名器（可带 0x 前缀）
+func NewPrivateKeySigner(privateKeyHex string) (*PrivateKeySigner, error) {
+	key, err := crypto.HexToECDSA(strings.TrimPrefix(strings.TrimSpace(privateKeyHex), "0x"))
+	if err != nil {
+		return nil, ErrInvalidPrivateKey
+	}
+	return &PrivateKeySigner{
+		key:     key,
+		address: crypto.PubkeyToAddress(key.PublicKey),
+	}, nil
+}
+
+// AddressFromPrivateKey 计算私钥对应的地址
+func AddressFromPrivateKey(privateKeyHex string) (common.Address, error) {
+	s, err := NewPrivateKeySigner(privateKeyHex)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return s.Address(), nil
+}
+
+func (s *PrivateKeySigner) Address() common.Address {
+	return s.address
+}
+
+func (s *PrivateKeySigner) SignTx(ctx context.Context, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return types.SignTx(tx, types.LatestSignerForChainID(chainID), s.key)
+}
@@ -0,0 +1,288 @@
+package signer
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const testKeyHex = "4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318"
+
+var (
+	testKeyAddress = common.HexToAddress("0x2c7536E3605D9C16a7a3D7b1898e529396a65c23")
+	testChainID    = big.NewInt(56)
+	testRecipient  = common.HexToAddress("0x00000000000000000000000000000000000000aa")
+)
+
+func legacyTx() *types.Transaction {
+	return types.NewTx(&types.LegacyTx{Nonce: 3, To: &testRecipient, Value: big.NewInt(1000), Gas: 21000, GasPrice: big.NewInt(5e9)})
+}
+
+func dynamicFeeTx() *types.Transaction {
+	return types.NewTx(&types.DynamicFeeTx{
+		ChainID: testChainID, Nonce: 4, To: &testRecipient, Value: big.NewInt(1000), Gas: 60000,
+		GasTipCap: big.NewInt(1e9), GasFeeCap: big.NewInt(3e9), Data: []byte{0xa9, 0x05, 0x9c, 0xbb},
+	})
+}
+
+func TestPrivateKeySigner(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     string
+		wantErr bool
+	}{
+		{name: "plain hex", key: testKeyHex},
+		{name: "0x prefix and spaces", key: " 0x" + testKeyHex + "\n"},
+		{name: "invalid hex", key: "zz", wantErr: true},
+		{name: "empty", key: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewPrivateKeySigner(tt.key)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidPrivateKey) {
+					t.Fatalf("NewPrivateKeySigner err=%v, want ErrInvalidPrivateKey", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewPrivateKeySigner: %v", err)
+			}
+			if s.Address() != testKeyAddress {
+				t.Fatalf("Address=%s, want %s", s.Address().Hex(), testKeyAddress.Hex())
+			}
+			for _, tx := range []*types.Transaction{legacyTx(), dynamicFeeTx()} {
+				signed, err := s.SignTx(context.Background(), tx, testChainID)
+				if err != nil {
+					t.Fatalf("SignTx: %v", err)
+				}
+				sender, err := types.Sender(types.LatestSignerForChainID(testChainID), signed)
+				if err != nil || sender != testKeyAddress {
+					t.Fatalf("signed tx sender=%s err=%v, want %s", sender.Hex(), err, testKeyAddress.Hex())
+				}
+				if signed.ChainId().Cmp(testChainID) != 0 {
+					t.Fatalf("signed tx chain id=%s, want %s", signed.ChainId(), testChainID)
+				}
+			}
+		})
+	}
+}
+
+func TestKeystoreSigner(t *testing.T) {
+	key, err := crypto.HexToECDSA(testKeyHex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyJSON, err := keystore.EncryptKey(&keystore.Key{
+		Address:    testKeyAddress,
+		PrivateKey: key,
+	}, "correct horse", keystore.LightScryptN, keystore.LightScryptP)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "keystore.json")
+	if err := os.WriteFile(path, keyJSON, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		path       string
+		passphrase string
+		wantErr    bool
+	}{
+		{name: "correct passphrase", path: path, passphrase: "correct horse"},
+		{name: "wrong passphrase", path: path, passphrase: "battery staple", wantErr: true},
+		{name: "missing file", path: filepath.Join(t.TempDir(), "missing.json"), passphrase: "correct horse", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewKeystoreSigner(tt.path, tt.passphrase)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewKeystoreSigner err=%v, wantErr=%v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if s.Address() != testKeyAddress {
+				t.Fatalf("Address=%s, want %s", s.Address().Hex(), testKeyAddress.Hex())
+			}
+			signed, err := s.SignTx(context.Background(), legacyTx(), testChainID)
+			if err != nil {
+				t.Fatalf("SignTx: %v", err)
+			}
+			if sender, _ := types.Sender(types.LatestSignerForChainID(testChainID), signed); sender != testKeyAddress {
+				t.Fatalf("signed tx sender=%s, want %s", sender.Hex(), testKeyAddress.Hex())
+			}
+		})
+	}
+}
+
+// remoteNode 模拟远程签名服务：按请求参数重建交易并签名，tamper 可在签名前修改交易
+type remoteNode struct {
+	key        *ecdsa.PrivateKey
+	clefFormat bool
+	tamper     func(args *remoteTxArgs)
+	status     int
+	rpcError   string
+
+	gotMethod string
+	gotAuth   string
+}
+
+func (n *remoteNode) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	n.gotAuth = r.Header.Get("Authorization")
+	var req struct {
+		ID     uint64         `json:"id"`
+		Method string         `json:"method"`
+		Params []remoteTxArgs `json:"params"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Params) != 1 {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	n.gotMethod = req.Method
+	if n.status != 0 {
+		http.Error(w, "unavailable", n.status)
+		return
+	}
+	if n.rpcError != "" {
+		json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "error": map[string]interface{}{"code": -32000, "message": n.rpcError}})
+		return
+	}
+
+	args := req.Params[0]
+	if n.tamper != nil {
+		n.tamper(&args)
+	}
+	var tx *types.Transaction
+	if args.MaxFeePerGas != nil {
+		tx = types.NewTx(&types.DynamicFeeTx{
+			ChainID: args.ChainID.ToInt(), Nonce: uint64(args.Nonce), To: args.To, Value: args.Value.ToInt(), Gas: uint64(args.Gas),
+			GasTipCap: args.MaxPriorityFeePerGas.ToInt(), GasFeeCap: args.MaxFeePerGas.ToInt(), Data: args.Data,
+		})
+	} else {
+		tx = types.NewTx(&types.LegacyTx{
+			Nonce: uint64(args.Nonce), To: args.To, Value: args.Value.ToInt(), Gas: uint64(args.Gas),
+			GasPrice: args.GasPrice.ToInt(), Data: args.Data,
+		})
+	}
+	signed, err := types.SignTx(tx, types.LatestSignerForChainID(args.ChainID.ToInt()), n.key)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	raw, _ := signed.MarshalBinary()
+
+	var result interface{} = hexutil.Encode(raw)
+	if n.clefFormat {
+		result = map[string]interface{}{"raw": hexutil.Encode(raw), "tx": signed}
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "result": result})
+}
+
+func TestRemoteSigner(t *testing.T) {
+	key, err := crypto.HexToECDSA(testKeyHex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		node       *remoteNode
+		tx         *types.Transaction
+		opts       []RemoteSignerOption
+		wantMethod string
+		wantAuth   string
+		wantErr    error
+		wantAnyErr bool
+	}{
+		{name: "web3signer legacy", node: &remoteNode{key: key}, tx: legacyTx(), wantMethod: DefaultRemoteMethod},
+		{name: "web3signer eip-1559", node: &remoteNode{key: key}, tx: dynamicFeeTx(), wantMethod: DefaultRemoteMethod},
+		{
+			name: "clef format with options", node: &remoteNode{key: key, clefFormat: true}, tx: dynamicFeeTx(),
+			opts:       []RemoteSignerOption{WithMethod("account_signTransaction"), WithAuthToken("secret")},
+			wantMethod: "account_signTransaction", wantAuth: "Bearer secret",
+		},
+		{
+			// 签名服务替换了收款金额
+			name: "tampered value", node: &remoteNode{key: key, tamper: func(args *remoteTxArgs) { args.Value = (*hexutil.Big)(big.NewInt(999999)) }},
+			tx: legacyTx(), wantErr: ErrRemoteTxMismatch,
+		},
+		{
+			name: "tampered recipient", node: &remoteNode{key: key, tamper: func(args *remoteTxArgs) { other := common.Address{0xee}; args.To = &other }},
+			tx: dynamicFeeTx(), wantErr: ErrRemoteTxMismatch,
+		},
+		{name: "signed by another account", node: &remoteNode{key: otherKey}, tx: legacyTx(), wantErr: ErrRemoteTxMismatch},
+		{name: "rpc error", node: &remoteNode{key: key, rpcError: "account locked"}, tx: legacyTx(), wantAnyErr: true},
+		{name: "http error", node: &remoteNode{key: key, status: http.StatusServiceUnavailable}, tx: legacyTx(), wantAnyErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(tt.node)
+			defer srv.Close()
+
+			s := NewRemoteSigner(srv.URL, testKeyAddress, tt.opts...)
+			signed, err := s.SignTx(context.Background(), tt.tx, testChainID)
+			if tt.wantErr != nil || tt.wantAnyErr {
+				if err == nil || (tt.wantErr != nil && !errors.Is(err, tt.wantErr)) {
+					t.Fatalf("SignTx err=%v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SignTx: %v", err)
+			}
+			if signed.Hash() == tt.tx.Hash() || signed.Nonce() != tt.tx.Nonce() || signed.Type() != tt.tx.Type() {
+				t.Fatalf("unexpected signed tx: type=%d nonce=%d", signed.Type(), signed.Nonce())
+			}
+			if tt.node.gotMethod != tt.wantMethod || tt.node.gotAuth != tt.wantAuth {
+				t.Fatalf("request method=%q auth=%q, want %q/%q", tt.node.gotMethod, tt.node.gotAuth, tt.wantMethod, tt.wantAuth)
+			}
+		})
+	}
+}
+
+func TestParseSignResult(t *testing.T) {
+	tests := []struct {
+		name    string
+		result  string
+		want    string
+		wantErr bool
+	}{
+		{name: "hex string", result: `"0x0102"`, want: "0x0102"},
+		{name: "clef object", result: `{"raw":"0x0102","tx":{}}`, want: "0x0102"},
+		{name: "empty object", result: `{}`, wantErr: true},
+		{name: "invalid hex", result: `"0102"`, wantErr: true},
+		{name: "null", result: `null`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw, err := parseSignResult(json.RawMessage(tt.result))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseSignResult err=%v, wantErr=%v", err, tt.wantErr)
+			}
+			if err == nil && !strings.EqualFold(hexutil.Encode(raw), tt.want) {
+				t.Fatalf("parseSignResult=%s, want %s", hexutil.Encode(raw), tt.want)
+			}
+		})
+	}
+}
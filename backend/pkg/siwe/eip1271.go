@@ -0,0 +1,163 @@
+package siwe
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// EIP-1271 isValidSignature(bytes32,bytes) 校验通过时返回的魔数
+var eip1271MagicValue = [4]byte{0x16, 0x26, 0xba, 0x7e}
+
+const eip1271ABI = `[{"inputs":[{"name":"hash","type":"bytes32"},{"name":"signature","type":"bytes"}],"name":"isValidSignature","outputs":[{"name":"magicValue","type":"bytes4"}],"stateMutability":"view","type":"function"}]`
+
+var parsedEIP1271ABI, _ = abi.JSON(strings.NewReader(eip1271ABI))
+
+// ContractCaller 合约只读调用接口（ethclient.Client、simulated 客户端均已实现）
+type ContractCaller interface {
+	CodeAt(ctx context.Context, account common.Address, blockNumber *big.Int) ([]byte, error)
+	CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error)
+}
+
+type cacheEntry struct {
+	valid     bool
+	expiresAt time.Time
+}
+
+// ContractVerifier 智能合约钱包签名校验（EIP-1271），结果带过期缓存
+type ContractVerifier struct {
+	caller ContractCaller
+	ttl    time.Duration
+
+	mu        sync.Mutex
+	codeCache map[common.Address]cacheEntry // 地址是否为合约
+	sigCache  map[[32]byte]cacheEntry       // 签名校验结果
+}
+
+// NewContractVerifier 创建合约钱包签名校验器，ttl 为缓存有效期
+func NewContractVerifier(caller ContractCaller, ttl time.Duration) *ContractVerifier {
+	return &ContractVerifier{
+		caller:    caller,
+		ttl:       ttl,
+		codeCache: make(map[common.Address]cacheEntry),
+		sigCache:  make(map[[32]byte]cacheEntry),
+	}
+}
+
+// VerifySignature 调用钱包合约的 isValidSignature 校验 personal_sign 签名
+// 地址不是合约或合约判定签名无效时返回 ErrSignerMismatch，RPC 异常时返回原始错误
+func (v *ContractVerifier) VerifySignature(ctx context.Context, message, signatureHex, address string) error {
+	sig, err := hexutil.Decode(signatureHex)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	wallet := common.HexToAddress(address)
+	isContract, err := v.isContract(ctx, wallet)
+	if err != nil {
+		return err
+	}
+	if !isContract {
+		return ErrSignerMismatch
+	}
+
+	hash := HashMessage(message)
+	key := sha256.Sum256(append(append(wallet.Bytes(), hash.Bytes()...), sig...))
+	if valid, ok := v.lookup(v.sigCache, key); ok {
+		if !valid {
+			return ErrSignerMismatch
+		}
+		return nil
+	}
+
+	input, err := parsedEIP1271ABI.Pack("isValidSignature", hash, sig)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	output, err := v.caller.CallContract(ctx, ethereum.CallMsg{To: &wallet, Data: input}, nil)
+	if err != nil {
+		// 合约 revert 视为签名无效，但不缓存（无法与节点异常区分）
+		return ErrSignerMismatch
+	}
+
+	valid := len(output) >= 4 && bytes.Equal(output[:4], eip1271MagicValue[:])
+	v.store(v.sigCache, key, valid)
+
+	if !valid {
+		return ErrSignerMismatch
+	}
+	return nil
+}
+
+// isContract 检查地址是否部署了合约代码（带缓存）
+func (v *ContractVerifier) isContract(ctx context.Context, address common.Address) (bool, error) {
+	v.mu.Lock()
+	entry, ok := v.codeCache[address]
+	v.mu.Unlock()
+	if ok && time.Now().Before(entry.expiresAt) {
+		return entry.valid, nil
+	}
+
+	code, err := v.caller.CodeAt(ctx, address, nil)
+	if err != nil {
+		return false, err
+	}
+
+	v.mu.Lock()
+	v.codeCache[address] = cacheEntry{valid: len(code) > 0, expiresAt: time.Now().Add(v.ttl)}
+	v.mu.Unlock()
+	return len(code) > 0, nil
+}
+
+func (v *ContractVerifier) lookup(cache map[[32]byte]cacheEntry, key [32]byte) (bool, bool) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	entry, ok := cache[key]
+	if !ok {
+		return false, false
+	}
+	if time.Now().After(entry.expiresAt) {
+		delete(cache, key)
+		return false, false
+	}
+	return entry.valid, true
+}
+
+func (v *ContractVerifier) store(cache map[[32]byte]cacheEntry, key [32]byte, valid bool) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	// 清理过期条目，避免缓存无限增长
+	now := time.Now()
+	for k, entry := range cache {
+		if now.After(entry.expiresAt) {
+			delete(cache, k)
+		}
+	}
+	cache[key] = cacheEntry{valid: valid, expiresAt: now.Add(v.ttl)}
+}
+
+// ParseChainID 从 SIWE 消息中解析链ID
+func ParseChainID(message string) (int, bool) {
+	for _, line := range strings.Split(message, "\n") {
+		if value, ok := strings.CutPrefix(line, "Chain ID: "); ok {
+			var chainID int
+			if _, err := fmt.Sscanf(value, "%d", &chainID); err == nil && chainID > 0 {
+				return chainID, true
+			}
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,289 @@
+package siwe
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient/simulated"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+const (
+	testWallet    = "0x00000000000000000000000000000000000000bb"
+	testSignature = "0x0102030405"
+)
+
+// fakeCaller 模拟合约钱包：code 为空表示 EOA，output 为 isValidSignature 返回值
+type fakeCaller struct {
+	code    []byte
+	codeErr error
+	output  []byte
+	callErr error
+
+	codeCalls int
+	calls     int
+	lastHash  common.Hash
+	lastSig   []byte
+}
+
+func (c *fakeCaller) CodeAt(ctx context.Context, account common.Address, blockNumber *big.Int) ([]byte, error) {
+	c.codeCalls++
+	return c.code, c.codeErr
+}
+
+func (c *fakeCaller) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	c.calls++
+	args, err := parsedEIP1271ABI.Methods["isValidSignature"].Inputs.Unpack(call.Data[4:])
+	if err == nil && len(args) == 2 {
+		c.lastHash = common.Hash(args[0].([32]byte))
+		c.lastSig = args[1].([]byte)
+	}
+	return c.output, c.callErr
+}
+
+// magicOutput isValidSignature 的 ABI 编码返回值（bytes4 右侧补零到 32 字节）
+func magicOutput(magic [4]byte) []byte {
+	out := make([]byte, 32)
+	copy(out, magic[:])
+	return out
+}
+
+func TestContractVerifierVerifySignature(t *testing.T) {
+	rpcErr := errors.New("connection refused")
+
+	tests := []struct {
+		name      string
+		caller    *fakeCaller
+		signature string
+		wantErr   error
+		wantCalls int
+	}{
+		{
+			name:      "magic value",
+			caller:    &fakeCaller{code: []byte{0x60}, output: magicOutput(eip1271MagicValue)},
+			signature: testSignature,
+			wantCalls: 1,
+		},
+		{
+			name:      "wrong magic value",
+			caller:    &fakeCaller{code: []byte{0x60}, output: magicOutput([4]byte{0xff, 0xff, 0xff, 0xff})},
+			signature: testSignature,
+			wantErr:   ErrSignerMismatch,
+			wantCalls: 1,
+		},
+		{
+			name:      "short output",
+			caller:    &fakeCaller{code: []byte{0x60}, output: []byte{0x16, 0x26}},
+			signature: testSignature,
+			wantErr:   ErrSignerMismatch,
+			wantCalls: 1,
+		},
+		{
+			name:      "contract reverts",
+			caller:    &fakeCaller{code: []byte{0x60}, callErr: errors.New("execution reverted")},
+			signature: testSignature,
+			wantErr:   ErrSignerMismatch,
+			wantCalls: 1,
+		},
+		{
+			// EOA 不调用合约，交给 ECDSA 校验的结果
+			name:      "not a contract",
+			caller:    &fakeCaller{output: magicOutput(eip1271MagicValue)},
+			signature: testSignature,
+			wantErr:   ErrSignerMismatch,
+		},
+		{
+			name:      "code lookup error",
+			caller:    &fakeCaller{codeErr: rpcErr},
+			signature: testSignature,
+			wantErr:   rpcErr,
+		},
+		{
+			name:      "malformed signature",
+			caller:    &fakeCaller{code: []byte{0x60}, output: magicOutput(eip1271MagicValue)},
+			signature: "not-hex",
+			wantErr:   ErrInvalidSignature,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			message := testMessage().String()
+			verifier := NewContractVerifier(tt.caller, time.Minute)
+
+			err := verifier.VerifySignature(context.Background(), message, tt.signature, testWallet)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("VerifySignature err=%v, want %v", err, tt.wantErr)
+			}
+			if tt.caller.calls != tt.wantCalls {
+				t.Fatalf("CallContract calls=%d, want %d", tt.caller.calls, tt.wantCalls)
+			}
+			if tt.wantCalls > 0 {
+				// 合约收到的是 EIP-191 消息哈希和原始签名
+				if tt.caller.lastHash != HashMessage(message) {
+					t.Fatalf("contract called with hash %s, want %s", tt.caller.lastHash.Hex(), HashMessage(message).Hex())
+				}
+				if common.Bytes2Hex(tt.caller.lastSig) != "0102030405" {
+					t.Fatalf("contract called with signature %x", tt.caller.lastSig)
+				}
+			}
+		})
+	}
+}
+
+func TestContractVerifierCache(t *testing.T) {
+	tests := []struct {
+		name          string
+		caller        *fakeCaller
+		ttl           time.Duration
+		wantCodeCalls int
+		wantCalls     int
+	}{
+		{
+			name:          "valid result cached",
+			caller:        &fakeCaller{code: []byte{0x60}, output: magicOutput(eip1271MagicValue)},
+			ttl:           time.Minute,
+			wantCodeCalls: 1,
+			wantCalls:     1,
+		},
+		{
+			name:          "invalid result cached",
+			caller:        &fakeCaller{code: []byte{0x60}, output: magicOutput([4]byte{})},
+			ttl:           time.Minute,
+			wantCodeCalls: 1,
+			wantCalls:     1,
+		},
+		{
+			// revert 无法与节点异常区分，不缓存
+			name:          "revert not cached",
+			caller:        &fakeCaller{code: []byte{0x60}, callErr: errors.New("execution reverted")},
+			ttl:           time.Minute,
+			wantCodeCalls: 1,
+			wantCalls:     3,
+		},
+		{
+			name:          "expired entries refreshed",
+			caller:        &fakeCaller{code: []byte{0x60}, output: magicOutput(eip1271MagicValue)},
+			ttl:           -time.Second,
+			wantCodeCalls: 3,
+			wantCalls:     3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			message := testMessage().String()
+			verifier := NewContractVerifier(tt.caller, tt.ttl)
+
+			first := verifier.VerifySignature(context.Background(), message, testSignature, testWallet)
+			for i := 0; i < 2; i++ {
+				if err := verifier.VerifySignature(context.Background(), message, testSignature, testWallet); err != first {
+					t.Fatalf("call %d err=%v, want %v", i+2, err, first)
+				}
+			}
+			if tt.caller.codeCalls != tt.wantCodeCalls || tt.caller.calls != tt.wantCalls {
+				t.Fatalf("CodeAt calls=%d CallContract calls=%d, want %d/%d",
+					tt.caller.codeCalls, tt.caller.calls, tt.wantCodeCalls, tt.wantCalls)
+			}
+		})
+	}
+
+	// 不同签名不共用缓存结果
+	caller := &fakeCaller{code: []byte{0x60}, output: magicOutput(eip1271MagicValue)}
+	verifier := NewContractVerifier(caller, time.Minute)
+	message := testMessage().String()
+	if err := verifier.VerifySignature(context.Background(), message, testSignature, testWallet); err != nil {
+		t.Fatalf("VerifySignature: %v", err)
+	}
+	caller.output = magicOutput([4]byte{})
+	if err := verifier.VerifySignature(context.Background(), message, "0x0a0b", testWallet); !errors.Is(err, ErrSignerMismatch) {
+		t.Fatalf("different signature err=%v, want ErrSignerMismatch", err)
+	}
+}
+
+// eip1271WalletCode 最小 EIP-1271 合约的部署代码：isValidSignature 的 hash 参数等于 validHash 时返回魔术值，否则返回 0xffffffff
+func eip1271WalletCode(validHash common.Hash) []byte {
+	runtime := []byte{0x60, 0x04, 0x35, 0x7f} // PUSH1 4 CALLDATALOAD PUSH32
+	runtime = append(runtime, validHash.Bytes()...)
+	runtime = append(runtime,
+		0x14, 0x60, 0x38, 0x57, // EQ PUSH1 valid JUMPI
+		0x63, 0xff, 0xff, 0xff, 0xff, 0x60, 0xe0, 0x1b, // PUSH4 0xffffffff PUSH1 224 SHL
+		0x60, 0x00, 0x52, 0x60, 0x20, 0x60, 0x00, 0xf3, // MSTORE(0) RETURN(0, 32)
+		0x5b, // valid: JUMPDEST
+		0x63, eip1271MagicValue[0], eip1271MagicValue[1], eip1271MagicValue[2], eip1271MagicValue[3], 0x60, 0xe0, 0x1b,
+		0x60, 0x00, 0x52, 0x60, 0x20, 0x60, 0x00, 0xf3,
+	)
+	// 构造函数：CODECOPY 运行时代码并返回
+	initCode := []byte{0x60, byte(len(runtime)), 0x80, 0x60, 0x0b, 0x60, 0x00, 0x39, 0x60, 0x00, 0xf3}
+	return append(initCode, runtime...)
+}
+
+// TestContractVerifierSimulated 在模拟链上部署合约钱包，经真实的 eth_getCode / eth_call 校验
+func TestContractVerifierSimulated(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	deployer := crypto.PubkeyToAddress(key.PublicKey)
+	backend := simulated.NewBackend(types.GenesisAlloc{deployer: {Balance: big.NewInt(params.Ether)}})
+	defer backend.Close()
+	client := backend.Client()
+	ctx := context.Background()
+
+	message := testMessage().String()
+	chainID, err := client.ChainID(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	head, err := client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx, err := types.SignNewTx(key, types.LatestSignerForChainID(chainID), &types.DynamicFeeTx{
+		ChainID:   chainID,
+		Gas:       200000,
+		GasTipCap: big.NewInt(params.GWei),
+		GasFeeCap: new(big.Int).Add(new(big.Int).Mul(head.BaseFee, big.NewInt(2)), big.NewInt(params.GWei)),
+		Data:      eip1271WalletCode(HashMessage(message)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.SendTransaction(ctx, tx); err != nil {
+		t.Fatalf("deploy: %v", err)
+	}
+	backend.Commit()
+	receipt, err := client.TransactionReceipt(ctx, tx.Hash())
+	if err != nil || receipt.Status != types.ReceiptStatusSuccessful {
+		t.Fatalf("deploy receipt=%v err=%v", receipt, err)
+	}
+	wallet := receipt.ContractAddress.Hex()
+
+	tests := []struct {
+		name    string
+		message string
+		address string
+		wantErr error
+	}{
+		{name: "valid signature", message: message, address: wallet},
+		{
+			// 合约对其他消息返回非魔术值
+			name: "invalid magic value", message: testMessage("https://example.com/other").String(), address: wallet,
+			wantErr: ErrSignerMismatch,
+		},
+		{name: "account without code", message: message, address: deployer.Hex(), wantErr: ErrSignerMismatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verifier := NewContractVerifier(client, time.Minute)
+			if err := verifier.VerifySignature(ctx, tt.message, testSignature, tt.address); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("VerifySignature err=%v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
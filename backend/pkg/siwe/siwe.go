@@ -0,0 +1,132 @@
+package siwe
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+var (
+	// ErrInvalidSignature 签名格式错误（长度、编码、v值）
+	ErrInvalidSignature = errors.New("invalid signature format")
+	// ErrSignerMismatch 签名可以恢复出地址，但与声明的钱包地址不一致
+	ErrSignerMismatch = errors.New("signature signer does not match wallet address")
+)
+
+// Message Sign-In with Ethereum 登录消息（EIP-4361）
+type Message struct {
+	Domain         string    // 请求签名的域名，例如 example.com
+	Address        string    // 钱包地址（输出时使用 EIP-55 校验和格式）
+	Statement      string    // 展示给用户的说明文字（可选）
+	URI            string    // 登录的资源地址
+	Version        string    // 固定为 1
+	ChainID        int       // 链ID
+	Nonce          string    // 随机数（至少8位字母数字）
+	IssuedAt       time.Time // 签发时间
+	ExpirationTime time.Time // 过期时间
+	Resources      []string  // 随登录一起授权的资源 URI（可选）
+}
+
+// String 按 EIP-4361 规定的格式生成待签名文本
+func (m *Message) String() string {
+	var b strings.Builder
+
+	b.WriteString(fmt.Sprintf("%s wants you to sign in with your Ethereum account:\n", m.Domain))
+	b.WriteString(common.HexToAddress(m.Address).Hex())
+	b.WriteString("\n\n")
+	if m.Statement != "" {
+		b.WriteString(m.Statement)
+		b.WriteString("\n")
+	}
+	b.WriteString("\n")
+
+	version := m.Version
+	if version == "" {
+		version = "1"
+	}
+
+	b.WriteString(fmt.Sprintf("URI: %s\n", m.URI))
+	b.WriteString(fmt.Sprintf("Version: %s\n", version))
+	b.WriteString(fmt.Sprintf("Chain ID: %d\n", m.ChainID))
+	b.WriteString(fmt.Sprintf("Nonce: %s\n", m.Nonce))
+	b.WriteString(fmt.Sprintf("Issued At: %s", m.IssuedAt.UTC().Format(time.RFC3339)))
+	if !m.ExpirationTime.IsZero() {
+		b.WriteString(fmt.Sprintf("\nExpiration Time: %s", m.ExpirationTime.UTC().Format(time.RFC3339)))
+	}
+	if len(m.Resources) > 0 {
+		b.WriteString("\nResources:")
+		for _, resource := range m.Resources {
+			b.WriteString("\n- " + resource)
+		}
+	}
+
+	return b.String()
+}
+
+// ParseResources 从 SIWE 消息中解析 Resources 列表
+func ParseResources(message string) []string {
+	var resources []string
+	inResources := false
+	for _, line := range strings.Split(message, "\n") {
+		if line == "Resources:" {
+			inResources = true
+			continue
+		}
+		if !inResources {
+			continue
+		}
+		resource, ok := strings.CutPrefix(line, "- ")
+		if !ok {
+			break
+		}
+		resources = append(resources, resource)
+	}
+	return resources
+}
+
+// HashMessage 计算 EIP-191 personal_sign 消息哈希
+func HashMessage(message string) common.Hash {
+	return common.BytesToHash(accounts.TextHash([]byte(message)))
+}
+
+// RecoverAddress 从 personal_sign 签名中恢复签名者地址
+func RecoverAddress(message, signatureHex string) (common.Address, error) {
+	sig, err := hexutil.Decode(signatureHex)
+	if err != nil || len(sig) != crypto.SignatureLength {
+		return common.Address{}, ErrInvalidSignature
+	}
+
+	// 钱包返回的 v 值为 27/28，ecrecover 需要 0/1
+	sig = append([]byte(nil), sig...)
+	if sig[crypto.RecoveryIDOffset] >= 27 {
+		sig[crypto.RecoveryIDOffset] -= 27
+	}
+	if sig[crypto.RecoveryIDOffset] > 1 {
+		return common.Address{}, ErrInvalidSignature
+	}
+
+	pubKey, err := crypto.SigToPub(HashMessage(message).Bytes(), sig)
+	if err != nil {
+		return common.Address{}, ErrInvalidSignature
+	}
+
+	return crypto.PubkeyToAddress(*pubKey), nil
+}
+
+// VerifySignature 校验签名是否由指定地址签出（EOA 钱包）
+func VerifySignature(message, signatureHex, address string) error {
+	signer, err := RecoverAddress(message, signatureHex)
+	if err != nil {
+		return err
+	}
+	if signer != common.HexToAddress(address) {
+		return ErrSignerMismatch
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+package siwe
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func testMessage(resources ...string) *Message {
+	issuedAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	return &Message{
+		Domain:         "example.com",
+		Address:        "0x00000000000000000000000000000000000000aa",
+		Statement:      "Sign in",
+		URI:            "https://example.com",
+		Version:        "1",
+		ChainID:        56,
+		Nonce:          "0123456789abcdef",
+		IssuedAt:       issuedAt,
+		ExpirationTime: issuedAt.Add(5 * time.Minute),
+		Resources:      resources,
+	}
+}
+
+func TestMessageString(t *testing.T) {
+	want := "example.com wants you to sign in with your Ethereum account:\n" +
+		"0x00000000000000000000000000000000000000AA\n\n" +
+		"Sign in\n\n" +
+		"URI: https://example.com\n" +
+		"Version: 1\n" +
+		"Chain ID: 56\n" +
+		"Nonce: 0123456789abcdef\n" +
+		"Issued At: 2024-01-02T03:04:05Z\n" +
+		"Expiration Time: 2024-01-02T03:09:05Z\n" +
+		"Resources:\n" +
+		"- urn:example:a"
+	if got := testMessage("urn:example:a").String(); got != want {
+		t.Fatalf("unexpected message:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestParseResources(t *testing.T) {
+	tests := []struct {
+		name      string
+		resources []string
+	}{
+		{name: "none"},
+		{name: "single", resources: []string{"urn:expchange:referral:ABCD2345"}},
+		{name: "multiple", resources: []string{"urn:example:a", "https://example.com/b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseResources(testMessage(tt.resources...).String())
+			if !reflect.DeepEqual(got, tt.resources) {
+				t.Fatalf("ParseResources=%v, want %v", got, tt.resources)
+			}
+		})
+	}
+}
+
+func TestParseChainID(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		want    int
+		wantOK  bool
+	}{
+		{name: "siwe message", message: testMessage().String(), want: 56, wantOK: true},
+		{name: "missing", message: "hello"},
+		{name: "invalid", message: "Chain ID: abc"},
+		{name: "zero", message: "Chain ID: 0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ParseChainID(tt.message)
+			if got != tt.want || ok != tt.wantOK {
+				t.Fatalf("ParseChainID=(%d, %v), want (%d, %v)", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestVerifySignature(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := crypto.PubkeyToAddress(key.PublicKey).Hex()
+	message := testMessage().String()
+
+	sign := func(message string, v27 bool) string {
+		sig, err := crypto.Sign(HashMessage(message).Bytes(), key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v27 {
+			sig[crypto.RecoveryIDOffset] += 27
+		}
+		return hexutil.Encode(sig)
+	}
+
+	tests := []struct {
+		name      string
+		message   string
+		signature string
+		address   string
+		wantErr   error
+	}{
+		{name: "valid v 27/28", message: message, signature: sign(message, true), address: address},
+		{name: "valid v 0/1", message: message, signature: sign(message, false), address: address},
+		{name: "lowercase address", message: message, signature: sign(message, true), address: strings.ToLower(address)},
+		{name: "other signer", message: message, signature: sign(message, true), address: crypto.PubkeyToAddress(other.PublicKey).Hex(), wantErr: ErrSignerMismatch},
+		{name: "tampered message", message: message + "\n- urn:evil", signature: sign(message, true), address: address, wantErr: ErrSignerMismatch},
+		{name: "bad length", message: message, signature: "0x1234", address: address, wantErr: ErrInvalidSignature},
+		{name: "bad hex", message: message, signature: "not-hex", address: address, wantErr: ErrInvalidSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifySignature(tt.message, tt.signature, tt.address)
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("VerifySignature: %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("VerifySignature err=%v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHashMessage(t *testing.T) {
+	tests := []struct {
+		message string
+		want    string
+	}{
+		// 与 ethers.js hashMessage / eth_sign 的结果一致
+		{message: "hello", want: "0x50b2c43fd39106bafbba0da34fc430e1f91e3c96ea2acee2bc34119f92b37750"},
+		{message: "", want: "0x5f35dce98ba4fba25530a026ed80b2cecdaa31091ba4958b99b52ea1d068adad"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.message, func(t *testing.T) {
+			if got := HashMessage(tt.message).Hex(); got != tt.want {
+				t.Fatalf("HashMessage(%q)=%s, want %s", tt.message, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecoverAddress(t *testing.T) {
+	key, err := crypto.HexToECDSA("4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318")
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = "0x2c7536E3605D9C16a7a3D7b1898e529396a65c23"
+	message := testMessage().String()
+	sig, err := crypto.Sign(HashMessage(message).Bytes(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	withV := func(v byte) string {
+		s := append([]byte(nil), sig...)
+		s[crypto.RecoveryIDOffset] = v
+		return hexutil.Encode(s)
+	}
+	recID := sig[crypto.RecoveryIDOffset]
+
+	tests := []struct {
+		name      string
+		signature string
+		wantErr   error
+	}{
+		{name: "v 0/1", signature: withV(recID)},
+		{name: "v 27/28", signature: withV(recID + 27)},
+		{name: "v out of range", signature: withV(recID + 2), wantErr: ErrInvalidSignature},
+		{name: "eip-155 style v", signature: withV(recID + 37), wantErr: ErrInvalidSignature},
+		{name: "too long", signature: hexutil.Encode(append(append([]byte(nil), sig...), 0)), wantErr: ErrInvalidSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RecoverAddress(message, tt.signature)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("RecoverAddress err=%v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && got.Hex() != want {
+				t.Fatalf("RecoverAddress=%s, want %s", got.Hex(), want)
+			}
+		})
+	}
+}
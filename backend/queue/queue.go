@@ -1,6 +1,7 @@
 package queue
 
 import (
+	"errors"
 	"expchange-backend/database"
 	"expchange-backend/models"
 	"expchange-backend/services"
@@ -21,6 +22,10 @@ const (
 	TaskGenerateKlines  TaskType = "generate_klines"
 	TaskVerifyDeposit   TaskType = "verify_deposit"
 	TaskProcessWithdraw TaskType = "process_withdraw"
+	TaskSettleReferral  TaskType = "settle_referral"
+	TaskTreasurySweep   TaskType = "treasury_sweep"
+	TaskDepositSweep    TaskType = "deposit_sweep"
+	TaskWalletMonitor   TaskType = "wallet_monitor"
 )
 
 // Task 任务
@@ -38,6 +43,8 @@ type Task struct {
 	StartedAt  *time.Time
 	EndedAt    *time.Time
 	Error      string
+	Attempts   int        // 已重试次数
+	RunAt      *time.Time // 计划执行时间（等待重试），为空表示立即执行
 }
 
 // TaskQueue 任务队列
@@ -50,6 +57,8 @@ type TaskQueue struct {
 	workers           int // 当前运行的worker数
 	depositVerifier   *services.DepositVerifier
 	withdrawProcessor *services.WithdrawProcessor
+	referralService   *services.ReferralService
+	treasuryService   *services.TreasuryService
 }
 
 var (
@@ -79,6 +88,8 @@ func GetQueue() *TaskQueue {
 			workers:           0,
 			depositVerifier:   depositVerifier,
 			withdrawProcessor: withdrawProcessor,
+			referralService:   services.NewReferralService(),
+			treasuryService:   services.NewTreasuryService(),
 		}
 		instance.loadFromDB() // 从数据库加载未完成的任务
 		instance.Start()
@@ -107,11 +118,14 @@ func (q *TaskQueue) loadFromDB() {
 			StartedAt:  dbTask.StartedAt,
 			EndedAt:    dbTask.EndedAt,
 			Error:      dbTask.Error,
+			Attempts:   dbTask.Attempts,
+			RunAt:      dbTask.RunAt,
 		}
 		q.tasks[task.ID] = task
 
-		// 如果是 pending 状态，重新加入队列
-		if task.Status == "pending" {
+		// 如果是 pending 状态，重新加入队列（等待重试的任务到期后由 retryScheduler 加入）
+		if task.Status == "pending" && (task.RunAt == nil || !task.RunAt.After(time.Now())) {
+			task.RunAt = nil
 			q.queue <- task
 		}
 	}
@@ -144,11 +158,38 @@ func (q *TaskQueue) Start() {
 	// 启动专门的充值验证worker（单独进程）
 	go q.depositWorker()
 
+	// 启动到期重试任务调度
+	go q.retryScheduler()
+
+	// 启动链上充值扫描
+	go q.depositScanner()
+
 	// 启动专门的提现处理worker（单独进程）
 	go q.withdrawWorker()
 
+	// 启动提现链上确认跟踪
+	go q.withdrawTracker()
+
+	// 启动批量提现发送
+	go q.withdrawBatcher()
+
+	// 启动热钱包 nonce 对账
+	go q.nonceReconciler()
+
+	// 启动充值地址归集调度
+	go q.depositSweepScheduler()
+
+	// 启动 RPC 节点健康检查
+	go q.rpcHealthChecker()
+
+	// 启动钱包余额监控调度
+	go q.walletMonitorScheduler()
+
 	// 启动worker数量监控协程，支持动态调整
 	go q.monitorWorkerCount()
+
+	// 启动每日调度（邀请返佣结算、国库归集）
+	go q.dailyScheduler()
 }
 
 // Stop 停止任务队列
@@ -195,7 +236,7 @@ func (q *TaskQueue) monitorWorkerCount() {
 	}
 }
 
-// worker 工作协程（只处理数据生成任务）
+// worker 工作协程（处理数据生成、返佣结算、国库归集、充值地址归集和钱包余额监控任务）
 func (q *TaskQueue) worker(id int) {
 	log.Printf("🔧 数据生成 Worker %d 已启动", id)
 
@@ -204,8 +245,10 @@ func (q *TaskQueue) worker(id int) {
 			break
 		}
 
-		// 只处理数据生成类型的任务
-		if task.Type == TaskGenerateTrades || task.Type == TaskGenerateKlines {
+		// 只处理数据生成、返佣结算、国库归集、充值地址归集和钱包余额监控类型的任务
+		if task.Type == TaskGenerateTrades || task.Type == TaskGenerateKlines ||
+			task.Type == TaskSettleReferral || task.Type == TaskTreasurySweep || task.Type == TaskDepositSweep ||
+			task.Type == TaskWalletMonitor {
 			q.processTask(task)
 		} else {
 			// 其他类型的任务重新放回队列，等待专门的worker处理
@@ -246,11 +289,30 @@ func (q *TaskQueue) processTask(task *Task) {
 	case TaskProcessWithdraw:
 		q.logTask(task.ID, "info", "execution_started", "开始处理提现", fmt.Sprintf("提现记录ID: %s", task.RecordID))
 		err = q.executeProcessWithdraw(task)
+	case TaskSettleReferral:
+		q.logTask(task.ID, "info", "execution_started", "开始结算邀请返佣", formatTimeRange(task.StartTime, task.EndTime))
+		err = q.executeSettleReferral(task)
+	case TaskTreasurySweep:
+		q.logTask(task.ID, "info", "execution_started", "开始国库归集", "")
+		err = q.executeTreasurySweep(task)
+	case TaskDepositSweep:
+		q.logTask(task.ID, "info", "execution_started", "开始充值地址归集", "")
+		err = q.executeDepositSweep(task)
+	case TaskWalletMonitor:
+		q.logTask(task.ID, "info", "execution_started", "开始检查钱包余额", "")
+		err = q.executeWalletMonitor(task)
 	default:
 		err = fmt.Errorf("unknown task type: %s", task.Type)
 		q.logTask(task.ID, "error", "execution_error", "未知的任务类型", string(task.Type))
 	}
 
+	// 需要稍后重试：回到 pending，按计划时间重新执行
+	var retry *services.RetryLater
+	if errors.As(err, &retry) {
+		q.scheduleRetry(task, retry)
+		return
+	}
+
 	// 更新任务状态
 	endTime := time.Now()
 	task.EndedAt = &endTime
@@ -383,6 +445,24 @@ func (q *TaskQueue) depositWorker() {
 	log.Printf("💰 充值验证 Worker 已停止")
 }
 
+// depositScanner 定时扫描链上充值（间隔见 deposit.check.interval，支持热更新）
+func (q *TaskQueue) depositScanner() {
+	if q.depositVerifier == nil {
+		return
+	}
+	log.Println("🔎 链上充值扫描已启动")
+
+	for q.running {
+		interval := database.GetSystemConfigManager().GetInt("deposit.check.interval", 30)
+		if interval < 1 {
+			interval = 1
+		}
+		time.Sleep(time.Duration(interval) * time.Second)
+
+		q.depositVerifier.ScanDeposits()
+	}
+}
+
 // withdrawWorker 专门处理提现任务的worker（单独进程，带Nonce管理）
 func (q *TaskQueue) withdrawWorker() {
 	log.Printf("💸 提现处理 Worker 已启动（独立进程，线程安全）")
@@ -449,20 +529,9 @@ func (q *TaskQueue) executeVerifyDeposit(task *Task) error {
 	// 调用充值验证服务（返回error表示需要重试）
 	verifyErr := q.depositVerifier.VerifyDeposit(&deposit)
 
-	// 检查是否需要重试
-	if verifyErr != nil && strings.Contains(verifyErr.Error(), "RETRY_LATER") {
-		q.logTask(task.ID, "info", "retry_scheduled",
-			"交易未确认，10秒后重试",
-			fmt.Sprintf("原因: %s", verifyErr.Error()))
-
-		// 10秒后重新加入队列
-		go func() {
-			time.Sleep(10 * time.Second)
-			q.queue <- task
-			log.Printf("🔄 充值验证任务已重新加入队列: TaskID=%s, RecordID=%s", task.ID, task.RecordID)
-		}()
-
-		return fmt.Errorf("RETRY_SCHEDULED: %w", verifyErr)
+	// 需要重试时由 processTask 按计划重新执行
+	if verifyErr != nil {
+		return verifyErr
 	}
 
 	// 重新加载充值记录，检查验证结果
@@ -517,6 +586,20 @@ func (q *TaskQueue) executeProcessWithdraw(task *Task) error {
 		fmt.Sprintf("Address: %s, Amount: %s %s, Chain: %s",
 			withdrawal.Address, withdrawal.Amount.String(), withdrawal.Asset, withdrawal.Chain))
 
+	// 风控审核中的提现不能发出（审核通过后会重新创建任务）
+	if withdrawal.Status == "pending_review" {
+		q.logTask(task.ID, "error", "withdraw_pending_review", "提现待风控审核，不能处理", "")
+		return fmt.Errorf("withdrawal is pending risk review")
+	}
+
+	// 热钱包余额不足时提现已暂停：保持 pending，稍后重试（余额监控恢复后自动发出）
+	if withdrawal.Status == "pending" {
+		if reason := services.WithdrawPauseReason(withdrawal.ChainID, withdrawal.Asset); reason != "" {
+			q.logTask(task.ID, "info", "withdraw_paused", "该资产提现已暂停，稍后重试", fmt.Sprintf("原因: %s", reason))
+			return &services.RetryLater{Reason: "withdrawals paused: " + reason}
+		}
+	}
+
 	// 调用提现处理服务
 	if q.withdrawProcessor == nil {
 		q.logTask(task.ID, "error", "service_unavailable", "提现处理服务未初始化", "")
@@ -537,12 +620,17 @@ func (q *TaskQueue) executeProcessWithdraw(task *Task) error {
 		return fmt.Errorf("failed to reload withdrawal record: %w", err)
 	}
 
-	if withdrawal.Status == "completed" {
+	if withdrawal.Status == "broadcast" || withdrawal.Status == "completed" {
 		q.logTask(task.ID, "info", "withdraw_processing_completed",
-			"提现处理成功",
+			"提现交易已广播，等待链上确认",
 			fmt.Sprintf("TxHash: %s, Amount: %s %s",
 				withdrawal.TxHash, withdrawal.Amount.String(), withdrawal.Asset))
 		return nil
+	} else if withdrawal.Status == "batching" {
+		q.logTask(task.ID, "info", "withdraw_batching",
+			"提现已进入批量队列，等待批量发送",
+			fmt.Sprintf("Amount: %s %s", withdrawal.Amount.String(), withdrawal.Asset))
+		return nil
 	} else if withdrawal.Status == "failed" {
 		q.logTask(task.ID, "error", "withdraw_processing_failed",
 			"提现处理失败",
@@ -553,6 +641,475 @@ func (q *TaskQueue) executeProcessWithdraw(task *Task) error {
 	return nil
 }
 
+// withdrawTracker 定时检查已广播提现的链上确认状态（间隔见 withdraw.tracker.interval，支持热更新）
+func (q *TaskQueue) withdrawTracker() {
+	if q.withdrawProcessor == nil {
+		return
+	}
+	log.Println("🔍 提现确认跟踪已启动")
+
+	for q.running {
+		interval := database.GetSystemConfigManager().GetInt("withdraw.tracker.interval", 15)
+		if interval < 1 {
+			interval = 1
+		}
+		time.Sleep(time.Duration(interval) * time.Second)
+
+		q.withdrawProcessor.CheckBroadcastWithdrawals()
+	}
+}
+
+// withdrawBatcher 定时发送批量队列中的提现（收集窗口和批量大小见 withdraw.batch.*）
+func (q *TaskQueue) withdrawBatcher() {
+	if q.withdrawProcessor == nil {
+		return
+	}
+	log.Println("📦 批量提现发送已启动")
+
+	for q.running {
+		time.Sleep(5 * time.Second)
+
+		q.withdrawProcessor.SendWithdrawBatches()
+	}
+}
+
+// nonceReconciler 定时与链上核对热钱包 nonce（间隔见 withdraw.nonce.reconcile_interval，支持热更新）
+func (q *TaskQueue) nonceReconciler() {
+	if q.withdrawProcessor == nil {
+		return
+	}
+	log.Println("🔢 nonce 对账已启动")
+
+	for q.running {
+		interval := database.GetSystemConfigManager().GetInt("withdraw.nonce.reconcile_interval", 60)
+		if interval < 10 {
+			interval = 10
+		}
+		time.Sleep(time.Duration(interval) * time.Second)
+
+		q.withdrawProcessor.ReconcileNonces()
+	}
+}
+
+// rpcHealthChecker 定时检查各链 RPC 节点的可用性和区块高度（间隔见 rpc.health.interval_seconds，支持热更新）
+func (q *TaskQueue) rpcHealthChecker() {
+	log.Println("🩺 RPC 节点健康检查已启动")
+
+	for q.running {
+		services.CheckRpcHealth()
+
+		interval := database.GetSystemConfigManager().GetInt("rpc.health.interval_seconds", 30)
+		if interval < 5 {
+			interval = 5
+		}
+		time.Sleep(time.Duration(interval) * time.Second)
+	}
+}
+
+// depositSweepScheduler 定时创建充值地址归集任务（deposit.sweep.enabled 开启时，间隔见 deposit.sweep.interval_minutes）
+// 补充 gas、转出代币和确认分别在连续的几次任务中推进
+func (q *TaskQueue) depositSweepScheduler() {
+	if q.withdrawProcessor == nil {
+		return
+	}
+
+	for q.running {
+		interval := database.GetSystemConfigManager().GetInt("deposit.sweep.interval_minutes", 10)
+		if interval < 1 {
+			interval = 1
+		}
+		time.Sleep(time.Duration(interval) * time.Minute)
+
+		if !database.GetSystemConfigManager().GetBool("deposit.sweep.enabled", false) {
+			continue
+		}
+		if _, err := q.AddDepositSweepTask("scheduler"); err != nil {
+			if _, ok := err.(*TaskError); !ok {
+				log.Printf("❌ 创建充值地址归集任务失败: %v", err)
+			}
+		}
+	}
+}
+
+// walletMonitorScheduler 定时创建钱包余额监控任务（monitor.wallet.enabled 开启时，间隔见 monitor.wallet.interval_minutes）
+func (q *TaskQueue) walletMonitorScheduler() {
+	if q.withdrawProcessor == nil {
+		return
+	}
+
+	for q.running {
+		interval := database.GetSystemConfigManager().GetInt("monitor.wallet.interval_minutes", 5)
+		if interval < 1 {
+			interval = 1
+		}
+		time.Sleep(time.Duration(interval) * time.Minute)
+
+		if !database.GetSystemConfigManager().GetBool("monitor.wallet.enabled", true) {
+			continue
+		}
+		if _, err := q.AddWalletMonitorTask("scheduler"); err != nil {
+			if _, ok := err.(*TaskError); !ok {
+				log.Printf("❌ 创建钱包余额监控任务失败: %v", err)
+			}
+		}
+	}
+}
+
+// SpeedUpWithdrawal 手动加速未确认的提现（相同 nonce 提高 gas price 重新广播）
+func (q *TaskQueue) SpeedUpWithdrawal(withdrawID string) (*models.WithdrawRecord, error) {
+	if q.withdrawProcessor == nil {
+		return nil, fmt.Errorf("withdraw processor not available")
+	}
+	return q.withdrawProcessor.SpeedUpWithdrawal(withdrawID)
+}
+
+// executeSettleReferral 执行邀请返佣结算
+func (q *TaskQueue) executeSettleReferral(task *Task) error {
+	defer func() {
+		if r := recover(); r != nil {
+			errMsg := fmt.Sprintf("邀请返佣结算 panic: %v", r)
+			q.logTask(task.ID, "error", "panic_recovered", errMsg, "")
+			log.Printf("❌ %s", errMsg)
+		}
+	}()
+
+	if task.StartTime == nil || task.EndTime == nil {
+		q.logTask(task.ID, "error", "validation_failed", "结算时间范围为空", "")
+		return fmt.Errorf("time range is required for referral settlement")
+	}
+
+	settled, totals, err := q.referralService.SettleRange(*task.StartTime, *task.EndTime)
+	if err != nil {
+		q.logTask(task.ID, "error", "settlement_failed",
+			fmt.Sprintf("邀请返佣结算失败: %v", err),
+			fmt.Sprintf("已结算: %d 笔", settled))
+		return err
+	}
+
+	details := make([]string, 0, len(totals))
+	for asset, amount := range totals {
+		details = append(details, fmt.Sprintf("%s: %s", asset, amount.String()))
+	}
+	q.logTask(task.ID, "info", "settlement_completed",
+		fmt.Sprintf("邀请返佣结算完成，共 %d 笔", settled),
+		strings.Join(details, ", "))
+
+	return nil
+}
+
+// dailyScheduler 每小时检查一次：为前一天创建返佣结算任务、每天执行一次国库归集（每天只创建一次）
+func (q *TaskQueue) dailyScheduler() {
+	ticker := time.NewTicker(1 * time.Hour)
+	defer ticker.Stop()
+
+	for {
+		if !q.running {
+			return
+		}
+
+		now := time.Now()
+		today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+		if q.referralService.IsEnabled() {
+			if _, err := q.AddReferralSettlementTask(today.AddDate(0, 0, -1), today); err != nil {
+				if _, ok := err.(*TaskError); !ok {
+					log.Printf("❌ 创建邀请返佣结算任务失败: %v", err)
+				}
+			}
+		}
+
+		// 返佣结算先于归集执行，避免把待支付的返佣归集走
+		if q.treasuryService.IsSweepEnabled() && now.Hour() >= 1 {
+			var count int64
+			database.DB.Model(&models.Task{}).
+				Where("type = ? AND created_at >= ?", string(TaskTreasurySweep), today).
+				Count(&count)
+			if count == 0 {
+				if _, err := q.AddTreasurySweepTask("scheduler"); err != nil {
+					if _, ok := err.(*TaskError); !ok {
+						log.Printf("❌ 创建国库归集任务失败: %v", err)
+					}
+				}
+			}
+		}
+
+		<-ticker.C
+	}
+}
+
+// AddReferralSettlementTask 添加邀请返佣结算任务（同一时间范围只会创建一次）
+func (q *TaskQueue) AddReferralSettlementTask(start, end time.Time) (*Task, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var count int64
+	database.DB.Model(&models.Task{}).
+		Where("type = ? AND start_time = ? AND end_time = ? AND status IN ?",
+			string(TaskSettleReferral), start, end, []string{"pending", "running", "completed"}).
+		Count(&count)
+	if count > 0 {
+		return nil, &TaskError{Message: "Referral settlement for this period already exists"}
+	}
+
+	task := &Task{
+		ID:         generateTaskID(),
+		Type:       TaskSettleReferral,
+		RecordType: "referral",
+		StartTime:  &start,
+		EndTime:    &end,
+		Status:     "pending",
+		Message:    "等待结算邀请返佣",
+		CreatedAt:  time.Now(),
+	}
+
+	q.tasks[task.ID] = task
+
+	dbTask := q.taskToModel(task)
+	if err := database.DB.Create(&dbTask).Error; err != nil {
+		log.Printf("❌ 保存返佣结算任务到数据库失败: %v", err)
+		delete(q.tasks, task.ID)
+		return nil, fmt.Errorf("failed to save referral settlement task to database: %w", err)
+	}
+
+	q.queue <- task
+
+	log.Printf("📝 邀请返佣结算任务已添加到队列: %s (TaskID: %s)", formatTimeRange(&start, &end), task.ID)
+	q.logTask(task.ID, "info", "task_created", "邀请返佣结算任务已创建", formatTimeRange(&start, &end))
+
+	return task, nil
+}
+
+// executeTreasurySweep 执行国库归集
+func (q *TaskQueue) executeTreasurySweep(task *Task) error {
+	defer func() {
+		if r := recover(); r != nil {
+			errMsg := fmt.Sprintf("国库归集 panic: %v", r)
+			q.logTask(task.ID, "error", "panic_recovered", errMsg, "")
+			log.Printf("❌ %s", errMsg)
+		}
+	}()
+
+	sweeps, err := q.treasuryService.Sweep(task.ID)
+	if err != nil {
+		q.logTask(task.ID, "error", "sweep_failed", fmt.Sprintf("国库归集失败: %v", err), "")
+		return err
+	}
+
+	failed := 0
+	for _, sweep := range sweeps {
+		if sweep.Status != "completed" {
+			failed++
+			q.logTask(task.ID, "error", "asset_sweep_failed",
+				fmt.Sprintf("%s 归集失败", sweep.Asset),
+				fmt.Sprintf("原因: %s", sweep.Reason))
+			continue
+		}
+		q.logTask(task.ID, "info", "asset_swept",
+			fmt.Sprintf("%s 归集成功", sweep.Asset),
+			fmt.Sprintf("Amount: %s, To: %s", sweep.Amount.String(), sweep.ToAddress))
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d assets failed to sweep", failed, len(sweeps))
+	}
+	return nil
+}
+
+// AddTreasurySweepTask 添加国库归集任务（同一时间只允许一个归集任务）
+func (q *TaskQueue) AddTreasurySweepTask(triggeredBy string) (*Task, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, t := range q.tasks {
+		if t.Type == TaskTreasurySweep && (t.Status == "pending" || t.Status == "running") {
+			return nil, &TaskError{Message: "Treasury sweep is already running or pending"}
+		}
+	}
+
+	task := &Task{
+		ID:         generateTaskID(),
+		Type:       TaskTreasurySweep,
+		RecordType: "treasury",
+		Status:     "pending",
+		Message:    "等待国库归集",
+		CreatedAt:  time.Now(),
+	}
+
+	q.tasks[task.ID] = task
+
+	dbTask := q.taskToModel(task)
+	if err := database.DB.Create(&dbTask).Error; err != nil {
+		log.Printf("❌ 保存国库归集任务到数据库失败: %v", err)
+		delete(q.tasks, task.ID)
+		return nil, fmt.Errorf("failed to save treasury sweep task to database: %w", err)
+	}
+
+	q.queue <- task
+
+	log.Printf("📝 国库归集任务已添加到队列: TaskID=%s, 触发者=%s", task.ID, triggeredBy)
+	q.logTask(task.ID, "info", "task_created", "国库归集任务已创建", fmt.Sprintf("触发者: %s", triggeredBy))
+
+	return task, nil
+}
+
+// executeDepositSweep 执行充值地址归集
+func (q *TaskQueue) executeDepositSweep(task *Task) error {
+	defer func() {
+		if r := recover(); r != nil {
+			errMsg := fmt.Sprintf("充值地址归集 panic: %v", r)
+			q.logTask(task.ID, "error", "panic_recovered", errMsg, "")
+			log.Printf("❌ %s", errMsg)
+		}
+	}()
+
+	if q.withdrawProcessor == nil {
+		return fmt.Errorf("withdraw processor not available")
+	}
+
+	sweeps, err := q.withdrawProcessor.SweepDepositAddresses(task.ID)
+	failed := 0
+	for _, sweep := range sweeps {
+		detail := fmt.Sprintf("Chain: %s, Address: %s, Amount: %s %s", sweep.Chain, sweep.DepositAddress, sweep.Amount.String(), sweep.Asset)
+		switch sweep.Status {
+		case "failed":
+			failed++
+			q.logTask(task.ID, "error", "address_sweep_failed", "充值地址归集失败", fmt.Sprintf("%s, 原因: %s", detail, sweep.Reason))
+		case "gas_pending":
+			q.logTask(task.ID, "info", "address_gas_topped_up", "已补充归集 gas", fmt.Sprintf("%s, GasTxHash: %s", detail, sweep.GasTxHash))
+		case "broadcast":
+			q.logTask(task.ID, "info", "address_sweep_broadcast", "归集交易已广播", fmt.Sprintf("%s, TxHash: %s", detail, sweep.TxHash))
+		case "completed":
+			q.logTask(task.ID, "info", "address_swept", "充值地址归集完成", fmt.Sprintf("%s, TxHash: %s", detail, sweep.TxHash))
+		}
+	}
+
+	if err != nil {
+		q.logTask(task.ID, "error", "sweep_failed", fmt.Sprintf("充值地址归集失败: %v", err), "")
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d deposit address sweeps failed", failed, len(sweeps))
+	}
+	return nil
+}
+
+// AddDepositSweepTask 添加充值地址归集任务（同一时间只允许一个归集任务）
+func (q *TaskQueue) AddDepositSweepTask(triggeredBy string) (*Task, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, t := range q.tasks {
+		if t.Type == TaskDepositSweep && (t.Status == "pending" || t.Status == "running") {
+			return nil, &TaskError{Message: "Deposit sweep is already running or pending"}
+		}
+	}
+
+	task := &Task{
+		ID:         generateTaskID(),
+		Type:       TaskDepositSweep,
+		RecordType: "deposit_sweep",
+		Status:     "pending",
+		Message:    "等待充值地址归集",
+		CreatedAt:  time.Now(),
+	}
+
+	q.tasks[task.ID] = task
+
+	dbTask := q.taskToModel(task)
+	if err := database.DB.Create(&dbTask).Error; err != nil {
+		log.Printf("❌ 保存充值地址归集任务到数据库失败: %v", err)
+		delete(q.tasks, task.ID)
+		return nil, fmt.Errorf("failed to save deposit sweep task to database: %w", err)
+	}
+
+	q.queue <- task
+
+	log.Printf("📝 充值地址归集任务已添加到队列: TaskID=%s, 触发者=%s", task.ID, triggeredBy)
+	q.logTask(task.ID, "info", "task_created", "充值地址归集任务已创建", fmt.Sprintf("触发者: %s", triggeredBy))
+
+	return task, nil
+}
+
+// executeWalletMonitor 执行钱包余额检查
+func (q *TaskQueue) executeWalletMonitor(task *Task) error {
+	defer func() {
+		if r := recover(); r != nil {
+			errMsg := fmt.Sprintf("钱包余额监控 panic: %v", r)
+			q.logTask(task.ID, "error", "panic_recovered", errMsg, "")
+			log.Printf("❌ %s", errMsg)
+		}
+	}()
+
+	if q.withdrawProcessor == nil {
+		return fmt.Errorf("withdraw processor not available")
+	}
+
+	result, err := q.withdrawProcessor.CheckWalletBalances()
+	if err != nil {
+		q.logTask(task.ID, "error", "monitor_failed", fmt.Sprintf("钱包余额检查失败: %v", err), "")
+		return err
+	}
+
+	failed := 0
+	for _, snapshot := range result.Snapshots {
+		detail := fmt.Sprintf("Chain: %s, Asset: %s, Hot: %s, Cold: %s, Pending: %s",
+			snapshot.Chain, snapshot.Asset, snapshot.HotBalance.String(), snapshot.ColdBalance.String(), snapshot.PendingWithdrawals.String())
+		switch snapshot.Status {
+		case "error":
+			failed++
+			q.logTask(task.ID, "error", "balance_read_failed", "钱包余额读取失败", fmt.Sprintf("%s, 原因: %s", detail, snapshot.Message))
+		case "paused":
+			q.logTask(task.ID, "warning", "withdraw_paused", "热钱包余额低于暂停值，提现已暂停", fmt.Sprintf("%s, 原因: %s", detail, snapshot.Message))
+		case "low":
+			q.logTask(task.ID, "warning", "balance_low", "热钱包余额不足", fmt.Sprintf("%s, 原因: %s", detail, snapshot.Message))
+		default:
+			q.logTask(task.ID, "info", "balance_ok", "钱包余额正常", detail)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d wallet balances could not be read", failed, len(result.Snapshots))
+	}
+	return nil
+}
+
+// AddWalletMonitorTask 添加钱包余额监控任务（同一时间只允许一个监控任务）
+func (q *TaskQueue) AddWalletMonitorTask(triggeredBy string) (*Task, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, t := range q.tasks {
+		if t.Type == TaskWalletMonitor && (t.Status == "pending" || t.Status == "running") {
+			return nil, &TaskError{Message: "Wallet monitor is already running or pending"}
+		}
+	}
+
+	task := &Task{
+		ID:         generateTaskID(),
+		Type:       TaskWalletMonitor,
+		RecordType: "wallet_monitor",
+		Status:     "pending",
+		Message:    "等待钱包余额检查",
+		CreatedAt:  time.Now(),
+	}
+
+	q.tasks[task.ID] = task
+
+	dbTask := q.taskToModel(task)
+	if err := database.DB.Create(&dbTask).Error; err != nil {
+		log.Printf("❌ 保存钱包余额监控任务到数据库失败: %v", err)
+		delete(q.tasks, task.ID)
+		return nil, fmt.Errorf("failed to save wallet monitor task to database: %w", err)
+	}
+
+	q.queue <- task
+
+	log.Printf("📝 钱包余额监控任务已添加到队列: TaskID=%s, 触发者=%s", task.ID, triggeredBy)
+	q.logTask(task.ID, "info", "task_created", "钱包余额监控任务已创建", fmt.Sprintf("触发者: %s", triggeredBy))
+
+	return task, nil
+}
+
 // AddTask 添加任务到队列
 func (q *TaskQueue) AddTask(taskType TaskType, symbol string, startTime, endTime *time.Time) (*Task, error) {
 	q.mu.Lock()
@@ -728,6 +1285,8 @@ func (q *TaskQueue) GetAllTasks() []*Task {
 			StartedAt:  dbTask.StartedAt,
 			EndedAt:    dbTask.EndedAt,
 			Error:      dbTask.Error,
+			Attempts:   dbTask.Attempts,
+			RunAt:      dbTask.RunAt,
 		}
 		tasks = append(tasks, task)
 	}
@@ -788,6 +1347,8 @@ func (q *TaskQueue) RetryTask(taskID string) error {
 		"error":      "",
 		"started_at": nil,
 		"ended_at":   nil,
+		"attempts":   0,
+		"run_at":     nil,
 	}).Error; err != nil {
 		delete(q.tasks, task.ID)
 		return fmt.Errorf("failed to update task in database: %w", err)
@@ -834,7 +1395,75 @@ func (q *TaskQueue) taskToModel(task *Task) models.Task {
 		StartedAt:  task.StartedAt,
 		EndedAt:    task.EndedAt,
 		Error:      task.Error,
+		Attempts:   task.Attempts,
+		RunAt:      task.RunAt,
+	}
+}
+
+// scheduleRetry 任务需要稍后重试：回到 pending 并设置下次执行时间（到期后由 retryScheduler 重新加入队列）
+func (q *TaskQueue) scheduleRetry(task *Task, retry *services.RetryLater) {
+	task.Attempts++
+	delay := retry.After
+	if delay <= 0 {
+		delay = retryBackoff(task.Attempts)
+	}
+	runAt := time.Now().Add(delay)
+	task.Status = "pending"
+	task.RunAt = &runAt
+	task.Error = ""
+	task.Message = fmt.Sprintf("等待重试（第%d次）: %s", task.Attempts, retry.Reason)
+	q.updateTask(task)
+
+	q.logTask(task.ID, "info", "retry_scheduled",
+		fmt.Sprintf("%s后重试", delay),
+		fmt.Sprintf("原因: %s", retry.Reason))
+	log.Printf("⏳ 任务将在 %s 后重试: %s (ID: %s), 原因: %s", delay, task.Type, task.ID, retry.Reason)
+}
+
+// retryBackoff 按重试次数指数退避（task.queue.retry_base_seconds 起每次翻倍，最长 task.queue.retry_max_seconds）
+func retryBackoff(attempts int) time.Duration {
+	sysConfig := database.GetSystemConfigManager()
+	delay := time.Duration(max(sysConfig.GetInt("task.queue.retry_base_seconds", 10), 1)) * time.Second
+	maxDelay := time.Duration(max(sysConfig.GetInt("task.queue.retry_max_seconds", 300), 1)) * time.Second
+	for i := 1; i < attempts && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxDelay)
+}
+
+// retryScheduler 每秒把到期的待重试任务重新加入队列
+func (q *TaskQueue) retryScheduler() {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for q.running {
+		<-ticker.C
+
+		now := time.Now()
+		var due []*Task
+		q.mu.Lock()
+		for _, task := range q.tasks {
+			if task.Status == "pending" && task.RunAt != nil && !task.RunAt.After(now) {
+				task.RunAt = nil
+				due = append(due, task)
+			}
+		}
+		q.mu.Unlock()
+
+		// 在锁外入队，避免队列已满时阻塞其他任务更新
+		for _, task := range due {
+			q.queue <- task
+			log.Printf("🔄 任务已重新加入队列: %s (ID: %s, 第%d次重试)", task.Type, task.ID, task.Attempts)
+		}
+	}
+}
+
+// formatTimeRange 格式化任务时间范围
+func formatTimeRange(start, end *time.Time) string {
+	if start == nil || end == nil {
+		return ""
 	}
+	return fmt.Sprintf("时间范围: %s ~ %s", start.Format("2006-01-02 15:04"), end.Format("2006-01-02 15:04"))
 }
 
 // generateTaskID 生成任务ID
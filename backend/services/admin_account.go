@@ -0,0 +1,364 @@
+package services
+
+import (
+	"errors"
+	"expchange-backend/database"
+	"expchange-backend/models"
+	"expchange-backend/pkg/secretbox"
+	"expchange-backend/pkg/totp"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// 管理员角色
+const (
+	AdminRoleViewer     = "viewer"     // 只读
+	AdminRoleOperator   = "operator"   // 运营：交易对、行情数据、任务、用户
+	AdminRoleFinance    = "finance"    // 财务：手续费、国库、返佣、充提
+	AdminRoleSuperAdmin = "superadmin" // 超级管理员：全部权限
+)
+
+// 管理后台权限
+const (
+	AdminPermView    = "view"           // 查看所有数据
+	AdminPermMarket  = "market.manage"  // 交易对、K线、数据生成任务
+	AdminPermUser    = "user.manage"    // 用户等级、返佣比例、会话
+	AdminPermFinance = "finance.manage" // 手续费、国库归集、返佣结算
+	AdminPermSystem  = "system.manage"  // 系统配置、链配置（含私钥）
+	AdminPermAdmin   = "admin.manage"   // 管理员账户
+	AdminPermAudit   = "audit.view"     // 审计日志
+)
+
+var adminRolePermissions = map[string][]string{
+	AdminRoleViewer:     {AdminPermView},
+	AdminRoleOperator:   {AdminPermView, AdminPermMarket, AdminPermUser},
+	AdminRoleFinance:    {AdminPermView, AdminPermFinance},
+	AdminRoleSuperAdmin: {AdminPermView, AdminPermMarket, AdminPermUser, AdminPermFinance, AdminPermSystem, AdminPermAdmin, AdminPermAudit},
+}
+
+const (
+	adminMaxFailedLogins = 5
+	adminLockDuration    = 15 * time.Minute
+	adminMinPasswordLen  = 10
+	adminTOTPIssuer      = "Velocity Exchange Admin"
+)
+
+// dummyPasswordHash 用户名不存在时用于比对的哈希
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("dummy-admin-password"), bcrypt.DefaultCost)
+
+var (
+	ErrAdminInvalidCredentials = errors.New("invalid username or password, or account temporarily locked")
+	ErrAdminLocked             = errors.New("account is locked, please try again later")
+	ErrAdminDisabled           = errors.New("account is disabled")
+	ErrAdminTOTPRequired       = errors.New("totp code required")
+	ErrAdminTOTPInvalid        = errors.New("invalid totp code")
+)
+
+// IsValidAdminRole 角色是否有效
+func IsValidAdminRole(role string) bool {
+	_, ok := adminRolePermissions[role]
+	return ok
+}
+
+// AdminRoleHasPermission 角色是否拥有权限
+func AdminRoleHasPermission(role, permission string) bool {
+	for _, p := range adminRolePermissions[role] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// GetAdminRolePermissions 角色的权限列表
+func GetAdminRolePermissions(role string) []string {
+	return adminRolePermissions[role]
+}
+
+// AdminAccountService 管理员账户：密码 + TOTP 登录、账户管理
+type AdminAccountService struct {
+	box *secretbox.Box
+}
+
+func NewAdminAccountService(encryptionKey string) *AdminAccountService {
+	box, err := secretbox.New(encryptionKey)
+	if err != nil {
+		panic(fmt.Sprintf("failed to init admin encryption: %v", err))
+	}
+	return &AdminAccountService{box: box}
+}
+
+// EnsureBootstrapAdmin 没有任何管理员时创建初始超级管理员
+func (s *AdminAccountService) EnsureBootstrapAdmin(username, password string) error {
+	var count int64
+	database.DB.Model(&models.AdminUser{}).Count(&count)
+	if count > 0 {
+		return nil
+	}
+
+	generated := password == ""
+	if generated {
+		random, err := randomHex(12)
+		if err != nil {
+			return err
+		}
+		password = random
+	}
+
+	if _, err := s.Create(username, password, AdminRoleSuperAdmin); err != nil {
+		return err
+	}
+
+	if generated {
+		log.Printf("🔑 已创建初始超级管理员: %s / %s （请登录后立即绑定 TOTP 并修改密码）", username, password)
+	} else {
+		log.Printf("🔑 已创建初始超级管理员: %s", username)
+	}
+	return nil
+}
+
+// Create 创建管理员
+func (s *AdminAccountService) Create(username, password, role string) (*models.AdminUser, error) {
+	username = strings.TrimSpace(username)
+	if username == "" {
+		return nil, errors.New("username is required")
+	}
+	if !IsValidAdminRole(role) {
+		return nil, fmt.Errorf("invalid role: %s", role)
+	}
+
+	hash, err := hashAdminPassword(password)
+	if err != nil {
+		return nil, err
+	}
+
+	admin := &models.AdminUser{
+		Username:     username,
+		PasswordHash: hash,
+		Role:         role,
+		TokenVersion: 1,
+	}
+	if err := database.DB.Create(admin).Error; err != nil {
+		return nil, errors.New("username already exists")
+	}
+	return admin, nil
+}
+
+// Authenticate 校验用户名、密码和 TOTP（已绑定 TOTP 的账户必须提供验证码）
+func (s *AdminAccountService) Authenticate(username, password, code, ip string) (*models.AdminUser, error) {
+	var admin models.AdminUser
+	if err := database.DB.Where("username = ?", strings.TrimSpace(username)).First(&admin).Error; err != nil {
+		// 用户不存在时同样执行一次 bcrypt，避免通过响应时间枚举用户名
+		bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
+		return nil, ErrAdminInvalidCredentials
+	}
+
+	// 锁定期间不校验密码（否则锁定期内仍可继续猜测），调用方应按密码错误回复客户端
+	if admin.LockedUntil != nil && time.Now().Before(*admin.LockedUntil) {
+		bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
+		return nil, ErrAdminLocked
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(admin.PasswordHash), []byte(password)) != nil {
+		s.recordFailedLogin(&admin)
+		return nil, ErrAdminInvalidCredentials
+	}
+
+	// 停用状态只告知知道密码的人，避免按用户名探测账户状态
+	if admin.Disabled {
+		return nil, ErrAdminDisabled
+	}
+
+	if admin.TOTPEnabled {
+		if strings.TrimSpace(code) == "" {
+			return nil, ErrAdminTOTPRequired
+		}
+		if err := s.verifyTOTP(&admin, code); err != nil {
+			s.recordFailedLogin(&admin)
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	database.DB.Model(&admin).Updates(map[string]interface{}{
+		"failed_login_count": 0,
+		"locked_until":       nil,
+		"last_login_at":      now,
+		"last_login_ip":      ip,
+	})
+	admin.LastLoginAt = &now
+	admin.LastLoginIP = ip
+
+	return &admin, nil
+}
+
+// SetupTOTP 生成新的 TOTP 密钥（需调用 EnableTOTP 验证后才生效）
+func (s *AdminAccountService) SetupTOTP(admin *models.AdminUser) (string, string, error) {
+	if admin.TOTPEnabled {
+		return "", "", errors.New("totp is already enabled")
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return "", "", err
+	}
+	encrypted, err := s.box.Seal(secret)
+	if err != nil {
+		return "", "", err
+	}
+	if err := database.DB.Model(admin).Update("totp_secret", encrypted).Error; err != nil {
+		return "", "", err
+	}
+	admin.TOTPSecret = encrypted
+
+	return secret, totp.URL(adminTOTPIssuer, admin.Username, secret), nil
+}
+
+// EnableTOTP 验证第一个验证码后启用 TOTP，并使之前签发的（未完成 TOTP 的）令牌失效
+func (s *AdminAccountService) EnableTOTP(admin *models.AdminUser, code string) error {
+	if admin.TOTPEnabled {
+		return errors.New("totp is already enabled")
+	}
+	if admin.TOTPSecret == "" {
+		return errors.New("please setup totp first")
+	}
+	if err := s.verifyTOTP(admin, code); err != nil {
+		return err
+	}
+
+	return database.DB.Model(admin).Updates(map[string]interface{}{
+		"totp_enabled":  true,
+		"token_version": gorm.Expr("token_version + 1"),
+	}).Error
+}
+
+// ChangePassword 修改密码（所有登录令牌失效）
+func (s *AdminAccountService) ChangePassword(admin *models.AdminUser, oldPassword, newPassword string) error {
+	if bcrypt.CompareHashAndPassword([]byte(admin.PasswordHash), []byte(oldPassword)) != nil {
+		return ErrAdminInvalidCredentials
+	}
+	return s.ResetPassword(admin.ID, newPassword)
+}
+
+// ResetPassword 重置密码（所有登录令牌失效）
+func (s *AdminAccountService) ResetPassword(adminID, newPassword string) error {
+	hash, err := hashAdminPassword(newPassword)
+	if err != nil {
+		return err
+	}
+	return database.DB.Model(&models.AdminUser{}).Where("id = ?", adminID).Updates(map[string]interface{}{
+		"password_hash":      hash,
+		"failed_login_count": 0,
+		"locked_until":       nil,
+		"token_version":      gorm.Expr("token_version + 1"),
+	}).Error
+}
+
+// ResetTOTP 清除 TOTP 绑定（管理员丢失设备时由超级管理员操作）
+func (s *AdminAccountService) ResetTOTP(adminID string) error {
+	return database.DB.Model(&models.AdminUser{}).Where("id = ?", adminID).Updates(map[string]interface{}{
+		"totp_secret":    "",
+		"totp_enabled":   false,
+		"last_totp_step": 0,
+		"token_version":  gorm.Expr("token_version + 1"),
+	}).Error
+}
+
+// Update 修改角色或禁用状态
+func (s *AdminAccountService) Update(adminID string, role *string, disabled *bool) (*models.AdminUser, error) {
+	var admin models.AdminUser
+	if err := database.DB.Where("id = ?", adminID).First(&admin).Error; err != nil {
+		return nil, errors.New("admin not found")
+	}
+
+	updates := map[string]interface{}{}
+	if role != nil {
+		if !IsValidAdminRole(*role) {
+			return nil, fmt.Errorf("invalid role: %s", *role)
+		}
+		updates["role"] = *role
+	}
+	if disabled != nil {
+		updates["disabled"] = *disabled
+	}
+	if len(updates) == 0 {
+		return &admin, nil
+	}
+
+	// 降级或禁用超级管理员时，至少保留一个可用的超级管理员
+	if admin.Role == AdminRoleSuperAdmin && ((role != nil && *role != AdminRoleSuperAdmin) || (disabled != nil && *disabled)) {
+		var count int64
+		database.DB.Model(&models.AdminUser{}).
+			Where("role = ? AND disabled = ? AND id <> ?", AdminRoleSuperAdmin, false, admin.ID).
+			Count(&count)
+		if count == 0 {
+			return nil, errors.New("at least one active superadmin is required")
+		}
+	}
+
+	// 权限变更后旧令牌立即失效
+	updates["token_version"] = gorm.Expr("token_version + 1")
+	if err := database.DB.Model(&admin).Updates(updates).Error; err != nil {
+		return nil, err
+	}
+
+	database.DB.Where("id = ?", admin.ID).First(&admin)
+	return &admin, nil
+}
+
+// Logout 使该管理员所有已签发的令牌失效
+func (s *AdminAccountService) Logout(adminID string) error {
+	return database.DB.Model(&models.AdminUser{}).Where("id = ?", adminID).
+		Update("token_version", gorm.Expr("token_version + 1")).Error
+}
+
+// verifyTOTP 校验验证码，同一时间步的验证码只能使用一次
+func (s *AdminAccountService) verifyTOTP(admin *models.AdminUser, code string) error {
+	secret, err := s.box.Open(admin.TOTPSecret)
+	if err != nil {
+		return err
+	}
+
+	step, ok := totp.Validate(secret, code, time.Now())
+	if !ok || step <= admin.LastTOTPStep {
+		return ErrAdminTOTPInvalid
+	}
+
+	result := database.DB.Model(&models.AdminUser{}).
+		Where("id = ? AND last_totp_step < ?", admin.ID, step).
+		Update("last_totp_step", step)
+	if result.Error != nil || result.RowsAffected != 1 {
+		return ErrAdminTOTPInvalid
+	}
+	admin.LastTOTPStep = step
+	return nil
+}
+
+// recordFailedLogin 记录登录失败，连续失败超过上限后锁定账户
+func (s *AdminAccountService) recordFailedLogin(admin *models.AdminUser) {
+	updates := map[string]interface{}{
+		"failed_login_count": gorm.Expr("failed_login_count + 1"),
+	}
+	if admin.FailedLoginCount+1 >= adminMaxFailedLogins {
+		updates["locked_until"] = time.Now().Add(adminLockDuration)
+		updates["failed_login_count"] = 0
+		log.Printf("🔒 管理员登录失败次数过多，账户已锁定: %s", admin.Username)
+	}
+	database.DB.Model(admin).Updates(updates)
+}
+
+func hashAdminPassword(password string) (string, error) {
+	if len(password) < adminMinPasswordLen {
+		return "", fmt.Errorf("password must be at least %d characters", adminMinPasswordLen)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
@@ -0,0 +1,59 @@
+package services
+
+import (
+	"errors"
+	"expchange-backend/database"
+	"expchange-backend/models"
+	"testing"
+	"time"
+)
+
+func TestAdminAuthenticateChecksPasswordBeforeStatus(t *testing.T) {
+	const password = "correct-horse-battery"
+	future := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name     string
+		admin    *models.AdminUser
+		username string
+		password string
+		wantErr  error
+	}{
+		{name: "valid", admin: &models.AdminUser{}, password: password},
+		{name: "unknown user", username: "nobody", password: password, wantErr: ErrAdminInvalidCredentials},
+		{name: "wrong password", admin: &models.AdminUser{}, password: "wrong-password", wantErr: ErrAdminInvalidCredentials},
+		{name: "disabled with wrong password", admin: &models.AdminUser{Disabled: true}, password: "wrong-password", wantErr: ErrAdminInvalidCredentials},
+		{name: "disabled with correct password", admin: &models.AdminUser{Disabled: true}, password: password, wantErr: ErrAdminDisabled},
+		{name: "locked with wrong password", admin: &models.AdminUser{LockedUntil: &future}, password: "wrong-password", wantErr: ErrAdminLocked},
+		{name: "locked with correct password", admin: &models.AdminUser{LockedUntil: &future}, password: password, wantErr: ErrAdminLocked},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newTestDB(t, &models.AdminUser{})
+			s := NewAdminAccountService("test-encryption-key")
+
+			username := tt.username
+			if tt.admin != nil {
+				hash, err := hashAdminPassword(password)
+				if err != nil {
+					t.Fatal(err)
+				}
+				tt.admin.Username = "root"
+				tt.admin.Role = AdminRoleSuperAdmin
+				tt.admin.PasswordHash = hash
+				if err := database.DB.Create(tt.admin).Error; err != nil {
+					t.Fatal(err)
+				}
+				username = tt.admin.Username
+			}
+
+			_, err := s.Authenticate(username, tt.password, "", "127.0.0.1")
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("Authenticate: %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Authenticate err=%v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,304 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"expchange-backend/database"
+	"expchange-backend/models"
+	"expchange-backend/pkg/secretbox"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// API 密钥权限
+const (
+	APIKeyScopeRead     = "read"
+	APIKeyScopeTrade    = "trade"
+	APIKeyScopeWithdraw = "withdraw"
+)
+
+// maxAPIKeysPerUser 每个用户最多可创建的有效 API 密钥数量
+const maxAPIKeysPerUser = 20
+
+// 签名请求的时间窗口：客户端可通过 recvWindow 缩小，但不能超过服务端上限
+const (
+	DefaultAPIRecvWindow = 5 * time.Second
+	MaxAPIRecvWindow     = 60 * time.Second
+)
+
+var (
+	ErrAPIKeyNotFound     = errors.New("api key not found")
+	ErrAPIKeyIPNotAllowed = errors.New("ip address not allowed")
+	ErrAPIKeySignature    = errors.New("invalid signature")
+	ErrAPIKeyReplay       = errors.New("duplicate request")
+)
+
+// APIKeyService API 密钥管理与请求签名校验
+type APIKeyService struct {
+	box *secretbox.Box
+
+	// 已使用的签名（时间窗口内拒绝完全相同的请求）；配置 Redis 后多实例共享
+	seenMu  sync.Mutex
+	seenSig map[string]time.Time
+	redis   *redis.Client
+}
+
+func NewAPIKeyService(encryptionKey string) *APIKeyService {
+	box, err := secretbox.New(encryptionKey)
+	if err != nil {
+		panic(fmt.Sprintf("failed to init api key encryption: %v", err))
+	}
+	return &APIKeyService{
+		box:     box,
+		seenSig: make(map[string]time.Time),
+	}
+}
+
+// UseRedisReplayStore 使用 Redis 记录已使用的签名（多实例部署时共享防重放状态）
+func (s *APIKeyService) UseRedisReplayStore(client *redis.Client) {
+	s.redis = client
+}
+
+// NormalizeScopes 校验并规范化权限列表（read 权限默认包含）
+func NormalizeScopes(scopes []string) (string, error) {
+	set := map[string]bool{APIKeyScopeRead: true}
+	for _, scope := range scopes {
+		scope = strings.ToLower(strings.TrimSpace(scope))
+		switch scope {
+		case APIKeyScopeRead, APIKeyScopeTrade, APIKeyScopeWithdraw:
+			set[scope] = true
+		case "":
+		default:
+			return "", fmt.Errorf("invalid permission: %s", scope)
+		}
+	}
+
+	result := make([]string, 0, len(set))
+	for _, scope := range []string{APIKeyScopeRead, APIKeyScopeTrade, APIKeyScopeWithdraw} {
+		if set[scope] {
+			result = append(result, scope)
+		}
+	}
+	return strings.Join(result, ","), nil
+}
+
+// NormalizeIPWhitelist 校验 IP/CIDR 白名单
+func NormalizeIPWhitelist(entries []string) (string, error) {
+	result := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if strings.Contains(entry, "/") {
+			if _, _, err := net.ParseCIDR(entry); err != nil {
+				return "", fmt.Errorf("invalid CIDR: %s", entry)
+			}
+		} else if net.ParseIP(entry) == nil {
+			return "", fmt.Errorf("invalid IP: %s", entry)
+		}
+		result = append(result, entry)
+	}
+	return strings.Join(result, ","), nil
+}
+
+// Create 创建 API 密钥，返回记录和明文签名密钥（明文只在此时返回一次）
+func (s *APIKeyService) Create(userID, label string, scopes, ipWhitelist []string) (*models.APIKey, string, error) {
+	permissions, err := NormalizeScopes(scopes)
+	if err != nil {
+		return nil, "", err
+	}
+	whitelist, err := NormalizeIPWhitelist(ipWhitelist)
+	if err != nil {
+		return nil, "", err
+	}
+	// 提现权限必须绑定IP白名单
+	if strings.Contains(permissions, APIKeyScopeWithdraw) && whitelist == "" {
+		return nil, "", errors.New("withdraw permission requires an IP whitelist")
+	}
+
+	var count int64
+	database.DB.Model(&models.APIKey{}).Where("user_id = ? AND revoked_at IS NULL", userID).Count(&count)
+	if count >= maxAPIKeysPerUser {
+		return nil, "", fmt.Errorf("at most %d api keys are allowed", maxAPIKeysPerUser)
+	}
+
+	accessKey, err := randomHex(16)
+	if err != nil {
+		return nil, "", err
+	}
+	secret, err := randomHex(32)
+	if err != nil {
+		return nil, "", err
+	}
+	encrypted, err := s.box.Seal(secret)
+	if err != nil {
+		return nil, "", err
+	}
+
+	key := &models.APIKey{
+		UserID:          userID,
+		Label:           truncate(strings.TrimSpace(label), 50),
+		AccessKey:       "vk_" + accessKey,
+		SecretEncrypted: encrypted,
+		Permissions:     permissions,
+		IPWhitelist:     whitelist,
+	}
+	if err := database.DB.Create(key).Error; err != nil {
+		return nil, "", err
+	}
+
+	// 新 API 密钥属于安全变更，锁定提现
+	LockWithdrawals(userID, "api_key_created")
+
+	return key, secret, nil
+}
+
+// List 获取用户的有效 API 密钥
+func (s *APIKeyService) List(userID string) []models.APIKey {
+	keys := []models.APIKey{}
+	database.DB.Where("user_id = ? AND revoked_at IS NULL", userID).Order("created_at DESC").Find(&keys)
+	return keys
+}
+
+// Revoke 吊销 API 密钥
+func (s *APIKeyService) Revoke(userID, keyID string) error {
+	result := database.DB.Model(&models.APIKey{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", keyID, userID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// Authenticate 校验签名请求
+// 签名内容：timestamp + recvWindow(请求头原值，未传为空) + method + path(含query) + body，HMAC-SHA256 后十六进制编码
+func (s *APIKeyService) Authenticate(accessKey, signature, timestamp, recvWindow, method, path, body, clientIP string) (*models.APIKey, error) {
+	var key models.APIKey
+	err := database.DB.Session(&gorm.Session{Logger: database.DB.Logger.LogMode(logger.Silent)}).
+		Where("access_key = ? AND revoked_at IS NULL", accessKey).First(&key).Error
+	if err != nil {
+		return nil, ErrAPIKeyNotFound
+	}
+
+	if !ipAllowed(key.IPWhitelist, clientIP) {
+		return nil, ErrAPIKeyIPNotAllowed
+	}
+
+	secret, err := s.box.Open(key.SecretEncrypted)
+	if err != nil {
+		return nil, err
+	}
+
+	expected := signAPIRequest(secret, timestamp, recvWindow, method, path, body)
+	if !hmac.Equal([]byte(expected), []byte(strings.ToLower(signature))) {
+		return nil, ErrAPIKeySignature
+	}
+
+	if !s.markSignatureUsed(expected) {
+		return nil, ErrAPIKeyReplay
+	}
+
+	// 最后使用时间按分钟更新，避免每个请求都写库
+	now := time.Now()
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) > time.Minute {
+		database.DB.Model(&models.APIKey{}).Where("id = ?", key.ID).Update("last_used_at", now)
+		key.LastUsedAt = &now
+	}
+
+	return &key, nil
+}
+
+// signAPIRequest 计算请求签名
+func signAPIRequest(secret, timestamp, recvWindow, method, path, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + recvWindow + strings.ToUpper(method) + path + body))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// HasScope 检查 API 密钥是否拥有指定权限
+func HasScope(permissions, scope string) bool {
+	for _, p := range strings.Split(permissions, ",") {
+		if p == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// markSignatureUsed 记录签名，重复出现则视为重放
+// 时间戳允许前后偏差不超过 MaxAPIRecvWindow，签名保留两倍上限，与客户端传入的 recvWindow 无关
+func (s *APIKeyService) markSignatureUsed(signature string) bool {
+	ttl := 2 * MaxAPIRecvWindow
+	if s.redis != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		ok, err := s.redis.SetNX(ctx, "apikey:sig:"+signature, 1, ttl).Result()
+		if err == nil {
+			return ok
+		}
+		log.Printf("⚠️  API 密钥防重放 Redis 不可用，使用内存记录: %v", err)
+	}
+
+	s.seenMu.Lock()
+	defer s.seenMu.Unlock()
+
+	now := time.Now()
+	for sig, expiresAt := range s.seenSig {
+		if now.After(expiresAt) {
+			delete(s.seenSig, sig)
+		}
+	}
+
+	if _, ok := s.seenSig[signature]; ok {
+		return false
+	}
+	s.seenSig[signature] = now.Add(ttl)
+	return true
+}
+
+// ipAllowed 检查客户端IP是否在白名单中（白名单为空不限制）
+func ipAllowed(whitelist, clientIP string) bool {
+	if whitelist == "" {
+		return true
+	}
+	ip := net.ParseIP(clientIP)
+	if ip == nil {
+		return false
+	}
+
+	for _, entry := range strings.Split(whitelist, ",") {
+		if strings.Contains(entry, "/") {
+			if _, network, err := net.ParseCIDR(entry); err == nil && network.Contains(ip) {
+				return true
+			}
+		} else if allowed := net.ParseIP(entry); allowed != nil && allowed.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,57 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSignAPIRequest(t *testing.T) {
+	const (
+		secret    = "secret"
+		timestamp = "1700000000000"
+		path      = "/api/orders?symbol=BTC/USDT"
+		body      = `{"amount":"1"}`
+	)
+	base := signAPIRequest(secret, timestamp, "5000", "POST", path, body)
+
+	tests := []struct {
+		name       string
+		recvWindow string
+		method     string
+		path       string
+		body       string
+		wantSame   bool
+	}{
+		{name: "method case insensitive", recvWindow: "5000", method: "post", path: path, body: body, wantSame: true},
+		{name: "recvWindow enlarged", recvWindow: "60000", method: "POST", path: path, body: body},
+		{name: "recvWindow removed", recvWindow: "", method: "POST", path: path, body: body},
+		{name: "path changed", recvWindow: "5000", method: "POST", path: path + "&x=1", body: body},
+		{name: "body changed", recvWindow: "5000", method: "POST", path: path, body: `{"amount":"2"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signature := signAPIRequest(secret, timestamp, tt.recvWindow, tt.method, tt.path, tt.body)
+			if (signature == base) != tt.wantSame {
+				t.Fatalf("signature equal=%v, want %v", signature == base, tt.wantSame)
+			}
+		})
+	}
+}
+
+func TestMarkSignatureUsed(t *testing.T) {
+	s := &APIKeyService{seenSig: make(map[string]time.Time)}
+
+	if !s.markSignatureUsed("sig-a") {
+		t.Fatal("first use should be accepted")
+	}
+	if s.markSignatureUsed("sig-a") {
+		t.Fatal("replayed signature should be rejected")
+	}
+	if !s.markSignatureUsed("sig-b") {
+		t.Fatal("different signature should be accepted")
+	}
+	// 签名保留时间取服务端上限，不受客户端 recvWindow 影响
+	if ttl := time.Until(s.seenSig["sig-a"]); ttl < 2*MaxAPIRecvWindow-time.Second {
+		t.Fatalf("replay record kept for %v, want about %v", ttl, 2*MaxAPIRecvWindow)
+	}
+}
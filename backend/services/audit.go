@@ -0,0 +1,255 @@
+package services
+
+import (
+	"encoding/json"
+	"expchange-backend/database"
+	"expchange-backend/models"
+	"log"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// auditMaskedValue 脱敏后的占位值
+const auditMaskedValue = "***"
+
+// auditMaxFieldLen 单个快照字段最大长度（超出截断）
+const auditMaxFieldLen = 16 * 1024
+
+// 字段名包含以下关键字时脱敏
+var auditSensitiveKeywords = []string{"private_key", "privatekey", "secret", "password", "token", "totp", "mnemonic", "signature", "webhook"}
+
+// 字段名完全匹配时脱敏
+var auditSensitiveKeys = map[string]bool{"code": true}
+
+func isSensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+	if auditSensitiveKeys[key] {
+		return true
+	}
+	for _, keyword := range auditSensitiveKeywords {
+		if strings.Contains(key, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// MaskSecrets 递归脱敏 JSON 对象中的敏感字段（私钥、密码、令牌等）
+func MaskSecrets(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for key, item := range value {
+			if isSensitiveKey(key) {
+				value[key] = maskValue(item)
+				continue
+			}
+			value[key] = MaskSecrets(item)
+		}
+		return value
+	case []interface{}:
+		for i, item := range value {
+			value[i] = MaskSecrets(item)
+		}
+		return value
+	default:
+		return v
+	}
+}
+
+// maskValue 敏感值替换为固定占位符（不保留哈希：短值或低熵值可被离线穷举还原），空值保持原样
+func maskValue(v interface{}) interface{} {
+	if v == nil || v == "" {
+		return v
+	}
+	return auditMaskedValue
+}
+
+// SystemConfigSnapshot 系统配置快照：以配置键为字段记录值，敏感配置（私钥、webhook 等）按键名脱敏
+func SystemConfigSnapshot(config *models.SystemConfig) map[string]interface{} {
+	return map[string]interface{}{
+		"key":        config.Key,
+		"category":   config.Category,
+		"value_type": config.ValueType,
+		config.Key:   config.Value,
+	}
+}
+
+// MaskSystemConfigRequest 修改系统配置的请求体：value 以配置键记录后脱敏
+func MaskSystemConfigRequest(key string, body []byte) string {
+	var data map[string]interface{}
+	if err := json.Unmarshal(body, &data); err != nil {
+		return "[unparsed body]"
+	}
+	if value, ok := data["value"]; ok {
+		delete(data, "value")
+		data[key] = value
+	}
+	return MaskedJSON(data)
+}
+
+// MaskedJSON 将任意对象序列化为脱敏后的 JSON
+func MaskedJSON(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return maskJSONBytes(raw)
+}
+
+// MaskRequestBody 请求体脱敏（支持 JSON 和表单）
+func MaskRequestBody(contentType string, body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+
+	if strings.Contains(contentType, "application/x-www-form-urlencoded") {
+		values, err := url.ParseQuery(string(body))
+		if err != nil {
+			return ""
+		}
+		form := make(map[string]interface{}, len(values))
+		for key := range values {
+			form[key] = values.Get(key)
+		}
+		return MaskedJSON(form)
+	}
+
+	if masked := maskJSONBytes(body); masked != "" {
+		return masked
+	}
+	// 无法解析的请求体不记录原文，避免泄露敏感信息
+	return "[unparsed body]"
+}
+
+func maskJSONBytes(raw []byte) string {
+	var data interface{}
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return ""
+	}
+	masked, err := json.Marshal(MaskSecrets(data))
+	if err != nil {
+		return ""
+	}
+	return truncate(string(masked), auditMaxFieldLen)
+}
+
+// DiffSnapshots 计算两个 JSON 快照的字段级差异（只比较顶层字段）
+func DiffSnapshots(before, after string) string {
+	var beforeMap, afterMap map[string]interface{}
+	json.Unmarshal([]byte(before), &beforeMap)
+	json.Unmarshal([]byte(after), &afterMap)
+	if beforeMap == nil && afterMap == nil {
+		return ""
+	}
+
+	keys := make(map[string]bool)
+	for key := range beforeMap {
+		keys[key] = true
+	}
+	for key := range afterMap {
+		keys[key] = true
+	}
+
+	sorted := make([]string, 0, len(keys))
+	for key := range keys {
+		// 更新时间每次都会变化，不计入差异
+		if key == "updated_at" {
+			continue
+		}
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+
+	changes := make(map[string]map[string]interface{})
+	for _, key := range sorted {
+		from, to := beforeMap[key], afterMap[key]
+		if reflect.DeepEqual(from, to) {
+			continue
+		}
+		changes[key] = map[string]interface{}{"from": from, "to": to}
+	}
+	if len(changes) == 0 {
+		return ""
+	}
+
+	raw, _ := json.Marshal(changes)
+	return string(raw)
+}
+
+// RecordAdminAudit 写入审计日志（失败只打印日志，不影响业务请求）
+func RecordAdminAudit(entry *models.AdminAuditLog) {
+	entry.Request = truncate(entry.Request, auditMaxFieldLen)
+	entry.Before = truncate(entry.Before, auditMaxFieldLen)
+	entry.After = truncate(entry.After, auditMaxFieldLen)
+	entry.Changes = truncate(entry.Changes, auditMaxFieldLen)
+	entry.UserAgent = truncate(entry.UserAgent, 255)
+	entry.Path = truncate(entry.Path, 255)
+
+	if err := database.DB.Create(entry).Error; err != nil {
+		log.Printf("❌ 写入审计日志失败: Action=%s, Admin=%s, %v", entry.Action, entry.AdminUsername, err)
+	}
+}
+
+// AuditLogFilter 审计日志查询条件
+type AuditLogFilter struct {
+	AdminID    string
+	Admin      string // 用户名
+	Action     string // 模糊匹配
+	TargetType string
+	TargetID   string
+	Start      *time.Time
+	End        *time.Time
+	Page       int
+	PageSize   int
+}
+
+// QueryAuditLogs 按条件分页查询审计日志（按时间倒序）
+func QueryAuditLogs(filter AuditLogFilter) ([]models.AdminAuditLog, int64) {
+	query := database.DB.Model(&models.AdminAuditLog{})
+
+	if filter.AdminID != "" {
+		query = query.Where("admin_id = ?", filter.AdminID)
+	}
+	if filter.Admin != "" {
+		query = query.Where("admin_username = ?", filter.Admin)
+	}
+	if filter.Action != "" {
+		query = query.Where("action LIKE ?", "%"+filter.Action+"%")
+	}
+	if filter.TargetType != "" {
+		query = query.Where("target_type = ?", filter.TargetType)
+	}
+	if filter.TargetID != "" {
+		query = query.Where("target_id = ?", filter.TargetID)
+	}
+	if filter.Start != nil {
+		query = query.Where("created_at >= ?", *filter.Start)
+	}
+	if filter.End != nil {
+		query = query.Where("created_at < ?", *filter.End)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	if filter.PageSize <= 0 || filter.PageSize > 200 {
+		filter.PageSize = 50
+	}
+	if filter.Page <= 0 {
+		filter.Page = 1
+	}
+
+	logs := []models.AdminAuditLog{}
+	query.Order("created_at DESC").
+		Offset((filter.Page - 1) * filter.PageSize).
+		Limit(filter.PageSize).
+		Find(&logs)
+
+	return logs, total
+}
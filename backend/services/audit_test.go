@@ -0,0 +1,74 @@
+package services
+
+import (
+	"expchange-backend/models"
+	"strings"
+	"testing"
+)
+
+func TestMaskedJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   interface{}
+		want    string
+		notWant string
+	}{
+		{
+			name:    "private key replaced by fixed placeholder",
+			value:   map[string]interface{}{"chain_id": 56, "private_key": "0xdeadbeef"},
+			want:    `{"chain_id":56,"private_key":"***"}`,
+			notWant: "deadbeef",
+		},
+		{
+			name:  "empty secret kept empty",
+			value: map[string]interface{}{"password": ""},
+			want:  `{"password":""}`,
+		},
+		{
+			name:  "nested values",
+			value: map[string]interface{}{"admins": []interface{}{map[string]interface{}{"username": "root", "totp_code": "123456"}}},
+			want:  `{"admins":[{"totp_code":"***","username":"root"}]}`,
+		},
+		{
+			name:  "sensitive system config keyed by config name",
+			value: SystemConfigSnapshot(&models.SystemConfig{Key: "alert.webhook_url", Category: "alert", ValueType: "string", Value: "https://hooks.example.com/T0/B0/xyz"}),
+			want:  `{"alert.webhook_url":"***","category":"alert","key":"alert.webhook_url","value_type":"string"}`,
+		},
+		{
+			name:  "plain system config kept",
+			value: SystemConfigSnapshot(&models.SystemConfig{Key: "withdraw.risk.review_score", Category: "withdraw", ValueType: "number", Value: "50"}),
+			want:  `{"category":"withdraw","key":"withdraw.risk.review_score","value_type":"number","withdraw.risk.review_score":"50"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := MaskedJSON(tt.value)
+			if got != tt.want {
+				t.Fatalf("MaskedJSON=%s, want %s", got, tt.want)
+			}
+			if tt.notWant != "" && strings.Contains(got, tt.notWant) {
+				t.Fatalf("masked output leaks %q: %s", tt.notWant, got)
+			}
+		})
+	}
+}
+
+func TestMaskSystemConfigRequest(t *testing.T) {
+	tests := []struct {
+		name string
+		key  string
+		body string
+		want string
+	}{
+		{name: "sensitive", key: "treasury.private_key", body: `{"value":"0xabc"}`, want: `{"treasury.private_key":"***"}`},
+		{name: "plain", key: "withdraw.risk.review_score", body: `{"value":"60"}`, want: `{"withdraw.risk.review_score":"60"}`},
+		{name: "unparsed", key: "treasury.private_key", body: `value=0xabc`, want: "[unparsed body]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MaskSystemConfigRequest(tt.key, []byte(tt.body)); got != tt.want {
+				t.Fatalf("MaskSystemConfigRequest=%s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,197 @@
+package services
+
+import (
+	"errors"
+	"expchange-backend/database"
+	"expchange-backend/models"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrAssetNotSupported     = errors.New("asset is not supported on this chain")
+	ErrAssetDepositDisabled  = errors.New("deposits of this asset are disabled on this chain")
+	ErrAssetWithdrawDisabled = errors.New("withdrawals of this asset are disabled on this chain")
+	ErrAssetWithdrawPaused   = errors.New("withdrawals of this asset are temporarily paused on this chain")
+	ErrDepositBelowMinimum   = errors.New("deposit amount is below the minimum")
+	ErrAssetPrecision        = errors.New("amount has more decimal places than the asset supports on this chain")
+	ErrUsdtTokenRequired     = errors.New("USDT is configured in the chain settings and cannot be removed, disable it instead")
+)
+
+// usdtAsset 链配置中单独设置合约和精度的资产
+const usdtAsset = "USDT"
+
+// balancePrecision 余额和充值提现记录的小数位数（decimal(30,8)），链上精度更高的资产按该精度记账
+const balancePrecision = 8
+
+// amountPrecision 资产可记账的小数位数
+func amountPrecision(token *models.ChainToken) int32 {
+	return min(int32(token.Decimals), balancePrecision)
+}
+
+// normalizeAsset 资产符号统一为大写
+func normalizeAsset(asset string) string {
+	return strings.ToUpper(strings.TrimSpace(asset))
+}
+
+// GetChainToken 获取链上资产配置（未注册时返回 ErrAssetNotSupported）
+func GetChainToken(chainID int, asset string) (*models.ChainToken, error) {
+	var token models.ChainToken
+	err := database.DB.Where("chain_id = ? AND asset = ?", chainID, normalizeAsset(asset)).First(&token).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: %s", ErrAssetNotSupported, asset)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// DepositTokens 链上开放充值的资产
+func DepositTokens(chainID int) []models.ChainToken {
+	var tokens []models.ChainToken
+	database.DB.Where("chain_id = ? AND deposit_enabled = ?", chainID, true).Order("asset ASC").Find(&tokens)
+	return tokens
+}
+
+// GetChainTokensByChain 按链ID分组的资产配置
+func GetChainTokensByChain() map[int][]models.ChainToken {
+	var tokens []models.ChainToken
+	database.DB.Order("chain_id ASC, asset ASC").Find(&tokens)
+
+	result := make(map[int][]models.ChainToken)
+	for _, token := range tokens {
+		result[token.ChainID] = append(result[token.ChainID], token)
+	}
+	return result
+}
+
+// ValidateDepositAsset 校验资产在该链开放充值、金额不低于最小充值额且不超过链上精度
+func ValidateDepositAsset(chainID int, asset string, amount decimal.Decimal) (*models.ChainToken, error) {
+	token, err := GetChainToken(chainID, asset)
+	if err != nil {
+		return nil, err
+	}
+	if !token.DepositEnabled {
+		return nil, ErrAssetDepositDisabled
+	}
+	if amount.LessThan(token.MinDepositAmount) {
+		return nil, fmt.Errorf("%w: %s %s", ErrDepositBelowMinimum, token.MinDepositAmount.String(), token.Asset)
+	}
+	if !amount.Shift(amountPrecision(token)).IsInteger() {
+		return nil, fmt.Errorf("%w: %d", ErrAssetPrecision, amountPrecision(token))
+	}
+	return token, nil
+}
+
+// ValidateWithdrawAsset 校验资产在该链开放提现、未因热钱包余额不足暂停，且到账金额（扣除手续费后）不超过链上精度
+func ValidateWithdrawAsset(chainID int, asset string, amount, fee decimal.Decimal) (*models.ChainToken, error) {
+	token, err := GetChainToken(chainID, asset)
+	if err != nil {
+		return nil, err
+	}
+	if !token.WithdrawEnabled {
+		return nil, ErrAssetWithdrawDisabled
+	}
+	if token.WithdrawPaused {
+		return nil, ErrAssetWithdrawPaused
+	}
+	if !amount.Sub(fee).Shift(amountPrecision(token)).IsInteger() {
+		return nil, fmt.Errorf("%w: %d", ErrAssetPrecision, amountPrecision(token))
+	}
+	return token, nil
+}
+
+// UpsertChainToken 创建或更新链上资产配置；USDT 的合约和精度同时写回链配置
+func UpsertChainToken(chain *models.ChainConfig, token models.ChainToken) (*models.ChainToken, error) {
+	token.ChainID = chain.ChainID
+	token.Asset = normalizeAsset(token.Asset)
+	if token.Asset == "" {
+		return nil, errors.New("asset is required")
+	}
+	token.ContractAddress = strings.TrimSpace(token.ContractAddress)
+	if token.ContractAddress != "" {
+		if !common.IsHexAddress(token.ContractAddress) {
+			return nil, errors.New("invalid contract_address")
+		}
+		token.ContractAddress = common.HexToAddress(token.ContractAddress).Hex()
+	}
+	if token.Asset == usdtAsset && token.ContractAddress == "" {
+		return nil, errors.New("contract_address is required for USDT")
+	}
+	if token.Decimals < 0 || token.Decimals > 36 {
+		return nil, errors.New("decimals must be between 0 and 36")
+	}
+	if token.MinDepositAmount.IsNegative() {
+		return nil, errors.New("min_deposit_amount must not be negative")
+	}
+	if token.HotAlertBalance.IsNegative() || token.HotPauseBalance.IsNegative() {
+		return nil, errors.New("hot wallet thresholds must not be negative")
+	}
+	if token.RiskLargeAmount.IsNegative() || token.RiskDailyLimit.IsNegative() {
+		return nil, errors.New("risk thresholds must not be negative")
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "chain_id"}, {Name: "asset"}},
+			DoUpdates: clause.AssignmentColumns([]string{
+				"contract_address", "decimals", "deposit_enabled", "withdraw_enabled", "min_deposit_amount",
+				"hot_alert_balance", "hot_pause_balance", "risk_large_amount", "risk_daily_limit", "updated_at",
+			}),
+		}).Create(&token).Error; err != nil {
+			return err
+		}
+		if token.Asset != usdtAsset {
+			return nil
+		}
+		return tx.Model(&models.ChainConfig{}).Where("chain_id = ?", chain.ChainID).Updates(map[string]interface{}{
+			"usdt_contract_address": token.ContractAddress,
+			"usdt_decimals":         token.Decimals,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return GetChainToken(chain.ChainID, token.Asset)
+}
+
+// SyncUsdtToken 按链配置的 USDT 合约和精度更新资产注册表（不存在时创建并开放充值提现）
+func SyncUsdtToken(db *gorm.DB, chain *models.ChainConfig) error {
+	if !common.IsHexAddress(chain.UsdtContractAddress) {
+		return nil
+	}
+	token := models.ChainToken{
+		ChainID:         chain.ChainID,
+		Asset:           usdtAsset,
+		ContractAddress: common.HexToAddress(chain.UsdtContractAddress).Hex(),
+		Decimals:        chain.UsdtDecimals,
+		DepositEnabled:  true,
+		WithdrawEnabled: true,
+	}
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "chain_id"}, {Name: "asset"}},
+		DoUpdates: clause.AssignmentColumns([]string{"contract_address", "decimals", "updated_at"}),
+	}).Create(&token).Error
+}
+
+// DeleteChainToken 删除链上资产配置（USDT 只能关闭充值提现）
+func DeleteChainToken(chainID int, asset string) error {
+	asset = normalizeAsset(asset)
+	if asset == usdtAsset {
+		return ErrUsdtTokenRequired
+	}
+	result := database.DB.Where("chain_id = ? AND asset = ?", chainID, asset).Delete(&models.ChainToken{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrAssetNotSupported
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package services
+
+import (
+	"context"
+	"expchange-backend/database"
+	"expchange-backend/models"
+	"expchange-backend/pkg/siwe"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// contractWalletCacheTTL 合约钱包校验结果缓存时间
+const contractWalletCacheTTL = 10 * time.Minute
+
+type chainVerifier struct {
+	rpcURL   string
+	verifier *siwe.ContractVerifier
+}
+
+// ContractWalletService 智能合约钱包（Safe 等）登录签名校验，按链复用 RPC 连接和缓存
+type ContractWalletService struct {
+	mu        sync.Mutex
+	verifiers map[int]*chainVerifier
+	dial      func(chain *models.ChainConfig) (siwe.ContractCaller, error)
+}
+
+func NewContractWalletService() *ContractWalletService {
+	return &ContractWalletService{
+		verifiers: make(map[int]*chainVerifier),
+		dial: func(chain *models.ChainConfig) (siwe.ContractCaller, error) {
+			return ChainClient(chain)
+		},
+	}
+}
+
+// VerifySignature 通过链上 isValidSignature 校验签名（EIP-1271）
+func (s *ContractWalletService) VerifySignature(chainID int, message, signature, address string) error {
+	verifier, err := s.getVerifier(chainID)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	return verifier.VerifySignature(ctx, message, signature, address)
+}
+
+// getVerifier 获取链对应的校验器，RPC 地址变更后重新连接
+func (s *ContractWalletService) getVerifier(chainID int) (*siwe.ContractVerifier, error) {
+	var chainConfig models.ChainConfig
+	if err := database.DB.Where("chain_id = ? AND enabled = ?", chainID, true).First(&chainConfig).Error; err != nil {
+		return nil, fmt.Errorf("chain %d not supported", chainID)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if cached, ok := s.verifiers[chainID]; ok && cached.rpcURL == chainConfig.RpcURL {
+		return cached.verifier, nil
+	}
+
+	caller, err := s.dial(&chainConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to RPC: %w", err)
+	}
+
+	verifier := siwe.NewContractVerifier(caller, contractWalletCacheTTL)
+	s.verifiers[chainID] = &chainVerifier{rpcURL: chainConfig.RpcURL, verifier: verifier}
+	return verifier, nil
+}
@@ -0,0 +1,154 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"expchange-backend/database"
+	"expchange-backend/models"
+	"expchange-backend/pkg/hdwallet"
+	"expchange-backend/pkg/signer"
+	"fmt"
+	"log"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrDepositXpubNotConfigured = errors.New("deposit xpub not configured for this chain")
+	ErrInvalidDepositXpub       = errors.New("deposit_xpub must be an extended public key (xpub)")
+	ErrInvalidDepositSweepKey   = errors.New("deposit_sweep_key must be an extended private key (xprv)")
+	ErrDepositSweepKeyMismatch  = errors.New("deposit_sweep_key does not match deposit_xpub")
+	ErrDepositXpubLocked        = errors.New("deposit_xpub cannot be changed after deposit addresses have been allocated")
+)
+
+// depositAddressChain 派生路径中的 change 层级（BIP-44 外部链 0）：地址路径为 xpub/0/index
+const depositAddressChain = 0
+
+// ParseDepositXpub 校验充值 xpub（必须是扩展公钥，不能是扩展私钥）
+func ParseDepositXpub(xpub string) (*hdwallet.ExtendedKey, error) {
+	key, err := hdwallet.ParseExtendedKey(xpub)
+	if err != nil || key.IsPrivate() {
+		return nil, ErrInvalidDepositXpub
+	}
+	return key, nil
+}
+
+// deriveDepositKey 按序号派生充值地址密钥；该序号无效（概率约 2^-127）时返回 hdwallet.ErrInvalidChild
+func deriveDepositKey(account *hdwallet.ExtendedKey, index uint32) (*hdwallet.ExtendedKey, error) {
+	if index >= hdwallet.HardenedOffset {
+		return nil, errors.New("deposit address index exhausted")
+	}
+	return account.Derive(depositAddressChain, index)
+}
+
+// GetDepositAddress 获取用户在该链的充值地址（不存在时分配下一个派生序号）
+// 并发分配依靠 (chain_id, derivation_index) 和 (user_id, chain_id) 唯一索引，冲突时重试
+func GetDepositAddress(userID string, chain *models.ChainConfig) (*models.DepositAddress, error) {
+	if chain.DepositXpub == "" {
+		return nil, ErrDepositXpubNotConfigured
+	}
+	account, err := ParseDepositXpub(chain.DepositXpub)
+	if err != nil {
+		return nil, err
+	}
+
+	var lastErr error
+	for attempt := 0; attempt < 5; attempt++ {
+		var address models.DepositAddress
+		err := database.DB.Where("user_id = ? AND chain_id = ?", userID, chain.ChainID).First(&address).Error
+		if err == nil {
+			return &address, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+
+		var maxIndex sql.NullInt64
+		if err := database.DB.Model(&models.DepositAddress{}).
+			Where("chain_id = ?", chain.ChainID).
+			Select("MAX(derivation_index)").
+			Row().Scan(&maxIndex); err != nil {
+			return nil, err
+		}
+		index := uint32(0)
+		if maxIndex.Valid {
+			index = uint32(maxIndex.Int64) + 1
+		}
+
+		key, err := deriveDepositKey(account, index)
+		for errors.Is(err, hdwallet.ErrInvalidChild) {
+			index++
+			key, err = deriveDepositKey(account, index)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		address = models.DepositAddress{
+			UserID:          userID,
+			ChainID:         chain.ChainID,
+			Address:         strings.ToLower(key.Address().Hex()),
+			DerivationIndex: index,
+		}
+		if lastErr = database.DB.Create(&address).Error; lastErr == nil {
+			log.Printf("🏷️  已分配充值地址: 用户ID=%s, Chain=%s, Index=%d, Address=%s",
+				userID, chain.ChainName, index, address.Address)
+			return &address, nil
+		}
+	}
+	return nil, fmt.Errorf("failed to allocate deposit address: %w", lastErr)
+}
+
+// depositAddressOwners 链上所有充值地址（小写）到用户ID的映射
+func depositAddressOwners(chainID int) map[string]string {
+	var addresses []models.DepositAddress
+	database.DB.Where("chain_id = ?", chainID).Find(&addresses)
+
+	owners := make(map[string]string, len(addresses))
+	for _, address := range addresses {
+		owners[address.Address] = address.UserID
+	}
+	return owners
+}
+
+// SealDepositSweepKey 校验扩展私钥与充值 xpub 对应后加密保存
+func (v *WalletKeyVault) SealDepositSweepKey(xprv, xpub string) (string, error) {
+	key, err := hdwallet.ParseExtendedKey(xprv)
+	if err != nil || !key.IsPrivate() {
+		return "", ErrInvalidDepositSweepKey
+	}
+	if key.Neuter().String() != strings.TrimSpace(xpub) {
+		return "", ErrDepositSweepKeyMismatch
+	}
+	return v.SealSecret(key.String())
+}
+
+// DepositAddressSigner 充值地址的签名器（用于归集；派生出的地址必须与记录一致）
+func (v *WalletKeyVault) DepositAddressSigner(chain *models.ChainConfig, address *models.DepositAddress) (signer.Signer, error) {
+	if chain.DepositSweepKey == "" {
+		return nil, ErrWalletKeyNotConfigured
+	}
+	xprv, err := v.OpenSecret(chain.DepositSweepKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt deposit sweep key of chain %s: %w", chain.ChainName, err)
+	}
+	account, err := hdwallet.ParseExtendedKey(xprv)
+	if err != nil {
+		return nil, err
+	}
+	key, err := deriveDepositKey(account, address.DerivationIndex)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(key.Address().Hex(), address.Address) {
+		return nil, fmt.Errorf("%w: index %d derives %s, want %s",
+			ErrDepositSweepKeyMismatch, address.DerivationIndex, key.Address().Hex(), address.Address)
+	}
+
+	privateKeyHex, err := key.PrivateKeyHex()
+	if err != nil {
+		return nil, err
+	}
+	return signer.NewPrivateKeySigner(privateKeyHex)
+}
@@ -0,0 +1,522 @@
+package services
+
+import (
+	"cmp"
+	"errors"
+	"expchange-backend/database"
+	"expchange-backend/models"
+	"fmt"
+	"log"
+	"math/big"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrUnclaimedDepositHandled = errors.New("deposit has already been handled")
+	ErrDepositAlreadyRecorded  = errors.New("a deposit record already exists for this transaction")
+)
+
+// scanAddressesPerQuery 单次 eth_getLogs 查询的收款地址数量
+const scanAddressesPerQuery = 500
+
+// scannedTransfer 同一笔交易中转入同一充值地址的同一资产（ERC20 Transfer 事件金额合并，原生币为交易 value）
+type scannedTransfer struct {
+	Token       *models.ChainToken
+	TxHash      string
+	From        []string
+	To          string
+	Value       *big.Int
+	BlockNumber uint64
+	BlockHash   string
+}
+
+// ScanDeposits 扫描所有启用链上转入平台充值地址和用户充值地址的充值并自动入账（由任务队列定时调用）
+//   - ERC20 资产按 Transfer 事件扫描；原生币逐个区块检查直接转入的交易（合约内部转账不会被扫描到，需用户提交交易hash）
+//   - 只扫描达到链配置充值确认数（required_confirmations）的区块；能归属到用户时直接入账，否则记为待归属入账
+func (v *DepositVerifier) ScanDeposits() {
+	if !database.GetSystemConfigManager().GetBool("deposit.scanner.enabled", true) {
+		return
+	}
+
+	var chains []models.ChainConfig
+	database.DB.Where("enabled = ?", true).Find(&chains)
+
+	for i := range chains {
+		if err := v.scanChain(&chains[i]); err != nil {
+			log.Printf("⚠️  充值扫描失败: Chain=%s, err=%v", chains[i].ChainName, err)
+		}
+	}
+}
+
+// scanChain 从上次的进度扫描到最新的安全区块（达到充值确认数的最新区块）
+func (v *DepositVerifier) scanChain(chain *models.ChainConfig) error {
+	var native *models.ChainToken
+	contracts := make(map[common.Address]*models.ChainToken)
+	tokens := DepositTokens(chain.ChainID)
+	for i := range tokens {
+		if tokens[i].IsNative() {
+			native = &tokens[i]
+		} else {
+			contracts[common.HexToAddress(tokens[i].ContractAddress)] = &tokens[i]
+		}
+	}
+	if native == nil && len(contracts) == 0 {
+		return nil
+	}
+
+	// 监听平台共享充值地址和所有用户专属充值地址
+	owners := depositAddressOwners(chain.ChainID)
+	var watched []common.Hash
+	receivers := make(map[common.Address]bool)
+	if common.IsHexAddress(chain.PlatformDepositAddress) {
+		receivers[common.HexToAddress(chain.PlatformDepositAddress)] = true
+	}
+	for address := range owners {
+		receivers[common.HexToAddress(address)] = true
+	}
+	for address := range receivers {
+		watched = append(watched, common.BytesToHash(address.Bytes()))
+	}
+	if len(watched) == 0 {
+		return nil
+	}
+
+	// 区块高度和日志需从同一节点读取
+	client, err := ChainSession(chain)
+	if err != nil {
+		return err
+	}
+
+	head, err := client.BlockNumber(v.ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get block number: %w", err)
+	}
+	sysConfig := database.GetSystemConfigManager()
+	depth := uint64(RequiredDepositConfirmations(chain))
+	if head+1 < depth {
+		return nil
+	}
+	// 区块自身计为 1 个确认
+	safe := head + 1 - depth
+
+	cursor, err := v.loadScanCursor(client, chain, safe)
+	if err != nil {
+		return err
+	}
+	if err := v.checkCursorReorg(client, chain, cursor, depth); err != nil {
+		return err
+	}
+
+	batchBlocks := uint64(sysConfig.GetInt("deposit.scanner.batch_blocks", 2000))
+	if native != nil {
+		// 原生币需要逐个区块读取交易，单轮扫描的区块数更少
+		batchBlocks = min(batchBlocks, uint64(sysConfig.GetInt("deposit.scanner.native_batch_blocks", 100)))
+	}
+	if batchBlocks < 1 {
+		batchBlocks = 1
+	}
+
+	addresses := make([]common.Address, 0, len(contracts))
+	for address := range contracts {
+		addresses = append(addresses, address)
+	}
+	for cursor.BlockNumber < safe {
+		from := cursor.BlockNumber + 1
+		to := from + batchBlocks - 1
+		if to > safe {
+			to = safe
+		}
+
+		// 收款地址较多时分组查询（节点对单个 topic 的候选值数量有限制）
+		var logs []types.Log
+		for start := 0; len(addresses) > 0 && start < len(watched); start += scanAddressesPerQuery {
+			end := min(start+scanAddressesPerQuery, len(watched))
+			chunk, err := client.FilterLogs(v.ctx, ethereum.FilterQuery{
+				FromBlock: new(big.Int).SetUint64(from),
+				ToBlock:   new(big.Int).SetUint64(to),
+				Addresses: addresses,
+				Topics:    [][]common.Hash{{transferEventSignature}, nil, watched[start:end]},
+			})
+			if err != nil {
+				return fmt.Errorf("failed to filter logs %d-%d: %w", from, to, err)
+			}
+			logs = append(logs, chunk...)
+		}
+		// 分组查询的结果按区块内顺序合并
+		slices.SortStableFunc(logs, func(a, b types.Log) int {
+			if a.BlockNumber != b.BlockNumber {
+				return cmp.Compare(a.BlockNumber, b.BlockNumber)
+			}
+			return cmp.Compare(a.Index, b.Index)
+		})
+
+		transfers := groupTransferLogs(logs, contracts)
+		if native != nil {
+			nativeTransfers, err := v.scanNativeTransfers(client, native, receivers, from, to)
+			if err != nil {
+				return err
+			}
+			transfers = append(transfers, nativeTransfers...)
+			slices.SortStableFunc(transfers, func(a, b *scannedTransfer) int {
+				return cmp.Compare(a.BlockNumber, b.BlockNumber)
+			})
+		}
+
+		for _, transfer := range transfers {
+			if err := v.recordScannedDeposit(chain, transfer, owners, head); err != nil {
+				// 未处理完的区块不推进进度，下一轮重新扫描（按交易hash去重）
+				return fmt.Errorf("record deposit %s: %w", transfer.TxHash, err)
+			}
+		}
+
+		header, err := client.HeaderByNumber(v.ctx, new(big.Int).SetUint64(to))
+		if err != nil {
+			return fmt.Errorf("failed to get header %d: %w", to, err)
+		}
+		if err := database.DB.Model(cursor).Updates(map[string]interface{}{
+			"block_number": to,
+			"block_hash":   header.Hash().Hex(),
+		}).Error; err != nil {
+			return fmt.Errorf("save scan cursor: %w", err)
+		}
+	}
+	return nil
+}
+
+// loadScanCursor 读取扫描进度；首次扫描从当前安全区块开始（更早的充值仍可由用户提交交易hash入账）
+func (v *DepositVerifier) loadScanCursor(client *ethclient.Client, chain *models.ChainConfig, safe uint64) (*models.DepositScanCursor, error) {
+	var cursor models.DepositScanCursor
+	err := database.DB.Where("chain_id = ?", chain.ChainID).First(&cursor).Error
+	if err == nil {
+		return &cursor, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	header, err := client.HeaderByNumber(v.ctx, new(big.Int).SetUint64(safe))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get header %d: %w", safe, err)
+	}
+	cursor = models.DepositScanCursor{ChainID: chain.ChainID, BlockNumber: safe, BlockHash: header.Hash().Hex()}
+	if err := database.DB.Create(&cursor).Error; err != nil {
+		return nil, err
+	}
+	log.Printf("📍 充值扫描起点: Chain=%s, Block=%d", chain.ChainName, safe)
+	return &cursor, nil
+}
+
+// checkCursorReorg 进度区块已不在主链上（超过确认深度的重组）时回退 depth 个区块重新扫描
+// 已入账的充值按交易hash去重不会重复入账，但重组前入账的交易可能已失效，需人工核对
+func (v *DepositVerifier) checkCursorReorg(client *ethclient.Client, chain *models.ChainConfig, cursor *models.DepositScanCursor, depth uint64) error {
+	if cursor.BlockHash == "" {
+		return nil
+	}
+	header, err := client.HeaderByNumber(v.ctx, new(big.Int).SetUint64(cursor.BlockNumber))
+	if err != nil {
+		return fmt.Errorf("failed to get header %d: %w", cursor.BlockNumber, err)
+	}
+	if header.Hash().Hex() == cursor.BlockHash {
+		return nil
+	}
+
+	rewind := depth
+	if rewind < 1 {
+		rewind = 1
+	}
+	if rewind > cursor.BlockNumber {
+		rewind = cursor.BlockNumber
+	}
+	log.Printf("⚠️  检测到深度链重组，充值扫描回退 %d 个区块（需人工核对已入账充值）: Chain=%s, Block=%d, Hash=%s -> %s",
+		rewind, chain.ChainName, cursor.BlockNumber, cursor.BlockHash, header.Hash().Hex())
+
+	cursor.BlockNumber -= rewind
+	cursor.BlockHash = ""
+	return database.DB.Model(cursor).Updates(map[string]interface{}{
+		"block_number": cursor.BlockNumber,
+		"block_hash":   "",
+	}).Error
+}
+
+// scanNativeTransfers 扫描区块内直接转入充值地址的原生币交易（跳过失败交易和归集补充 gas 的交易）
+func (v *DepositVerifier) scanNativeTransfers(client *ethclient.Client, token *models.ChainToken, receivers map[common.Address]bool, from, to uint64) ([]*scannedTransfer, error) {
+	var transfers []*scannedTransfer
+	for number := from; number <= to; number++ {
+		block, err := client.BlockByNumber(v.ctx, new(big.Int).SetUint64(number))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get block %d: %w", number, err)
+		}
+		for i, tx := range block.Transactions() {
+			if tx.To() == nil || !receivers[*tx.To()] || tx.Value().Sign() <= 0 {
+				continue
+			}
+			txHash := strings.ToLower(tx.Hash().Hex())
+			var count int64
+			database.DB.Model(&models.DepositSweep{}).Where("gas_tx_hash = ?", txHash).Count(&count)
+			if count > 0 {
+				continue
+			}
+
+			receipt, err := client.TransactionReceipt(v.ctx, tx.Hash())
+			if err != nil {
+				return nil, fmt.Errorf("failed to get receipt %s: %w", txHash, err)
+			}
+			if receipt.Status != 1 {
+				continue
+			}
+			sender, err := client.TransactionSender(v.ctx, tx, block.Hash(), uint(i))
+			if err != nil {
+				return nil, fmt.Errorf("failed to get sender of %s: %w", txHash, err)
+			}
+			transfers = append(transfers, &scannedTransfer{
+				Token:       token,
+				TxHash:      txHash,
+				From:        []string{strings.ToLower(sender.Hex())},
+				To:          strings.ToLower(tx.To().Hex()),
+				Value:       new(big.Int).Set(tx.Value()),
+				BlockNumber: number,
+				BlockHash:   block.Hash().Hex(),
+			})
+		}
+	}
+	return transfers, nil
+}
+
+// groupTransferLogs 按交易、资产和收款地址合并 Transfer 事件，保持区块顺序
+// 充值记录按交易hash唯一，同一笔交易转入多个充值地址时只有第一笔能自动入账，其余需人工处理
+func groupTransferLogs(logs []types.Log, contracts map[common.Address]*models.ChainToken) []*scannedTransfer {
+	type transferKey struct {
+		tx    common.Hash
+		token common.Address
+		to    common.Address
+	}
+	var transfers []*scannedTransfer
+	byKey := make(map[transferKey]*scannedTransfer)
+	recipients := make(map[common.Hash]int)
+
+	for _, entry := range logs {
+		token := contracts[entry.Address]
+		if entry.Removed || len(entry.Topics) != 3 || token == nil {
+			continue
+		}
+		from := strings.ToLower(common.BytesToAddress(entry.Topics[1].Bytes()).Hex())
+		to := common.BytesToAddress(entry.Topics[2].Bytes())
+		value := new(big.Int).SetBytes(entry.Data)
+
+		key := transferKey{tx: entry.TxHash, token: entry.Address, to: to}
+		transfer, ok := byKey[key]
+		if !ok {
+			transfer = &scannedTransfer{
+				Token:       token,
+				TxHash:      strings.ToLower(entry.TxHash.Hex()),
+				To:          strings.ToLower(to.Hex()),
+				Value:       new(big.Int),
+				BlockNumber: entry.BlockNumber,
+				BlockHash:   entry.BlockHash.Hex(),
+			}
+			byKey[key] = transfer
+			transfers = append(transfers, transfer)
+			if recipients[entry.TxHash]++; recipients[entry.TxHash] == 2 {
+				log.Printf("⚠️  同一笔交易转入多个充值地址，只有第一笔会自动入账，其余需人工处理: TxHash=%s", transfer.TxHash)
+			}
+		}
+		transfer.Value.Add(transfer.Value, value)
+		if !slices.Contains(transfer.From, from) {
+			transfer.From = append(transfer.From, from)
+		}
+	}
+	return transfers
+}
+
+// recordScannedDeposit 为扫描到的转账入账或记为待归属入账（按交易hash去重）
+// 转入用户专属充值地址的按地址归属，转入共享充值地址的按转出钱包归属
+func (v *DepositVerifier) recordScannedDeposit(chain *models.ChainConfig, transfer *scannedTransfer, owners map[string]string, head uint64) error {
+	var count int64
+	database.DB.Model(&models.DepositRecord{}).Where("tx_hash = ?", transfer.TxHash).Count(&count)
+	if count > 0 {
+		return nil
+	}
+	database.DB.Model(&models.UnclaimedDeposit{}).Where("tx_hash = ?", transfer.TxHash).Count(&count)
+	if count > 0 {
+		return nil
+	}
+
+	token := transfer.Token
+	// 超出记账精度的部分不入账（留在链上随归集转入热钱包）
+	amount := decimal.NewFromBigInt(transfer.Value, -int32(token.Decimals)).Truncate(amountPrecision(token))
+	if !amount.IsPositive() {
+		return nil
+	}
+	if amount.LessThan(token.MinDepositAmount) {
+		log.Printf("⚠️  扫描到低于最小充值额的转账，不自动入账: Chain=%s, Asset=%s, Amount=%s, TxHash=%s",
+			chain.ChainName, token.Asset, amount.String(), transfer.TxHash)
+		return nil
+	}
+
+	userID := owners[transfer.To]
+	if userID == "" {
+		userID = attributeDeposit(transfer.From)
+	}
+	if userID == "" {
+		unclaimed := models.UnclaimedDeposit{
+			ChainID:     chain.ChainID,
+			Chain:       chain.ChainName,
+			Asset:       token.Asset,
+			Amount:      amount,
+			TxHash:      transfer.TxHash,
+			FromAddress: transfer.From[0],
+			ToAddress:   transfer.To,
+			BlockNumber: transfer.BlockNumber,
+			Status:      "unclaimed",
+		}
+		if err := database.DB.Create(&unclaimed).Error; err != nil {
+			return err
+		}
+		log.Printf("❓ 扫描到无法归属的充值，等待用户提交或管理员处理: Chain=%s, From=%s, Amount=%s %s, TxHash=%s",
+			chain.ChainName, strings.Join(transfer.From, ","), amount.String(), token.Asset, transfer.TxHash)
+		return nil
+	}
+
+	deposit := models.DepositRecord{
+		UserID:                userID,
+		Asset:                 token.Asset,
+		Amount:                amount,
+		TxHash:                transfer.TxHash,
+		Chain:                 chain.ChainName,
+		ChainID:               chain.ChainID,
+		Status:                "confirmed",
+		Source:                "scanner",
+		FromAddress:           transfer.From[0],
+		BlockNumber:           transfer.BlockNumber,
+		BlockHash:             transfer.BlockHash,
+		Confirmations:         int(head - transfer.BlockNumber + 1),
+		RequiredConfirmations: RequiredDepositConfirmations(chain),
+	}
+	if err := createCreditedDeposit(&deposit); err != nil {
+		return err
+	}
+	log.Printf("🎉 扫描充值已到账: 用户ID=%s, 链=%s, 金额=%s %s, TxHash=%s", userID, chain.ChainName, amount.String(), token.Asset, transfer.TxHash)
+	return nil
+}
+
+// attributeDeposit 按转出地址匹配用户钱包；多个转出地址时必须属于同一用户
+func attributeDeposit(fromAddresses []string) string {
+	if len(fromAddresses) != 1 {
+		return ""
+	}
+	var user models.User
+	if err := database.DB.Where("wallet_address = ?", strings.ToLower(fromAddresses[0])).First(&user).Error; err != nil {
+		return ""
+	}
+	return user.ID
+}
+
+// createCreditedDeposit 在一个事务中创建已确认的充值记录并增加余额（交易hash唯一，重复创建会失败）
+func createCreditedDeposit(deposit *models.DepositRecord) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(deposit).Error; err != nil {
+			return err
+		}
+		return creditDeposit(tx, deposit)
+	})
+}
+
+// AssignUnclaimedDeposit 管理员把待归属入账指定给用户并入账
+func AssignUnclaimedDeposit(id, userID, adminID, note string) (*models.DepositRecord, error) {
+	var deposit models.DepositRecord
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var unclaimed models.UnclaimedDeposit
+		if err := tx.Where("id = ?", id).First(&unclaimed).Error; err != nil {
+			return err
+		}
+		if unclaimed.Status != "unclaimed" {
+			return ErrUnclaimedDepositHandled
+		}
+
+		var user models.User
+		if err := tx.Where("id = ?", userID).First(&user).Error; err != nil {
+			return err
+		}
+
+		var count int64
+		tx.Model(&models.DepositRecord{}).Where("tx_hash = ?", unclaimed.TxHash).Count(&count)
+		if count > 0 {
+			return ErrDepositAlreadyRecorded
+		}
+
+		now := time.Now()
+		result := tx.Model(&models.UnclaimedDeposit{}).
+			Where("id = ? AND status = ?", id, "unclaimed").
+			Updates(map[string]interface{}{
+				"status":     "credited",
+				"handled_by": adminID,
+				"handled_at": now,
+				"note":       truncate(note, 255),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrUnclaimedDepositHandled
+		}
+
+		deposit = models.DepositRecord{
+			UserID:      user.ID,
+			Asset:       unclaimed.Asset,
+			Amount:      unclaimed.Amount,
+			TxHash:      unclaimed.TxHash,
+			Chain:       unclaimed.Chain,
+			ChainID:     unclaimed.ChainID,
+			Status:      "confirmed",
+			Source:      "admin",
+			FromAddress: unclaimed.FromAddress,
+			BlockNumber: unclaimed.BlockNumber,
+		}
+		if err := tx.Create(&deposit).Error; err != nil {
+			return err
+		}
+		if err := creditDeposit(tx, &deposit); err != nil {
+			return err
+		}
+		return tx.Model(&models.UnclaimedDeposit{}).Where("id = ?", id).Update("deposit_id", deposit.ID).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("✅ 待归属充值已指定用户入账: ID=%s, 用户ID=%s, 金额=%s, 管理员=%s", id, userID, deposit.Amount.String(), adminID)
+	return &deposit, nil
+}
+
+// IgnoreUnclaimedDeposit 管理员忽略待归属入账（例如平台内部转账）
+func IgnoreUnclaimedDeposit(id, adminID, note string) (*models.UnclaimedDeposit, error) {
+	result := database.DB.Model(&models.UnclaimedDeposit{}).
+		Where("id = ? AND status = ?", id, "unclaimed").
+		Updates(map[string]interface{}{
+			"status":     "ignored",
+			"handled_by": adminID,
+			"handled_at": time.Now(),
+			"note":       truncate(note, 255),
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	var unclaimed models.UnclaimedDeposit
+	if err := database.DB.Where("id = ?", id).First(&unclaimed).Error; err != nil {
+		return nil, err
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrUnclaimedDepositHandled
+	}
+	return &unclaimed, nil
+}
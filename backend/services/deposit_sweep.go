@@ -0,0 +1,409 @@
+package services
+
+import (
+	"cmp"
+	"errors"
+	"expchange-backend/database"
+	"expchange-backend/models"
+	"expchange-backend/pkg/signer"
+	"fmt"
+	"log"
+	"math/big"
+	"slices"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/shopspring/decimal"
+)
+
+// ERC20 balanceOf ABI
+const balanceOfABI = `[{"constant":true,"inputs":[{"name":"owner","type":"address"}],"name":"balanceOf","outputs":[{"name":"","type":"uint256"}],"type":"function"}]`
+
+// errSweepNeedsGas 充值地址原生币不足以支付代币转出的 gas
+type errSweepNeedsGas struct {
+	missing *big.Int
+}
+
+func (e *errSweepNeedsGas) Error() string {
+	return fmt.Sprintf("deposit address needs %s wei more for gas", e.missing)
+}
+
+// GetDepositSweepMinAmount 单个充值地址的最小归集金额（低于该金额不归集，避免 gas 成本高于归集金额）
+// USDT 使用 deposit.sweep.min_amount，其他资产使用该资产的最小充值额
+func GetDepositSweepMinAmount(token *models.ChainToken) decimal.Decimal {
+	if token.Asset != usdtAsset {
+		return token.MinDepositAmount
+	}
+	amount, err := decimal.NewFromString(database.GetSystemConfigManager().Get("deposit.sweep.min_amount", "10"))
+	if err != nil || amount.IsNegative() {
+		return decimal.Zero
+	}
+	return amount
+}
+
+// SweepDepositAddresses 把用户充值地址中开放充值的资产归集到各链提现热钱包（由归集任务调用，每次推进一步）
+//   - 无进行中的归集：按资产依次检查余额（原生币最后），达到最小归集金额时发起；
+//     代币归集时原生币不足以支付 gas 先由热钱包补充（gas_pending），原生币归集转出扣除 gas 后的余额
+//   - 同一充值地址同时只有一笔进行中的归集
+//   - gas_pending：补充 gas 的交易打包后转出代币（broadcast）
+//   - broadcast：代币转出交易达到链配置的确认数后完成
+//
+// 返回本次新建或状态有变化的归集记录
+func (p *WithdrawProcessor) SweepDepositAddresses(taskID string) ([]models.DepositSweep, error) {
+	vault, err := GetWalletKeyVault()
+	if err != nil {
+		return nil, err
+	}
+
+	var chains []models.ChainConfig
+	database.DB.Where("enabled = ? AND deposit_xpub <> ? AND deposit_sweep_key <> ?", true, "", "").Find(&chains)
+
+	var sweeps []models.DepositSweep
+	var errs []error
+	for i := range chains {
+		chainSweeps, err := p.sweepChain(vault, &chains[i], taskID)
+		sweeps = append(sweeps, chainSweeps...)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("chain %s: %w", chains[i].ChainName, err))
+		}
+	}
+	return sweeps, errors.Join(errs...)
+}
+
+func (p *WithdrawProcessor) sweepChain(vault *WalletKeyVault, chain *models.ChainConfig, taskID string) ([]models.DepositSweep, error) {
+	tokens := DepositTokens(chain.ChainID)
+	if len(tokens) == 0 {
+		return nil, nil
+	}
+	// 代币归集可能需要补充 gas，原生币放在最后归集
+	slices.SortStableFunc(tokens, func(a, b models.ChainToken) int {
+		return cmp.Compare(boolToInt(a.IsNative()), boolToInt(b.IsNative()))
+	})
+
+	hotSigner, err := vault.SignerForChain(chain)
+	if err != nil {
+		return nil, fmt.Errorf("hot wallet signer: %w", err)
+	}
+
+	// 同一轮归集固定一个节点：确认跟踪先查 nonce 再查回执，必须读取同一个区块视图
+	client, err := ChainSession(chain)
+	if err != nil {
+		return nil, err
+	}
+
+	var addresses []models.DepositAddress
+	database.DB.Where("chain_id = ?", chain.ChainID).Order("derivation_index ASC").Find(&addresses)
+
+	var sweeps []models.DepositSweep
+	for i := range addresses {
+		sweep, err := p.sweepAddress(client, vault, chain, hotSigner, &addresses[i], tokens, taskID)
+		if err != nil {
+			log.Printf("⚠️  充值地址归集失败: Chain=%s, Address=%s, err=%v", chain.ChainName, addresses[i].Address, err)
+		}
+		if sweep != nil {
+			sweeps = append(sweeps, *sweep)
+		}
+	}
+	return sweeps, nil
+}
+
+// sweepAddress 推进单个充值地址的归集
+func (p *WithdrawProcessor) sweepAddress(
+	client *ethclient.Client,
+	vault *WalletKeyVault,
+	chain *models.ChainConfig,
+	hotSigner signer.Signer,
+	address *models.DepositAddress,
+	tokens []models.ChainToken,
+	taskID string,
+) (*models.DepositSweep, error) {
+	var sweep models.DepositSweep
+	err := database.DB.Where("chain_id = ? AND deposit_address = ? AND status IN ?",
+		chain.ChainID, address.Address, []string{"gas_pending", "broadcast"}).
+		Order("created_at DESC").First(&sweep).Error
+	if err == nil {
+		return p.advanceSweep(client, vault, chain, hotSigner, address, &sweep)
+	}
+
+	for i := range tokens {
+		token := &tokens[i]
+		balance, err := p.assetBalance(client, token, common.HexToAddress(address.Address))
+		if err != nil {
+			return nil, err
+		}
+		amount := decimal.NewFromBigInt(balance, -int32(token.Decimals))
+		if !amount.IsPositive() || amount.LessThan(GetDepositSweepMinAmount(token)) {
+			continue
+		}
+
+		sweep = models.DepositSweep{
+			TaskID:         taskID,
+			ChainID:        chain.ChainID,
+			Chain:          chain.ChainName,
+			Asset:          token.Asset,
+			UserID:         address.UserID,
+			DepositAddress: address.Address,
+			ToAddress:      hotSigner.Address().Hex(),
+			Amount:         amount,
+			Status:         "gas_pending",
+		}
+		if err := database.DB.Create(&sweep).Error; err != nil {
+			return nil, err
+		}
+		return &sweep, p.sendSweep(client, vault, chain, token, hotSigner, address, &sweep)
+	}
+	return nil, nil
+}
+
+// advanceSweep 检查进行中归集的链上状态
+func (p *WithdrawProcessor) advanceSweep(
+	client *ethclient.Client,
+	vault *WalletKeyVault,
+	chain *models.ChainConfig,
+	hotSigner signer.Signer,
+	address *models.DepositAddress,
+	sweep *models.DepositSweep,
+) (*models.DepositSweep, error) {
+	if sweep.Status == "gas_pending" {
+		result, err := p.checkTx(client, hotSigner.Address().Hex(), sweep.GasNonce, []string{sweep.GasTxHash}, 1)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case result.Receipt == nil && result.NonceConsumed:
+			return sweep, p.failSweep(sweep, "gas top-up dropped: nonce consumed by another transaction")
+		case result.Receipt == nil:
+			return nil, nil
+		case result.Receipt.Status != 1:
+			return sweep, p.failSweep(sweep, "gas top-up transaction reverted")
+		}
+		token, err := GetChainToken(chain.ChainID, sweep.Asset)
+		if err != nil {
+			return sweep, p.failSweep(sweep, err.Error())
+		}
+		return sweep, p.sendSweep(client, vault, chain, token, hotSigner, address, sweep)
+	}
+
+	result, err := p.checkTx(client, sweep.DepositAddress, sweep.Nonce, []string{sweep.TxHash}, requiredConfirmations(chain))
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case result.Receipt == nil && result.NonceConsumed:
+		return sweep, p.failSweep(sweep, "sweep transaction dropped: nonce consumed by another transaction")
+	case result.Receipt == nil:
+		return nil, nil
+	case result.Receipt.Status != 1:
+		return sweep, p.failSweep(sweep, "sweep transaction reverted")
+	case result.Confirmations < requiredConfirmations(chain):
+		return nil, nil
+	}
+
+	sweep.Status = "completed"
+	sweep.BlockNumber = result.BlockNumber
+	if err := database.DB.Model(&models.DepositSweep{}).Where("id = ?", sweep.ID).Updates(map[string]interface{}{
+		"status":       sweep.Status,
+		"block_number": sweep.BlockNumber,
+	}).Error; err != nil {
+		return nil, err
+	}
+	log.Printf("✅ 充值地址归集完成: Chain=%s, Address=%s, Amount=%s %s, TxHash=%s",
+		chain.ChainName, sweep.DepositAddress, sweep.Amount.String(), sweep.Asset, sweep.TxHash)
+	return sweep, nil
+}
+
+// sendSweep 原生币足够时从充值地址转出全部代币，否则由热钱包补充差额
+func (p *WithdrawProcessor) sendSweep(
+	client *ethclient.Client,
+	vault *WalletKeyVault,
+	chain *models.ChainConfig,
+	token *models.ChainToken,
+	hotSigner signer.Signer,
+	address *models.DepositAddress,
+	sweep *models.DepositSweep,
+) error {
+	depositSigner, err := vault.DepositAddressSigner(chain, address)
+	if err != nil {
+		return p.failSweep(sweep, err.Error())
+	}
+
+	err = p.sendSweepTransfer(client, chain, token, depositSigner, sweep)
+	var needsGas *errSweepNeedsGas
+	if errors.As(err, &needsGas) {
+		if err := p.topUpSweepGas(client, chain, hotSigner, sweep, needsGas.missing); err != nil {
+			return p.failSweep(sweep, fmt.Sprintf("gas top-up failed: %v", err))
+		}
+		return nil
+	}
+	if err != nil {
+		return p.failSweep(sweep, err.Error())
+	}
+	return nil
+}
+
+// sendSweepTransfer 以充值地址私钥签名，把链上全部余额转到热钱包（原生币扣除 gas 后转出）
+func (p *WithdrawProcessor) sendSweepTransfer(client *ethclient.Client, chain *models.ChainConfig, token *models.ChainToken, depositSigner signer.Signer, sweep *models.DepositSweep) error {
+	from := depositSigner.Address()
+	native, err := client.BalanceAt(p.ctx, from, nil)
+	if err != nil {
+		return fmt.Errorf("failed to get native balance: %w", err)
+	}
+
+	var (
+		to     common.Address
+		value  *big.Int
+		data   []byte
+		amount decimal.Decimal
+		params *WithdrawTxParams
+	)
+	if token.IsNative() {
+		to = common.HexToAddress(sweep.ToAddress)
+		if params, err = SuggestWithdrawTxParams(p.ctx, client, chain, from, to, nil, nil); err != nil {
+			return err
+		}
+		value = new(big.Int).Sub(native, params.MaxFee())
+		if value.Sign() <= 0 {
+			return errors.New("deposit address balance does not cover the gas fee")
+		}
+		amount = decimal.NewFromBigInt(value, -int32(token.Decimals))
+	} else {
+		balance, err := p.tokenBalance(client, common.HexToAddress(token.ContractAddress), from)
+		if err != nil {
+			return err
+		}
+		if balance.Sign() == 0 {
+			return errors.New("deposit address token balance is zero")
+		}
+		amount = decimal.NewFromBigInt(balance, -int32(token.Decimals))
+
+		if to, value, data, err = transferCall(token, sweep.ToAddress, amount); err != nil {
+			return err
+		}
+		if params, err = SuggestWithdrawTxParams(p.ctx, client, chain, from, to, value, data); err != nil {
+			return err
+		}
+		if fee := params.MaxFee(); native.Cmp(fee) < 0 {
+			return &errSweepNeedsGas{missing: new(big.Int).Sub(fee, native)}
+		}
+	}
+
+	// 充值地址只会发送归集交易，直接使用链上 pending nonce
+	nonce, err := client.PendingNonceAt(p.ctx, from)
+	if err != nil {
+		return fmt.Errorf("failed to get nonce: %w", err)
+	}
+	signedTx, err := p.signAndSend(client, depositSigner, chain, to, value, nonce, params, data)
+	if err != nil {
+		return err
+	}
+
+	sweep.Status = "broadcast"
+	sweep.Amount = amount
+	sweep.Nonce = nonce
+	sweep.TxHash = signedTx.Hash().Hex()
+	if err := database.DB.Model(&models.DepositSweep{}).Where("id = ?", sweep.ID).Updates(map[string]interface{}{
+		"status":  sweep.Status,
+		"amount":  sweep.Amount,
+		"nonce":   sweep.Nonce,
+		"tx_hash": sweep.TxHash,
+	}).Error; err != nil {
+		return err
+	}
+	log.Printf("🧹 充值地址归集已广播: Chain=%s, Address=%s, Amount=%s %s, TxHash=%s",
+		chain.ChainName, sweep.DepositAddress, amount.String(), sweep.Asset, sweep.TxHash)
+	return nil
+}
+
+// topUpSweepGas 从热钱包向充值地址转入支付归集 gas 所需的原生币
+func (p *WithdrawProcessor) topUpSweepGas(client *ethclient.Client, chain *models.ChainConfig, hotSigner signer.Signer, sweep *models.DepositSweep, missing *big.Int) error {
+	to := common.HexToAddress(sweep.DepositAddress)
+	params, err := SuggestWithdrawTxParams(p.ctx, client, chain, hotSigner.Address(), to, missing, nil)
+	if err != nil {
+		return err
+	}
+
+	lease, err := p.nonceManager.AcquireNonce(client, hotSigner.Address().Hex(), chain.ChainID)
+	if err != nil {
+		return fmt.Errorf("failed to acquire nonce: %w", err)
+	}
+	defer lease.Rollback()
+
+	signedTx, err := p.signAndSend(client, hotSigner, chain, to, missing, lease.Nonce, params, nil)
+	if err != nil {
+		return err
+	}
+	if err := lease.Commit(); err != nil {
+		log.Printf("⚠️  保存 nonce 失败（内存已更新）: %v", err)
+	}
+
+	sweep.GasNonce = signedTx.Nonce()
+	sweep.GasTxHash = signedTx.Hash().Hex()
+	sweep.GasAmount = sweep.GasAmount.Add(decimal.NewFromBigInt(missing, -18))
+	if err := database.DB.Model(&models.DepositSweep{}).Where("id = ?", sweep.ID).Updates(map[string]interface{}{
+		"gas_nonce":   sweep.GasNonce,
+		"gas_tx_hash": sweep.GasTxHash,
+		"gas_amount":  sweep.GasAmount,
+	}).Error; err != nil {
+		return err
+	}
+	log.Printf("⛽ 已为充值地址补充归集 gas: Chain=%s, Address=%s, Amount=%s wei, TxHash=%s",
+		chain.ChainName, sweep.DepositAddress, missing.String(), sweep.GasTxHash)
+	return nil
+}
+
+// failSweep 标记归集失败（下次归集任务会重新发起）
+func (p *WithdrawProcessor) failSweep(sweep *models.DepositSweep, reason string) error {
+	sweep.Status = "failed"
+	sweep.Reason = truncate(reason, 500)
+	if err := database.DB.Model(&models.DepositSweep{}).Where("id = ?", sweep.ID).Updates(map[string]interface{}{
+		"status": sweep.Status,
+		"reason": sweep.Reason,
+	}).Error; err != nil {
+		return err
+	}
+	log.Printf("❌ 充值地址归集失败: Chain=%s, Address=%s, 原因=%s", sweep.Chain, sweep.DepositAddress, reason)
+	return nil
+}
+
+// assetBalance 查询资产余额（原生币或 ERC20，链上最小单位）
+func (p *WithdrawProcessor) assetBalance(client *ethclient.Client, token *models.ChainToken, owner common.Address) (*big.Int, error) {
+	if token.IsNative() {
+		balance, err := client.BalanceAt(p.ctx, owner, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get native balance: %w", err)
+		}
+		return balance, nil
+	}
+	return p.tokenBalance(client, common.HexToAddress(token.ContractAddress), owner)
+}
+
+// tokenBalance 查询 ERC20 余额（链上最小单位）
+func (p *WithdrawProcessor) tokenBalance(client *ethclient.Client, token, owner common.Address) (*big.Int, error) {
+	parsedABI, err := abi.JSON(strings.NewReader(balanceOfABI))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse balanceOf ABI: %w", err)
+	}
+	data, err := parsedABI.Pack("balanceOf", owner)
+	if err != nil {
+		return nil, err
+	}
+	output, err := client.CallContract(p.ctx, ethereum.CallMsg{To: &token, Data: data}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call balanceOf: %w", err)
+	}
+	values, err := parsedABI.Unpack("balanceOf", output)
+	if err != nil || len(values) != 1 {
+		return nil, fmt.Errorf("failed to unpack balanceOf: %v", err)
+	}
+	return values[0].(*big.Int), nil
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -7,18 +7,38 @@ import (
 	"fmt"
 	"log"
 	"math/big"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
-	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
 )
 
 // ERC20 Transfer 事件签名: Transfer(address,address,uint256)
 var transferEventSignature = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
 
+// RetryLater 充值暂时无法完成验证（交易未上链、等待确认、RPC 故障等），由任务队列按计划重新执行
+type RetryLater struct {
+	Reason string
+	After  time.Duration // 建议的重试间隔，0 表示由任务队列按重试次数退避
+}
+
+func (e *RetryLater) Error() string {
+	return "RETRY_LATER: " + e.Reason
+}
+
+// RequiredDepositConfirmations 链上充值入账所需确认数（至少 1）
+func RequiredDepositConfirmations(chain *models.ChainConfig) int {
+	if chain.RequiredConfirmations < 1 {
+		return 1
+	}
+	return chain.RequiredConfirmations
+}
+
 // DepositVerifier 充值验证服务（支持多链）
 type DepositVerifier struct {
 	ctx context.Context
@@ -33,10 +53,14 @@ func NewDepositVerifier() (*DepositVerifier, error) {
 }
 
 // VerifyDeposit 验证单个充值记录（支持多链）
-// 返回 error 表示需要重试，nil 表示验证完成（成功或失败）
+// 返回 *RetryLater 表示需要稍后重试（打包后未达到确认数时记录变为 confirming），nil 表示验证完成（成功或失败）
 func (v *DepositVerifier) VerifyDeposit(deposit *models.DepositRecord) error {
 	log.Printf("🔍 验证充值: ID=%s, Chain=%s(%d), Hash=%s, Amount=%s",
 		deposit.ID, deposit.Chain, deposit.ChainID, deposit.TxHash, deposit.Amount.String())
+	if deposit.Status != "pending" && deposit.Status != "confirming" {
+		log.Printf("⏭️  充值记录已处理（%s），跳过验证: ID=%s", deposit.Status, deposit.ID)
+		return nil
+	}
 
 	// 1. 获取链配置
 	var chainConfig models.ChainConfig
@@ -45,14 +69,26 @@ func (v *DepositVerifier) VerifyDeposit(deposit *models.DepositRecord) error {
 		v.MarkDepositFailed(deposit, fmt.Sprintf("Chain %d not found or disabled", deposit.ChainID))
 		return nil // 不重试
 	}
+	token, err := GetChainToken(deposit.ChainID, deposit.Asset)
+	if err == nil && !token.DepositEnabled {
+		err = ErrAssetDepositDisabled
+	}
+	if err == nil && deposit.Amount.LessThan(token.MinDepositAmount) {
+		err = fmt.Errorf("%w: %s %s", ErrDepositBelowMinimum, token.MinDepositAmount.String(), token.Asset)
+	}
+	if err != nil {
+		log.Printf("❌ 充值资产校验失败: Chain=%s, Asset=%s, err=%v", chainConfig.ChainName, deposit.Asset, err)
+		v.MarkDepositFailed(deposit, err.Error())
+		return nil // 不重试
+	}
 
 	// 2. 连接到对应的链
-	client, err := ethclient.Dial(chainConfig.RpcURL)
+	// 回执、区块高度和区块哈希需从同一节点读取
+	client, err := ChainSession(&chainConfig)
 	if err != nil {
 		log.Printf("❌ 连接RPC失败 (%s): %v", chainConfig.ChainName, err)
-		return fmt.Errorf("RETRY_LATER: RPC connection failed") // 重试
+		return &RetryLater{Reason: "RPC connection failed"} // 重试
 	}
-	defer client.Close()
 
 	// 3. 获取交易收据
 	txHash := common.HexToHash(deposit.TxHash)
@@ -60,9 +96,18 @@ func (v *DepositVerifier) VerifyDeposit(deposit *models.DepositRecord) error {
 	if err != nil {
 		// 如果是交易未找到，返回特殊错误让任务队列重试
 		if strings.Contains(err.Error(), "not found") {
+			// 已打包的交易又找不到：所在区块被重组掉，回到待验证状态等待重新打包
+			if deposit.Status == "confirming" {
+				v.resetConfirming(deposit, "transaction no longer found")
+			}
+			timeout := time.Duration(database.GetSystemConfigManager().GetInt("deposit.verify.pending_timeout_hours", 24)) * time.Hour
+			if time.Since(deposit.CreatedAt) > timeout {
+				log.Printf("❌ 交易超时仍未上链: %s", deposit.TxHash)
+				v.MarkDepositFailed(deposit, fmt.Sprintf("Transaction not found within %s", timeout))
+				return nil // 不重试
+			}
 			log.Printf("⏳ 交易还未上链，稍后重试: %s", deposit.TxHash)
-			// 返回特殊错误，任务队列会识别并重试
-			return fmt.Errorf("RETRY_LATER: transaction not found yet")
+			return &RetryLater{Reason: "transaction not found yet"}
 		}
 		log.Printf("❌ 获取交易收据失败: %v", err)
 		v.MarkDepositFailed(deposit, fmt.Sprintf("Failed to get receipt: %v", err))
@@ -76,94 +121,273 @@ func (v *DepositVerifier) VerifyDeposit(deposit *models.DepositRecord) error {
 		return nil // 不重试
 	}
 
-	// 5. 获取交易详情
-	tx, isPending, err := client.TransactionByHash(v.ctx, txHash)
+	// 归集时热钱包补充 gas 的交易不是用户充值
+	var sweepCount int64
+	database.DB.Model(&models.DepositSweep{}).Where("gas_tx_hash = ?", strings.ToLower(txHash.Hex())).Count(&sweepCount)
+	if sweepCount > 0 {
+		log.Printf("❌ 交易是归集补充 gas 的交易，不能作为充值: %s", deposit.TxHash)
+		v.MarkDepositFailed(deposit, "Transaction is a sweep gas top-up")
+		return nil // 不重试
+	}
+
+	// 5. 核对转出地址、接收地址和金额
+	//    ERC20 按 Transfer 事件核对（不依赖 tx.To()，支持经路由合约、合约钱包转入）；原生币按交易的 to 和 value 核对
+	var user models.User
+	if err := database.DB.Where("id = ?", deposit.UserID).First(&user).Error; err != nil {
+		log.Printf("❌ 获取充值用户失败: %v", err)
+		return &RetryLater{Reason: "failed to load user"}
+	}
+	rule := newDepositTransferRule(&chainConfig, token, &user)
+
+	txSender := func() (common.Address, *types.Transaction, error) {
+		tx, _, err := client.TransactionByHash(v.ctx, txHash)
+		if err != nil {
+			return common.Address{}, nil, err
+		}
+		sender, err := types.Sender(types.LatestSignerForChainID(big.NewInt(int64(chainConfig.ChainID))), tx)
+		return sender, tx, err
+	}
+	var match *depositTransferMatch
+	if token.IsNative() {
+		var sender common.Address
+		var tx *types.Transaction
+		if sender, tx, err = txSender(); err == nil {
+			match = rule.matchNative(tx, sender)
+		}
+	} else {
+		match, err = rule.match(receipt.Logs, func() (common.Address, error) {
+			sender, _, err := txSender()
+			return sender, err
+		})
+	}
 	if err != nil {
-		log.Printf("❌ 获取交易详情失败: %v", err)
-		return fmt.Errorf("RETRY_LATER: failed to get transaction") // 重试
+		log.Printf("❌ 获取交易发起地址失败: %v", err)
+		return &RetryLater{Reason: "failed to get transaction sender"} // 重试
 	}
 
-	if isPending {
-		log.Printf("⏳ 交易还在pending，稍后重试: %s", deposit.TxHash)
-		return fmt.Errorf("RETRY_LATER: transaction pending") // 重试
+	if match.value.Sign() == 0 {
+		if match.foreignSenders > 0 {
+			log.Printf("❌ 转出地址与用户钱包不匹配: 用户钱包=%s, TxHash=%s", user.WalletAddress, deposit.TxHash)
+			v.MarkDepositFailed(deposit, "Transfer sender does not match user wallet")
+			return nil // 不重试
+		}
+		log.Printf("❌ 未找到转入充值地址的有效转账")
+		v.MarkDepositFailed(deposit, "No valid transfer event found")
+		return nil // 不重试
 	}
 
-	// 6. 验证接收合约地址（必须是USDT合约）
-	toAddress := strings.ToLower(tx.To().Hex())
-	expectedContract := strings.ToLower(chainConfig.UsdtContractAddress)
+	// 6. 金额按资产精度精确匹配（同一交易中多笔转入合并计算）
+	actualAmount := decimal.NewFromBigInt(match.value, -int32(token.Decimals))
+	log.Printf("🔍 解析到转账: from=%s, amount=%s %s (%d 笔)", match.from, actualAmount.String(), token.Asset, match.count)
 
-	if toAddress != expectedContract {
-		log.Printf("❌ 合约地址不匹配: got %s, want %s", toAddress, expectedContract)
-		v.MarkDepositFailed(deposit, "Invalid contract address")
+	if !depositAmountMatches(deposit.Amount, token.Decimals, match.value) {
+		log.Printf("❌ 金额不匹配: got %s, want %s", actualAmount.String(), deposit.Amount.String())
+		v.MarkDepositFailed(deposit, fmt.Sprintf("Amount mismatch: got %s, want %s", actualAmount.String(), deposit.Amount.String()))
 		return nil // 不重试
 	}
+	deposit.FromAddress = match.from
+	if deposit.BlockHash != "" && deposit.BlockHash != receipt.BlockHash.Hex() {
+		log.Printf("⚠️  充值交易所在区块已变化（链重组），重新计算确认数: TxHash=%s, Block=%d -> %d",
+			deposit.TxHash, deposit.BlockNumber, receipt.BlockNumber.Uint64())
+	}
+	deposit.BlockNumber = receipt.BlockNumber.Uint64()
+	deposit.BlockHash = receipt.BlockHash.Hex()
+	deposit.RequiredConfirmations = RequiredDepositConfirmations(&chainConfig)
 
-	// 7. 解析 Transfer 事件验证金额和目标地址
-	transferFound := false
-	for _, vLog := range receipt.Logs {
-		// 检查是否是 Transfer 事件
-		if len(vLog.Topics) != 3 || vLog.Topics[0] != transferEventSignature {
-			continue
+	// 7. 确认区块数（交易所在区块计为 1 个确认）
+	currentBlock, err := client.BlockNumber(v.ctx)
+	if err != nil {
+		log.Printf("❌ 获取当前区块失败: %v", err)
+		return &RetryLater{Reason: "failed to get block number"} // 重试
+	}
+	deposit.Confirmations = 0
+	if currentBlock >= deposit.BlockNumber {
+		deposit.Confirmations = int(currentBlock - deposit.BlockNumber + 1)
+	}
+	if deposit.Confirmations < deposit.RequiredConfirmations {
+		v.markConfirming(deposit)
+		interval := database.GetSystemConfigManager().GetInt("deposit.verify.retry_seconds", 15)
+		return &RetryLater{
+			Reason: fmt.Sprintf("waiting for confirmations %d/%d", deposit.Confirmations, deposit.RequiredConfirmations),
+			After:  time.Duration(max(interval, 1)) * time.Second,
 		}
+	}
 
-		// 解析 Transfer 事件: Transfer(from, to, value)
-		// Topics[0] = event signature
-		// Topics[1] = from address
-		// Topics[2] = to address
-		// Data = value (amount)
-
-		toAddr := common.HexToAddress(vLog.Topics[2].Hex())
-		platformAddr := common.HexToAddress(chainConfig.PlatformDepositAddress)
+	// 8. 入账前核对交易所在区块仍在主链上
+	header, err := client.HeaderByNumber(v.ctx, receipt.BlockNumber)
+	if err != nil {
+		log.Printf("❌ 获取区块头失败: %v", err)
+		return &RetryLater{Reason: "failed to get block header"} // 重试
+	}
+	if header.Hash() != receipt.BlockHash {
+		log.Printf("⚠️  充值交易所在区块已不在主链上（链重组），重新等待确认: TxHash=%s, Block=%d, Hash=%s -> %s",
+			deposit.TxHash, deposit.BlockNumber, deposit.BlockHash, header.Hash().Hex())
+		v.resetConfirming(deposit, "block reorganized")
+		return &RetryLater{Reason: "block reorganized"}
+	}
 
-		// 检查接收地址是否是平台地址
-		if strings.ToLower(toAddr.Hex()) != strings.ToLower(platformAddr.Hex()) {
-			continue
-		}
+	// 9. 充值成功，增加用户余额
+	log.Printf("✅ 充值验证成功: Chain=%s, TxHash=%s, Confirmations=%d/%d",
+		chainConfig.ChainName, deposit.TxHash, deposit.Confirmations, deposit.RequiredConfirmations)
+	v.ConfirmDeposit(deposit)
+	return nil // 验证完成
+}
 
-		// 解析转账金额
-		amount := new(big.Int).SetBytes(vLog.Data)
+// markConfirming 交易已打包但确认数不足：记录为 confirming 并更新确认进度
+func (v *DepositVerifier) markConfirming(deposit *models.DepositRecord) {
+	if deposit.Status != "confirming" {
+		log.Printf("⏳ 充值交易已打包，等待确认: TxHash=%s, Block=%d, %d/%d",
+			deposit.TxHash, deposit.BlockNumber, deposit.Confirmations, deposit.RequiredConfirmations)
+	}
+	deposit.Status = "confirming"
+	if err := database.DB.Model(&models.DepositRecord{}).
+		Where("id = ? AND status IN ?", deposit.ID, []string{"pending", "confirming"}).
+		Updates(map[string]interface{}{
+			"status":                 "confirming",
+			"from_address":           deposit.FromAddress,
+			"block_number":           deposit.BlockNumber,
+			"block_hash":             deposit.BlockHash,
+			"confirmations":          deposit.Confirmations,
+			"required_confirmations": deposit.RequiredConfirmations,
+		}).Error; err != nil {
+		log.Printf("❌ 更新充值确认进度失败: %v", err)
+	}
+}
 
-		// 转换为 decimal，使用链配置的精度
-		actualAmount := decimal.NewFromBigInt(amount, -int32(chainConfig.UsdtDecimals))
+// resetConfirming 交易所在区块被重组：回到 pending，清空区块和确认进度
+func (v *DepositVerifier) resetConfirming(deposit *models.DepositRecord, reason string) {
+	log.Printf("⚠️  充值确认进度已重置: TxHash=%s, 原因=%s", deposit.TxHash, reason)
+	deposit.Status = "pending"
+	deposit.BlockNumber = 0
+	deposit.BlockHash = ""
+	deposit.Confirmations = 0
+	if err := database.DB.Model(&models.DepositRecord{}).
+		Where("id = ? AND status = ?", deposit.ID, "confirming").
+		Updates(map[string]interface{}{
+			"status":        "pending",
+			"block_number":  0,
+			"block_hash":    "",
+			"confirmations": 0,
+		}).Error; err != nil {
+		log.Printf("❌ 重置充值确认进度失败: %v", err)
+	}
+}
 
-		log.Printf("🔍 解析到Transfer: to=%s, amount=%s USDT", toAddr.Hex(), actualAmount.String())
+// depositTransferRule 用户提交的充值交易中哪些转账可以记入该用户
+type depositTransferRule struct {
+	token          common.Address
+	receivers      []string // 平台共享收款地址和用户专属充值地址（小写）
+	depositAddress string   // 用户专属充值地址：转入该地址的不再校验转出地址
+	wallet         string   // 用户登录钱包（小写）
+	requireSender  bool
+	routers        []string // 中转合约：由这些地址转出时改为校验交易发起地址
+}
 
-		// 验证金额是否匹配（允许小数点后6位的误差）
-		expectedAmount := deposit.Amount
-		if !actualAmount.Round(6).Equal(expectedAmount.Round(6)) {
-			log.Printf("❌ 金额不匹配: got %s, want %s", actualAmount.String(), expectedAmount.String())
-			v.MarkDepositFailed(deposit, fmt.Sprintf("Amount mismatch: got %s, want %s", actualAmount.String(), expectedAmount.String()))
-			return nil // 不重试
-		}
+// depositTransferMatch 符合规则的 Transfer 汇总
+type depositTransferMatch struct {
+	value          *big.Int
+	count          int
+	from           string // 第一笔符合规则的转出地址
+	foreignSenders int    // 转入充值地址但转出地址不属于该用户的笔数
+}
 
-		transferFound = true
-		break
+func newDepositTransferRule(chain *models.ChainConfig, token *models.ChainToken, user *models.User) *depositTransferRule {
+	sysConfig := database.GetSystemConfigManager()
+	rule := &depositTransferRule{
+		token:         common.HexToAddress(token.ContractAddress),
+		receivers:     []string{strings.ToLower(common.HexToAddress(chain.PlatformDepositAddress).Hex())},
+		wallet:        strings.ToLower(user.WalletAddress),
+		requireSender: sysConfig.GetBool("deposit.verify.require_sender", true),
+		routers:       parseAddressList(sysConfig.Get("deposit.verify.router_addresses", "")),
 	}
 
-	if !transferFound {
-		log.Printf("❌ 未找到有效的Transfer事件到平台地址")
-		v.MarkDepositFailed(deposit, "No valid transfer event found")
-		return nil // 不重试
+	var address models.DepositAddress
+	if err := database.DB.Where("user_id = ? AND chain_id = ?", user.ID, chain.ChainID).First(&address).Error; err == nil {
+		rule.depositAddress = address.Address
+		rule.receivers = append(rule.receivers, address.Address)
 	}
+	return rule
+}
 
-	// 8. 确认区块数（至少1个确认）
-	currentBlock, err := client.BlockNumber(v.ctx)
-	if err != nil {
-		log.Printf("❌ 获取当前区块失败: %v", err)
-		return fmt.Errorf("RETRY_LATER: failed to get block number") // 重试
+// match 汇总收据中可以记入该用户的代币转入；txSender 仅在转出地址是中转合约时调用
+func (r *depositTransferRule) match(logs []*types.Log, txSender func() (common.Address, error)) (*depositTransferMatch, error) {
+	result := &depositTransferMatch{value: new(big.Int)}
+	var sender string
+
+	for _, vLog := range logs {
+		// 只认代币合约发出的 Transfer(from, to, value) 事件
+		if vLog.Address != r.token || len(vLog.Topics) != 3 || vLog.Topics[0] != transferEventSignature {
+			continue
+		}
+		from := strings.ToLower(common.BytesToAddress(vLog.Topics[1].Bytes()).Hex())
+		to := strings.ToLower(common.BytesToAddress(vLog.Topics[2].Bytes()).Hex())
+		if !slices.Contains(r.receivers, to) {
+			continue
+		}
+
+		owned := to == r.depositAddress || !r.requireSender || from == r.wallet
+		if !owned && slices.Contains(r.routers, from) {
+			if sender == "" {
+				address, err := txSender()
+				if err != nil {
+					return nil, err
+				}
+				sender = strings.ToLower(address.Hex())
+			}
+			owned = sender == r.wallet
+		}
+		if !owned {
+			result.foreignSenders++
+			continue
+		}
+
+		result.value.Add(result.value, new(big.Int).SetBytes(vLog.Data))
+		result.count++
+		if result.from == "" {
+			result.from = from
+		}
 	}
+	return result, nil
+}
 
-	confirmations := currentBlock - receipt.BlockNumber.Uint64()
-	if confirmations < 1 {
-		log.Printf("⏳ 等待确认: %d/1，稍后重试", confirmations)
-		return fmt.Errorf("RETRY_LATER: waiting for confirmations") // 重试
+// matchNative 原生币充值只认直接转入充值地址的交易（合约内部转账无法从收据中核对）
+func (r *depositTransferRule) matchNative(tx *types.Transaction, sender common.Address) *depositTransferMatch {
+	result := &depositTransferMatch{value: new(big.Int)}
+	if tx.To() == nil || tx.Value().Sign() == 0 {
+		return result
+	}
+	from := strings.ToLower(sender.Hex())
+	to := strings.ToLower(tx.To().Hex())
+	if !slices.Contains(r.receivers, to) {
+		return result
+	}
+	if to != r.depositAddress && r.requireSender && from != r.wallet {
+		result.foreignSenders++
+		return result
 	}
 
-	// 9. 充值成功，增加用户余额
-	log.Printf("✅ 充值验证成功: Chain=%s, TxHash=%s, Confirmations=%d",
-		chainConfig.ChainName, deposit.TxHash, confirmations)
-	v.ConfirmDeposit(deposit)
-	return nil // 验证完成
+	result.value.Set(tx.Value())
+	result.count = 1
+	result.from = from
+	return result
+}
+
+// depositAmountMatches 申报金额按资产精度换算后与链上最小单位金额完全相等（超出精度的小数视为不匹配）
+func depositAmountMatches(amount decimal.Decimal, decimals int, value *big.Int) bool {
+	expectedUnits := amount.Shift(int32(decimals))
+	return expectedUnits.IsInteger() && expectedUnits.BigInt().Cmp(value) == 0
+}
+
+// parseAddressList 解析逗号分隔的地址列表（小写，忽略无效地址）
+func parseAddressList(value string) []string {
+	var addresses []string
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if common.IsHexAddress(item) {
+			addresses = append(addresses, strings.ToLower(common.HexToAddress(item).Hex()))
+		}
+	}
+	return addresses
 }
 
 // ConfirmDeposit 确认充值并增加余额
@@ -175,17 +399,49 @@ func (v *DepositVerifier) ConfirmDeposit(deposit *models.DepositRecord) {
 		}
 	}()
 
-	// 1. 更新充值记录状态
-	if err := tx.Model(deposit).Updates(map[string]interface{}{
-		"status":     "confirmed",
-		"updated_at": time.Now(),
-	}).Error; err != nil {
+	// 1. 更新充值记录状态（只处理未入账的记录，防止重复入账）
+	result := tx.Model(&models.DepositRecord{}).
+		Where("id = ? AND status IN ?", deposit.ID, []string{"pending", "confirming"}).
+		Updates(map[string]interface{}{
+			"status":                 "confirmed",
+			"from_address":           deposit.FromAddress,
+			"block_number":           deposit.BlockNumber,
+			"block_hash":             deposit.BlockHash,
+			"confirmations":          deposit.Confirmations,
+			"required_confirmations": deposit.RequiredConfirmations,
+			"updated_at":             time.Now(),
+		})
+	if result.Error != nil {
 		tx.Rollback()
-		log.Printf("❌ 更新充值记录失败: %v", err)
+		log.Printf("❌ 更新充值记录失败: %v", result.Error)
 		return
 	}
+	if result.RowsAffected == 0 {
+		tx.Rollback()
+		log.Printf("⚠️  充值记录已处理，跳过入账: ID=%s, TxHash=%s", deposit.ID, deposit.TxHash)
+		return
+	}
+	deposit.Status = "confirmed"
 
 	// 2. 增加用户余额
+	if err := creditDeposit(tx, deposit); err != nil {
+		tx.Rollback()
+		log.Printf("❌ 充值入账失败: %v", err)
+		return
+	}
+
+	// 提交事务
+	if err := tx.Commit().Error; err != nil {
+		log.Printf("❌ 提交事务失败: %v", err)
+		return
+	}
+
+	log.Printf("🎉 充值已到账: 用户ID=%s, 链=%s, 资产=%s, 金额=%s",
+		deposit.UserID, deposit.Chain, deposit.Asset, deposit.Amount.String())
+}
+
+// creditDeposit 在事务中增加用户余额，并把扫描到的同一笔未归属入账标记为已入账
+func creditDeposit(tx *gorm.DB, deposit *models.DepositRecord) error {
 	var balance models.Balance
 	err := tx.Where("user_id = ? AND asset = ?", deposit.UserID, deposit.Asset).First(&balance).Error
 
@@ -198,36 +454,33 @@ func (v *DepositVerifier) ConfirmDeposit(deposit *models.DepositRecord) {
 			Frozen:    decimal.Zero,
 		}
 		if err := tx.Create(&balance).Error; err != nil {
-			tx.Rollback()
-			log.Printf("❌ 创建余额记录失败: %v", err)
-			return
+			return fmt.Errorf("create balance: %w", err)
 		}
 	} else {
 		// 增加可用余额
 		balance.Available = balance.Available.Add(deposit.Amount)
 		if err := tx.Save(&balance).Error; err != nil {
-			tx.Rollback()
-			log.Printf("❌ 更新余额失败: %v", err)
-			return
+			return fmt.Errorf("update balance: %w", err)
 		}
 	}
 
-	// 提交事务
-	if err := tx.Commit().Error; err != nil {
-		log.Printf("❌ 提交事务失败: %v", err)
-		return
-	}
-
-	log.Printf("🎉 充值已到账: 用户ID=%s, 链=%s, 资产=%s, 金额=%s",
-		deposit.UserID, deposit.Chain, deposit.Asset, deposit.Amount.String())
+	return tx.Model(&models.UnclaimedDeposit{}).
+		Where("tx_hash = ? AND status = ?", deposit.TxHash, "unclaimed").
+		Updates(map[string]interface{}{
+			"status":     "credited",
+			"deposit_id": deposit.ID,
+			"handled_at": time.Now(),
+		}).Error
 }
 
 // MarkDepositFailed 标记充值失败
 func (v *DepositVerifier) MarkDepositFailed(deposit *models.DepositRecord, reason string) {
-	err := database.DB.Model(deposit).Updates(map[string]interface{}{
-		"status":     "failed",
-		"updated_at": time.Now(),
-	}).Error
+	err := database.DB.Model(&models.DepositRecord{}).
+		Where("id = ? AND status IN ?", deposit.ID, []string{"pending", "confirming"}).
+		Updates(map[string]interface{}{
+			"status":     "failed",
+			"updated_at": time.Now(),
+		}).Error
 
 	if err != nil {
 		log.Printf("❌ 标记充值失败: %v", err)
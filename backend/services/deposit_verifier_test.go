@@ -0,0 +1,258 @@
+package services
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/shopspring/decimal"
+)
+
+var (
+	testToken          = common.HexToAddress("0x55d398326f99059ff775485246999027b3197955")
+	testPlatformAddr   = common.HexToAddress("0x00000000000000000000000000000000000000a1")
+	testDepositAddr    = common.HexToAddress("0x00000000000000000000000000000000000000a2")
+	testUserWallet     = common.HexToAddress("0x00000000000000000000000000000000000000b1")
+	testForeignWallet  = common.HexToAddress("0x00000000000000000000000000000000000000b2")
+	testRouterContract = common.HexToAddress("0x00000000000000000000000000000000000000c1")
+)
+
+func lowerAddress(address common.Address) string {
+	return strings.ToLower(address.Hex())
+}
+
+func testTransferRule() *depositTransferRule {
+	return &depositTransferRule{
+		token:          testToken,
+		receivers:      []string{lowerAddress(testPlatformAddr), lowerAddress(testDepositAddr)},
+		depositAddress: lowerAddress(testDepositAddr),
+		wallet:         lowerAddress(testUserWallet),
+		requireSender:  true,
+		routers:        []string{lowerAddress(testRouterContract)},
+	}
+}
+
+func transferLog(token, from, to common.Address, value int64) *types.Log {
+	return &types.Log{
+		Address: token,
+		Topics:  []common.Hash{transferEventSignature, common.BytesToHash(from.Bytes()), common.BytesToHash(to.Bytes())},
+		Data:    common.LeftPadBytes(big.NewInt(value).Bytes(), 32),
+	}
+}
+
+func TestDepositTransferRuleMatch(t *testing.T) {
+	otherToken := common.HexToAddress("0x00000000000000000000000000000000000000d1")
+	approvalSig := common.HexToHash("0x8c5be1e5ebec7d5bd14f71427d1e84f3dd0314c0f7b2291e5b200ac8c7c3b925")
+
+	tests := []struct {
+		name          string
+		logs          []*types.Log
+		requireSender bool
+		txSender      common.Address
+		senderErr     error
+		wantValue     int64
+		wantCount     int
+		wantFrom      common.Address
+		wantForeign   int
+		wantErr       bool
+		wantLookups   int
+	}{
+		{
+			name:          "user wallet to platform address",
+			logs:          []*types.Log{transferLog(testToken, testUserWallet, testPlatformAddr, 100)},
+			requireSender: true,
+			wantValue:     100, wantCount: 1, wantFrom: testUserWallet,
+		},
+		{
+			// 他人转入共享收款地址不能记入该用户
+			name:          "foreign sender to platform address",
+			logs:          []*types.Log{transferLog(testToken, testForeignWallet, testPlatformAddr, 100)},
+			requireSender: true,
+			wantForeign:   1,
+		},
+		{
+			// 专属充值地址只属于该用户，不校验转出地址
+			name:          "foreign sender to user deposit address",
+			logs:          []*types.Log{transferLog(testToken, testForeignWallet, testDepositAddr, 100)},
+			requireSender: true,
+			wantValue:     100, wantCount: 1, wantFrom: testForeignWallet,
+		},
+		{
+			name:          "sender check disabled",
+			logs:          []*types.Log{transferLog(testToken, testForeignWallet, testPlatformAddr, 100)},
+			requireSender: false,
+			wantValue:     100, wantCount: 1, wantFrom: testForeignWallet,
+		},
+		{
+			name: "other token and other receiver ignored",
+			logs: []*types.Log{
+				transferLog(otherToken, testUserWallet, testPlatformAddr, 100),
+				transferLog(testToken, testUserWallet, testForeignWallet, 100),
+			},
+			requireSender: true,
+		},
+		{
+			name: "non-transfer event ignored",
+			logs: []*types.Log{{
+				Address: testToken,
+				Topics:  []common.Hash{approvalSig, common.BytesToHash(testUserWallet.Bytes()), common.BytesToHash(testPlatformAddr.Bytes())},
+				Data:    common.LeftPadBytes(big.NewInt(100).Bytes(), 32),
+			}},
+			requireSender: true,
+		},
+		{
+			// 同一交易中多笔转入合并计算，他人的转入单独计数
+			name: "multiple transfers summed",
+			logs: []*types.Log{
+				transferLog(testToken, testUserWallet, testPlatformAddr, 60),
+				transferLog(testToken, testForeignWallet, testPlatformAddr, 1000),
+				transferLog(testToken, testUserWallet, testPlatformAddr, 40),
+			},
+			requireSender: true,
+			wantValue:     100, wantCount: 2, wantFrom: testUserWallet, wantForeign: 1,
+		},
+		{
+			// 经中转合约转出时按交易发起地址校验，且只查询一次
+			name: "router with user as tx sender",
+			logs: []*types.Log{
+				transferLog(testToken, testRouterContract, testPlatformAddr, 30),
+				transferLog(testToken, testRouterContract, testPlatformAddr, 70),
+			},
+			requireSender: true,
+			txSender:      testUserWallet,
+			wantValue:     100, wantCount: 2, wantFrom: testRouterContract, wantLookups: 1,
+		},
+		{
+			name:          "router with foreign tx sender",
+			logs:          []*types.Log{transferLog(testToken, testRouterContract, testPlatformAddr, 100)},
+			requireSender: true,
+			txSender:      testForeignWallet,
+			wantForeign:   1, wantLookups: 1,
+		},
+		{
+			name:          "router sender lookup error",
+			logs:          []*types.Log{transferLog(testToken, testRouterContract, testPlatformAddr, 100)},
+			requireSender: true,
+			senderErr:     errors.New("rpc unavailable"),
+			wantErr:       true, wantLookups: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := testTransferRule()
+			rule.requireSender = tt.requireSender
+
+			lookups := 0
+			match, err := rule.match(tt.logs, func() (common.Address, error) {
+				lookups++
+				return tt.txSender, tt.senderErr
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("match err=%v, wantErr=%v", err, tt.wantErr)
+			}
+			if lookups != tt.wantLookups {
+				t.Fatalf("tx sender lookups=%d, want %d", lookups, tt.wantLookups)
+			}
+			if err != nil {
+				return
+			}
+			if match.value.Int64() != tt.wantValue || match.count != tt.wantCount || match.foreignSenders != tt.wantForeign {
+				t.Fatalf("match value=%s count=%d foreign=%d, want %d/%d/%d",
+					match.value, match.count, match.foreignSenders, tt.wantValue, tt.wantCount, tt.wantForeign)
+			}
+			wantFrom := ""
+			if tt.wantCount > 0 {
+				wantFrom = lowerAddress(tt.wantFrom)
+			}
+			if match.from != wantFrom {
+				t.Fatalf("match from=%q, want %q", match.from, wantFrom)
+			}
+		})
+	}
+}
+
+func TestDepositTransferRuleMatchNative(t *testing.T) {
+	nativeTx := func(to *common.Address, value int64) *types.Transaction {
+		return types.NewTx(&types.LegacyTx{To: to, Value: big.NewInt(value), Gas: 21000})
+	}
+
+	tests := []struct {
+		name        string
+		tx          *types.Transaction
+		sender      common.Address
+		wantValue   int64
+		wantForeign int
+	}{
+		{name: "user wallet to platform address", tx: nativeTx(&testPlatformAddr, 100), sender: testUserWallet, wantValue: 100},
+		{name: "foreign sender to platform address", tx: nativeTx(&testPlatformAddr, 100), sender: testForeignWallet, wantForeign: 1},
+		{name: "foreign sender to user deposit address", tx: nativeTx(&testDepositAddr, 100), sender: testForeignWallet, wantValue: 100},
+		{name: "other receiver", tx: nativeTx(&testForeignWallet, 100), sender: testUserWallet},
+		{name: "zero value", tx: nativeTx(&testPlatformAddr, 0), sender: testUserWallet},
+		{name: "contract creation", tx: nativeTx(nil, 100), sender: testUserWallet},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			match := testTransferRule().matchNative(tt.tx, tt.sender)
+			if match.value.Int64() != tt.wantValue || match.foreignSenders != tt.wantForeign {
+				t.Fatalf("matchNative value=%s foreign=%d, want %d/%d", match.value, match.foreignSenders, tt.wantValue, tt.wantForeign)
+			}
+			if tt.wantValue > 0 && (match.count != 1 || match.from != lowerAddress(tt.sender)) {
+				t.Fatalf("matchNative count=%d from=%q, want 1/%q", match.count, match.from, lowerAddress(tt.sender))
+			}
+		})
+	}
+}
+
+func TestDepositAmountMatches(t *testing.T) {
+	units := func(s string) *big.Int {
+		v, _ := new(big.Int).SetString(s, 10)
+		return v
+	}
+
+	tests := []struct {
+		name     string
+		amount   string
+		decimals int
+		value    *big.Int
+		want     bool
+	}{
+		{name: "exact 18 decimals", amount: "1.5", decimals: 18, value: units("1500000000000000000"), want: true},
+		{name: "exact 6 decimals", amount: "100", decimals: 6, value: units("100000000"), want: true},
+		{name: "smallest unit", amount: "0.000001", decimals: 6, value: units("1"), want: true},
+		{name: "less than declared", amount: "100", decimals: 6, value: units("99999999")},
+		{name: "more than declared", amount: "100", decimals: 6, value: units("100000001")},
+		// 申报金额超出资产精度，无法与任何链上金额相等
+		{name: "beyond precision", amount: "1.0000001", decimals: 6, value: units("1000000")},
+		{name: "zero transfer", amount: "1", decimals: 18, value: new(big.Int)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := depositAmountMatches(decimal.RequireFromString(tt.amount), tt.decimals, tt.value); got != tt.want {
+				t.Fatalf("depositAmountMatches(%s, %d, %s)=%v, want %v", tt.amount, tt.decimals, tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseAddressList(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  []string
+	}{
+		{name: "empty", value: ""},
+		{name: "mixed case and spaces", value: " 0x00000000000000000000000000000000000000C1 , " + testUserWallet.Hex(), want: []string{lowerAddress(testRouterContract), lowerAddress(testUserWallet)}},
+		{name: "invalid entries skipped", value: "0x1234,not-an-address," + testUserWallet.Hex(), want: []string{lowerAddress(testUserWallet)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseAddressList(tt.value)
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Fatalf("parseAddressList=%v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -3,15 +3,27 @@ package services
 import (
 	"expchange-backend/database"
 	"expchange-backend/models"
+	"fmt"
+	"log"
+	"time"
 
 	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
 )
 
-type FeeService struct{}
+type FeeService struct {
+	referralService *ReferralService
+}
 
 func NewFeeService() *FeeService {
-	return &FeeService{}
+	// 预先加载平台手续费账户，避免在成交事务中创建
+	if _, err := GetPlatformFeeAccountID(); err != nil {
+		log.Printf("⚠️  %v", err)
+	}
+
+	return &FeeService{
+		referralService: NewReferralService(),
+	}
 }
 
 // 初始化默认手续费配置
@@ -99,19 +111,12 @@ func (s *FeeService) CalculateFee(userLevel string, isMaker bool, tradeAmount de
 
 // 记录手续费
 func (s *FeeService) RecordFee(userID, orderID, tradeID string, asset string, amount, feeRate decimal.Decimal, orderSide string) error {
-	record := models.FeeRecord{
-		UserID:    userID,
-		OrderID:   orderID,
-		TradeID:   tradeID,
-		Asset:     asset,
-		Amount:    amount,
-		FeeRate:   feeRate,
-		OrderSide: orderSide,
-	}
-	return database.DB.Create(&record).Error
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		return s.RecordFeeInTx(tx, userID, orderID, tradeID, asset, amount, feeRate, orderSide)
+	})
 }
 
-// RecordFeeInTx 在事务中记录手续费（性能优化版）
+// RecordFeeInTx 在事务中记录手续费并计入平台手续费账户（性能优化版）
 func (s *FeeService) RecordFeeInTx(tx *gorm.DB, userID, orderID, tradeID string, asset string, amount, feeRate decimal.Decimal, orderSide string) error {
 	record := models.FeeRecord{
 		UserID:    userID,
@@ -122,7 +127,32 @@ func (s *FeeService) RecordFeeInTx(tx *gorm.DB, userID, orderID, tradeID string,
 		FeeRate:   feeRate,
 		OrderSide: orderSide,
 	}
-	return tx.Create(&record).Error
+	if err := tx.Create(&record).Error; err != nil {
+		return err
+	}
+
+	// 手续费计入平台手续费账户
+	if amount.IsPositive() {
+		feeAccountID, err := GetPlatformFeeAccountID()
+		if err != nil {
+			return err
+		}
+		if err := AdjustBalanceInTx(tx, feeAccountID, asset, amount); err != nil {
+			return err
+		}
+	}
+
+	// 实时返佣模式下，在同一事务中给邀请人返佣
+	if s.referralService.IsEnabled() && s.referralService.GetMode() == ReferralModeRealtime {
+		err := tx.Transaction(func(sp *gorm.DB) error {
+			_, err := s.referralService.AccrueInTx(sp, &record, ReferralModeRealtime)
+			return err
+		})
+		if err != nil {
+			log.Printf("⚠️  邀请返佣失败（将由每日结算补发）: FeeRecordID=%s, %v", record.ID, err)
+		}
+	}
+	return nil
 }
 
 // 获取用户手续费统计
@@ -141,3 +171,70 @@ func (s *FeeService) GetUserFeeStats(userID string) (map[string]decimal.Decimal,
 	return stats, nil
 }
 
+// FeeRevenueRow 手续费收入报表行
+type FeeRevenueRow struct {
+	Day        string          `json:"day,omitempty"`
+	Symbol     string          `json:"symbol,omitempty"`
+	UserLevel  string          `json:"user_level,omitempty"`
+	Asset      string          `json:"asset"`
+	TotalFee   decimal.Decimal `json:"total_fee"`
+	Commission decimal.Decimal `json:"commission"`  // 邀请返佣支出
+	NetRevenue decimal.Decimal `json:"net_revenue"` // 手续费 - 返佣
+	FeeCount   int64           `json:"fee_count"`
+}
+
+// GetRevenueReport 手续费收入报表
+// groupBy: day, pair, asset, user_level；时间范围为 [start, end)，为空则不限制
+func (s *FeeService) GetRevenueReport(groupBy string, start, end *time.Time) ([]FeeRevenueRow, error) {
+	query := database.DB.Table("fee_records").
+		Joins("LEFT JOIN referral_commissions ON referral_commissions.fee_record_id = fee_records.id").
+		Where("fee_records.amount > 0")
+
+	if start != nil {
+		query = query.Where("fee_records.created_at >= ?", *start)
+	}
+	if end != nil {
+		query = query.Where("fee_records.created_at < ?", *end)
+	}
+
+	aggregates := "fee_records.asset AS asset, SUM(fee_records.amount) AS total_fee, " +
+		"SUM(COALESCE(referral_commissions.amount, 0)) AS commission, COUNT(*) AS fee_count"
+
+	switch groupBy {
+	case "day":
+		dayExpr := "DATE_FORMAT(fee_records.created_at, '%Y-%m-%d')"
+		if database.DB.Dialector.Name() == "sqlite" {
+			dayExpr = "strftime('%Y-%m-%d', fee_records.created_at)"
+		}
+		query = query.Select(dayExpr + " AS day, " + aggregates).
+			Group(dayExpr + ", fee_records.asset").
+			Order("day DESC, asset")
+	case "pair":
+		query = query.Joins("JOIN trades ON trades.id = fee_records.trade_id").
+			Select("trades.symbol AS symbol, " + aggregates).
+			Group("trades.symbol, fee_records.asset").
+			Order("symbol, asset")
+	case "user_level":
+		query = query.Joins("JOIN users ON users.id = fee_records.user_id").
+			Select("users.user_level AS user_level, " + aggregates).
+			Group("users.user_level, fee_records.asset").
+			Order("user_level, asset")
+	case "asset":
+		query = query.Select(aggregates).
+			Group("fee_records.asset").
+			Order("asset")
+	default:
+		return nil, fmt.Errorf("unsupported group_by: %s", groupBy)
+	}
+
+	var rows []FeeRevenueRow
+	if err := query.Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	for i := range rows {
+		rows[i].NetRevenue = rows[i].TotalFee.Sub(rows[i].Commission)
+	}
+
+	return rows, nil
+}
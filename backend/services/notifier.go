@@ -0,0 +1,105 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"expchange-backend/database"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Notifier 用户通知发送（验证码等）
+// 默认使用本地日志通知，接入邮件服务时通过 SetNotifier 替换
+type Notifier interface {
+	Send(to, subject, body string) error
+}
+
+// LogNotifier 本地通知：只写入服务日志（开发环境或未接入邮件服务时使用）
+type LogNotifier struct{}
+
+func (LogNotifier) Send(to, subject, body string) error {
+	log.Printf("📧 [本地通知] To=%s, Subject=%s, Body=%s", to, subject, body)
+	return nil
+}
+
+// WebhookNotifier 以 JSON（to、subject、body）POST 到 webhook 地址（对接 IM 机器人或告警中转服务）
+type WebhookNotifier struct {
+	URL    string
+	Client *http.Client
+}
+
+func (n WebhookNotifier) Send(to, subject, body string) error {
+	payload, err := json.Marshal(map[string]string{"to": to, "subject": subject, "body": body})
+	if err != nil {
+		return err
+	}
+	client := n.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Post(n.URL, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+var (
+	notifier   Notifier = LogNotifier{}
+	notifierMu sync.RWMutex
+
+	// alertNotifier 运维告警发送实现（为 nil 时按 alert.webhook_url 选择）
+	alertNotifier Notifier
+)
+
+// SetNotifier 替换通知发送实现
+func SetNotifier(n Notifier) {
+	notifierMu.Lock()
+	notifier = n
+	notifierMu.Unlock()
+}
+
+// GetNotifier 获取当前通知发送实现
+func GetNotifier() Notifier {
+	notifierMu.RLock()
+	defer notifierMu.RUnlock()
+	return notifier
+}
+
+// SetAlertNotifier 替换运维告警发送实现（传 nil 恢复默认）
+func SetAlertNotifier(n Notifier) {
+	notifierMu.Lock()
+	alertNotifier = n
+	notifierMu.Unlock()
+}
+
+// GetAlertNotifier 运维告警发送实现：未通过 SetAlertNotifier 替换时，配置了 alert.webhook_url 则推送到 webhook，否则写日志
+func GetAlertNotifier() Notifier {
+	notifierMu.RLock()
+	n := alertNotifier
+	notifierMu.RUnlock()
+	if n != nil {
+		return n
+	}
+	if url := strings.TrimSpace(database.GetSystemConfigManager().Get("alert.webhook_url", "")); url != "" {
+		return WebhookNotifier{URL: url}
+	}
+	return LogNotifier{}
+}
+
+// SendAlert 发送运维告警（接收方为 alert.recipient，发送失败只记录日志）
+func SendAlert(subject, body string) {
+	log.Printf("🚨 告警: %s", subject)
+	to := database.GetSystemConfigManager().Get("alert.recipient", "ops")
+	if err := GetAlertNotifier().Send(to, subject, body); err != nil {
+		log.Printf("⚠️  告警发送失败: %v", err)
+	}
+}
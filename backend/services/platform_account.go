@@ -0,0 +1,76 @@
+package services
+
+import (
+	"expchange-backend/database"
+	"expchange-backend/models"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// PlatformFeeWalletAddress 平台手续费账户（系统账户，不可登录）
+const PlatformFeeWalletAddress = "0x0000000000000000000000000000000000000fee"
+
+var (
+	feeAccountID string
+	feeAccountMu sync.Mutex
+)
+
+// GetPlatformFeeAccountID 获取平台手续费账户ID（不存在则创建）
+func GetPlatformFeeAccountID() (string, error) {
+	feeAccountMu.Lock()
+	defer feeAccountMu.Unlock()
+
+	if feeAccountID != "" {
+		return feeAccountID, nil
+	}
+
+	var user models.User
+	if err := database.DB.Where("wallet_address = ?", PlatformFeeWalletAddress).
+		FirstOrCreate(&user, models.User{
+			WalletAddress: PlatformFeeWalletAddress,
+			UserLevel:     "normal",
+		}).Error; err != nil {
+		return "", fmt.Errorf("failed to load platform fee account: %w", err)
+	}
+
+	feeAccountID = user.ID
+	return feeAccountID, nil
+}
+
+// GetSystemWalletAddresses 系统账户地址（做市商虚拟用户、平台手续费账户），用于在用户列表中排除
+func GetSystemWalletAddresses() []string {
+	return []string{
+		"0x0000000000000000000000000000000000000000",
+		PlatformFeeWalletAddress,
+	}
+}
+
+// AdjustBalanceInTx 在事务中调整账户可用余额（delta 可为负数）
+func AdjustBalanceInTx(tx *gorm.DB, userID, asset string, delta decimal.Decimal) error {
+	var balance models.Balance
+	if err := tx.Where("user_id = ? AND asset = ?", userID, asset).FirstOrCreate(&balance, models.Balance{
+		UserID: userID,
+		Asset:  asset,
+	}).Error; err != nil {
+		return err
+	}
+
+	return tx.Model(&models.Balance{}).
+		Where("id = ?", balance.ID).
+		Update("available", gorm.Expr("available + ?", delta)).Error
+}
+
+// IsSystemWallet 是否为系统账户地址（系统账户不允许登录）
+func IsSystemWallet(address string) bool {
+	address = strings.ToLower(address)
+	for _, addr := range GetSystemWalletAddresses() {
+		if address == addr {
+			return true
+		}
+	}
+	return false
+}
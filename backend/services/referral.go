@@ -0,0 +1,260 @@
+package services
+
+import (
+	"errors"
+	"expchange-backend/database"
+	"expchange-backend/models"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// 返佣结算模式
+const (
+	ReferralModeRealtime = "realtime" // 成交时实时返佣
+	ReferralModeDaily    = "daily"    // 每日结算任务统一返佣
+)
+
+// ReferralService 邀请返佣服务
+type ReferralService struct{}
+
+func NewReferralService() *ReferralService {
+	return &ReferralService{}
+}
+
+// IsEnabled 是否启用邀请返佣
+func (s *ReferralService) IsEnabled() bool {
+	return database.GetSystemConfigManager().GetBool("referral.enabled", true)
+}
+
+// GetMode 获取返佣结算模式
+func (s *ReferralService) GetMode() string {
+	mode := database.GetSystemConfigManager().Get("referral.settlement.mode", ReferralModeRealtime)
+	if mode != ReferralModeDaily {
+		return ReferralModeRealtime
+	}
+	return mode
+}
+
+// GetDefaultRate 获取系统默认返佣比例（0-1）
+func (s *ReferralService) GetDefaultRate() decimal.Decimal {
+	rateStr := database.GetSystemConfigManager().Get("referral.commission.rate", "0.2")
+	rate, err := decimal.NewFromString(rateStr)
+	if err != nil {
+		return decimal.NewFromFloat(0.2)
+	}
+	return clampRate(rate)
+}
+
+// GetRate 获取邀请人的返佣比例（个人比例优先）
+func (s *ReferralService) GetRate(referrer *models.User) decimal.Decimal {
+	if referrer.ReferralRate.Valid {
+		return clampRate(referrer.ReferralRate.Decimal)
+	}
+	return s.GetDefaultRate()
+}
+
+// BindReferrer 为用户绑定邀请人（仅在首次登录前可绑定，之后不可修改）
+func (s *ReferralService) BindReferrer(tx *gorm.DB, user *models.User, code string) error {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if code == "" {
+		return nil
+	}
+	if user.ReferrerID != "" {
+		return errors.New("referrer already bound")
+	}
+
+	var referrer models.User
+	if err := tx.Where("referral_code = ?", code).First(&referrer).Error; err != nil {
+		return errors.New("invalid referral code")
+	}
+
+	if referrer.ID == user.ID {
+		return errors.New("cannot refer yourself")
+	}
+	// 防止互相邀请形成环
+	if referrer.ReferrerID == user.ID {
+		return errors.New("circular referral is not allowed")
+	}
+
+	now := time.Now()
+	user.ReferrerID = referrer.ID
+	user.ReferredAt = &now
+	return tx.Model(user).Updates(map[string]interface{}{
+		"referrer_id": referrer.ID,
+		"referred_at": now,
+	}).Error
+}
+
+// AccrueInTx 在成交事务中为手续费记录计算并发放返佣（幂等：每条手续费记录只返佣一次）
+func (s *ReferralService) AccrueInTx(tx *gorm.DB, record *models.FeeRecord, mode string) (*models.ReferralCommission, error) {
+	if !record.Amount.IsPositive() {
+		return nil, nil
+	}
+
+	var referee models.User
+	if err := tx.Where("id = ?", record.UserID).First(&referee).Error; err != nil {
+		return nil, fmt.Errorf("referee not found: %w", err)
+	}
+	if referee.ReferrerID == "" {
+		return nil, nil
+	}
+	// 绑定关系之前产生的手续费不返佣
+	if referee.ReferredAt != nil && record.CreatedAt.Before(*referee.ReferredAt) {
+		return nil, nil
+	}
+
+	var count int64
+	tx.Model(&models.ReferralCommission{}).Where("fee_record_id = ?", record.ID).Count(&count)
+	if count > 0 {
+		return nil, nil
+	}
+
+	var referrer models.User
+	if err := tx.Where("id = ?", referee.ReferrerID).First(&referrer).Error; err != nil {
+		return nil, fmt.Errorf("referrer not found: %w", err)
+	}
+
+	rate := s.GetRate(&referrer)
+	amount := record.Amount.Mul(rate).Truncate(8)
+	if !amount.IsPositive() {
+		return nil, nil
+	}
+
+	now := time.Now()
+	commission := models.ReferralCommission{
+		ReferrerID:  referrer.ID,
+		RefereeID:   referee.ID,
+		FeeRecordID: record.ID,
+		TradeID:     record.TradeID,
+		Asset:       record.Asset,
+		FeeAmount:   record.Amount,
+		Rate:        rate,
+		Amount:      amount,
+		Mode:        mode,
+		Status:      "settled",
+		SettledAt:   &now,
+	}
+	if err := tx.Create(&commission).Error; err != nil {
+		return nil, err
+	}
+
+	// 返佣从平台手续费账户转入邀请人可用余额
+	feeAccountID, err := GetPlatformFeeAccountID()
+	if err != nil {
+		return nil, err
+	}
+	if err := AdjustBalanceInTx(tx, feeAccountID, record.Asset, amount.Neg()); err != nil {
+		return nil, err
+	}
+	if err := AdjustBalanceInTx(tx, referrer.ID, record.Asset, amount); err != nil {
+		return nil, err
+	}
+
+	return &commission, nil
+}
+
+// SettleRange 结算时间区间内尚未返佣的手续费记录（每日结算任务调用，可重复执行）
+// 返回：结算条数, 各资产返佣合计, error
+func (s *ReferralService) SettleRange(start, end time.Time) (int, map[string]decimal.Decimal, error) {
+	totals := make(map[string]decimal.Decimal)
+	settled := 0
+
+	var records []models.FeeRecord
+	result := database.DB.Model(&models.FeeRecord{}).
+		Select("fee_records.*").
+		Joins("JOIN users ON users.id = fee_records.user_id").
+		Where("users.referrer_id IS NOT NULL AND users.referrer_id <> ''").
+		Where("fee_records.created_at >= ? AND fee_records.created_at < ?", start, end).
+		Where("fee_records.amount > 0").
+		Where("NOT EXISTS (SELECT 1 FROM referral_commissions rc WHERE rc.fee_record_id = fee_records.id)").
+		FindInBatches(&records, 500, func(batch *gorm.DB, _ int) error {
+			return database.DB.Transaction(func(tx *gorm.DB) error {
+				for i := range records {
+					commission, err := s.AccrueInTx(tx, &records[i], ReferralModeDaily)
+					if err != nil {
+						return err
+					}
+					if commission == nil {
+						continue
+					}
+					settled++
+					totals[commission.Asset] = totals[commission.Asset].Add(commission.Amount)
+				}
+				return nil
+			})
+		})
+	if result.Error != nil {
+		return settled, totals, result.Error
+	}
+
+	log.Printf("🎁 邀请返佣结算完成: %s ~ %s, 共 %d 笔", start.Format("2006-01-02 15:04"), end.Format("2006-01-02 15:04"), settled)
+	return settled, totals, nil
+}
+
+// ReferralStats 用户邀请统计
+type ReferralStats struct {
+	ReferralCode    string                     `json:"referral_code"`
+	ReferrerID      string                     `json:"referrer_id,omitempty"`
+	Rate            decimal.Decimal            `json:"rate"`
+	InvitedCount    int64                      `json:"invited_count"`
+	CommissionCount int64                      `json:"commission_count"`
+	TotalCommission map[string]decimal.Decimal `json:"total_commission"`
+	Last24hByAsset  map[string]decimal.Decimal `json:"last_24h_commission"`
+}
+
+// GetStats 获取用户邀请统计
+func (s *ReferralService) GetStats(user *models.User) *ReferralStats {
+	stats := &ReferralStats{
+		ReferralCode:    user.ReferralCode,
+		ReferrerID:      user.ReferrerID,
+		Rate:            s.GetRate(user),
+		TotalCommission: make(map[string]decimal.Decimal),
+		Last24hByAsset:  make(map[string]decimal.Decimal),
+	}
+
+	database.DB.Model(&models.User{}).Where("referrer_id = ?", user.ID).Count(&stats.InvitedCount)
+	database.DB.Model(&models.ReferralCommission{}).Where("referrer_id = ?", user.ID).Count(&stats.CommissionCount)
+
+	type assetSum struct {
+		Asset string
+		Total decimal.Decimal
+	}
+
+	var totals []assetSum
+	database.DB.Model(&models.ReferralCommission{}).
+		Select("asset, SUM(amount) as total").
+		Where("referrer_id = ?", user.ID).
+		Group("asset").
+		Scan(&totals)
+	for _, t := range totals {
+		stats.TotalCommission[t.Asset] = t.Total
+	}
+
+	var recent []assetSum
+	database.DB.Model(&models.ReferralCommission{}).
+		Select("asset, SUM(amount) as total").
+		Where("referrer_id = ? AND created_at >= ?", user.ID, time.Now().Add(-24*time.Hour)).
+		Group("asset").
+		Scan(&recent)
+	for _, t := range recent {
+		stats.Last24hByAsset[t.Asset] = t.Total
+	}
+
+	return stats
+}
+
+// clampRate 将返佣比例限制在 [0, 1]
+func clampRate(rate decimal.Decimal) decimal.Decimal {
+	if rate.IsNegative() {
+		return decimal.Zero
+	}
+	if rate.GreaterThan(decimal.NewFromInt(1)) {
+		return decimal.NewFromInt(1)
+	}
+	return rate
+}
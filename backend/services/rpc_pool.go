@@ -0,0 +1,222 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"expchange-backend/database"
+	"expchange-backend/models"
+	"expchange-backend/pkg/rpcpool"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidRpcURL       = errors.New("rpc url must be an http(s) URL")
+	ErrRpcEndpointNotFound = errors.New("rpc endpoint not found")
+)
+
+var (
+	rpcPool     *rpcpool.Pool
+	rpcPoolOnce sync.Once
+)
+
+// GetRpcPool 全局 RPC 连接池（各链共享客户端）
+func GetRpcPool() *rpcpool.Pool {
+	rpcPoolOnce.Do(func() {
+		rpcPool = rpcpool.New(rpcPoolOptions(), logRpcStateChange)
+	})
+	return rpcPool
+}
+
+// rpcPoolOptions 连接池参数（rpc.pool.*，支持热更新）
+func rpcPoolOptions() rpcpool.Options {
+	sysConfig := database.GetSystemConfigManager()
+	defaults := rpcpool.DefaultOptions()
+	return rpcpool.Options{
+		FailureThreshold: sysConfig.GetInt("rpc.pool.failure_threshold", defaults.FailureThreshold),
+		OpenDuration:     time.Duration(sysConfig.GetInt("rpc.pool.open_seconds", 30)) * time.Second,
+		MaxHeadLag:       uint64(max(sysConfig.GetInt("rpc.pool.max_head_lag", int(defaults.MaxHeadLag)), 0)),
+		RequestTimeout:   time.Duration(sysConfig.GetInt("rpc.pool.request_timeout_seconds", 15)) * time.Second,
+	}
+}
+
+func logRpcStateChange(change rpcpool.StateChange) {
+	switch change.To {
+	case rpcpool.StateOpen:
+		log.Printf("🔌 RPC 节点熔断: ChainID=%d, URL=%s, err=%v", change.ChainID, change.URL, change.Err)
+	case rpcpool.StateLagging:
+		log.Printf("🐢 RPC 节点区块落后: ChainID=%d, URL=%s, %v", change.ChainID, change.URL, change.Err)
+	case rpcpool.StateHealthy:
+		log.Printf("✅ RPC 节点恢复: ChainID=%d, URL=%s (%s -> %s)", change.ChainID, change.URL, change.From, change.To)
+	}
+}
+
+// ValidateRpcURL 校验 RPC 地址（连接池通过 HTTP 转发请求）
+func ValidateRpcURL(rawURL string) error {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ErrInvalidRpcURL
+	}
+	return nil
+}
+
+// ChainRpcEndpoints 链的 RPC 节点：注册表中启用的节点，以及链配置的 RpcURL（未在注册表中出现时作为优先级 0、权重 1 的主节点）
+func ChainRpcEndpoints(chain *models.ChainConfig) []rpcpool.Endpoint {
+	var rows []models.ChainRpcEndpoint
+	database.DB.Where("chain_id = ?", chain.ChainID).Order("priority ASC, created_at ASC").Find(&rows)
+
+	endpoints := make([]rpcpool.Endpoint, 0, len(rows)+1)
+	primaryListed := false
+	for _, row := range rows {
+		if row.URL == chain.RpcURL {
+			primaryListed = true
+		}
+		if row.Enabled {
+			endpoints = append(endpoints, rpcpool.Endpoint{URL: row.URL, Priority: row.Priority, Weight: row.Weight})
+		}
+	}
+	if !primaryListed && chain.RpcURL != "" {
+		endpoints = append([]rpcpool.Endpoint{{URL: chain.RpcURL, Priority: 0, Weight: 1}}, endpoints...)
+	}
+	return endpoints
+}
+
+// ChainClient 链的共享 RPC 客户端（多节点故障切换和熔断；调用方不要 Close）
+func ChainClient(chain *models.ChainConfig) (*ethclient.Client, error) {
+	pool := GetRpcPool()
+	pool.SetOptions(rpcPoolOptions())
+	client, err := pool.Client(chain.ChainID, ChainRpcEndpoints(chain))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to RPC of chain %s: %w", chain.ChainName, err)
+	}
+	return client, nil
+}
+
+// ChainSession 固定单个节点的 RPC 客户端，用于需要前后一致读取的一组调用（如先查 nonce 再查回执）
+// 节点失败时返回错误而不切换，调用方下一轮重新创建；不同节点的区块视图可能不一致，不能混用读取结果做判断
+func ChainSession(chain *models.ChainConfig) (*ethclient.Client, error) {
+	pool := GetRpcPool()
+	pool.SetOptions(rpcPoolOptions())
+	if _, err := pool.Client(chain.ChainID, ChainRpcEndpoints(chain)); err != nil {
+		return nil, fmt.Errorf("failed to connect to RPC of chain %s: %w", chain.ChainName, err)
+	}
+	client, err := pool.Session(chain.ChainID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to RPC of chain %s: %w", chain.ChainName, err)
+	}
+	return client, nil
+}
+
+// CheckRpcHealth 检查所有启用链的 RPC 节点（由任务队列定时调用）
+func CheckRpcHealth() {
+	var chains []models.ChainConfig
+	database.DB.Where("enabled = ?", true).Find(&chains)
+
+	pool := GetRpcPool()
+	pool.SetOptions(rpcPoolOptions())
+	chainIDs := make([]int, 0, len(chains))
+	for i := range chains {
+		if _, err := pool.Client(chains[i].ChainID, ChainRpcEndpoints(&chains[i])); err != nil {
+			continue
+		}
+		chainIDs = append(chainIDs, chains[i].ChainID)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	pool.CheckHealth(ctx, chainIDs...)
+}
+
+// ChainRpcHealth 链的 RPC 节点健康状态
+type ChainRpcHealth struct {
+	ChainID   int                      `json:"chain_id"`
+	ChainName string                   `json:"chain_name"`
+	Endpoints []rpcpool.EndpointStatus `json:"endpoints"`
+}
+
+// GetRpcHealth 所有启用链的 RPC 节点健康状态（尚未检查过的节点没有区块高度）
+func GetRpcHealth() []ChainRpcHealth {
+	var chains []models.ChainConfig
+	database.DB.Where("enabled = ?", true).Order("chain_id ASC").Find(&chains)
+
+	pool := GetRpcPool()
+	result := make([]ChainRpcHealth, 0, len(chains))
+	for i := range chains {
+		chain := &chains[i]
+		pool.Client(chain.ChainID, ChainRpcEndpoints(chain))
+		result = append(result, ChainRpcHealth{
+			ChainID:   chain.ChainID,
+			ChainName: chain.ChainName,
+			Endpoints: pool.Status(chain.ChainID),
+		})
+	}
+	return result
+}
+
+// GetChainRpcEndpointsByChain 按链ID分组的 RPC 节点注册表
+func GetChainRpcEndpointsByChain() map[int][]models.ChainRpcEndpoint {
+	var rows []models.ChainRpcEndpoint
+	database.DB.Order("chain_id ASC, priority ASC, created_at ASC").Find(&rows)
+
+	result := make(map[int][]models.ChainRpcEndpoint)
+	for _, row := range rows {
+		result[row.ChainID] = append(result[row.ChainID], row)
+	}
+	return result
+}
+
+// UpsertChainRpcEndpoint 按 URL 创建或更新链的 RPC 节点
+func UpsertChainRpcEndpoint(chain *models.ChainConfig, endpoint models.ChainRpcEndpoint) (*models.ChainRpcEndpoint, error) {
+	endpoint.URL = strings.TrimSpace(endpoint.URL)
+	if err := ValidateRpcURL(endpoint.URL); err != nil {
+		return nil, err
+	}
+	if endpoint.Weight < 1 {
+		return nil, errors.New("weight must be at least 1")
+	}
+
+	var existing models.ChainRpcEndpoint
+	err := database.DB.Where("chain_id = ? AND url = ?", chain.ChainID, endpoint.URL).First(&existing).Error
+	if err == nil {
+		if err := database.DB.Model(&existing).Updates(map[string]interface{}{
+			"priority": endpoint.Priority,
+			"weight":   endpoint.Weight,
+			"enabled":  endpoint.Enabled,
+		}).Error; err != nil {
+			return nil, err
+		}
+		if err := database.DB.First(&existing, "id = ?", existing.ID).Error; err != nil {
+			return nil, err
+		}
+		return &existing, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	endpoint.ID = ""
+	endpoint.ChainID = chain.ChainID
+	if err := database.DB.Create(&endpoint).Error; err != nil {
+		return nil, err
+	}
+	return &endpoint, nil
+}
+
+// DeleteChainRpcEndpoint 删除链的 RPC 节点（链配置的 RpcURL 删除后恢复为默认优先级和权重）
+func DeleteChainRpcEndpoint(chainID int, endpointID string) error {
+	result := database.DB.Where("chain_id = ? AND id = ?", chainID, endpointID).Delete(&models.ChainRpcEndpoint{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrRpcEndpointNotFound
+	}
+	return nil
+}
@@ -0,0 +1,247 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"expchange-backend/database"
+	"expchange-backend/models"
+	"log"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+var (
+	ErrSessionNotFound     = errors.New("session not found")
+	ErrSessionExpired      = errors.New("session expired")
+	ErrSessionRevoked      = errors.New("session revoked")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected")
+	ErrRefreshTokenInvalid = errors.New("invalid refresh token")
+)
+
+// 会话吊销原因
+const (
+	SessionRevokeLogout = "logout"
+	SessionRevokeAdmin  = "admin"
+	SessionRevokeReuse  = "reuse_detected"
+)
+
+// sessionStatusTTL 会话状态缓存时间（鉴权中间件每个请求都会检查吊销列表）
+const sessionStatusTTL = 30 * time.Second
+
+type sessionStatus struct {
+	revoked   bool
+	checkedAt time.Time
+}
+
+// 吊销列表缓存（进程内共享，吊销时立即更新）
+var (
+	sessionStatusCache = make(map[string]sessionStatus)
+	sessionStatusMu    sync.RWMutex
+)
+
+// SessionService 登录会话与刷新令牌管理
+type SessionService struct {
+	refreshTTL time.Duration
+}
+
+func NewSessionService(refreshTTL time.Duration) *SessionService {
+	return &SessionService{refreshTTL: refreshTTL}
+}
+
+// CreateSession 登录时创建会话，返回会话和明文刷新令牌（只返回给客户端一次）
+func (s *SessionService) CreateSession(userID, userAgent, ip string) (*models.UserSession, string, error) {
+	refreshToken, err := randomHex(32)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// 已有会话的用户从新 IP 登录视为安全变更，锁定提现
+	var priorSessions, sameIPSessions int64
+	database.DB.Model(&models.UserSession{}).Where("user_id = ?", userID).Count(&priorSessions)
+	if priorSessions > 0 {
+		database.DB.Model(&models.UserSession{}).Where("user_id = ? AND ip_address = ?", userID, ip).Count(&sameIPSessions)
+		if sameIPSessions == 0 {
+			LockWithdrawals(userID, "new_login_ip")
+		}
+	}
+
+	now := time.Now()
+	session := &models.UserSession{
+		UserID:           userID,
+		RefreshTokenHash: hashRefreshToken(refreshToken),
+		UserAgent:        truncate(userAgent, 255),
+		IPAddress:        ip,
+		ExpiresAt:        now.Add(s.refreshTTL),
+		LastUsedAt:       now,
+	}
+	if err := database.DB.Create(session).Error; err != nil {
+		return nil, "", err
+	}
+
+	return session, refreshToken, nil
+}
+
+// Rotate 使用刷新令牌换取新的刷新令牌（旧令牌立即失效）
+// 已轮换过的旧令牌再次出现说明令牌可能被盗，直接吊销整个会话
+func (s *SessionService) Rotate(refreshToken, userAgent, ip string) (*models.UserSession, string, error) {
+	if refreshToken == "" {
+		return nil, "", ErrRefreshTokenInvalid
+	}
+	tokenHash := hashRefreshToken(refreshToken)
+
+	// 令牌不存在属于正常情况（过期、伪造），不打印 record not found 日志
+	quiet := database.DB.Session(&gorm.Session{Logger: database.DB.Logger.LogMode(logger.Silent)})
+
+	var session models.UserSession
+	if err := quiet.Where("refresh_token_hash = ?", tokenHash).First(&session).Error; err != nil {
+		var reused models.UserSession
+		if quiet.Where("previous_token_hash = ?", tokenHash).First(&reused).Error == nil {
+			if reused.RevokedAt == nil {
+				s.Revoke(reused.ID, SessionRevokeReuse)
+				log.Printf("🚨 检测到刷新令牌重放，会话已吊销: SessionID=%s, UserID=%s, IP=%s", reused.ID, reused.UserID, ip)
+			}
+			return nil, "", ErrRefreshTokenReused
+		}
+		return nil, "", ErrSessionNotFound
+	}
+
+	if session.RevokedAt != nil {
+		return nil, "", ErrSessionRevoked
+	}
+	if time.Now().After(session.ExpiresAt) {
+		return nil, "", ErrSessionExpired
+	}
+
+	newToken, err := randomHex(32)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// 条件更新：并发使用同一个刷新令牌时只有一个请求能成功
+	now := time.Now()
+	result := database.DB.Model(&models.UserSession{}).
+		Where("id = ? AND refresh_token_hash = ? AND revoked_at IS NULL", session.ID, tokenHash).
+		Updates(map[string]interface{}{
+			"refresh_token_hash":  hashRefreshToken(newToken),
+			"previous_token_hash": tokenHash,
+			"user_agent":          truncate(userAgent, 255),
+			"ip_address":          ip,
+			"last_used_at":        now,
+			"expires_at":          now.Add(s.refreshTTL),
+		})
+	if result.Error != nil {
+		return nil, "", result.Error
+	}
+	if result.RowsAffected != 1 {
+		return nil, "", ErrRefreshTokenReused
+	}
+
+	database.DB.Where("id = ?", session.ID).First(&session)
+	return &session, newToken, nil
+}
+
+// Revoke 吊销单个会话
+func (s *SessionService) Revoke(sessionID, reason string) error {
+	now := time.Now()
+	err := database.DB.Model(&models.UserSession{}).
+		Where("id = ? AND revoked_at IS NULL", sessionID).
+		Updates(map[string]interface{}{
+			"revoked_at":    now,
+			"revoke_reason": reason,
+		}).Error
+	if err != nil {
+		return err
+	}
+
+	setSessionStatus(sessionID, true)
+	return nil
+}
+
+// RevokeAllForUser 吊销用户的所有会话，返回吊销数量
+func (s *SessionService) RevokeAllForUser(userID, reason string) (int64, error) {
+	var sessionIDs []string
+	database.DB.Model(&models.UserSession{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Pluck("id", &sessionIDs)
+	if len(sessionIDs) == 0 {
+		return 0, nil
+	}
+
+	now := time.Now()
+	result := database.DB.Model(&models.UserSession{}).
+		Where("id IN ? AND revoked_at IS NULL", sessionIDs).
+		Updates(map[string]interface{}{
+			"revoked_at":    now,
+			"revoke_reason": reason,
+		})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	for _, id := range sessionIDs {
+		setSessionStatus(id, true)
+	}
+
+	log.Printf("🔒 用户会话已全部吊销: UserID=%s, 数量=%d, 原因=%s", userID, result.RowsAffected, reason)
+	return result.RowsAffected, nil
+}
+
+// GetUserSessions 获取用户的有效会话（未吊销且未过期）
+func (s *SessionService) GetUserSessions(userID string) []models.UserSession {
+	sessions := []models.UserSession{}
+	database.DB.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_used_at DESC").
+		Find(&sessions)
+	return sessions
+}
+
+// IsSessionRevoked 检查会话是否已吊销或不存在（鉴权中间件调用，带短时缓存）
+func IsSessionRevoked(sessionID string) bool {
+	if sessionID == "" {
+		return true
+	}
+
+	sessionStatusMu.RLock()
+	status, ok := sessionStatusCache[sessionID]
+	sessionStatusMu.RUnlock()
+	if ok && (status.revoked || time.Since(status.checkedAt) < sessionStatusTTL) {
+		return status.revoked
+	}
+
+	var session models.UserSession
+	revoked := true
+	if err := database.DB.Session(&gorm.Session{Logger: database.DB.Logger.LogMode(logger.Silent)}).
+		Select("id", "revoked_at").Where("id = ?", sessionID).First(&session).Error; err == nil {
+		revoked = session.RevokedAt != nil
+	}
+
+	setSessionStatus(sessionID, revoked)
+	return revoked
+}
+
+func setSessionStatus(sessionID string, revoked bool) {
+	sessionStatusMu.Lock()
+	defer sessionStatusMu.Unlock()
+
+	// 简单清理，避免缓存无限增长
+	if len(sessionStatusCache) > 10000 {
+		sessionStatusCache = make(map[string]sessionStatus)
+	}
+	sessionStatusCache[sessionID] = sessionStatus{revoked: revoked, checkedAt: time.Now()}
+}
+
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	return s[:max]
+}
@@ -0,0 +1,203 @@
+package services
+
+import (
+	"errors"
+	"expchange-backend/database"
+	"expchange-backend/models"
+	"testing"
+	"time"
+)
+
+func TestSessionRotate(t *testing.T) {
+	tests := []struct {
+		name    string
+		prepare func(t *testing.T, s *SessionService, session *models.UserSession, token string) string
+		wantErr error
+	}{
+		{
+			name: "valid token",
+			prepare: func(t *testing.T, s *SessionService, session *models.UserSession, token string) string {
+				return token
+			},
+		},
+		{
+			name: "empty token",
+			prepare: func(t *testing.T, s *SessionService, session *models.UserSession, token string) string {
+				return ""
+			},
+			wantErr: ErrRefreshTokenInvalid,
+		},
+		{
+			name: "unknown token",
+			prepare: func(t *testing.T, s *SessionService, session *models.UserSession, token string) string {
+				return "forged-token"
+			},
+			wantErr: ErrSessionNotFound,
+		},
+		{
+			name: "expired session",
+			prepare: func(t *testing.T, s *SessionService, session *models.UserSession, token string) string {
+				database.DB.Model(session).Update("expires_at", time.Now().Add(-time.Minute))
+				return token
+			},
+			wantErr: ErrSessionExpired,
+		},
+		{
+			name: "revoked session",
+			prepare: func(t *testing.T, s *SessionService, session *models.UserSession, token string) string {
+				if err := s.Revoke(session.ID, SessionRevokeLogout); err != nil {
+					t.Fatal(err)
+				}
+				return token
+			},
+			wantErr: ErrSessionRevoked,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newTestDB(t, &models.User{}, &models.UserSession{})
+			s := NewSessionService(time.Hour)
+			session, token, err := s.CreateSession("user-1", "test-agent", "10.0.0.1")
+			if err != nil {
+				t.Fatalf("CreateSession: %v", err)
+			}
+
+			rotated, newToken, err := s.Rotate(tt.prepare(t, s, session, token), "test-agent", "10.0.0.2")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Rotate err=%v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if rotated.ID != session.ID || newToken == "" || newToken == token {
+				t.Fatalf("unexpected rotation: session=%s token changed=%v", rotated.ID, newToken != token)
+			}
+			if rotated.IPAddress != "10.0.0.2" {
+				t.Fatalf("IPAddress=%q, want the rotating client IP", rotated.IPAddress)
+			}
+			// 新令牌可以继续轮换
+			if _, _, err := s.Rotate(newToken, "test-agent", "10.0.0.2"); err != nil {
+				t.Fatalf("Rotate with new token: %v", err)
+			}
+		})
+	}
+}
+
+// TestSessionRotateReuseRevokes 已轮换的旧令牌再次出现：吊销整个会话，最新令牌也随之失效
+func TestSessionRotateReuseRevokes(t *testing.T) {
+	newTestDB(t, &models.User{}, &models.UserSession{})
+	s := NewSessionService(time.Hour)
+	session, oldToken, err := s.CreateSession("user-1", "test-agent", "10.0.0.1")
+	if err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+	_, newToken, err := s.Rotate(oldToken, "test-agent", "10.0.0.1")
+	if err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	if IsSessionRevoked(session.ID) {
+		t.Fatal("session revoked after a normal rotation")
+	}
+
+	if _, _, err := s.Rotate(oldToken, "attacker", "10.6.6.6"); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("reused token err=%v, want ErrRefreshTokenReused", err)
+	}
+	var saved models.UserSession
+	database.DB.First(&saved, "id = ?", session.ID)
+	if saved.RevokedAt == nil || saved.RevokeReason != SessionRevokeReuse {
+		t.Fatalf("session revoked_at=%v reason=%q, want revoked for %q", saved.RevokedAt, saved.RevokeReason, SessionRevokeReuse)
+	}
+	if !IsSessionRevoked(session.ID) {
+		t.Fatal("IsSessionRevoked=false after reuse detection")
+	}
+	if _, _, err := s.Rotate(newToken, "test-agent", "10.0.0.1"); !errors.Is(err, ErrSessionRevoked) {
+		t.Fatalf("latest token err=%v, want ErrSessionRevoked", err)
+	}
+}
+
+func TestSessionRevoke(t *testing.T) {
+	tests := []struct {
+		name         string
+		revoke       func(t *testing.T, s *SessionService, sessions []*models.UserSession)
+		wantRevoked  []bool // 依次对应 user-1 的两个会话和 user-2 的会话
+		wantReason   string
+		wantRemained int // user-1 剩余的有效会话数
+	}{
+		{
+			name: "single session",
+			revoke: func(t *testing.T, s *SessionService, sessions []*models.UserSession) {
+				if err := s.Revoke(sessions[0].ID, SessionRevokeLogout); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantRevoked:  []bool{true, false, false},
+			wantReason:   SessionRevokeLogout,
+			wantRemained: 1,
+		},
+		{
+			name: "all sessions of a user",
+			revoke: func(t *testing.T, s *SessionService, sessions []*models.UserSession) {
+				count, err := s.RevokeAllForUser("user-1", SessionRevokeAdmin)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if count != 2 {
+					t.Fatalf("RevokeAllForUser count=%d, want 2", count)
+				}
+				// 再次吊销没有可吊销的会话
+				if count, _ := s.RevokeAllForUser("user-1", SessionRevokeAdmin); count != 0 {
+					t.Fatalf("second RevokeAllForUser count=%d, want 0", count)
+				}
+			},
+			wantRevoked: []bool{true, true, false},
+			wantReason:  SessionRevokeAdmin,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newTestDB(t, &models.User{}, &models.UserSession{})
+			s := NewSessionService(time.Hour)
+			var sessions []*models.UserSession
+			for _, userID := range []string{"user-1", "user-1", "user-2"} {
+				session, _, err := s.CreateSession(userID, "test-agent", "10.0.0.1")
+				if err != nil {
+					t.Fatalf("CreateSession: %v", err)
+				}
+				sessions = append(sessions, session)
+			}
+			// 先查询一次，确认吊销会更新缓存的状态
+			for _, session := range sessions {
+				if IsSessionRevoked(session.ID) {
+					t.Fatalf("new session %s reported revoked", session.ID)
+				}
+			}
+
+			tt.revoke(t, s, sessions)
+
+			for i, session := range sessions {
+				if got := IsSessionRevoked(session.ID); got != tt.wantRevoked[i] {
+					t.Fatalf("session %d IsSessionRevoked=%v, want %v", i, got, tt.wantRevoked[i])
+				}
+				if tt.wantRevoked[i] {
+					var saved models.UserSession
+					database.DB.First(&saved, "id = ?", session.ID)
+					if saved.RevokeReason != tt.wantReason {
+						t.Fatalf("session %d revoke reason=%q, want %q", i, saved.RevokeReason, tt.wantReason)
+					}
+				}
+			}
+			if got := len(s.GetUserSessions("user-1")); got != tt.wantRemained {
+				t.Fatalf("GetUserSessions=%d, want %d", got, tt.wantRemained)
+			}
+		})
+	}
+}
+
+func TestIsSessionRevokedUnknown(t *testing.T) {
+	newTestDB(t, &models.UserSession{})
+	for _, id := range []string{"", "000000000000000000000000"} {
+		if !IsSessionRevoked(id) {
+			t.Fatalf("IsSessionRevoked(%q)=false, want true for unknown session", id)
+		}
+	}
+}
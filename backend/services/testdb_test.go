@@ -0,0 +1,37 @@
+package services
+
+import (
+	"expchange-backend/database"
+	"expchange-backend/models"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestDB 使用内存 SQLite 替换 database.DB 并迁移测试用到的表，测试结束后恢复
+func newTestDB(t *testing.T, tables ...interface{}) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 内存数据库每个连接独立，固定单连接
+	sqlDB.SetMaxOpenConns(1)
+	if err := db.AutoMigrate(append([]interface{}{&models.SystemConfig{}}, tables...)...); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	previous := database.DB
+	database.DB = db
+	t.Cleanup(func() {
+		database.DB = previous
+		sqlDB.Close()
+	})
+	return db
+}
@@ -0,0 +1,165 @@
+package services
+
+import (
+	"errors"
+	"expchange-backend/database"
+	"expchange-backend/models"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// TreasuryService 国库归集服务：把平台手续费账户累积的手续费转入国库账户
+type TreasuryService struct{}
+
+func NewTreasuryService() *TreasuryService {
+	return &TreasuryService{}
+}
+
+// IsSweepEnabled 是否启用每日自动归集
+func (s *TreasuryService) IsSweepEnabled() bool {
+	return database.GetSystemConfigManager().GetBool("treasury.sweep.enabled", false)
+}
+
+// GetTreasuryAddress 国库账户钱包地址
+func (s *TreasuryService) GetTreasuryAddress() string {
+	return strings.ToLower(strings.TrimSpace(database.GetSystemConfigManager().Get("treasury.account.address", "")))
+}
+
+// GetMinSweepAmount 单资产最小归集金额（低于该金额不归集）
+func (s *TreasuryService) GetMinSweepAmount() decimal.Decimal {
+	amount, err := decimal.NewFromString(database.GetSystemConfigManager().Get("treasury.sweep.min_amount", "0"))
+	if err != nil || amount.IsNegative() {
+		return decimal.Zero
+	}
+	return amount
+}
+
+// getTreasuryAccount 获取国库账户（按钱包地址查找，不存在则创建）
+func (s *TreasuryService) getTreasuryAccount() (*models.User, error) {
+	address := s.GetTreasuryAddress()
+	if address == "" {
+		return nil, errors.New("treasury account address is not configured")
+	}
+	if !strings.HasPrefix(address, "0x") || len(address) != 42 {
+		return nil, fmt.Errorf("invalid treasury account address: %s", address)
+	}
+	if IsSystemWallet(address) {
+		return nil, errors.New("treasury account cannot be a system account")
+	}
+
+	var user models.User
+	if err := database.DB.Where("wallet_address = ?", address).
+		FirstOrCreate(&user, models.User{WalletAddress: address}).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// Sweep 执行一次归集，每个资产一条归集记录
+func (s *TreasuryService) Sweep(taskID string) ([]models.TreasurySweep, error) {
+	feeAccountID, err := GetPlatformFeeAccountID()
+	if err != nil {
+		return nil, err
+	}
+
+	treasury, err := s.getTreasuryAccount()
+	if err != nil {
+		return nil, err
+	}
+
+	minAmount := s.GetMinSweepAmount()
+
+	var balances []models.Balance
+	database.DB.Where("user_id = ? AND available > 0", feeAccountID).Find(&balances)
+
+	sweeps := make([]models.TreasurySweep, 0, len(balances))
+	for _, balance := range balances {
+		if balance.Available.LessThanOrEqual(minAmount) {
+			continue
+		}
+
+		sweep := models.TreasurySweep{
+			TaskID:     taskID,
+			Asset:      balance.Asset,
+			FromUserID: feeAccountID,
+			ToUserID:   treasury.ID,
+			ToAddress:  treasury.WalletAddress,
+		}
+
+		err := database.DB.Transaction(func(tx *gorm.DB) error {
+			// 事务内重新读取，只归集当前可用余额
+			var current models.Balance
+			if err := tx.Where("id = ?", balance.ID).First(&current).Error; err != nil {
+				return err
+			}
+			if !current.Available.IsPositive() {
+				return errors.New("nothing to sweep")
+			}
+
+			sweep.Amount = current.Available
+			if err := AdjustBalanceInTx(tx, feeAccountID, current.Asset, current.Available.Neg()); err != nil {
+				return err
+			}
+			if err := AdjustBalanceInTx(tx, treasury.ID, current.Asset, current.Available); err != nil {
+				return err
+			}
+
+			sweep.Status = "completed"
+			return tx.Create(&sweep).Error
+		})
+
+		if err != nil {
+			sweep.Status = "failed"
+			sweep.Reason = err.Error()
+			database.DB.Create(&sweep)
+			log.Printf("❌ 国库归集失败: Asset=%s, %v", balance.Asset, err)
+		} else {
+			log.Printf("🏦 国库归集完成: Asset=%s, Amount=%s, To=%s", sweep.Asset, sweep.Amount.String(), sweep.ToAddress)
+		}
+		sweeps = append(sweeps, sweep)
+	}
+
+	return sweeps, nil
+}
+
+// TreasuryOverview 平台手续费账户与国库账户余额
+type TreasuryOverview struct {
+	FeeAccountID     string           `json:"fee_account_id"`
+	FeeBalances      []models.Balance `json:"fee_balances"`
+	TreasuryAddress  string           `json:"treasury_address"`
+	TreasuryBalances []models.Balance `json:"treasury_balances"`
+	SweepEnabled     bool             `json:"sweep_enabled"`
+	MinSweepAmount   decimal.Decimal  `json:"min_sweep_amount"`
+}
+
+// GetOverview 获取平台手续费账户与国库账户余额
+func (s *TreasuryService) GetOverview() (*TreasuryOverview, error) {
+	feeAccountID, err := GetPlatformFeeAccountID()
+	if err != nil {
+		return nil, err
+	}
+
+	overview := &TreasuryOverview{
+		FeeAccountID:     feeAccountID,
+		FeeBalances:      []models.Balance{},
+		TreasuryAddress:  s.GetTreasuryAddress(),
+		TreasuryBalances: []models.Balance{},
+		SweepEnabled:     s.IsSweepEnabled(),
+		MinSweepAmount:   s.GetMinSweepAmount(),
+	}
+
+	database.DB.Where("user_id = ?", feeAccountID).Order("asset").Find(&overview.FeeBalances)
+
+	if overview.TreasuryAddress != "" {
+		var treasury models.User
+		if err := database.DB.Where("wallet_address = ?", overview.TreasuryAddress).First(&treasury).Error; err == nil {
+			database.DB.Where("user_id = ?", treasury.ID).Order("asset").Find(&overview.TreasuryBalances)
+		}
+	}
+
+	return overview, nil
+}
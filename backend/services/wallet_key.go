@@ -0,0 +1,272 @@
+package services
+
+import (
+	"errors"
+	"expchange-backend/database"
+	"expchange-backend/models"
+	"expchange-backend/pkg/secretbox"
+	"expchange-backend/pkg/signer"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var (
+	ErrWalletKeyNotConfigured = errors.New("private key not configured for this chain")
+	ErrWalletVaultNotReady    = errors.New("wallet key vault not initialized")
+	ErrInvalidSignerType      = errors.New("invalid signer type")
+)
+
+// WalletKeyVault 链上钱包私钥的加密存储（信封加密，主密钥来自环境变量或密钥文件）
+// 同时负责按链配置创建提现签名器（本地私钥 / keystore 文件 / 远程签名服务）
+type WalletKeyVault struct {
+	master   *secretbox.Box
+	previous *secretbox.Box // 轮换前的主密钥（可选）
+
+	// keystore 解密（scrypt）耗时较长，按链缓存签名器
+	keystoreMu    sync.Mutex
+	keystoreCache map[string]cachedKeystoreSigner
+}
+
+type cachedKeystoreSigner struct {
+	fingerprint string
+	signer      signer.Signer
+}
+
+var (
+	walletVault   *WalletKeyVault
+	walletVaultMu sync.RWMutex
+)
+
+// InitWalletKeyVault 初始化私钥加密存储（启动时调用一次）
+// previousMasterKey 不为空时，用旧主密钥加密的私钥会在迁移时重新加密
+func InitWalletKeyVault(masterKey, previousMasterKey string) (*WalletKeyVault, error) {
+	master, err := secretbox.New(masterKey)
+	if err != nil {
+		return nil, fmt.Errorf("wallet master key: %w", err)
+	}
+
+	vault := &WalletKeyVault{
+		master:        master,
+		keystoreCache: make(map[string]cachedKeystoreSigner),
+	}
+	if previousMasterKey != "" {
+		if vault.previous, err = secretbox.New(previousMasterKey); err != nil {
+			return nil, fmt.Errorf("previous wallet master key: %w", err)
+		}
+	}
+
+	walletVaultMu.Lock()
+	walletVault = vault
+	walletVaultMu.Unlock()
+	return vault, nil
+}
+
+// GetWalletKeyVault 获取私钥加密存储
+func GetWalletKeyVault() (*WalletKeyVault, error) {
+	walletVaultMu.RLock()
+	defer walletVaultMu.RUnlock()
+	if walletVault == nil {
+		return nil, ErrWalletVaultNotReady
+	}
+	return walletVault, nil
+}
+
+// SealPrivateKey 校验并加密私钥，返回密文和对应地址
+func (v *WalletKeyVault) SealPrivateKey(privateKeyHex string) (string, string, error) {
+	address, err := signer.AddressFromPrivateKey(privateKeyHex)
+	if err != nil {
+		return "", "", err
+	}
+	sealed, err := v.SealSecret(privateKeyHex)
+	if err != nil {
+		return "", "", err
+	}
+	return sealed, address.Hex(), nil
+}
+
+// SealSecret 加密签名相关的敏感配置（keystore 密码、远程签名令牌等）
+func (v *WalletKeyVault) SealSecret(plaintext string) (string, error) {
+	return v.master.SealEnvelope(plaintext)
+}
+
+// OpenPrivateKey 解密私钥
+func (v *WalletKeyVault) OpenPrivateKey(sealed string) (string, error) {
+	return v.OpenSecret(sealed)
+}
+
+// OpenSecret 解密（依次尝试当前主密钥和旧主密钥）
+func (v *WalletKeyVault) OpenSecret(sealed string) (string, error) {
+	if !secretbox.IsEnvelope(sealed) {
+		return "", secretbox.ErrNotEnvelope
+	}
+	plaintext, err := v.master.OpenEnvelope(sealed)
+	if err != nil && v.previous != nil {
+		plaintext, err = v.previous.OpenEnvelope(sealed)
+	}
+	return plaintext, err
+}
+
+// SignerForChain 按链配置的签名方式创建交易签名器
+func (v *WalletKeyVault) SignerForChain(chain *models.ChainConfig) (signer.Signer, error) {
+	switch chain.SignerType {
+	case "", models.SignerTypeLocal:
+		return v.localSigner(chain)
+	case models.SignerTypeKeystore:
+		return v.keystoreSigner(chain)
+	case models.SignerTypeRemote:
+		return v.remoteSigner(chain)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrInvalidSignerType, chain.SignerType)
+	}
+}
+
+// localSigner 数据库中加密保存的私钥（仅在签名期间解密到内存）
+func (v *WalletKeyVault) localSigner(chain *models.ChainConfig) (signer.Signer, error) {
+	if chain.PlatformWithdrawPrivateKey == "" {
+		return nil, ErrWalletKeyNotConfigured
+	}
+	privateKeyHex, err := v.OpenPrivateKey(chain.PlatformWithdrawPrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt private key of chain %s: %w", chain.ChainName, err)
+	}
+	return signer.NewPrivateKeySigner(privateKeyHex)
+}
+
+// keystoreSigner go-ethereum keystore 文件（文件或密码变更后重新解密）
+func (v *WalletKeyVault) keystoreSigner(chain *models.ChainConfig) (signer.Signer, error) {
+	if chain.SignerKeystorePath == "" {
+		return nil, ErrWalletKeyNotConfigured
+	}
+	info, err := os.Stat(chain.SignerKeystorePath)
+	if err != nil {
+		return nil, fmt.Errorf("keystore file of chain %s: %w", chain.ChainName, err)
+	}
+	fingerprint := fmt.Sprintf("%s|%s|%d|%d", chain.SignerKeystorePath, chain.SignerKeystorePassword, info.Size(), info.ModTime().UnixNano())
+
+	v.keystoreMu.Lock()
+	defer v.keystoreMu.Unlock()
+	if cached, ok := v.keystoreCache[chain.ID]; ok && cached.fingerprint == fingerprint {
+		return cached.signer, nil
+	}
+
+	passphrase := ""
+	if chain.SignerKeystorePassword != "" {
+		if passphrase, err = v.OpenSecret(chain.SignerKeystorePassword); err != nil {
+			return nil, fmt.Errorf("failed to decrypt keystore password of chain %s: %w", chain.ChainName, err)
+		}
+	}
+	s, err := signer.NewKeystoreSigner(chain.SignerKeystorePath, passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	v.keystoreCache[chain.ID] = cachedKeystoreSigner{fingerprint: fingerprint, signer: s}
+	return s, nil
+}
+
+// remoteSigner 外部签名服务（私钥不进入本进程）
+func (v *WalletKeyVault) remoteSigner(chain *models.ChainConfig) (signer.Signer, error) {
+	if chain.SignerRemoteURL == "" || !common.IsHexAddress(chain.PlatformWithdrawAddress) {
+		return nil, ErrWalletKeyNotConfigured
+	}
+
+	opts := []signer.RemoteSignerOption{}
+	if chain.SignerRemoteMethod != "" {
+		opts = append(opts, signer.WithMethod(chain.SignerRemoteMethod))
+	}
+	if chain.SignerRemoteAuthToken != "" {
+		token, err := v.OpenSecret(chain.SignerRemoteAuthToken)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt remote signer token of chain %s: %w", chain.ChainName, err)
+		}
+		opts = append(opts, signer.WithAuthToken(token))
+	}
+
+	return signer.NewRemoteSigner(chain.SignerRemoteURL, common.HexToAddress(chain.PlatformWithdrawAddress), opts...), nil
+}
+
+// EncryptChainPrivateKeys 迁移：加密历史明文私钥、补全提现地址，并将旧主密钥加密的数据重新加密
+func (v *WalletKeyVault) EncryptChainPrivateKeys() error {
+	var chains []models.ChainConfig
+	if err := database.DB.Find(&chains).Error; err != nil {
+		return err
+	}
+
+	migrated := 0
+	for _, chain := range chains {
+		updates := map[string]interface{}{}
+
+		if chain.PlatformWithdrawPrivateKey != "" && !secretbox.IsEnvelope(chain.PlatformWithdrawPrivateKey) {
+			sealed, address, err := v.SealPrivateKey(chain.PlatformWithdrawPrivateKey)
+			if err != nil {
+				return fmt.Errorf("chain %s: %w", chain.ChainName, err)
+			}
+			updates["platform_withdraw_private_key"] = sealed
+			if chain.SignerType == "" || chain.SignerType == models.SignerTypeLocal {
+				updates["platform_withdraw_address"] = address
+			}
+		}
+
+		// 旧主密钥加密的数据重新加密
+		sealedFields := map[string]string{
+			"platform_withdraw_private_key": chain.PlatformWithdrawPrivateKey,
+			"signer_keystore_password":      chain.SignerKeystorePassword,
+			"signer_remote_auth_token":      chain.SignerRemoteAuthToken,
+			"deposit_sweep_key":             chain.DepositSweepKey,
+		}
+		for column, value := range sealedFields {
+			if !secretbox.IsEnvelope(value) {
+				continue
+			}
+			rewrapped, changed, err := v.rewrap(value)
+			if err != nil {
+				return fmt.Errorf("chain %s: %s cannot be decrypted with current or previous master key", chain.ChainName, column)
+			}
+			if changed {
+				updates[column] = rewrapped
+			}
+		}
+
+		// 补全本地私钥对应的提现地址
+		if (chain.SignerType == "" || chain.SignerType == models.SignerTypeLocal) &&
+			chain.PlatformWithdrawAddress == "" && secretbox.IsEnvelope(chain.PlatformWithdrawPrivateKey) {
+			s, err := v.localSigner(&chain)
+			if err != nil {
+				return err
+			}
+			updates["platform_withdraw_address"] = s.Address().Hex()
+		}
+
+		if len(updates) == 0 {
+			continue
+		}
+		if err := database.DB.Model(&models.ChainConfig{}).Where("id = ?", chain.ID).Updates(updates).Error; err != nil {
+			return fmt.Errorf("chain %s: %w", chain.ChainName, err)
+		}
+		migrated++
+	}
+
+	if migrated > 0 {
+		log.Printf("🔒 已加密/重新加密 %d 条链签名配置", migrated)
+	}
+	return nil
+}
+
+// rewrap 当前主密钥无法解密时，用旧主密钥解密数据密钥并以当前主密钥重新加密
+func (v *WalletKeyVault) rewrap(sealed string) (string, bool, error) {
+	if _, err := v.master.OpenEnvelope(sealed); err == nil {
+		return sealed, false, nil
+	}
+	if v.previous == nil {
+		return "", false, secretbox.ErrDecrypt
+	}
+	rewrapped, err := v.previous.RewrapEnvelope(sealed, v.master)
+	if err != nil {
+		return "", false, err
+	}
+	return rewrapped, true, nil
+}
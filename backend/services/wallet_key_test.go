@@ -0,0 +1,133 @@
+package services
+
+import (
+	"expchange-backend/database"
+	"expchange-backend/models"
+	"expchange-backend/pkg/secretbox"
+	"testing"
+)
+
+const (
+	testWalletKeyHex  = "4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318"
+	testWalletAddress = "0x2c7536E3605D9C16a7a3D7b1898e529396a65c23"
+)
+
+// newTestWalletVault 初始化全局私钥加密存储，测试结束后恢复
+func newTestWalletVault(t *testing.T, masterKey, previousMasterKey string) *WalletKeyVault {
+	t.Helper()
+	walletVaultMu.RLock()
+	previous := walletVault
+	walletVaultMu.RUnlock()
+	t.Cleanup(func() {
+		walletVaultMu.Lock()
+		walletVault = previous
+		walletVaultMu.Unlock()
+	})
+
+	vault, err := InitWalletKeyVault(masterKey, previousMasterKey)
+	if err != nil {
+		t.Fatalf("InitWalletKeyVault: %v", err)
+	}
+	return vault
+}
+
+// TestEncryptChainPrivateKeys 升级迁移：明文私钥只加密一次并补全提现地址，旧主密钥加密的配置重新加密，重复执行不再修改
+func TestEncryptChainPrivateKeys(t *testing.T) {
+	newTestDB(t, &models.ChainConfig{})
+	oldBox, err := secretbox.New("old-master-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealWithOld := func(plaintext string) string {
+		sealed, err := oldBox.SealEnvelope(plaintext)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sealed
+	}
+
+	chains := []*models.ChainConfig{
+		{ChainName: "plaintext", ChainID: 1, PlatformWithdrawPrivateKey: testWalletKeyHex},
+		{
+			ChainName: "old master", ChainID: 2, PlatformWithdrawPrivateKey: sealWithOld(testWalletKeyHex), PlatformWithdrawAddress: testWalletAddress,
+			SignerRemoteAuthToken: sealWithOld("remote-token"),
+		},
+		{ChainName: "keystore", ChainID: 3, SignerType: models.SignerTypeKeystore, SignerKeystorePath: "/etc/keystore.json", SignerKeystorePassword: sealWithOld("passphrase")},
+		{ChainName: "empty", ChainID: 4},
+	}
+	for _, chain := range chains {
+		if err := database.DB.Create(chain).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	vault := newTestWalletVault(t, "new-master-key", "old-master-key")
+	if err := vault.EncryptChainPrivateKeys(); err != nil {
+		t.Fatalf("EncryptChainPrivateKeys: %v", err)
+	}
+
+	var migrated []models.ChainConfig
+	database.DB.Order("chain_id").Find(&migrated)
+	newOnly := newTestWalletVault(t, "new-master-key", "")
+	for _, chain := range migrated[:2] {
+		if !secretbox.IsEnvelope(chain.PlatformWithdrawPrivateKey) {
+			t.Fatalf("chain %s private key not envelope encrypted", chain.ChainName)
+		}
+		// 迁移后只用新主密钥即可解密
+		if key, err := newOnly.OpenPrivateKey(chain.PlatformWithdrawPrivateKey); err != nil || key != testWalletKeyHex {
+			t.Fatalf("chain %s OpenPrivateKey=%q err=%v", chain.ChainName, key, err)
+		}
+		if chain.PlatformWithdrawAddress != testWalletAddress {
+			t.Fatalf("chain %s withdraw address=%s, want %s", chain.ChainName, chain.PlatformWithdrawAddress, testWalletAddress)
+		}
+	}
+	if token, err := newOnly.OpenSecret(migrated[1].SignerRemoteAuthToken); err != nil || token != "remote-token" {
+		t.Fatalf("remote token=%q err=%v", token, err)
+	}
+	if passphrase, err := newOnly.OpenSecret(migrated[2].SignerKeystorePassword); err != nil || passphrase != "passphrase" {
+		t.Fatalf("keystore password=%q err=%v", passphrase, err)
+	}
+	if migrated[2].PlatformWithdrawAddress != "" || migrated[3].PlatformWithdrawPrivateKey != "" {
+		t.Fatalf("unexpected changes: keystore address=%q empty key=%q", migrated[2].PlatformWithdrawAddress, migrated[3].PlatformWithdrawPrivateKey)
+	}
+
+	// 再次执行（下次启动）不重复加密
+	if err := vault.EncryptChainPrivateKeys(); err != nil {
+		t.Fatalf("second EncryptChainPrivateKeys: %v", err)
+	}
+	var again []models.ChainConfig
+	database.DB.Order("chain_id").Find(&again)
+	for i := range again {
+		if again[i].PlatformWithdrawPrivateKey != migrated[i].PlatformWithdrawPrivateKey ||
+			again[i].SignerKeystorePassword != migrated[i].SignerKeystorePassword ||
+			again[i].SignerRemoteAuthToken != migrated[i].SignerRemoteAuthToken {
+			t.Fatalf("chain %s changed on second migration", again[i].ChainName)
+		}
+	}
+}
+
+func TestEncryptChainPrivateKeysUnknownMaster(t *testing.T) {
+	newTestDB(t, &models.ChainConfig{})
+	otherBox, err := secretbox.New("unknown-master-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := otherBox.SealEnvelope(testWalletKeyHex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := database.DB.Create(&models.ChainConfig{ChainName: "bsc", ChainID: 56, PlatformWithdrawPrivateKey: sealed}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// 主密钥配置错误时拒绝启动，且不修改数据
+	vault := newTestWalletVault(t, "new-master-key", "old-master-key")
+	if err := vault.EncryptChainPrivateKeys(); err == nil {
+		t.Fatal("EncryptChainPrivateKeys succeeded with an unknown master key")
+	}
+	var chain models.ChainConfig
+	database.DB.First(&chain, "chain_id = ?", 56)
+	if chain.PlatformWithdrawPrivateKey != sealed {
+		t.Fatal("private key changed after a failed migration")
+	}
+}
@@ -0,0 +1,416 @@
+package services
+
+import (
+	"expchange-backend/database"
+	"expchange-backend/models"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/shopspring/decimal"
+)
+
+// pendingWithdrawStatuses 尚未在链上确认、仍需要热钱包出资的提现状态
+var pendingWithdrawStatuses = []string{"pending_review", "pending", "batching", "processing", "broadcast", "review"}
+
+var (
+	walletAlertMu   sync.Mutex
+	walletAlertSent = make(map[string]time.Time) // 告警键 -> 上次发送时间
+)
+
+// AssetCoverage 资产储备覆盖率：各链热钱包和冷钱包余额合计 / 用户负债
+type AssetCoverage struct {
+	Asset       string          `json:"asset"`
+	HotBalance  decimal.Decimal `json:"hot_balance"`
+	ColdBalance decimal.Decimal `json:"cold_balance"`
+	Liabilities decimal.Decimal `json:"liabilities"`
+	Ratio       decimal.Decimal `json:"ratio"`    // 负债为 0 时为 0
+	Complete    bool            `json:"complete"` // 所有链的余额都读取成功（否则比例偏低，不告警）
+}
+
+// WalletMonitorResult 一轮余额检查的结果
+type WalletMonitorResult struct {
+	Snapshots []models.WalletBalanceSnapshot `json:"snapshots"`
+	Coverage  []AssetCoverage                `json:"coverage"`
+}
+
+// CheckWalletBalances 检查各启用链提现热钱包（及冷钱包）的资产余额（由任务队列定时调用）
+// 与用户负债和待发出提现比较并记录快照；低于告警值或不足以支付待发出提现时告警，
+// 低于暂停值（或原生币不足以支付 gas）时自动暂停该资产提现，余额恢复后自动解除
+func (p *WithdrawProcessor) CheckWalletBalances() (*WalletMonitorResult, error) {
+	liabilities, err := userLiabilities()
+	if err != nil {
+		return nil, fmt.Errorf("failed to sum user liabilities: %w", err)
+	}
+	pending, err := pendingWithdrawAmounts()
+	if err != nil {
+		return nil, fmt.Errorf("failed to sum pending withdrawals: %w", err)
+	}
+
+	var chains []models.ChainConfig
+	if err := database.DB.Where("enabled = ?", true).Order("chain_id ASC").Find(&chains).Error; err != nil {
+		return nil, err
+	}
+	tokensByChain := GetChainTokensByChain()
+
+	result := &WalletMonitorResult{}
+	for i := range chains {
+		tokens := tokensByChain[chains[i].ChainID]
+		if len(tokens) == 0 {
+			continue
+		}
+		result.Snapshots = append(result.Snapshots, p.checkChainWallet(&chains[i], tokens, liabilities, pending)...)
+	}
+
+	if len(result.Snapshots) > 0 {
+		if err := database.DB.Create(&result.Snapshots).Error; err != nil {
+			return nil, fmt.Errorf("failed to save wallet balance snapshots: %w", err)
+		}
+	}
+
+	result.Coverage = walletCoverage(result.Snapshots, liabilities)
+	minRatio := database.GetSystemConfigManager().GetDecimal("monitor.wallet.min_coverage_ratio", decimal.NewFromInt(1))
+	for _, coverage := range result.Coverage {
+		low := coverage.Complete && coverage.Liabilities.IsPositive() && coverage.Ratio.LessThan(minRatio)
+		sendWalletAlert("coverage:"+coverage.Asset, low,
+			fmt.Sprintf("%s 储备覆盖率不足: %s", coverage.Asset, coverage.Ratio.StringFixed(4)),
+			fmt.Sprintf("热钱包 %s + 冷钱包 %s，用户负债 %s，要求覆盖率不低于 %s",
+				coverage.HotBalance.String(), coverage.ColdBalance.String(), coverage.Liabilities.String(), minRatio.String()))
+	}
+
+	pruneWalletSnapshots()
+	return result, nil
+}
+
+// checkChainWallet 检查一条链的全部资产；有资产余额读取失败时不改变该链的提现暂停状态
+func (p *WithdrawProcessor) checkChainWallet(chain *models.ChainConfig, tokens []models.ChainToken, liabilities, pending map[string]decimal.Decimal) []models.WalletBalanceSnapshot {
+	snapshots := make([]models.WalletBalanceSnapshot, len(tokens))
+	hotBalances := make([]decimal.Decimal, len(tokens))
+
+	client, clientErr := ChainClient(chain)
+	readFailed := false
+	for i := range tokens {
+		token := &tokens[i]
+		snapshot := &snapshots[i]
+		*snapshot = models.WalletBalanceSnapshot{
+			ChainID:            chain.ChainID,
+			Chain:              chain.ChainName,
+			Asset:              token.Asset,
+			HotAddress:         chain.PlatformWithdrawAddress,
+			ColdAddress:        chain.ColdWalletAddress,
+			PendingWithdrawals: pending[pendingKey(chain.ChainID, token.Asset)],
+			Liabilities:        liabilities[token.Asset],
+			Status:             "ok",
+		}
+
+		var err error
+		switch {
+		case clientErr != nil:
+			err = clientErr
+		case !common.IsHexAddress(chain.PlatformWithdrawAddress):
+			err = fmt.Errorf("withdraw hot wallet address is not configured")
+		default:
+			hotBalances[i], err = p.walletAssetBalance(client, token, chain.PlatformWithdrawAddress)
+			if err == nil && common.IsHexAddress(chain.ColdWalletAddress) {
+				snapshot.ColdBalance, err = p.walletAssetBalance(client, token, chain.ColdWalletAddress)
+				if err != nil {
+					err = fmt.Errorf("cold wallet: %w", err)
+				}
+			}
+		}
+		if err != nil {
+			readFailed = true
+			snapshot.Status = "error"
+			snapshot.Message = truncateMessage(err.Error())
+			continue
+		}
+		snapshot.HotBalance = hotBalances[i].Truncate(balancePrecision)
+		snapshot.ColdBalance = snapshot.ColdBalance.Truncate(balancePrecision)
+	}
+
+	// 原生币低于暂停值时热钱包无法支付 gas，该链所有资产一起暂停
+	gasReason := ""
+	for i := range tokens {
+		if tokens[i].IsNative() && snapshots[i].Status != "error" && belowThreshold(hotBalances[i], tokens[i].HotPauseBalance) {
+			gasReason = fmt.Sprintf("热钱包 %s 余额低于暂停值 %s，无法支付 gas", tokens[i].Asset, tokens[i].HotPauseBalance.String())
+		}
+	}
+
+	autoPause := database.GetSystemConfigManager().GetBool("monitor.wallet.auto_pause", true)
+	for i := range tokens {
+		token := &tokens[i]
+		snapshot := &snapshots[i]
+		alertKey := fmt.Sprintf("%d:%s", chain.ChainID, token.Asset)
+		if snapshot.Status == "error" {
+			sendWalletAlert("error:"+alertKey, true,
+				fmt.Sprintf("%s %s 钱包余额读取失败", chain.ChainName, token.Asset), snapshot.Message)
+			continue
+		}
+		sendWalletAlert("error:"+alertKey, false, "", "")
+
+		hot := hotBalances[i]
+		pauseReason := ""
+		if belowThreshold(hot, token.HotPauseBalance) {
+			pauseReason = fmt.Sprintf("热钱包余额低于暂停值 %s", token.HotPauseBalance.String())
+		} else if gasReason != "" && !token.IsNative() {
+			pauseReason = gasReason
+		}
+
+		var warnings []string
+		if belowThreshold(hot, token.HotAlertBalance) {
+			warnings = append(warnings, fmt.Sprintf("热钱包余额低于告警值 %s", token.HotAlertBalance.String()))
+		}
+		if hot.LessThan(snapshot.PendingWithdrawals) {
+			warnings = append(warnings, fmt.Sprintf("热钱包余额不足以支付待发出提现 %s", snapshot.PendingWithdrawals.String()))
+		}
+
+		switch {
+		case pauseReason != "":
+			snapshot.Status = "paused"
+			snapshot.Message = pauseReason
+		case len(warnings) > 0:
+			snapshot.Status = "low"
+			snapshot.Message = strings.Join(warnings, "；")
+		}
+
+		sendWalletAlert("low:"+alertKey, snapshot.Status != "ok",
+			fmt.Sprintf("%s %s 热钱包余额不足: %s", chain.ChainName, token.Asset, hot.Truncate(balancePrecision).String()),
+			fmt.Sprintf("地址 %s，%s", chain.PlatformWithdrawAddress, snapshot.Message))
+
+		if readFailed {
+			continue
+		}
+		if !autoPause {
+			pauseReason = ""
+		}
+		if err := setWithdrawPaused(chain, token, pauseReason); err != nil {
+			log.Printf("❌ 更新提现暂停状态失败: Chain=%s, Asset=%s, err=%v", chain.ChainName, token.Asset, err)
+		}
+	}
+	return snapshots
+}
+
+// walletAssetBalance 查询钱包地址的资产余额（按资产精度换算）
+func (p *WithdrawProcessor) walletAssetBalance(client *ethclient.Client, token *models.ChainToken, address string) (decimal.Decimal, error) {
+	balance, err := p.assetBalance(client, token, common.HexToAddress(address))
+	if err != nil {
+		return decimal.Zero, err
+	}
+	return decimal.NewFromBigInt(balance, -int32(token.Decimals)), nil
+}
+
+// setWithdrawPaused 设置资产的提现暂停状态（reason 为空表示恢复），状态变化时发送告警
+func setWithdrawPaused(chain *models.ChainConfig, token *models.ChainToken, reason string) error {
+	paused := reason != ""
+	reason = truncateMessage(reason)
+	if token.WithdrawPaused == paused && token.PauseReason == reason {
+		return nil
+	}
+	if err := database.DB.Model(&models.ChainToken{}).Where("id = ?", token.ID).Updates(map[string]interface{}{
+		"withdraw_paused": paused,
+		"pause_reason":    reason,
+	}).Error; err != nil {
+		return err
+	}
+
+	switch {
+	case paused && !token.WithdrawPaused:
+		log.Printf("⏸️  提现已自动暂停: Chain=%s, Asset=%s, 原因=%s", chain.ChainName, token.Asset, reason)
+		SendAlert(fmt.Sprintf("%s %s 提现已自动暂停", chain.ChainName, token.Asset), reason)
+	case !paused && token.WithdrawPaused:
+		log.Printf("▶️  提现已恢复: Chain=%s, Asset=%s", chain.ChainName, token.Asset)
+		SendAlert(fmt.Sprintf("%s %s 提现已恢复", chain.ChainName, token.Asset), "热钱包余额已恢复到暂停值以上")
+	}
+	token.WithdrawPaused = paused
+	token.PauseReason = reason
+	return nil
+}
+
+// WithdrawPauseReason 资产在该链的提现暂停原因（未暂停返回空）
+func WithdrawPauseReason(chainID int, asset string) string {
+	token, err := GetChainToken(chainID, asset)
+	if err != nil || !token.WithdrawPaused {
+		return ""
+	}
+	if token.PauseReason == "" {
+		return "withdrawals paused"
+	}
+	return token.PauseReason
+}
+
+// sendWalletAlert 发送余额告警：同一问题持续存在时按 monitor.wallet.alert_repeat_minutes 重复提醒，问题消失后重置
+func sendWalletAlert(key string, active bool, subject, body string) {
+	walletAlertMu.Lock()
+	if !active {
+		delete(walletAlertSent, key)
+		walletAlertMu.Unlock()
+		return
+	}
+	repeat := time.Duration(database.GetSystemConfigManager().GetInt("monitor.wallet.alert_repeat_minutes", 60)) * time.Minute
+	if last, ok := walletAlertSent[key]; ok && time.Since(last) < repeat {
+		walletAlertMu.Unlock()
+		return
+	}
+	walletAlertSent[key] = time.Now()
+	walletAlertMu.Unlock()
+
+	SendAlert(subject, body)
+}
+
+// userLiabilities 按资产汇总用户余额（可用+冻结，不含系统账户）
+func userLiabilities() (map[string]decimal.Decimal, error) {
+	var rows []struct {
+		Asset string
+		Total decimal.Decimal
+	}
+	err := database.DB.Table("balances").
+		Select("balances.asset AS asset, SUM(balances.available + balances.frozen) AS total").
+		Joins("JOIN users ON users.id = balances.user_id").
+		Where("users.wallet_address NOT IN ?", GetSystemWalletAddresses()).
+		Group("balances.asset").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]decimal.Decimal, len(rows))
+	for _, row := range rows {
+		result[row.Asset] = row.Total
+	}
+	return result, nil
+}
+
+// pendingWithdrawAmounts 按链和资产汇总尚未确认的提现到账金额（扣除手续费）
+func pendingWithdrawAmounts() (map[string]decimal.Decimal, error) {
+	var rows []struct {
+		ChainID int
+		Asset   string
+		Total   decimal.Decimal
+	}
+	err := database.DB.Model(&models.WithdrawRecord{}).
+		Select("chain_id, asset, SUM(amount - fee) AS total").
+		Where("status IN ?", pendingWithdrawStatuses).
+		Group("chain_id, asset").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]decimal.Decimal, len(rows))
+	for _, row := range rows {
+		result[pendingKey(row.ChainID, row.Asset)] = row.Total
+	}
+	return result, nil
+}
+
+func pendingKey(chainID int, asset string) string {
+	return fmt.Sprintf("%d:%s", chainID, asset)
+}
+
+// walletCoverage 按资产汇总各链钱包余额并计算覆盖率
+func walletCoverage(snapshots []models.WalletBalanceSnapshot, liabilities map[string]decimal.Decimal) []AssetCoverage {
+	var assets []string
+	byAsset := make(map[string]*AssetCoverage)
+	for _, snapshot := range snapshots {
+		coverage, ok := byAsset[snapshot.Asset]
+		if !ok {
+			coverage = &AssetCoverage{Asset: snapshot.Asset, Liabilities: liabilities[snapshot.Asset], Complete: true}
+			byAsset[snapshot.Asset] = coverage
+			assets = append(assets, snapshot.Asset)
+		}
+		if snapshot.Status == "error" {
+			coverage.Complete = false
+			continue
+		}
+		coverage.HotBalance = coverage.HotBalance.Add(snapshot.HotBalance)
+		coverage.ColdBalance = coverage.ColdBalance.Add(snapshot.ColdBalance)
+	}
+
+	result := make([]AssetCoverage, 0, len(assets))
+	for _, asset := range assets {
+		coverage := byAsset[asset]
+		if coverage.Liabilities.IsPositive() {
+			coverage.Ratio = coverage.HotBalance.Add(coverage.ColdBalance).DivRound(coverage.Liabilities, 4)
+		}
+		result = append(result, *coverage)
+	}
+	return result
+}
+
+// GetLatestWalletSnapshots 每条链每个资产最近一次的余额快照，以及按资产汇总的覆盖率
+func GetLatestWalletSnapshots() (*WalletMonitorResult, error) {
+	var latest models.WalletBalanceSnapshot
+	if err := database.DB.Order("created_at DESC").Limit(1).Find(&latest).Error; err != nil {
+		return nil, err
+	}
+	result := &WalletMonitorResult{Snapshots: []models.WalletBalanceSnapshot{}, Coverage: []AssetCoverage{}}
+	if latest.ID == "" {
+		return result, nil
+	}
+
+	// 同一轮检查的快照在同一时刻前后写入，取最近一轮
+	var snapshots []models.WalletBalanceSnapshot
+	if err := database.DB.Where("created_at >= ?", latest.CreatedAt.Add(-time.Minute)).
+		Order("chain_id ASC, asset ASC, created_at DESC").Find(&snapshots).Error; err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	for _, snapshot := range snapshots {
+		key := pendingKey(snapshot.ChainID, snapshot.Asset)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		result.Snapshots = append(result.Snapshots, snapshot)
+	}
+
+	liabilities, err := userLiabilities()
+	if err != nil {
+		return nil, err
+	}
+	result.Coverage = walletCoverage(result.Snapshots, liabilities)
+	return result, nil
+}
+
+// GetWalletSnapshotHistory 余额快照历史（可按链和资产筛选，最近 500 条）
+func GetWalletSnapshotHistory(chainID int, asset string) []models.WalletBalanceSnapshot {
+	query := database.DB.Order("created_at DESC").Limit(500)
+	if chainID > 0 {
+		query = query.Where("chain_id = ?", chainID)
+	}
+	if asset != "" {
+		query = query.Where("asset = ?", normalizeAsset(asset))
+	}
+
+	var snapshots []models.WalletBalanceSnapshot
+	query.Find(&snapshots)
+	return snapshots
+}
+
+// pruneWalletSnapshots 删除超过 monitor.wallet.history_days 的快照
+func pruneWalletSnapshots() {
+	days := database.GetSystemConfigManager().GetInt("monitor.wallet.history_days", 30)
+	if days < 1 {
+		return
+	}
+	cutoff := time.Now().AddDate(0, 0, -days)
+	database.DB.Where("created_at < ?", cutoff).Delete(&models.WalletBalanceSnapshot{})
+}
+
+// belowThreshold 阈值大于 0 且余额低于阈值
+func belowThreshold(balance, threshold decimal.Decimal) bool {
+	return threshold.IsPositive() && balance.LessThan(threshold)
+}
+
+// truncateMessage 截断到 255 个字符以内（快照和暂停原因字段长度）
+func truncateMessage(message string) string {
+	runes := []rune(message)
+	if len(runes) > 255 {
+		return string(runes[:255])
+	}
+	return message
+}
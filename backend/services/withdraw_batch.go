@@ -0,0 +1,614 @@
+package services
+
+import (
+	"errors"
+	"expchange-backend/database"
+	"expchange-backend/models"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/shopspring/decimal"
+)
+
+// Disperse 合约（disperse.app）批量转账 ABI：合约先 transferFrom 热钱包的总额，再逐笔 transfer 给收款地址
+const multisendABI = `[{"constant":false,"inputs":[{"name":"token","type":"address"},{"name":"recipients","type":"address[]"},{"name":"values","type":"uint256[]"}],"name":"disperseToken","outputs":[],"type":"function"}]`
+
+// ERC20 allowance ABI
+const allowanceABI = `[{"constant":true,"inputs":[{"name":"owner","type":"address"},{"name":"spender","type":"address"}],"name":"allowance","outputs":[{"name":"","type":"uint256"}],"type":"function"}]`
+
+// transferEventTopic ERC20 Transfer(address,address,uint256) 事件签名
+var transferEventTopic = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+
+var ErrMultisendAllowance = errors.New("insufficient token allowance for the multisend contract")
+
+// queueForBatch 批量模式下把待处理的代币提现放入批量队列（pending → batching），返回 true 表示无需单笔发送
+// 原生币提现始终单笔发送
+func (p *WithdrawProcessor) queueForBatch(withdrawal *models.WithdrawRecord) bool {
+	if !database.GetSystemConfigManager().GetBool("withdraw.batch.enabled", false) {
+		return false
+	}
+
+	var chain models.ChainConfig
+	if err := database.DB.Where("chain_id = ? AND enabled = ?", withdrawal.ChainID, true).First(&chain).Error; err != nil {
+		return false
+	}
+	if chain.MultisendContractAddress == "" {
+		return false
+	}
+	if token, err := GetChainToken(chain.ChainID, withdrawal.Asset); err != nil || token.IsNative() {
+		return false
+	}
+
+	result := database.DB.Model(&models.WithdrawRecord{}).
+		Where("id = ? AND status = ?", withdrawal.ID, "pending").
+		Updates(map[string]interface{}{"status": "batching", "updated_at": time.Now()})
+	if result.Error != nil {
+		log.Printf("⚠️  提现进入批量队列失败，改为单笔发送: ID=%s, err=%v", withdrawal.ID, result.Error)
+		return false
+	}
+	if result.RowsAffected == 0 {
+		log.Printf("⚠️  提现记录已被处理，跳过: ID=%s", withdrawal.ID)
+		return true
+	}
+
+	withdrawal.Status = "batching"
+	log.Printf("📦 提现进入批量队列: ID=%s, Chain=%s", withdrawal.ID, chain.ChainName)
+	return true
+}
+
+// SendWithdrawBatches 发送批量队列中的提现（由任务队列定时调用）
+// 按链和资产分组，每组最早进入队列的提现等待满 withdraw.batch.window_seconds，或数量达到 withdraw.batch.max_size 时发送；
+// 批量模式关闭或链未配置 multisend 合约时逐笔发送；资产提现暂停时不发送
+func (p *WithdrawProcessor) SendWithdrawBatches() {
+	var records []models.WithdrawRecord
+	// batching 状态下 updated_at 即进入批量队列的时间
+	database.DB.Where("status = ?", "batching").Order("updated_at ASC, id ASC").Find(&records)
+	if len(records) == 0 {
+		return
+	}
+
+	sysConfig := database.GetSystemConfigManager()
+	enabled := sysConfig.GetBool("withdraw.batch.enabled", false)
+	window := time.Duration(sysConfig.GetInt("withdraw.batch.window_seconds", 60)) * time.Second
+	maxSize := sysConfig.GetInt("withdraw.batch.max_size", 50)
+	if maxSize < 2 {
+		maxSize = 2
+	}
+
+	type groupKey struct {
+		chainID int
+		asset   string
+	}
+	var keys []groupKey
+	groups := make(map[groupKey][]models.WithdrawRecord)
+	for _, record := range records {
+		key := groupKey{chainID: record.ChainID, asset: record.Asset}
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], record)
+	}
+
+	for _, key := range keys {
+		group := groups[key]
+		// 热钱包余额不足暂停提现时留在队列中，余额监控恢复后再发送
+		if WithdrawPauseReason(key.chainID, key.asset) != "" {
+			continue
+		}
+
+		var chain models.ChainConfig
+		err := database.DB.Where("chain_id = ? AND enabled = ?", key.chainID, true).First(&chain).Error
+		if err != nil || !enabled || chain.MultisendContractAddress == "" {
+			for i := range group {
+				p.fallbackToIndividual(&group[i], "batching", "batching disabled for this chain")
+			}
+			continue
+		}
+		token, err := GetChainToken(chain.ChainID, key.asset)
+		if err != nil || token.IsNative() {
+			for i := range group {
+				p.fallbackToIndividual(&group[i], "batching", "batching not available for this asset")
+			}
+			continue
+		}
+
+		// 窗口未到期时只发送凑满的批次，剩余的继续等待
+		count := len(group)
+		if time.Since(group[0].UpdatedAt) < window {
+			count -= count % maxSize
+		}
+
+		for start := 0; start < count; start += maxSize {
+			end := start + maxSize
+			if end > count {
+				end = count
+			}
+			if end-start == 1 {
+				p.fallbackToIndividual(&group[start], "batching", "only one withdrawal in batch window")
+				continue
+			}
+			p.sendBatch(&chain, token, group[start:end])
+		}
+	}
+}
+
+// sendBatch 把同一代币的一组提现合并为一笔 multisend 交易发送，失败时全部回退为单笔发送
+func (p *WithdrawProcessor) sendBatch(chain *models.ChainConfig, token *models.ChainToken, records []models.WithdrawRecord) {
+	batch := models.WithdrawBatch{
+		ChainID:         chain.ChainID,
+		Asset:           token.Asset,
+		ContractAddress: chain.MultisendContractAddress,
+		Status:          "processing",
+	}
+	if err := database.DB.Create(&batch).Error; err != nil {
+		log.Printf("❌ 创建批量提现失败: %v", err)
+		return
+	}
+
+	// 条件更新认领记录（batching → processing），防止与其他流程并发处理
+	ids := make([]string, 0, len(records))
+	for _, record := range records {
+		ids = append(ids, record.ID)
+	}
+	database.DB.Model(&models.WithdrawRecord{}).
+		Where("id IN ? AND status = ?", ids, "batching").
+		Updates(map[string]interface{}{"status": "processing", "batch_id": batch.ID})
+
+	var claimed []models.WithdrawRecord
+	database.DB.Where("batch_id = ? AND status = ?", batch.ID, "processing").Order("id ASC").Find(&claimed)
+	if len(claimed) < 2 {
+		p.failBatch(&batch, claimed, "processing", "not enough withdrawals for a batch")
+		return
+	}
+
+	signedTx, params, fromAddress, total, err := p.sendBatchTx(chain, token, claimed)
+	if err != nil {
+		p.failBatch(&batch, claimed, "processing", err.Error())
+		return
+	}
+
+	now := time.Now()
+	gasPrice, gasTipCap := params.feeStrings()
+	txFields := map[string]interface{}{
+		"status":       "broadcast",
+		"tx_hash":      signedTx.Hash().Hex(),
+		"from_address": fromAddress,
+		"nonce":        signedTx.Nonce(),
+		"tx_type":      params.TxType,
+		"gas_limit":    params.GasLimit,
+		"gas_price":    gasPrice,
+		"gas_tip_cap":  gasTipCap,
+		"broadcast_at": now,
+		"updated_at":   now,
+	}
+
+	batchFields := map[string]interface{}{"record_count": len(claimed), "total_amount": total}
+	for key, value := range txFields {
+		batchFields[key] = value
+	}
+	if err := database.DB.Model(&batch).Updates(batchFields).Error; err != nil {
+		// 交易已发出，不能回退为单笔发送；记录保持 processing 由人工核对
+		log.Printf("❌ 记录批量提现交易失败（需人工核对）: BatchID=%s, TxHash=%s, err=%v", batch.ID, signedTx.Hash().Hex(), err)
+		return
+	}
+	if err := database.DB.Model(&models.WithdrawRecord{}).
+		Where("batch_id = ? AND status = ?", batch.ID, "processing").
+		Updates(txFields).Error; err != nil {
+		log.Printf("❌ 更新批量提现记录失败（需人工核对）: BatchID=%s, err=%v", batch.ID, err)
+		return
+	}
+
+	log.Printf("📦 批量提现已广播: BatchID=%s, Chain=%s, 笔数=%d, 总额=%s %s, TxHash=%s",
+		batch.ID, chain.ChainName, len(claimed), total.String(), token.Asset, signedTx.Hash().Hex())
+}
+
+// sendBatchTx 构建、签名并发送 multisend 交易
+func (p *WithdrawProcessor) sendBatchTx(chain *models.ChainConfig, token *models.ChainToken, records []models.WithdrawRecord) (*types.Transaction, *WithdrawTxParams, string, decimal.Decimal, error) {
+	vault, err := GetWalletKeyVault()
+	if err != nil {
+		return nil, nil, "", decimal.Zero, err
+	}
+	txSigner, err := vault.SignerForChain(chain)
+	if err != nil {
+		return nil, nil, "", decimal.Zero, fmt.Errorf("withdraw signer not available: %w", err)
+	}
+
+	client, err := ChainClient(chain)
+	if err != nil {
+		return nil, nil, "", decimal.Zero, err
+	}
+
+	data, total, err := packMultisendData(token, records)
+	if err != nil {
+		return nil, nil, "", decimal.Zero, err
+	}
+
+	// Disperse 合约通过 transferFrom 从热钱包扣款，需要预先 approve 足够额度
+	contract := common.HexToAddress(chain.MultisendContractAddress)
+	allowance, err := p.tokenAllowance(client, common.HexToAddress(token.ContractAddress), txSigner.Address(), contract)
+	if err != nil {
+		return nil, nil, "", decimal.Zero, err
+	}
+	if allowance.Cmp(tokenUnits(total, token.Decimals)) < 0 {
+		return nil, nil, "", decimal.Zero, fmt.Errorf("%w: %s < %s %s", ErrMultisendAllowance,
+			decimal.NewFromBigInt(allowance, int32(-token.Decimals)).String(), total.String(), token.Asset)
+	}
+
+	// 估算 gas 和费用（在获取 nonce 之前，失败时不占用 nonce）
+	params, err := SuggestWithdrawTxParams(p.ctx, client, chain, txSigner.Address(), contract, nil, data)
+	if err != nil {
+		return nil, nil, "", decimal.Zero, err
+	}
+
+	fromAddress := txSigner.Address().Hex()
+	lease, err := p.nonceManager.AcquireNonce(client, fromAddress, chain.ChainID)
+	if err != nil {
+		return nil, nil, "", decimal.Zero, fmt.Errorf("failed to acquire nonce: %w", err)
+	}
+	defer lease.Rollback()
+
+	signedTx, err := p.signAndSend(client, txSigner, chain, contract, nil, lease.Nonce, params, data)
+	if err != nil {
+		return nil, nil, "", decimal.Zero, err
+	}
+	if err := lease.Commit(); err != nil {
+		log.Printf("⚠️  保存 nonce 失败（内存已更新）: %v", err)
+	}
+	return signedTx, params, fromAddress, total, nil
+}
+
+// packMultisendData 打包 disperseToken 调用数据，返回数据和转出总额（扣除手续费后）
+func packMultisendData(token *models.ChainToken, records []models.WithdrawRecord) ([]byte, decimal.Decimal, error) {
+	parsedABI, err := abi.JSON(strings.NewReader(multisendABI))
+	if err != nil {
+		return nil, decimal.Zero, fmt.Errorf("failed to parse multisend ABI: %w", err)
+	}
+
+	recipients := make([]common.Address, 0, len(records))
+	values := make([]*big.Int, 0, len(records))
+	total := decimal.Zero
+	for _, record := range records {
+		amount := record.Amount.Sub(record.Fee)
+		recipients = append(recipients, common.HexToAddress(record.Address))
+		values = append(values, tokenUnits(amount, token.Decimals))
+		total = total.Add(amount)
+	}
+
+	data, err := parsedABI.Pack("disperseToken", common.HexToAddress(token.ContractAddress), recipients, values)
+	if err != nil {
+		return nil, decimal.Zero, fmt.Errorf("failed to pack multisend data: %w", err)
+	}
+	return data, total, nil
+}
+
+// tokenAllowance 查询 ERC20 授权额度
+func (p *WithdrawProcessor) tokenAllowance(client *ethclient.Client, token, owner, spender common.Address) (*big.Int, error) {
+	parsedABI, err := abi.JSON(strings.NewReader(allowanceABI))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse allowance ABI: %w", err)
+	}
+	data, err := parsedABI.Pack("allowance", owner, spender)
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := client.CallContract(p.ctx, ethereum.CallMsg{To: &token, Data: data}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query allowance: %w", err)
+	}
+	values, err := parsedABI.Unpack("allowance", output)
+	if err != nil || len(values) != 1 {
+		return nil, fmt.Errorf("invalid allowance result: %x", output)
+	}
+	allowance, ok := values[0].(*big.Int)
+	if !ok {
+		return nil, fmt.Errorf("invalid allowance result: %x", output)
+	}
+	return allowance, nil
+}
+
+// failBatch 标记批量交易失败，并把包含的提现回退为单笔发送
+func (p *WithdrawProcessor) failBatch(batch *models.WithdrawBatch, records []models.WithdrawRecord, fromStatus, reason string) {
+	database.DB.Model(batch).Updates(map[string]interface{}{
+		"status":      "failed",
+		"fail_reason": truncate(reason, 255),
+	})
+	log.Printf("❌ 批量提现失败，回退为单笔发送: BatchID=%s, 笔数=%d, 原因=%s", batch.ID, len(records), reason)
+
+	for i := range records {
+		p.fallbackToIndividual(&records[i], fromStatus, reason)
+	}
+}
+
+// holdBatchForReview 无法确认批量交易是否已打包：批量转入 review，包含的提现保持 broadcast 并冻结资金，由人工核对
+func (p *WithdrawProcessor) holdBatchForReview(batch *models.WithdrawBatch, reason string) {
+	database.DB.Model(batch).
+		Where("status = ?", "broadcast").
+		Updates(map[string]interface{}{
+			"status":      "review",
+			"fail_reason": truncate(reason, 255),
+		})
+	log.Printf("❌ 批量提现状态无法确认（需人工核对）: BatchID=%s, TxHash=%s, 原因=%s", batch.ID, batch.TxHash, reason)
+	SendAlert("批量提现需人工核对", fmt.Sprintf("BatchID=%s\nChainID=%d\nNonce=%d\nTxHash=%s\n原因=%s",
+		batch.ID, batch.ChainID, batch.Nonce, batch.TxHash, reason))
+}
+
+// fallbackToIndividual 把批量队列或失败批量中的提现重置为 pending 并单笔发送
+func (p *WithdrawProcessor) fallbackToIndividual(withdrawal *models.WithdrawRecord, fromStatus, reason string) {
+	result := database.DB.Model(&models.WithdrawRecord{}).
+		Where("id = ? AND status = ?", withdrawal.ID, fromStatus).
+		Updates(map[string]interface{}{
+			"status":        "pending",
+			"batch_id":      "",
+			"tx_hash":       "",
+			"from_address":  "",
+			"nonce":         0,
+			"tx_type":       "",
+			"gas_limit":     0,
+			"gas_price":     "",
+			"gas_tip_cap":   "",
+			"broadcast_at":  nil,
+			"block_number":  0,
+			"confirmations": 0,
+			"updated_at":    time.Now(),
+		})
+	if result.Error != nil || result.RowsAffected == 0 {
+		log.Printf("⚠️  提现状态已变更，跳过单笔回退: ID=%s", withdrawal.ID)
+		return
+	}
+
+	log.Printf("↩️  提现改为单笔发送: ID=%s, 原因=%s", withdrawal.ID, reason)
+	withdrawal.Status = "pending"
+	withdrawal.BatchID = ""
+	p.sendIndividually(withdrawal)
+}
+
+// trackBatch 检查已广播批量交易的链上状态
+func (p *WithdrawProcessor) trackBatch(client *ethclient.Client, chain *models.ChainConfig, batch *models.WithdrawBatch) error {
+	required := requiredConfirmations(chain)
+	hashes := splitTxHashes(batch.TxHash, batch.ReplacedTxHashes)
+	result, err := p.checkTx(client, batch.FromAddress, batch.Nonce, hashes, required)
+	if err != nil {
+		return err
+	}
+
+	records := batchRecords(batch.ID)
+
+	if result.Receipt == nil {
+		if result.NonceConsumed {
+			// 回退为单笔发送会再次转账，必须先在同一节点确认批量交易（含被替换的交易）均不存在
+			dropped, err := p.confirmTxDropped(client, hashes)
+			if err != nil {
+				return err
+			}
+			if !dropped {
+				p.holdBatchForReview(batch, "nonce consumed but batch transaction is still known to the node")
+				return nil
+			}
+			p.failBatch(batch, records, "broadcast", "Transaction dropped: nonce consumed by another transaction")
+			return nil
+		}
+
+		if batch.BlockNumber > 0 {
+			log.Printf("⚠️  批量提现交易回执消失（可能发生链重组）: BatchID=%s, TxHash=%s", batch.ID, batch.TxHash)
+			updateBatchConfirmations(batch, 0, 0)
+			return nil
+		}
+
+		if isStuck(batch.BroadcastAt) {
+			if err := p.bumpBatch(client, chain, batch, records); err != nil {
+				if errors.Is(err, ErrWithdrawMaxBumps) {
+					log.Printf("⚠️  批量提现交易长时间未打包且已达最大加速次数，需人工处理: BatchID=%s, TxHash=%s", batch.ID, batch.TxHash)
+					return nil
+				}
+				return fmt.Errorf("bump fee: %w", err)
+			}
+		}
+		return nil
+	}
+
+	if result.Receipt.Status != types.ReceiptStatusSuccessful {
+		p.failBatch(batch, records, "broadcast", fmt.Sprintf("Transaction reverted: %s", result.MinedHash))
+		return nil
+	}
+
+	if result.Confirmations < required {
+		if result.Confirmations != batch.Confirmations || result.BlockNumber != batch.BlockNumber {
+			updateBatchConfirmations(batch, result.BlockNumber, result.Confirmations)
+		}
+		return nil
+	}
+
+	// 逐笔核对回执中的 Transfer 事件：已到账的完成，未找到的回退为单笔发送
+	token, err := GetChainToken(chain.ChainID, batch.Asset)
+	if err != nil {
+		return err
+	}
+	included := matchBatchTransfers(result.Receipt, token, batch, records)
+	confirmed := 0
+	for i := range records {
+		record := &records[i]
+		if !included[i] {
+			p.fallbackToIndividual(record, "broadcast", "transfer not found in batch receipt")
+			continue
+		}
+		record.BlockNumber = result.BlockNumber
+		record.Confirmations = result.Confirmations
+		p.ConfirmWithdrawal(record, result.MinedHash)
+		confirmed++
+	}
+
+	database.DB.Model(batch).Updates(map[string]interface{}{
+		"status":        "completed",
+		"tx_hash":       result.MinedHash,
+		"block_number":  result.BlockNumber,
+		"confirmations": result.Confirmations,
+	})
+	log.Printf("🎉 批量提现已确认: BatchID=%s, 到账=%d/%d, TxHash=%s", batch.ID, confirmed, len(records), result.MinedHash)
+	return nil
+}
+
+// matchBatchTransfers 按收款地址和金额匹配回执中的 Transfer 事件（每个事件只匹配一次）
+func matchBatchTransfers(receipt *types.Receipt, token *models.ChainToken, batch *models.WithdrawBatch, records []models.WithdrawRecord) []bool {
+	contract := common.HexToAddress(token.ContractAddress)
+	// Disperse 的 disperseToken 从合约转出，disperseTokenSimple 从热钱包直接转出
+	senders := map[common.Address]bool{
+		common.HexToAddress(batch.FromAddress):     true,
+		common.HexToAddress(batch.ContractAddress): true,
+	}
+
+	used := make([]bool, len(receipt.Logs))
+	included := make([]bool, len(records))
+	for i, record := range records {
+		recipient := common.HexToAddress(record.Address)
+		value := tokenUnits(record.Amount.Sub(record.Fee), token.Decimals)
+
+		for j, entry := range receipt.Logs {
+			if used[j] || entry.Address != contract || len(entry.Topics) != 3 || entry.Topics[0] != transferEventTopic {
+				continue
+			}
+			if !senders[common.BytesToAddress(entry.Topics[1].Bytes())] ||
+				common.BytesToAddress(entry.Topics[2].Bytes()) != recipient ||
+				new(big.Int).SetBytes(entry.Data).Cmp(value) != 0 {
+				continue
+			}
+			used[j] = true
+			included[i] = true
+			break
+		}
+	}
+	return included
+}
+
+// bumpBatch 使用相同 nonce、更高的 gas price 重新广播批量交易
+func (p *WithdrawProcessor) bumpBatch(client *ethclient.Client, chain *models.ChainConfig, batch *models.WithdrawBatch, records []models.WithdrawRecord) error {
+	if batch.BumpCount >= database.GetSystemConfigManager().GetInt("withdraw.tracker.max_bumps", 3) {
+		return ErrWithdrawMaxBumps
+	}
+	if len(records) == 0 {
+		return fmt.Errorf("batch %s has no broadcast withdrawals", batch.ID)
+	}
+
+	oldParams, err := parseWithdrawTxParams(batch.TxType, batch.GasLimit, batch.GasPrice, batch.GasTipCap)
+	if err != nil {
+		return err
+	}
+	token, err := GetChainToken(chain.ChainID, batch.Asset)
+	if err != nil {
+		return err
+	}
+	data, _, err := packMultisendData(token, records)
+	if err != nil {
+		return err
+	}
+
+	signedTx, params, err := p.signReplacementTx(client, chain, batch.FromAddress, batch.Nonce, oldParams,
+		common.HexToAddress(batch.ContractAddress), nil, data)
+	if err != nil {
+		return err
+	}
+	// 广播前先记录新交易hash：广播后保存失败时，新交易被打包也能通过回执找到
+	pending := appendTxHash(batch.ReplacedTxHashes, signedTx.Hash().Hex())
+	if err := database.DB.Model(&models.WithdrawBatch{}).
+		Where("id = ? AND status = ?", batch.ID, "broadcast").
+		Update("replaced_tx_hashes", pending).Error; err != nil {
+		return fmt.Errorf("save replacement tx %s: %w", signedTx.Hash().Hex(), err)
+	}
+	if err := p.sendSignedTx(client, signedTx); err != nil {
+		// 未广播成功的hash留在列表中，查询不到时跳过
+		batch.ReplacedTxHashes = pending
+		return err
+	}
+
+	now := time.Now()
+	gasPrice, gasTipCap := params.feeStrings()
+	txFields := map[string]interface{}{
+		"tx_hash":      signedTx.Hash().Hex(),
+		"tx_type":      params.TxType,
+		"gas_limit":    params.GasLimit,
+		"gas_price":    gasPrice,
+		"gas_tip_cap":  gasTipCap,
+		"broadcast_at": now,
+		"updated_at":   now,
+	}
+	batchFields := map[string]interface{}{
+		"bump_count":         batch.BumpCount + 1,
+		"replaced_tx_hashes": appendTxHash(batch.ReplacedTxHashes, batch.TxHash),
+	}
+	for key, value := range txFields {
+		batchFields[key] = value
+	}
+	if err := database.DB.Model(&models.WithdrawBatch{}).
+		Where("id = ? AND status = ?", batch.ID, "broadcast").
+		Updates(batchFields).Error; err != nil {
+		// 新交易已发出，其hash已在 replaced_tx_hashes 中，下一轮会通过 nonce 和回执继续跟踪
+		return fmt.Errorf("save replacement tx %s: %w", signedTx.Hash().Hex(), err)
+	}
+	database.DB.Model(&models.WithdrawRecord{}).
+		Where("batch_id = ? AND status = ?", batch.ID, "broadcast").
+		Updates(txFields)
+
+	log.Printf("⛽ 批量提现交易已加速替换: BatchID=%s, Nonce=%d, GasPrice=%s -> %s, TxHash=%s -> %s",
+		batch.ID, batch.Nonce, batch.GasPrice, gasPrice, batch.TxHash, signedTx.Hash().Hex())
+	return nil
+}
+
+// speedUpBatch 管理员手动加速批量交易（调用方持有 trackMu）
+func (p *WithdrawProcessor) speedUpBatch(batchID string) error {
+	var batch models.WithdrawBatch
+	if err := database.DB.Where("id = ?", batchID).First(&batch).Error; err != nil {
+		return err
+	}
+	if batch.Status != "broadcast" {
+		return ErrWithdrawNotBroadcast
+	}
+
+	var chain models.ChainConfig
+	if err := database.DB.Where("chain_id = ?", batch.ChainID).First(&chain).Error; err != nil {
+		return fmt.Errorf("chain %d not found", batch.ChainID)
+	}
+
+	client, err := ChainClient(&chain)
+	if err != nil {
+		return err
+	}
+
+	// 已被打包的交易不能再替换
+	for _, hash := range splitTxHashes(batch.TxHash, batch.ReplacedTxHashes) {
+		if _, err := client.TransactionReceipt(p.ctx, common.HexToHash(hash)); err == nil {
+			return fmt.Errorf("transaction %s is already mined", hash)
+		}
+	}
+
+	return p.bumpBatch(client, &chain, &batch, batchRecords(batch.ID))
+}
+
+// batchRecords 批量交易中仍在等待确认的提现记录
+func batchRecords(batchID string) []models.WithdrawRecord {
+	var records []models.WithdrawRecord
+	database.DB.Where("batch_id = ? AND status = ?", batchID, "broadcast").Order("id ASC").Find(&records)
+	return records
+}
+
+// updateBatchConfirmations 同步更新批量交易及其提现记录的打包区块和确认数
+func updateBatchConfirmations(batch *models.WithdrawBatch, blockNumber uint64, confirmations int) {
+	updates := map[string]interface{}{
+		"block_number":  blockNumber,
+		"confirmations": confirmations,
+	}
+	database.DB.Model(batch).Updates(updates)
+	database.DB.Model(&models.WithdrawRecord{}).
+		Where("batch_id = ? AND status = ?", batch.ID, "broadcast").
+		Updates(updates)
+	batch.BlockNumber = blockNumber
+	batch.Confirmations = confirmations
+}
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"errors"
+	"expchange-backend/database"
+	"expchange-backend/models"
+	"fmt"
+	"strings"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrWithdrawBelowMinimum = errors.New("withdrawal amount is below the minimum")
+	ErrWithdrawBelowFee     = errors.New("withdrawal amount must be greater than the fee")
+)
+
+// GetWithdrawFee 获取链上资产的提现手续费配置（未配置时返回零手续费、无最小额）
+func GetWithdrawFee(chainID int, asset string) models.WithdrawFee {
+	fee := models.WithdrawFee{ChainID: chainID, Asset: asset}
+	database.DB.Where("chain_id = ? AND asset = ?", chainID, asset).Limit(1).Find(&fee)
+	return fee
+}
+
+// CalculateWithdrawFee 校验最小提现额并返回手续费（amount 为含手续费的申请金额）
+func CalculateWithdrawFee(chainID int, asset string, amount decimal.Decimal) (decimal.Decimal, error) {
+	config := GetWithdrawFee(chainID, asset)
+
+	if amount.LessThan(config.MinAmount) {
+		return decimal.Zero, fmt.Errorf("%w: %s %s", ErrWithdrawBelowMinimum, config.MinAmount.String(), asset)
+	}
+	if !amount.GreaterThan(config.Fee) {
+		return decimal.Zero, fmt.Errorf("%w: %s %s", ErrWithdrawBelowFee, config.Fee.String(), asset)
+	}
+	return config.Fee, nil
+}
+
+// GetWithdrawFeesByChain 按链ID分组的提现手续费配置
+func GetWithdrawFeesByChain() map[int][]models.WithdrawFee {
+	var fees []models.WithdrawFee
+	database.DB.Order("chain_id ASC, asset ASC").Find(&fees)
+
+	result := make(map[int][]models.WithdrawFee)
+	for _, fee := range fees {
+		result[fee.ChainID] = append(result[fee.ChainID], fee)
+	}
+	return result
+}
+
+// UpsertWithdrawFee 创建或更新提现手续费配置
+func UpsertWithdrawFee(chainID int, asset string, fee, minAmount decimal.Decimal) (*models.WithdrawFee, error) {
+	asset = strings.ToUpper(strings.TrimSpace(asset))
+	if asset == "" {
+		return nil, errors.New("asset is required")
+	}
+	if fee.IsNegative() || minAmount.IsNegative() {
+		return nil, errors.New("fee and min_amount must not be negative")
+	}
+
+	config := models.WithdrawFee{ChainID: chainID, Asset: asset, Fee: fee, MinAmount: minAmount}
+	if err := database.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "chain_id"}, {Name: "asset"}},
+		DoUpdates: clause.AssignmentColumns([]string{"fee", "min_amount", "updated_at"}),
+	}).Create(&config).Error; err != nil {
+		return nil, err
+	}
+
+	result := GetWithdrawFee(chainID, asset)
+	return &result, nil
+}
+
+// DeleteWithdrawFee 删除提现手续费配置（删除后该资产不收手续费）
+func DeleteWithdrawFee(chainID int, asset string) error {
+	result := database.DB.Where("chain_id = ? AND asset = ?", chainID, strings.ToUpper(asset)).Delete(&models.WithdrawFee{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("withdraw fee not found")
+	}
+	return nil
+}
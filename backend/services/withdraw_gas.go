@@ -0,0 +1,294 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"expchange-backend/database"
+	"expchange-backend/models"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/shopspring/decimal"
+)
+
+var (
+	ErrGasPriceAboveCap   = errors.New("network gas price exceeds the chain gas cap")
+	ErrGasLimitAboveCap   = errors.New("estimated gas limit exceeds the chain gas limit cap")
+	ErrEIP1559Unsupported = errors.New("chain does not support EIP-1559 (no base fee)")
+	ErrInvalidTxType      = errors.New("invalid tx type")
+)
+
+const (
+	// minReplacementBumpPercent 节点接受替换交易要求的最低涨幅
+	minReplacementBumpPercent = 10
+	// legacyWithdrawGasLimit 未记录 gas limit 的历史提现使用的固定 gas limit
+	legacyWithdrawGasLimit = 100000
+)
+
+// WithdrawTxClient 构建和发送提现交易所需的链上接口（ethclient.Client 和 go-ethereum 模拟链客户端均已实现）
+type WithdrawTxClient interface {
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+	SuggestGasPrice(ctx context.Context) (*big.Int, error)
+	SuggestGasTipCap(ctx context.Context) (*big.Int, error)
+	EstimateGas(ctx context.Context, call ethereum.CallMsg) (uint64, error)
+	SendTransaction(ctx context.Context, tx *types.Transaction) error
+	TransactionByHash(ctx context.Context, hash common.Hash) (*types.Transaction, bool, error)
+}
+
+// WithdrawTxParams 提现交易的 gas 参数
+type WithdrawTxParams struct {
+	TxType    string   // legacy / eip1559
+	GasLimit  uint64   // gas limit
+	GasPrice  *big.Int // legacy gas price
+	GasFeeCap *big.Int // EIP-1559 max fee per gas
+	GasTipCap *big.Int // EIP-1559 max priority fee per gas
+}
+
+// NewTx 按交易类型创建未签名交易（value 为 nil 表示不转原生币）
+func (params *WithdrawTxParams) NewTx(chainID int, nonce uint64, to common.Address, value *big.Int, data []byte) *types.Transaction {
+	if value == nil {
+		value = big.NewInt(0)
+	}
+	if params.TxType == models.TxTypeEIP1559 {
+		return types.NewTx(&types.DynamicFeeTx{
+			ChainID:   big.NewInt(int64(chainID)),
+			Nonce:     nonce,
+			GasTipCap: params.GasTipCap,
+			GasFeeCap: params.GasFeeCap,
+			Gas:       params.GasLimit,
+			To:        &to,
+			Value:     value,
+			Data:      data,
+		})
+	}
+	return types.NewTx(&types.LegacyTx{
+		Nonce:    nonce,
+		GasPrice: params.GasPrice,
+		Gas:      params.GasLimit,
+		To:       &to,
+		Value:    value,
+		Data:     data,
+	})
+}
+
+// MaxFee 交易最多消耗的 gas 费用（gas limit × gas price / max fee per gas）
+func (params *WithdrawTxParams) MaxFee() *big.Int {
+	fee := new(big.Int).SetUint64(params.GasLimit)
+	if params.TxType == models.TxTypeEIP1559 {
+		return fee.Mul(fee, params.GasFeeCap)
+	}
+	return fee.Mul(fee, params.GasPrice)
+}
+
+// SuggestWithdrawTxParams 估算 gas limit（加安全余量）并按链配置的交易类型计算费用
+//   - legacy：gasPrice = SuggestGasPrice
+//   - eip1559：tip = SuggestGasTipCap，maxFee = 2 × 最新区块 baseFee + tip
+//
+// 费用超过链配置上限时按上限截断；截断后仍不足以打包（低于当前 gas price / baseFee + tip）时返回 ErrGasPriceAboveCap
+func SuggestWithdrawTxParams(
+	ctx context.Context,
+	client WithdrawTxClient,
+	chain *models.ChainConfig,
+	from, to common.Address,
+	value *big.Int,
+	data []byte,
+) (*WithdrawTxParams, error) {
+	gasLimit, err := estimateWithdrawGas(ctx, client, chain, from, to, value, data)
+	if err != nil {
+		return nil, err
+	}
+
+	params := &WithdrawTxParams{TxType: normalizeTxType(chain.TxType), GasLimit: gasLimit}
+	maxFee := gweiToWei(chain.MaxGasPriceGwei)
+
+	if params.TxType == models.TxTypeEIP1559 {
+		header, err := client.HeaderByNumber(ctx, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get latest header: %w", err)
+		}
+		if header.BaseFee == nil {
+			return nil, ErrEIP1559Unsupported
+		}
+		tip, err := client.SuggestGasTipCap(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get gas tip cap: %w", err)
+		}
+		tip = capWei(tip, gweiToWei(chain.MaxPriorityFeeGwei))
+
+		feeCap := new(big.Int).Mul(header.BaseFee, big.NewInt(2))
+		feeCap.Add(feeCap, tip)
+		feeCap = capWei(feeCap, maxFee)
+
+		if required := new(big.Int).Add(header.BaseFee, tip); feeCap.Cmp(required) < 0 {
+			return nil, fmt.Errorf("%w: base fee %s + tip %s wei", ErrGasPriceAboveCap, header.BaseFee, tip)
+		}
+		params.GasFeeCap = feeCap
+		params.GasTipCap = tip
+		return params, nil
+	}
+
+	gasPrice, err := client.SuggestGasPrice(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get gas price: %w", err)
+	}
+	if maxFee != nil && gasPrice.Cmp(maxFee) > 0 {
+		return nil, fmt.Errorf("%w: gas price %s wei", ErrGasPriceAboveCap, gasPrice)
+	}
+	params.GasPrice = gasPrice
+	return params, nil
+}
+
+// BumpWithdrawTxParams 计算替换交易的费用：在原费用基础上提高 bumpPercent%（不低于当前建议值），沿用原 gas limit
+// 受链配置上限限制，无法达到节点要求的最低涨幅时返回 ErrGasPriceAboveCap
+func BumpWithdrawTxParams(
+	ctx context.Context,
+	client WithdrawTxClient,
+	chain *models.ChainConfig,
+	old *WithdrawTxParams,
+	bumpPercent int,
+) (*WithdrawTxParams, error) {
+	if bumpPercent < minReplacementBumpPercent {
+		bumpPercent = minReplacementBumpPercent
+	}
+	maxFee := gweiToWei(chain.MaxGasPriceGwei)
+	params := &WithdrawTxParams{TxType: old.TxType, GasLimit: old.GasLimit}
+
+	if old.TxType == models.TxTypeEIP1559 {
+		tip := bumpWei(old.GasTipCap, bumpPercent)
+		if suggested, err := client.SuggestGasTipCap(ctx); err == nil && suggested.Cmp(tip) > 0 {
+			tip = suggested
+		}
+		tip = capWei(tip, gweiToWei(chain.MaxPriorityFeeGwei))
+
+		feeCap := bumpWei(old.GasFeeCap, bumpPercent)
+		if header, err := client.HeaderByNumber(ctx, nil); err == nil && header.BaseFee != nil {
+			suggested := new(big.Int).Mul(header.BaseFee, big.NewInt(2))
+			suggested.Add(suggested, tip)
+			if suggested.Cmp(feeCap) > 0 {
+				feeCap = suggested
+			}
+		}
+		feeCap = capWei(feeCap, maxFee)
+		if feeCap.Cmp(tip) < 0 {
+			tip = new(big.Int).Set(feeCap)
+		}
+
+		if tip.Cmp(bumpWei(old.GasTipCap, minReplacementBumpPercent)) < 0 ||
+			feeCap.Cmp(bumpWei(old.GasFeeCap, minReplacementBumpPercent)) < 0 {
+			return nil, fmt.Errorf("%w: cannot raise fee cap above %s wei", ErrGasPriceAboveCap, old.GasFeeCap)
+		}
+		params.GasTipCap = tip
+		params.GasFeeCap = feeCap
+		return params, nil
+	}
+
+	gasPrice := bumpWei(old.GasPrice, bumpPercent)
+	if suggested, err := client.SuggestGasPrice(ctx); err == nil && suggested.Cmp(gasPrice) > 0 {
+		gasPrice = suggested
+	}
+	gasPrice = capWei(gasPrice, maxFee)
+	if gasPrice.Cmp(bumpWei(old.GasPrice, minReplacementBumpPercent)) < 0 {
+		return nil, fmt.Errorf("%w: cannot raise gas price above %s wei", ErrGasPriceAboveCap, old.GasPrice)
+	}
+	params.GasPrice = gasPrice
+	return params, nil
+}
+
+// estimateWithdrawGas 估算 gas limit 并加上安全余量（withdraw.gas.limit_margin_percent）
+func estimateWithdrawGas(ctx context.Context, client WithdrawTxClient, chain *models.ChainConfig, from, to common.Address, value *big.Int, data []byte) (uint64, error) {
+	estimated, err := client.EstimateGas(ctx, ethereum.CallMsg{From: from, To: &to, Value: value, Data: data})
+	if err != nil {
+		return 0, fmt.Errorf("failed to estimate gas: %w", err)
+	}
+
+	margin := database.GetSystemConfigManager().GetInt("withdraw.gas.limit_margin_percent", 20)
+	if margin < 0 {
+		margin = 0
+	}
+	gasLimit := estimated * uint64(100+margin) / 100
+
+	if chain.MaxGasLimit > 0 && gasLimit > chain.MaxGasLimit {
+		if estimated > chain.MaxGasLimit {
+			return 0, fmt.Errorf("%w: estimated %d > %d", ErrGasLimitAboveCap, estimated, chain.MaxGasLimit)
+		}
+		// 余量超出上限时使用上限（估算值本身未超限）
+		gasLimit = chain.MaxGasLimit
+	}
+	return gasLimit, nil
+}
+
+// ValidateTxType 校验链配置的交易类型（空值视为 legacy）
+func ValidateTxType(txType string) (string, error) {
+	switch txType {
+	case "", models.TxTypeLegacy:
+		return models.TxTypeLegacy, nil
+	case models.TxTypeEIP1559:
+		return models.TxTypeEIP1559, nil
+	default:
+		return "", fmt.Errorf("%w: %s", ErrInvalidTxType, txType)
+	}
+}
+
+func normalizeTxType(txType string) string {
+	if txType == models.TxTypeEIP1559 {
+		return models.TxTypeEIP1559
+	}
+	return models.TxTypeLegacy
+}
+
+// gweiToWei gwei 转 wei（0 或负数返回 nil，表示不限）
+func gweiToWei(gwei decimal.Decimal) *big.Int {
+	if !gwei.IsPositive() {
+		return nil
+	}
+	return gwei.Shift(9).Floor().BigInt()
+}
+
+// capWei 按上限截断（limit 为 nil 时不限）
+func capWei(value, limit *big.Int) *big.Int {
+	if limit != nil && value.Cmp(limit) > 0 {
+		return new(big.Int).Set(limit)
+	}
+	return value
+}
+
+// bumpWei value × (100 + percent) / 100
+func bumpWei(value *big.Int, percent int) *big.Int {
+	bumped := new(big.Int).Mul(value, big.NewInt(int64(100+percent)))
+	return bumped.Div(bumped, big.NewInt(100))
+}
+
+// feeStrings 费用参数的十进制字符串（用于保存到提现记录：gas_price / gas_tip_cap）
+func (params *WithdrawTxParams) feeStrings() (string, string) {
+	if params.TxType == models.TxTypeEIP1559 {
+		return params.GasFeeCap.String(), params.GasTipCap.String()
+	}
+	return params.GasPrice.String(), ""
+}
+
+// parseWithdrawTxParams 从记录中保存的字段恢复上一次广播的 gas 参数（用于加速替换）
+func parseWithdrawTxParams(txType string, gasLimit uint64, gasPrice, gasTipCap string) (*WithdrawTxParams, error) {
+	params := &WithdrawTxParams{TxType: normalizeTxType(txType), GasLimit: gasLimit}
+	if params.GasLimit == 0 {
+		params.GasLimit = legacyWithdrawGasLimit
+	}
+
+	feeCap, ok := new(big.Int).SetString(gasPrice, 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid gas price %q", gasPrice)
+	}
+	if params.TxType == models.TxTypeEIP1559 {
+		tip, ok := new(big.Int).SetString(gasTipCap, 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid gas tip cap %q", gasTipCap)
+		}
+		params.GasFeeCap = feeCap
+		params.GasTipCap = tip
+	} else {
+		params.GasPrice = feeCap
+	}
+	return params, nil
+}
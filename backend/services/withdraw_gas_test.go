@@ -0,0 +1,398 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"expchange-backend/models"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient/simulated"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/shopspring/decimal"
+)
+
+// fakeGasClient 模拟节点的费用建议：baseFee 为 nil 表示链不支持 EIP-1559
+type fakeGasClient struct {
+	baseFee  *big.Int
+	gasPrice *big.Int
+	tipCap   *big.Int
+	estimate uint64
+}
+
+func (c *fakeGasClient) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	return &types.Header{Number: big.NewInt(100), BaseFee: c.baseFee}, nil
+}
+
+func (c *fakeGasClient) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
+	return c.gasPrice, nil
+}
+
+func (c *fakeGasClient) SuggestGasTipCap(ctx context.Context) (*big.Int, error) {
+	return c.tipCap, nil
+}
+
+func (c *fakeGasClient) EstimateGas(ctx context.Context, call ethereum.CallMsg) (uint64, error) {
+	return c.estimate, nil
+}
+
+func (c *fakeGasClient) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	return nil
+}
+
+func (c *fakeGasClient) TransactionByHash(ctx context.Context, hash common.Hash) (*types.Transaction, bool, error) {
+	return nil, false, ethereum.NotFound
+}
+
+// gwei 小数 gwei 转 wei
+func gwei(value string) *big.Int {
+	return decimal.RequireFromString(value).Shift(9).BigInt()
+}
+
+func TestSuggestWithdrawTxParams(t *testing.T) {
+	tests := []struct {
+		name        string
+		txType      string
+		client      *fakeGasClient
+		maxGas      string
+		maxTip      string
+		maxGasLimit uint64
+		wantErr     error
+		want        WithdrawTxParams
+	}{
+		{
+			name:   "legacy",
+			txType: models.TxTypeLegacy,
+			client: &fakeGasClient{gasPrice: gwei("5"), estimate: 50000},
+			maxGas: "10",
+			want:   WithdrawTxParams{TxType: models.TxTypeLegacy, GasLimit: 60000, GasPrice: gwei("5")},
+		},
+		{
+			// 空值按 legacy 处理
+			name:   "empty tx type",
+			client: &fakeGasClient{gasPrice: gwei("5"), estimate: 50000},
+			want:   WithdrawTxParams{TxType: models.TxTypeLegacy, GasLimit: 60000, GasPrice: gwei("5")},
+		},
+		{
+			name:    "legacy above cap",
+			txType:  models.TxTypeLegacy,
+			client:  &fakeGasClient{gasPrice: gwei("5"), estimate: 50000},
+			maxGas:  "4",
+			wantErr: ErrGasPriceAboveCap,
+		},
+		{
+			// maxFee = 2 × baseFee + tip
+			name:   "eip1559",
+			txType: models.TxTypeEIP1559,
+			client: &fakeGasClient{baseFee: gwei("10"), tipCap: gwei("2"), estimate: 50000},
+			want:   WithdrawTxParams{TxType: models.TxTypeEIP1559, GasLimit: 60000, GasFeeCap: gwei("22"), GasTipCap: gwei("2")},
+		},
+		{
+			name:   "eip1559 tip capped",
+			txType: models.TxTypeEIP1559,
+			client: &fakeGasClient{baseFee: gwei("10"), tipCap: gwei("2"), estimate: 50000},
+			maxTip: "1.5",
+			want:   WithdrawTxParams{TxType: models.TxTypeEIP1559, GasLimit: 60000, GasFeeCap: gwei("21.5"), GasTipCap: gwei("1.5")},
+		},
+		{
+			// 截断后仍不低于 baseFee + tip，可以打包
+			name:   "eip1559 fee cap truncated",
+			txType: models.TxTypeEIP1559,
+			client: &fakeGasClient{baseFee: gwei("10"), tipCap: gwei("2"), estimate: 50000},
+			maxGas: "15",
+			want:   WithdrawTxParams{TxType: models.TxTypeEIP1559, GasLimit: 60000, GasFeeCap: gwei("15"), GasTipCap: gwei("2")},
+		},
+		{
+			name:    "eip1559 cap below base fee plus tip",
+			txType:  models.TxTypeEIP1559,
+			client:  &fakeGasClient{baseFee: gwei("10"), tipCap: gwei("2"), estimate: 50000},
+			maxGas:  "11",
+			wantErr: ErrGasPriceAboveCap,
+		},
+		{
+			name:    "eip1559 without base fee",
+			txType:  models.TxTypeEIP1559,
+			client:  &fakeGasClient{tipCap: gwei("2"), estimate: 50000},
+			wantErr: ErrEIP1559Unsupported,
+		},
+		{
+			// 余量超出上限时使用上限
+			name:        "gas limit margin truncated",
+			txType:      models.TxTypeLegacy,
+			client:      &fakeGasClient{gasPrice: gwei("5"), estimate: 50000},
+			maxGasLimit: 55000,
+			want:        WithdrawTxParams{TxType: models.TxTypeLegacy, GasLimit: 55000, GasPrice: gwei("5")},
+		},
+		{
+			name:        "estimate above gas limit cap",
+			txType:      models.TxTypeLegacy,
+			client:      &fakeGasClient{gasPrice: gwei("5"), estimate: 50000},
+			maxGasLimit: 40000,
+			wantErr:     ErrGasLimitAboveCap,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newTestDB(t)
+			chain := &models.ChainConfig{TxType: tt.txType, MaxGasLimit: tt.maxGasLimit}
+			if tt.maxGas != "" {
+				chain.MaxGasPriceGwei = decimal.RequireFromString(tt.maxGas)
+			}
+			if tt.maxTip != "" {
+				chain.MaxPriorityFeeGwei = decimal.RequireFromString(tt.maxTip)
+			}
+
+			params, err := SuggestWithdrawTxParams(context.Background(), tt.client, chain, common.Address{1}, common.Address{2}, big.NewInt(0), nil)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("SuggestWithdrawTxParams err=%v, want %v", err, tt.wantErr)
+			}
+			if err == nil {
+				assertTxParams(t, params, &tt.want)
+			}
+		})
+	}
+}
+
+func TestBumpWithdrawTxParams(t *testing.T) {
+	oldLegacy := &WithdrawTxParams{TxType: models.TxTypeLegacy, GasLimit: 60000, GasPrice: gwei("5")}
+	oldDynamic := &WithdrawTxParams{TxType: models.TxTypeEIP1559, GasLimit: 60000, GasFeeCap: gwei("22"), GasTipCap: gwei("2")}
+
+	tests := []struct {
+		name    string
+		old     *WithdrawTxParams
+		client  *fakeGasClient
+		percent int
+		maxGas  string
+		maxTip  string
+		wantErr error
+		want    WithdrawTxParams
+	}{
+		{
+			name:    "legacy bump",
+			old:     oldLegacy,
+			client:  &fakeGasClient{gasPrice: gwei("4")},
+			percent: 20,
+			want:    WithdrawTxParams{TxType: models.TxTypeLegacy, GasLimit: 60000, GasPrice: gwei("6")},
+		},
+		{
+			// 当前建议值高于涨幅时使用建议值
+			name:    "legacy follows suggestion",
+			old:     oldLegacy,
+			client:  &fakeGasClient{gasPrice: gwei("8")},
+			percent: 20,
+			want:    WithdrawTxParams{TxType: models.TxTypeLegacy, GasLimit: 60000, GasPrice: gwei("8")},
+		},
+		{
+			// 涨幅低于节点要求时按最低涨幅计算
+			name:    "legacy minimum bump",
+			old:     oldLegacy,
+			client:  &fakeGasClient{gasPrice: gwei("4")},
+			percent: 5,
+			want:    WithdrawTxParams{TxType: models.TxTypeLegacy, GasLimit: 60000, GasPrice: gwei("5.5")},
+		},
+		{
+			name:    "legacy capped above minimum bump",
+			old:     oldLegacy,
+			client:  &fakeGasClient{gasPrice: gwei("8")},
+			percent: 20,
+			maxGas:  "7",
+			want:    WithdrawTxParams{TxType: models.TxTypeLegacy, GasLimit: 60000, GasPrice: gwei("7")},
+		},
+		{
+			name:    "legacy cap blocks replacement",
+			old:     oldLegacy,
+			client:  &fakeGasClient{gasPrice: gwei("8")},
+			percent: 20,
+			maxGas:  "5.4",
+			wantErr: ErrGasPriceAboveCap,
+		},
+		{
+			name:    "eip1559 bump",
+			old:     oldDynamic,
+			client:  &fakeGasClient{baseFee: gwei("10"), tipCap: gwei("1")},
+			percent: 10,
+			want:    WithdrawTxParams{TxType: models.TxTypeEIP1559, GasLimit: 60000, GasFeeCap: gwei("24.2"), GasTipCap: gwei("2.2")},
+		},
+		{
+			// baseFee 上涨：maxFee 跟随 2 × baseFee + tip
+			name:    "eip1559 follows base fee",
+			old:     oldDynamic,
+			client:  &fakeGasClient{baseFee: gwei("20"), tipCap: gwei("3")},
+			percent: 10,
+			want:    WithdrawTxParams{TxType: models.TxTypeEIP1559, GasLimit: 60000, GasFeeCap: gwei("43"), GasTipCap: gwei("3")},
+		},
+		{
+			name:    "eip1559 fee cap blocks replacement",
+			old:     oldDynamic,
+			client:  &fakeGasClient{baseFee: gwei("10"), tipCap: gwei("1")},
+			percent: 10,
+			maxGas:  "24",
+			wantErr: ErrGasPriceAboveCap,
+		},
+		{
+			name:    "eip1559 tip cap blocks replacement",
+			old:     oldDynamic,
+			client:  &fakeGasClient{baseFee: gwei("10"), tipCap: gwei("1")},
+			percent: 10,
+			maxTip:  "2",
+			wantErr: ErrGasPriceAboveCap,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chain := &models.ChainConfig{TxType: tt.old.TxType}
+			if tt.maxGas != "" {
+				chain.MaxGasPriceGwei = decimal.RequireFromString(tt.maxGas)
+			}
+			if tt.maxTip != "" {
+				chain.MaxPriorityFeeGwei = decimal.RequireFromString(tt.maxTip)
+			}
+
+			params, err := BumpWithdrawTxParams(context.Background(), tt.client, chain, tt.old, tt.percent)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("BumpWithdrawTxParams err=%v, want %v", err, tt.wantErr)
+			}
+			if err == nil {
+				assertTxParams(t, params, &tt.want)
+			}
+		})
+	}
+}
+
+func TestWithdrawTxParamsRoundTrip(t *testing.T) {
+	tests := []struct {
+		name     string
+		params   *WithdrawTxParams
+		wantType uint8
+		wantFee  *big.Int
+	}{
+		{
+			name:     "legacy",
+			params:   &WithdrawTxParams{TxType: models.TxTypeLegacy, GasLimit: 60000, GasPrice: gwei("5")},
+			wantType: types.LegacyTxType,
+			wantFee:  new(big.Int).Mul(big.NewInt(60000), gwei("5")),
+		},
+		{
+			name:     "eip1559",
+			params:   &WithdrawTxParams{TxType: models.TxTypeEIP1559, GasLimit: 60000, GasFeeCap: gwei("22"), GasTipCap: gwei("2")},
+			wantType: types.DynamicFeeTxType,
+			wantFee:  new(big.Int).Mul(big.NewInt(60000), gwei("22")),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx := tt.params.NewTx(56, 7, common.Address{2}, nil, nil)
+			if tx.Type() != tt.wantType || tx.Nonce() != 7 || tx.Gas() != tt.params.GasLimit || tx.Value().Sign() != 0 {
+				t.Fatalf("unexpected tx: type=%d nonce=%d gas=%d value=%s", tx.Type(), tx.Nonce(), tx.Gas(), tx.Value())
+			}
+			if tt.params.MaxFee().Cmp(tt.wantFee) != 0 {
+				t.Fatalf("MaxFee=%s, want %s", tt.params.MaxFee(), tt.wantFee)
+			}
+
+			// 保存到提现记录后恢复，用于加速替换
+			gasPrice, gasTipCap := tt.params.feeStrings()
+			restored, err := parseWithdrawTxParams(tt.params.TxType, tt.params.GasLimit, gasPrice, gasTipCap)
+			if err != nil {
+				t.Fatalf("parseWithdrawTxParams: %v", err)
+			}
+			assertTxParams(t, restored, tt.params)
+		})
+	}
+
+	// 未记录 gas limit 的历史提现使用固定值
+	restored, err := parseWithdrawTxParams("", 0, "5000000000", "")
+	if err != nil || restored.GasLimit != legacyWithdrawGasLimit || restored.TxType != models.TxTypeLegacy {
+		t.Fatalf("legacy record restored as %+v, err=%v", restored, err)
+	}
+	if _, err := parseWithdrawTxParams(models.TxTypeEIP1559, 60000, "22000000000", ""); err == nil {
+		t.Fatal("expected error for eip1559 record without tip cap")
+	}
+}
+
+func assertTxParams(t *testing.T, got, want *WithdrawTxParams) {
+	t.Helper()
+	sameWei := func(a, b *big.Int) bool {
+		if a == nil || b == nil {
+			return a == nil && b == nil
+		}
+		return a.Cmp(b) == 0
+	}
+	if got.TxType != want.TxType || got.GasLimit != want.GasLimit ||
+		!sameWei(got.GasPrice, want.GasPrice) || !sameWei(got.GasFeeCap, want.GasFeeCap) || !sameWei(got.GasTipCap, want.GasTipCap) {
+		t.Fatalf("params=%+v, want %+v", got, want)
+	}
+}
+
+// TestWithdrawTxReplacementAccepted 在模拟链的交易池中发送提现交易，加速后的替换交易必须满足节点的最低涨幅要求
+func TestWithdrawTxReplacementAccepted(t *testing.T) {
+	newTestDB(t)
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	to := common.Address{0xaa}
+	value := big.NewInt(params.GWei)
+
+	for _, txType := range []string{models.TxTypeEIP1559, models.TxTypeLegacy} {
+		t.Run(txType, func(t *testing.T) {
+			backend := simulated.NewBackend(types.GenesisAlloc{from: {Balance: big.NewInt(params.Ether)}})
+			defer backend.Close()
+			client := backend.Client()
+			ctx := context.Background()
+
+			chainID, err := client.ChainID(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			chain := &models.ChainConfig{ChainID: int(chainID.Int64()), TxType: txType}
+			txSigner := types.LatestSignerForChainID(chainID)
+			send := func(p *WithdrawTxParams) (*types.Transaction, error) {
+				tx, err := types.SignTx(p.NewTx(chain.ChainID, 0, to, value, nil), txSigner, key)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return tx, client.SendTransaction(ctx, tx)
+			}
+
+			original, err := SuggestWithdrawTxParams(ctx, client, chain, from, to, value, nil)
+			if err != nil {
+				t.Fatalf("SuggestWithdrawTxParams: %v", err)
+			}
+			if _, err := send(original); err != nil {
+				t.Fatalf("send original: %v", err)
+			}
+
+			// 涨幅不足时节点拒绝替换，确认交易池确实在校验涨幅
+			underpriced := &WithdrawTxParams{TxType: original.TxType, GasLimit: original.GasLimit}
+			if txType == models.TxTypeEIP1559 {
+				underpriced.GasTipCap = bumpWei(original.GasTipCap, 5)
+				underpriced.GasFeeCap = bumpWei(original.GasFeeCap, 5)
+			} else {
+				underpriced.GasPrice = bumpWei(original.GasPrice, 5)
+			}
+			if _, err := send(underpriced); err == nil {
+				t.Fatal("txpool accepted a replacement with a 5% bump")
+			}
+
+			bumped, err := BumpWithdrawTxParams(ctx, client, chain, original, minReplacementBumpPercent)
+			if err != nil {
+				t.Fatalf("BumpWithdrawTxParams: %v", err)
+			}
+			replacement, err := send(bumped)
+			if err != nil {
+				t.Fatalf("txpool rejected the bumped replacement: %v", err)
+			}
+
+			backend.Commit()
+			receipt, err := client.TransactionReceipt(ctx, replacement.Hash())
+			if err != nil || receipt.Status != types.ReceiptStatusSuccessful {
+				t.Fatalf("replacement receipt=%v err=%v", receipt, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package services
+
+import (
+	"context"
+	"expchange-backend/database"
+	"expchange-backend/models"
+	"expchange-backend/pkg/noncemanager"
+	"expchange-backend/pkg/signer"
+	"log"
+	"time"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// ReconcileNonces 与链上核对各链热钱包的 nonce（由任务队列定时调用）
+// 本地落后时前进到链上值；存在缺口时用零值自转账补洞；最低未打包交易长时间不变时替换
+func (p *WithdrawProcessor) ReconcileNonces() {
+	vault, err := GetWalletKeyVault()
+	if err != nil {
+		return
+	}
+
+	var chains []models.ChainConfig
+	database.DB.Where("enabled = ?", true).Find(&chains)
+
+	sysConfig := database.GetSystemConfigManager()
+	fillGaps := sysConfig.GetBool("withdraw.nonce.fill_gaps", true)
+	stuckAfter := time.Duration(sysConfig.GetInt("withdraw.tracker.stuck_minutes", 10)) * time.Minute
+
+	for i := range chains {
+		chain := &chains[i]
+		txSigner, err := vault.SignerForChain(chain)
+		if err != nil {
+			continue
+		}
+		if err := p.reconcileChainNonce(chain, txSigner, fillGaps, stuckAfter); err != nil {
+			log.Printf("⚠️  nonce 对账失败: Chain=%s, err=%v", chain.ChainName, err)
+		}
+	}
+}
+
+func (p *WithdrawProcessor) reconcileChainNonce(chain *models.ChainConfig, txSigner signer.Signer, fillGaps bool, stuckAfter time.Duration) error {
+	// pending/latest nonce 和补洞前的检查必须来自同一节点，否则落后节点会把已打包的 nonce 误判为缺口
+	client, err := ChainSession(chain)
+	if err != nil {
+		return err
+	}
+
+	address := txSigner.Address().Hex()
+	opts := noncemanager.ReconcileOptions{StuckAfter: stuckAfter}
+	if fillGaps {
+		opts.Fill = p.nonceFiller(client, chain, txSigner)
+	}
+
+	result, err := p.nonceManager.Reconcile(p.ctx, client, address, chain.ChainID, opts)
+	if result != nil {
+		if result.Next != result.Local {
+			log.Printf("🔢 nonce 已与链上同步: Chain=%s, Address=%s, %d -> %d", chain.ChainName, address, result.Local, result.Next)
+		}
+		if !fillGaps && result.Pending < result.Local {
+			log.Printf("⚠️  nonce 存在缺口（未开启补洞）: Chain=%s, Address=%s, 链上=%d, 本地=%d",
+				chain.ChainName, address, result.Pending, result.Local)
+		}
+	}
+	return err
+}
+
+// nonceFiller 使用零值自转账占用缺失或卡住的 nonce；已知交易占用的 nonce 由各自的跟踪流程处理，这里跳过
+func (p *WithdrawProcessor) nonceFiller(client *ethclient.Client, chain *models.ChainConfig, txSigner signer.Signer) noncemanager.GapFiller {
+	from := txSigner.Address()
+
+	return func(ctx context.Context, nonce uint64, replace bool) error {
+		if nonceInUse(chain.ChainID, from.Hex(), nonce) {
+			return noncemanager.ErrSkipNonce
+		}
+
+		params, err := SuggestWithdrawTxParams(ctx, client, chain, from, from, nil, nil)
+		if err != nil {
+			return err
+		}
+		if replace {
+			// 被替换交易的费用未知，在当前建议值基础上提高
+			bumpPercent := database.GetSystemConfigManager().GetInt("withdraw.tracker.fee_bump_percent", 20)
+			if params, err = BumpWithdrawTxParams(ctx, client, chain, params, bumpPercent); err != nil {
+				return err
+			}
+		}
+
+		signedTx, err := p.signAndSend(client, txSigner, chain, from, nil, nonce, params, nil)
+		if err != nil {
+			return err
+		}
+
+		action := "补洞"
+		if replace {
+			action = "替换卡住交易"
+		}
+		log.Printf("🩹 nonce %s: Chain=%s, Address=%s, Nonce=%d, TxHash=%s", action, chain.ChainName, from.Hex(), nonce, signedTx.Hash().Hex())
+		return nil
+	}
+}
+
+// nonceInUse 热钱包的 nonce 是否被已广播、未完成的交易占用：
+// 单笔提现和批量提现（含待人工核对的）、归集时补充 gas 的交易
+func nonceInUse(chainID int, from string, nonce uint64) bool {
+	var count int64
+	database.DB.Model(&models.WithdrawRecord{}).
+		Where("chain_id = ? AND from_address = ? AND nonce = ? AND status IN ?", chainID, from, nonce, []string{"broadcast", "review"}).
+		Count(&count)
+	if count > 0 {
+		return true
+	}
+
+	database.DB.Model(&models.WithdrawBatch{}).
+		Where("chain_id = ? AND from_address = ? AND nonce = ? AND status IN ?", chainID, from, nonce, []string{"broadcast", "review"}).
+		Count(&count)
+	if count > 0 {
+		return true
+	}
+
+	// 补充 gas 的交易由同一热钱包发出，归集记录未保存发送地址
+	database.DB.Model(&models.DepositSweep{}).
+		Where("chain_id = ? AND gas_nonce = ? AND gas_tx_hash <> ? AND status = ?", chainID, nonce, "", "gas_pending").
+		Count(&count)
+	return count > 0
+}
@@ -0,0 +1,82 @@
+package services
+
+import (
+	"expchange-backend/database"
+	"expchange-backend/models"
+	"testing"
+)
+
+func TestNonceInUse(t *testing.T) {
+	const (
+		chainID = 56
+		hot     = "0x00000000000000000000000000000000000000Aa"
+		other   = "0x00000000000000000000000000000000000000Bb"
+	)
+
+	tests := []struct {
+		name  string
+		rows  []interface{}
+		nonce uint64
+		want  bool
+	}{
+		{name: "free nonce", nonce: 7},
+		{
+			name:  "broadcast withdrawal",
+			rows:  []interface{}{&models.WithdrawRecord{ChainID: chainID, FromAddress: hot, Nonce: 7, Status: "broadcast"}},
+			nonce: 7, want: true,
+		},
+		{
+			name:  "completed withdrawal",
+			rows:  []interface{}{&models.WithdrawRecord{ChainID: chainID, FromAddress: hot, Nonce: 7, Status: "completed"}},
+			nonce: 7,
+		},
+		{
+			name:  "withdrawal from another wallet",
+			rows:  []interface{}{&models.WithdrawRecord{ChainID: chainID, FromAddress: other, Nonce: 7, Status: "broadcast"}},
+			nonce: 7,
+		},
+		{
+			name:  "broadcast batch",
+			rows:  []interface{}{&models.WithdrawBatch{ChainID: chainID, FromAddress: hot, Nonce: 7, Status: "broadcast"}},
+			nonce: 7, want: true,
+		},
+		{
+			name:  "batch under review",
+			rows:  []interface{}{&models.WithdrawBatch{ChainID: chainID, FromAddress: hot, Nonce: 7, Status: "review"}},
+			nonce: 7, want: true,
+		},
+		{
+			name:  "failed batch",
+			rows:  []interface{}{&models.WithdrawBatch{ChainID: chainID, FromAddress: hot, Nonce: 7, Status: "failed"}},
+			nonce: 7,
+		},
+		{
+			name:  "pending sweep gas top-up",
+			rows:  []interface{}{&models.DepositSweep{ChainID: chainID, GasNonce: 7, GasTxHash: "0xabc", Status: "gas_pending"}},
+			nonce: 7, want: true,
+		},
+		{
+			name:  "sweep without gas top-up",
+			rows:  []interface{}{&models.DepositSweep{ChainID: chainID, Status: "gas_pending"}},
+			nonce: 0,
+		},
+		{
+			name:  "other chain",
+			rows:  []interface{}{&models.WithdrawBatch{ChainID: 1, FromAddress: hot, Nonce: 7, Status: "broadcast"}},
+			nonce: 7,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newTestDB(t, &models.WithdrawRecord{}, &models.WithdrawBatch{}, &models.DepositSweep{})
+			for _, row := range tt.rows {
+				if err := database.DB.Create(row).Error; err != nil {
+					t.Fatalf("create %T: %v", row, err)
+				}
+			}
+			if got := nonceInUse(chainID, hot, tt.nonce); got != tt.want {
+				t.Fatalf("nonceInUse=%v, want %v", got, tt.want)
+			}
+		})
+	}
+}
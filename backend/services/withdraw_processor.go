@@ -5,17 +5,17 @@ import (
 	"expchange-backend/database"
 	"expchange-backend/models"
 	"expchange-backend/pkg/noncemanager"
+	"expchange-backend/pkg/signer"
 	"fmt"
 	"log"
 	"math/big"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/crypto"
-	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/shopspring/decimal"
 )
 
@@ -26,6 +26,7 @@ const transferABI = `[{"constant":false,"inputs":[{"name":"_to","type":"address"
 type WithdrawProcessor struct {
 	ctx          context.Context
 	nonceManager *noncemanager.NonceManager
+	trackMu      sync.Mutex // 确认跟踪与手动加速互斥（避免同一 nonce 并发替换）
 }
 
 // NewWithdrawProcessor 创建提现处理服务（支持多链）
@@ -44,6 +45,22 @@ func (p *WithdrawProcessor) ProcessWithdrawal(withdrawal *models.WithdrawRecord)
 	log.Printf("💸 处理提现: ID=%s, Amount=%s %s, Chain=%s(%d), Address=%s",
 		withdrawal.ID, withdrawal.Amount.String(), withdrawal.Asset, withdrawal.Chain, withdrawal.ChainID, withdrawal.Address)
 
+	// 只处理待处理状态的记录（待审核、已处理、已拒绝的记录不能被任务重试再次发出）
+	if withdrawal.Status != "pending" {
+		log.Printf("⚠️  提现状态为 %s，跳过处理: ID=%s", withdrawal.Status, withdrawal.ID)
+		return
+	}
+
+	// 批量模式：进入批量队列，由批量发送定时合并为一笔 multisend 交易
+	if p.queueForBatch(withdrawal) {
+		return
+	}
+
+	p.sendIndividually(withdrawal)
+}
+
+// sendIndividually 单笔发送（代币为 ERC20 transfer，原生币直接转账；非批量模式，或批量发送失败后的回退）
+func (p *WithdrawProcessor) sendIndividually(withdrawal *models.WithdrawRecord) {
 	// 1. 获取链配置
 	var chainConfig models.ChainConfig
 	if err := database.DB.Where("chain_id = ? AND enabled = ?", withdrawal.ChainID, true).First(&chainConfig).Error; err != nil {
@@ -52,128 +69,224 @@ func (p *WithdrawProcessor) ProcessWithdrawal(withdrawal *models.WithdrawRecord)
 		return
 	}
 
-	// 2. 验证私钥已配置
-	if chainConfig.PlatformWithdrawPrivateKey == "" {
-		log.Printf("❌ 链 %s 未配置提现私钥", chainConfig.ChainName)
-		p.MarkWithdrawalFailed(withdrawal, "Private key not configured for this chain")
+	token, err := GetChainToken(chainConfig.ChainID, withdrawal.Asset)
+	if err != nil {
+		log.Printf("❌ 获取链上资产配置失败: %v", err)
+		p.MarkWithdrawalFailed(withdrawal, err.Error())
 		return
 	}
 
-	// 3. 标记为处理中
-	if err := database.DB.Model(withdrawal).Update("status", "processing").Error; err != nil {
-		log.Printf("❌ 更新提现状态失败: %v", err)
+	// 2. 按链配置加载签名器（本地加密私钥 / keystore 文件 / 远程签名服务）
+	vault, err := GetWalletKeyVault()
+	if err != nil {
+		log.Printf("❌ %v", err)
+		p.MarkWithdrawalFailed(withdrawal, err.Error())
+		return
+	}
+	txSigner, err := vault.SignerForChain(&chainConfig)
+	if err != nil {
+		log.Printf("❌ 链 %s 提现签名器不可用: %v", chainConfig.ChainName, err)
+		p.MarkWithdrawalFailed(withdrawal, "Withdraw signer not available for this chain")
 		return
 	}
 
-	// 4. 执行链上转账
-	txHash, err := p.TransferUSDT(
-		chainConfig.RpcURL,
-		chainConfig.UsdtContractAddress,
-		chainConfig.PlatformWithdrawPrivateKey,
-		withdrawal.Address,
-		withdrawal.Amount,
-		withdrawal.ChainID,
-		chainConfig.UsdtDecimals,
-	)
+	// 3. 标记为处理中（条件更新，防止同一记录被并发处理）
+	result := database.DB.Model(&models.WithdrawRecord{}).
+		Where("id = ? AND status = ?", withdrawal.ID, "pending").
+		Update("status", "processing")
+	if result.Error != nil {
+		log.Printf("❌ 更新提现状态失败: %v", result.Error)
+		return
+	}
+	if result.RowsAffected == 0 {
+		log.Printf("⚠️  提现记录已被处理，跳过: ID=%s", withdrawal.ID)
+		return
+	}
+	withdrawal.Status = "processing"
+
+	// 4. 执行链上转账（到账金额为扣除手续费后的净额）
+	signedTx, params, err := p.TransferAsset(&chainConfig, token, txSigner, withdrawal.Address, withdrawal.Amount.Sub(withdrawal.Fee))
 	if err != nil {
 		log.Printf("❌ 转账失败: %v", err)
 		p.MarkWithdrawalFailed(withdrawal, err.Error())
 		return
 	}
 
-	log.Printf("✅ 转账成功: Chain=%s, TxHash=%s", chainConfig.ChainName, txHash)
+	log.Printf("📡 交易已广播: Chain=%s, TxHash=%s", chainConfig.ChainName, signedTx.Hash().Hex())
+
+	// 5. 标记为已广播，等待确认跟踪器确认后再扣减冻结资金
+	p.markWithdrawalBroadcast(withdrawal, txSigner.Address().Hex(), signedTx, params)
+}
+
+// markWithdrawalBroadcast 记录已广播的交易（processing → broadcast）
+func (p *WithdrawProcessor) markWithdrawalBroadcast(withdrawal *models.WithdrawRecord, fromAddress string, signedTx *types.Transaction, params *WithdrawTxParams) {
+	now := time.Now()
+	gasPrice, gasTipCap := params.feeStrings()
+	updates := map[string]interface{}{
+		"status":       "broadcast",
+		"tx_hash":      signedTx.Hash().Hex(),
+		"from_address": fromAddress,
+		"nonce":        signedTx.Nonce(),
+		"tx_type":      params.TxType,
+		"gas_limit":    params.GasLimit,
+		"gas_price":    gasPrice,
+		"gas_tip_cap":  gasTipCap,
+		"broadcast_at": now,
+		"updated_at":   now,
+	}
+	if err := database.DB.Model(&models.WithdrawRecord{}).
+		Where("id = ? AND status = ?", withdrawal.ID, "processing").
+		Updates(updates).Error; err != nil {
+		// 交易已发出，不能标记失败解冻；记录保持 processing 由人工核对
+		log.Printf("❌ 记录广播交易失败（需人工核对）: ID=%s, TxHash=%s, err=%v", withdrawal.ID, signedTx.Hash().Hex(), err)
+		return
+	}
 
-	// 5. 更新提现记录
-	p.ConfirmWithdrawal(withdrawal, txHash)
+	withdrawal.Status = "broadcast"
+	withdrawal.TxHash = signedTx.Hash().Hex()
+	withdrawal.FromAddress = fromAddress
+	withdrawal.Nonce = signedTx.Nonce()
+	withdrawal.TxType = params.TxType
+	withdrawal.GasLimit = params.GasLimit
+	withdrawal.GasPrice = gasPrice
+	withdrawal.GasTipCap = gasTipCap
+	withdrawal.BroadcastAt = &now
 }
 
-// TransferUSDT 执行 USDT 转账（支持多链，线程安全的nonce管理）
-func (p *WithdrawProcessor) TransferUSDT(
-	rpcURL string,
-	usdtContract string,
-	privateKeyHex string,
+// TransferAsset 执行资产转账（支持多链和原生币，线程安全的nonce管理），返回已广播的签名交易及其 gas 参数
+func (p *WithdrawProcessor) TransferAsset(
+	chain *models.ChainConfig,
+	token *models.ChainToken,
+	txSigner signer.Signer,
 	toAddress string,
 	amount decimal.Decimal,
-	chainID int,
-	usdtDecimals int,
-) (string, error) {
+) (*types.Transaction, *WithdrawTxParams, error) {
 	// 1. 连接到链
-	client, err := ethclient.Dial(rpcURL)
+	client, err := ChainClient(chain)
 	if err != nil {
-		return "", fmt.Errorf("failed to connect to RPC %s: %w", rpcURL, err)
+		return nil, nil, err
 	}
-	defer client.Close()
 
-	// 2. 加载私钥
-	privateKey, err := crypto.HexToECDSA(strings.TrimPrefix(privateKeyHex, "0x"))
+	// 2. 构建转账（代币打包 transfer 函数调用，原生币直接转账）
+	to, value, data, err := transferCall(token, toAddress, amount)
 	if err != nil {
-		return "", fmt.Errorf("invalid private key: %w", err)
+		return nil, nil, err
 	}
 
-	fromAddress := crypto.PubkeyToAddress(privateKey.PublicKey)
-	fromAddressStr := fromAddress.Hex()
-
-	// 3. 使用 NonceManager 获取 nonce（线程安全）
-	nonce, releaseNonce, err := p.nonceManager.AcquireNonce(rpcURL, fromAddressStr, chainID)
+	// 3. 估算 gas limit 和费用（在获取 nonce 之前，失败时不占用 nonce）
+	params, err := SuggestWithdrawTxParams(p.ctx, client, chain, txSigner.Address(), to, value, data)
 	if err != nil {
-		return "", fmt.Errorf("failed to acquire nonce: %w", err)
+		return nil, nil, err
 	}
-	defer releaseNonce() // 确保释放锁
 
-	log.Printf("📝 使用 Nonce: %d (Address: %s, ChainID: %d)", nonce, fromAddressStr, chainID)
-
-	// 4. 获取 gas price
-	gasPrice, err := client.SuggestGasPrice(p.ctx)
+	// 4. 使用 NonceManager 获取 nonce（线程安全）
+	fromAddressStr := txSigner.Address().Hex()
+	lease, err := p.nonceManager.AcquireNonce(client, fromAddressStr, chain.ChainID)
 	if err != nil {
-		return "", fmt.Errorf("failed to get gas price: %w", err)
+		return nil, nil, fmt.Errorf("failed to acquire nonce: %w", err)
 	}
+	defer lease.Rollback() // 发送失败时归还 nonce 并释放锁
+	nonce := lease.Nonce
 
-	// 5. 解析 ABI
-	parsedABI, err := abi.JSON(strings.NewReader(transferABI))
+	log.Printf("📝 使用 Nonce: %d (Address: %s, ChainID: %d, Type: %s, GasLimit: %d)",
+		nonce, fromAddressStr, chain.ChainID, params.TxType, params.GasLimit)
+
+	// 5. 签名并发送交易
+	signedTx, err := p.signAndSend(client, txSigner, chain, to, value, nonce, params, data)
 	if err != nil {
-		return "", fmt.Errorf("failed to parse ABI: %w", err)
+		return nil, nil, err
+	}
+	if err := lease.Commit(); err != nil {
+		log.Printf("⚠️  保存 nonce 失败（内存已更新）: %v", err)
 	}
 
-	// 6. 根据链的USDT精度转换金额
-	value := new(big.Int)
-	value.SetString(amount.Shift(int32(usdtDecimals)).StringFixed(0), 10)
+	log.Printf("✅ 交易已发送: TxHash=%s, Nonce=%d", signedTx.Hash().Hex(), nonce)
+	return signedTx, params, nil
+}
 
-	// 7. 打包 transfer 函数调用
-	data, err := parsedABI.Pack("transfer", common.HexToAddress(toAddress), value)
+// transferCall 资产转账交易的目标地址、原生币金额和调用数据：代币为调用合约 transfer，原生币直接转给收款地址
+func transferCall(token *models.ChainToken, toAddress string, amount decimal.Decimal) (common.Address, *big.Int, []byte, error) {
+	if token.IsNative() {
+		return common.HexToAddress(toAddress), tokenUnits(amount, token.Decimals), nil, nil
+	}
+	data, err := packTransferData(toAddress, amount, token.Decimals)
 	if err != nil {
-		return "", fmt.Errorf("failed to pack data: %w", err)
+		return common.Address{}, nil, nil, err
 	}
+	return common.HexToAddress(token.ContractAddress), nil, data, nil
+}
 
-	// 8. 创建交易
-	tx := types.NewTransaction(
-		nonce,
-		common.HexToAddress(usdtContract),
-		big.NewInt(0),  // value = 0 (ERC20 transfer)
-		uint64(100000), // gas limit
-		gasPrice,
-		data,
-	)
+// packTransferData 打包 ERC20 transfer 调用数据（按代币精度转换金额）
+func packTransferData(toAddress string, amount decimal.Decimal, decimals int) ([]byte, error) {
+	parsedABI, err := abi.JSON(strings.NewReader(transferABI))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ABI: %w", err)
+	}
 
-	// 9. 签名交易
-	signedTx, err := types.SignTx(tx, types.NewEIP155Signer(big.NewInt(int64(chainID))), privateKey)
+	data, err := parsedABI.Pack("transfer", common.HexToAddress(toAddress), tokenUnits(amount, decimals))
 	if err != nil {
-		return "", fmt.Errorf("failed to sign transaction: %w", err)
+		return nil, fmt.Errorf("failed to pack data: %w", err)
 	}
+	return data, nil
+}
+
+// tokenUnits 按代币精度把金额转换为链上最小单位
+func tokenUnits(amount decimal.Decimal, decimals int) *big.Int {
+	value := new(big.Int)
+	value.SetString(amount.Shift(int32(decimals)).StringFixed(0), 10)
+	return value
+}
 
-	// 10. 发送交易
-	err = client.SendTransaction(p.ctx, signedTx)
+// signAndSend 构建、签名并发送交易（to 为代币合约、multisend 合约或原生币收款地址；加速替换时使用相同 nonce 重新签名）
+// value 为随交易转出的原生币（nil 表示不转）
+func (p *WithdrawProcessor) signAndSend(
+	client WithdrawTxClient,
+	txSigner signer.Signer,
+	chain *models.ChainConfig,
+	to common.Address,
+	value *big.Int,
+	nonce uint64,
+	params *WithdrawTxParams,
+	data []byte,
+) (*types.Transaction, error) {
+	tx := params.NewTx(chain.ChainID, nonce, to, value, data)
+
+	signedTx, err := txSigner.SignTx(p.ctx, tx, big.NewInt(int64(chain.ChainID)))
 	if err != nil {
-		return "", fmt.Errorf("failed to send transaction: %w", err)
+		return nil, fmt.Errorf("failed to sign transaction: %w", err)
 	}
 
-	txHash := signedTx.Hash().Hex()
-	log.Printf("✅ 交易已发送: TxHash=%s, Nonce=%d", txHash, nonce)
+	if err := p.sendSignedTx(client, signedTx); err != nil {
+		return nil, err
+	}
+	return signedTx, nil
+}
 
-	return txHash, nil
+// sendSignedTx 广播已签名的交易
+func (p *WithdrawProcessor) sendSignedTx(client WithdrawTxClient, signedTx *types.Transaction) error {
+	if err := client.SendTransaction(p.ctx, signedTx); err != nil {
+		// 发送报错（如超时）但节点已收到交易时按已发送处理，避免归还的 nonce 被再次使用
+		if _, _, lookupErr := client.TransactionByHash(p.ctx, signedTx.Hash()); lookupErr == nil {
+			log.Printf("⚠️  发送交易返回错误但节点已收到交易: TxHash=%s, err=%v", signedTx.Hash().Hex(), err)
+			return nil
+		}
+		return fmt.Errorf("failed to send transaction: %w", err)
+	}
+	return nil
 }
 
-// ConfirmWithdrawal 确认提现完成
+// ConfirmWithdrawal 确认提现完成（交易达到确认数后由确认跟踪器调用，txHash 为实际打包的交易）
 func (p *WithdrawProcessor) ConfirmWithdrawal(withdrawal *models.WithdrawRecord, txHash string) {
+	// 手续费入账账户需在事务外获取（首次使用时会创建账户）
+	var feeAccountID string
+	if withdrawal.Fee.IsPositive() {
+		var err error
+		if feeAccountID, err = GetPlatformFeeAccountID(); err != nil {
+			log.Printf("❌ 获取平台手续费账户失败: %v", err)
+			return
+		}
+	}
+
 	tx := database.DB.Begin()
 	defer func() {
 		if r := recover(); r != nil {
@@ -181,14 +294,24 @@ func (p *WithdrawProcessor) ConfirmWithdrawal(withdrawal *models.WithdrawRecord,
 		}
 	}()
 
-	// 1. 更新提现记录
-	if err := tx.Model(withdrawal).Updates(map[string]interface{}{
-		"status":     "completed",
-		"tx_hash":    txHash,
-		"updated_at": time.Now(),
-	}).Error; err != nil {
+	// 1. 更新提现记录（条件更新保证只扣减一次冻结资金）
+	result := tx.Model(&models.WithdrawRecord{}).
+		Where("id = ? AND status = ?", withdrawal.ID, "broadcast").
+		Updates(map[string]interface{}{
+			"status":        "completed",
+			"tx_hash":       txHash,
+			"block_number":  withdrawal.BlockNumber,
+			"confirmations": withdrawal.Confirmations,
+			"updated_at":    time.Now(),
+		})
+	if result.Error != nil {
 		tx.Rollback()
-		log.Printf("❌ 更新提现记录失败: %v", err)
+		log.Printf("❌ 更新提现记录失败: %v", result.Error)
+		return
+	}
+	if result.RowsAffected == 0 {
+		tx.Rollback()
+		log.Printf("⚠️  提现记录状态已变更，跳过确认: ID=%s", withdrawal.ID)
 		return
 	}
 
@@ -208,14 +331,25 @@ func (p *WithdrawProcessor) ConfirmWithdrawal(withdrawal *models.WithdrawRecord,
 		return
 	}
 
+	// 3. 提现手续费计入平台手续费账户
+	if withdrawal.Fee.IsPositive() {
+		if err := AdjustBalanceInTx(tx, feeAccountID, withdrawal.Asset, withdrawal.Fee); err != nil {
+			tx.Rollback()
+			log.Printf("❌ 提现手续费入账失败: %v", err)
+			return
+		}
+	}
+
 	// 提交事务
 	if err := tx.Commit().Error; err != nil {
 		log.Printf("❌ 提交事务失败: %v", err)
 		return
 	}
 
-	log.Printf("🎉 提现已完成: 用户ID=%s, 资产=%s, 金额=%s, TxHash=%s",
-		withdrawal.UserID, withdrawal.Asset, withdrawal.Amount.String(), txHash)
+	withdrawal.Status = "completed"
+	withdrawal.TxHash = txHash
+	log.Printf("🎉 提现已完成: 用户ID=%s, 资产=%s, 金额=%s, 手续费=%s, TxHash=%s",
+		withdrawal.UserID, withdrawal.Asset, withdrawal.Amount.String(), withdrawal.Fee.String(), txHash)
 }
 
 // MarkWithdrawalFailed 标记提现失败
@@ -227,30 +361,29 @@ func (p *WithdrawProcessor) MarkWithdrawalFailed(withdrawal *models.WithdrawReco
 		}
 	}()
 
-	// 1. 更新提现记录状态
-	if err := tx.Model(withdrawal).Updates(map[string]interface{}{
-		"status":     "failed",
-		"updated_at": time.Now(),
-	}).Error; err != nil {
+	// 1. 更新提现记录状态（条件更新保证只解冻一次）
+	result := tx.Model(&models.WithdrawRecord{}).
+		Where("id = ? AND status IN ?", withdrawal.ID, []string{"pending", "processing", "broadcast"}).
+		Updates(map[string]interface{}{
+			"status":      "failed",
+			"fail_reason": truncate(reason, 255),
+			"updated_at":  time.Now(),
+		})
+	if result.Error != nil {
 		tx.Rollback()
-		log.Printf("❌ 更新提现记录失败: %v", err)
+		log.Printf("❌ 更新提现记录失败: %v", result.Error)
 		return
 	}
-
-	// 2. 解冻资金
-	var balance models.Balance
-	if err := tx.Where("user_id = ? AND asset = ?",
-		withdrawal.UserID, withdrawal.Asset).First(&balance).Error; err != nil {
+	if result.RowsAffected == 0 {
 		tx.Rollback()
-		log.Printf("❌ 查询余额失败: %v", err)
+		log.Printf("⚠️  提现记录状态已变更，跳过失败处理: ID=%s", withdrawal.ID)
 		return
 	}
 
-	balance.Frozen = balance.Frozen.Sub(withdrawal.Amount)
-	balance.Available = balance.Available.Add(withdrawal.Amount)
-	if err := tx.Save(&balance).Error; err != nil {
+	// 2. 解冻资金
+	if err := unfreezeWithdrawal(tx, withdrawal); err != nil {
 		tx.Rollback()
-		log.Printf("❌ 更新余额失败: %v", err)
+		log.Printf("❌ 解冻资金失败: %v", err)
 		return
 	}
 
@@ -260,5 +393,7 @@ func (p *WithdrawProcessor) MarkWithdrawalFailed(withdrawal *models.WithdrawReco
 		return
 	}
 
+	withdrawal.Status = "failed"
+	withdrawal.FailReason = truncate(reason, 255)
 	log.Printf("❌ 提现已标记为失败并解冻资金: ID=%s, 原因=%s", withdrawal.ID, reason)
 }
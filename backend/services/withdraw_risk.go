@@ -0,0 +1,203 @@
+package services
+
+import (
+	"errors"
+	"expchange-backend/database"
+	"expchange-backend/models"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// 风控规则（命中后累加评分，评分达到 withdraw.risk.review_score 进入人工审核）
+const (
+	RiskFlagLargeAmount     = "large_amount"     // 单笔金额超过阈值
+	RiskFlagDailyLimit      = "daily_limit"      // 24 小时累计提现超过限额
+	RiskFlagNewAccount      = "new_account"      // 新注册账户
+	RiskFlagNewAddress      = "new_address"      // 从未成功提现过的地址
+	RiskFlagDepositVelocity = "deposit_velocity" // 充值后短时间内提走大部分资金
+)
+
+// riskFlagScores 各规则的评分
+var riskFlagScores = map[string]int{
+	RiskFlagLargeAmount:     60,
+	RiskFlagDailyLimit:      60,
+	RiskFlagNewAccount:      30,
+	RiskFlagNewAddress:      20,
+	RiskFlagDepositVelocity: 30,
+}
+
+var ErrWithdrawNotReviewable = errors.New("withdrawal is not pending review")
+
+// WithdrawRiskAssessment 风控评估结果
+type WithdrawRiskAssessment struct {
+	Score          int
+	Flags          []string
+	RequiresReview bool
+}
+
+// withdrawRiskThresholds 资产的大额和 24 小时累计阈值（资产单位）：优先使用链资产配置，
+// USDT 未配置时使用系统配置 withdraw.risk.large_amount / daily_limit，其他资产未配置时不检查（返回 0）
+func withdrawRiskThresholds(chainID int, asset string) (decimal.Decimal, decimal.Decimal) {
+	largeAmount, dailyLimit := decimal.Zero, decimal.Zero
+	if token, err := GetChainToken(chainID, asset); err == nil {
+		largeAmount, dailyLimit = token.RiskLargeAmount, token.RiskDailyLimit
+	}
+	if normalizeAsset(asset) == usdtAsset {
+		sysConfig := database.GetSystemConfigManager()
+		if !largeAmount.IsPositive() {
+			largeAmount = sysConfig.GetDecimal("withdraw.risk.large_amount", decimal.NewFromInt(10000))
+		}
+		if !dailyLimit.IsPositive() {
+			dailyLimit = sysConfig.GetDecimal("withdraw.risk.daily_limit", decimal.NewFromInt(50000))
+		}
+	}
+	return largeAmount, dailyLimit
+}
+
+// EvaluateWithdrawRisk 评估提现风险（在创建提现记录之前调用，金额阈值按资产分别配置，见 withdrawRiskThresholds）
+func EvaluateWithdrawRisk(user *models.User, chainID int, asset, address string, amount decimal.Decimal) WithdrawRiskAssessment {
+	sysConfig := database.GetSystemConfigManager()
+	var result WithdrawRiskAssessment
+	if !sysConfig.GetBool("withdraw.risk.enabled", true) {
+		return result
+	}
+
+	now := time.Now()
+	flag := func(name string) {
+		result.Flags = append(result.Flags, name)
+		result.Score += riskFlagScores[name]
+	}
+
+	// 1. 单笔大额
+	largeAmount, dailyLimit := withdrawRiskThresholds(chainID, asset)
+	if largeAmount.IsPositive() && amount.GreaterThanOrEqual(largeAmount) {
+		flag(RiskFlagLargeAmount)
+	}
+
+	// 2. 24 小时累计（同一资产所有链合计，失败和被拒绝的不计入）
+	if dailyLimit.IsPositive() {
+		var withdrawn decimal.NullDecimal
+		database.DB.Model(&models.WithdrawRecord{}).
+			Where("user_id = ? AND asset = ? AND created_at > ? AND status NOT IN ?",
+				user.ID, asset, now.Add(-24*time.Hour), []string{"failed", "rejected"}).
+			Select("SUM(amount)").Scan(&withdrawn)
+		if withdrawn.Decimal.Add(amount).GreaterThan(dailyLimit) {
+			flag(RiskFlagDailyLimit)
+		}
+	}
+
+	// 3. 新账户
+	newAccountDays := sysConfig.GetInt("withdraw.risk.new_account_days", 7)
+	if newAccountDays > 0 && user.CreatedAt.After(now.AddDate(0, 0, -newAccountDays)) {
+		flag(RiskFlagNewAccount)
+	}
+
+	// 4. 新地址（未成功提现过）
+	var completed int64
+	database.DB.Model(&models.WithdrawRecord{}).
+		Where("user_id = ? AND address = ? AND status = ?", user.ID, strings.ToLower(address), "completed").
+		Count(&completed)
+	if completed == 0 {
+		flag(RiskFlagNewAddress)
+	}
+
+	// 5. 充值后快速提现：窗口内同一资产的充值金额占本次提现的比例达到阈值（按比例比较，与资产单位无关）
+	velocityHours := sysConfig.GetInt("withdraw.risk.deposit_velocity_hours", 24)
+	velocityRatio := sysConfig.GetDecimal("withdraw.risk.deposit_velocity_ratio", decimal.NewFromFloat(0.8))
+	if velocityHours > 0 {
+		var deposited decimal.NullDecimal
+		database.DB.Model(&models.DepositRecord{}).
+			Where("user_id = ? AND asset = ? AND status = ? AND created_at > ?",
+				user.ID, asset, "confirmed", now.Add(-time.Duration(velocityHours)*time.Hour)).
+			Select("SUM(amount)").Scan(&deposited)
+		if deposited.Decimal.IsPositive() && deposited.Decimal.GreaterThanOrEqual(amount.Mul(velocityRatio)) {
+			flag(RiskFlagDepositVelocity)
+		}
+	}
+
+	reviewScore := sysConfig.GetInt("withdraw.risk.review_score", 50)
+	result.RequiresReview = result.Score >= reviewScore
+	return result
+}
+
+// ApproveWithdrawal 审核通过（pending_review → pending），调用方负责创建提现任务
+func ApproveWithdrawal(withdrawID, adminID, note string) (*models.WithdrawRecord, error) {
+	now := time.Now()
+	result := database.DB.Model(&models.WithdrawRecord{}).
+		Where("id = ? AND status = ?", withdrawID, "pending_review").
+		Updates(map[string]interface{}{
+			"status":      "pending",
+			"reviewed_by": adminID,
+			"reviewed_at": now,
+			"review_note": truncate(note, 255),
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	var withdrawal models.WithdrawRecord
+	if err := database.DB.Where("id = ?", withdrawID).First(&withdrawal).Error; err != nil {
+		return nil, err
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrWithdrawNotReviewable
+	}
+
+	log.Printf("✅ 提现审核通过: ID=%s, 管理员=%s", withdrawID, adminID)
+	return &withdrawal, nil
+}
+
+// RejectWithdrawal 审核拒绝（pending_review → rejected）并解冻资金
+func RejectWithdrawal(withdrawID, adminID, note string) (*models.WithdrawRecord, error) {
+	var withdrawal models.WithdrawRecord
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id = ?", withdrawID).First(&withdrawal).Error; err != nil {
+			return err
+		}
+
+		// 条件更新保证只解冻一次
+		result := tx.Model(&models.WithdrawRecord{}).
+			Where("id = ? AND status = ?", withdrawID, "pending_review").
+			Updates(map[string]interface{}{
+				"status":      "rejected",
+				"reviewed_by": adminID,
+				"reviewed_at": time.Now(),
+				"review_note": truncate(note, 255),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrWithdrawNotReviewable
+		}
+
+		return unfreezeWithdrawal(tx, &withdrawal)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	withdrawal.Status = "rejected"
+	log.Printf("🚫 提现审核拒绝并解冻资金: ID=%s, 管理员=%s, 备注=%s", withdrawID, adminID, note)
+	return &withdrawal, nil
+}
+
+// unfreezeWithdrawal 退回提现冻结的资金
+func unfreezeWithdrawal(tx *gorm.DB, withdrawal *models.WithdrawRecord) error {
+	var balance models.Balance
+	if err := tx.Where("user_id = ? AND asset = ?", withdrawal.UserID, withdrawal.Asset).First(&balance).Error; err != nil {
+		return fmt.Errorf("query balance: %w", err)
+	}
+
+	balance.Frozen = balance.Frozen.Sub(withdrawal.Amount)
+	balance.Available = balance.Available.Add(withdrawal.Amount)
+	if err := tx.Save(&balance).Error; err != nil {
+		return fmt.Errorf("update balance: %w", err)
+	}
+	return nil
+}
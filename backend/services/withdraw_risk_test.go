@@ -0,0 +1,77 @@
+package services
+
+import (
+	"expchange-backend/database"
+	"expchange-backend/models"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestEvaluateWithdrawRiskPerAsset(t *testing.T) {
+	const chainID = 56
+	d := decimal.RequireFromString
+
+	tests := []struct {
+		name      string
+		token     *models.ChainToken
+		asset     string
+		withdrawn string // 24 小时内已提现
+		amount    string
+		wantLarge bool
+		wantDaily bool
+	}{
+		{name: "usdt falls back to system config", asset: "USDT", amount: "10000", wantLarge: true},
+		{name: "usdt below system config", asset: "USDT", amount: "9999"},
+		{
+			name:   "usdt token threshold overrides system config",
+			token:  &models.ChainToken{Asset: "USDT", ContractAddress: "0x55d398326f99059fF775485246999027B3197955", RiskLargeAmount: d("50000")},
+			asset:  "USDT",
+			amount: "20000",
+		},
+		{
+			name:   "native coin without thresholds is not compared against usdt limits",
+			token:  &models.ChainToken{Asset: "BNB"},
+			asset:  "BNB",
+			amount: "20000", withdrawn: "60000",
+		},
+		{
+			name:   "native coin large amount",
+			token:  &models.ChainToken{Asset: "BNB", RiskLargeAmount: d("10")},
+			asset:  "BNB",
+			amount: "10", wantLarge: true,
+		},
+		{
+			name:   "native coin daily limit",
+			token:  &models.ChainToken{Asset: "BNB", RiskLargeAmount: d("10"), RiskDailyLimit: d("30")},
+			asset:  "BNB",
+			amount: "6", withdrawn: "25", wantDaily: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newTestDB(t, &models.User{}, &models.ChainToken{}, &models.WithdrawRecord{}, &models.DepositRecord{})
+			user := &models.User{WalletAddress: "0x00000000000000000000000000000000000000aa"}
+			database.DB.Create(user)
+			if tt.token != nil {
+				tt.token.ChainID = chainID
+				if err := database.DB.Create(tt.token).Error; err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.withdrawn != "" {
+				database.DB.Create(&models.WithdrawRecord{UserID: user.ID, Asset: tt.asset, Amount: d(tt.withdrawn), ChainID: chainID, Status: "completed", CreatedAt: time.Now().Add(-time.Hour)})
+			}
+
+			result := EvaluateWithdrawRisk(user, chainID, tt.asset, "0x00000000000000000000000000000000000000bb", d(tt.amount))
+			if got := slices.Contains(result.Flags, RiskFlagLargeAmount); got != tt.wantLarge {
+				t.Fatalf("large_amount=%v, want %v (flags %v)", got, tt.wantLarge, result.Flags)
+			}
+			if got := slices.Contains(result.Flags, RiskFlagDailyLimit); got != tt.wantDaily {
+				t.Fatalf("daily_limit=%v, want %v (flags %v)", got, tt.wantDaily, result.Flags)
+			}
+		})
+	}
+}
@@ -0,0 +1,447 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"expchange-backend/database"
+	"expchange-backend/models"
+	"expchange-backend/pkg/secretbox"
+	"expchange-backend/pkg/totp"
+	"fmt"
+	"log"
+	"math/big"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"gorm.io/gorm"
+)
+
+// userTOTPIssuer 用户 TOTP 的签发方名称
+const userTOTPIssuer = "Velocity Exchange"
+
+// 邮件验证码参数
+const (
+	verificationCodeMaxAttempts = 5
+	verificationCodeResend      = time.Minute
+)
+
+var (
+	ErrWithdrawLocked          = errors.New("withdrawals are temporarily locked after a security change")
+	ErrSecondFactorRequired    = errors.New("two-factor verification code required")
+	ErrSecondFactorNotBound    = errors.New("two-factor verification required: please enable TOTP or verify your email first")
+	ErrSecondFactorInvalid     = errors.New("invalid or expired verification code")
+	ErrAddressNotWhitelisted   = errors.New("address is not in your withdrawal address book")
+	ErrAddressLocked           = errors.New("new withdrawal address is still in its lock period")
+	ErrEmailNotVerified        = errors.New("email is not verified")
+	ErrVerificationCodeTooFast = errors.New("verification code was sent recently, please wait")
+)
+
+// WithdrawSecurityService 提现安全：地址簿、白名单模式、二次验证（TOTP / 邮件验证码）、安全变更后锁定
+type WithdrawSecurityService struct {
+	box *secretbox.Box
+}
+
+func NewWithdrawSecurityService(encryptionKey string) *WithdrawSecurityService {
+	box, err := secretbox.New(encryptionKey)
+	if err != nil {
+		panic(fmt.Sprintf("failed to init withdraw security encryption: %v", err))
+	}
+	return &WithdrawSecurityService{box: box}
+}
+
+// LockWithdrawals 安全设置变更后锁定提现（锁定时长：系统配置 withdraw.security_lock_hours）
+func LockWithdrawals(userID, reason string) {
+	hours := database.GetSystemConfigManager().GetInt("withdraw.security_lock_hours", 24)
+	if hours <= 0 {
+		return
+	}
+
+	until := time.Now().Add(time.Duration(hours) * time.Hour)
+	result := database.DB.Model(&models.User{}).
+		Where("id = ? AND (withdraw_locked_until IS NULL OR withdraw_locked_until < ?)", userID, until).
+		Updates(map[string]interface{}{
+			"withdraw_locked_until": until,
+			"withdraw_lock_reason":  reason,
+		})
+	if result.Error != nil {
+		log.Printf("❌ 锁定提现失败: UserID=%s, %v", userID, result.Error)
+		return
+	}
+
+	log.Printf("🔒 用户提现已锁定: UserID=%s, 原因=%s, 截止=%s", userID, reason, until.Format(time.RFC3339))
+}
+
+// CheckWithdrawal 创建提现前的安全检查（需在冻结资金、创建记录之前调用）
+// viaAPIKey 为 true 时只能提现到地址簿中已生效的地址，且不要求二次验证（API 密钥已绑定 IP 白名单）
+func (s *WithdrawSecurityService) CheckWithdrawal(user *models.User, address string, viaAPIKey bool, totpCode, emailCode string) error {
+	if user.WithdrawLockedUntil != nil && time.Now().Before(*user.WithdrawLockedUntil) {
+		return fmt.Errorf("%w until %s", ErrWithdrawLocked, user.WithdrawLockedUntil.Format(time.RFC3339))
+	}
+
+	// 不在地址簿中属于正常情况，使用 Find 避免打印 record not found 日志
+	var entry models.WithdrawAddress
+	result := database.DB.Where("user_id = ? AND address = ?", user.ID, strings.ToLower(address)).Limit(1).Find(&entry)
+	inAddressBook := result.Error == nil && result.RowsAffected == 1
+
+	if (viaAPIKey || user.WithdrawWhitelistOnly) && !inAddressBook {
+		return ErrAddressNotWhitelisted
+	}
+	if inAddressBook && time.Now().Before(entry.ActivatedAt) {
+		return fmt.Errorf("%w until %s", ErrAddressLocked, entry.ActivatedAt.Format(time.RFC3339))
+	}
+
+	if viaAPIKey {
+		return nil
+	}
+	return s.VerifySecondFactor(user, models.VerificationPurposeWithdraw, totpCode, emailCode)
+}
+
+// VerifySecondFactor 二次验证：已启用 TOTP 时校验 TOTP，否则校验邮件验证码
+// 未绑定任何二次验证方式时，根据 withdraw.require_2fa 决定是否拒绝
+func (s *WithdrawSecurityService) VerifySecondFactor(user *models.User, purpose, totpCode, emailCode string) error {
+	switch {
+	case user.TOTPEnabled:
+		return s.verifyTOTP(user, totpCode)
+	case user.EmailVerified:
+		return s.verifyEmailCode(user, purpose, emailCode)
+	case database.GetSystemConfigManager().GetBool("withdraw.require_2fa", true):
+		return ErrSecondFactorNotBound
+	default:
+		return nil
+	}
+}
+
+// ==================== TOTP ====================
+
+// SetupTOTP 生成 TOTP 密钥（启用前可重复生成）
+func (s *WithdrawSecurityService) SetupTOTP(user *models.User) (string, string, error) {
+	if user.TOTPEnabled {
+		return "", "", errors.New("totp is already enabled")
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return "", "", err
+	}
+	encrypted, err := s.box.Seal(secret)
+	if err != nil {
+		return "", "", err
+	}
+	if err := database.DB.Model(user).Update("totp_secret", encrypted).Error; err != nil {
+		return "", "", err
+	}
+	user.TOTPSecret = encrypted
+
+	return secret, totp.URL(userTOTPIssuer, user.WalletAddress, secret), nil
+}
+
+// EnableTOTP 验证验证码并启用 TOTP（启用后锁定提现，防止盗号后绑定自己的验证器立即提现）
+func (s *WithdrawSecurityService) EnableTOTP(user *models.User, code string) error {
+	if user.TOTPEnabled {
+		return errors.New("totp is already enabled")
+	}
+	if user.TOTPSecret == "" {
+		return errors.New("please setup totp first")
+	}
+	if err := s.verifyTOTP(user, code); err != nil {
+		return err
+	}
+
+	if err := database.DB.Model(user).Update("totp_enabled", true).Error; err != nil {
+		return err
+	}
+	LockWithdrawals(user.ID, "totp_enabled")
+	return nil
+}
+
+// DisableTOTP 关闭 TOTP（需当前 TOTP 验证码，关闭后锁定提现）
+func (s *WithdrawSecurityService) DisableTOTP(user *models.User, code string) error {
+	if !user.TOTPEnabled {
+		return errors.New("totp is not enabled")
+	}
+	if err := s.verifyTOTP(user, code); err != nil {
+		return err
+	}
+
+	if err := database.DB.Model(user).Updates(map[string]interface{}{
+		"totp_enabled": false,
+		"totp_secret":  "",
+	}).Error; err != nil {
+		return err
+	}
+	LockWithdrawals(user.ID, "totp_disabled")
+	return nil
+}
+
+func (s *WithdrawSecurityService) verifyTOTP(user *models.User, code string) error {
+	if code == "" {
+		return ErrSecondFactorRequired
+	}
+	secret, err := s.box.Open(user.TOTPSecret)
+	if err != nil {
+		return err
+	}
+
+	step, ok := totp.Validate(secret, code, time.Now())
+	if !ok || step <= user.LastTOTPStep {
+		return ErrSecondFactorInvalid
+	}
+
+	// 条件更新防止同一验证码被并发重复使用
+	result := database.DB.Model(&models.User{}).
+		Where("id = ? AND last_totp_step < ?", user.ID, step).
+		Update("last_totp_step", step)
+	if result.Error != nil || result.RowsAffected != 1 {
+		return ErrSecondFactorInvalid
+	}
+	user.LastTOTPStep = step
+	return nil
+}
+
+// ==================== 邮件验证码 ====================
+
+// SetEmail 绑定或更换邮箱（已有二次验证时需先验证；首次绑定和更换都会锁定提现），并发送验证邮件
+func (s *WithdrawSecurityService) SetEmail(user *models.User, email, totpCode, emailCode string) error {
+	address, err := mail.ParseAddress(strings.TrimSpace(email))
+	if err != nil {
+		return errors.New("invalid email address")
+	}
+	email = strings.ToLower(address.Address)
+
+	if user.TOTPEnabled || user.EmailVerified {
+		if err := s.VerifySecondFactor(user, models.VerificationPurposeSecurity, totpCode, emailCode); err != nil {
+			return err
+		}
+	}
+
+	hadVerifiedEmail := user.EmailVerified
+	if err := database.DB.Model(user).Updates(map[string]interface{}{
+		"email":          email,
+		"email_verified": false,
+	}).Error; err != nil {
+		return err
+	}
+	user.Email = email
+	user.EmailVerified = false
+
+	if hadVerifiedEmail {
+		LockWithdrawals(user.ID, "email_changed")
+	} else {
+		LockWithdrawals(user.ID, "email_set")
+	}
+
+	_, err = s.SendCode(user, models.VerificationPurposeEmailVerify)
+	return err
+}
+
+// VerifyEmail 校验绑定邮箱的验证码（验证通过后锁定提现）
+func (s *WithdrawSecurityService) VerifyEmail(user *models.User, code string) error {
+	if user.Email == "" {
+		return errors.New("please set email first")
+	}
+	if user.EmailVerified {
+		return nil
+	}
+	if err := s.verifyEmailCode(user, models.VerificationPurposeEmailVerify, code); err != nil {
+		return err
+	}
+	if err := database.DB.Model(user).Update("email_verified", true).Error; err != nil {
+		return err
+	}
+	LockWithdrawals(user.ID, "email_verified")
+	return nil
+}
+
+// SendCode 发送邮件验证码，返回脱敏后的邮箱
+func (s *WithdrawSecurityService) SendCode(user *models.User, purpose string) (string, error) {
+	switch purpose {
+	case models.VerificationPurposeWithdraw, models.VerificationPurposeSecurity:
+		if !user.EmailVerified {
+			return "", ErrEmailNotVerified
+		}
+	case models.VerificationPurposeEmailVerify:
+		if user.Email == "" {
+			return "", errors.New("please set email first")
+		}
+	default:
+		return "", errors.New("invalid purpose")
+	}
+
+	var last models.VerificationCode
+	err := database.DB.Where("user_id = ? AND purpose = ?", user.ID, purpose).
+		Order("created_at DESC").First(&last).Error
+	if err == nil && time.Since(last.CreatedAt) < verificationCodeResend {
+		return "", ErrVerificationCodeTooFast
+	}
+
+	code, err := randomDigits(6)
+	if err != nil {
+		return "", err
+	}
+	ttl := database.GetSystemConfigManager().GetInt("withdraw.code_ttl_minutes", 10)
+	record := &models.VerificationCode{
+		UserID:    user.ID,
+		Purpose:   purpose,
+		Target:    user.Email,
+		CodeHash:  hashVerificationCode(user.ID, purpose, code),
+		ExpiresAt: time.Now().Add(time.Duration(ttl) * time.Minute),
+	}
+
+	// 同一用途只保留最新的验证码
+	if err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.VerificationCode{}).
+			Where("user_id = ? AND purpose = ? AND used_at IS NULL", user.ID, purpose).
+			Update("used_at", time.Now()).Error; err != nil {
+			return err
+		}
+		return tx.Create(record).Error
+	}); err != nil {
+		return "", err
+	}
+
+	subject := "Velocity Exchange verification code"
+	body := fmt.Sprintf("Your verification code for %s is %s. It expires in %d minutes.", purpose, code, ttl)
+	if err := GetNotifier().Send(user.Email, subject, body); err != nil {
+		return "", fmt.Errorf("failed to send verification code: %w", err)
+	}
+
+	return maskEmail(user.Email), nil
+}
+
+func (s *WithdrawSecurityService) verifyEmailCode(user *models.User, purpose, code string) error {
+	if code == "" {
+		return ErrSecondFactorRequired
+	}
+
+	var record models.VerificationCode
+	if err := database.DB.Where("user_id = ? AND purpose = ? AND used_at IS NULL AND expires_at > ?", user.ID, purpose, time.Now()).
+		Order("created_at DESC").First(&record).Error; err != nil {
+		return ErrSecondFactorInvalid
+	}
+	if record.Attempts >= verificationCodeMaxAttempts {
+		return ErrSecondFactorInvalid
+	}
+
+	expected := hashVerificationCode(user.ID, purpose, code)
+	if subtle.ConstantTimeCompare([]byte(expected), []byte(record.CodeHash)) != 1 {
+		database.DB.Model(&record).Update("attempts", gorm.Expr("attempts + 1"))
+		return ErrSecondFactorInvalid
+	}
+
+	// 条件更新保证验证码只能使用一次
+	result := database.DB.Model(&models.VerificationCode{}).
+		Where("id = ? AND used_at IS NULL", record.ID).
+		Update("used_at", time.Now())
+	if result.Error != nil || result.RowsAffected != 1 {
+		return ErrSecondFactorInvalid
+	}
+	return nil
+}
+
+// ==================== 地址簿 ====================
+
+// GetAddresses 用户的提现地址簿
+func (s *WithdrawSecurityService) GetAddresses(userID string) []models.WithdrawAddress {
+	addresses := []models.WithdrawAddress{}
+	database.DB.Where("user_id = ?", userID).Order("created_at DESC").Find(&addresses)
+	return addresses
+}
+
+// AddAddress 添加提现地址（需二次验证，锁定期结束后生效：系统配置 withdraw.address_lock_hours）
+func (s *WithdrawSecurityService) AddAddress(user *models.User, address, label, totpCode, emailCode string) (*models.WithdrawAddress, error) {
+	if !common.IsHexAddress(address) {
+		return nil, errors.New("invalid wallet address")
+	}
+	address = strings.ToLower(address)
+
+	var count int64
+	database.DB.Model(&models.WithdrawAddress{}).Where("user_id = ? AND address = ?", user.ID, address).Count(&count)
+	if count > 0 {
+		return nil, errors.New("address already exists")
+	}
+
+	if err := s.VerifySecondFactor(user, models.VerificationPurposeSecurity, totpCode, emailCode); err != nil {
+		return nil, err
+	}
+
+	lockHours := database.GetSystemConfigManager().GetInt("withdraw.address_lock_hours", 24)
+	entry := &models.WithdrawAddress{
+		UserID:      user.ID,
+		Address:     address,
+		Label:       truncate(strings.TrimSpace(label), 50),
+		ActivatedAt: time.Now().Add(time.Duration(lockHours) * time.Hour),
+	}
+	if err := database.DB.Create(entry).Error; err != nil {
+		return nil, err
+	}
+
+	log.Printf("📒 添加提现地址: UserID=%s, Address=%s, 生效时间=%s", user.ID, address, entry.ActivatedAt.Format(time.RFC3339))
+	return entry, nil
+}
+
+// DeleteAddress 删除提现地址
+func (s *WithdrawSecurityService) DeleteAddress(userID, id string) error {
+	result := database.DB.Where("id = ? AND user_id = ?", id, userID).Delete(&models.WithdrawAddress{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("address not found")
+	}
+	return nil
+}
+
+// SetWhitelistOnly 开启/关闭白名单模式（关闭需二次验证并锁定提现）
+func (s *WithdrawSecurityService) SetWhitelistOnly(user *models.User, enabled bool, totpCode, emailCode string) error {
+	if user.WithdrawWhitelistOnly == enabled {
+		return nil
+	}
+	if !enabled {
+		if err := s.VerifySecondFactor(user, models.VerificationPurposeSecurity, totpCode, emailCode); err != nil {
+			return err
+		}
+	}
+
+	if err := database.DB.Model(user).Update("withdraw_whitelist_only", enabled).Error; err != nil {
+		return err
+	}
+	if !enabled {
+		LockWithdrawals(user.ID, "whitelist_disabled")
+	}
+	return nil
+}
+
+func hashVerificationCode(userID, purpose, code string) string {
+	sum := sha256.Sum256([]byte(userID + ":" + purpose + ":" + strings.TrimSpace(code)))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomDigits(n int) (string, error) {
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		d, err := rand.Int(rand.Reader, big.NewInt(10))
+		if err != nil {
+			return "", err
+		}
+		b.WriteByte(byte('0' + d.Int64()))
+	}
+	return b.String(), nil
+}
+
+// maskEmail 邮箱脱敏：ab***@example.com
+func maskEmail(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at <= 0 {
+		return email
+	}
+	name := email[:at]
+	if len(name) > 2 {
+		name = name[:2]
+	}
+	return name + "***" + email[at:]
+}
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"expchange-backend/database"
+	"expchange-backend/models"
+	"expchange-backend/pkg/totp"
+	"regexp"
+	"testing"
+	"time"
+)
+
+// captureNotifier 记录最后一封通知（用于取出邮件验证码）
+type captureNotifier struct{ body string }
+
+func (n *captureNotifier) Send(to, subject, body string) error {
+	n.body = body
+	return nil
+}
+
+func TestSecurityChangesLockWithdrawals(t *testing.T) {
+	tests := []struct {
+		name       string
+		setup      func(t *testing.T, s *WithdrawSecurityService, user *models.User)
+		action     func(t *testing.T, s *WithdrawSecurityService, user *models.User, mail *captureNotifier) error
+		wantReason string
+	}{
+		{
+			name: "enable totp",
+			setup: func(t *testing.T, s *WithdrawSecurityService, user *models.User) {
+				if _, _, err := s.SetupTOTP(user); err != nil {
+					t.Fatalf("SetupTOTP: %v", err)
+				}
+			},
+			action: func(t *testing.T, s *WithdrawSecurityService, user *models.User, mail *captureNotifier) error {
+				secret, err := s.box.Open(user.TOTPSecret)
+				if err != nil {
+					t.Fatal(err)
+				}
+				code, err := totp.CodeAt(secret, totp.Step(time.Now()))
+				if err != nil {
+					t.Fatal(err)
+				}
+				return s.EnableTOTP(user, code)
+			},
+			wantReason: "totp_enabled",
+		},
+		{
+			name: "set first email",
+			action: func(t *testing.T, s *WithdrawSecurityService, user *models.User, mail *captureNotifier) error {
+				return s.SetEmail(user, "alice@example.com", "", "")
+			},
+			wantReason: "email_set",
+		},
+		{
+			name: "verify email",
+			setup: func(t *testing.T, s *WithdrawSecurityService, user *models.User) {
+				database.DB.Model(user).Update("email", "alice@example.com")
+				user.Email = "alice@example.com"
+			},
+			action: func(t *testing.T, s *WithdrawSecurityService, user *models.User, mail *captureNotifier) error {
+				if _, err := s.SendCode(user, models.VerificationPurposeEmailVerify); err != nil {
+					t.Fatalf("SendCode: %v", err)
+				}
+				code := regexp.MustCompile(`\b\d{6}\b`).FindString(mail.body)
+				return s.VerifyEmail(user, code)
+			},
+			wantReason: "email_verified",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newTestDB(t, &models.User{}, &models.VerificationCode{})
+			mail := &captureNotifier{}
+			SetNotifier(mail)
+			t.Cleanup(func() { SetNotifier(LogNotifier{}) })
+
+			s := NewWithdrawSecurityService("test-encryption-key")
+			user := &models.User{WalletAddress: "0x00000000000000000000000000000000000000aa"}
+			if err := database.DB.Create(user).Error; err != nil {
+				t.Fatal(err)
+			}
+			if tt.setup != nil {
+				tt.setup(t, s, user)
+			}
+
+			if err := tt.action(t, s, user, mail); err != nil {
+				t.Fatalf("action: %v", err)
+			}
+
+			var saved models.User
+			database.DB.First(&saved, "id = ?", user.ID)
+			if saved.WithdrawLockedUntil == nil || !saved.WithdrawLockedUntil.After(time.Now()) {
+				t.Fatalf("withdrawals not locked: %v", saved.WithdrawLockedUntil)
+			}
+			if saved.WithdrawLockReason != tt.wantReason {
+				t.Fatalf("lock reason=%q, want %q", saved.WithdrawLockReason, tt.wantReason)
+			}
+		})
+	}
+}
@@ -0,0 +1,457 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"expchange-backend/database"
+	"expchange-backend/models"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+var (
+	ErrWithdrawNotBroadcast = errors.New("withdrawal is not awaiting on-chain confirmation")
+	ErrWithdrawMaxBumps     = errors.New("withdrawal has reached the maximum number of fee bumps")
+)
+
+// CheckBroadcastWithdrawals 轮询所有已广播提现（单笔和批量）的交易回执（由任务队列定时调用）
+//   - 回执成功且确认数达到链配置 → completed，扣减冻结资金
+//   - 回执失败（revert）→ 单笔提现 failed 并解冻资金；批量提现回退为单笔发送
+//   - 无回执但 nonce 已在确认深度内被其他交易使用（被替换/丢弃）→ 同上；批量交易仍能查到时转入 review 人工核对
+//   - 长时间未打包 → 使用相同 nonce 提高 gas price 重新广播
+func (p *WithdrawProcessor) CheckBroadcastWithdrawals() {
+	p.trackMu.Lock()
+	defer p.trackMu.Unlock()
+
+	var withdrawals []models.WithdrawRecord
+	database.DB.Where("status = ? AND batch_id = ?", "broadcast", "").Order("broadcast_at ASC").Find(&withdrawals)
+	var batches []models.WithdrawBatch
+	database.DB.Where("status = ?", "broadcast").Order("broadcast_at ASC").Find(&batches)
+	if len(withdrawals) == 0 && len(batches) == 0 {
+		return
+	}
+
+	// 同一轮检查内按链复用 RPC 连接
+	conns := newChainConns()
+
+	for i := range withdrawals {
+		withdrawal := &withdrawals[i]
+		chain, client, err := conns.get(withdrawal.ChainID)
+		if err != nil {
+			log.Printf("⚠️  提现确认跟踪：%v: ID=%s", err, withdrawal.ID)
+			continue
+		}
+		if err := p.trackWithdrawal(client, chain, withdrawal); err != nil {
+			log.Printf("⚠️  提现确认跟踪失败: ID=%s, err=%v", withdrawal.ID, err)
+		}
+	}
+
+	for i := range batches {
+		batch := &batches[i]
+		chain, client, err := conns.get(batch.ChainID)
+		if err != nil {
+			log.Printf("⚠️  批量提现确认跟踪：%v: BatchID=%s", err, batch.ID)
+			continue
+		}
+		if err := p.trackBatch(client, chain, batch); err != nil {
+			log.Printf("⚠️  批量提现确认跟踪失败: BatchID=%s, err=%v", batch.ID, err)
+		}
+	}
+}
+
+// txCheckResult 已广播交易的链上检查结果
+type txCheckResult struct {
+	Receipt       *types.Receipt // 已打包的回执（未打包为 nil）
+	MinedHash     string         // 实际打包的交易hash（可能是被替换的旧交易）
+	BlockNumber   uint64
+	Confirmations int
+	NonceConsumed bool // 无回执且 nonce 已在确认深度内被其他交易使用
+}
+
+// checkTx 查询一组同 nonce 交易（当前交易及被替换的交易）的链上状态
+// client 必须固定单个节点（ChainSession），否则 nonce 与回执可能来自区块高度不同的节点
+func (p *WithdrawProcessor) checkTx(client *ethclient.Client, fromAddress string, nonce uint64, hashes []string, required int) (*txCheckResult, error) {
+	latestBlock, err := client.BlockNumber(p.ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get block number: %w", err)
+	}
+
+	// 先读取确认深度处的 nonce，再查回执：若 nonce 已被使用，随后的回执查询一定能查到本提现的交易（如果是它打包的）
+	var nonceConsumed bool
+	if fromAddress != "" && latestBlock+1 >= uint64(required) {
+		safeBlock := new(big.Int).SetUint64(latestBlock + 1 - uint64(required))
+		safeNonce, err := client.NonceAt(p.ctx, common.HexToAddress(fromAddress), safeBlock)
+		if err != nil {
+			return nil, fmt.Errorf("get nonce: %w", err)
+		}
+		nonceConsumed = safeNonce > nonce
+	}
+
+	receipt, minedHash, err := p.findReceipt(client, hashes)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &txCheckResult{Receipt: receipt, MinedHash: minedHash}
+	if receipt == nil {
+		result.NonceConsumed = nonceConsumed
+		return result, nil
+	}
+
+	result.BlockNumber = receipt.BlockNumber.Uint64()
+	if latestBlock >= result.BlockNumber {
+		result.Confirmations = int(latestBlock-result.BlockNumber) + 1
+	}
+	return result, nil
+}
+
+// isStuck 广播后超过 withdraw.tracker.stuck_minutes 仍未打包
+func isStuck(broadcastAt *time.Time) bool {
+	stuckMinutes := database.GetSystemConfigManager().GetInt("withdraw.tracker.stuck_minutes", 10)
+	return broadcastAt != nil && time.Since(*broadcastAt) > time.Duration(stuckMinutes)*time.Minute
+}
+
+// trackWithdrawal 检查单笔已广播提现的链上状态
+func (p *WithdrawProcessor) trackWithdrawal(client *ethclient.Client, chain *models.ChainConfig, withdrawal *models.WithdrawRecord) error {
+	required := requiredConfirmations(chain)
+	hashes := splitTxHashes(withdrawal.TxHash, withdrawal.ReplacedTxHashes)
+	result, err := p.checkTx(client, withdrawal.FromAddress, withdrawal.Nonce, hashes, required)
+	if err != nil {
+		return err
+	}
+
+	if result.Receipt == nil {
+		if result.NonceConsumed {
+			// 标记失败会解冻资金，交易若实际已打包会重复出金：必须先在同一节点确认提现交易（含被替换的交易）均不存在
+			dropped, err := p.confirmTxDropped(client, hashes)
+			if err != nil {
+				return err
+			}
+			if !dropped {
+				p.holdWithdrawalForReview(withdrawal, "nonce consumed but withdrawal transaction is still known to the node")
+				return nil
+			}
+			p.MarkWithdrawalFailed(withdrawal, "Transaction dropped: nonce consumed by another transaction")
+			return nil
+		}
+
+		if withdrawal.BlockNumber > 0 {
+			// 之前已打包的交易回执消失（链重组），重新等待
+			log.Printf("⚠️  提现交易回执消失（可能发生链重组）: ID=%s, TxHash=%s", withdrawal.ID, withdrawal.TxHash)
+			p.updateConfirmations(withdrawal, 0, 0)
+			return nil
+		}
+
+		// 未打包：超过等待时间后加速替换
+		if isStuck(withdrawal.BroadcastAt) {
+			if err := p.bumpWithdrawal(client, chain, withdrawal); err != nil {
+				if errors.Is(err, ErrWithdrawMaxBumps) {
+					log.Printf("⚠️  提现交易长时间未打包且已达最大加速次数，需人工处理: ID=%s, TxHash=%s", withdrawal.ID, withdrawal.TxHash)
+					return nil
+				}
+				return fmt.Errorf("bump fee: %w", err)
+			}
+		}
+		return nil
+	}
+
+	if result.Receipt.Status != types.ReceiptStatusSuccessful {
+		withdrawal.TxHash = result.MinedHash
+		p.MarkWithdrawalFailed(withdrawal, fmt.Sprintf("Transaction reverted: %s", result.MinedHash))
+		return nil
+	}
+
+	if result.Confirmations < required {
+		if result.Confirmations != withdrawal.Confirmations || result.BlockNumber != withdrawal.BlockNumber {
+			p.updateConfirmations(withdrawal, result.BlockNumber, result.Confirmations)
+		}
+		return nil
+	}
+
+	withdrawal.BlockNumber = result.BlockNumber
+	withdrawal.Confirmations = result.Confirmations
+	p.ConfirmWithdrawal(withdrawal, result.MinedHash)
+	return nil
+}
+
+// holdWithdrawalForReview 无法确认提现交易是否已打包：提现转入 review 并保持资金冻结，由人工核对
+func (p *WithdrawProcessor) holdWithdrawalForReview(withdrawal *models.WithdrawRecord, reason string) {
+	database.DB.Model(&models.WithdrawRecord{}).
+		Where("id = ? AND status = ?", withdrawal.ID, "broadcast").
+		Updates(map[string]interface{}{
+			"status":      "review",
+			"fail_reason": truncate(reason, 255),
+			"updated_at":  time.Now(),
+		})
+	log.Printf("❌ 提现状态无法确认（需人工核对）: ID=%s, TxHash=%s, 原因=%s", withdrawal.ID, withdrawal.TxHash, reason)
+	SendAlert("提现需人工核对", fmt.Sprintf("WithdrawID=%s\nChainID=%d\nNonce=%d\nTxHash=%s\n原因=%s",
+		withdrawal.ID, withdrawal.ChainID, withdrawal.Nonce, withdrawal.TxHash, reason))
+}
+
+// findReceipt 依次查询交易回执，返回已打包的回执和对应的交易hash（均未打包时返回 nil）
+func (p *WithdrawProcessor) findReceipt(client *ethclient.Client, hashes []string) (*types.Receipt, string, error) {
+	for _, hash := range hashes {
+		receipt, err := client.TransactionReceipt(p.ctx, common.HexToHash(hash))
+		if errors.Is(err, ethereum.NotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, "", fmt.Errorf("get receipt %s: %w", hash, err)
+		}
+		return receipt, hash, nil
+	}
+	return nil, "", nil
+}
+
+// confirmTxDropped 在同一节点上逐个查询交易，全部查不到（既未打包也不在交易池中）才确认已被丢弃
+func (p *WithdrawProcessor) confirmTxDropped(client *ethclient.Client, hashes []string) (bool, error) {
+	for _, hash := range hashes {
+		_, _, err := client.TransactionByHash(p.ctx, common.HexToHash(hash))
+		if errors.Is(err, ethereum.NotFound) {
+			continue
+		}
+		if err != nil {
+			return false, fmt.Errorf("get transaction %s: %w", hash, err)
+		}
+		return false, nil
+	}
+	return true, nil
+}
+
+// updateConfirmations 更新打包区块和当前确认数
+func (p *WithdrawProcessor) updateConfirmations(withdrawal *models.WithdrawRecord, blockNumber uint64, confirmations int) {
+	database.DB.Model(&models.WithdrawRecord{}).
+		Where("id = ? AND status = ?", withdrawal.ID, "broadcast").
+		Updates(map[string]interface{}{
+			"block_number":  blockNumber,
+			"confirmations": confirmations,
+		})
+	withdrawal.BlockNumber = blockNumber
+	withdrawal.Confirmations = confirmations
+}
+
+// bumpWithdrawal 使用相同 nonce、更高的 gas price 重新签名并广播（替换未打包的交易）
+func (p *WithdrawProcessor) bumpWithdrawal(client *ethclient.Client, chain *models.ChainConfig, withdrawal *models.WithdrawRecord) error {
+	if withdrawal.BumpCount >= database.GetSystemConfigManager().GetInt("withdraw.tracker.max_bumps", 3) {
+		return ErrWithdrawMaxBumps
+	}
+
+	oldParams, err := parseWithdrawTxParams(withdrawal.TxType, withdrawal.GasLimit, withdrawal.GasPrice, withdrawal.GasTipCap)
+	if err != nil {
+		return err
+	}
+	token, err := GetChainToken(chain.ChainID, withdrawal.Asset)
+	if err != nil {
+		return err
+	}
+	to, value, data, err := transferCall(token, withdrawal.Address, withdrawal.Amount.Sub(withdrawal.Fee))
+	if err != nil {
+		return err
+	}
+
+	signedTx, params, err := p.signReplacementTx(client, chain, withdrawal.FromAddress, withdrawal.Nonce, oldParams, to, value, data)
+	if err != nil {
+		return err
+	}
+
+	// 广播前先记录新交易hash：广播后保存失败时，新交易被打包也能通过回执找到
+	pending := appendTxHash(withdrawal.ReplacedTxHashes, signedTx.Hash().Hex())
+	if err := database.DB.Model(&models.WithdrawRecord{}).
+		Where("id = ? AND status = ?", withdrawal.ID, "broadcast").
+		Update("replaced_tx_hashes", pending).Error; err != nil {
+		return fmt.Errorf("save replacement tx %s: %w", signedTx.Hash().Hex(), err)
+	}
+	if err := p.sendSignedTx(client, signedTx); err != nil {
+		// 未广播成功的hash留在列表中，查询不到时跳过
+		withdrawal.ReplacedTxHashes = pending
+		return err
+	}
+
+	gasPrice, gasTipCap := params.feeStrings()
+	replaced := appendTxHash(withdrawal.ReplacedTxHashes, withdrawal.TxHash)
+	now := time.Now()
+	if err := database.DB.Model(&models.WithdrawRecord{}).
+		Where("id = ? AND status = ?", withdrawal.ID, "broadcast").
+		Updates(map[string]interface{}{
+			"tx_hash":            signedTx.Hash().Hex(),
+			"tx_type":            params.TxType,
+			"gas_limit":          params.GasLimit,
+			"gas_price":          gasPrice,
+			"gas_tip_cap":        gasTipCap,
+			"broadcast_at":       now,
+			"bump_count":         withdrawal.BumpCount + 1,
+			"replaced_tx_hashes": replaced,
+			"updated_at":         now,
+		}).Error; err != nil {
+		// 新交易已发出，其hash已在 replaced_tx_hashes 中，下一轮会通过 nonce 和回执继续跟踪
+		return fmt.Errorf("save replacement tx %s: %w", signedTx.Hash().Hex(), err)
+	}
+
+	log.Printf("⛽ 提现交易已加速替换: ID=%s, Nonce=%d, GasPrice=%s -> %s, TxHash=%s -> %s",
+		withdrawal.ID, withdrawal.Nonce, withdrawal.GasPrice, gasPrice, withdrawal.TxHash, signedTx.Hash().Hex())
+
+	withdrawal.TxHash = signedTx.Hash().Hex()
+	withdrawal.TxType = params.TxType
+	withdrawal.GasLimit = params.GasLimit
+	withdrawal.GasPrice = gasPrice
+	withdrawal.GasTipCap = gasTipCap
+	withdrawal.BroadcastAt = &now
+	withdrawal.BumpCount++
+	withdrawal.ReplacedTxHashes = replaced
+	return nil
+}
+
+// signReplacementTx 使用相同 nonce 和更高费用重新签名（费用计算见 BumpWithdrawTxParams），由调用方记录hash后广播
+func (p *WithdrawProcessor) signReplacementTx(
+	client *ethclient.Client,
+	chain *models.ChainConfig,
+	fromAddress string,
+	nonce uint64,
+	oldParams *WithdrawTxParams,
+	to common.Address,
+	value *big.Int,
+	data []byte,
+) (*types.Transaction, *WithdrawTxParams, error) {
+	vault, err := GetWalletKeyVault()
+	if err != nil {
+		return nil, nil, err
+	}
+	txSigner, err := vault.SignerForChain(chain)
+	if err != nil {
+		return nil, nil, fmt.Errorf("withdraw signer not available: %w", err)
+	}
+	if !strings.EqualFold(txSigner.Address().Hex(), fromAddress) {
+		return nil, nil, fmt.Errorf("chain withdraw address changed from %s, cannot replace transaction", fromAddress)
+	}
+
+	bumpPercent := database.GetSystemConfigManager().GetInt("withdraw.tracker.fee_bump_percent", 20)
+	params, err := BumpWithdrawTxParams(p.ctx, client, chain, oldParams, bumpPercent)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	signedTx, err := txSigner.SignTx(p.ctx, params.NewTx(chain.ChainID, nonce, to, value, data), big.NewInt(int64(chain.ChainID)))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to sign transaction: %w", err)
+	}
+	return signedTx, params, nil
+}
+
+// SpeedUpWithdrawal 管理员手动加速未确认的提现（不等待超时）
+func (p *WithdrawProcessor) SpeedUpWithdrawal(withdrawID string) (*models.WithdrawRecord, error) {
+	p.trackMu.Lock()
+	defer p.trackMu.Unlock()
+
+	var withdrawal models.WithdrawRecord
+	if err := database.DB.Where("id = ?", withdrawID).First(&withdrawal).Error; err != nil {
+		return nil, err
+	}
+	if withdrawal.Status != "broadcast" {
+		return nil, ErrWithdrawNotBroadcast
+	}
+
+	// 批量提现：加速整笔批量交易
+	if withdrawal.BatchID != "" {
+		if err := p.speedUpBatch(withdrawal.BatchID); err != nil {
+			return nil, err
+		}
+		database.DB.Where("id = ?", withdrawID).First(&withdrawal)
+		return &withdrawal, nil
+	}
+
+	var chain models.ChainConfig
+	if err := database.DB.Where("chain_id = ?", withdrawal.ChainID).First(&chain).Error; err != nil {
+		return nil, fmt.Errorf("chain %d not found", withdrawal.ChainID)
+	}
+
+	client, err := ChainClient(&chain)
+	if err != nil {
+		return nil, err
+	}
+
+	// 已被打包的交易不能再替换
+	ctx, cancel := context.WithTimeout(p.ctx, 10*time.Second)
+	defer cancel()
+	for _, hash := range splitTxHashes(withdrawal.TxHash, withdrawal.ReplacedTxHashes) {
+		if _, err := client.TransactionReceipt(ctx, common.HexToHash(hash)); err == nil {
+			return nil, fmt.Errorf("transaction %s is already mined", hash)
+		}
+	}
+
+	if err := p.bumpWithdrawal(client, &chain, &withdrawal); err != nil {
+		return nil, err
+	}
+	return &withdrawal, nil
+}
+
+// splitTxHashes 同一 nonce 的所有候选交易hash（当前交易优先，其后为被替换的交易）
+func splitTxHashes(current, replaced string) []string {
+	hashes := []string{current}
+	if replaced != "" {
+		hashes = append(hashes, strings.Split(replaced, ",")...)
+	}
+	return hashes
+}
+
+// appendTxHash 把被替换的交易hash追加到列表
+func appendTxHash(replaced, hash string) string {
+	if replaced == "" {
+		return hash
+	}
+	return replaced + "," + hash
+}
+
+// requiredConfirmations 链配置的提现确认数（至少 1）
+func requiredConfirmations(chain *models.ChainConfig) int {
+	if chain.WithdrawConfirmations < 1 {
+		return 1
+	}
+	return chain.WithdrawConfirmations
+}
+
+// chainConns 一轮检查内按链缓存的链配置和 RPC 连接
+// 每条链一轮内固定一个节点（ChainSession）：checkTx 先查确认深度处的 nonce 再查回执，
+// 两次读取落到不同节点时，落后节点查不到已打包交易的回执，会被误判为 nonce 已被其他交易使用
+type chainConns struct {
+	chains  map[int]*models.ChainConfig
+	clients map[int]*ethclient.Client
+}
+
+func newChainConns() *chainConns {
+	return &chainConns{
+		chains:  make(map[int]*models.ChainConfig),
+		clients: make(map[int]*ethclient.Client),
+	}
+}
+
+func (c *chainConns) get(chainID int) (*models.ChainConfig, *ethclient.Client, error) {
+	chain, ok := c.chains[chainID]
+	if !ok {
+		var config models.ChainConfig
+		if err := database.DB.Where("chain_id = ?", chainID).First(&config).Error; err == nil {
+			chain = &config
+		}
+		c.chains[chainID] = chain
+	}
+	if chain == nil {
+		return nil, nil, fmt.Errorf("chain %d not found", chainID)
+	}
+
+	client, ok := c.clients[chainID]
+	if !ok {
+		var err error
+		if client, err = ChainSession(chain); err != nil {
+			return nil, nil, err
+		}
+		c.clients[chainID] = client
+	}
+	return chain, client, nil
+}
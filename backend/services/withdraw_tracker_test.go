@@ -0,0 +1,237 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"expchange-backend/database"
+	"expchange-backend/models"
+	"expchange-backend/pkg/rpcpool"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	testTxNonce = 5
+	testTxHash  = "0x1111111111111111111111111111111111111111111111111111111111111111"
+)
+
+// newChainNode 模拟节点：ahead 节点已打包 nonce 5 的交易（nonce 为 6，可查到回执），
+// behind 节点尚未同步到该区块（nonce 为 5，查不到回执），区块高度差在落后阈值以内
+func newChainNode(t *testing.T, ahead bool) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var req struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+		}
+		json.Unmarshal(body, &req)
+
+		var result interface{}
+		switch req.Method {
+		case "eth_blockNumber":
+			result = "0x64"
+		case "eth_getTransactionCount":
+			if ahead {
+				result = fmt.Sprintf("0x%x", testTxNonce+1)
+			} else {
+				result = fmt.Sprintf("0x%x", testTxNonce)
+			}
+		case "eth_getTransactionReceipt":
+			if ahead {
+				result = map[string]interface{}{
+					"transactionHash":   testTxHash,
+					"transactionIndex":  "0x0",
+					"blockHash":         common.Hash{2}.Hex(),
+					"blockNumber":       "0x60",
+					"cumulativeGasUsed": "0x5208",
+					"gasUsed":           "0x5208",
+					"effectiveGasPrice": "0x1",
+					"status":            "0x1",
+					"type":              "0x0",
+					"logs":              []interface{}{},
+					"logsBloom":         "0x" + fmt.Sprintf("%0512x", 0),
+				}
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "result": result})
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// TestCheckTxSessionIsConsistent 节点视图不一致时，同一次检查的 nonce 和回执来自同一节点：
+// 要么查到回执（已打包），要么 nonce 未被使用（继续等待），不会出现“nonce 已被使用但查不到回执”
+// 从而把已打包的提现判为失败退款，或把已打包的批量交易回退为单笔重发
+func TestCheckTxSessionIsConsistent(t *testing.T) {
+	ahead := newChainNode(t, true)
+	behind := newChainNode(t, false)
+
+	pool := rpcpool.New(rpcpool.Options{FailureThreshold: 3, OpenDuration: time.Second, MaxHeadLag: 20, RequestTimeout: 2 * time.Second}, nil)
+	if _, err := pool.Client(1, []rpcpool.Endpoint{{URL: ahead.URL, Weight: 1}, {URL: behind.URL, Weight: 1}}); err != nil {
+		t.Fatalf("Client: %v", err)
+	}
+	p := &WithdrawProcessor{ctx: context.Background()}
+	from := common.Address{9}.Hex()
+
+	seen := map[bool]int{}
+	for i := 0; i < 50; i++ {
+		session, err := pool.Session(1)
+		if err != nil {
+			t.Fatalf("Session: %v", err)
+		}
+		result, err := p.checkTx(session, from, testTxNonce, []string{testTxHash}, 1)
+		if err != nil {
+			t.Fatalf("checkTx: %v", err)
+		}
+		if result.Receipt == nil && result.NonceConsumed {
+			t.Fatalf("iteration %d: mined transaction reported as dropped (nonce consumed, no receipt)", i)
+		}
+		seen[result.Receipt != nil]++
+	}
+	// 两个节点都应被选中过，确认测试覆盖了视图不一致的情况
+	if seen[true] == 0 || seen[false] == 0 {
+		t.Fatalf("expected sessions on both nodes, got mined=%d pending=%d", seen[true], seen[false])
+	}
+}
+
+// newTxLookupNode 模拟 eth_getTransactionByHash：known 中的交易返回待打包交易，failing 中的交易返回 RPC 错误，其余返回 null
+// 该节点上 nonce 5 已被使用且查不到任何回执
+func newTxLookupNode(t *testing.T, known, failing map[string]bool) *ethclient.Client {
+	t.Helper()
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx, err := types.SignTx(types.NewTx(&types.LegacyTx{Nonce: testTxNonce, Gas: 21000, GasPrice: common.Big1}), types.HomesteadSigner{}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	txJSON, _ := tx.MarshalJSON()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var req struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+			Params []string        `json:"params"`
+		}
+		json.Unmarshal(body, &req)
+
+		resp := map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "result": nil}
+		switch req.Method {
+		case "eth_blockNumber":
+			resp["result"] = "0x64"
+		case "eth_getTransactionCount":
+			resp["result"] = fmt.Sprintf("0x%x", testTxNonce+1)
+		}
+		if req.Method == "eth_getTransactionByHash" && len(req.Params) == 1 {
+			switch {
+			case failing[req.Params[0]]:
+				delete(resp, "result")
+				resp["error"] = map[string]interface{}{"code": -32000, "message": "internal error"}
+			case known[req.Params[0]]:
+				resp["result"] = json.RawMessage(txJSON)
+			}
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(srv.Close)
+
+	client, err := ethclient.Dial(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+// TestConfirmTxDropped 批量交易回退为单笔发送前的确认：任一交易（含被替换的交易）仍可查到或查询出错都不能判定为丢弃
+func TestConfirmTxDropped(t *testing.T) {
+	const replacedHash = "0x2222222222222222222222222222222222222222222222222222222222222222"
+	hashes := []string{testTxHash, replacedHash}
+
+	tests := []struct {
+		name        string
+		known       map[string]bool
+		failing     map[string]bool
+		wantDropped bool
+		wantErr     bool
+	}{
+		{name: "all absent", wantDropped: true},
+		{name: "current tx known", known: map[string]bool{testTxHash: true}},
+		{name: "replaced tx known", known: map[string]bool{replacedHash: true}},
+		{name: "lookup error", failing: map[string]bool{replacedHash: true}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &WithdrawProcessor{ctx: context.Background()}
+			client := newTxLookupNode(t, tt.known, tt.failing)
+
+			dropped, err := p.confirmTxDropped(client, hashes)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("confirmTxDropped err=%v, wantErr=%v", err, tt.wantErr)
+			}
+			if dropped != tt.wantDropped {
+				t.Fatalf("confirmTxDropped dropped=%v, want %v", dropped, tt.wantDropped)
+			}
+		})
+	}
+}
+
+// TestTrackWithdrawalNonceConsumed nonce 已被使用但查不到回执：只有确认提现交易（含被替换的交易）均已丢弃才标记失败并解冻，
+// 否则转入 review 保持冻结，避免交易实际已打包时重复出金
+func TestTrackWithdrawalNonceConsumed(t *testing.T) {
+	const replacedHash = "0x2222222222222222222222222222222222222222222222222222222222222222"
+
+	tests := []struct {
+		name       string
+		known      map[string]bool
+		failing    map[string]bool
+		wantStatus string
+		wantFrozen string
+		wantErr    bool
+	}{
+		{name: "all transactions dropped", wantStatus: "failed", wantFrozen: "0"},
+		{name: "replaced tx still known", known: map[string]bool{replacedHash: true}, wantStatus: "review", wantFrozen: "100"},
+		{name: "lookup error", failing: map[string]bool{testTxHash: true}, wantStatus: "broadcast", wantFrozen: "100", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newTestDB(t, &models.WithdrawRecord{}, &models.Balance{})
+			balance := &models.Balance{UserID: "user-1", Asset: "USDT", Available: decimal.Zero, Frozen: decimal.NewFromInt(100)}
+			withdrawal := &models.WithdrawRecord{
+				UserID: "user-1", Asset: "USDT", Amount: decimal.NewFromInt(100), ChainID: 56, Status: "broadcast",
+				FromAddress: common.Address{9}.Hex(), Nonce: testTxNonce, TxHash: testTxHash, ReplacedTxHashes: replacedHash,
+			}
+			for _, row := range []interface{}{balance, withdrawal} {
+				if err := database.DB.Create(row).Error; err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			p := &WithdrawProcessor{ctx: context.Background()}
+			client := newTxLookupNode(t, tt.known, tt.failing)
+			err := p.trackWithdrawal(client, &models.ChainConfig{ChainID: 56, WithdrawConfirmations: 1}, withdrawal)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("trackWithdrawal err=%v, wantErr=%v", err, tt.wantErr)
+			}
+
+			var saved models.WithdrawRecord
+			database.DB.First(&saved, "id = ?", withdrawal.ID)
+			database.DB.First(balance, "id = ?", balance.ID)
+			if saved.Status != tt.wantStatus || balance.Frozen.String() != tt.wantFrozen {
+				t.Fatalf("status=%s frozen=%s, want %s/%s", saved.Status, balance.Frozen, tt.wantStatus, tt.wantFrozen)
+			}
+		})
+	}
+}
@@ -18,3 +18,16 @@ func GenerateObjectID() string {
 	return id
 }
 
+
+// GenerateReferralCode 生成8位邀请码（去除易混淆字符 0/O/1/I）
+func GenerateReferralCode() string {
+	const charset = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	randomBytes := make([]byte, 8)
+	rand.Read(randomBytes)
+
+	code := make([]byte, len(randomBytes))
+	for i, b := range randomBytes {
+		code[i] = charset[int(b)%len(charset)]
+	}
+	return string(code)
+}
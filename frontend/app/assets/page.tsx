@@ -2,7 +2,7 @@
 
 import { useState, useEffect } from 'react';
 import { useAppSelector } from '@/lib/store/hooks';
-import { useGetBalancesQuery, useDepositMutation, useWithdrawMutation, useGetDepositRecordsQuery, useGetWithdrawRecordsQuery, useGetAllTickersQuery } from '@/lib/services/api';
+import { useGetBalancesQuery, useGetDepositAddressQuery, useDepositMutation, useWithdrawMutation, useGetDepositRecordsQuery, useGetWithdrawRecordsQuery, useGetAllTickersQuery } from '@/lib/services/api';
 import { useRouter } from 'next/navigation';
 import { useAccount, useChainId } from 'wagmi';
 import { useWalletClient } from 'wagmi';
@@ -48,6 +48,11 @@ export default function AssetsPage() {
   const [processing, setProcessing] = useState(false);
   const [usdtBalance, setUsdtBalance] = useState('0');
 
+  // 充值地址（打开充值窗口时按当前链获取）
+  const { data: depositAddress } = useGetDepositAddressQuery(chainId, {
+    skip: !isAuthenticated || !showDepositModal || !chainId,
+  });
+
   // 获取充值提现记录
   const { data: depositRecords = [] } = useGetDepositRecordsQuery(undefined, {
     skip: !isAuthenticated,
@@ -78,11 +83,6 @@ export default function AssetsPage() {
       return;
     }
 
-    if (selectedAsset !== 'USDT') {
-      toast.error('目前仅支持 USDT 充值');
-      return;
-    }
-
     if (!walletClient) {
       toast.error('请先连接钱包');
       return;
@@ -99,6 +99,30 @@ export default function AssetsPage() {
       toast.error('不支持的链');
       return;
     }
+
+    // 资产需在该链开放充值
+    const token = chainConfig.tokens?.find((t) => t.asset === selectedAsset && t.deposit_enabled);
+    if (!token) {
+      toast.error(`${chainConfig.chain_name} 暂不支持 ${selectedAsset} 充值`);
+      return;
+    }
+    if (parseFloat(amount) < parseFloat(token.min_deposit_amount)) {
+      toast.error(`最小充值金额为 ${token.min_deposit_amount} ${selectedAsset}`);
+      return;
+    }
+
+    // 最小提现额和手续费
+    const feeConfig = chainConfig.withdraw_fees?.find((f) => f.asset === selectedAsset);
+    if (feeConfig) {
+      if (parseFloat(amount) < parseFloat(feeConfig.min_amount)) {
+        toast.error(`最小提现金额为 ${feeConfig.min_amount} ${selectedAsset}`);
+        return;
+      }
+      if (parseFloat(amount) <= parseFloat(feeConfig.fee)) {
+        toast.error(`提现金额需大于手续费 ${feeConfig.fee} ${selectedAsset}`);
+        return;
+      }
+    }
     
     setProcessing(true);
     
@@ -106,9 +130,9 @@ export default function AssetsPage() {
       await toast.promise(
         (async () => {
           // 1. 调用合约转账
-          console.log('📤 开始 USDT 转账...');
+          console.log(`📤 开始 ${selectedAsset} 转账...`);
           console.log('链:', chainConfig.chain_name, 'ChainID:', chainId);
-          const txHash = await DepositService.depositUSDT(walletClient, amount, chainConfig);
+          const txHash = await DepositService.depositAsset(walletClient, amount, chainConfig, token, depositAddress?.address);
           console.log('✅ 转账成功，hash:', txHash);
 
           // 2. 提交到后端验证
@@ -145,11 +169,6 @@ export default function AssetsPage() {
       return;
     }
 
-    if (selectedAsset !== 'USDT') {
-      toast.error('目前仅支持 USDT 提现');
-      return;
-    }
-
     // 验证地址格式
     if (!/^0x[a-fA-F0-9]{40}$/.test(withdrawAddress)) {
       toast.error('请输入正确的钱包地址');
@@ -167,6 +186,17 @@ export default function AssetsPage() {
       toast.error('不支持的链');
       return;
     }
+
+    // 资产需在该链开放提现
+    const withdrawToken = chainConfig.tokens?.find((t) => t.asset === selectedAsset && t.withdraw_enabled);
+    if (!withdrawToken) {
+      toast.error(`${chainConfig.chain_name} 暂不支持 ${selectedAsset} 提现`);
+      return;
+    }
+    if (withdrawToken.withdraw_paused) {
+      toast.error(`${chainConfig.chain_name} ${selectedAsset} 提现维护中，请稍后再试`);
+      return;
+    }
     
     setProcessing(true);
     
@@ -196,6 +226,11 @@ export default function AssetsPage() {
     }
   };
 
+  // 当前网络的提现手续费配置
+  const withdrawFeeConfig = chainId
+    ? getChainById(chainId)?.withdraw_fees?.find((f) => f.asset === selectedAsset)
+    : undefined;
+
   // 获取代币价格
   const getAssetPrice = (asset: string): number => {
     if (asset === 'USDT') return 1;
@@ -371,6 +406,14 @@ export default function AssetsPage() {
               </div>
             )}
             
+            {depositAddress && !depositAddress.shared && (
+              <div className="mb-4 p-3 bg-[#151a35] rounded-lg border border-gray-700">
+                <div className="text-xs text-gray-400 mb-1">您的专属充值地址（{depositAddress.chain}）</div>
+                <div className="text-xs font-mono break-all">{depositAddress.address}</div>
+                <div className="text-xs text-gray-500 mt-1">也可从交易所或其他钱包直接转入 USDT，确认后自动到账</div>
+              </div>
+            )}
+
             <div className="mb-4">
               <label className="block text-sm text-gray-400 mb-2">充值金额</label>
               <input
@@ -384,7 +427,7 @@ export default function AssetsPage() {
                 step="0.01"
               />
               <div className="text-xs text-gray-400 mt-2">
-                💡 将通过智能合约转账到平台地址
+                💡 将通过智能合约转账到{depositAddress && !depositAddress.shared ? '您的专属充值地址' : '平台地址'}
               </div>
             </div>
             
@@ -439,7 +482,16 @@ export default function AssetsPage() {
                 min="0"
                 step="0.01"
               />
-             
+              {withdrawFeeConfig && (
+                <div className="mt-2 text-xs text-gray-400 space-y-1">
+                  <div>手续费: {withdrawFeeConfig.fee} {selectedAsset}，最小提现: {withdrawFeeConfig.min_amount} {selectedAsset}</div>
+                  {parseFloat(amount) > parseFloat(withdrawFeeConfig.fee) && (
+                    <div>
+                      预计到账: {formatQuantity(parseFloat(amount) - parseFloat(withdrawFeeConfig.fee))} {selectedAsset}
+                    </div>
+                  )}
+                </div>
+              )}
             </div>
             <div className="flex gap-3 lg:gap-4">
               <button
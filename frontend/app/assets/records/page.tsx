@@ -151,11 +151,17 @@ export default function RecordsPage() {
                           <span className={`px-2 py-1 lg:px-3 lg:py-1.5 rounded text-xs lg:text-sm ${
                             record.status === 'confirmed' 
                               ? 'bg-green-500/20 text-green-400'
-                              : record.status === 'pending'
+                              : record.status === 'pending' || record.status === 'confirming'
                               ? 'bg-yellow-500/20 text-yellow-400'
                               : 'bg-red-500/20 text-red-400'
                           }`}>
-                            {record.status === 'confirmed' ? '已确认' : record.status === 'pending' ? '待确认' : '失败'}
+                            {record.status === 'confirmed'
+                              ? '已确认'
+                              : record.status === 'confirming'
+                              ? `确认中 ${record.confirmations}/${record.required_confirmations}`
+                              : record.status === 'pending'
+                              ? '待确认'
+                              : '失败'}
                           </span>
                         </div>
                       </div>
@@ -227,9 +233,9 @@ export default function RecordsPage() {
                           <span className={`px-2 py-1 lg:px-3 lg:py-1.5 rounded text-xs lg:text-sm whitespace-nowrap ${
                             record.status === 'completed' 
                               ? 'bg-green-500/20 text-green-400'
-                              : record.status === 'pending'
+                              : record.status === 'pending' || record.status === 'pending_review' || record.status === 'batching'
                               ? 'bg-yellow-500/20 text-yellow-400'
-                              : record.status === 'processing'
+                              : record.status === 'processing' || record.status === 'broadcast' || record.status === 'review'
                               ? 'bg-blue-500/20 text-blue-400'
                               : 'bg-red-500/20 text-red-400'
                           }`}>
@@ -237,8 +243,16 @@ export default function RecordsPage() {
                               ? '已完成' 
                               : record.status === 'pending' 
                               ? '待处理' 
-                              : record.status === 'processing'
+                              : record.status === 'pending_review'
+                              ? '审核中'
+                              : record.status === 'batching'
+                              ? '待处理'
+                              : record.status === 'processing' || record.status === 'review'
                               ? '处理中'
+                              : record.status === 'broadcast'
+                              ? '确认中'
+                              : record.status === 'rejected'
+                              ? '已拒绝'
                               : '失败'}
                           </span>
                         </div>
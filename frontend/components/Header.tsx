@@ -3,7 +3,7 @@
 import Link from 'next/link';
 import { useAppDispatch, useAppSelector } from '@/lib/store/hooks';
 import { logout, setAuth } from '@/lib/store/slices/authSlice';
-import { useGetNonceMutation, useLoginMutation } from '@/lib/services/api';
+import { useGetNonceMutation, useLoginMutation, useLogoutMutation } from '@/lib/services/api';
 import { useState, useEffect } from 'react';
 import { ConnectButton } from '@rainbow-me/rainbowkit';
 import { useAccount, useSignMessage, useDisconnect } from 'wagmi';
@@ -27,6 +27,7 @@ export default function Header() {
 
   const [getNonce] = useGetNonceMutation();
   const [login] = useLoginMutation();
+  const [logoutSession] = useLogoutMutation();
 
   // 当钱包连接且未认证时，自动触发登录
   useEffect(() => {
@@ -45,11 +46,10 @@ export default function Header() {
       
       // 获取nonce
       console.log('📡 获取 nonce...');
-      const { nonce } = await getNonce(lowerAddress).unwrap();
+      const { nonce, message } = await getNonce(lowerAddress).unwrap();
       console.log('✅ 获取 nonce 成功:', nonce);
 
-      // 签名
-      const message = `登录到 Velocity Exchange\nNonce: ${nonce}`;
+      // 签名（后端生成的 SIWE 消息，需原样签名）
       console.log('✍️ 请求签名，消息:', message);
       const signature = await signMessageAsync({ message });
       console.log('✅ 签名成功:', signature?.slice(0, 10) + '...');
@@ -67,7 +67,7 @@ export default function Header() {
         signature 
       }).unwrap();
       
-      dispatch(setAuth({ user: result.user, token: result.token }));
+      dispatch(setAuth({ user: result.user, token: result.token, refreshToken: result.refresh_token }));
       
       console.log('✅ 登录成功！');
       showToast.success('登录成功！');
@@ -89,7 +89,9 @@ export default function Header() {
     }
   };
 
-  const handleLogout = () => {
+  const handleLogout = async () => {
+    // 通知后端吊销当前会话（失败不影响本地登出）
+    await logoutSession().unwrap().catch(() => {});
     dispatch(logout());
     disconnect(); // 断开钱包连接
     window.location.href = '/';
@@ -1,22 +1,26 @@
 import { ethers } from 'ethers';
 import { USDT_ABI } from '../chains/config';
-import type { ChainConfig } from '../services/api';
+import type { ChainConfig, ChainToken } from '../services/api';
 
 /**
  * 充值服务（支持多链）
  */
 export class DepositService {
   /**
-   * 执行 USDT 充值转账
+   * 执行充值转账（ERC20 调用合约 transfer，原生币直接转账）
    * @param provider Web3 Provider
-   * @param amount 充值金额（USDT）
+   * @param amount 充值金额
    * @param chainConfig 链配置（从后端获取）
+   * @param token 充值资产（链配置中的 tokens）
+   * @param depositAddress 用户专属充值地址（未配置时转入平台共享地址）
    * @returns 交易 hash
    */
-  static async depositUSDT(
+  static async depositAsset(
     provider: any,
     amount: string,
-    chainConfig: ChainConfig
+    chainConfig: ChainConfig,
+    token: ChainToken,
+    depositAddress?: string
   ): Promise<string> {
     try {
       if (!provider) {
@@ -44,36 +48,48 @@ export class DepositService {
       console.log('💰 开始充值流程');
       console.log('链:', chainConfig.chain_name);
       console.log('用户地址:', userAddress);
-      console.log('充值金额:', amount, 'USDT');
-      console.log('平台地址:', chainConfig.platform_deposit_address);
-
-      // 创建 USDT 合约实例
-      const usdtContract = new ethers.Contract(
-        chainConfig.usdt_contract_address,
-        USDT_ABI,
-        signer
-      );
-
-      // 检查用户 USDT 余额
-      const balance = await usdtContract.balanceOf(userAddress);
-      const balanceInUSDT = ethers.formatUnits(balance, chainConfig.usdt_decimals);
-      console.log('当前 USDT 余额:', balanceInUSDT);
-
-      // 转换金额为 wei
-      const amountInWei = ethers.parseUnits(amount, chainConfig.usdt_decimals);
-
-      // 检查余额是否足够
-      if (balance < amountInWei) {
-        throw new Error(`Insufficient USDT balance. You have ${balanceInUSDT} USDT`);
+      console.log('充值金额:', amount, token.asset);
+      const toAddress = depositAddress || chainConfig.platform_deposit_address;
+      console.log('充值地址:', toAddress);
+
+      // 转换金额为链上最小单位
+      const amountInWei = ethers.parseUnits(amount, token.decimals);
+
+      let tx;
+      if (!token.contract_address) {
+        // 原生币：直接转账（gas 费用另外扣除）
+        const balance = await ethersProvider.getBalance(userAddress);
+        if (balance < amountInWei) {
+          throw new Error(`Insufficient ${token.asset} balance. You have ${ethers.formatUnits(balance, token.decimals)} ${token.asset}`);
+        }
+
+        console.log('🔄 发送转账交易...');
+        tx = await signer.sendTransaction({ to: toAddress, value: amountInWei });
+      } else {
+        // 创建代币合约实例
+        const tokenContract = new ethers.Contract(
+          token.contract_address,
+          USDT_ABI,
+          signer
+        );
+
+        // 检查用户代币余额
+        const balance = await tokenContract.balanceOf(userAddress);
+        const formattedBalance = ethers.formatUnits(balance, token.decimals);
+        console.log(`当前 ${token.asset} 余额:`, formattedBalance);
+
+        if (balance < amountInWei) {
+          throw new Error(`Insufficient ${token.asset} balance. You have ${formattedBalance} ${token.asset}`);
+        }
+
+        // 执行转账
+        console.log('🔄 发送转账交易...');
+        tx = await tokenContract.transfer(
+          toAddress,
+          amountInWei
+        );
       }
 
-      // 执行转账
-      console.log('🔄 发送转账交易...');
-      const tx = await usdtContract.transfer(
-        chainConfig.platform_deposit_address,
-        amountInWei
-      );
-
       console.log('✅ 交易已发送，hash:', tx.hash);
       console.log('ℹ️ 不等待确认，立即提交到后端验证');
 
@@ -1,4 +1,5 @@
 import { createApi, fetchBaseQuery } from '@reduxjs/toolkit/query/react';
+import type { BaseQueryFn, FetchArgs, FetchBaseQueryError } from '@reduxjs/toolkit/query/react';
 
 // API 配置 - 使用 Next.js API Routes 代理
 const API_URL = '';
@@ -95,6 +96,51 @@ export interface Balance {
   updated_at: string;
 }
 
+export interface SecurityStatus {
+  totp_enabled: boolean;
+  email: string;
+  email_verified: boolean;
+  withdraw_whitelist_only: boolean;
+  withdraw_locked_until: string | null;
+  withdraw_lock_reason: string;
+  require_2fa: boolean;
+  address_lock_hours: number;
+}
+
+export interface WithdrawAddress {
+  id: string;
+  address: string;
+  label: string;
+  activated_at: string;
+  created_at: string;
+}
+
+// 敏感操作的二次验证码（已启用 TOTP 填 totp_code，否则填邮件验证码）
+export interface SecondFactor {
+  totp_code?: string;
+  email_code?: string;
+}
+
+// 提现手续费（从提现金额中扣除，到账金额 = 提现金额 - 手续费）
+export interface WithdrawFee {
+  chain_id: number;
+  asset: string;
+  fee: string;
+  min_amount: string;
+}
+
+// 链上资产（合约为空表示原生币）
+export interface ChainToken {
+  chain_id: number;
+  asset: string;
+  contract_address: string;
+  decimals: number;
+  deposit_enabled: boolean;
+  withdraw_enabled: boolean;
+  withdraw_paused: boolean; // 热钱包余额不足时临时暂停提现
+  min_deposit_amount: string;
+}
+
 export interface ChainConfig {
   id: string;
   chain_name: string;
@@ -103,37 +149,93 @@ export interface ChainConfig {
   block_explorer_url: string;
   usdt_contract_address: string;
   usdt_decimals: number;
+  withdraw_confirmations: number;
+  required_confirmations: number;
   platform_deposit_address: string;
-  platform_withdraw_private_key?: string;
+  platform_withdraw_address?: string;
   enabled: boolean;
+  withdraw_fees: WithdrawFee[];
+  tokens?: ChainToken[];
   created_at: string;
   updated_at: string;
 }
 
+// 充值地址：shared=false 为用户专属地址（任意钱包转入都会自动入账）
+export interface DepositAddress {
+  chain_id: number;
+  chain: string;
+  address: string;
+  shared: boolean;
+}
+
+const rawBaseQuery = fetchBaseQuery({
+  baseUrl: `${API_URL}/api`,
+  prepareHeaders: (headers) => {
+    const token = typeof window !== 'undefined' ? localStorage.getItem('token') : null;
+    if (token) {
+      headers.set('Authorization', `Bearer ${token}`);
+    }
+    return headers;
+  },
+});
+
+// 同一时间只发起一次刷新请求
+let refreshing: Promise<boolean> | null = null;
+
+// 访问令牌过期（401）时用刷新令牌换取新令牌并重试一次
+const baseQueryWithReauth: BaseQueryFn<string | FetchArgs, unknown, FetchBaseQueryError> = async (args, apiCtx, extraOptions) => {
+  let result = await rawBaseQuery(args, apiCtx, extraOptions);
+  if (result.error?.status !== 401 || typeof window === 'undefined') {
+    return result;
+  }
+
+  const refreshToken = localStorage.getItem('refresh_token');
+  if (!refreshToken) {
+    return result;
+  }
+
+  if (!refreshing) {
+    refreshing = (async () => {
+      const refreshResult = await rawBaseQuery(
+        { url: '/auth/refresh', method: 'POST', body: { refresh_token: refreshToken } },
+        apiCtx,
+        extraOptions
+      );
+      const data = refreshResult.data as { token: string; refresh_token: string } | undefined;
+      if (data?.token) {
+        localStorage.setItem('token', data.token);
+        localStorage.setItem('refresh_token', data.refresh_token);
+        apiCtx.dispatch({ type: 'auth/setToken', payload: data.token });
+        return true;
+      }
+      apiCtx.dispatch({ type: 'auth/logout' });
+      return false;
+    })().finally(() => {
+      refreshing = null;
+    });
+  }
+
+  if (await refreshing) {
+    result = await rawBaseQuery(args, apiCtx, extraOptions);
+  }
+  return result;
+};
+
 // 创建 API
 export const api = createApi({
   reducerPath: 'api',
-  baseQuery: fetchBaseQuery({
-    baseUrl: `${API_URL}/api`,
-    prepareHeaders: (headers) => {
-      const token = typeof window !== 'undefined' ? localStorage.getItem('token') : null;
-      if (token) {
-        headers.set('Authorization', `Bearer ${token}`);
-      }
-      return headers;
-    },
-  }),
-  tagTypes: ['TradingPairs', 'Tickers', 'Orders', 'Balances', 'Trades', 'OrderBook', 'Klines'],
+  baseQuery: baseQueryWithReauth,
+  tagTypes: ['TradingPairs', 'Tickers', 'Orders', 'Balances', 'Trades', 'OrderBook', 'Klines', 'Security'],
   endpoints: (builder) => ({
     // ========== 认证接口 ==========
-    getNonce: builder.mutation<{ nonce: string }, string>({
+    getNonce: builder.mutation<{ nonce: string; message: string; expires_at: string }, string>({
       query: (walletAddress) => ({
         url: '/auth/nonce',
         method: 'POST',
         body: { wallet_address: walletAddress },
       }),
     }),
-    login: builder.mutation<{ token: string; user: User }, { walletAddress: string; signature: string }>({
+    login: builder.mutation<{ token: string; refresh_token: string; expires_in: number; user: User }, { walletAddress: string; signature: string }>({
       query: ({ walletAddress, signature }) => ({
         url: '/auth/login',
         method: 'POST',
@@ -143,6 +245,12 @@ export const api = createApi({
         },
       }),
     }),
+    logout: builder.mutation<{ message: string }, void>({
+      query: () => ({
+        url: '/auth/logout',
+        method: 'POST',
+      }),
+    }),
     getProfile: builder.query<User, void>({
       query: () => '/profile',
     }),
@@ -226,6 +334,9 @@ export const api = createApi({
       query: (asset) => `/balances/${asset}`,
       providesTags: (result, error, asset) => [{ type: 'Balances', id: asset }],
     }),
+    getDepositAddress: builder.query<DepositAddress, number>({
+      query: (chainId) => `/balances/deposit-address?chainId=${chainId}`,
+    }),
     deposit: builder.mutation<Balance, { asset: string; amount: string; txHash: string; chain?: string; chainId?: number }>({
       query: (data) => ({
         url: '/balances/deposit',
@@ -234,7 +345,7 @@ export const api = createApi({
       }),
       invalidatesTags: ['Balances'],
     }),
-    withdraw: builder.mutation<any, { asset: string; amount: string; address: string; chain?: string; chainId?: number }>({
+    withdraw: builder.mutation<any, { asset: string; amount: string; address: string; chain?: string; chainId?: number } & SecondFactor>({
       query: (data) => ({
         url: '/balances/withdraw',
         method: 'POST',
@@ -252,6 +363,50 @@ export const api = createApi({
     getWithdrawRecords: builder.query<any[], void>({
       query: () => '/balances/withdraws',
     }),
+
+    // ========== 安全设置接口 ==========
+    getSecurityStatus: builder.query<SecurityStatus, void>({
+      query: () => '/security',
+      providesTags: ['Security'],
+    }),
+    setupTOTP: builder.mutation<{ secret: string; otpauth_url: string }, void>({
+      query: () => ({ url: '/security/totp/setup', method: 'POST' }),
+    }),
+    enableTOTP: builder.mutation<{ message: string }, string>({
+      query: (code) => ({ url: '/security/totp/enable', method: 'POST', body: { code } }),
+      invalidatesTags: ['Security'],
+    }),
+    disableTOTP: builder.mutation<{ message: string }, string>({
+      query: (code) => ({ url: '/security/totp/disable', method: 'POST', body: { code } }),
+      invalidatesTags: ['Security'],
+    }),
+    setEmail: builder.mutation<{ message: string }, { email: string } & SecondFactor>({
+      query: (data) => ({ url: '/security/email', method: 'POST', body: data }),
+      invalidatesTags: ['Security'],
+    }),
+    verifyEmail: builder.mutation<{ message: string }, string>({
+      query: (code) => ({ url: '/security/email/verify', method: 'POST', body: { code } }),
+      invalidatesTags: ['Security'],
+    }),
+    sendVerificationCode: builder.mutation<{ message: string; sent_to: string }, 'withdraw' | 'security' | 'email_verify'>({
+      query: (purpose) => ({ url: '/security/codes', method: 'POST', body: { purpose } }),
+    }),
+    getWithdrawAddresses: builder.query<WithdrawAddress[], void>({
+      query: () => '/security/withdraw-addresses',
+      providesTags: ['Security'],
+    }),
+    addWithdrawAddress: builder.mutation<WithdrawAddress, { address: string; label?: string } & SecondFactor>({
+      query: (data) => ({ url: '/security/withdraw-addresses', method: 'POST', body: data }),
+      invalidatesTags: ['Security'],
+    }),
+    deleteWithdrawAddress: builder.mutation<{ message: string }, string>({
+      query: (id) => ({ url: `/security/withdraw-addresses/${id}`, method: 'DELETE' }),
+      invalidatesTags: ['Security'],
+    }),
+    setWithdrawWhitelist: builder.mutation<{ message: string }, { enabled: boolean } & SecondFactor>({
+      query: (data) => ({ url: '/security/withdraw-whitelist', method: 'PUT', body: data }),
+      invalidatesTags: ['Security'],
+    }),
   }),
 });
 
@@ -259,6 +414,7 @@ export const api = createApi({
 export const {
   // 认证
   useGetNonceMutation,
+  useLogoutMutation,
   useLoginMutation,
   useGetProfileQuery,
   
@@ -282,9 +438,23 @@ export const {
   // 余额
   useGetBalancesQuery,
   useGetBalanceQuery,
+  useGetDepositAddressQuery,
   useDepositMutation,
   useWithdrawMutation,
   useGetDepositRecordsQuery,
   useGetWithdrawRecordsQuery,
+
+  // 安全设置
+  useGetSecurityStatusQuery,
+  useSetupTOTPMutation,
+  useEnableTOTPMutation,
+  useDisableTOTPMutation,
+  useSetEmailMutation,
+  useVerifyEmailMutation,
+  useSendVerificationCodeMutation,
+  useGetWithdrawAddressesQuery,
+  useAddWithdrawAddressMutation,
+  useDeleteWithdrawAddressMutation,
+  useSetWithdrawWhitelistMutation,
 } = api;
 
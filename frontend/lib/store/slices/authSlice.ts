@@ -17,12 +17,15 @@ const authSlice = createSlice({
   name: 'auth',
   initialState,
   reducers: {
-    setAuth: (state, action: PayloadAction<{ user: User; token: string }>) => {
+    setAuth: (state, action: PayloadAction<{ user: User; token: string; refreshToken?: string }>) => {
       state.user = action.payload.user;
       state.token = action.payload.token;
       state.isAuthenticated = true;
       if (typeof window !== 'undefined') {
         localStorage.setItem('token', action.payload.token);
+        if (action.payload.refreshToken) {
+          localStorage.setItem('refresh_token', action.payload.refreshToken);
+        }
       }
     },
     logout: (state) => {
@@ -31,6 +34,7 @@ const authSlice = createSlice({
       state.isAuthenticated = false;
       if (typeof window !== 'undefined') {
         localStorage.removeItem('token');
+        localStorage.removeItem('refresh_token');
       }
     },
     setToken: (state, action: PayloadAction<string>) => {